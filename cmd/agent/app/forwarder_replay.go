@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/DataDog/datadog-agent/cmd/agent/common"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+var (
+	forwarderReplayURL    string
+	forwarderReplayAPIKey string
+)
+
+func init() {
+	AgentCmd.AddCommand(forwarderReplayCmd)
+	forwarderReplayCmd.Flags().StringVarP(&forwarderReplayURL, "url", "u", "", "Intake URL to send the payloads to. Defaults to the configured `dd_url`.")
+	forwarderReplayCmd.Flags().StringVarP(&forwarderReplayAPIKey, "api-key", "k", "", "API key used to send the payloads. Defaults to the configured `api_key`.")
+}
+
+var forwarderReplayCmd = &cobra.Command{
+	Use:   "forwarder-replay <directory>",
+	Short: "Send the payloads recorded by the forwarder shadow mode to an intake",
+	Long: `Send the payloads recorded in a directory by the forwarder shadow mode
+(see the forwarder_shadow_mode_path setting) to an intake, in the order they were recorded.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if flagNoColor {
+			color.NoColor = true
+		}
+
+		err := common.SetupConfig(confFilePath)
+		if err != nil {
+			return fmt.Errorf("unable to set up global agent configuration: %v", err)
+		}
+
+		err = config.SetupLogger(loggerName, config.GetEnvDefault("DD_LOG_LEVEL", "off"), "", "", false, true, false)
+		if err != nil {
+			fmt.Printf("Cannot setup logger, exiting: %v\n", err)
+			return err
+		}
+
+		return forwarderReplay(args[0])
+	},
+}
+
+func forwarderReplay(path string) error {
+	url := forwarderReplayURL
+	if url == "" {
+		url = config.GetMainInfraEndpoint()
+	}
+	apiKey := forwarderReplayAPIKey
+	if apiKey == "" {
+		apiKey = config.SanitizeAPIKey(config.Datadog.GetString("api_key"))
+	}
+	if apiKey == "" {
+		return fmt.Errorf("no API key configured, use --api-key")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigs
+		cancel()
+	}()
+
+	client := &http.Client{
+		Timeout:   config.Datadog.GetDuration("forwarder_timeout") * time.Second,
+		Transport: httputils.CreateHTTPTransport(),
+	}
+
+	fmt.Printf("Replaying the payloads recorded in %s to %s...\n\n", path, url)
+	results, err := forwarder.ReplayShadowRecords(ctx, path, url, apiKey, client)
+
+	failed := 0
+	for _, r := range results {
+		record := filepath.Base(r.Record)
+		if r.Err != nil {
+			failed++
+			fmt.Printf("%s %s: %v\n", color.RedString("FAILED"), record, r.Err)
+		} else {
+			fmt.Printf("%s %s: %d\n", color.GreenString("OK"), record, r.StatusCode)
+		}
+	}
+	fmt.Printf("\n%d payloads replayed, %d failed\n", len(results), failed)
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d payloads could not be replayed", failed)
+	}
+	return nil
+}
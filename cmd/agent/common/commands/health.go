@@ -96,6 +96,7 @@ func requestHealth() error {
 
 	sort.Strings(s.Unhealthy)
 	sort.Strings(s.Healthy)
+	sort.Strings(s.Degraded)
 
 	statusString := color.GreenString("PASS")
 	if len(s.Unhealthy) > 0 {
@@ -107,6 +108,10 @@ func requestHealth() error {
 		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s healthy components ===", color.GreenString(strconv.Itoa(len(s.Healthy)))))
 		fmt.Fprintln(color.Output, strings.Join(s.Healthy, ", "))
 	}
+	if len(s.Degraded) > 0 {
+		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s degraded components ===", color.YellowString(strconv.Itoa(len(s.Degraded)))))
+		fmt.Fprintln(color.Output, strings.Join(s.Degraded, ", "))
+	}
 	if len(s.Unhealthy) > 0 {
 		fmt.Fprintln(color.Output, fmt.Sprintf("=== %s unhealthy components ===", color.RedString(strconv.Itoa(len(s.Unhealthy)))))
 		fmt.Fprintln(color.Output, strings.Join(s.Unhealthy, ", "))
@@ -24,5 +24,8 @@ func init() {
 	complianceCmd.AddCommand(app.CheckCmd(func() []string {
 		return []string{confPath}
 	}))
+	complianceCmd.AddCommand(app.RegoInputCmd(func() []string {
+		return []string{confPath}
+	}))
 	ClusterAgentCmd.AddCommand(complianceCmd)
 }
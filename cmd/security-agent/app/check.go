@@ -12,13 +12,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
 	"github.com/DataDog/datadog-agent/cmd/security-agent/common"
+	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/agent"
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
 	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+	"github.com/DataDog/datadog-agent/pkg/compliance/results"
 	"github.com/DataDog/datadog-agent/pkg/config"
 	coreconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/util"
@@ -26,6 +29,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/kubernetes/apiserver"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+	"github.com/DataDog/datadog-agent/pkg/version"
 	"github.com/cihub/seelog"
 	"github.com/spf13/cobra"
 )
@@ -40,6 +44,10 @@ var (
 		dumpRegoInput     string
 		dumpReports       string
 		skipRegoEval      bool
+		reportFile        string
+		reportFormat      string
+		failOnFailure     bool
+		rootDir           string
 	}{}
 )
 
@@ -52,6 +60,10 @@ func setupCheckCmd(cmd *cobra.Command) {
 	cmd.Flags().StringVarP(&checkArgs.dumpRegoInput, "dump-rego-input", "", "", "Path to file where to dump the Rego input JSON")
 	cmd.Flags().StringVarP(&checkArgs.dumpReports, "dump-reports", "", "", "Path to file where to dump reports")
 	cmd.Flags().BoolVarP(&checkArgs.skipRegoEval, "skip-rego-eval", "", false, "Skip rego evaluation")
+	cmd.Flags().StringVarP(&checkArgs.reportFile, "report-file", "", "", "Path to file where to write the report of the rules and their results")
+	cmd.Flags().StringVarP(&checkArgs.reportFormat, "report-format", "", results.FormatJSON, fmt.Sprintf("Format of the report file, one of %v", results.Formats))
+	cmd.Flags().StringVarP(&checkArgs.rootDir, "root-dir", "", "", "Root directory, such as an unpacked container image or a mounted snapshot, to evaluate the filesystem resources against instead of the host")
+	cmd.Flags().BoolVarP(&checkArgs.failOnFailure, "fail-on-failure", "", false, "Exit with an error if a rule failed or couldn't be evaluated")
 }
 
 // CheckCmd returns a cobra command to run security agent checks
@@ -69,7 +81,7 @@ func CheckCmd(confPathArrayGetter func() []string) *cobra.Command {
 }
 
 func runCheck(cmd *cobra.Command, confPathArray []string, args []string) error {
-	err := configureLogger()
+	err := configureLogger(os.Stdout, checkArgs.verbose)
 	if err != nil {
 		return err
 	}
@@ -78,44 +90,17 @@ func runCheck(cmd *cobra.Command, confPathArray []string, args []string) error {
 		return errors.New("skipping the rego evaluation does not allow the generation of reports")
 	}
 
-	// We need to set before calling `SetupConfig`
-	configName := "datadog"
-	if flavor.GetFlavor() == flavor.ClusterAgent {
-		configName = "datadog-cluster"
+	if checkArgs.skipRegoEval && (checkArgs.reportFile != "" || checkArgs.failOnFailure) {
+		return errors.New("skipping the rego evaluation does not allow the generation of results")
 	}
 
-	// Read configuration files received from the command line arguments '-c'
-	if err := common.MergeConfigurationFiles(configName, confPathArray, cmd.Flags().Lookup("cfgpath").Changed); err != nil {
-		return err
+	if checkArgs.reportFile != "" && !isReportFormat(checkArgs.reportFormat) {
+		return fmt.Errorf("unknown report format `%s`, expecting one of %v", checkArgs.reportFormat, results.Formats)
 	}
 
-	options := []checks.BuilderOption{}
-
-	if flavor.GetFlavor() == flavor.ClusterAgent {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-
-		log.Info("Waiting for APIClient")
-		apiCl, err := apiserver.WaitForAPIClient(ctx)
-		if err != nil {
-			return err
-		}
-		options = append(options, checks.MayFail(checks.WithKubernetesClient(apiCl.DynamicCl, "")))
-	} else {
-		options = append(options, []checks.BuilderOption{
-			checks.WithHostRootMount(os.Getenv("HOST_ROOT")),
-			checks.MayFail(checks.WithDocker()),
-			checks.MayFail(checks.WithAudit()),
-		}...)
-
-		if config.IsKubernetes() {
-			nodeLabels, err := agent.WaitGetNodeLabels()
-			if err != nil {
-				log.Error(err)
-			} else {
-				options = append(options, checks.WithNodeLabels(nodeLabels))
-			}
-		}
+	options, hostname, err := newCheckBuilderOptions(cmd, confPathArray, checkArgs.rootDir)
+	if err != nil {
+		return err
 	}
 
 	var ruleID string
@@ -123,13 +108,6 @@ func runCheck(cmd *cobra.Command, confPathArray []string, args []string) error {
 		ruleID = args[0]
 	}
 
-	hostname, err := util.GetHostname(context.TODO())
-	if err != nil {
-		return err
-	}
-
-	options = append(options, checks.WithHostname(hostname))
-
 	stopper = startstop.NewSerialStopper()
 	defer stopper.Stop()
 
@@ -159,11 +137,12 @@ func runCheck(cmd *cobra.Command, confPathArray []string, args []string) error {
 
 	options = append(options, checks.WithRegoEvalSkip(checkArgs.skipRegoEval))
 
+	var statuses compliance.CheckStatusList
 	if checkArgs.file != "" {
-		err = agent.RunChecksFromFile(reporter, checkArgs.file, options...)
+		statuses, err = agent.RunChecksFromFile(reporter, checkArgs.file, options...)
 	} else {
 		configDir := config.Datadog.GetString("compliance_config.dir")
-		err = agent.RunChecks(reporter, configDir, options...)
+		statuses, err = agent.RunChecks(reporter, configDir, options...)
 	}
 
 	if err != nil {
@@ -176,20 +155,108 @@ func runCheck(cmd *cobra.Command, confPathArray []string, args []string) error {
 		return err
 	}
 
+	checkResults := results.New(hostname, version.AgentVersion, statuses, reporter.events)
+	if checkArgs.reportFile != "" {
+		if err := writeReportFile(checkResults, checkArgs.reportFile, checkArgs.reportFormat); err != nil {
+			log.Errorf("Failed to write report file: %v", err)
+			return err
+		}
+	}
+
+	if checkArgs.failOnFailure && checkResults.HasFailures() {
+		return fmt.Errorf("%d rule(s) failed, %d rule(s) in error", checkResults.Summary.Failed, checkResults.Summary.Error)
+	}
+
 	return nil
 }
 
-func configureLogger() error {
+// newCheckBuilderOptions reads the configuration and returns the options of the builder of the checks
+// running on this host, or against a root directory, along with the hostname
+func newCheckBuilderOptions(cmd *cobra.Command, confPathArray []string, rootDir string) ([]checks.BuilderOption, string, error) {
+	// We need to set before calling `SetupConfig`
+	configName := "datadog"
+	if flavor.GetFlavor() == flavor.ClusterAgent {
+		configName = "datadog-cluster"
+	}
+
+	// Read configuration files received from the command line arguments '-c'
+	if err := common.MergeConfigurationFiles(configName, confPathArray, cmd.Flags().Lookup("cfgpath").Changed); err != nil {
+		return nil, "", err
+	}
+
+	options := []checks.BuilderOption{}
+
+	if rootDir != "" {
+		options = append(options, checks.WithRootDir(rootDir))
+	} else if flavor.GetFlavor() == flavor.ClusterAgent {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		log.Info("Waiting for APIClient")
+		apiCl, err := apiserver.WaitForAPIClient(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		options = append(options, checks.MayFail(checks.WithKubernetesClient(apiCl.DynamicCl, "")))
+	} else {
+		options = append(options, []checks.BuilderOption{
+			checks.WithHostRootMount(os.Getenv("HOST_ROOT")),
+			checks.MayFail(checks.WithDocker()),
+			checks.MayFail(checks.WithAudit()),
+		}...)
+
+		if config.IsKubernetes() {
+			nodeLabels, err := agent.WaitGetNodeLabels()
+			if err != nil {
+				log.Error(err)
+			} else {
+				options = append(options, checks.WithNodeLabels(nodeLabels))
+			}
+		}
+	}
+
+	hostname, err := util.GetHostname(context.TODO())
+	if err != nil {
+		return nil, "", err
+	}
+
+	options = append(options, checks.WithHostname(hostname))
+	return options, hostname, nil
+}
+
+func isReportFormat(format string) bool {
+	for _, f := range results.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+func writeReportFile(checkResults *results.Results, path string, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := checkResults.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func configureLogger(w io.Writer, verbose bool) error {
 	var (
 		logFormat = "%LEVEL | %Msg%n"
 		logLevel  = "info"
 	)
-	if checkArgs.verbose {
+	if verbose {
 		const logDateFormat = "2006-01-02 15:04:05 MST"
 		logFormat = fmt.Sprintf("%%Date(%s) | %%LEVEL | (%%ShortFilePath:%%Line in %%FuncShort) | %%Msg%%n", logDateFormat)
 		logLevel = "trace"
 	}
-	logger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(os.Stdout, seelog.DebugLvl, logFormat)
+	logger, err := seelog.LoggerFromWriterWithMinLevelAndFormat(w, seelog.DebugLvl, logFormat)
 	if err != nil {
 		return err
 	}
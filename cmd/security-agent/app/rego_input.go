@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !windows && kubeapiserver
+// +build !windows,kubeapiserver
+
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/agent"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/util/startstop"
+	"github.com/spf13/cobra"
+)
+
+var (
+	regoInputArgs = struct {
+		framework string
+		file      string
+		verbose   bool
+		rootDir   string
+		output    string
+	}{}
+)
+
+// RegoInputCmd returns a cobra command to dump the Rego input of a compliance rule
+func RegoInputCmd(confPathArrayGetter func() []string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rego-input <rule-id>",
+		Short: "Dump the Rego input of a compliance rule",
+		Long: `Dump the input document built on this host for a compliance rule evaluated with Rego,
+so that the rule can be evaluated with opa eval --input`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runRegoInput(cmd, confPathArrayGetter(), args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&regoInputArgs.framework, "framework", "", "", "Framework to look for the rule in")
+	cmd.Flags().StringVarP(&regoInputArgs.file, "file", "f", "", "Compliance suite file to read the rule from")
+	cmd.Flags().BoolVarP(&regoInputArgs.verbose, "verbose", "v", false, "Include verbose details")
+	cmd.Flags().StringVarP(&regoInputArgs.rootDir, "root-dir", "", "", "Root directory, such as an unpacked container image or a mounted snapshot, to build the input against instead of the host")
+	cmd.Flags().StringVarP(&regoInputArgs.output, "output", "o", "", "Path to file where to write the Rego input, instead of the standard output")
+	return cmd
+}
+
+func runRegoInput(cmd *cobra.Command, confPathArray []string, ruleID string) error {
+	// the input is written to the standard output
+	if err := configureLogger(os.Stderr, regoInputArgs.verbose); err != nil {
+		return err
+	}
+
+	options, _, err := newCheckBuilderOptions(cmd, confPathArray, regoInputArgs.rootDir)
+	if err != nil {
+		return err
+	}
+
+	// the input is built by the check, which dumps it without evaluating the rule
+	dumpFile, err := os.CreateTemp("", "rego-input-*.json")
+	if err != nil {
+		return err
+	}
+	dumpPath := dumpFile.Name()
+	dumpFile.Close()
+	defer os.Remove(dumpPath)
+
+	options = append(options,
+		checks.WithMatchRule(checks.IsRuleID(ruleID)),
+		checks.WithRegoInputDumpPath(dumpPath),
+		checks.WithRegoEvalSkip(true),
+	)
+
+	if regoInputArgs.framework != "" {
+		options = append(options, checks.WithMatchSuite(checks.IsFramework(regoInputArgs.framework)))
+	}
+
+	stopper = startstop.NewSerialStopper()
+	defer stopper.Stop()
+
+	reporter, err := NewCheckReporter(stopper, false, "")
+	if err != nil {
+		return err
+	}
+
+	var statuses compliance.CheckStatusList
+	if regoInputArgs.file != "" {
+		statuses, err = agent.RunChecksFromFile(reporter, regoInputArgs.file, options...)
+	} else {
+		configDir := config.Datadog.GetString("compliance_config.dir")
+		statuses, err = agent.RunChecks(reporter, configDir, options...)
+	}
+	if err != nil {
+		return err
+	}
+
+	var found bool
+	for _, status := range statuses {
+		if status.RuleID != ruleID {
+			continue
+		}
+		if status.InitError != nil {
+			return fmt.Errorf("failed to load rule %s: %w", ruleID, status.InitError)
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("rule %s not found", ruleID)
+	}
+
+	content, err := os.ReadFile(dumpPath)
+	if err != nil {
+		return err
+	}
+
+	inputs := make(map[string]json.RawMessage)
+	if len(content) != 0 {
+		if err := json.Unmarshal(content, &inputs); err != nil {
+			return err
+		}
+	}
+
+	input, ok := inputs[ruleID]
+	if !ok {
+		return fmt.Errorf("no Rego input was built for rule %s, it may not be a Rego rule", ruleID)
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, input, "", "  "); err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+
+	if regoInputArgs.output != "" {
+		return os.WriteFile(regoInputArgs.output, buf.Bytes(), 0644)
+	}
+
+	_, err = os.Stdout.Write(buf.Bytes())
+	return err
+}
+
+func init() {
+	complianceCmd.AddCommand(RegoInputCmd(func() []string {
+		return confPathArray
+	}))
+}
@@ -36,6 +36,8 @@ import (
 	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/policylint"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/policytest"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
 	"github.com/DataDog/datadog-agent/pkg/status/health"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
@@ -110,6 +112,10 @@ var (
 		outputDirectory   string
 		outputFormat      string
 		remote            bool
+		base              string
+		json              bool
+		name              string
+		output            string
 	}{}
 
 	activityDumpGenerateCmd = &cobra.Command{
@@ -135,6 +141,21 @@ var (
 		RunE:  generateGraphFromActivityDump,
 	}
 
+	activityDumpGenerateAllowListCmd = &cobra.Command{
+		Use:   "allowlist",
+		Short: "generate a policy flagging the activity missing from an activity dump",
+		Long: `Generate a policy with the executables, the opened files and the DNS names recorded
+in an activity dump. Its rules flag the activity of the workload of the dump which
+deviates from the recorded one.`,
+		RunE: generateAllowListFromActivityDump,
+	}
+
+	activityDumpDiffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "list the executables, files and DNS names added and removed from an activity dump to another",
+		RunE:  diffActivityDumps,
+	}
+
 	activityDumpStopCmd = &cobra.Command{
 		Use:   "stop",
 		Short: "stops the first activity dump that matches the provided selector",
@@ -172,6 +193,39 @@ var (
 		RunE:  checkPolicies,
 	}
 
+	testPolicyCmd = &cobra.Command{
+		Use:   "test",
+		Short: "Evaluate policies against recorded or hand-written events",
+		Long: `Evaluate policies against events in the JSON format of the runtime security events.
+The events file contains JSON documents, in an array or as JSON lines. Each document
+is either an event or a test case with a name, an event and the IDs of the rules
+it is expected to match in "expected_rules". The command fails if a test case fails.`,
+		RunE: testPolicy,
+	}
+
+	testPolicyArgs = struct {
+		dir        string
+		policyFile string
+		eventsFile string
+		json       bool
+	}{}
+
+	lintPolicyCmd = &cobra.Command{
+		Use:   "lint",
+		Short: "Analyze policies and report the rules and macros which need attention",
+		Long: `Analyze policies against the runtime security model and the in-kernel filtering
+capabilities of the probe. On top of the load errors, it reports the rules which can
+never match, the rules with no approver, which disable the in-kernel filtering of their
+event type, the rule and macro IDs defined several times and the unused macros. The
+policies directories are loaded in order. The command fails if anything is reported.`,
+		RunE: lintPolicy,
+	}
+
+	lintPolicyArgs = struct {
+		dirs []string
+		json bool
+	}{}
+
 	downloadPolicyCmd = &cobra.Command{
 		Use:   "download",
 		Short: "Download policies",
@@ -264,16 +318,59 @@ func init() {
 		"when set, the profile generation will be done by system-probe, otherwise the current security-agent process will generate the profile",
 	)
 
+	activityDumpGenerateAllowListCmd.Flags().StringVar(
+		&activityDumpArgs.file,
+		"input",
+		"",
+		"path to the activity dump file",
+	)
+	_ = activityDumpGenerateAllowListCmd.MarkFlagRequired("input")
+	activityDumpGenerateAllowListCmd.Flags().StringVar(
+		&activityDumpArgs.name,
+		"name",
+		"",
+		"prefix of the rule and macro IDs, derived from the activity dump selector by default",
+	)
+	activityDumpGenerateAllowListCmd.Flags().StringVar(
+		&activityDumpArgs.output,
+		"output",
+		"",
+		"path to the policy file, the policy is written on the standard output by default",
+	)
+
+	activityDumpDiffCmd.Flags().StringVar(
+		&activityDumpArgs.base,
+		"base",
+		"",
+		"path to the activity dump file used as a reference",
+	)
+	_ = activityDumpDiffCmd.MarkFlagRequired("base")
+	activityDumpDiffCmd.Flags().StringVar(
+		&activityDumpArgs.file,
+		"input",
+		"",
+		"path to the activity dump file compared to the reference",
+	)
+	_ = activityDumpDiffCmd.MarkFlagRequired("input")
+	activityDumpDiffCmd.Flags().BoolVar(
+		&activityDumpArgs.json,
+		"json",
+		false,
+		"output the differences in JSON",
+	)
+
 	processCacheCmd.AddCommand(processCacheDumpCmd)
 	runtimeCmd.AddCommand(processCacheCmd)
 
 	activityDumpGenerateCmd.AddCommand(activityDumpGenerateDumpCmd)
 	activityDumpGenerateCmd.AddCommand(activityDumpGenerateProfileCmd)
 	activityDumpGenerateCmd.AddCommand(activityDumpGenerateGraphCmd)
+	activityDumpGenerateCmd.AddCommand(activityDumpGenerateAllowListCmd)
 
 	activityDumpCmd.AddCommand(activityDumpGenerateCmd)
 	activityDumpCmd.AddCommand(activityDumpListCmd)
 	activityDumpCmd.AddCommand(activityDumpStopCmd)
+	activityDumpCmd.AddCommand(activityDumpDiffCmd)
 	runtimeCmd.AddCommand(activityDumpCmd)
 
 	runtimeCmd.AddCommand(checkPoliciesCmd)
@@ -290,6 +387,17 @@ func init() {
 	commonPolicyCmd.AddCommand(commonCheckPoliciesCmd)
 
 	commonPolicyCmd.AddCommand(commonReloadPoliciesCmd)
+
+	testPolicyCmd.Flags().StringVar(&testPolicyArgs.dir, "policies-dir", coreconfig.DefaultRuntimePoliciesDir, "Path to policies directory")
+	testPolicyCmd.Flags().StringVar(&testPolicyArgs.policyFile, "policy", "", "Path to a policy file, tested instead of the policies directory")
+	testPolicyCmd.Flags().StringVar(&testPolicyArgs.eventsFile, "events", "", "Path to the events file")
+	_ = testPolicyCmd.MarkFlagRequired("events")
+	testPolicyCmd.Flags().BoolVar(&testPolicyArgs.json, "json", false, "Output the reports in JSON")
+	commonPolicyCmd.AddCommand(testPolicyCmd)
+
+	lintPolicyCmd.Flags().StringArrayVar(&lintPolicyArgs.dirs, "policies-dir", []string{coreconfig.DefaultRuntimePoliciesDir}, "Path to a policies directory, can be repeated")
+	lintPolicyCmd.Flags().BoolVar(&lintPolicyArgs.json, "json", false, "Output the findings in JSON")
+	commonPolicyCmd.AddCommand(lintPolicyCmd)
 	runtimeCmd.AddCommand(commonPolicyCmd)
 
 	dumpNetworkNamespaceCmd.Flags().BoolVar(&dumpNetworkNamespaceArgs.snapshotInterfaces, "snapshot-interfaces", true, "snapshot the interfaces of each network namespace during the dump")
@@ -522,6 +630,55 @@ func generateGraphFromActivityDump(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func generateAllowListFromActivityDump(cmd *cobra.Command, args []string) error {
+	dump, err := sprobe.LoadActivityDump(activityDumpArgs.file)
+	if err != nil {
+		return err
+	}
+
+	name := activityDumpArgs.name
+	if len(name) == 0 {
+		name = dump.GetAllowListName()
+	}
+
+	if len(activityDumpArgs.output) == 0 {
+		return dump.WriteAllowListPolicy(os.Stdout, name)
+	}
+
+	f, err := os.OpenFile(activityDumpArgs.output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("couldn't create policy file: %w", err)
+	}
+	defer f.Close()
+
+	if err = dump.WriteAllowListPolicy(f, name); err != nil {
+		return fmt.Errorf("allow-list generation failed: %w", err)
+	}
+
+	fmt.Printf("Generated allow-list policy: %s\n", activityDumpArgs.output)
+	return nil
+}
+
+func diffActivityDumps(cmd *cobra.Command, args []string) error {
+	base, err := sprobe.LoadActivityDump(activityDumpArgs.base)
+	if err != nil {
+		return err
+	}
+
+	other, err := sprobe.LoadActivityDump(activityDumpArgs.file)
+	if err != nil {
+		return err
+	}
+
+	diff := sprobe.DiffActivityDumps(base, other)
+	if activityDumpArgs.json {
+		content, _ := json.MarshalIndent(diff, "", "\t")
+		fmt.Printf("%s\n", string(content))
+		return nil
+	}
+	return diff.WriteText(os.Stdout)
+}
+
 func checkPoliciesInner(dir string) error {
 	cfg := &secconfig.Config{
 		PoliciesDir:         dir,
@@ -677,6 +834,78 @@ func startRuntimeSecurity(hostname string, stopper startstop.Stopper, statsdClie
 	return agent, nil
 }
 
+func testPolicy(cmd *cobra.Command, args []string) error {
+	var provider rules.PolicyProvider
+	if testPolicyArgs.policyFile != "" {
+		provider = policytest.NewPolicyFileProvider(testPolicyArgs.policyFile)
+	} else {
+		dirProvider, err := rules.NewPoliciesDirProvider(testPolicyArgs.dir, false)
+		if err != nil {
+			return err
+		}
+		provider = dirProvider
+	}
+
+	tester, err := policytest.NewTester(rules.NewPolicyLoader(provider), policytest.Opts{
+		FieldCapabilities:   sprobe.GetCapababilities(),
+		SupportedDiscarders: sprobe.SupportedDiscarders,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(testPolicyArgs.eventsFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	testCases, err := policytest.ReadTestCases(f)
+	if err != nil {
+		return fmt.Errorf("invalid events file: %w", err)
+	}
+
+	reports := tester.RunAll(testCases)
+	if testPolicyArgs.json {
+		content, _ := json.MarshalIndent(reports, "", "\t")
+		fmt.Printf("%s\n", string(content))
+	} else if err := policytest.WriteText(os.Stdout, reports); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, report := range reports {
+		if report.Failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d test case(s) failed", failed)
+	}
+	return nil
+}
+
+func lintPolicy(cmd *cobra.Command, args []string) error {
+	findings, err := policylint.Lint(lintPolicyArgs.dirs, policylint.Opts{
+		FieldCapabilities: sprobe.GetCapababilities(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if lintPolicyArgs.json {
+		content, _ := json.MarshalIndent(findings, "", "\t")
+		fmt.Printf("%s\n", string(content))
+	} else if err := policylint.WriteText(os.Stdout, findings); err != nil {
+		return err
+	}
+
+	if len(findings) > 0 {
+		return fmt.Errorf("%d finding(s)", len(findings))
+	}
+	return nil
+}
+
 func downloadPolicy(cmd *cobra.Command, args []string) error {
 	apiKey := coreconfig.Datadog.GetString("api_key")
 	appKey := coreconfig.Datadog.GetString("app_key")
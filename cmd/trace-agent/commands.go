@@ -0,0 +1,33 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// commands holds the trace-agent subcommands, keyed by name. Each one receives
+// the command line arguments following its name.
+var commands = map[string]func(args []string) error{
+	"lint":   runLint,
+	"replay": runReplay,
+}
+
+// runCommand runs the subcommand specified by the first element of args.
+func runCommand(args []string) error {
+	cmd, ok := commands[args[0]]
+	if !ok {
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown command %q, available commands: %s", args[0], strings.Join(names, ", "))
+	}
+	return cmd(args[1:])
+}
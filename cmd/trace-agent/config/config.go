@@ -207,6 +207,14 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 	if coreconfig.Datadog.IsSet("apm_config.max_remote_traces_per_second") {
 		c.MaxRemoteTPS = coreconfig.Datadog.GetFloat64("apm_config.max_remote_traces_per_second")
 	}
+	if k := "apm_config.sampling_rules"; coreconfig.Datadog.IsSet(k) {
+		rules := make([]*config.SamplingRule, 0)
+		if err := coreconfig.Datadog.UnmarshalKey(k, &rules); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"service\":\"web\",\"resource\":\"POST /checkout\",\"sample_rate\":1}]', error: %v", k, err)
+		} else {
+			c.SamplingRules = rules
+		}
+	}
 
 	if k := "apm_config.ignore_resources"; coreconfig.Datadog.IsSet(k) {
 		c.Ignore["resource"] = coreconfig.Datadog.GetStringSlice(k)
@@ -226,6 +234,14 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			c.ReplaceTags = rt
 		}
 	}
+	if k := "apm_config.span_metrics"; coreconfig.Datadog.IsSet(k) {
+		sm := make([]*config.SpanMetric, 0)
+		if err := coreconfig.Datadog.UnmarshalKey(k, &sm); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"metric_name\",\"type\":\"count\",\"filter\":[\"service:web\"],\"group_by\":[\"resource\"]}]', error: %v", k, err)
+		} else {
+			c.SpanMetrics = sm
+		}
+	}
 
 	if coreconfig.Datadog.IsSet("bind_host") || coreconfig.Datadog.IsSet("apm_config.apm_non_local_traffic") {
 		if coreconfig.Datadog.IsSet("bind_host") {
@@ -260,6 +276,9 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 		SpanNameRemappings:     coreconfig.Datadog.GetStringMapString("otlp_config.traces.span_name_remappings"),
 		SpanNameAsResourceName: coreconfig.Datadog.GetBool("otlp_config.traces.span_name_as_resource_name"),
 	}
+	c.ZipkinReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.zipkin_receiver.enabled")
+	c.JaegerReceiverEnabled = coreconfig.Datadog.GetBool("apm_config.jaeger_receiver.enabled")
+	c.LintLiveEnabled = coreconfig.Datadog.GetBool("apm_config.lint_live.enabled")
 
 	if coreconfig.Datadog.GetBool("apm_config.telemetry.enabled") {
 		c.TelemetryConfig.Enabled = true
@@ -322,6 +341,9 @@ func applyDatadogConfig(c *config.AgentConfig) error {
 			log.Errorf("Error reading writer config %q: %v", key, err)
 		}
 	}
+	if err := coreconfig.Datadog.UnmarshalKey("apm_config.local_sink", c.LocalSink); err != nil {
+		log.Errorf("Error reading local sink config: %v", err)
+	}
 	if coreconfig.Datadog.IsSet("apm_config.connection_reset_interval") {
 		c.ConnectionResetInterval = getDuration(coreconfig.Datadog.GetInt("apm_config.connection_reset_interval"))
 	}
@@ -824,6 +824,64 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_SPAN_METRICS"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"name":"checkout.latency","type":"distribution","value":"duration","filter":["service:web","resource:POST /checkout"],"group_by":["env"]}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		assert.Equal([]*config.SpanMetric{{
+			Name:    "checkout.latency",
+			Type:    "distribution",
+			Value:   "duration",
+			Filter:  []string{"service:web", "resource:POST /checkout"},
+			GroupBy: []string{"env"},
+		}}, cfg.SpanMetrics)
+	})
+
+	env = "DD_APM_SAMPLING_RULES"
+	t.Run(env, func(t *testing.T) {
+		defer cleanConfig()()
+		assert := assert.New(t)
+		err := os.Setenv(env, `[{"service":"web","resource":"POST /checkout","sample_rate":1},{"resource":"/healthz","target_tps":0.1}]`)
+		assert.NoError(err)
+		defer os.Unsetenv(env)
+		cfg, err := LoadConfigFile("./testdata/full.yaml")
+		assert.NoError(err)
+		rate := 1.0
+		assert.Equal([]*config.SamplingRule{
+			{Service: "web", Resource: "POST /checkout", SampleRate: &rate},
+			{Resource: "/healthz", TargetTPS: 0.1},
+		}, cfg.SamplingRules)
+	})
+
+	for _, envKey := range []string{
+		"DD_APM_ZIPKIN_RECEIVER_ENABLED",
+		"DD_APM_JAEGER_RECEIVER_ENABLED",
+		"DD_APM_LINT_LIVE_ENABLED",
+	} {
+		t.Run(envKey, func(t *testing.T) {
+			defer cleanConfig()()
+			assert := assert.New(t)
+			err := os.Setenv(envKey, "true")
+			assert.NoError(err)
+			defer os.Unsetenv(envKey)
+			cfg, err := LoadConfigFile("./testdata/full.yaml")
+			assert.NoError(err)
+			enabled := cfg.ZipkinReceiverEnabled
+			switch envKey {
+			case "DD_APM_JAEGER_RECEIVER_ENABLED":
+				enabled = cfg.JaegerReceiverEnabled
+			case "DD_APM_LINT_LIVE_ENABLED":
+				enabled = cfg.LintLiveEnabled
+			}
+			assert.True(enabled)
+		})
+	}
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		defer cleanConfig()()
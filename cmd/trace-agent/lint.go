@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cmdconfig "github.com/DataDog/datadog-agent/cmd/trace-agent/config"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/internal/flags"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+)
+
+// runLint implements the "lint" command, which reports how the running trace-agent
+// processes a payload read from a file, or the payloads it currently receives.
+func runLint(args []string) error {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	agentURL := fs.String("agent-url", "", "URL of the trace-agent (defaults to the configured receiver)")
+	version := fs.String("v", "v0.4", "API version the payload file is encoded for (v0.1 to v0.5, v0.7)")
+	contentType := fs.String("content-type", "", "Content-Type of the payload file (defaults to application/json for .json files, application/msgpack otherwise)")
+	lang := fs.String("lang", "", "language of the tracer which produced the payload")
+	live := fs.Int("live", 0, "lint this number of payloads received by the trace-agent instead of a file (requires apm_config.lint_live.enabled)")
+	timeout := fs.Duration("timeout", 30*time.Second, "maximum time to wait for live payloads")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trace-agent [-config <path>] lint [flags] <payload file>")
+		fmt.Fprintln(fs.Output(), "       trace-agent [-config <path>] lint [flags] -live <n>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args) //nolint:errcheck
+	if (*live > 0) == (fs.NArg() > 0) {
+		fs.Usage()
+		return errors.New("exactly one of a payload file or -live must be specified")
+	}
+
+	base := *agentURL
+	if base == "" {
+		cfg, err := cmdconfig.LoadConfigFile(flags.ConfigPath)
+		if err != nil {
+			return err
+		}
+		host := cfg.ReceiverHost
+		if host == "" || host == "0.0.0.0" {
+			host = "localhost"
+		}
+		base = fmt.Sprintf("http://%s:%d", host, cfg.ReceiverPort)
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	var (
+		rep *api.LintReport
+		err error
+	)
+	if *live > 0 {
+		rep, err = lintLive(base, *live, *timeout)
+	} else {
+		rep, err = lintFile(base, fs.Arg(0), *version, *contentType, *lang)
+	}
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rep)
+	}
+	printLintReport(os.Stdout, rep)
+	return nil
+}
+
+// lintFile sends the payload found at path to the /debug/lint endpoint of the
+// trace-agent at base.
+func lintFile(base, path, version, contentType, lang string) (*api.LintReport, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if contentType == "" {
+		contentType = "application/msgpack"
+		if filepath.Ext(path) == ".json" {
+			contentType = "application/json"
+		}
+	}
+	req, err := http.NewRequest("POST", base+"/debug/lint?v="+url.QueryEscape(version), f)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if lang != "" {
+		req.Header.Set("Datadog-Meta-Lang", lang)
+	}
+	return doLintRequest(req)
+}
+
+// lintLive lints n payloads received by the trace-agent at base, waiting for them at
+// most timeout. As each request to /debug/lint/live is bound by the receiver timeout,
+// it is called repeatedly.
+func lintLive(base string, n int, timeout time.Duration) (*api.LintReport, error) {
+	rep := &api.LintReport{}
+	deadline := time.Now().Add(timeout)
+	for rep.Payloads < n {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		u := fmt.Sprintf("%s/debug/lint/live?n=%d&timeout=%s", base, n-rep.Payloads, left)
+		req, err := http.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+		r, err := doLintRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		rep.Add(r)
+	}
+	return rep, nil
+}
+
+func doLintRequest(req *http.Request) (*api.LintReport, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("%s: %s: %s", req.URL.Path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	var rep api.LintReport
+	if err := json.NewDecoder(resp.Body).Decode(&rep); err != nil {
+		return nil, err
+	}
+	return &rep, nil
+}
+
+// printLintReport prints a human readable version of rep to w.
+func printLintReport(w io.Writer, rep *api.LintReport) {
+	var dropped, modified int
+	for _, t := range rep.Traces {
+		switch {
+		case t.DropReason != "":
+			dropped++
+			fmt.Fprintf(w, "trace %d (%d spans): dropped (reason:%s): %s\n", t.TraceID, t.Spans, t.DropReason, t.Error)
+		case len(t.ModifiedSpans) > 0:
+			modified++
+			fmt.Fprintf(w, "trace %d (%d spans): %d spans modified\n", t.TraceID, t.Spans, len(t.ModifiedSpans))
+			for _, s := range t.ModifiedSpans {
+				fmt.Fprintf(w, "  span %d (service=%q name=%q): %s\n", s.SpanID, s.Service, s.Name, strings.Join(s.Reasons, ", "))
+				for _, c := range s.Changes {
+					if c.Removed {
+						fmt.Fprintf(w, "    %s: %s removed\n", c.Field, truncateLintValue(c.Before))
+					} else {
+						fmt.Fprintf(w, "    %s: %s -> %s\n", c.Field, truncateLintValue(c.Before), truncateLintValue(c.After))
+					}
+				}
+			}
+		}
+	}
+	fmt.Fprintf(w, "Linted %d payloads, %d traces: %d dropped, %d with modified spans.\n", rep.Payloads, len(rep.Traces), dropped, modified)
+}
+
+// truncateLintValue quotes v, shortening it when it is too long to be printed.
+func truncateLintValue(v string) string {
+	const max = 80
+	if len(v) <= max {
+		return strconv.Quote(v)
+	}
+	return fmt.Sprintf("%q... (%d bytes)", v[:max], len(v))
+}
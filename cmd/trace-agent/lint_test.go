@@ -0,0 +1,64 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLint(t *testing.T) {
+	rep := &api.LintReport{
+		Payloads: 1,
+		Traces: []*api.TraceDiagnostic{
+			{TraceID: 1, Spans: 1, DropReason: "trace_id_zero", Error: "TraceID is zero"},
+			{TraceID: 2, Spans: 2, ModifiedSpans: []*api.SpanDiagnostic{{
+				SpanID:  3,
+				Name:    "web.request",
+				Reasons: []string{"service_empty", "invalid_http_status_code"},
+				Changes: []api.FieldChange{
+					{Field: "meta.http.status_code", Before: "999", Removed: true},
+					{Field: "service", After: "unnamed-go-service"},
+				},
+			}}},
+			{TraceID: 4, Spans: 1},
+		},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/debug/lint", req.URL.Path)
+		assert.Equal(t, "v0.5", req.URL.Query().Get("v"))
+		assert.Equal(t, "application/msgpack", req.Header.Get("Content-Type"))
+		assert.Equal(t, "go", req.Header.Get("Datadog-Meta-Lang"))
+		json.NewEncoder(w).Encode(rep) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "payload.msgp")
+	require.NoError(t, ioutil.WriteFile(path, []byte{0x90}, 0600))
+	got, err := lintFile(srv.URL, path, "v0.5", "", "go")
+	require.NoError(t, err)
+	assert.Equal(t, rep, got)
+
+	var buf bytes.Buffer
+	printLintReport(&buf, got)
+	assert.Equal(t, `trace 1 (1 spans): dropped (reason:trace_id_zero): TraceID is zero
+trace 2 (2 spans): 1 spans modified
+  span 3 (service="" name="web.request"): service_empty, invalid_http_status_code
+    meta.http.status_code: "999" removed
+    service: "" -> "unnamed-go-service"
+Linted 1 payloads, 3 traces: 1 dropped, 1 with modified spans.
+`, buf.String())
+}
@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cmdconfig "github.com/DataDog/datadog-agent/cmd/trace-agent/config"
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/internal/flags"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+// runReplay implements the "replay" command, which sends payloads previously written
+// by the local sink (apm_config.local_sink) to the intake or to another trace-agent.
+func runReplay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	agentURL := fs.String("agent-url", "", "replay to the trace-agent at this URL (e.g. http://localhost:8126) instead of the configured intake")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: trace-agent [-config <path>] replay [-agent-url <url>] <file or directory>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args) //nolint:errcheck
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("no sink file or directory specified")
+	}
+
+	cfg, err := cmdconfig.LoadConfigFile(flags.ConfigPath)
+	if err != nil {
+		if *agentURL == "" {
+			return err
+		}
+		// the configuration is only needed for the intake endpoint and API key
+		fmt.Fprintf(os.Stderr, "Ignoring the configuration, using the default settings: %v\n", err)
+		cfg = nil
+	}
+	r, err := writer.NewReplayer(cfg, *agentURL)
+	if err != nil {
+		return err
+	}
+	files, err := sinkFiles(fs.Args())
+	if err != nil {
+		return err
+	}
+	var total int
+	for _, f := range files {
+		n, err := r.ReplayFile(f)
+		total += n
+		if err != nil {
+			return fmt.Errorf("%s: %v", f, err)
+		}
+		fmt.Printf("%s: %d requests sent\n", f, n)
+	}
+	fmt.Printf("Replayed %d files (%d requests).\n", len(files), total)
+	return nil
+}
+
+// sinkFiles expands the given paths into the list of sink files to replay. Directories
+// are expanded to the trace and stats files they contain, in the order they were written.
+func sinkFiles(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		var found []string
+		for _, kind := range []writer.SinkRecordKind{writer.SinkRecordTraces, writer.SinkRecordStats} {
+			matches, err := filepath.Glob(filepath.Join(path, string(kind)+"-*"))
+			if err != nil {
+				return nil, err
+			}
+			found = append(found, matches...)
+		}
+		// file names are suffixed with their creation timestamp
+		created := func(f string) string {
+			base := filepath.Base(f)
+			return base[strings.IndexByte(base, '-')+1:]
+		}
+		sort.Slice(found, func(i, j int) bool { return created(found[i]) < created(found[j]) })
+		files = append(files, found...)
+	}
+	return files, nil
+}
@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/cmd/trace-agent/internal/flags"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunReplayInvalidConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "datadog.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte("apm_config: [invalid"), 0600))
+	defer func(old string) { flags.ConfigPath = old }(flags.ConfigPath)
+	flags.ConfigPath = path
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer srv.Close()
+
+	// the configuration is ignored when replaying to a trace-agent
+	assert.NoError(t, runReplay([]string{"-agent-url", srv.URL, dir}))
+	assert.Error(t, runReplay([]string{dir}))
+}
@@ -7,6 +7,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -54,6 +55,13 @@ func Run(ctx context.Context) {
 		return
 	}
 
+	if flag.NArg() > 0 {
+		if err := runCommand(flag.Args()); err != nil {
+			osutil.Exitf("%v", err)
+		}
+		return
+	}
+
 	cfg, err := cmdconfig.LoadConfigFile(flags.ConfigPath)
 	if err != nil {
 		fmt.Println(err) // TODO: remove me
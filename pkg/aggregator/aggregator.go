@@ -450,11 +450,15 @@ func (agg *BufferedAggregator) getSeriesAndSketches(before time.Time, series met
 	defer agg.mu.Unlock()
 
 	var sketches metrics.SketchSeriesList
-	for _, checkSampler := range agg.checkSamplers {
+	for id, checkSampler := range agg.checkSamplers {
 		checkSeries, sk := checkSampler.flush()
 		for _, s := range checkSeries {
+			s.CheckID = string(id)
 			series.Append(s)
 		}
+		for i := range sk {
+			sk[i].CheckID = string(id)
+		}
 		sketches = append(sketches, sk...)
 	}
 	return sketches
@@ -197,6 +197,34 @@ func TestAddEventDefaultValues(t *testing.T) {
 	assert.Equal(t, "custom_source_type", event2.SourceTypeName)
 }
 
+func TestGetSeriesAndSketchesCheckID(t *testing.T) {
+	s := &MockSerializerIterableSerie{}
+	agg := NewBufferedAggregator(s, nil, "hostname", DefaultFlushInterval)
+	require.NoError(t, agg.registerSender(checkID1))
+
+	agg.handleSenderSample(senderMetricSample{checkID1, &metrics.MetricSample{
+		Name:       "my.gauge",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		SampleRate: 1,
+		Timestamp:  12345,
+	}, false})
+	agg.handleSenderBucket(senderHistogramBucket{checkID1, &metrics.HistogramBucket{
+		Name:       "my.histogram",
+		Value:      1,
+		LowerBound: 0,
+		UpperBound: 10,
+		Timestamp:  12345,
+	}})
+	agg.handleSenderSample(senderMetricSample{id: checkID1, commit: true})
+
+	series, sketches := agg.GetSeriesAndSketches(time.Now())
+	require.Len(t, series, 1)
+	assert.Equal(t, string(checkID1), series[0].CheckID)
+	require.Len(t, sketches, 1)
+	assert.Equal(t, string(checkID1), sketches[0].CheckID)
+}
+
 func TestSetHostname(t *testing.T) {
 	// this test IS USING globals
 	// -
@@ -17,6 +17,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer"
+	"github.com/DataDog/datadog-agent/pkg/serializer/otlp"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
+	"github.com/DataDog/datadog-agent/pkg/serializer/tenant"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -64,6 +67,8 @@ type forwarders struct {
 	orchestrator       forwarder.Forwarder
 	eventPlatform      epforwarder.EventPlatformForwarder
 	containerLifecycle *forwarder.DefaultForwarder
+	tenants            map[string]forwarder.Forwarder
+	otlp               *otlp.Forwarder
 }
 
 type dataOutputs struct {
@@ -120,6 +125,8 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 	var sharedForwarder forwarder.Forwarder
 	if options.UseNoopForwarder {
 		sharedForwarder = forwarder.NoopForwarder{}
+	} else if shadowPath := config.Datadog.GetString("forwarder_shadow_mode_path"); shadowPath != "" {
+		sharedForwarder = forwarder.NewShadowForwarder(shadowPath, config.Datadog.GetInt64("forwarder_shadow_mode_max_size"))
 	} else {
 		sharedForwarder = forwarder.NewDefaultForwarder(options.SharedForwarderOptions)
 	}
@@ -127,7 +134,25 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 	// prepare the serializer
 	// ----------------------
 
-	sharedSerializer := serializer.NewSerializer(sharedForwarder, orchestratorForwarder, containerLifecycleForwarder)
+	var sharedSerializer serializer.MetricSerializer = serializer.NewSerializer(sharedForwarder, orchestratorForwarder, containerLifecycleForwarder)
+	var tenantForwarders map[string]forwarder.Forwarder
+	if config.Datadog.GetBool("tenant_routing.enabled") && !options.UseNoopForwarder {
+		if tenants, err := tenant.GetConfigs(); err != nil {
+			log.Errorf("The data of the tenants is not routed to their organization: %v", err)
+		} else {
+			tenantForwarders = tenant.NewForwarders(tenants)
+			sharedSerializer = tenant.NewRouterFromConfig(sharedSerializer, tenants, tenantForwarders)
+		}
+	}
+	var otlpForwarder *otlp.Forwarder
+	if config.Datadog.GetBool("otlp_metrics_exporter.enabled") {
+		if otlpSerializer, err := otlp.NewSerializerFromConfig(sharedSerializer); err != nil {
+			log.Errorf("Metrics are not exported to the OTLP collector: %v", err)
+		} else {
+			sharedSerializer = otlpSerializer
+			otlpForwarder = otlpSerializer.Forwarder()
+		}
+	}
 
 	// prepare the embedded aggregator
 	// --
@@ -174,6 +199,8 @@ func initAgentDemultiplexer(options DemultiplexerOptions, hostname string) *Agen
 				orchestrator:       orchestratorForwarder,
 				eventPlatform:      eventPlatformForwarder,
 				containerLifecycle: containerLifecycleForwarder,
+				tenants:            tenantForwarders,
+				otlp:               otlpForwarder,
 			},
 
 			sharedSerializer: sharedSerializer,
@@ -252,6 +279,18 @@ func (d *AgentDemultiplexer) Run() {
 		} else {
 			log.Debug("not starting the shared forwarder")
 		}
+
+		// tenant forwarders
+		for tenant, fwd := range d.forwarders.tenants {
+			if err := fwd.Start(); err != nil {
+				log.Errorf("error starting the forwarder of tenant %q: %v", tenant, err)
+			}
+		}
+
+		// OTLP metrics forwarder
+		if d.forwarders.otlp != nil {
+			d.forwarders.otlp.Start()
+		}
 		log.Debug("Forwarders started")
 	}
 
@@ -349,6 +388,14 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 			d.dataOutputs.forwarders.shared.Stop()
 			d.dataOutputs.forwarders.shared = nil
 		}
+		for _, fwd := range d.dataOutputs.forwarders.tenants {
+			fwd.Stop()
+		}
+		d.dataOutputs.forwarders.tenants = nil
+		if d.dataOutputs.forwarders.otlp != nil {
+			d.dataOutputs.forwarders.otlp.Stop()
+			d.dataOutputs.forwarders.otlp = nil
+		}
 	}
 
 	// misc
@@ -392,6 +439,9 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 		return
 	}
 
+	// the payloads sent from now on are reported with this flush
+	sizereport.Get().StartFlush(start)
+
 	logPayloads := config.Datadog.GetBool("log_payloads")
 	flushedSketches := make([]metrics.SketchSeriesList, 0)
 
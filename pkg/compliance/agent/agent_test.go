@@ -226,7 +226,7 @@ func TestRunChecks(t *testing.T) {
 	dockerClient.On("Close").Return(nil).Once()
 	defer dockerClient.AssertExpectations(t)
 
-	err := RunChecks(
+	_, err := RunChecks(
 		reporter,
 		e.dir,
 		checks.WithMatchSuite(checks.IsFramework("cis-docker")),
@@ -274,7 +274,7 @@ func TestRunChecksFromFile(t *testing.T) {
 		"node-role.kubernetes.io/worker": "",
 	}
 
-	err := RunChecksFromFile(
+	statuses, err := RunChecksFromFile(
 		reporter,
 		filepath.Join(e.dir, "cis-kubernetes.yaml"),
 		checks.WithHostname("the-host"),
@@ -284,4 +284,7 @@ func TestRunChecksFromFile(t *testing.T) {
 		checks.WithKubernetesClient(kubeClient, "kube_system_uuid"),
 	)
 	assert.NoError(err)
+	assert.Len(statuses, 1)
+	assert.Equal("cis-kubernetes-1", statuses[0].RuleID)
+	assert.Equal("cis-kubernetes", statuses[0].Framework)
 }
@@ -12,6 +12,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -90,6 +91,34 @@ func WithHostRootMount(hostRootMount string) BuilderOption {
 	}
 }
 
+// WithRootDir evaluates the filesystem resources against a root directory, such as an unpacked
+// container image or a mounted filesystem snapshot, instead of the host.
+// The rules with resources which can only be evaluated on a live host don't apply.
+func WithRootDir(rootDir string) BuilderOption {
+	return func(b *builder) error {
+		rootDir, err := filepath.Abs(rootDir)
+		if err != nil {
+			return err
+		}
+
+		fi, err := os.Stat(rootDir)
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("root directory %s is not a directory", rootDir)
+		}
+
+		log.Infof("Filesystem resources will be evaluated against the root directory %s", rootDir)
+		b.rootDir = rootDir
+		b.pathMapper = &pathMapper{
+			hostMountPath: rootDir,
+		}
+		b.etcGroupPath = filepath.Join(rootDir, "/etc/group")
+		return nil
+	}
+}
+
 // WithDocker configures using docker
 func WithDocker() BuilderOption {
 	return func(b *builder) error {
@@ -293,6 +322,7 @@ type builder struct {
 
 	hostname     string
 	pathMapper   *pathMapper
+	rootDir      string
 	etcGroupPath string
 	nodeLabels   map[string]string
 
@@ -445,6 +475,17 @@ func (b *builder) checkFromRule(meta *compliance.SuiteMeta, rule *compliance.Con
 		return nil, err
 	}
 
+	var kinds []compliance.ResourceKind
+	for _, resource := range rule.Resources {
+		kinds = append(kinds, resource.Kind())
+		if resource.Fallback != nil {
+			kinds = append(kinds, resource.Fallback.Resource.Kind())
+		}
+	}
+	if !b.rootDirMatcher(rule.ID, kinds) {
+		return nil, ErrRuleDoesNotApply
+	}
+
 	eligible, err := b.hostMatcher(ruleScope, rule.ID, rule.HostSelector)
 	if err != nil {
 		return nil, err
@@ -465,6 +506,14 @@ func (b *builder) checkFromRegoRule(meta *compliance.SuiteMeta, rule *compliance
 		return nil, err
 	}
 
+	var kinds []compliance.ResourceKind
+	for _, input := range rule.Inputs {
+		kinds = append(kinds, input.Kind())
+	}
+	if !b.rootDirMatcher(rule.ID, kinds) {
+		return nil, ErrRuleDoesNotApply
+	}
+
 	// skip host match check if rego input is overridden
 	if b.regoInputOverride == nil {
 		eligible, err := b.hostMatcher(ruleScope, rule.ID, rule.HostSelector)
@@ -570,7 +619,42 @@ func (b *builder) getRuleResourceReporter(scope compliance.RuleScope, rule compl
 	}
 }
 
+// rootDirResourceKinds are the kinds of resources read from the filesystem, which can be evaluated against a root directory
+var rootDirResourceKinds = map[compliance.ResourceKind]bool{
+	compliance.KindFile:      true,
+	compliance.KindGroup:     true,
+	compliance.KindPackage:   true,
+	compliance.KindSystemd:   true,
+	compliance.KindConstants: true,
+}
+
+// rootDirMatcher returns whether the resources of a rule can be evaluated, which is always the case
+// on a live host
+func (b *builder) rootDirMatcher(ruleID string, kinds []compliance.ResourceKind) bool {
+	if b.rootDir == "" {
+		return true
+	}
+
+	for _, kind := range kinds {
+		if !rootDirResourceKinds[kind] {
+			log.Infof("rule %s skipped - resource kind %s can't be evaluated against a root directory", ruleID, kind)
+			return false
+		}
+	}
+	return true
+}
+
 func (b *builder) hostMatcher(scope compliance.RuleScope, ruleID string, hostSelector string) (bool, error) {
+	// the filesystem of a root directory is evaluated whatever the environment of the host
+	if b.rootDir != "" {
+		switch scope {
+		case compliance.DockerScope:
+			return true, nil
+		case compliance.KubernetesNodeScope:
+			return b.isKubernetesNodeEligible(hostSelector)
+		}
+	}
+
 	switch scope {
 	case compliance.DockerScope:
 		if b.dockerClient == nil {
@@ -779,6 +863,10 @@ func (b *builder) RelativeToHostRoot(path string) string {
 	return b.pathMapper.relativeToHostRoot(path)
 }
 
+func (b *builder) RootDir() string {
+	return b.rootDir
+}
+
 func (b *builder) IsLeader() bool {
 	if b.isLeaderFunc != nil {
 		return b.isLeaderFunc()
@@ -8,8 +8,12 @@ package checks
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
 	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
 	"github.com/DataDog/datadog-agent/pkg/compliance/mocks"
@@ -220,3 +224,123 @@ func TestResolveValueFrom(t *testing.T) {
 		})
 	}
 }
+
+func TestRootDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		// the files of the test are owned by the users of the root directory, not of the host
+		"/etc/passwd":             fmt.Sprintf("image-user:x:%d:%d::/:/sbin/nologin\n", os.Getuid(), os.Getgid()),
+		"/etc/group":              fmt.Sprintf("image-group:x:%d:\ndocker:x:999:alice,bob\n", os.Getgid()),
+		"/etc/docker/daemon.json": `{"icc": false}`,
+	}
+	for path, content := range files {
+		hostPath := filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath), 0755))
+		assert.NoError(t, os.WriteFile(hostPath, []byte(content), 0644))
+	}
+	assert.NoError(t, os.Symlink("/etc/docker/daemon.json", filepath.Join(root, "/etc/docker/absolute.json")))
+	assert.NoError(t, os.Symlink("../../../../etc/docker/daemon.json", filepath.Join(root, "/etc/docker/relative.json")))
+	// a symbolic link to a parent directory, as /var/run -> /run
+	assert.NoError(t, os.Symlink("/etc/docker", filepath.Join(root, "/etc/docker-link")))
+
+	reporter := &mocks.Reporter{}
+	b, err := NewBuilder(reporter, WithRootDir(root))
+	assert.NoError(t, err)
+	e := b.(*builder)
+	assert.Equal(t, root, e.RootDir())
+
+	t.Run("file", func(t *testing.T) {
+		assert := assert.New(t)
+		for _, path := range []string{"/etc/docker/daemon.json", "/etc/docker/absolute.json", "/etc/docker/relative.json", "/etc/docker-link/daemon.json"} {
+			fileCheck, err := newResourceCheck(e, "rule-id", compliance.Resource{
+				ResourceCommon: compliance.ResourceCommon{
+					File: &compliance.File{
+						Path: path,
+					},
+				},
+				Condition: `file.jq(".icc") == "false" && file.user == "image-user" && file.group == "image-group"`,
+			})
+			assert.NoError(err)
+
+			reports := fileCheck.check(e)
+			assert.NoError(reports[0].Error)
+			assert.True(reports[0].Passed, path)
+			assert.Equal(path, reports[0].Data["file.path"])
+		}
+	})
+
+	t.Run("file glob under a symbolic link", func(t *testing.T) {
+		assert := assert.New(t)
+		fileCheck, err := newResourceCheck(e, "rule-id", compliance.Resource{
+			ResourceCommon: compliance.ResourceCommon{
+				File: &compliance.File{
+					Path: "/etc/docker-link/d*.json",
+				},
+			},
+			Condition: `file.jq(".icc") == "false"`,
+		})
+		assert.NoError(err)
+
+		reports := fileCheck.check(e)
+		assert.Len(reports, 1)
+		assert.True(reports[0].Passed)
+		assert.Equal("/etc/docker-link/daemon.json", reports[0].Data["file.path"])
+	})
+
+	t.Run("group", func(t *testing.T) {
+		assert := assert.New(t)
+		groupCheck, err := newResourceCheck(e, "rule-id", compliance.Resource{
+			ResourceCommon: compliance.ResourceCommon{
+				Group: &compliance.Group{
+					Name: "docker",
+				},
+			},
+			Condition: `"alice" in group.users`,
+		})
+		assert.NoError(err)
+
+		reports := groupCheck.check(e)
+		assert.True(reports[0].Passed)
+	})
+
+	t.Run("live host resources", func(t *testing.T) {
+		assert := assert.New(t)
+		_, err := e.checkFromRule(&compliance.SuiteMeta{}, &compliance.ConditionFallbackRule{
+			RuleCommon: compliance.RuleCommon{
+				ID:    "rule-id",
+				Scope: compliance.RuleScopeList{compliance.DockerScope},
+			},
+			Resources: []compliance.Resource{{
+				ResourceCommon: compliance.ResourceCommon{
+					Process: &compliance.Process{
+						Name: "dockerd",
+					},
+				},
+				Condition: `process.flag("--icc") == "false"`,
+			}},
+		})
+		assert.Equal(ErrRuleDoesNotApply, err)
+
+		check, err := e.checkFromRule(&compliance.SuiteMeta{}, &compliance.ConditionFallbackRule{
+			RuleCommon: compliance.RuleCommon{
+				ID:    "rule-id",
+				Scope: compliance.RuleScopeList{compliance.DockerScope},
+			},
+			Resources: []compliance.Resource{{
+				ResourceCommon: compliance.ResourceCommon{
+					File: &compliance.File{
+						Path: "/etc/docker/daemon.json",
+					},
+				},
+				Condition: `file.permissions == 0644`,
+			}},
+		})
+		assert.NoError(err)
+		assert.NotNil(check)
+	})
+
+	t.Run("not a directory", func(t *testing.T) {
+		_, err := NewBuilder(reporter, WithRootDir(filepath.Join(root, "/etc/passwd")))
+		assert.Error(t, err)
+	})
+}
@@ -39,6 +39,7 @@ type Configuration interface {
 	EtcGroupPath() string
 	NormalizeToHostRoot(path string) string
 	RelativeToHostRoot(path string) string
+	RootDir() string
 	EvaluateFromCache(e eval.Evaluatable) (interface{}, error)
 	IsLeader() bool
 	NodeLabels() map[string]string
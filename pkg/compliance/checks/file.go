@@ -9,11 +9,15 @@
 package checks
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"os/user"
+	"path/filepath"
 	"strconv"
 	"syscall"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
 )
 
 func getFileStatt(fi os.FileInfo) (*syscall.Stat_t, error) {
@@ -24,26 +28,56 @@ func getFileStatt(fi os.FileInfo) (*syscall.Stat_t, error) {
 	return statt, nil
 }
 
-func getFileUser(fi os.FileInfo) (string, error) {
+func getFileUser(e env.Env, fi os.FileInfo) (string, error) {
 	statt, err := getFileStatt(fi)
 	if err != nil {
 		return "", nil
 	}
 	u := strconv.Itoa(int(statt.Uid))
-	if user, err := user.LookupId(u); err == nil {
+	if rootDir := e.RootDir(); rootDir != "" {
+		if name, ok := lookupNameByID(filepath.Join(rootDir, "/etc/passwd"), u); ok {
+			u = name
+		}
+	} else if user, err := user.LookupId(u); err == nil {
 		u = user.Username
 	}
 	return u, nil
 }
 
-func getFileGroup(fi os.FileInfo) (string, error) {
+func getFileGroup(e env.Env, fi os.FileInfo) (string, error) {
 	statt, err := getFileStatt(fi)
 	if err != nil {
 		return "", nil
 	}
 	g := strconv.Itoa(int(statt.Gid))
-	if group, err := user.LookupGroupId(g); err == nil {
+	if e.RootDir() != "" {
+		if name, ok := lookupNameByID(e.EtcGroupPath(), g); ok {
+			g = name
+		}
+	} else if group, err := user.LookupGroupId(g); err == nil {
 		g = group.Name
 	}
 	return g, nil
 }
+
+// lookupNameByID looks up the name of a user or a group by its ID in a passwd or group file,
+// the files of a root directory being used instead of the users and groups of the host
+func lookupNameByID(path string, id string) (string, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false
+	}
+	defer f.Close()
+
+	var name string
+	_ = readEtcGroup(f, func(line []byte) (bool, error) {
+		// name:password:ID:...
+		fields := bytes.SplitN(line, []byte(":"), 4)
+		if len(fields) < 3 || string(fields[2]) != id {
+			return false, nil
+		}
+		name = string(fields[0])
+		return true, nil
+	})
+	return name, name != ""
+}
@@ -9,7 +9,6 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance"
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
@@ -45,17 +44,22 @@ func resolveFile(_ context.Context, e env.Env, ruleID string, res compliance.Res
 	}
 
 	initialGlob := path
-	paths, err := filepath.Glob(e.NormalizeToHostRoot(path))
+	paths, err := globUnderHostRoot(e, path)
 	if err != nil {
 		return nil, err
 	}
 
 	var instances []resolvedInstance
 
-	for _, path := range paths {
-		// Re-computing relative after glob filtering
-		relPath := e.RelativeToHostRoot(path)
-		fi, err := os.Stat(path)
+	for _, relPath := range paths {
+		path := e.NormalizeToHostRoot(relPath)
+		realPath, err := resolvePathUnderHostRoot(e, relPath)
+		if err != nil {
+			log.Debugf("%s: file check failed to resolve %s [%s]: %v", ruleID, path, relPath, err)
+			continue
+		}
+
+		fi, err := os.Stat(realPath)
 		if err != nil {
 			// This is not a failure unless we don't have any paths to act on
 			log.Debugf("%s: file check failed to stat %s [%s]", ruleID, path, relPath)
@@ -75,7 +79,7 @@ func resolveFile(_ context.Context, e env.Env, ruleID string, res compliance.Res
 			"permissions": filePermissions,
 		}
 
-		content, err := readContent(path, fileContentParser)
+		content, err := readContent(realPath, fileContentParser)
 		if err == nil {
 			vars[compliance.FileFieldContent] = content
 			regoInput["content"] = content
@@ -83,22 +87,22 @@ func resolveFile(_ context.Context, e env.Env, ruleID string, res compliance.Res
 			log.Errorf("error reading file: %v", err)
 		}
 
-		user, err := getFileUser(fi)
+		user, err := getFileUser(e, fi)
 		if err == nil {
 			vars[compliance.FileFieldUser] = user
 			regoInput["user"] = user
 		}
 
-		group, err := getFileGroup(fi)
+		group, err := getFileGroup(e, fi)
 		if err == nil {
 			vars[compliance.FileFieldGroup] = group
 			regoInput["group"] = group
 		}
 
 		functions := eval.FunctionMap{
-			compliance.FileFuncJQ:     fileJQ(path),
-			compliance.FileFuncYAML:   fileYAML(path),
-			compliance.FileFuncRegexp: fileRegexp(path),
+			compliance.FileFuncJQ:     fileJQ(realPath),
+			compliance.FileFuncYAML:   fileYAML(realPath),
+			compliance.FileFuncRegexp: fileRegexp(realPath),
 		}
 
 		instance := eval.NewInstance(vars, functions, regoInput)
@@ -32,8 +32,7 @@ func TestFileCheck(t *testing.T) {
 	normalizePath := func(t *testing.T, env *mocks.Env, file *compliance.File) {
 		t.Helper()
 		env.On("MaxEventsPerRun").Return(30).Maybe()
-		env.On("NormalizeToHostRoot", file.Path).Return(file.Path)
-		env.On("RelativeToHostRoot", file.Path).Return(file.Path)
+		mockHostRootPaths(env, nil)
 	}
 
 	cleanUpDirs := make([]string, 0)
@@ -77,8 +76,7 @@ func TestFileCheck(t *testing.T) {
 				_, filePaths := createTempFiles(t, 1)
 
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return(filePaths[0])
-				env.On("RelativeToHostRoot", filePaths[0]).Return(file.Path)
+				mockHostRootPaths(env, map[string]string{file.Path: filePaths[0]})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -99,12 +97,8 @@ func TestFileCheck(t *testing.T) {
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
 
-				tempDir, filePaths := createTempFiles(t, 2)
-				for _, filePath := range filePaths {
-					env.On("RelativeToHostRoot", filePath).Return(path.Join("/etc/", path.Base(filePath)))
-				}
-
-				env.On("NormalizeToHostRoot", file.Path).Return(path.Join(tempDir, "/*.dat"))
+				tempDir, _ := createTempFiles(t, 2)
+				mockHostRootPaths(env, map[string]string{"/etc": tempDir})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -142,8 +136,7 @@ func TestFileCheck(t *testing.T) {
 			},
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/file/daemon.json")
-				env.On("RelativeToHostRoot", "./testdata/file/daemon.json").Return(file.Path)
+				mockHostRootPaths(env, map[string]string{"/etc/docker": "./testdata/file"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -164,8 +157,7 @@ func TestFileCheck(t *testing.T) {
 			},
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/file/daemon.json")
-				env.On("RelativeToHostRoot", "./testdata/file/daemon.json").Return(file.Path)
+				mockHostRootPaths(env, map[string]string{"/etc/docker": "./testdata/file"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.False(report.Passed)
@@ -188,8 +180,7 @@ func TestFileCheck(t *testing.T) {
 				path := "/etc/docker/daemon.json"
 				env.On("MaxEventsPerRun").Return(30).Maybe()
 				env.On("EvaluateFromCache", mock.Anything).Return(path, nil)
-				env.On("NormalizeToHostRoot", path).Return("./testdata/file/daemon.json")
-				env.On("RelativeToHostRoot", "./testdata/file/daemon.json").Return(path)
+				mockHostRootPaths(env, map[string]string{"/etc/docker": "./testdata/file"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -258,8 +249,7 @@ func TestFileCheck(t *testing.T) {
 			},
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/file/daemon.json")
-				env.On("RelativeToHostRoot", "./testdata/file/daemon.json").Return(file.Path)
+				mockHostRootPaths(env, map[string]string{"/etc/docker": "./testdata/file"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -280,8 +270,7 @@ func TestFileCheck(t *testing.T) {
 			},
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/file/pod.yaml")
-				env.On("RelativeToHostRoot", "./testdata/file/pod.yaml").Return(file.Path)
+				mockHostRootPaths(env, map[string]string{file.Path: "./testdata/file/pod.yaml"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -302,8 +291,7 @@ func TestFileCheck(t *testing.T) {
 			},
 			setup: func(t *testing.T, env *mocks.Env, file *compliance.File) {
 				env.On("MaxEventsPerRun").Return(30).Maybe()
-				env.On("NormalizeToHostRoot", file.Path).Return("./testdata/file/mounts")
-				env.On("RelativeToHostRoot", "./testdata/file/mounts").Return(file.Path)
+				mockHostRootPaths(env, map[string]string{file.Path: "./testdata/file/mounts"})
 			},
 			validate: func(t *testing.T, file *compliance.File, report *compliance.Report) {
 				assert.True(report.Passed)
@@ -318,6 +306,7 @@ func TestFileCheck(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			env := &mocks.Env{}
 			defer env.AssertExpectations(t)
+			env.On("RootDir").Return("").Maybe()
 
 			if test.setup != nil {
 				test.setup(t, env, test.resource.File)
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
@@ -41,6 +42,117 @@ func (m pathMapper) relativeToHostRoot(path string) string {
 	return path
 }
 
+// maxSymlinks is the maximum number of symbolic links followed to resolve a path
+const maxSymlinks = 40
+
+// resolvePathUnderHostRoot returns the real path of a path relative to the host root. The symbolic
+// links of all its components are followed, their targets being resolved under the host root so that
+// the links of a root directory, such as /var/run -> /run, don't point to the files of the host.
+func resolvePathUnderHostRoot(e env.Env, path string) (string, error) {
+	// resolved is the part of the path already resolved, relative to the host root
+	resolved := string(os.PathSeparator)
+	remaining := filepath.Clean(string(os.PathSeparator) + path)
+	links := 0
+
+	for len(remaining) > 0 {
+		var component string
+		remaining = strings.TrimLeft(remaining, string(os.PathSeparator))
+		if i := strings.IndexRune(remaining, os.PathSeparator); i >= 0 {
+			component, remaining = remaining[:i], remaining[i:]
+		} else {
+			component, remaining = remaining, ""
+		}
+
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			// joining to the host root prevents escaping it with ..
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, component)
+		fi, err := os.Lstat(e.NormalizeToHostRoot(next))
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("too many levels of symbolic links: %s", path)
+		}
+
+		target, err := os.Readlink(e.NormalizeToHostRoot(next))
+		if err != nil {
+			return "", err
+		}
+		// the target is resolved again, relative to the host root if absolute or else to the directory of the link
+		if filepath.IsAbs(target) {
+			resolved = string(os.PathSeparator)
+		}
+		remaining = target + string(os.PathSeparator) + remaining
+	}
+
+	return e.NormalizeToHostRoot(resolved), nil
+}
+
+// globUnderHostRoot returns the paths relative to the host root matching a pattern, as filepath.Glob does,
+// the directories of the pattern being resolved under the host root by resolvePathUnderHostRoot. The
+// pattern is returned as is when it has no meta characters.
+func globUnderHostRoot(e env.Env, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	pattern = filepath.Clean(string(os.PathSeparator) + pattern)
+	if !hasGlobMeta(pattern) {
+		return []string{pattern}, nil
+	}
+
+	dir, file := filepath.Split(pattern)
+	dirs, err := globUnderHostRoot(e, filepath.Clean(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []string
+	for _, dir := range dirs {
+		if !hasGlobMeta(file) {
+			matches = append(matches, filepath.Join(dir, file))
+			continue
+		}
+
+		realDir, err := resolvePathUnderHostRoot(e, dir)
+		if err != nil {
+			continue
+		}
+		entries, err := os.ReadDir(realDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if matched, _ := filepath.Match(file, entry.Name()); matched {
+				matches = append(matches, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+	return matches, nil
+}
+
+// hasGlobMeta returns whether a path holds any of the meta characters recognized by filepath.Match
+func hasGlobMeta(path string) bool {
+	magicChars := `*?[`
+	if runtime.GOOS != "windows" {
+		magicChars = `*?[\`
+	}
+	return strings.ContainsAny(path, magicChars)
+}
+
 func resolvePath(e env.Env, path string) (string, error) {
 	pathExpr, err := eval.Cache.ParsePath(path)
 	if err != nil {
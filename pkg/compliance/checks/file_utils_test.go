@@ -9,6 +9,8 @@
 package checks
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	assert "github.com/stretchr/testify/require"
@@ -44,3 +46,41 @@ func TestMapperRelative(t *testing.T) {
 		})
 	}
 }
+
+func TestResolvePathUnderHostRoot(t *testing.T) {
+	assert := assert.New(t)
+
+	env, root := newHostRootEnv(t, map[string]string{
+		"/run/docker.sock":        "",
+		"/etc/docker/daemon.json": "{}",
+	})
+	links := map[string]string{
+		"/var/run":           "/run",
+		"/etc/docker/escape": "../../../../..",
+		"/etc/loop":          "/etc/loop",
+	}
+	for link, target := range links {
+		assert.NoError(os.MkdirAll(filepath.Join(root, filepath.Dir(link)), 0755))
+		assert.NoError(os.Symlink(target, filepath.Join(root, link)))
+	}
+
+	// the parent directory is resolved under the host root, not to the /run of the host
+	path, err := resolvePathUnderHostRoot(env, "/var/run/docker.sock")
+	assert.NoError(err)
+	assert.Equal(filepath.Join(root, "/run/docker.sock"), path)
+
+	// .. can't escape the host root
+	path, err = resolvePathUnderHostRoot(env, "/etc/docker/escape/etc/docker/daemon.json")
+	assert.NoError(err)
+	assert.Equal(filepath.Join(root, "/etc/docker/daemon.json"), path)
+
+	_, err = resolvePathUnderHostRoot(env, "/etc/loop/file")
+	assert.Error(err)
+
+	_, err = resolvePathUnderHostRoot(env, "/var/run/unknown.sock")
+	assert.True(os.IsNotExist(err))
+
+	matches, err := globUnderHostRoot(env, "/var/run/*.sock")
+	assert.NoError(err)
+	assert.Equal([]string{"/var/run/docker.sock"}, matches)
+}
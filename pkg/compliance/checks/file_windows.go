@@ -11,12 +11,14 @@ package checks
 import (
 	"errors"
 	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
 )
 
-func getFileUser(fi os.FileInfo) (string, error) {
+func getFileUser(e env.Env, fi os.FileInfo) (string, error) {
 	return "", errors.New("retrieving file user not supported in windows")
 }
 
-func getFileGroup(fi os.FileInfo) (string, error) {
+func getFileGroup(e env.Env, fi os.FileInfo) (string, error) {
 	return "", errors.New("retrieving file group not supported in windows")
 }
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/mocks"
+
+	"github.com/stretchr/testify/mock"
+	assert "github.com/stretchr/testify/require"
+)
+
+// newHostRootEnv writes files under a temporary host root and returns an env resolving the paths under it
+func newHostRootEnv(t *testing.T, files map[string]string) (*mocks.Env, string) {
+	root := t.TempDir()
+	for path, content := range files {
+		hostPath := filepath.Join(root, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(hostPath), 0755))
+		assert.NoError(t, os.WriteFile(hostPath, []byte(content), 0644))
+	}
+
+	env := &mocks.Env{}
+	env.On("NormalizeToHostRoot", mock.AnythingOfType("string")).Return(func(path string) string {
+		return filepath.Join(root, path)
+	})
+	env.On("RootDir").Return("").Maybe()
+	return env, root
+}
+
+// mockHostRootPaths makes env map the host paths under the given ones to their real paths, the other paths
+// being unchanged
+func mockHostRootPaths(env *mocks.Env, paths map[string]string) {
+	env.On("NormalizeToHostRoot", mock.AnythingOfType("string")).Return(func(path string) string {
+		for hostPath, realPath := range paths {
+			if path == hostPath || strings.HasPrefix(path, hostPath+"/") {
+				return realPath + strings.TrimPrefix(path, hostPath)
+			}
+		}
+		return path
+	})
+}
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const procModulesPath = "/proc/modules"
+
+var kernelModuleReportedFields = []string{
+	compliance.KernelModuleFieldName,
+	compliance.KernelModuleFieldLoaded,
+	compliance.KernelModuleFieldBlacklisted,
+	compliance.KernelModuleFieldDisabled,
+}
+
+// modprobeConfigPaths lists the directories of the modprobe configuration, by decreasing precedence
+var modprobeConfigPaths = []string{
+	"/etc/modprobe.d",
+	"/run/modprobe.d",
+	"/usr/local/lib/modprobe.d",
+	"/usr/lib/modprobe.d",
+	"/lib/modprobe.d",
+}
+
+// modprobeConfig holds the modules blacklisted, and the modules whose loading is replaced by a
+// command doing nothing, such as /bin/true, to disable them
+type modprobeConfig struct {
+	blacklisted map[string]bool
+	disabled    map[string]bool
+}
+
+func resolveKernelModule(_ context.Context, e env.Env, id string, res compliance.ResourceCommon, rego bool) (resolved, error) {
+	if res.KernelModule == nil {
+		return nil, fmt.Errorf("%s: expecting kernel module resource in kernel module check", id)
+	}
+
+	name := normalizeKernelModuleName(res.KernelModule.Name)
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%s: kernel module resource is missing name", id)
+	}
+
+	log.Debugf("%s: reading the state of kernel module %s", id, name)
+
+	loaded, err := isKernelModuleLoaded(e, name)
+	if err != nil {
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	config, err := readModprobeConfig(e)
+	if err != nil {
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	blacklisted, disabled := config.blacklisted[name], config.disabled[name]
+
+	instance := eval.NewInstance(
+		eval.VarMap{
+			compliance.KernelModuleFieldName:        name,
+			compliance.KernelModuleFieldLoaded:      loaded,
+			compliance.KernelModuleFieldBlacklisted: blacklisted,
+			compliance.KernelModuleFieldDisabled:    disabled,
+		},
+		nil,
+		eval.RegoInputMap{
+			"name":        name,
+			"loaded":      loaded,
+			"blacklisted": blacklisted,
+			"disabled":    disabled,
+		},
+	)
+
+	return newResolvedInstance(instance, name, "kernelModule"), nil
+}
+
+// normalizeKernelModuleName returns the name of a module as listed in /proc/modules,
+// dashes and underscores being interchangeable in module names
+func normalizeKernelModuleName(name string) string {
+	return strings.ReplaceAll(name, "-", "_")
+}
+
+func isKernelModuleLoaded(e env.Env, name string) (bool, error) {
+	f, err := os.Open(e.NormalizeToHostRoot(procModulesPath))
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if fields := strings.Fields(scanner.Text()); len(fields) > 0 && fields[0] == name {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// readModprobeConfig reads the modprobe configuration files in the lexicographic order of their names,
+// a file overrides the files of the same name in the directories of lower precedence
+func readModprobeConfig(e env.Env) (*modprobeConfig, error) {
+	files := make(map[string]string)
+	for _, dir := range modprobeConfigPaths {
+		matches, err := filepath.Glob(e.NormalizeToHostRoot(filepath.Join(dir, "*.conf")))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if _, exists := files[filepath.Base(match)]; !exists {
+				files[filepath.Base(match)] = match
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	config := &modprobeConfig{
+		blacklisted: make(map[string]bool),
+		disabled:    make(map[string]bool),
+	}
+	for _, name := range names {
+		if err := config.readFile(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
+
+func (c *modprobeConfig) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return c.read(f)
+}
+
+func (c *modprobeConfig) read(r io.Reader) error {
+	var line string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line += strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			// a continued line is joined to the next one with a space
+			line = strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+
+		fields := strings.Fields(line)
+		line = ""
+
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		name := normalizeKernelModuleName(fields[1])
+		switch fields[0] {
+		case "blacklist":
+			c.blacklisted[name] = true
+		case "install":
+			// as with modprobe, the first install command of a module wins
+			if _, exists := c.disabled[name]; !exists {
+				c.disabled[name] = len(fields) >= 3 && isNoopCommand(fields[2])
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// isNoopCommand returns whether a command does nothing, as the install commands used to disable a module
+func isNoopCommand(command string) bool {
+	switch filepath.Base(command) {
+	case "true", "false":
+		return true
+	default:
+		return false
+	}
+}
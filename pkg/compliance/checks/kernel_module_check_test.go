@@ -0,0 +1,120 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+var kernelModuleFiles = map[string]string{
+	"/proc/modules": `usb_storage 77824 0 - Live 0x0000000000000000
+overlay 151552 0 - Live 0x0000000000000000
+`,
+	"/etc/modprobe.d/CIS.conf": `# disable unused filesystems
+install cramfs /bin/true
+install udf /bin/false
+blacklist usb-storage
+install dccp \
+  /bin/true
+`,
+	"/lib/modprobe.d/CIS.conf": `install squashfs /bin/true
+`,
+	"/lib/modprobe.d/fs.conf": `install udf /sbin/modprobe --ignore-install udf
+`,
+}
+
+func TestKernelModuleCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		module    string
+		condition string
+
+		expectPassed      bool
+		expectName        string
+		expectLoaded      bool
+		expectBlacklisted bool
+		expectDisabled    bool
+	}{
+		{
+			name:           "disabled",
+			module:         "cramfs",
+			condition:      `!kernelModule.loaded && kernelModule.disabled`,
+			expectPassed:   true,
+			expectName:     "cramfs",
+			expectDisabled: true,
+		},
+		{
+			name:              "loaded and blacklisted",
+			module:            "usb-storage",
+			condition:         `!kernelModule.loaded`,
+			expectPassed:      false,
+			expectName:        "usb_storage",
+			expectLoaded:      true,
+			expectBlacklisted: true,
+		},
+		{
+			name:         "overridden configuration file",
+			module:       "squashfs",
+			condition:    `kernelModule.disabled`,
+			expectPassed: false,
+			expectName:   "squashfs",
+		},
+		{
+			name:           "first install command",
+			module:         "udf",
+			condition:      `kernelModule.disabled`,
+			expectPassed:   true,
+			expectName:     "udf",
+			expectDisabled: true,
+		},
+		{
+			name:           "continued line",
+			module:         "dccp",
+			condition:      `kernelModule.disabled`,
+			expectPassed:   true,
+			expectName:     "dccp",
+			expectDisabled: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			env, _ := newHostRootEnv(t, kernelModuleFiles)
+
+			kernelModuleCheck, err := newResourceCheck(env, "rule-id", compliance.Resource{
+				ResourceCommon: compliance.ResourceCommon{
+					KernelModule: &compliance.KernelModule{
+						Name: test.module,
+					},
+				},
+				Condition: test.condition,
+			})
+			assert.NoError(err)
+
+			reports := kernelModuleCheck.check(env)
+			assert.Equal(&compliance.Report{
+				Passed: test.expectPassed,
+				Data: event.Data{
+					"kernelModule.name":        test.expectName,
+					"kernelModule.loaded":      test.expectLoaded,
+					"kernelModule.blacklisted": test.expectBlacklisted,
+					"kernelModule.disabled":    test.expectDisabled,
+				},
+				Resource: compliance.ReportResource{
+					ID:   test.expectName,
+					Type: "kernelModule",
+				},
+			}, reports[0])
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/packages"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/util/cache"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const packagesCacheKey string = "compliance-packages"
+
+var packageReportedFields = []string{
+	compliance.PackageFieldName,
+	compliance.PackageFieldInstalled,
+	compliance.PackageFieldVersion,
+	compliance.PackageFieldArch,
+}
+
+// ErrPackageDatabaseNotFound is returned when neither the dpkg nor the rpm database can be found
+var ErrPackageDatabaseNotFound = errors.New("package database not found")
+
+type packageDatabase struct {
+	path string
+	list func(path string) ([]packages.Package, error)
+}
+
+// packageDatabases lists the package databases, the first one found is used
+var packageDatabases = []packageDatabase{
+	{path: "/var/lib/dpkg/status", list: listDpkgPackages},
+	{path: "/var/lib/rpm/rpmdb.sqlite", list: packages.ListRPM},
+	{path: "/var/lib/rpm/Packages", list: packages.ListRPM},
+	{path: "/usr/lib/sysimage/rpm/rpmdb.sqlite", list: packages.ListRPM},
+	{path: "/usr/lib/sysimage/rpm/Packages", list: packages.ListRPM},
+}
+
+func resolvePackage(_ context.Context, e env.Env, id string, res compliance.ResourceCommon, rego bool) (resolved, error) {
+	if res.Package == nil {
+		return nil, fmt.Errorf("%s: expecting package resource in package check", id)
+	}
+
+	name := res.Package.Name
+	if len(name) == 0 {
+		return nil, fmt.Errorf("%s: package resource is missing name", id)
+	}
+
+	installedPackages, err := getInstalledPackages(e)
+	if err != nil {
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	// several versions of a package may be installed, such as the kernel, or
+	// several architectures with multiarch
+	var instances []resolvedInstance
+	for _, pkg := range installedPackages {
+		if pkg.Name == name {
+			instances = append(instances, newPackageInstance(pkg, true))
+		}
+	}
+
+	switch len(instances) {
+	case 0:
+		return newPackageInstance(packages.Package{Name: name}, false), nil
+	case 1:
+		return instances[0].(*_resolvedInstance), nil
+	default:
+		return newResolvedInstances(instances), nil
+	}
+}
+
+func newPackageInstance(pkg packages.Package, installed bool) *_resolvedInstance {
+	instance := eval.NewInstance(
+		eval.VarMap{
+			compliance.PackageFieldName:      pkg.Name,
+			compliance.PackageFieldInstalled: installed,
+			compliance.PackageFieldVersion:   pkg.Version,
+			compliance.PackageFieldArch:      pkg.Arch,
+		},
+		nil,
+		eval.RegoInputMap{
+			"name":      pkg.Name,
+			"installed": installed,
+			"version":   pkg.Version,
+			"arch":      pkg.Arch,
+		},
+	)
+	return newResolvedInstance(instance, pkg.Name, "package")
+}
+
+// getInstalledPackages returns the packages of the first package database found, the
+// packages are cached as reading the rpm database is expensive
+func getInstalledPackages(e env.Env) ([]packages.Package, error) {
+	for _, db := range packageDatabases {
+		path := e.NormalizeToHostRoot(db.path)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		cacheKey := packagesCacheKey + ":" + path
+		if value, found := cache.Cache.Get(cacheKey); found {
+			return value.([]packages.Package), nil
+		}
+
+		log.Debugf("Updating package cache from %s", path)
+		installedPackages, err := db.list(path)
+		if err != nil {
+			return nil, err
+		}
+
+		cache.Cache.Set(cacheKey, installedPackages, cacheValidity)
+		return installedPackages, nil
+	}
+
+	return nil, ErrPackageDatabaseNotFound
+}
+
+func listDpkgPackages(path string) ([]packages.Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return packages.ListDpkg(f)
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"os"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestPackageCheck(t *testing.T) {
+	dpkgStatus, err := os.ReadFile("./packages/testdata/dpkg-status")
+	assert.NoError(t, err)
+	rpmDB, err := os.ReadFile("./packages/testdata/rpmdb.sqlite")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		files     map[string]string
+		pkg       string
+		condition string
+
+		expectReport *compliance.Report
+	}{
+		{
+			name:      "dpkg installed",
+			files:     map[string]string{"/var/lib/dpkg/status": string(dpkgStatus)},
+			pkg:       "openssh-server",
+			condition: `package.installed && package.version == "1:8.9p1-3ubuntu0.1"`,
+
+			expectReport: &compliance.Report{
+				Passed: true,
+				Data: event.Data{
+					"package.name":      "openssh-server",
+					"package.installed": true,
+					"package.version":   "1:8.9p1-3ubuntu0.1",
+					"package.arch":      "amd64",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "openssh-server",
+					Type: "package",
+				},
+			},
+		},
+		{
+			name:      "dpkg removed",
+			files:     map[string]string{"/var/lib/dpkg/status": string(dpkgStatus)},
+			pkg:       "telnetd",
+			condition: `!package.installed`,
+
+			expectReport: &compliance.Report{
+				Passed: true,
+				Data: event.Data{
+					"package.name":      "telnetd",
+					"package.installed": false,
+					"package.version":   "",
+					"package.arch":      "",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "telnetd",
+					Type: "package",
+				},
+			},
+		},
+		{
+			name:      "rpm installed",
+			files:     map[string]string{"/var/lib/rpm/rpmdb.sqlite": string(rpmDB)},
+			pkg:       "openssl-libs",
+			condition: `!package.installed`,
+
+			expectReport: &compliance.Report{
+				Passed: false,
+				Data: event.Data{
+					"package.name":      "openssl-libs",
+					"package.installed": true,
+					"package.version":   "1:1.1.1k-7.el8_6",
+					"package.arch":      "x86_64",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "openssl-libs",
+					Type: "package",
+				},
+			},
+		},
+		{
+			name:      "no database",
+			pkg:       "openssl-libs",
+			condition: `!package.installed`,
+
+			expectReport: compliance.BuildReportForError(wrapErrorWithID("rule-id", ErrPackageDatabaseNotFound)),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			env, _ := newHostRootEnv(t, test.files)
+
+			packageCheck, err := newResourceCheck(env, "rule-id", compliance.Resource{
+				ResourceCommon: compliance.ResourceCommon{
+					Package: &compliance.Package{
+						Name: test.pkg,
+					},
+				},
+				Condition: test.condition,
+			})
+			assert.NoError(err)
+
+			reports := packageCheck.check(env)
+			assert.Equal(test.expectReport, reports[0])
+		})
+	}
+}
@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Layout of the Berkeley DB hash databases, as used by rpm before the SQLite backend
+const (
+	bdbHashMagic = 0x061561
+
+	bdbMetaSize       = 72
+	bdbPageHeaderSize = 26
+
+	bdbPageTypeHashUnsorted = 2
+	bdbPageTypeOverflow     = 7
+	bdbPageTypeHashMeta     = 8
+	bdbPageTypeHash         = 13
+
+	bdbItemKeyData = 1
+	bdbItemOffPage = 3
+
+	bdbMaxPageSize = 64 * 1024
+)
+
+var errInvalidBerkeleyDB = errors.New("invalid Berkeley DB hash database")
+
+type berkeleyDB struct {
+	r        io.ReaderAt
+	order    binary.ByteOrder
+	pageSize uint32
+	lastPage uint32
+}
+
+// readBerkeleyDBValues returns the values of the records of a Berkeley DB hash database
+func readBerkeleyDBValues(r io.ReaderAt) ([][]byte, error) {
+	meta := make([]byte, bdbMetaSize)
+	if _, err := r.ReadAt(meta, 0); err != nil {
+		return nil, err
+	}
+
+	db := &berkeleyDB{r: r}
+
+	// the database is in the byte order of the host which created it
+	switch {
+	case binary.LittleEndian.Uint32(meta[12:16]) == bdbHashMagic:
+		db.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(meta[12:16]) == bdbHashMagic:
+		db.order = binary.BigEndian
+	default:
+		return nil, errInvalidBerkeleyDB
+	}
+
+	db.pageSize = db.order.Uint32(meta[20:24])
+	db.lastPage = db.order.Uint32(meta[32:36])
+	if encrypted := meta[24] != 0; encrypted {
+		return nil, errors.New("encrypted Berkeley DB databases aren't supported")
+	}
+	if meta[25] != bdbPageTypeHashMeta || db.pageSize < bdbMetaSize || db.pageSize > bdbMaxPageSize {
+		return nil, errInvalidBerkeleyDB
+	}
+
+	var values [][]byte
+	for pgno := uint32(1); pgno <= db.lastPage; pgno++ {
+		page, err := db.readPage(pgno)
+		if err != nil {
+			return nil, err
+		}
+
+		if pageType := page[25]; pageType != bdbPageTypeHash && pageType != bdbPageTypeHashUnsorted {
+			continue
+		}
+
+		pageValues, err := db.hashPageValues(page)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, pageValues...)
+	}
+
+	return values, nil
+}
+
+func (db *berkeleyDB) readPage(pgno uint32) ([]byte, error) {
+	page := make([]byte, db.pageSize)
+	if _, err := db.r.ReadAt(page, int64(pgno)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", pgno, err)
+	}
+	return page, nil
+}
+
+// hashPageValues returns the values of the key/data pairs of a hash page
+func (db *berkeleyDB) hashPageValues(page []byte) ([][]byte, error) {
+	entries := int(db.order.Uint16(page[20:22]))
+	if bdbPageHeaderSize+2*entries > len(page) {
+		return nil, errInvalidBerkeleyDB
+	}
+
+	offsets := make([]int, entries)
+	for i := range offsets {
+		offsets[i] = int(db.order.Uint16(page[bdbPageHeaderSize+2*i:]))
+		if offsets[i] >= len(page) {
+			return nil, errInvalidBerkeleyDB
+		}
+	}
+
+	var values [][]byte
+	for i := 1; i < entries; i += 2 {
+		item := page[offsets[i]:]
+
+		switch item[0] {
+		case bdbItemKeyData:
+			// the items are stored from the end of the page, an item ends where the previous one starts
+			end := offsets[i-1]
+			if end <= offsets[i] {
+				return nil, errInvalidBerkeleyDB
+			}
+			values = append(values, page[offsets[i]+1:end])
+		case bdbItemOffPage:
+			if len(item) < 12 {
+				return nil, errInvalidBerkeleyDB
+			}
+			value, err := db.overflowValue(db.order.Uint32(item[4:8]), db.order.Uint32(item[8:12]))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+	}
+
+	return values, nil
+}
+
+// overflowValue returns a value stored in a chain of overflow pages
+func (db *berkeleyDB) overflowValue(pgno, length uint32) ([]byte, error) {
+	var value []byte
+	for visited := uint32(0); pgno != 0; visited++ {
+		if pgno > db.lastPage || visited > db.lastPage {
+			return nil, errInvalidBerkeleyDB
+		}
+
+		page, err := db.readPage(pgno)
+		if err != nil {
+			return nil, err
+		}
+		if page[25] != bdbPageTypeOverflow {
+			return nil, errInvalidBerkeleyDB
+		}
+
+		// the offset of the free area of an overflow page is the length of its data
+		used := int(db.order.Uint16(page[22:24]))
+		if bdbPageHeaderSize+used > len(page) {
+			return nil, errInvalidBerkeleyDB
+		}
+		value = append(value, page[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+
+		pgno = db.order.Uint32(page[16:20])
+	}
+
+	if uint32(len(value)) < length {
+		return nil, errInvalidBerkeleyDB
+	}
+	return value[:length], nil
+}
@@ -0,0 +1,67 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// ListDpkg returns the packages installed according to a dpkg status file, such as /var/lib/dpkg/status
+func ListDpkg(r io.Reader) ([]Package, error) {
+	var (
+		packages []Package
+		pkg      Package
+		status   string
+	)
+
+	flush := func() {
+		// the status holds the wanted state, an error flag and the package state
+		if fields := strings.Fields(status); len(pkg.Name) > 0 && len(fields) == 3 && fields[2] == "installed" {
+			packages = append(packages, pkg)
+		}
+		pkg, status = Package{}, ""
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case len(strings.TrimSpace(trimmed)) == 0:
+			flush()
+		case trimmed[0] == ' ' || trimmed[0] == '\t':
+			// continuation of a multiline field, such as the description
+		default:
+			parts := strings.SplitN(trimmed, ":", 2)
+			if len(parts) != 2 {
+				break
+			}
+
+			value := strings.TrimSpace(parts[1])
+			switch parts[0] {
+			case "Package":
+				pkg.Name = value
+			case "Version":
+				pkg.Version = value
+			case "Architecture":
+				pkg.Arch = value
+			case "Status":
+				status = value
+			}
+		}
+
+		if err == io.EOF {
+			flush()
+			return packages, nil
+		}
+	}
+}
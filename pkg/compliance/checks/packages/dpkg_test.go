@@ -0,0 +1,29 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDpkg(t *testing.T) {
+	f, err := os.Open("./testdata/dpkg-status")
+	require.NoError(t, err)
+	defer f.Close()
+
+	packages, err := ListDpkg(f)
+	require.NoError(t, err)
+
+	// telnetd was removed, only its configuration files remain
+	assert.Equal(t, []Package{
+		{Name: "openssh-server", Version: "1:8.9p1-3ubuntu0.1", Arch: "amd64"},
+		{Name: "libc6", Version: "2.35-0ubuntu3.1", Arch: "amd64"},
+	}, packages)
+}
@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package packages reads the installed packages from the dpkg and rpm databases,
+// without relying on the package managers.
+package packages
+
+// Package describes an installed package
+type Package struct {
+	Name    string
+	Version string
+	Arch    string
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Tags and types of the rpm header entries
+const (
+	rpmTagName    = 1000
+	rpmTagVersion = 1001
+	rpmTagRelease = 1002
+	rpmTagEpoch   = 1003
+	rpmTagArch    = 1022
+
+	rpmTypeInt32      = 4
+	rpmTypeString     = 6
+	rpmTypeI18NString = 9
+
+	rpmHeaderEntrySize  = 16
+	rpmHeaderMaxEntries = 0xffff
+	rpmHeaderMaxData    = 0x0fffffff
+)
+
+var sqliteMagic = []byte("SQLite format 3\x00")
+
+// errInvalidRPMHeader is returned when a package of the rpm database can't be parsed
+var errInvalidRPMHeader = errors.New("invalid rpm header")
+
+// ListRPM returns the packages installed according to a rpm database, either in the
+// Berkeley DB format, such as /var/lib/rpm/Packages, or in the SQLite format, such as
+// /var/lib/rpm/rpmdb.sqlite. The transactions committed to the write-ahead log of a SQLite
+// database, <path>-wal, are applied. The ndb format isn't supported.
+func ListRPM(path string) ([]Package, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	magic := make([]byte, len(sqliteMagic))
+	if _, err := f.ReadAt(magic, 0); err != nil {
+		return nil, fmt.Errorf("failed to read rpm database %s: %w", path, err)
+	}
+
+	var blobs [][]byte
+	if bytes.Equal(magic, sqliteMagic) {
+		var wal *os.File
+		wal, err = os.Open(path + "-wal")
+		if err == nil {
+			defer wal.Close()
+			blobs, err = readSQLiteRPMHeaders(f, wal)
+		} else if os.IsNotExist(err) {
+			blobs, err = readSQLiteRPMHeaders(f, nil)
+		}
+	} else {
+		blobs, err = readBerkeleyDBValues(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rpm database %s: %w", path, err)
+	}
+
+	packages := make([]Package, 0, len(blobs))
+	for _, blob := range blobs {
+		pkg, err := parseRPMHeader(blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rpm database %s: %w", path, err)
+		}
+		// the Berkeley DB holds records which aren't packages, such as the next package index
+		if pkg != nil {
+			packages = append(packages, *pkg)
+		}
+	}
+	return packages, nil
+}
+
+// parseRPMHeader returns the package described by a rpm header, as stored in the database,
+// without lead nor header magic. It returns nil when the header doesn't describe a package.
+func parseRPMHeader(blob []byte) (*Package, error) {
+	if len(blob) < 8 {
+		return nil, nil
+	}
+
+	entries := binary.BigEndian.Uint32(blob[0:4])
+	dataLen := binary.BigEndian.Uint32(blob[4:8])
+	if entries > rpmHeaderMaxEntries || dataLen > rpmHeaderMaxData {
+		return nil, errInvalidRPMHeader
+	}
+
+	dataStart := 8 + int(entries)*rpmHeaderEntrySize
+	if len(blob) < dataStart+int(dataLen) {
+		return nil, errInvalidRPMHeader
+	}
+	data := blob[dataStart : dataStart+int(dataLen)]
+
+	var (
+		pkg              Package
+		epoch            string
+		version, release string
+	)
+
+	for i := 0; i < int(entries); i++ {
+		entry := blob[8+i*rpmHeaderEntrySize:]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		kind := binary.BigEndian.Uint32(entry[4:8])
+		offset := binary.BigEndian.Uint32(entry[8:12])
+		if int(offset) >= len(data) {
+			continue
+		}
+		value := data[offset:]
+
+		var str string
+		switch kind {
+		case rpmTypeString, rpmTypeI18NString:
+			end := bytes.IndexByte(value, 0)
+			if end < 0 {
+				return nil, errInvalidRPMHeader
+			}
+			str = string(value[:end])
+		case rpmTypeInt32:
+			if len(value) < 4 {
+				return nil, errInvalidRPMHeader
+			}
+			str = strconv.FormatUint(uint64(binary.BigEndian.Uint32(value[:4])), 10)
+		default:
+			continue
+		}
+
+		switch tag {
+		case rpmTagName:
+			pkg.Name = str
+		case rpmTagVersion:
+			version = str
+		case rpmTagRelease:
+			release = str
+		case rpmTagEpoch:
+			epoch = str
+		case rpmTagArch:
+			pkg.Arch = str
+		}
+	}
+
+	if len(pkg.Name) == 0 {
+		return nil, nil
+	}
+
+	// as printed by rpm -q --queryformat '%{EPOCH}:%{VERSION}-%{RELEASE}', without an unset epoch
+	pkg.Version = version
+	if len(release) > 0 {
+		pkg.Version += "-" + release
+	}
+	if len(epoch) > 0 {
+		pkg.Version = epoch + ":" + pkg.Version
+	}
+
+	return &pkg, nil
+}
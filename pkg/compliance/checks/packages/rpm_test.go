@@ -0,0 +1,266 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func beUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, v)
+	return b
+}
+
+func leUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, v)
+	return b
+}
+
+type rpmTag struct {
+	tag   uint32
+	kind  uint32
+	value interface{}
+}
+
+// buildRPMHeader returns a rpm header as stored in the rpm databases
+func buildRPMHeader(tags ...rpmTag) []byte {
+	var entries, data []byte
+	for _, tag := range tags {
+		offset := len(data)
+		switch value := tag.value.(type) {
+		case string:
+			data = append(data, value...)
+			data = append(data, 0)
+		case uint32:
+			data = append(data, beUint32(value)...)
+		}
+		entries = append(entries, beUint32(tag.tag)...)
+		entries = append(entries, beUint32(tag.kind)...)
+		entries = append(entries, beUint32(uint32(offset))...)
+		entries = append(entries, beUint32(1)...)
+	}
+
+	header := append(beUint32(uint32(len(tags))), beUint32(uint32(len(data)))...)
+	return append(append(header, entries...), data...)
+}
+
+func TestParseRPMHeader(t *testing.T) {
+	pkg, err := parseRPMHeader(buildRPMHeader(
+		rpmTag{rpmTagName, rpmTypeString, "openssl-libs"},
+		rpmTag{rpmTagVersion, rpmTypeString, "1.1.1k"},
+		rpmTag{rpmTagRelease, rpmTypeString, "7.el8_6"},
+		rpmTag{rpmTagEpoch, rpmTypeInt32, uint32(1)},
+		rpmTag{rpmTagArch, rpmTypeString, "x86_64"},
+	))
+	require.NoError(t, err)
+	assert.Equal(t, &Package{Name: "openssl-libs", Version: "1:1.1.1k-7.el8_6", Arch: "x86_64"}, pkg)
+
+	pkg, err = parseRPMHeader([]byte{0, 0, 0, 1})
+	assert.NoError(t, err)
+	assert.Nil(t, pkg)
+
+	_, err = parseRPMHeader(buildRPMHeader(rpmTag{rpmTagName, rpmTypeString, "bash"})[:20])
+	assert.Error(t, err)
+}
+
+// berkeleyDBBuilder builds a little-endian Berkeley DB hash database with a single hash page
+type berkeleyDBBuilder struct {
+	pageSize int
+	pages    [][]byte
+	items    [][]byte
+}
+
+func newBerkeleyDBBuilder(pageSize int) *berkeleyDBBuilder {
+	// the metadata page and the hash page
+	return &berkeleyDBBuilder{
+		pageSize: pageSize,
+		pages:    [][]byte{make([]byte, pageSize), make([]byte, pageSize)},
+	}
+}
+
+func (b *berkeleyDBBuilder) addInline(key uint32, value []byte) {
+	b.items = append(b.items,
+		append([]byte{bdbItemKeyData}, leUint32(key)...),
+		append([]byte{bdbItemKeyData}, value...),
+	)
+}
+
+func (b *berkeleyDBBuilder) addOverflow(key uint32, value []byte) {
+	first, length := uint32(len(b.pages)), uint32(len(value))
+	for chunkSize := b.pageSize - bdbPageHeaderSize; len(value) > 0; {
+		chunk := value
+		if len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		value = value[len(chunk):]
+
+		page := make([]byte, b.pageSize)
+		page[25] = bdbPageTypeOverflow
+		binary.LittleEndian.PutUint16(page[22:24], uint16(len(chunk)))
+		if len(value) > 0 {
+			binary.LittleEndian.PutUint32(page[16:20], uint32(len(b.pages)+1))
+		}
+		copy(page[bdbPageHeaderSize:], chunk)
+		b.pages = append(b.pages, page)
+	}
+
+	item := append([]byte{bdbItemOffPage, 0, 0, 0}, leUint32(first)...)
+	item = append(item, leUint32(length)...)
+	b.items = append(b.items, append([]byte{bdbItemKeyData}, leUint32(key)...), item)
+}
+
+func (b *berkeleyDBBuilder) build() []byte {
+	meta := b.pages[0]
+	binary.LittleEndian.PutUint32(meta[12:16], bdbHashMagic)
+	binary.LittleEndian.PutUint32(meta[20:24], uint32(b.pageSize))
+	meta[25] = bdbPageTypeHashMeta
+	binary.LittleEndian.PutUint32(meta[32:36], uint32(len(b.pages)-1))
+
+	// the items are stored from the end of the page
+	hash := b.pages[1]
+	hash[25] = bdbPageTypeHash
+	binary.LittleEndian.PutUint16(hash[20:22], uint16(len(b.items)))
+	offset := b.pageSize
+	for i, item := range b.items {
+		offset -= len(item)
+		copy(hash[offset:], item)
+		binary.LittleEndian.PutUint16(hash[bdbPageHeaderSize+2*i:], uint16(offset))
+	}
+	binary.LittleEndian.PutUint16(hash[22:24], uint16(offset))
+
+	var db []byte
+	for _, page := range b.pages {
+		db = append(db, page...)
+	}
+	return db
+}
+
+func TestListRPMBerkeleyDB(t *testing.T) {
+	bash := buildRPMHeader(
+		rpmTag{rpmTagName, rpmTypeString, "bash"},
+		rpmTag{rpmTagVersion, rpmTypeString, "4.2.46"},
+		rpmTag{rpmTagRelease, rpmTypeString, "35.el7_9"},
+		rpmTag{rpmTagArch, rpmTypeString, "x86_64"},
+		rpmTag{1004, rpmTypeI18NString, strings.Repeat("The GNU Bourne Again shell", 100)},
+	)
+
+	builder := newBerkeleyDBBuilder(512)
+	// the record 0 holds the index of the next package
+	builder.addInline(0, []byte{3, 0, 0, 0})
+	builder.addInline(1, buildRPMHeader(
+		rpmTag{rpmTagName, rpmTypeString, "gpg-pubkey"},
+		rpmTag{rpmTagVersion, rpmTypeString, "f4a80eb5"},
+		rpmTag{rpmTagRelease, rpmTypeString, "53a7ff4b"},
+	))
+	builder.addOverflow(2, bash)
+
+	path := filepath.Join(t.TempDir(), "Packages")
+	require.NoError(t, os.WriteFile(path, builder.build(), 0644))
+
+	packages, err := ListRPM(path)
+	require.NoError(t, err)
+	assert.Equal(t, []Package{
+		{Name: "gpg-pubkey", Version: "f4a80eb5-53a7ff4b"},
+		{Name: "bash", Version: "4.2.46-35.el7_9", Arch: "x86_64"},
+	}, packages)
+}
+
+func TestListRPMSQLite(t *testing.T) {
+	// the database has interior pages and a package spilled to overflow pages
+	packages, err := ListRPM("./testdata/rpmdb.sqlite")
+	require.NoError(t, err)
+	require.Len(t, packages, 43)
+	assert.Equal(t, []Package{
+		{Name: "openssl-libs", Version: "1:1.1.1k-7.el8_6", Arch: "x86_64"},
+		{Name: "bash", Version: "4.4.20-4.el8_6", Arch: "x86_64"},
+		{Name: "gpg-pubkey", Version: "fd431d51-4ae0493b"},
+	}, packages[:3])
+	assert.Equal(t, Package{Name: "filler39", Version: "1.0.39-1.el8", Arch: "noarch"}, packages[42])
+}
+
+func TestListRPMSQLiteWAL(t *testing.T) {
+	// the write-ahead log deletes gpg-pubkey, adds openssh and updates bash
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rpmdb.sqlite")
+	for _, suffix := range []string{"", "-wal"} {
+		content, err := os.ReadFile("./testdata/rpmdb-wal.sqlite" + suffix)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(path+suffix, content, 0644))
+	}
+
+	expected := []Package{
+		{Name: "openssl-libs", Version: "1:1.1.1k-7.el8_6", Arch: "x86_64"},
+		{Name: "bash", Version: "4.4.20-5.el8_7", Arch: "x86_64"},
+		{Name: "filler00", Version: "1.0.0-1.el8", Arch: "noarch"},
+	}
+
+	packages, err := ListRPM(path)
+	require.NoError(t, err)
+	require.Len(t, packages, 43)
+	assert.Equal(t, expected, packages[:3])
+	assert.Equal(t, Package{Name: "openssh", Version: "8.0p1-13.el8", Arch: "x86_64"}, packages[42])
+
+	// the frames of an uncommitted transaction are ignored
+	wal, err := os.OpenFile(path+"-wal", os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = wal.Write(make([]byte, 24+1024))
+	require.NoError(t, err)
+	require.NoError(t, wal.Close())
+
+	packages, err = ListRPM(path)
+	require.NoError(t, err)
+	require.Len(t, packages, 43)
+	assert.Equal(t, expected, packages[:3])
+
+	// without the write-ahead log, only the checkpointed packages are listed
+	require.NoError(t, os.Remove(path+"-wal"))
+	packages, err = ListRPM(path)
+	require.NoError(t, err)
+	require.Len(t, packages, 43)
+	assert.Equal(t, "gpg-pubkey", packages[2].Name)
+}
+
+func TestListRPMSQLiteSharedPage(t *testing.T) {
+	content, err := os.ReadFile("./testdata/rpmdb.sqlite")
+	require.NoError(t, err)
+
+	// make all the children of the interior root page of the packages point to the same leaf
+	// page, which must not be walked several times
+	db := &sqliteDB{r: bytes.NewReader(content), pageSize: 1024, usableSize: 1024}
+	rootPage, err := db.tableRootPage(rpmSQLiteTable)
+	require.NoError(t, err)
+	page := content[int(rootPage-1)*1024:]
+	require.Equal(t, byte(sqlitePageInteriorTable), page[0])
+	child := binary.BigEndian.Uint32(page[binary.BigEndian.Uint16(page[12:]):])
+	for i := 1; i < int(binary.BigEndian.Uint16(page[3:5])); i++ {
+		binary.BigEndian.PutUint32(page[binary.BigEndian.Uint16(page[12+2*i:]):], child)
+	}
+	binary.BigEndian.PutUint32(page[8:12], child)
+
+	path := filepath.Join(t.TempDir(), "rpmdb.sqlite")
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	_, err = ListRPM(path)
+	assert.ErrorIs(t, err, errInvalidSQLite)
+}
+
+func TestListRPMInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Packages")
+	require.NoError(t, os.WriteFile(path, make([]byte, 4096), 0644))
+
+	_, err := ListRPM(path)
+	assert.Error(t, err)
+}
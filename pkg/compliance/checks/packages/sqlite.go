@@ -0,0 +1,437 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package packages
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Layout of the SQLite databases, see https://www.sqlite.org/fileformat.html
+const (
+	sqliteHeaderSize = 100
+
+	sqlitePageInteriorTable = 0x05
+	sqlitePageLeafTable     = 0x0d
+
+	sqliteMinPageSize = 512
+	sqliteMaxPageSize = 65536
+	sqliteMaxDepth    = 32
+	sqliteMaxPayload  = 1 << 30
+
+	// Layout of the write-ahead logs, see https://www.sqlite.org/fileformat.html#the_write_ahead_log
+	sqliteWALHeaderSize      = 32
+	sqliteWALFrameHeaderSize = 24
+	sqliteWALMagic           = 0x377f0682
+
+	// rpmSQLiteTable is the table of the packages, holding the headers in its second column
+	rpmSQLiteTable = "Packages"
+)
+
+var errInvalidSQLite = errors.New("invalid SQLite database")
+
+type sqliteDB struct {
+	r          io.ReaderAt
+	pageSize   int
+	usableSize int
+
+	// wal is the write-ahead log, and walFrames the offsets in it of the last committed
+	// version of the pages it holds
+	wal       io.ReaderAt
+	walFrames map[uint32]int64
+}
+
+// readSQLiteRPMHeaders returns the headers of the packages of a rpm SQLite database. The
+// transactions committed to the write-ahead log wal, if not nil, but not yet checkpointed
+// to the database are applied.
+func readSQLiteRPMHeaders(r io.ReaderAt, wal io.ReaderAt) ([][]byte, error) {
+	header := make([]byte, sqliteHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	db := &sqliteDB{r: r}
+
+	db.pageSize = int(binary.BigEndian.Uint16(header[16:18]))
+	if db.pageSize == 1 {
+		db.pageSize = sqliteMaxPageSize
+	}
+	if db.pageSize < sqliteMinPageSize || db.pageSize > sqliteMaxPageSize || db.pageSize&(db.pageSize-1) != 0 {
+		return nil, errInvalidSQLite
+	}
+	db.usableSize = db.pageSize - int(header[20])
+
+	if wal != nil {
+		if err := db.readWAL(wal); err != nil {
+			return nil, err
+		}
+	}
+
+	rootPage, err := db.tableRootPage(rpmSQLiteTable)
+	if err != nil {
+		return nil, err
+	}
+
+	var headers [][]byte
+	err = db.walkTable(rootPage, 0, make(map[uint32]bool), func(record []interface{}) error {
+		if len(record) < 2 {
+			return errInvalidSQLite
+		}
+		blob, ok := record[1].([]byte)
+		if !ok {
+			return errInvalidSQLite
+		}
+		headers = append(headers, blob)
+		return nil
+	})
+	return headers, err
+}
+
+// tableRootPage returns the root page of a table, from the schema table stored in the first page
+func (db *sqliteDB) tableRootPage(name string) (uint32, error) {
+	var rootPage uint32
+	err := db.walkTable(1, 0, make(map[uint32]bool), func(record []interface{}) error {
+		if len(record) < 4 || record[0] != "table" || record[1] != name {
+			return nil
+		}
+		if page, ok := record[3].(int64); ok && page > 0 && page <= math.MaxUint32 {
+			rootPage = uint32(page)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if rootPage == 0 {
+		return 0, fmt.Errorf("table %s not found", name)
+	}
+	return rootPage, nil
+}
+
+// readWAL indexes the frames of the transactions committed to a write-ahead log. The
+// frames after the last commit, or whose salt or checksum don't match, are ignored.
+func (db *sqliteDB) readWAL(wal io.ReaderAt) error {
+	header := make([]byte, sqliteWALHeaderSize)
+	if _, err := wal.ReadAt(header, 0); err != nil {
+		// an empty log has no frame
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("failed to read the write-ahead log: %w", err)
+	}
+
+	magic := binary.BigEndian.Uint32(header[0:4])
+	if magic&^1 != sqliteWALMagic {
+		return errInvalidSQLite
+	}
+	// the checksums are computed on big-endian words when the low bit of the magic is set
+	var order binary.ByteOrder = binary.LittleEndian
+	if magic&1 != 0 {
+		order = binary.BigEndian
+	}
+
+	if pageSize := binary.BigEndian.Uint32(header[8:12]); int(pageSize) != db.pageSize {
+		return errInvalidSQLite
+	}
+
+	s0, s1 := sqliteWALChecksum(order, 0, 0, header[:24])
+	if s0 != binary.BigEndian.Uint32(header[24:28]) || s1 != binary.BigEndian.Uint32(header[28:32]) {
+		// the log was never written to since its last reset
+		return nil
+	}
+
+	frames := make(map[uint32]int64)
+	committed := make(map[uint32]int64)
+	frame := make([]byte, sqliteWALFrameHeaderSize+db.pageSize)
+	for offset := int64(sqliteWALHeaderSize); ; offset += int64(len(frame)) {
+		if _, err := wal.ReadAt(frame, offset); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return fmt.Errorf("failed to read the write-ahead log: %w", err)
+		}
+
+		if !bytes.Equal(frame[8:16], header[16:24]) {
+			break
+		}
+		s0, s1 = sqliteWALChecksum(order, s0, s1, frame[:8])
+		s0, s1 = sqliteWALChecksum(order, s0, s1, frame[sqliteWALFrameHeaderSize:])
+		if s0 != binary.BigEndian.Uint32(frame[16:20]) || s1 != binary.BigEndian.Uint32(frame[20:24]) {
+			break
+		}
+
+		pgno := binary.BigEndian.Uint32(frame[0:4])
+		if pgno == 0 {
+			return errInvalidSQLite
+		}
+		frames[pgno] = offset + sqliteWALFrameHeaderSize
+
+		// the frame is the last one of a transaction when it holds the size of the database
+		if binary.BigEndian.Uint32(frame[4:8]) != 0 {
+			for pgno, offset := range frames {
+				committed[pgno] = offset
+			}
+		}
+	}
+
+	if len(committed) > 0 {
+		db.wal = wal
+		db.walFrames = committed
+	}
+	return nil
+}
+
+// sqliteWALChecksum continues the checksum s0, s1 of a write-ahead log over data
+func sqliteWALChecksum(order binary.ByteOrder, s0, s1 uint32, data []byte) (uint32, uint32) {
+	for i := 0; i+8 <= len(data); i += 8 {
+		s0 += order.Uint32(data[i:]) + s1
+		s1 += order.Uint32(data[i+4:]) + s0
+	}
+	return s0, s1
+}
+
+func (db *sqliteDB) readPage(pgno uint32) ([]byte, error) {
+	if pgno == 0 {
+		return nil, errInvalidSQLite
+	}
+	page := make([]byte, db.pageSize)
+	if offset, ok := db.walFrames[pgno]; ok {
+		if _, err := db.wal.ReadAt(page, offset); err != nil {
+			return nil, fmt.Errorf("failed to read page %d from the write-ahead log: %w", pgno, err)
+		}
+		return page, nil
+	}
+	if _, err := db.r.ReadAt(page, int64(pgno-1)*int64(db.pageSize)); err != nil {
+		return nil, fmt.Errorf("failed to read page %d: %w", pgno, err)
+	}
+	return page, nil
+}
+
+// walkTable calls fn with the records of the table b-tree rooted at a page, in the order of their row IDs.
+// The pages already visited in the walk are an error, so that a corrupted database can't loop.
+func (db *sqliteDB) walkTable(pgno uint32, depth int, visited map[uint32]bool, fn func(record []interface{}) error) error {
+	if depth > sqliteMaxDepth || visited[pgno] {
+		return errInvalidSQLite
+	}
+	visited[pgno] = true
+
+	page, err := db.readPage(pgno)
+	if err != nil {
+		return err
+	}
+
+	// the first page starts with the database header
+	header := page
+	if pgno == 1 {
+		header = page[sqliteHeaderSize:]
+	}
+
+	pageType := header[0]
+	cells := int(binary.BigEndian.Uint16(header[3:5]))
+
+	headerSize := 8
+	if pageType == sqlitePageInteriorTable {
+		headerSize = 12
+	} else if pageType != sqlitePageLeafTable {
+		return errInvalidSQLite
+	}
+
+	if headerSize+2*cells > len(header) {
+		return errInvalidSQLite
+	}
+
+	for i := 0; i < cells; i++ {
+		offset := int(binary.BigEndian.Uint16(header[headerSize+2*i:]))
+		if offset >= db.usableSize {
+			return errInvalidSQLite
+		}
+		cell := page[offset:db.usableSize]
+
+		if pageType == sqlitePageInteriorTable {
+			if len(cell) < 4 {
+				return errInvalidSQLite
+			}
+			if err := db.walkTable(binary.BigEndian.Uint32(cell[0:4]), depth+1, visited, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		payload, err := db.leafPayload(cell)
+		if err != nil {
+			return err
+		}
+		record, err := parseSQLiteRecord(payload)
+		if err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	if pageType == sqlitePageInteriorTable {
+		return db.walkTable(binary.BigEndian.Uint32(header[8:12]), depth+1, visited, fn)
+	}
+	return nil
+}
+
+// leafPayload returns the payload of a table leaf cell, including the part spilled to overflow pages
+func (db *sqliteDB) leafPayload(cell []byte) ([]byte, error) {
+	payloadSize, n := sqliteVarint(cell)
+	if n == 0 || payloadSize > sqliteMaxPayload {
+		return nil, errInvalidSQLite
+	}
+	cell = cell[n:]
+
+	// skip the row ID
+	if _, n = sqliteVarint(cell); n == 0 {
+		return nil, errInvalidSQLite
+	}
+	cell = cell[n:]
+
+	size := int(payloadSize)
+	local := db.localPayloadSize(size)
+	if local > len(cell) {
+		return nil, errInvalidSQLite
+	}
+
+	payload := make([]byte, 0, local)
+	payload = append(payload, cell[:local]...)
+	if local == size {
+		return payload, nil
+	}
+
+	if len(cell) < local+4 {
+		return nil, errInvalidSQLite
+	}
+
+	// each overflow page starts with the number of the next one
+	for next := binary.BigEndian.Uint32(cell[local:]); len(payload) < size; {
+		page, err := db.readPage(next)
+		if err != nil {
+			return nil, err
+		}
+
+		content := page[4:db.usableSize]
+		if remaining := size - len(payload); len(content) > remaining {
+			content = content[:remaining]
+		}
+		payload = append(payload, content...)
+		next = binary.BigEndian.Uint32(page[0:4])
+	}
+
+	return payload, nil
+}
+
+// localPayloadSize returns the size of the part of a payload stored in a table leaf cell
+func (db *sqliteDB) localPayloadSize(size int) int {
+	maxLocal := db.usableSize - 35
+	if size <= maxLocal {
+		return size
+	}
+
+	minLocal := (db.usableSize-12)*32/255 - 23
+	local := minLocal + (size-minLocal)%(db.usableSize-4)
+	if local > maxLocal {
+		return minLocal
+	}
+	return local
+}
+
+// parseSQLiteRecord returns the values of a record: nil, int64, float64, string or []byte
+func parseSQLiteRecord(payload []byte) ([]interface{}, error) {
+	headerSize, n := sqliteVarint(payload)
+	if n == 0 || headerSize < uint64(n) || headerSize > uint64(len(payload)) {
+		return nil, errInvalidSQLite
+	}
+
+	var serialTypes []uint64
+	for header := payload[n:headerSize]; len(header) > 0; {
+		serialType, n := sqliteVarint(header)
+		if n == 0 {
+			return nil, errInvalidSQLite
+		}
+		serialTypes = append(serialTypes, serialType)
+		header = header[n:]
+	}
+
+	body := payload[headerSize:]
+	values := make([]interface{}, 0, len(serialTypes))
+	for _, serialType := range serialTypes {
+		var size int
+		switch {
+		case serialType >= 1 && serialType <= 4:
+			size = int(serialType)
+		case serialType == 5:
+			size = 6
+		case serialType == 6, serialType == 7:
+			size = 8
+		case serialType >= 12:
+			if serialType-12 > sqliteMaxPayload {
+				return nil, errInvalidSQLite
+			}
+			size = int(serialType-12) / 2
+		}
+		if size > len(body) {
+			return nil, errInvalidSQLite
+		}
+		data := body[:size]
+		body = body[size:]
+
+		switch {
+		case serialType == 0:
+			values = append(values, nil)
+		case serialType <= 6:
+			values = append(values, sqliteInt(data))
+		case serialType == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(data)))
+		case serialType == 8:
+			values = append(values, int64(0))
+		case serialType == 9:
+			values = append(values, int64(1))
+		case serialType >= 12 && serialType%2 == 0:
+			values = append(values, data)
+		case serialType >= 13:
+			values = append(values, string(data))
+		default:
+			return nil, errInvalidSQLite
+		}
+	}
+
+	return values, nil
+}
+
+// sqliteInt returns the value of a big-endian two's complement integer
+func sqliteInt(data []byte) int64 {
+	var value int64
+	if len(data) > 0 && data[0]&0x80 != 0 {
+		value = -1
+	}
+	for _, b := range data {
+		value = value<<8 | int64(b)
+	}
+	return value
+}
+
+// sqliteVarint decodes a variable-length integer, it returns the number of bytes read or 0 on error
+func sqliteVarint(data []byte) (uint64, int) {
+	var value uint64
+	for i := 0; i < 8 && i < len(data); i++ {
+		value = value<<7 | uint64(data[i]&0x7f)
+		if data[i]&0x80 == 0 {
+			return value, i + 1
+		}
+	}
+	if len(data) < 9 {
+		return 0, 0
+	}
+	return value<<8 | uint64(data[8]), 9
+}
@@ -10,8 +10,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
 	"time"
 
@@ -31,6 +33,10 @@ import (
 const regoEvaluator = "rego"
 const regoEvalTimeout = 20 * time.Second
 
+// regoLibraryDir is the directory, relative to the policy directory, holding the Rego libraries
+// shared by the rules. They are loaded for every rule, which imports them by package (`import data.<package>`).
+const regoLibraryDir = "lib"
+
 type regoCheck struct {
 	evalLock sync.Mutex
 
@@ -56,6 +62,36 @@ func importModule(importPath, parentDir string, required bool) (string, error) {
 	return string(mod), nil
 }
 
+// listRegoLibraries returns the paths, relative to the policy directory, of the Rego libraries
+func listRegoLibraries(policyDir string) ([]string, error) {
+	var libraries []string
+	err := filepath.WalkDir(filepath.Join(policyDir, regoLibraryDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".rego" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(policyDir, path)
+		if err != nil {
+			return err
+		}
+		libraries = append(libraries, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(libraries)
+	return libraries, nil
+}
+
 func computeRuleModulesAndQuery(rule *compliance.RegoRule, meta *compliance.SuiteMeta) ([]func(*rego.Rego), string, error) {
 	options := make([]func(*rego.Rego), 0)
 
@@ -99,6 +135,33 @@ func computeRuleModulesAndQuery(rule *compliance.RegoRule, meta *compliance.Suit
 	}
 	alreadyImported[imp] = true
 
+	// import the libraries of the policy directory
+	if parentDir != "" {
+		libraries, err := listRegoLibraries(parentDir)
+		if err != nil {
+			return nil, "", err
+		}
+
+		for _, imp := range libraries {
+			mod, err := importModule(imp, parentDir, true)
+			if err != nil {
+				return nil, "", err
+			}
+
+			parsed, err := ast.ParseModule(imp, mod)
+			if err != nil {
+				return nil, "", fmt.Errorf("failed to parse rego library %s: %w", imp, err)
+			}
+			if parsed == nil {
+				// empty or comment only file
+				continue
+			}
+
+			options = append(options, rego.ParsedModule(parsed))
+			alreadyImported[imp] = true
+		}
+	}
+
 	// import explicitly required imports
 	for _, imp := range rule.Imports {
 		if imp == "" || alreadyImported[imp] {
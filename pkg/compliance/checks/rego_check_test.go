@@ -6,6 +6,8 @@ package checks
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/compliance"
@@ -24,10 +26,12 @@ type regoFixture struct {
 	findings string
 
 	processes     processes
+	files         map[string]string
+	libraries     map[string]string
 	expectReports []*compliance.Report
 }
 
-func (f *regoFixture) newRegoCheck() (*regoCheck, error) {
+func (f *regoFixture) newRegoCheck(t *testing.T) (*regoCheck, error) {
 	ruleID := "rule-id"
 	rule := &compliance.RegoRule{
 		RuleCommon: compliance.RuleCommon{
@@ -42,7 +46,18 @@ func (f *regoFixture) newRegoCheck() (*regoCheck, error) {
 		inputs: f.inputs,
 	}
 
-	if err := regoCheck.compileRule(rule, "", &compliance.SuiteMeta{}); err != nil {
+	meta := &compliance.SuiteMeta{}
+	if f.libraries != nil {
+		policyDir := t.TempDir()
+		for path, content := range f.libraries {
+			libraryPath := filepath.Join(policyDir, path)
+			assert.NoError(t, os.MkdirAll(filepath.Dir(libraryPath), 0755))
+			assert.NoError(t, os.WriteFile(libraryPath, []byte(content), 0644))
+		}
+		meta.Source = filepath.Join(policyDir, "suite.yaml")
+	}
+
+	if err := regoCheck.compileRule(rule, "", meta); err != nil {
 		return nil, err
 	}
 
@@ -62,6 +77,10 @@ func (f *regoFixture) run(t *testing.T) {
 	}
 
 	env := &mocks.Env{}
+	if f.files != nil {
+		mockSystemctl(t, "auditd.service")
+		env, _ = newHostRootEnv(t, f.files)
+	}
 	env.On("MaxEventsPerRun").Return(30).Maybe()
 	env.On("ProvidedInput", mock.Anything).Return(nil).Once()
 	env.On("Hostname").Return("hostname_test").Once()
@@ -70,7 +89,7 @@ func (f *regoFixture) run(t *testing.T) {
 
 	defer env.AssertExpectations(t)
 
-	regoCheck, err := f.newRegoCheck()
+	regoCheck, err := f.newRegoCheck(t)
 	assert.NoError(err)
 
 	reports := regoCheck.check(env)
@@ -273,6 +292,129 @@ func TestRegoCheck(t *testing.T) {
 			},
 			expectReports: nil,
 		},
+		{
+			name: "host resources case",
+			inputs: []compliance.RegoInput{
+				{
+					ResourceCommon: compliance.ResourceCommon{
+						Systemd: &compliance.SystemdUnit{
+							Unit: "auditd.service",
+						},
+					},
+				},
+				{
+					ResourceCommon: compliance.ResourceCommon{
+						KernelModule: &compliance.KernelModule{
+							Name: "cramfs",
+						},
+					},
+					TagName: "cramfs",
+				},
+				{
+					ResourceCommon: compliance.ResourceCommon{
+						Sysctl: &compliance.Sysctl{
+							Key: "net.ipv4.ip_forward",
+						},
+					},
+				},
+			},
+			module: `
+				package test
+
+				import data.datadog as dd
+
+				default valid = false
+
+				valid {
+					input.systemd.enabled
+					input.systemd.active
+					input.systemd.properties.Service.Restart == "always"
+					input.cramfs.disabled
+					input.sysctl.value == "0"
+				}
+
+				findings[f] {
+					valid
+					f := dd.passed_finding("host", "host", {"systemd.state": input.systemd.state})
+				}
+			`,
+			findings: "data.test.findings",
+			files: map[string]string{
+				"/lib/systemd/system/auditd.service":                         "[Service]\nRestart=always\n[Install]\nWantedBy=multi-user.target\n",
+				"/etc/systemd/system/multi-user.target.wants/auditd.service": "",
+				"/proc/modules":                 "",
+				"/etc/modprobe.d/cramfs.conf":   "install cramfs /bin/true\n",
+				"/proc/sys/net/ipv4/ip_forward": "0\n",
+			},
+			expectReports: []*compliance.Report{
+				{
+					Passed: true,
+					Data: event.Data{
+						"systemd.state": "enabled",
+					},
+					Resource: compliance.ReportResource{
+						ID:   "host",
+						Type: "host",
+					},
+					Evaluator: "rego",
+				},
+			},
+		},
+		{
+			name: "library case",
+			inputs: []compliance.RegoInput{
+				{
+					ResourceCommon: compliance.ResourceCommon{
+						Process: &compliance.Process{
+							Name: "proc1",
+						},
+					},
+					TagName: "process",
+					Type:    "object",
+				},
+			},
+			module: `
+				package test
+
+				import data.datadog as dd
+				import data.lib.process as process
+
+				findings[f] {
+					process.has_flag(input.process, "--path")
+					f := dd.passed_finding("process", "proc1", {"process.name": input.process.name})
+				}
+			`,
+			findings: "data.test.findings",
+			processes: processes{
+				42: {
+					Name:    "proc1",
+					Cmdline: []string{"arg1", "--path=foo"},
+				},
+			},
+			libraries: map[string]string{
+				"lib/process.rego": `
+					package lib.process
+
+					has_flag(p, flag) {
+						p.flags[flag]
+					}
+				`,
+				"lib/README.md": "not a rego module",
+			},
+			expectReports: []*compliance.Report{
+				{
+					Passed: true,
+					Data: event.Data{
+						"process.name": "proc1",
+					},
+					Resource: compliance.ReportResource{
+						ID:   "proc1",
+						Type: "process",
+					},
+					Evaluator: "rego",
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -281,3 +423,22 @@ func TestRegoCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestRegoLibraryError(t *testing.T) {
+	f := &regoFixture{
+		module: `
+			package test
+
+			findings[f] {
+				f := {}
+			}
+		`,
+		libraries: map[string]string{
+			"lib/invalid.rego": "package lib.invalid\n\ninvalid {",
+		},
+	}
+
+	_, err := f.newRegoCheck(t)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse rego library lib/invalid.rego")
+}
@@ -0,0 +1,79 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const procSysPath = "/proc/sys"
+
+var sysctlReportedFields = []string{
+	compliance.SysctlFieldKey,
+	compliance.SysctlFieldValue,
+}
+
+// ErrSysctlNotFound is returned when a kernel parameter cannot be found
+var ErrSysctlNotFound = errors.New("kernel parameter not found")
+
+func resolveSysctl(_ context.Context, e env.Env, id string, res compliance.ResourceCommon, rego bool) (resolved, error) {
+	if res.Sysctl == nil {
+		return nil, fmt.Errorf("%s: expecting sysctl resource in sysctl check", id)
+	}
+
+	key := res.Sysctl.Key
+	if len(key) == 0 || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("%s: invalid kernel parameter `%s`", id, key)
+	}
+
+	path := e.NormalizeToHostRoot(sysctlPath(key))
+
+	log.Debugf("%s: reading kernel parameter %s from %s", id, key, path)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSysctlNotFound
+		}
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	// multiple values are separated by tabulations, as in the output of sysctl
+	value := strings.Join(strings.Fields(string(content)), " ")
+
+	instance := eval.NewInstance(
+		eval.VarMap{
+			compliance.SysctlFieldKey:   key,
+			compliance.SysctlFieldValue: value,
+		},
+		nil,
+		eval.RegoInputMap{
+			"key":   key,
+			"value": value,
+		},
+	)
+
+	return newResolvedInstance(instance, key, "sysctl"), nil
+}
+
+// sysctlPath returns the path of a kernel parameter. As with sysctl, the components of the key
+// are separated by dots, or by slashes when a component contains dots, such as an interface name.
+func sysctlPath(key string) string {
+	if !strings.Contains(key, "/") {
+		key = strings.ReplaceAll(key, ".", "/")
+	}
+	return filepath.Join(procSysPath, key)
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestSysctlCheck(t *testing.T) {
+	files := map[string]string{
+		"/proc/sys/net/ipv4/ip_forward":            "0\n",
+		"/proc/sys/net/ipv4/ip_local_port_range":   "32768\t60999\n",
+		"/proc/sys/net/ipv4/conf/eth0.1/rp_filter": "1\n",
+	}
+
+	tests := []struct {
+		name      string
+		key       string
+		condition string
+
+		expectReport *compliance.Report
+	}{
+		{
+			name:      "passed",
+			key:       "net.ipv4.ip_forward",
+			condition: `sysctl.value == "0"`,
+
+			expectReport: &compliance.Report{
+				Passed: true,
+				Data: event.Data{
+					"sysctl.key":   "net.ipv4.ip_forward",
+					"sysctl.value": "0",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "net.ipv4.ip_forward",
+					Type: "sysctl",
+				},
+			},
+		},
+		{
+			name:      "multiple values",
+			key:       "net.ipv4.ip_local_port_range",
+			condition: `sysctl.value == "1024 65535"`,
+
+			expectReport: &compliance.Report{
+				Passed: false,
+				Data: event.Data{
+					"sysctl.key":   "net.ipv4.ip_local_port_range",
+					"sysctl.value": "32768 60999",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "net.ipv4.ip_local_port_range",
+					Type: "sysctl",
+				},
+			},
+		},
+		{
+			name:      "slash separated key",
+			key:       "net/ipv4/conf/eth0.1/rp_filter",
+			condition: `sysctl.value == "1"`,
+
+			expectReport: &compliance.Report{
+				Passed: true,
+				Data: event.Data{
+					"sysctl.key":   "net/ipv4/conf/eth0.1/rp_filter",
+					"sysctl.value": "1",
+				},
+				Resource: compliance.ReportResource{
+					ID:   "net/ipv4/conf/eth0.1/rp_filter",
+					Type: "sysctl",
+				},
+			},
+		},
+		{
+			name:      "not found",
+			key:       "net.ipv4.unknown",
+			condition: `sysctl.value == "1"`,
+
+			expectReport: compliance.BuildReportForError(ErrSysctlNotFound),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			env, _ := newHostRootEnv(t, files)
+
+			sysctlCheck, err := newResourceCheck(env, "rule-id", compliance.Resource{
+				ResourceCommon: compliance.ResourceCommon{
+					Sysctl: &compliance.Sysctl{
+						Key: test.key,
+					},
+				},
+				Condition: test.condition,
+			})
+			assert.NoError(err)
+
+			reports := sysctlCheck.check(env)
+			assert.Equal(test.expectReport, reports[0])
+		})
+	}
+}
@@ -0,0 +1,350 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks/env"
+	"github.com/DataDog/datadog-agent/pkg/compliance/eval"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var systemdReportedFields = []string{
+	compliance.SystemdFieldUnit,
+	compliance.SystemdFieldPath,
+	compliance.SystemdFieldState,
+	compliance.SystemdFieldActive,
+}
+
+// systemdUnitPaths lists the directories of the system unit files, by decreasing precedence
+var systemdUnitPaths = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+	"/usr/local/lib/systemd/system",
+	"/usr/lib/systemd/system",
+	"/lib/systemd/system",
+}
+
+// systemdEnablementPaths lists the directories holding the symlinks of the enabled units
+var systemdEnablementPaths = []string{
+	"/etc/systemd/system",
+	"/run/systemd/system",
+}
+
+// States of the unit files, as reported by systemctl is-enabled
+const (
+	systemdStateEnabled  = "enabled"
+	systemdStateDisabled = "disabled"
+	systemdStateStatic   = "static"
+	systemdStateMasked   = "masked"
+	systemdStateNotFound = "not-found"
+)
+
+// systemdIsActiveTimeout is the timeout of systemctl is-active
+const systemdIsActiveTimeout = 5 * time.Second
+
+// systemdProperties holds the properties of a unit file by section
+type systemdProperties map[string]map[string]string
+
+type systemdUnitFile struct {
+	// path is the path of the unit file relative to the host root
+	path string
+	// realPath is the path the unit file is read from
+	realPath string
+	masked   bool
+}
+
+func resolveSystemd(ctx context.Context, e env.Env, id string, res compliance.ResourceCommon, rego bool) (resolved, error) {
+	if res.Systemd == nil {
+		return nil, fmt.Errorf("%s: expecting systemd resource in systemd check", id)
+	}
+
+	unit := res.Systemd.Unit
+	if len(unit) == 0 || strings.Contains(unit, "/") {
+		return nil, fmt.Errorf("%s: invalid systemd unit `%s`", id, unit)
+	}
+
+	log.Debugf("%s: reading systemd unit %s", id, unit)
+
+	unitFile, err := findSystemdUnitFile(e, unit)
+	if err != nil {
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	properties := systemdProperties{}
+	state := systemdStateNotFound
+
+	switch {
+	case unitFile == nil:
+	case unitFile.masked:
+		state = systemdStateMasked
+	default:
+		if properties, err = readSystemdUnit(e, unit, unitFile); err != nil {
+			return nil, wrapErrorWithID(id, err)
+		}
+
+		if enabled, err := isSystemdUnitEnabled(e, unit); err != nil {
+			return nil, wrapErrorWithID(id, err)
+		} else if enabled {
+			state = systemdStateEnabled
+		} else if properties.installable() {
+			state = systemdStateDisabled
+		} else {
+			state = systemdStateStatic
+		}
+	}
+
+	active, err := isSystemdUnitActive(ctx, e, unit)
+	if err != nil {
+		return nil, wrapErrorWithID(id, err)
+	}
+
+	var path string
+	if unitFile != nil {
+		path = unitFile.path
+	}
+	enabled := state == systemdStateEnabled
+
+	instance := eval.NewInstance(
+		eval.VarMap{
+			compliance.SystemdFieldUnit:    unit,
+			compliance.SystemdFieldPath:    path,
+			compliance.SystemdFieldState:   state,
+			compliance.SystemdFieldEnabled: enabled,
+			compliance.SystemdFieldActive:  active,
+		},
+		eval.FunctionMap{
+			compliance.SystemdFuncProperty: systemdProperty(properties),
+		},
+		eval.RegoInputMap{
+			"unit":       unit,
+			"path":       path,
+			"state":      state,
+			"enabled":    enabled,
+			"active":     active,
+			"properties": properties,
+		},
+	)
+
+	return newResolvedInstance(instance, unit, "systemd"), nil
+}
+
+// systemdTemplate returns the template of an instantiated unit, such as getty@.service for getty@tty1.service
+func systemdTemplate(unit string) (string, bool) {
+	at := strings.Index(unit, "@")
+	ext := strings.LastIndex(unit, ".")
+	if at < 0 || ext < at || ext == at+1 {
+		return "", false
+	}
+	return unit[:at+1] + unit[ext:], true
+}
+
+// findSystemdUnitFile returns the unit file with the highest precedence, or nil if there is none
+func findSystemdUnitFile(e env.Env, unit string) (*systemdUnitFile, error) {
+	names := []string{unit}
+	if template, ok := systemdTemplate(unit); ok {
+		names = append(names, template)
+	}
+
+	for _, name := range names {
+		for _, dir := range systemdUnitPaths {
+			path := filepath.Join(dir, name)
+			realPath := e.NormalizeToHostRoot(path)
+
+			fi, err := os.Lstat(realPath)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+
+			if fi.Mode()&os.ModeSymlink != 0 {
+				target, err := os.Readlink(realPath)
+				if err != nil {
+					return nil, err
+				}
+				if target == os.DevNull {
+					return &systemdUnitFile{path: path, masked: true}, nil
+				}
+				// the links are relative to the host root
+				if filepath.IsAbs(target) {
+					realPath = e.NormalizeToHostRoot(target)
+				} else {
+					realPath = filepath.Join(filepath.Dir(realPath), target)
+				}
+			}
+
+			return &systemdUnitFile{path: path, realPath: realPath}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// isSystemdUnitEnabled returns whether a unit is wanted or required by another unit
+func isSystemdUnitEnabled(e env.Env, unit string) (bool, error) {
+	for _, dir := range systemdEnablementPaths {
+		for _, kind := range []string{"wants", "requires"} {
+			matches, err := filepath.Glob(e.NormalizeToHostRoot(filepath.Join(dir, "*."+kind, unit)))
+			if err != nil {
+				return false, err
+			}
+			if len(matches) > 0 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// isSystemdUnitActive returns whether a unit is active, that is running for a service, according to
+// systemctl is-active. The units evaluated against a root directory, such as an image, are never active.
+func isSystemdUnitActive(ctx context.Context, e env.Env, unit string) (bool, error) {
+	if e.RootDir() != "" {
+		return false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, systemdIsActiveTimeout)
+	defer cancel()
+
+	// systemctl exits with a non-zero code when the unit isn't active
+	_, stdout, err := commandRunner(ctx, "systemctl", []string{"is-active", "--", unit}, true)
+	if err != nil {
+		return false, fmt.Errorf("failed to read the active state of unit %s: %w", unit, err)
+	}
+	return strings.TrimSpace(string(stdout)) == "active", nil
+}
+
+// readSystemdUnit returns the properties of a unit file, overridden by its drop-in files
+func readSystemdUnit(e env.Env, unit string, unitFile *systemdUnitFile) (systemdProperties, error) {
+	properties := systemdProperties{}
+	if err := properties.readFile(unitFile.realPath); err != nil {
+		return nil, err
+	}
+
+	// drop-in files are applied in the lexicographic order of their names, a drop-in
+	// file overrides the files of the same name in the directories of lower precedence
+	dropIns := make(map[string]string)
+	for _, dir := range systemdUnitPaths {
+		matches, err := filepath.Glob(e.NormalizeToHostRoot(filepath.Join(dir, unit+".d", "*.conf")))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if _, exists := dropIns[filepath.Base(match)]; !exists {
+				dropIns[filepath.Base(match)] = match
+			}
+		}
+	}
+
+	names := make([]string, 0, len(dropIns))
+	for name := range dropIns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := properties.readFile(dropIns[name]); err != nil {
+			return nil, err
+		}
+	}
+
+	return properties, nil
+}
+
+func (p systemdProperties) readFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return p.read(f)
+}
+
+// read parses a unit file, the last assignment of a property wins and an empty assignment resets it
+func (p systemdProperties) read(r io.Reader) error {
+	var section, line string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line += strings.TrimSpace(scanner.Text())
+		if strings.HasSuffix(line, "\\") {
+			// a continued line is joined to the next one with a space
+			line = strings.TrimSpace(strings.TrimSuffix(line, "\\")) + " "
+			continue
+		}
+
+		current := line
+		line = ""
+
+		switch {
+		case len(current) == 0 || current[0] == '#' || current[0] == ';':
+		case current[0] == '[' && current[len(current)-1] == ']':
+			section = current[1 : len(current)-1]
+		default:
+			if len(section) == 0 {
+				return errors.New("malformed unit file, property outside of a section")
+			}
+
+			parts := strings.SplitN(current, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("malformed unit file, invalid line `%s`", current)
+			}
+			key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+			if p[section] == nil {
+				p[section] = make(map[string]string)
+			}
+			if len(value) == 0 {
+				delete(p[section], key)
+			} else {
+				p[section][key] = value
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// installable returns whether the unit file has an [Install] section to enable it
+func (p systemdProperties) installable() bool {
+	for _, key := range []string{"WantedBy", "RequiredBy", "Also", "Alias"} {
+		if len(p["Install"][key]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func systemdProperty(properties systemdProperties) eval.Function {
+	return func(_ eval.Instance, args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf(`invalid number of arguments, expecting 2 got %d`, len(args))
+		}
+		section, ok := args[0].(string)
+		if !ok {
+			return nil, errors.New(`expecting string value for section argument`)
+		}
+		key, ok := args[1].(string)
+		if !ok {
+			return nil, errors.New(`expecting string value for property argument`)
+		}
+		return properties[section][key], nil
+	}
+}
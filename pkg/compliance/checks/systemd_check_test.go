@@ -0,0 +1,265 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package checks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+var systemdUnitFiles = map[string]string{
+	"/lib/systemd/system/auditd.service": `[Unit]
+Description=Security Auditing Service
+# a comment
+[Service]
+Type=forking
+ExecStart=/sbin/auditd \
+  -n
+Restart=on-failure
+
+[Install]
+WantedBy=multi-user.target
+`,
+	"/etc/systemd/system/auditd.service.d/override.conf": `[Service]
+Restart=always
+`,
+	"/lib/systemd/system/auditd.service.d/override.conf": `[Service]
+Restart=no
+`,
+	"/lib/systemd/system/rsync.service": `[Service]
+ExecStart=/usr/bin/rsync --daemon --no-detach
+
+[Install]
+WantedBy=multi-user.target
+`,
+	"/lib/systemd/system/systemd-journald.service": `[Service]
+ExecStart=/lib/systemd/systemd-journald
+`,
+	"/lib/systemd/system/telnet.socket": `[Socket]
+ListenStream=23
+
+[Install]
+WantedBy=sockets.target
+`,
+	"/lib/systemd/system/getty@.service": `[Service]
+ExecStart=-/sbin/agetty -o '-p -- \\u' --noclear %I $TERM
+
+[Install]
+WantedBy=getty.target
+`,
+	"/lib/systemd/system/ssh.service": `[Service]
+ExecStart=/usr/sbin/sshd -D
+
+[Install]
+WantedBy=multi-user.target
+Alias=sshd.service
+`,
+}
+
+var systemdUnitLinks = map[string]string{
+	"/etc/systemd/system/multi-user.target.wants/auditd.service": "/lib/systemd/system/auditd.service",
+	"/etc/systemd/system/getty.target.wants/getty@tty1.service":  "/lib/systemd/system/getty@.service",
+	"/etc/systemd/system/telnet.socket":                          "/dev/null",
+	"/etc/systemd/system/sshd.service":                           "/lib/systemd/system/ssh.service",
+}
+
+// mockSystemctl replaces the command runner with a systemctl is-active reporting the given units as active
+func mockSystemctl(t *testing.T, activeUnits ...string) {
+	prevCommandRunner := commandRunner
+	t.Cleanup(func() {
+		commandRunner = prevCommandRunner
+	})
+
+	commandRunner = func(ctx context.Context, name string, args []string, captureStdout bool) (int, []byte, error) {
+		assert.Equal(t, "systemctl", name)
+		assert.Len(t, args, 3)
+		assert.Equal(t, []string{"is-active", "--"}, args[:2])
+		for _, unit := range activeUnits {
+			if args[2] == unit {
+				return 0, []byte("active\n"), nil
+			}
+		}
+		return 3, []byte("inactive\n"), nil
+	}
+}
+
+func TestSystemdCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		unit      string
+		condition string
+
+		expectPassed bool
+		expectPath   string
+		expectState  string
+		expectActive bool
+	}{
+		{
+			name:         "enabled with drop-in",
+			unit:         "auditd.service",
+			condition:    `systemd.enabled && systemd.property("Service", "Restart") == "always" && systemd.property("Service", "ExecStart") == "/sbin/auditd -n"`,
+			expectPassed: true,
+			expectPath:   "/lib/systemd/system/auditd.service",
+			expectState:  "enabled",
+			expectActive: true,
+		},
+		{
+			name:         "active",
+			unit:         "auditd.service",
+			condition:    `systemd.active`,
+			expectPassed: true,
+			expectPath:   "/lib/systemd/system/auditd.service",
+			expectState:  "enabled",
+			expectActive: true,
+		},
+		{
+			name:         "not active",
+			unit:         "rsync.service",
+			condition:    `systemd.active`,
+			expectPassed: false,
+			expectPath:   "/lib/systemd/system/rsync.service",
+			expectState:  "disabled",
+		},
+		{
+			name:         "disabled",
+			unit:         "rsync.service",
+			condition:    `!systemd.enabled`,
+			expectPassed: true,
+			expectPath:   "/lib/systemd/system/rsync.service",
+			expectState:  "disabled",
+		},
+		{
+			name:         "static",
+			unit:         "systemd-journald.service",
+			condition:    `systemd.state == "static"`,
+			expectPassed: true,
+			expectPath:   "/lib/systemd/system/systemd-journald.service",
+			expectState:  "static",
+		},
+		{
+			name:         "masked",
+			unit:         "telnet.socket",
+			condition:    `systemd.state in ["masked", "not-found"]`,
+			expectPassed: true,
+			expectPath:   "/etc/systemd/system/telnet.socket",
+			expectState:  "masked",
+		},
+		{
+			name:         "not found",
+			unit:         "unknown.service",
+			condition:    `systemd.enabled`,
+			expectPassed: false,
+			expectPath:   "",
+			expectState:  "not-found",
+		},
+		{
+			name:         "template instance",
+			unit:         "getty@tty1.service",
+			condition:    `systemd.enabled`,
+			expectPassed: true,
+			expectPath:   "/lib/systemd/system/getty@.service",
+			expectState:  "enabled",
+		},
+		{
+			name:         "alias",
+			unit:         "sshd.service",
+			condition:    `systemd.property("Service", "ExecStart") == "/usr/sbin/sshd -D"`,
+			expectPassed: true,
+			expectPath:   "/etc/systemd/system/sshd.service",
+			expectState:  "disabled",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mockSystemctl(t, "auditd.service")
+			env, root := newHostRootEnv(t, systemdUnitFiles)
+			for link, target := range systemdUnitLinks {
+				assert.NoError(os.MkdirAll(filepath.Join(root, filepath.Dir(link)), 0755))
+				assert.NoError(os.Symlink(target, filepath.Join(root, link)))
+			}
+
+			systemdCheck, err := newResourceCheck(env, "rule-id", compliance.Resource{
+				ResourceCommon: compliance.ResourceCommon{
+					Systemd: &compliance.SystemdUnit{
+						Unit: test.unit,
+					},
+				},
+				Condition: test.condition,
+			})
+			assert.NoError(err)
+
+			reports := systemdCheck.check(env)
+			assert.Equal(&compliance.Report{
+				Passed: test.expectPassed,
+				Data: event.Data{
+					"systemd.unit":   test.unit,
+					"systemd.path":   test.expectPath,
+					"systemd.state":  test.expectState,
+					"systemd.active": test.expectActive,
+				},
+				Resource: compliance.ReportResource{
+					ID:   test.unit,
+					Type: "systemd",
+				},
+			}, reports[0])
+		})
+	}
+}
+
+func TestSystemdCheckActiveError(t *testing.T) {
+	assert := assert.New(t)
+
+	prevCommandRunner := commandRunner
+	defer func() {
+		commandRunner = prevCommandRunner
+	}()
+	commandRunner = func(ctx context.Context, name string, args []string, captureStdout bool) (int, []byte, error) {
+		return 0, nil, errors.New("command 'systemctl' not found")
+	}
+
+	env, _ := newHostRootEnv(t, systemdUnitFiles)
+	_, err := resolveSystemd(context.Background(), env, "rule-id", compliance.ResourceCommon{
+		Systemd: &compliance.SystemdUnit{
+			Unit: "auditd.service",
+		},
+	}, false)
+	assert.EqualError(err, "rule-id: failed to read the active state of unit auditd.service: command 'systemctl' not found")
+}
+
+func TestSystemdUnitProperties(t *testing.T) {
+	assert := assert.New(t)
+
+	env, _ := newHostRootEnv(t, systemdUnitFiles)
+	unitFile, err := findSystemdUnitFile(env, "auditd.service")
+	assert.NoError(err)
+
+	properties, err := readSystemdUnit(env, "auditd.service", unitFile)
+	assert.NoError(err)
+	assert.Equal(systemdProperties{
+		"Unit": {
+			"Description": "Security Auditing Service",
+		},
+		"Service": {
+			"Type":      "forking",
+			"ExecStart": "/sbin/auditd -n",
+			"Restart":   "always",
+		},
+		"Install": {
+			"WantedBy": "multi-user.target",
+		},
+	}, properties)
+}
@@ -135,6 +135,20 @@ func (_m *Configuration) RelativeToHostRoot(path string) string {
 	return r0
 }
 
+// RootDir provides a mock function with given fields:
+func (_m *Configuration) RootDir() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 type NewConfigurationT interface {
 	mock.TestingT
 	Cleanup(func())
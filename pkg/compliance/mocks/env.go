@@ -217,6 +217,20 @@ func (_m *Env) RelativeToHostRoot(path string) string {
 	return r0
 }
 
+// RootDir provides a mock function with given fields:
+func (_m *Env) RootDir() string {
+	ret := _m.Called()
+
+	var r0 string
+	if rf, ok := ret.Get(0).(func() string); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	return r0
+}
+
 // Reporter provides a mock function with given fields:
 func (_m *Env) Reporter() event.Reporter {
 	ret := _m.Called()
@@ -34,6 +34,14 @@ const (
 	KindConstants = ResourceKind("constants")
 	// KindCustom is used for a Custom check
 	KindCustom = ResourceKind("custom")
+	// KindSysctl is used for a Sysctl resource
+	KindSysctl = ResourceKind("sysctl")
+	// KindSystemd is used for a SystemdUnit resource
+	KindSystemd = ResourceKind("systemd")
+	// KindPackage is used for a Package resource
+	KindPackage = ResourceKind("package")
+	// KindKernelModule is used for a KernelModule resource
+	KindKernelModule = ResourceKind("kernelModule")
 )
 
 // ResourceCommon describes the base fields of resource types
@@ -47,6 +55,10 @@ type ResourceCommon struct {
 	KubeApiserver *KubernetesResource `yaml:"kubeApiserver,omitempty"`
 	Constants     *ConstantsResource  `yaml:"constants,omitempty"`
 	Custom        *Custom             `yaml:"custom,omitempty"`
+	Sysctl        *Sysctl             `yaml:"sysctl,omitempty"`
+	Systemd       *SystemdUnit        `yaml:"systemd,omitempty"`
+	Package       *Package            `yaml:"package,omitempty"`
+	KernelModule  *KernelModule       `yaml:"kernelModule,omitempty"`
 }
 
 // Resource describes supported resource types observed by a Rule
@@ -96,6 +108,14 @@ func (r *ResourceCommon) Kind() ResourceKind {
 		return KindConstants
 	case r.Custom != nil:
 		return KindCustom
+	case r.Sysctl != nil:
+		return KindSysctl
+	case r.Systemd != nil:
+		return KindSystemd
+	case r.Package != nil:
+		return KindPackage
+	case r.KernelModule != nil:
+		return KindKernelModule
 	default:
 		return KindInvalid
 	}
@@ -301,3 +321,57 @@ type Custom struct {
 	Name      string            `yaml:"name"`
 	Variables map[string]string `yaml:"variables,omitempty"`
 }
+
+// Fields & functions available for Sysctl
+const (
+	SysctlFieldKey   = "sysctl.key"
+	SysctlFieldValue = "sysctl.value"
+)
+
+// Sysctl describes a kernel parameter resource, read from /proc/sys
+type Sysctl struct {
+	Key string `yaml:"key"`
+}
+
+// Fields & functions available for SystemdUnit
+const (
+	SystemdFieldUnit    = "systemd.unit"
+	SystemdFieldPath    = "systemd.path"
+	SystemdFieldState   = "systemd.state"
+	SystemdFieldEnabled = "systemd.enabled"
+	SystemdFieldActive  = "systemd.active"
+
+	SystemdFuncProperty = "systemd.property"
+)
+
+// SystemdUnit describes a systemd unit resource, read from the unit files. Its active state
+// is read with systemctl on a live host.
+type SystemdUnit struct {
+	Unit string `yaml:"unit"`
+}
+
+// Fields & functions available for Package
+const (
+	PackageFieldName      = "package.name"
+	PackageFieldInstalled = "package.installed"
+	PackageFieldVersion   = "package.version"
+	PackageFieldArch      = "package.arch"
+)
+
+// Package describes an installed package resource, read from the dpkg or rpm database
+type Package struct {
+	Name string `yaml:"name"`
+}
+
+// Fields & functions available for KernelModule
+const (
+	KernelModuleFieldName        = "kernelModule.name"
+	KernelModuleFieldLoaded      = "kernelModule.loaded"
+	KernelModuleFieldBlacklisted = "kernelModule.blacklisted"
+	KernelModuleFieldDisabled    = "kernelModule.disabled"
+)
+
+// KernelModule describes a kernel module resource, read from /proc/modules and the modprobe configuration
+type KernelModule struct {
+	Name string `yaml:"name"`
+}
@@ -62,6 +62,30 @@ docker:
 condition: docker.template("{{ $.Config.Healthcheck }}") != ""
 `
 
+const testResourceSysctl = `
+sysctl:
+  key: net.ipv4.ip_forward
+condition: sysctl.value == "0"
+`
+
+const testResourceSystemd = `
+systemd:
+  unit: auditd.service
+condition: systemd.enabled
+`
+
+const testResourcePackage = `
+package:
+  name: telnetd
+condition: "!package.installed"
+`
+
+const testResourceKernelModule = `
+kernelModule:
+  name: cramfs
+condition: "!kernelModule.loaded && kernelModule.disabled"
+`
+
 func TestResources(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -166,6 +190,54 @@ func TestResources(t *testing.T) {
 				Condition: `docker.template("{{ $.Config.Healthcheck }}") != ""`,
 			},
 		},
+		{
+			name:  "sysctl",
+			input: testResourceSysctl,
+			expected: Resource{
+				ResourceCommon: ResourceCommon{
+					Sysctl: &Sysctl{
+						Key: "net.ipv4.ip_forward",
+					},
+				},
+				Condition: `sysctl.value == "0"`,
+			},
+		},
+		{
+			name:  "systemd",
+			input: testResourceSystemd,
+			expected: Resource{
+				ResourceCommon: ResourceCommon{
+					Systemd: &SystemdUnit{
+						Unit: "auditd.service",
+					},
+				},
+				Condition: `systemd.enabled`,
+			},
+		},
+		{
+			name:  "package",
+			input: testResourcePackage,
+			expected: Resource{
+				ResourceCommon: ResourceCommon{
+					Package: &Package{
+						Name: "telnetd",
+					},
+				},
+				Condition: `!package.installed`,
+			},
+		},
+		{
+			name:  "kernel module",
+			input: testResourceKernelModule,
+			expected: Resource{
+				ResourceCommon: ResourceCommon{
+					KernelModule: &KernelModule{
+						Name: "cramfs",
+					},
+				},
+				Condition: `!kernelModule.loaded && kernelModule.disabled`,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,168 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package results
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+)
+
+// junitDefaultSuite is the test suite of the rules without framework
+const junitDefaultSuite = "compliance"
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Name     string            `xml:"name,attr"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Errors   int               `xml:"errors,attr"`
+	Skipped  int               `xml:"skipped,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Hostname   string           `xml:"hostname,attr,omitempty"`
+	Properties []junitProperty  `xml:"properties>property,omitempty"`
+	TestCases  []*junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr,omitempty"`
+	Content string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML, with a test suite by framework and a test case by rule.
+// The findings of a test case are listed in its output, or in its failure or error.
+func (r *Results) WriteJUnit(w io.Writer) error {
+	suites := &junitTestSuites{Name: junitDefaultSuite}
+	suitesByName := make(map[string]*junitTestSuite)
+
+	for _, rule := range r.Rules {
+		name := rule.Framework
+		if len(name) == 0 {
+			name = junitDefaultSuite
+		}
+
+		suite, exists := suitesByName[name]
+		if !exists {
+			suite = &junitTestSuite{
+				Name:     name,
+				Hostname: r.Hostname,
+			}
+			if len(rule.Version) > 0 {
+				suite.Properties = []junitProperty{{Name: "version", Value: rule.Version}}
+			}
+			suitesByName[name] = suite
+			suites.Suites = append(suites.Suites, suite)
+		}
+
+		testCase := &junitTestCase{
+			Name:      rule.ID,
+			ClassName: name,
+		}
+		if len(rule.Description) > 0 {
+			testCase.Name = compliance.CheckName(rule.ID, rule.Description)
+		}
+
+		details, err := rule.junitDetails()
+		if err != nil {
+			return err
+		}
+
+		switch rule.Result {
+		case event.Passed:
+			testCase.SystemOut = details
+		case event.Failed:
+			testCase.Failure = &junitMessage{Message: fmt.Sprintf("rule failed on %d resource(s)", rule.countFindings(event.Failed)), Content: details}
+			suite.Failures++
+		case event.Error:
+			message := rule.Error
+			if len(message) == 0 {
+				message = fmt.Sprintf("rule evaluation failed on %d resource(s)", rule.countFindings(event.Error))
+			}
+			testCase.Error = &junitMessage{Message: message, Content: details}
+			suite.Errors++
+		default:
+			testCase.Skipped = &junitMessage{Message: "no resource reported"}
+			suite.Skipped++
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	for _, suite := range suites.Suites {
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Errors += suite.Errors
+		suites.Skipped += suite.Skipped
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func (r *Rule) countFindings(result string) int {
+	var count int
+	for _, finding := range r.Findings {
+		if finding.Result == result {
+			count++
+		}
+	}
+	return count
+}
+
+// junitDetails returns a line by finding, with its evidence
+func (r *Rule) junitDetails() (string, error) {
+	var b strings.Builder
+	for _, finding := range r.Findings {
+		b.WriteString(r.message(finding))
+		if finding.Evidence != nil {
+			evidence, err := json.Marshal(finding.Evidence)
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(" ")
+			b.Write(evidence)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package results holds the results of a compliance checks run, and writes them
+// as JSON, SARIF or JUnit XML reports to be consumed by CI pipelines
+package results
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+)
+
+// Formats of the reports
+const (
+	FormatJSON  = "json"
+	FormatSARIF = "sarif"
+	FormatJUnit = "junit"
+)
+
+// Formats lists the supported formats of the reports
+var Formats = []string{FormatJSON, FormatSARIF, FormatJUnit}
+
+// Skipped is the result of a rule which didn't report any resource, or which doesn't apply to the environment
+const Skipped = "skipped"
+
+// Finding describes the result of a rule evaluated on a resource
+type Finding struct {
+	Result       string      `json:"result"`
+	ResourceType string      `json:"resource_type,omitempty"`
+	ResourceID   string      `json:"resource_id,omitempty"`
+	Evidence     interface{} `json:"evidence,omitempty"`
+}
+
+// Rule describes a rule and its findings
+type Rule struct {
+	ID          string    `json:"id"`
+	Description string    `json:"description,omitempty"`
+	Framework   string    `json:"framework,omitempty"`
+	Version     string    `json:"version,omitempty"`
+	Source      string    `json:"source,omitempty"`
+	Result      string    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+	Findings    []Finding `json:"findings"`
+}
+
+// Summary counts the rules by result
+type Summary struct {
+	Passed  int `json:"passed"`
+	Failed  int `json:"failed"`
+	Error   int `json:"error"`
+	Skipped int `json:"skipped"`
+}
+
+// Results holds the rules of a compliance checks run and their findings
+type Results struct {
+	Hostname     string  `json:"hostname,omitempty"`
+	AgentVersion string  `json:"agent_version,omitempty"`
+	Summary      Summary `json:"summary"`
+	Rules        []*Rule `json:"rules"`
+}
+
+// New returns the results of the checks, with the events they reported by rule ID
+func New(hostname, agentVersion string, statuses compliance.CheckStatusList, events map[string][]*event.Event) *Results {
+	results := &Results{
+		Hostname:     hostname,
+		AgentVersion: agentVersion,
+		Rules:        []*Rule{},
+	}
+
+	seen := make(map[string]bool)
+	for _, status := range statuses {
+		rule := &Rule{
+			ID:          status.RuleID,
+			Description: status.Description,
+			Framework:   status.Framework,
+			Version:     status.Version,
+			Source:      status.Source,
+		}
+		if status.InitError != nil && !errors.Is(status.InitError, checks.ErrRuleDoesNotApply) {
+			rule.Error = status.InitError.Error()
+		}
+		results.addRule(rule, events[status.RuleID])
+		seen[status.RuleID] = true
+	}
+
+	// events of rules missing from the statuses, which shouldn't happen
+	var ruleIDs []string
+	for ruleID := range events {
+		if !seen[ruleID] {
+			ruleIDs = append(ruleIDs, ruleID)
+		}
+	}
+	sort.Strings(ruleIDs)
+
+	for _, ruleID := range ruleIDs {
+		rule := &Rule{ID: ruleID}
+		if len(events[ruleID]) > 0 {
+			rule.Framework = events[ruleID][0].AgentFrameworkID
+		}
+		results.addRule(rule, events[ruleID])
+	}
+
+	return results
+}
+
+func (r *Results) addRule(rule *Rule, events []*event.Event) {
+	rule.Findings = []Finding{}
+	for _, e := range events {
+		rule.Findings = append(rule.Findings, Finding{
+			Result:       e.Result,
+			ResourceType: e.ResourceType,
+			ResourceID:   e.ResourceID,
+			Evidence:     e.Data,
+		})
+	}
+	rule.Result = rule.result()
+
+	switch rule.Result {
+	case event.Passed:
+		r.Summary.Passed++
+	case event.Failed:
+		r.Summary.Failed++
+	case event.Error:
+		r.Summary.Error++
+	default:
+		r.Summary.Skipped++
+	}
+
+	r.Rules = append(r.Rules, rule)
+}
+
+// result returns the result of a rule: error if it failed to load or to be evaluated on
+// a resource, failed if it failed on a resource, passed if it passed on all the resources
+func (r *Rule) result() string {
+	if len(r.Error) > 0 {
+		return event.Error
+	}
+	if len(r.Findings) == 0 {
+		return Skipped
+	}
+
+	result := event.Passed
+	for _, finding := range r.Findings {
+		switch finding.Result {
+		case event.Error:
+			return event.Error
+		case event.Failed:
+			result = event.Failed
+		}
+	}
+	return result
+}
+
+// HasFailures returns whether a rule failed or couldn't be evaluated
+func (r *Results) HasFailures() bool {
+	return r.Summary.Failed > 0 || r.Summary.Error > 0
+}
+
+// Write writes the results in a format
+func (r *Results) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatJSON:
+		return r.WriteJSON(w)
+	case FormatSARIF:
+		return r.WriteSARIF(w)
+	case FormatJUnit:
+		return r.WriteJUnit(w)
+	default:
+		return fmt.Errorf("unknown report format `%s`, expecting one of %v", format, Formats)
+	}
+}
+
+// WriteJSON writes the results as JSON
+func (r *Results) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// message returns a human readable description of a finding
+func (r *Rule) message(finding Finding) string {
+	name := r.ID
+	if len(r.Description) > 0 {
+		name = compliance.CheckName(r.ID, r.Description)
+	}
+
+	if finding.Result == event.Error {
+		if data, ok := finding.Evidence.(event.Data); ok {
+			if err, ok := data["error"]; ok {
+				return fmt.Sprintf("%s: error on %s %s: %v", name, finding.ResourceType, finding.ResourceID, err)
+			}
+		}
+	}
+	return fmt.Sprintf("%s: %s on %s %s", name, finding.Result, finding.ResourceType, finding.ResourceID)
+}
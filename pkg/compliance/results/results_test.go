@@ -0,0 +1,219 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package results
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance"
+	"github.com/DataDog/datadog-agent/pkg/compliance/checks"
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+
+	assert "github.com/stretchr/testify/require"
+)
+
+func newTestResults() *Results {
+	statuses := compliance.CheckStatusList{
+		{RuleID: "rule-passed", Description: "passing rule", Framework: "cis-docker", Version: "1.2.0"},
+		{RuleID: "rule-failed", Description: "failing rule", Framework: "cis-docker", Version: "1.2.0"},
+		{RuleID: "rule-error", Description: "rule in error", Framework: "cis-kubernetes"},
+		{RuleID: "rule-init-error", Framework: "cis-kubernetes", InitError: errors.New("unknown resource")},
+		{RuleID: "rule-skipped", Description: "skipped rule"},
+		{RuleID: "rule-does-not-apply", Framework: "cis-docker", InitError: checks.ErrRuleDoesNotApply},
+	}
+
+	events := map[string][]*event.Event{
+		"rule-passed": {
+			{AgentRuleID: "rule-passed", Result: event.Passed, ResourceType: "file", ResourceID: "/etc/docker/daemon.json", Data: event.Data{"file.permissions": 0644}},
+		},
+		"rule-failed": {
+			{AgentRuleID: "rule-failed", Result: event.Passed, ResourceType: "docker_container", ResourceID: "c1"},
+			{AgentRuleID: "rule-failed", Result: event.Failed, ResourceType: "docker_container", ResourceID: "c2", Data: event.Data{"container.privileged": true}},
+		},
+		"rule-error": {
+			{AgentRuleID: "rule-error", Result: event.Error, ResourceType: "kube_node", ResourceID: "node", Data: event.Data{"error": "permission denied"}},
+		},
+		"rule-unknown": {
+			{AgentRuleID: "rule-unknown", AgentFrameworkID: "custom", Result: event.Passed, ResourceType: "file", ResourceID: "/etc/passwd"},
+		},
+	}
+
+	return New("host", "7.35.0", statuses, events)
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+
+	results := newTestResults()
+	assert.Equal(Summary{Passed: 2, Failed: 1, Error: 2, Skipped: 2}, results.Summary)
+	assert.True(results.HasFailures())
+
+	var ids, outcomes []string
+	for _, rule := range results.Rules {
+		ids = append(ids, rule.ID)
+		outcomes = append(outcomes, rule.Result)
+	}
+	assert.Equal([]string{"rule-passed", "rule-failed", "rule-error", "rule-init-error", "rule-skipped", "rule-does-not-apply", "rule-unknown"}, ids)
+	assert.Equal([]string{event.Passed, event.Failed, event.Error, event.Error, Skipped, Skipped, event.Passed}, outcomes)
+
+	assert.Equal("unknown resource", results.Rules[3].Error)
+	assert.Empty(results.Rules[5].Error)
+	assert.Equal("custom", results.Rules[6].Framework)
+	assert.Equal("rule-error: rule in error: error on kube_node node: permission denied", results.Rules[2].message(results.Rules[2].Findings[0]))
+
+	passing := New("host", "7.35.0", compliance.CheckStatusList{{RuleID: "rule-passed"}}, map[string][]*event.Event{
+		"rule-passed": {{AgentRuleID: "rule-passed", Result: event.Passed}},
+	})
+	assert.False(passing.HasFailures())
+}
+
+func TestWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(newTestResults().Write(&buf, FormatJSON))
+
+	var report struct {
+		Hostname string  `json:"hostname"`
+		Summary  Summary `json:"summary"`
+		Rules    []struct {
+			ID       string `json:"id"`
+			Result   string `json:"result"`
+			Findings []struct {
+				Result     string                 `json:"result"`
+				ResourceID string                 `json:"resource_id"`
+				Evidence   map[string]interface{} `json:"evidence"`
+			} `json:"findings"`
+		} `json:"rules"`
+	}
+	assert.NoError(json.Unmarshal(buf.Bytes(), &report))
+
+	assert.Equal("host", report.Hostname)
+	assert.Equal(1, report.Summary.Failed)
+	assert.Len(report.Rules, 7)
+	assert.Equal("rule-failed", report.Rules[1].ID)
+	assert.Equal(event.Failed, report.Rules[1].Result)
+	assert.Len(report.Rules[1].Findings, 2)
+	assert.Equal("c2", report.Rules[1].Findings[1].ResourceID)
+	assert.Equal(true, report.Rules[1].Findings[1].Evidence["container.privileged"])
+}
+
+func TestWriteSARIF(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(newTestResults().Write(&buf, FormatSARIF))
+
+	var log sarifLog
+	assert.NoError(json.Unmarshal(buf.Bytes(), &log))
+
+	assert.Equal(sarifVersion, log.Version)
+	assert.Len(log.Runs, 1)
+
+	run := log.Runs[0]
+	assert.Equal(sarifToolName, run.Tool.Driver.Name)
+	assert.Equal("7.35.0", run.Tool.Driver.Version)
+	assert.Len(run.Tool.Driver.Rules, 7)
+	assert.Equal("passing rule", run.Tool.Driver.Rules[0].ShortDescription.Text)
+
+	assert.Len(run.Invocations[0].ToolConfigurationNotifications, 1)
+	assert.Equal(sarifRuleReference{ID: "rule-init-error", Index: 3}, run.Invocations[0].ToolConfigurationNotifications[0].Rule)
+
+	assert.Len(run.Results, 5)
+	failed := run.Results[2]
+	assert.Equal("rule-failed", failed.RuleID)
+	assert.Equal(1, failed.RuleIndex)
+	assert.Equal("fail", failed.Kind)
+	assert.Equal("error", failed.Level)
+	assert.Equal("rule-failed: failing rule: failed on docker_container c2", failed.Message.Text)
+	assert.Equal("docker_container/c2", failed.Locations[0].LogicalLocations[0].FullyQualifiedName)
+
+	inError := run.Results[3]
+	assert.Equal("review", inError.Kind)
+	assert.Equal("none", inError.Level)
+	assert.Len(run.Invocations[0].ToolExecutionNotifications, 1)
+	notification := run.Invocations[0].ToolExecutionNotifications[0]
+	assert.Equal("error", notification.Level)
+	assert.Equal(sarifRuleReference{ID: "rule-error", Index: 2}, notification.Rule)
+	assert.Equal(inError.Message, notification.Message)
+	assert.Equal("kube_node/node", notification.Locations[0].LogicalLocations[0].FullyQualifiedName)
+}
+
+func TestWriteSARIFValidResults(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(newTestResults().Write(&buf, FormatSARIF))
+
+	var log sarifLog
+	assert.NoError(json.Unmarshal(buf.Bytes(), &log))
+
+	// the values allowed by the SARIF 2.1.0 schema, see §3.27.9, §3.27.10 and §3.58.6
+	kinds := map[string]bool{"notApplicable": true, "pass": true, "fail": true, "review": true, "open": true, "informational": true}
+	levels := map[string]bool{"none": true, "note": true, "warning": true, "error": true}
+
+	for _, run := range log.Runs {
+		for _, result := range run.Results {
+			assert.True(kinds[result.Kind], "invalid kind %s of result %s", result.Kind, result.Message.Text)
+			assert.True(levels[result.Level], "invalid level %s of result %s", result.Level, result.Message.Text)
+			if result.Kind != "fail" {
+				assert.Equal("none", result.Level, "the level of a result must be none when its kind isn't fail: %s", result.Message.Text)
+			}
+			assert.Less(result.RuleIndex, len(run.Tool.Driver.Rules))
+			assert.Equal(run.Tool.Driver.Rules[result.RuleIndex].ID, result.RuleID)
+		}
+
+		for _, invocation := range run.Invocations {
+			for _, notification := range append(invocation.ToolConfigurationNotifications, invocation.ToolExecutionNotifications...) {
+				assert.True(levels[notification.Level], "invalid level %s of notification %s", notification.Level, notification.Message.Text)
+				assert.Equal(run.Tool.Driver.Rules[notification.Rule.Index].ID, notification.Rule.ID)
+			}
+		}
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	assert := assert.New(t)
+
+	var buf bytes.Buffer
+	assert.NoError(newTestResults().Write(&buf, FormatJUnit))
+
+	var suites junitTestSuites
+	assert.NoError(xml.Unmarshal(buf.Bytes(), &suites))
+
+	assert.Equal(7, suites.Tests)
+	assert.Equal(1, suites.Failures)
+	assert.Equal(2, suites.Errors)
+	assert.Equal(2, suites.Skipped)
+
+	var names []string
+	for _, suite := range suites.Suites {
+		names = append(names, suite.Name)
+	}
+	assert.Equal([]string{"cis-docker", "cis-kubernetes", junitDefaultSuite, "custom"}, names)
+
+	docker := suites.Suites[0]
+	assert.Equal(3, docker.Tests)
+	assert.Equal([]junitProperty{{Name: "version", Value: "1.2.0"}}, docker.Properties)
+	assert.Equal("rule-failed: failing rule", docker.TestCases[1].Name)
+	assert.NotNil(docker.TestCases[1].Failure)
+	assert.Equal("rule failed on 1 resource(s)", docker.TestCases[1].Failure.Message)
+	assert.Contains(docker.TestCases[1].Failure.Content, `on docker_container c2 {"container.privileged":true}`)
+
+	kubernetes := suites.Suites[1]
+	assert.Equal("unknown resource", kubernetes.TestCases[1].Error.Message)
+	assert.NotNil(suites.Suites[2].TestCases[0].Skipped)
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, newTestResults().Write(&buf, "html"))
+	assert.Zero(t, buf.Len())
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package results
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/DataDog/datadog-agent/pkg/compliance/event"
+)
+
+// SARIF 2.1.0 log, see https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+const (
+	sarifVersion  = "2.1.0"
+	sarifSchema   = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifToolName = "datadog-security-agent"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool        sarifTool         `json:"tool"`
+	Invocations []sarifInvocation `json:"invocations"`
+	Results     []sarifResult     `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string                 `json:"id"`
+	ShortDescription *sarifMessage          `json:"shortDescription,omitempty"`
+	Properties       map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifInvocation struct {
+	ExecutionSuccessful            bool                `json:"executionSuccessful"`
+	ToolConfigurationNotifications []sarifNotification `json:"toolConfigurationNotifications,omitempty"`
+	ToolExecutionNotifications     []sarifNotification `json:"toolExecutionNotifications,omitempty"`
+}
+
+type sarifNotification struct {
+	Level     string             `json:"level"`
+	Message   sarifMessage       `json:"message"`
+	Rule      sarifRuleReference `json:"associatedRule"`
+	Locations []sarifLocation    `json:"locations,omitempty"`
+}
+
+type sarifRuleReference struct {
+	ID    string `json:"id"`
+	Index int    `json:"index"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	RuleIndex  int                    `json:"ruleIndex"`
+	Kind       string                 `json:"kind"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind,omitempty"`
+}
+
+// sarifKindAndLevel returns the kind and the level of a SARIF result. The level must be
+// "none" unless the kind is "fail", see §3.27.10 of the specification.
+func sarifKindAndLevel(result string) (string, string) {
+	switch result {
+	case event.Passed:
+		return "pass", "none"
+	case event.Failed:
+		return "fail", "error"
+	default:
+		return "review", "none"
+	}
+}
+
+// WriteSARIF writes the results as a SARIF log, the rules which failed to load are reported
+// as configuration notifications, the findings in error as results to review along with an
+// execution notification, the resources as logical locations
+func (r *Results) WriteSARIF(w io.Writer) error {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:    sarifToolName,
+				Version: r.AgentVersion,
+				Rules:   []sarifRule{},
+			},
+		},
+		Invocations: []sarifInvocation{{ExecutionSuccessful: true}},
+		Results:     []sarifResult{},
+	}
+
+	for index, rule := range r.Rules {
+		descriptor := sarifRule{
+			ID: rule.ID,
+			Properties: map[string]interface{}{
+				"framework": rule.Framework,
+				"version":   rule.Version,
+			},
+		}
+		if len(rule.Description) > 0 {
+			descriptor.ShortDescription = &sarifMessage{Text: rule.Description}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, descriptor)
+
+		if len(rule.Error) > 0 {
+			run.Invocations[0].ToolConfigurationNotifications = append(run.Invocations[0].ToolConfigurationNotifications, sarifNotification{
+				Level:   "error",
+				Message: sarifMessage{Text: rule.Error},
+				Rule:    sarifRuleReference{ID: rule.ID, Index: index},
+			})
+		}
+
+		for _, finding := range rule.Findings {
+			kind, level := sarifKindAndLevel(finding.Result)
+			locations := []sarifLocation{{
+				LogicalLocations: []sarifLogicalLocation{{
+					Name:               finding.ResourceID,
+					FullyQualifiedName: finding.ResourceType + "/" + finding.ResourceID,
+					Kind:               finding.ResourceType,
+				}},
+			}}
+			result := sarifResult{
+				RuleID:    rule.ID,
+				RuleIndex: index,
+				Kind:      kind,
+				Level:     level,
+				Message:   sarifMessage{Text: rule.message(finding)},
+				Locations: locations,
+			}
+			if finding.Result == event.Error {
+				run.Invocations[0].ToolExecutionNotifications = append(run.Invocations[0].ToolExecutionNotifications, sarifNotification{
+					Level:     "error",
+					Message:   result.Message,
+					Rule:      sarifRuleReference{ID: rule.ID, Index: index},
+					Locations: locations,
+				})
+			}
+			if finding.Evidence != nil {
+				result.Properties = map[string]interface{}{"evidence": finding.Evidence}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{run},
+	})
+}
@@ -434,6 +434,26 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("enable_sketch_stream_payload_serialization", true)
 	config.BindEnvAndSetDefault("enable_json_stream_shared_compressor_buffers", true)
 
+	// Serializer compression: `serializer_compressor_kind` applies to all payloads unless overridden
+	// by `serializer_compressor_kinds.<payload kind>`.
+	config.BindEnvAndSetDefault("serializer_compressor_kind", "zlib")
+	config.BindEnvAndSetDefault("serializer_compressor_kinds.events", "")
+	config.BindEnvAndSetDefault("serializer_compressor_kinds.service_checks", "")
+	config.BindEnvAndSetDefault("serializer_compressor_kinds.series", "")
+	config.BindEnvAndSetDefault("serializer_compressor_kinds.sketches", "")
+	config.BindEnvAndSetDefault("serializer_compressor_kinds.metadata", "")
+
+	// Size report of the payloads of each flush, by payload type, check and metric prefix.
+	config.BindEnvAndSetDefault("serializer_size_report.enabled", false)
+	config.BindEnvAndSetDefault("serializer_size_report.max_entries", 20)
+
+	// Tenant routing: the data tagged `<tenant_routing.tag>:<tag_value>` is sent to the
+	// organization of the tenant configured in `tenant_routing.tenants`.
+	config.BindEnvAndSetDefault("tenant_routing.enabled", false)
+	config.BindEnvAndSetDefault("tenant_routing.tag", "")
+	config.BindEnvAndSetDefault("tenant_routing.copy_to_default", false)
+	config.SetKnown("tenant_routing.tenants")
+
 	// Warning: do not change the following values. Your payloads will get dropped by Datadog's intake.
 	config.BindEnvAndSetDefault("serializer_max_payload_size", 2*megaByte+megaByte/2)
 	config.BindEnvAndSetDefault("serializer_max_uncompressed_payload_size", 4*megaByte)
@@ -473,6 +493,28 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("forwarder_storage_max_disk_ratio", 0.80)                // Do not store transactions on disk when the disk usage exceeds 80% of the disk capacity. Use 80% as some applications do not behave well when the disk space is very small.
 	config.BindEnvAndSetDefault("forwarder_retry_queue_capacity_time_interval_sec", 900) // 15 mins
 
+	// Forwarder storage encryption. Keys are base64 encoded AES keys, usually sourced from the secrets backend.
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_key", "") // empty means the files are not encrypted.
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_previous_keys", []string{})
+	config.BindEnvAndSetDefault("forwarder_storage_encryption_migrate_plaintext_files", false)
+
+	// Forwarder bandwidth shaping. 0 means unlimited.
+	config.BindEnvAndSetDefault("forwarder_bandwidth_limit_bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_domain_bandwidth_limit_bytes_per_second", 0)
+	config.BindEnvAndSetDefault("forwarder_daily_bytes_budget", 0)
+
+	// Forwarder shadow mode: payloads are written to this directory instead of being sent.
+	config.BindEnvAndSetDefault("forwarder_shadow_mode_path", "")
+	config.BindEnvAndSetDefault("forwarder_shadow_mode_max_size", 1024*1024*1024) // 1 GB
+
+	// Forwarder connections. `forwarder_domain_http_settings` overrides them per domain.
+	config.BindEnvAndSetDefault("forwarder_http_protocol", "http1")
+	config.BindEnvAndSetDefault("forwarder_max_idle_conns_per_host", 5)
+	config.BindEnvAndSetDefault("forwarder_max_conns_per_host", 0)
+	config.BindEnvAndSetDefault("forwarder_idle_conn_timeout", 90)
+	config.BindEnvAndSetDefault("forwarder_http2_health_check_interval", 30)
+	config.SetKnown("forwarder_domain_http_settings")
+
 	// Forwarder channels buffer size
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
@@ -1041,13 +1083,31 @@ func InitConfig(config Config) {
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cleanup_period", 30)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.tags_resolution_period", 60)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.traced_cgroups_count", -1)
-	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.traced_event_types", []string{"exec", "open"})
+	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.traced_event_types", []string{"exec", "open", "dns"})
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_dump_timeout", 30)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_wait_list_size", 10)
 	config.BindEnvAndSetDefault("runtime_security_config.activity_dump.cgroup_output_directory", "")
 	config.BindEnvAndSetDefault("runtime_security_config.network.enabled", true)
 	config.BindEnvAndSetDefault("runtime_security_config.network.lazy_interface_prefixes", []string{})
 	config.BindEnvAndSetDefault("runtime_security_config.remote_configuration.enabled", false)
+	config.BindEnvAndSetDefault("runtime_security_config.actions.kill.dry_run", false)
+	config.BindEnvAndSetDefault("runtime_security_config.actions.kill.allowlist", []string{})
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.rule_ids", []string{})
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.min_severity", "")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.queue_size", 1000)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.file.enabled", false)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.file.path", "/var/log/datadog/runtime-security-events.json")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.file.max_size", 100)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.file.max_backups", 5)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.syslog.enabled", false)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.syslog.network", "udp")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.syslog.address", "localhost:514")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.syslog.app_name", "datadog-cws")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.syslog.facility", 10)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.webhook.enabled", false)
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.webhook.url", "")
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.webhook.headers", map[string]string{})
+	config.BindEnvAndSetDefault("runtime_security_config.event_export.webhook.timeout", 10)
 
 	// Serverless Agent
 	config.BindEnvAndSetDefault("serverless.logs_enabled", true)
@@ -38,6 +38,15 @@ func SetupOTLP(config Config) {
 
 	// set environment variables for selected fields
 	setupOTLPEnvironmentVariables(config)
+
+	// OTLP export of the metrics aggregated by the Agent
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.enabled", false)
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.endpoint", "http://localhost:4318")
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.headers", map[string]string{})
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.compression", "gzip")
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.timeout", 10)
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.queue_size", 100)
+	config.BindEnvAndSetDefault("otlp_metrics_exporter.send_to_datadog", true)
 }
 
 // setupOTLPEnvironmentVariables sets up the environment variables associated with different OTLP ingest settings:
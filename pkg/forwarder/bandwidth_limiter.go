@@ -0,0 +1,337 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"context"
+	"expvar"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const throughputWindow = 60 // seconds
+
+var (
+	bandwidthExpvar        = expvar.Map{}
+	bandwidthDomainsExpvar = expvar.Map{}
+
+	sharedBandwidthOnce sync.Once
+	sharedBandwidth     *processBandwidth
+)
+
+// processBandwidth holds the limits shared by every domain of every forwarder of the
+// process: they bound what goes through the network link, whatever the destination is.
+type processBandwidth struct {
+	limiter *byteRateLimiter
+	budget  *dailyBudget
+	meter   *throughputMeter
+}
+
+// getProcessBandwidth returns the process wide limits, reading the configuration the
+// first time it is called.
+func getProcessBandwidth() *processBandwidth {
+	sharedBandwidthOnce.Do(func() {
+		sharedBandwidth = newProcessBandwidth(
+			config.Datadog.GetInt("forwarder_bandwidth_limit_bytes_per_second"),
+			config.Datadog.GetInt64("forwarder_daily_bytes_budget"),
+			time.Now())
+		transaction.ForwarderExpvars.Set("Bandwidth", &bandwidthExpvar)
+		bandwidthExpvar.Set("Global", expvar.Func(func() interface{} {
+			return sharedBandwidth.getStatus(time.Now())
+		}))
+		bandwidthExpvar.Set("Domains", &bandwidthDomainsExpvar)
+	})
+	return sharedBandwidth
+}
+
+func newProcessBandwidth(bytesPerSecond int, dailyBytesBudget int64, now time.Time) *processBandwidth {
+	return &processBandwidth{
+		limiter: newByteRateLimiter(bytesPerSecond),
+		budget:  newDailyBudget(dailyBytesBudget, now),
+		meter:   newThroughputMeter(),
+	}
+}
+
+// processBandwidthStatus is the status of the process wide limits reported in the
+// `Bandwidth` expvar.
+type processBandwidthStatus struct {
+	LimitBytesPerSecond       int
+	ThroughputBytesPerSecond  float64
+	DailyBudgetBytes          int64
+	DailyBudgetUsedBytes      int64
+	DailyBudgetResetInSeconds float64
+	DeferredTransactionsCount int64
+}
+
+func (p *processBandwidth) getStatus(now time.Time) processBandwidthStatus {
+	status := processBandwidthStatus{
+		LimitBytesPerSecond:      p.limiter.limit(),
+		ThroughputBytesPerSecond: p.meter.rate(now),
+	}
+	if p.budget != nil {
+		status.DailyBudgetBytes, status.DailyBudgetUsedBytes, status.DailyBudgetResetInSeconds, status.DeferredTransactionsCount = p.budget.getStatus(now)
+	}
+	return status
+}
+
+// bandwidthLimiter throttles the transactions sent to a domain. A transaction must fit
+// in the daily budget and go through both the domain and the process token buckets.
+type bandwidthLimiter struct {
+	domain  *byteRateLimiter
+	meter   *throughputMeter
+	process *processBandwidth
+}
+
+func newBandwidthLimiter(domainBytesPerSecond int, process *processBandwidth) *bandwidthLimiter {
+	return &bandwidthLimiter{
+		domain:  newByteRateLimiter(domainBytesPerSecond),
+		meter:   newThroughputMeter(),
+		process: process,
+	}
+}
+
+// register reports the status of the domain in the `Bandwidth` expvar.
+func (b *bandwidthLimiter) register(domain string) {
+	bandwidthDomainsExpvar.Set(domain, expvar.Func(func() interface{} {
+		return b.getStatus(time.Now())
+	}))
+}
+
+// domainBandwidthStatus is the status of the limits of a domain reported in the
+// `Bandwidth` expvar.
+type domainBandwidthStatus struct {
+	LimitBytesPerSecond      int
+	ThroughputBytesPerSecond float64
+}
+
+func (b *bandwidthLimiter) getStatus(now time.Time) domainBandwidthStatus {
+	return domainBandwidthStatus{
+		LimitBytesPerSecond:      b.domain.limit(),
+		ThroughputBytesPerSecond: b.meter.rate(now),
+	}
+}
+
+// budgetExhausted returns true when no transaction can be sent until the daily
+// budget is reset.
+func (b *bandwidthLimiter) budgetExhausted(now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	return b.process.budget.exhausted(now)
+}
+
+// deferTransaction records that a transaction was put back in the retry queue
+// because of the daily budget.
+func (b *bandwidthLimiter) deferTransaction() {
+	if b == nil {
+		return
+	}
+	b.process.budget.deferTransaction()
+}
+
+// wait blocks until the payload of the transaction can be sent. It returns false
+// without waiting when the payload does not fit in the daily budget, or when ctx is
+// cancelled while waiting.
+func (b *bandwidthLimiter) wait(ctx context.Context, t transaction.Transaction) bool {
+	if b == nil {
+		return true
+	}
+	size := t.GetPayloadSize()
+	if !b.process.budget.consume(time.Now(), size) {
+		return false
+	}
+	if err := b.domain.wait(ctx, size); err != nil {
+		b.process.budget.refund(size)
+		return false
+	}
+	if err := b.process.limiter.wait(ctx, size); err != nil {
+		b.process.budget.refund(size)
+		return false
+	}
+	now := time.Now()
+	b.meter.add(now, size)
+	b.process.meter.add(now, size)
+	return true
+}
+
+// byteRateLimiter is a token bucket counting bytes. A nil byteRateLimiter does not
+// limit anything.
+type byteRateLimiter struct {
+	limiter *rate.Limiter
+}
+
+func newByteRateLimiter(bytesPerSecond int) *byteRateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &byteRateLimiter{limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), bytesPerSecond)}
+}
+
+func (l *byteRateLimiter) limit() int {
+	if l == nil {
+		return 0
+	}
+	return l.limiter.Burst()
+}
+
+// wait blocks until n bytes can be sent. Payloads bigger than one second worth of
+// bytes are reserved in several chunks.
+func (l *byteRateLimiter) wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	burst := l.limiter.Burst()
+	for n > 0 {
+		chunk := n
+		if chunk > burst {
+			chunk = burst
+		}
+		if err := l.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// dailyBudget bounds the number of bytes sent per day. The budget is reset at local
+// midnight. A nil dailyBudget does not limit anything.
+type dailyBudget struct {
+	m              sync.Mutex
+	limit          int64
+	used           int64
+	resetAt        time.Time
+	deferredCount  int64
+	exhaustedToday bool
+}
+
+func newDailyBudget(limit int64, now time.Time) *dailyBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &dailyBudget{
+		limit:   limit,
+		resetAt: nextMidnight(now),
+	}
+}
+
+func nextMidnight(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, now.Location())
+}
+
+// resetIfNeeded must be called with the lock held.
+func (b *dailyBudget) resetIfNeeded(now time.Time) {
+	if now.Before(b.resetAt) {
+		return
+	}
+	if b.exhaustedToday {
+		log.Infof("The forwarder daily budget of %d bytes is reset, deferred transactions will be sent again", b.limit)
+	}
+	b.used = 0
+	b.exhaustedToday = false
+	b.resetAt = nextMidnight(now)
+}
+
+func (b *dailyBudget) consume(now time.Time, n int) bool {
+	if b == nil {
+		return true
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.resetIfNeeded(now)
+	if b.used+int64(n) > b.limit {
+		if !b.exhaustedToday {
+			log.Warnf("The forwarder daily budget of %d bytes is exhausted, transactions are kept in the retry queue until %s", b.limit, b.resetAt.Format(time.RFC3339))
+		}
+		b.exhaustedToday = true
+		return false
+	}
+	b.used += int64(n)
+	return true
+}
+
+func (b *dailyBudget) refund(n int) {
+	if b == nil {
+		return
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.used -= int64(n)
+	if b.used < 0 {
+		b.used = 0
+	}
+}
+
+// exhausted returns true once a transaction did not fit in today's budget.
+func (b *dailyBudget) exhausted(now time.Time) bool {
+	if b == nil {
+		return false
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.resetIfNeeded(now)
+	return b.exhaustedToday
+}
+
+func (b *dailyBudget) deferTransaction() {
+	if b == nil {
+		return
+	}
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.deferredCount++
+}
+
+func (b *dailyBudget) getStatus(now time.Time) (limit int64, used int64, resetInSeconds float64, deferredCount int64) {
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.resetIfNeeded(now)
+	return b.limit, b.used, b.resetAt.Sub(now).Seconds(), b.deferredCount
+}
+
+// throughputMeter computes the average number of bytes sent per second over the
+// last minute.
+type throughputMeter struct {
+	m       sync.Mutex
+	buckets [throughputWindow]int64
+	seconds [throughputWindow]int64
+}
+
+func newThroughputMeter() *throughputMeter {
+	return &throughputMeter{}
+}
+
+func (t *throughputMeter) add(now time.Time, n int) {
+	second := now.Unix()
+	i := second % throughputWindow
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.seconds[i] != second {
+		t.seconds[i] = second
+		t.buckets[i] = 0
+	}
+	t.buckets[i] += int64(n)
+}
+
+func (t *throughputMeter) rate(now time.Time) float64 {
+	second := now.Unix()
+	t.m.Lock()
+	defer t.m.Unlock()
+	var total int64
+	for i := range t.buckets {
+		if second-t.seconds[i] < throughputWindow {
+			total += t.buckets[i]
+		}
+	}
+	return float64(total) / throughputWindow
+}
@@ -0,0 +1,157 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyBudget(t *testing.T) {
+	a := assert.New(t)
+	now := time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC)
+	b := newDailyBudget(100, now)
+
+	a.True(b.consume(now, 60))
+	a.False(b.exhausted(now))
+	a.False(b.consume(now, 60))
+	a.True(b.exhausted(now))
+
+	b.deferTransaction()
+	limit, used, resetIn, deferred := b.getStatus(now)
+	a.Equal(int64(100), limit)
+	a.Equal(int64(60), used)
+	a.Equal(float64(3600), resetIn)
+	a.Equal(int64(1), deferred)
+
+	b.refund(60)
+	_, used, _, _ = b.getStatus(now)
+	a.Equal(int64(0), used)
+	a.True(b.exhausted(now), "the budget stays exhausted until midnight")
+
+	tomorrow := now.Add(2 * time.Hour)
+	a.False(b.exhausted(tomorrow))
+	a.True(b.consume(tomorrow, 100))
+	_, _, resetIn, _ = b.getStatus(tomorrow)
+	a.Equal(float64(23*3600), resetIn)
+}
+
+func TestDailyBudgetUnlimited(t *testing.T) {
+	b := newDailyBudget(0, time.Now())
+	assert.Nil(t, b)
+	assert.True(t, b.consume(time.Now(), 1<<40))
+	assert.False(t, b.exhausted(time.Now()))
+}
+
+func TestThroughputMeter(t *testing.T) {
+	a := assert.New(t)
+	m := newThroughputMeter()
+	now := time.Unix(1000, 0)
+
+	m.add(now, 600)
+	m.add(now.Add(30*time.Second), 1200)
+	a.Equal(float64(30), m.rate(now.Add(30*time.Second)))
+	a.Equal(float64(20), m.rate(now.Add(time.Minute)))
+	a.Equal(float64(0), m.rate(now.Add(2*time.Minute)))
+
+	// Old buckets are overwritten
+	m.add(now.Add(time.Minute), 60)
+	a.Equal(float64(21), m.rate(now.Add(time.Minute+time.Second)))
+}
+
+func TestByteRateLimiter(t *testing.T) {
+	a := assert.New(t)
+	var unlimited *byteRateLimiter
+	a.NoError(unlimited.wait(context.Background(), 1<<40))
+	a.Equal(0, unlimited.limit())
+
+	l := newByteRateLimiter(1000)
+	a.Equal(1000, l.limit())
+
+	// Payloads bigger than the burst are split in several reservations
+	start := time.Now()
+	a.NoError(l.wait(context.Background(), 1500))
+	a.True(time.Since(start) >= 400*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.Error(l.wait(ctx, 1000))
+}
+
+func TestBandwidthLimiterWait(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	process := newProcessBandwidth(0, 100, now)
+	l := newBandwidthLimiter(0, process)
+
+	tr := newTestTransactionDomainForwarder()
+	for i := 0; i < 100; i++ {
+		a.True(l.wait(context.Background(), tr))
+	}
+	a.False(l.budgetExhausted(now))
+	a.False(l.wait(context.Background(), tr))
+	a.True(l.budgetExhausted(now))
+
+	status := process.getStatus(time.Now())
+	a.Equal(int64(100), status.DailyBudgetUsedBytes)
+	a.True(status.ThroughputBytesPerSecond > 0)
+	a.True(l.getStatus(time.Now()).ThroughputBytesPerSecond > 0)
+
+	// Bytes are given back when the wait is cancelled
+	process = newProcessBandwidth(1, 100, now)
+	l = newBandwidthLimiter(0, process)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	a.False(l.wait(ctx, newTestTransactionDomainForwarder()))
+	a.Equal(int64(0), process.getStatus(time.Now()).DailyBudgetUsedBytes)
+}
+
+func TestWorkerDefersTransactionsOverBudget(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+	w.bandwidth = newBandwidthLimiter(0, newProcessBandwidth(0, 10, time.Now()))
+
+	mock := newTestTransaction()
+	mock.On("GetTarget").Return("")
+	mock.On("GetPayloadSize").Return(20)
+
+	w.Start()
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+
+	mock.AssertNumberOfCalls(t, "Process", 0)
+	assert.Equal(t, mock, retryTransaction)
+	assert.Equal(t, int64(1), w.bandwidth.process.getStatus(time.Now()).DeferredTransactionsCount)
+}
+
+func TestDomainForwarderDefersTransactionsOverBudget(t *testing.T) {
+	forwarder := newDomainForwarderForTest(0)
+	forwarder.bandwidth = newBandwidthLimiter(0, newProcessBandwidth(0, 10, time.Now()))
+	tooBig := newTestTransaction()
+	tooBig.On("GetPayloadSize").Return(20)
+	require.False(t, forwarder.bandwidth.wait(context.Background(), tooBig))
+
+	forwarder.init()
+	forwarder.sendHTTPTransactions(newTestTransactionDomainForwarder())
+	assert.Len(t, forwarder.highPrio, 0)
+	requireLenForwarderRetryQueue(t, forwarder, 1)
+
+	// Deferred transactions stay in the retry queue
+	forwarder.retryTransactions(time.Now())
+	assert.Len(t, forwarder.lowPrio, 0)
+	requireLenForwarderRetryQueue(t, forwarder, 1)
+}
@@ -6,23 +6,209 @@
 package forwarder
 
 import (
+	"errors"
+	"expvar"
+	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/backoff"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
+const (
+	// maxRetryAfter bounds the delay an intake can request with a Retry-After header.
+	maxRetryAfter = 15 * time.Minute
+	// maxRecentTransitions is the number of circuit breaker transitions kept for the status.
+	maxRecentTransitions = 20
+)
+
+var (
+	circuitBreakersExpvar = expvar.Map{}
+	// circuitBreakers holds the circuit breakers of the started domain forwarders.
+	circuitBreakers = &circuitBreakerRegistry{instances: make(map[*blockedEndpoints]struct{})}
+
+	tlmCircuitTransitions = telemetry.NewCounter("forwarder", "circuit_breaker_transitions",
+		[]string{"endpoint", "state"}, "Count of circuit breaker transitions grouped by endpoint and new state")
+	tlmCircuitState = telemetry.NewGauge("forwarder", "circuit_breaker_state",
+		[]string{"endpoint"}, "State of the circuit breaker of an endpoint: 0 closed, 1 open, 2 half-open")
+	tlmCircuitErrors = telemetry.NewCounter("forwarder", "circuit_breaker_errors",
+		[]string{"endpoint", "error_kind"}, "Count of transaction errors grouped by endpoint and kind of error")
+)
+
+func initCircuitBreakerExpvars() {
+	circuitBreakersExpvar.Init()
+	circuitBreakersExpvar.Set("Endpoints", expvar.Func(func() interface{} {
+		return circuitBreakers.endpointsStatus(time.Now())
+	}))
+	circuitBreakersExpvar.Set("RecentTransitions", expvar.Func(func() interface{} {
+		return circuitBreakers.recentTransitions()
+	}))
+	transaction.ForwarderExpvars.Set("CircuitBreakers", &circuitBreakersExpvar)
+}
+
+// circuitBreakerRegistry reports the circuit breakers of the started domain forwarders
+// in the `CircuitBreakers` expvar.
+type circuitBreakerRegistry struct {
+	m         sync.Mutex
+	instances map[*blockedEndpoints]struct{}
+}
+
+func (r *circuitBreakerRegistry) register(e *blockedEndpoints) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.instances[e] = struct{}{}
+}
+
+func (r *circuitBreakerRegistry) unregister(e *blockedEndpoints) {
+	r.m.Lock()
+	defer r.m.Unlock()
+	delete(r.instances, e)
+}
+
+// endpointsStatus returns the status of the endpoints of all the circuit breakers.
+func (r *circuitBreakerRegistry) endpointsStatus(now time.Time) map[string]circuitBreakerStatus {
+	r.m.Lock()
+	defer r.m.Unlock()
+	status := make(map[string]circuitBreakerStatus)
+	for e := range r.instances {
+		e.m.RLock()
+		for endpoint, b := range e.errorPerEndpoint {
+			status[qualifiedKey(endpoint, e.name)] = b.getStatus(now)
+		}
+		e.m.RUnlock()
+	}
+	return status
+}
+
+// recentTransitions returns the most recent transitions of all the circuit breakers,
+// oldest first.
+func (r *circuitBreakerRegistry) recentTransitions() []circuitTransition {
+	r.m.Lock()
+	defer r.m.Unlock()
+	var transitions []circuitTransition
+	for e := range r.instances {
+		e.m.RLock()
+		transitions = append(transitions, e.transitions...)
+		e.m.RUnlock()
+	}
+	sort.SliceStable(transitions, func(i, j int) bool {
+		return transitions[i].Time.Before(transitions[j].Time)
+	})
+	if len(transitions) > maxRecentTransitions {
+		transitions = transitions[len(transitions)-maxRecentTransitions:]
+	}
+	return transitions
+}
+
+// circuitState is the state of the circuit breaker of an endpoint.
+type circuitState int
+
+const (
+	// circuitClosed: transactions are sent to the endpoint.
+	circuitClosed circuitState = iota
+	// circuitOpen: transactions are held back until the backoff delay is over.
+	circuitOpen
+	// circuitHalfOpen: a single transaction is sent to probe the endpoint.
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// errorKind classifies the errors returned when sending a transaction.
+type errorKind int
+
+const (
+	// networkError: the request could not be sent or no response was received.
+	networkError errorKind = iota
+	// serverError: the intake is unavailable or overloaded (5xx, 408 and 429).
+	serverError
+	// clientError: the intake rejected the payload (other 4xx). The intake is
+	// reachable, so these errors do not open the circuit, but the transactions to
+	// the endpoint are still held back for a backoff delay.
+	clientError
+)
+
+func (k errorKind) String() string {
+	switch k {
+	case serverError:
+		return "server"
+	case clientError:
+		return "client"
+	default:
+		return "network"
+	}
+}
+
+// classifyError returns the kind of err and the delay requested by the intake, if any.
+func classifyError(err error) (errorKind, time.Duration) {
+	var httpErr *transaction.HTTPError
+	if !errors.As(err, &httpErr) {
+		return networkError, 0
+	}
+	if !httpErr.IsServerError() {
+		return clientError, 0
+	}
+	return serverError, httpErr.RetryAfter
+}
+
+// circuitTransition is a state change of a circuit breaker, reported in the status.
+type circuitTransition struct {
+	Endpoint  string
+	State     string
+	ErrorKind string `json:",omitempty"`
+	Time      time.Time
+}
+
 type block struct {
-	nbError int
-	until   time.Time
+	nbError       int
+	until         time.Time
+	state         circuitState
+	lastErrorKind errorKind
+	since         time.Time
+	// nbClientError and clientUntil hold back the transactions rejected by the intake
+	// without opening the circuit.
+	nbClientError int
+	clientUntil   time.Time
 }
 
+// circuitBreakerStatus is the status of an endpoint reported in the `CircuitBreakers` expvar.
+type circuitBreakerStatus struct {
+	State            string
+	ErrorCount       int
+	ClientErrorCount int     `json:",omitempty"`
+	LastErrorKind    string  `json:",omitempty"`
+	RetryInSecond    float64 `json:",omitempty"`
+	SinceSecond      float64
+}
+
+// blockedEndpoints is a circuit breaker per endpoint. An error opens the circuit of
+// the endpoint for a jittered exponential backoff delay, or for the delay requested
+// by the intake with a Retry-After header. Once the delay is over a single probe
+// transaction is let through: the circuit is closed if it succeeds and opened again
+// for a longer delay if it fails.
 type blockedEndpoints struct {
+	// name is the name of the forwarder, qualifying the endpoints in the expvars and the telemetry.
+	name             string
 	errorPerEndpoint map[string]*block
-	backoffPolicy    backoff.Policy
-	m                sync.RWMutex
+	// transitions holds the most recent transitions, reported in the status.
+	transitions   []circuitTransition
+	backoffPolicy backoff.Policy
+	probeTimeout  time.Duration
+	m             sync.RWMutex
 }
 
 func newBlockedEndpoints() *blockedEndpoints {
@@ -55,53 +241,217 @@ func newBlockedEndpoints() *blockedEndpoints {
 	return &blockedEndpoints{
 		errorPerEndpoint: make(map[string]*block),
 		backoffPolicy:    backoff.NewPolicy(backoffFactor, backoffBase, backoffMax, recInterval, recoveryReset),
+		// A probe which never reports back, for instance because the worker was
+		// stopped, must not keep the circuit half-open forever.
+		probeTimeout: config.Datadog.GetDuration("forwarder_timeout")*time.Second + time.Second,
 	}
 }
 
+// close records a network error on endpoint, opening its circuit.
 func (e *blockedEndpoints) close(endpoint string) {
+	e.closeWithError(endpoint, networkError, 0)
+}
+
+// fail records the error returned when sending a transaction to endpoint. Network and
+// server errors open the circuit. Client errors prove that the intake is reachable and
+// close the circuit, but the transactions are held back for a backoff delay so that a
+// persistent rejection is not retried on every retry tick.
+func (e *blockedEndpoints) fail(endpoint string, err error) {
+	kind, retryAfter := classifyError(err)
+	tlmCircuitErrors.Inc(qualifiedKey(endpoint, e.name), kind.String())
+	if kind == clientError {
+		e.delay(endpoint)
+		return
+	}
+	e.closeWithError(endpoint, kind, retryAfter)
+}
+
+// delay records a client error on endpoint, holding its transactions back without
+// opening its circuit.
+func (e *blockedEndpoints) delay(endpoint string) {
 	e.m.Lock()
 	defer e.m.Unlock()
 
-	var b *block
-	if knownBlock, ok := e.errorPerEndpoint[endpoint]; ok {
-		b = knownBlock
-	} else {
-		b = &block{}
-	}
+	b := e.getBlock(endpoint)
+	now := time.Now()
+	e.closeCircuit(endpoint, b, now)
+
+	b.nbClientError = e.backoffPolicy.IncError(b.nbClientError)
+	delay := e.getJitteredBackoffDuration(b.nbClientError)
+	b.clientUntil = now.Add(delay)
+	log.Debugf("Transactions to %q rejected %d time(s) by the intake: retrying in %s", endpoint, b.nbClientError, delay)
+}
+
+func (e *blockedEndpoints) closeWithError(endpoint string, kind errorKind, retryAfter time.Duration) {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	b := e.getBlock(endpoint)
+	now := time.Now()
 
 	b.nbError = e.backoffPolicy.IncError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+	b.lastErrorKind = kind
+	delay := e.getJitteredBackoffDuration(b.nbError)
+	if retryAfter > maxRetryAfter {
+		retryAfter = maxRetryAfter
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	b.until = now.Add(delay)
 
-	e.errorPerEndpoint[endpoint] = b
+	if b.state != circuitOpen {
+		log.Warnf("Circuit breaker opened for %q after %d error(s), last one: %s error. Retrying in %s", endpoint, b.nbError, kind, delay)
+	}
+	e.transition(endpoint, b, circuitOpen, now)
 }
 
 func (e *blockedEndpoints) recover(endpoint string) {
 	e.m.Lock()
 	defer e.m.Unlock()
 
-	var b *block
-	if knownBlock, ok := e.errorPerEndpoint[endpoint]; ok {
-		b = knownBlock
-	} else {
-		b = &block{}
-	}
+	b := e.getBlock(endpoint)
+	now := time.Now()
+	e.closeCircuit(endpoint, b, now)
 
+	b.nbClientError = e.backoffPolicy.DecError(b.nbClientError)
+	b.clientUntil = now
+}
+
+// closeCircuit closes the circuit of endpoint. It must be called with the lock held.
+func (e *blockedEndpoints) closeCircuit(endpoint string, b *block, now time.Time) {
+	// The error count decreases step by step so that the backoff delays stay long
+	// if the endpoint keeps failing after a success.
 	b.nbError = e.backoffPolicy.DecError(b.nbError)
-	b.until = time.Now().Add(e.getBackoffDuration(b.nbError))
+	b.until = now
 
-	e.errorPerEndpoint[endpoint] = b
+	if b.state != circuitClosed {
+		log.Infof("Circuit breaker closed for %q", endpoint)
+	}
+	e.transition(endpoint, b, circuitClosed, now)
 }
 
+// isBlock returns whether the transactions to endpoint are currently held back.
 func (e *blockedEndpoints) isBlock(endpoint string) bool {
 	e.m.RLock()
 	defer e.m.RUnlock()
 
-	if b, ok := e.errorPerEndpoint[endpoint]; ok && time.Now().Before(b.until) {
+	if b, ok := e.errorPerEndpoint[endpoint]; ok && b.heldBack(time.Now()) {
 		return true
 	}
 	return false
 }
 
+// heldBack returns whether the transactions to the endpoint b is the block of are
+// held back at now, by an open circuit or by client errors.
+func (b *block) heldBack(now time.Time) bool {
+	return now.Before(b.until) || now.Before(b.clientUntil)
+}
+
+// allow returns whether a transaction can be sent to endpoint. Once the backoff
+// delay of an open circuit is over, the first caller is allowed to send a probe
+// transaction and the circuit becomes half-open until the probe reports back.
+func (e *blockedEndpoints) allow(endpoint string) bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+
+	b, ok := e.errorPerEndpoint[endpoint]
+	if !ok {
+		return true
+	}
+	now := time.Now()
+	if b.heldBack(now) {
+		return false
+	}
+	if b.state != circuitClosed {
+		b.until = now.Add(e.probeTimeout)
+		e.transition(endpoint, b, circuitHalfOpen, now)
+	}
+	return true
+}
+
+// unavailableEndpoints returns the endpoints whose circuit is not closed.
+func (e *blockedEndpoints) unavailableEndpoints() []string {
+	e.m.RLock()
+	defer e.m.RUnlock()
+
+	var endpoints []string
+	for endpoint, b := range e.errorPerEndpoint {
+		if b.state != circuitClosed {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// knownEndpoints returns the number of endpoints tracked by the circuit breaker.
+func (e *blockedEndpoints) knownEndpoints() int {
+	e.m.RLock()
+	defer e.m.RUnlock()
+	return len(e.errorPerEndpoint)
+}
+
+func (e *blockedEndpoints) getBlock(endpoint string) *block {
+	if b, ok := e.errorPerEndpoint[endpoint]; ok {
+		return b
+	}
+	b := &block{since: time.Now()}
+	e.errorPerEndpoint[endpoint] = b
+	return b
+}
+
+// transition changes the state of the circuit of an endpoint. It must be called with
+// the lock held.
+func (e *blockedEndpoints) transition(endpoint string, b *block, state circuitState, now time.Time) {
+	if b.state == state {
+		return
+	}
+	b.state = state
+	b.since = now
+	key := qualifiedKey(endpoint, e.name)
+	tlmCircuitTransitions.Inc(key, state.String())
+	tlmCircuitState.Set(float64(state), key)
+
+	t := circuitTransition{Endpoint: key, State: state.String(), Time: now}
+	if state == circuitOpen {
+		t.ErrorKind = b.lastErrorKind.String()
+	}
+	e.transitions = append(e.transitions, t)
+	if len(e.transitions) > maxRecentTransitions {
+		e.transitions = e.transitions[len(e.transitions)-maxRecentTransitions:]
+	}
+}
+
+// getStatus returns the status of the endpoint b is the block of.
+func (b *block) getStatus(now time.Time) circuitBreakerStatus {
+	status := circuitBreakerStatus{
+		State:            b.state.String(),
+		ErrorCount:       b.nbError,
+		ClientErrorCount: b.nbClientError,
+		SinceSecond:      now.Sub(b.since).Seconds(),
+	}
+	if b.nbError > 0 {
+		status.LastErrorKind = b.lastErrorKind.String()
+	}
+	if b.state == circuitOpen && now.Before(b.until) {
+		status.RetryInSecond = b.until.Sub(now).Seconds()
+	} else if now.Before(b.clientUntil) {
+		status.RetryInSecond = b.clientUntil.Sub(now).Seconds()
+	}
+	return status
+}
+
 func (e *blockedEndpoints) getBackoffDuration(numErrors int) time.Duration {
 	return e.backoffPolicy.GetBackoffDuration(numErrors)
 }
+
+// getJitteredBackoffDuration returns the backoff duration, also jittered once the
+// maximum is reached so that agents blocked by the same outage do not all retry at
+// the same time.
+func (e *blockedEndpoints) getJitteredBackoffDuration(numErrors int) time.Duration {
+	d := e.getBackoffDuration(numErrors)
+	if numErrors >= e.backoffPolicy.MaxErrors {
+		d -= time.Duration(rand.Int63n(int64(d)/4 + 1))
+	}
+	return d
+}
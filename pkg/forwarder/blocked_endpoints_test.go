@@ -6,8 +6,10 @@
 package forwarder
 
 import (
+	"errors"
 	"math"
 	"math/rand"
+	"net/http"
 	"testing"
 	"time"
 
@@ -15,6 +17,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
 )
 
 func init() {
@@ -262,3 +265,129 @@ func TestIsblockUnknown(t *testing.T) {
 
 	assert.False(t, e.isBlock("test"))
 }
+
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+	e := newBlockedEndpoints()
+
+	e.close("test")
+	assert.Equal(t, circuitOpen, e.errorPerEndpoint["test"].state)
+	assert.False(t, e.allow("test"))
+	assert.Equal(t, []string{"test"}, e.unavailableEndpoints())
+
+	// The backoff delay is over: a single probe is let through
+	e.errorPerEndpoint["test"].until = time.Now().Add(-time.Second)
+	assert.False(t, e.isBlock("test"))
+	assert.True(t, e.allow("test"))
+	assert.Equal(t, circuitHalfOpen, e.errorPerEndpoint["test"].state)
+	assert.False(t, e.allow("test"))
+	assert.True(t, e.isBlock("test"))
+
+	// The probe fails: the circuit opens again for a longer delay
+	e.close("test")
+	assert.Equal(t, circuitOpen, e.errorPerEndpoint["test"].state)
+	assert.Equal(t, 2, e.errorPerEndpoint["test"].nbError)
+
+	// The next probe succeeds: the circuit is closed
+	e.errorPerEndpoint["test"].until = time.Now().Add(-time.Second)
+	assert.True(t, e.allow("test"))
+	e.recover("test")
+	assert.Equal(t, circuitClosed, e.errorPerEndpoint["test"].state)
+	assert.True(t, e.allow("test"))
+	assert.True(t, e.allow("test"))
+	assert.Empty(t, e.unavailableEndpoints())
+}
+
+func TestCircuitBreakerRetryAfter(t *testing.T) {
+	e := newBlockedEndpoints()
+
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Minute})
+	b := e.errorPerEndpoint["test"]
+	assert.Equal(t, circuitOpen, b.state)
+	assert.Equal(t, serverError, b.lastErrorKind)
+	assert.True(t, b.until.After(time.Now().Add(4*time.Minute)))
+
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 24 * time.Hour})
+	assert.True(t, b.until.Before(time.Now().Add(maxRetryAfter+time.Second)))
+}
+
+func TestCircuitBreakerErrorKinds(t *testing.T) {
+	e := newBlockedEndpoints()
+
+	// A client error holds the transactions back without opening the circuit
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusNotFound})
+	assert.Equal(t, circuitClosed, e.errorPerEndpoint["test"].state)
+	assert.True(t, e.isBlock("test"))
+	assert.False(t, e.allow("test"))
+	e.recover("test")
+	assert.False(t, e.isBlock("test"))
+
+	e.fail("test", errors.New("connection refused"))
+	assert.Equal(t, circuitOpen, e.errorPerEndpoint["test"].state)
+	assert.Equal(t, networkError, e.errorPerEndpoint["test"].lastErrorKind)
+
+	// A client error proves that the intake is reachable
+	e.errorPerEndpoint["test"].until = time.Now().Add(-time.Second)
+	assert.True(t, e.allow("test"))
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusNotFound})
+	assert.Equal(t, circuitClosed, e.errorPerEndpoint["test"].state)
+}
+
+func TestCircuitBreakerClientErrorBackoff(t *testing.T) {
+	e := newBlockedEndpoints()
+
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusNotFound})
+	b := e.errorPerEndpoint["test"]
+	first := b.clientUntil.Sub(time.Now())
+	assert.True(t, first > 0)
+
+	// A repeated rejection is held back for a longer delay
+	b.clientUntil = time.Now().Add(-time.Second)
+	assert.True(t, e.allow("test"))
+	e.fail("test", &transaction.HTTPError{StatusCode: http.StatusNotFound})
+	assert.True(t, b.clientUntil.Sub(time.Now()) > first)
+	assert.Equal(t, circuitClosed, b.state)
+	assert.Equal(t, 2, b.getStatus(time.Now()).ClientErrorCount)
+}
+
+func TestJitteredBackoffDuration(t *testing.T) {
+	e := newBlockedEndpoints()
+	max := time.Duration(e.backoffPolicy.MaxBackoffTime) * time.Second
+
+	for i := 0; i < 100; i++ {
+		d := e.getJitteredBackoffDuration(e.backoffPolicy.MaxErrors)
+		assert.True(t, d <= max)
+		assert.True(t, d >= max*3/4)
+	}
+}
+
+func TestCircuitBreakerRegistry(t *testing.T) {
+	registry := &circuitBreakerRegistry{instances: make(map[*blockedEndpoints]struct{})}
+	e1 := newBlockedEndpoints()
+	e2 := newBlockedEndpoints()
+	registry.register(e1)
+	registry.register(e2)
+
+	e1.close("endpoint1")
+	e2.close("endpoint2")
+	e2.recover("endpoint2")
+
+	// Each circuit breaker keeps its own transitions
+	require.Len(t, e1.transitions, 1)
+	require.Len(t, e2.transitions, 2)
+
+	status := registry.endpointsStatus(time.Now())
+	require.Len(t, status, 2)
+	assert.Equal(t, "open", status["endpoint1"].State)
+	assert.Equal(t, "closed", status["endpoint2"].State)
+
+	transitions := registry.recentTransitions()
+	require.Len(t, transitions, 3)
+	assert.Equal(t, "endpoint1", transitions[0].Endpoint)
+	assert.Equal(t, "network", transitions[0].ErrorKind)
+	assert.Equal(t, "closed", transitions[2].State)
+
+	// The circuit breakers of the stopped forwarders are no longer reported
+	registry.unregister(e1)
+	assert.NotContains(t, registry.endpointsStatus(time.Now()), "endpoint1")
+	assert.Len(t, registry.recentTransitions(), 2)
+}
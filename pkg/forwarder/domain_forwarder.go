@@ -27,6 +27,7 @@ var (
 type domainForwarder struct {
 	isRetrying                *atomic.Bool
 	domain                    string
+	key                       string // identifies the domain in the expvars and the telemetry
 	numberOfWorkers           int
 	highPrio                  chan transaction.Transaction // use to receive new transactions
 	lowPrio                   chan transaction.Transaction // use to retry transactions
@@ -40,6 +41,8 @@ type domainForwarder struct {
 	m                         sync.Mutex // To control Start/Stop races
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
+	bandwidth                 *bandwidthLimiter
+	clients                   *httpClientFactory
 }
 
 func newDomainForwarder(
@@ -51,6 +54,7 @@ func newDomainForwarder(
 	return &domainForwarder{
 		isRetrying:                atomic.NewBool(false),
 		domain:                    domain,
+		key:                       domain,
 		numberOfWorkers:           numberOfWorkers,
 		retryQueue:                retryQueue,
 		connectionResetInterval:   connectionResetInterval,
@@ -69,6 +73,11 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 	}
 	defer f.isRetrying.Store(false)
 
+	// Transactions stay in the retry queue until the daily budget is reset.
+	if f.bandwidth.budgetExhausted(time.Now()) {
+		return
+	}
+
 	droppedRetryQueueFull := 0
 	droppedWorkerBusy := 0
 
@@ -89,23 +98,23 @@ func (f *domainForwarder) retryTransactions(retryBefore time.Time) {
 			case f.lowPrio <- t:
 				transactionsRetriedByEndpoint.Add(transactionEndpointName, 1)
 				transactionsRetried.Add(1)
-				tlmTxRetried.Inc(f.domain, transactionEndpointName)
+				tlmTxRetried.Inc(f.key, transactionEndpointName)
 			default:
 				dropCount := f.addToTransactionRetryQueue(t)
-				tlmTxRequeued.Inc(f.domain, transactionEndpointName)
+				tlmTxRequeued.Inc(f.key, transactionEndpointName)
 				droppedWorkerBusy += dropCount
 			}
 		} else {
 			dropCount := f.addToTransactionRetryQueue(t)
 			transactionsRequeued.Add(1)
-			tlmTxRequeued.Inc(f.domain, transactionEndpointName)
+			tlmTxRequeued.Inc(f.key, transactionEndpointName)
 			droppedRetryQueueFull += dropCount
 		}
 	}
 
 	transactionCount := f.retryQueue.GetTransactionCount()
 	transactionsRetryQueueSize.Set(int64(transactionCount))
-	tlmTxRetryQueueSize.Set(float64(transactionCount), f.domain)
+	tlmTxRetryQueueSize.Set(float64(transactionCount), f.key)
 
 	if droppedRetryQueueFull+droppedWorkerBusy > 0 {
 		log.Errorf("Dropped %d transactions in this retry attempt:%d for exceeding the retry queue payloads size limit of %d, %d because the workers are too busy",
@@ -123,7 +132,7 @@ func (f *domainForwarder) addToTransactionRetryQueue(t transaction.Transaction)
 		transactionEndpointName := t.GetEndpointName()
 		transaction.TransactionsDroppedByEndpoint.Add(transactionEndpointName, int64(dropCount))
 		transaction.TransactionsDropped.Add(int64(dropCount))
-		transaction.TlmTxDropped.Inc(f.domain, transactionEndpointName)
+		transaction.TlmTxDropped.Inc(f.key, transactionEndpointName)
 	}
 	return dropCount
 }
@@ -134,7 +143,7 @@ func (f *domainForwarder) requeueTransaction(t transaction.Transaction) {
 	transactionsRequeuedByEndpoint.Add(t.GetEndpointName(), 1)
 	transactionsRequeued.Add(1)
 	transactionsRetryQueueSize.Set(int64(retryQueueSize))
-	tlmTxRetryQueueSize.Set(float64(retryQueueSize), f.domain)
+	tlmTxRetryQueueSize.Set(float64(retryQueueSize), f.key)
 }
 
 func (f *domainForwarder) handleFailedTransactions() {
@@ -160,6 +169,9 @@ func (f *domainForwarder) scheduleConnectionResets() {
 		select {
 		case <-ticker.C:
 			log.Debugf("Scheduling reset of connections used for domain: %q", f.domain)
+			if f.clients != nil {
+				f.clients.resetSharedTransport()
+			}
 			for _, worker := range f.workers {
 				worker.ScheduleConnectionReset()
 			}
@@ -196,11 +208,20 @@ func (f *domainForwarder) Start() error {
 	// reset internal state to purge transactions from past starts
 	f.init()
 
+	newClient := NewHTTPClient
+	if f.clients != nil {
+		newClient = f.clients.newClient
+	}
 	for i := 0; i < f.numberOfWorkers; i++ {
-		w := NewWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList)
+		w := newWorker(f.highPrio, f.lowPrio, f.requeuedTransaction, f.blockedList, newClient)
+		w.bandwidth = f.bandwidth
 		w.Start()
 		f.workers = append(f.workers, w)
 	}
+	circuitBreakers.register(f.blockedList)
+	if f.retryQueue != nil {
+		retry.RegisterTransactionRetryQueueStatus(f.key, f.retryQueue)
+	}
 	go f.handleFailedTransactions()
 	if f.connectionResetInterval != 0 {
 		go f.scheduleConnectionResets()
@@ -229,6 +250,10 @@ func (f *domainForwarder) Stop(purgeHighPrio bool) {
 		w.Stop(purgeHighPrio)
 	}
 	f.workers = []*Worker{}
+	circuitBreakers.unregister(f.blockedList)
+	if f.retryQueue != nil {
+		retry.UnregisterTransactionRetryQueueStatus(f.retryQueue)
+	}
 	close(f.highPrio)
 	close(f.lowPrio)
 	close(f.requeuedTransaction)
@@ -245,13 +270,19 @@ func (f *domainForwarder) State() uint32 {
 }
 
 func (f *domainForwarder) sendHTTPTransactions(t transaction.Transaction) {
+	if f.bandwidth.budgetExhausted(time.Now()) {
+		f.bandwidth.deferTransaction()
+		f.requeueTransaction(t)
+		return
+	}
+
 	// We don't want to block the collector if the highPrio queue is full
 	select {
 	case f.highPrio <- t:
 	default:
 		f.addToTransactionRetryQueue(t)
 		highPriorityQueueFull.Add(1)
-		tlmTxHighPriorityQueueFull.Inc(f.domain, t.GetEndpointName())
+		tlmTxHighPriorityQueueFull.Inc(f.key, t.GetEndpointName())
 		log.Debugf("Adding the transaction to the retry queue because the forwarder input queue for %s is full; consider increasing forwarder_num_workers", f.domain)
 	}
 }
@@ -105,6 +105,9 @@ type Options struct {
 	DomainResolvers                map[string]resolver.DomainResolver
 	ConnectionResetInterval        time.Duration
 	CompletionHandler              transaction.HTTPCompletionHandler
+	// Name identifies the forwarder in the expvars and the telemetry of its domains, when
+	// other forwarders send to the same domains.
+	Name string
 }
 
 // SetFeature sets forwarder features in a feature set
@@ -184,6 +187,15 @@ func NewOptionsWithResolvers(domainResolvers map[string]resolver.DomainResolver)
 	return option
 }
 
+// qualifiedKey returns the key of a domain or an endpoint in the expvars and the telemetry,
+// qualified with the name of its forwarder if any.
+func qualifiedKey(key string, forwarderName string) string {
+	if forwarderName == "" {
+		return key
+	}
+	return key + " (" + forwarderName + ")"
+}
+
 // setRetryQueuePayloadsTotalMaxSizeFromQueueMax set `RetryQueuePayloadsTotalMaxSize` from the value
 // of the deprecated settings `forwarder_retry_queue_max_size`
 func (o *Options) setRetryQueuePayloadsTotalMaxSizeFromQueueMax(v int) {
@@ -204,6 +216,7 @@ type DefaultForwarder struct {
 
 	completionHandler transaction.HTTPCompletionHandler
 
+	name                            string
 	agentName                       string
 	queueDurationCapacity           *retry.QueueDurationCapacity
 	retryQueueDurationCapacityMutex sync.Mutex
@@ -223,11 +236,13 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 			validationInterval:    options.APIKeyValidationInterval,
 		},
 		completionHandler: options.CompletionHandler,
+		name:              options.Name,
 		agentName:         agentName,
 	}
 	var optionalRemovalPolicy *retry.FileRemovalPolicy
 	storageMaxSize := config.Datadog.GetInt64("forwarder_storage_max_size_in_bytes")
 	var diskUsageLimit *retry.DiskUsageLimit
+	var fileEncryption *retry.FileEncryption
 
 	// Disk Persistence is a core-only feature for now.
 	if storageMaxSize == 0 {
@@ -252,8 +267,22 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 			log.Debugf("Outdated files removed: %v", strings.Join(filesRemoved, ", "))
 		}
 
-		diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
-		diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
+		// The encryption keys are usually sourced from the secrets backend with `ENC[<handle>]` values.
+		var encryptionErr error
+		if key := config.Datadog.GetString("forwarder_storage_encryption_key"); key != "" {
+			fileEncryption, encryptionErr = retry.NewFileEncryption(key, config.Datadog.GetStringSlice("forwarder_storage_encryption_previous_keys"))
+			if encryptionErr == nil && config.Datadog.GetBool("forwarder_storage_encryption_migrate_plaintext_files") {
+				log.Warnf("The plaintext retry files stored before the encryption was enabled are read once: disable `forwarder_storage_encryption_migrate_plaintext_files` after this restart")
+				fileEncryption.AllowPlaintextMigration()
+			}
+		}
+		if encryptionErr != nil {
+			// Never store transactions unencrypted when the encryption is required.
+			log.Errorf("Retry queue storage on disk is disabled: %v", encryptionErr)
+		} else {
+			diskRatio := config.Datadog.GetFloat64("forwarder_storage_max_disk_ratio")
+			diskUsageLimit = retry.NewDiskUsageLimit(storagePath, filesystem.NewDisk(), storageMaxSize, diskRatio)
+		}
 
 	} else {
 		log.Infof("Retry queue storage on disk is disabled because the feature is unavailable for this process.")
@@ -263,8 +292,11 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 	var queueDiskSpaceUsedList []retry.QueueDiskSpaceUsed
+	processBandwidth := getProcessBandwidth()
+	domainBandwidthLimit := config.Datadog.GetInt("forwarder_domain_bandwidth_limit_bytes_per_second")
 
 	for domain, resolver := range options.DomainResolvers {
+		httpSettings := getDomainHTTPSettings(domain)
 		domain, _ := config.AddAgentVersionToDomain(domain, "app")
 		resolver.SetBaseDomain(domain)
 		if resolver.GetAPIKeys() == nil || len(resolver.GetAPIKeys()) == 0 {
@@ -284,6 +316,7 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				flushToDiskMemRatio,
 				domainFolderPath,
 				diskUsageLimit,
+				fileEncryption,
 				transactionContainerSort,
 				resolver)
 			f.domainResolvers[domain] = resolver
@@ -294,6 +327,11 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 				options.NumberOfWorkers,
 				options.ConnectionResetInterval,
 				domainForwarderSort)
+			fwd.key = qualifiedKey(domain, options.Name)
+			fwd.blockedList.name = options.Name
+			fwd.bandwidth = newBandwidthLimiter(domainBandwidthLimit, processBandwidth)
+			fwd.bandwidth.register(fwd.key)
+			fwd.clients = newHTTPClientFactory(httpSettings)
 			f.domainForwarders[domain] = fwd
 			// Register all alternate domains for each forwarder
 			for _, v := range resolver.GetAlternateDomains() {
@@ -302,6 +340,9 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 		}
 	}
 
+	f.healthChecker.domainForwarders = f.domainForwarders
+	f.healthChecker.primaryDomain = getPrimaryDomain(f.domainForwarders)
+
 	timeInterval := config.Datadog.GetInt("forwarder_retry_queue_capacity_time_interval_sec")
 	if f.agentName != "" {
 		f.queueDurationCapacity = retry.NewQueueDurationCapacity(
@@ -323,6 +364,25 @@ func NewDefaultForwarder(options *Options) *DefaultForwarder {
 	return f
 }
 
+// getPrimaryDomain returns the domain of the main infra endpoint, or the only domain of
+// the forwarder. The other domains are secondary: the forwarder is only degraded when
+// they are unavailable.
+func getPrimaryDomain(domainForwarders map[string]*domainForwarder) string {
+	mainDomain, _ := config.AddAgentVersionToDomain(config.GetMainInfraEndpoint(), "app")
+	if fwd, ok := domainForwarders[mainDomain]; ok {
+		return fwd.domain
+	}
+
+	primaryDomain := ""
+	for _, fwd := range domainForwarders {
+		if primaryDomain != "" && primaryDomain != fwd.domain {
+			return ""
+		}
+		primaryDomain = fwd.domain
+	}
+	return primaryDomain
+}
+
 func getAgentName(options *Options) string {
 	if HasFeature(options.EnabledFeatures, CoreFeatures) {
 		return "core"
@@ -450,8 +510,9 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 					t.CompletionHandler = f.completionHandler
 				}
 
-				tlmTxInputCount.Inc(domain, endpoint.Name)
-				tlmTxInputBytes.Add(float64(t.GetPayloadSize()), domain, endpoint.Name)
+				domainKey := qualifiedKey(domain, f.name)
+				tlmTxInputCount.Inc(domainKey, endpoint.Name)
+				tlmTxInputBytes.Add(float64(t.GetPayloadSize()), domainKey, endpoint.Name)
 				transactionsInputCountByEndpoint.Add(endpoint.Name, 1)
 				transactionsInputBytesByEndpoint.Add(endpoint.Name, int64(t.GetPayloadSize()))
 
@@ -34,6 +34,8 @@ var (
 
 	validateAPIKeyTimeout = 10 * time.Second
 
+	endpointsHealthCheckInterval = 10 * time.Second
+
 	apiKeyStatus = expvar.Map{}
 )
 
@@ -51,7 +53,8 @@ func initForwarderHealthExpvars() {
 }
 
 // forwarderHealth report the health status of the Forwarder. A Forwarder is
-// unhealthy if the API keys are not longer valid
+// unhealthy if the API keys are not longer valid or if every endpoint of the primary
+// domain is unavailable, and degraded if only some endpoints are unavailable.
 type forwarderHealth struct {
 	health                *health.Handle
 	stop                  chan bool
@@ -61,6 +64,9 @@ type forwarderHealth struct {
 	keysPerAPIEndpoint    map[string][]string
 	disableAPIKeyChecking bool
 	validationInterval    time.Duration
+	domainForwarders      map[string]*domainForwarder
+	primaryDomain         string
+	primaryAvailable      bool
 }
 
 func (fh *forwarderHealth) init() {
@@ -117,6 +123,13 @@ func (fh *forwarderHealth) healthCheckLoop() {
 		return
 	}
 
+	endpointsTicker := time.NewTicker(endpointsHealthCheckInterval)
+	defer endpointsTicker.Stop()
+	fh.primaryAvailable = true
+	// Not reading the health channel while the primary domain is unavailable
+	// reports the forwarder as unhealthy.
+	healthC := fh.health.C
+
 	for {
 		select {
 		case <-fh.stop:
@@ -127,9 +140,44 @@ func (fh *forwarderHealth) healthCheckLoop() {
 				log.Errorf("No valid api key found, reporting the forwarder as unhealthy.")
 				return
 			}
-		case <-fh.health.C:
+		case <-endpointsTicker.C:
+			healthC = fh.health.C
+			if !fh.checkEndpoints() {
+				healthC = nil
+			}
+		case <-healthC:
+		}
+	}
+}
+
+// checkEndpoints reports the forwarder as degraded when the circuit breaker of some
+// endpoints is open. It returns false when all the endpoints of the primary domain are
+// unavailable.
+func (fh *forwarderHealth) checkEndpoints() bool {
+	var unavailable []string
+	primaryAvailable := true
+	for domain, fwd := range fh.domainForwarders {
+		if domain != fwd.domain {
+			// alternate domain of a domainForwarder
+			continue
+		}
+		endpoints := fwd.blockedList.unavailableEndpoints()
+		unavailable = append(unavailable, endpoints...)
+		if domain == fh.primaryDomain && len(endpoints) > 0 && len(endpoints) == fwd.blockedList.knownEndpoints() {
+			primaryAvailable = false
+		}
+	}
+
+	if primaryAvailable != fh.primaryAvailable {
+		if primaryAvailable {
+			log.Infof("The endpoints of %q are available again, reporting the forwarder as healthy.", fh.primaryDomain)
+		} else {
+			log.Errorf("No endpoint of %q is available, reporting the forwarder as unhealthy.", fh.primaryDomain)
 		}
+		fh.primaryAvailable = primaryAvailable
 	}
+	fh.health.SetDegraded(primaryAvailable && len(unavailable) > 0)
+	return primaryAvailable
 }
 
 // computeDomainsURL populates a map containing API Endpoints per API keys that belongs to the forwarderHealth struct
@@ -13,6 +13,7 @@ import (
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/status/health"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -120,3 +121,39 @@ func TestHasValidAPIKeyErrors(t *testing.T) {
 	assert.Equal(t, &apiKeyEndpointUnreachable, apiKeyStatus.Get("API key ending with key4"))
 
 }
+
+func TestCheckEndpoints(t *testing.T) {
+	primary := &domainForwarder{domain: "primary", blockedList: newBlockedEndpoints()}
+	secondary := &domainForwarder{domain: "secondary", blockedList: newBlockedEndpoints()}
+	fh := forwarderHealth{
+		health: health.RegisterReadiness("test-forwarder"),
+		domainForwarders: map[string]*domainForwarder{
+			"primary":   primary,
+			"alternate": primary,
+			"secondary": secondary,
+		},
+		primaryDomain:    "primary",
+		primaryAvailable: true,
+	}
+	defer fh.health.Deregister() //nolint:errcheck
+
+	primary.blockedList.recover("primary/series")
+	primary.blockedList.recover("primary/events")
+	assert.True(t, fh.checkEndpoints())
+
+	secondary.blockedList.close("secondary/series")
+	assert.True(t, fh.checkEndpoints(), "secondary endpoints do not make the forwarder unhealthy")
+
+	primary.blockedList.close("primary/series")
+	assert.True(t, fh.checkEndpoints(), "the primary domain still has an available endpoint")
+
+	primary.blockedList.close("primary/events")
+	assert.False(t, fh.checkEndpoints())
+	assert.False(t, fh.primaryAvailable)
+
+	primary.blockedList.recover("primary/series")
+	primary.blockedList.recover("primary/events")
+	secondary.blockedList.recover("secondary/series")
+	assert.True(t, fh.checkEndpoints())
+	assert.True(t, fh.primaryAvailable)
+}
@@ -0,0 +1,167 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// http1Protocol only uses HTTP/1.1, one request at a time per connection.
+	http1Protocol = "http1"
+	// autoProtocol negotiates HTTP/2 with ALPN, falling back to HTTP/1.1 when the
+	// intake or a proxy does not support it.
+	autoProtocol = "auto"
+)
+
+// domainHTTPSettings are the settings of the connections opened to a domain.
+type domainHTTPSettings struct {
+	Domain              string `mapstructure:"domain"`
+	Protocol            string `mapstructure:"http_protocol"`
+	MaxIdleConnsPerHost int    `mapstructure:"max_idle_conns_per_host"`
+	MaxConnsPerHost     int    `mapstructure:"max_conns_per_host"`
+	// IdleConnTimeout and HTTP2HealthCheckInterval are in seconds.
+	IdleConnTimeout          int `mapstructure:"idle_conn_timeout"`
+	HTTP2HealthCheckInterval int `mapstructure:"http2_health_check_interval"`
+}
+
+// getDomainHTTPSettings returns the settings of the connections to domain: the global
+// `forwarder_*` settings, overridden by the `forwarder_domain_http_settings` entry of
+// the domain if any.
+func getDomainHTTPSettings(domain string) domainHTTPSettings {
+	settings := domainHTTPSettings{
+		Domain:                   domain,
+		Protocol:                 config.Datadog.GetString("forwarder_http_protocol"),
+		MaxIdleConnsPerHost:      config.Datadog.GetInt("forwarder_max_idle_conns_per_host"),
+		MaxConnsPerHost:          config.Datadog.GetInt("forwarder_max_conns_per_host"),
+		IdleConnTimeout:          config.Datadog.GetInt("forwarder_idle_conn_timeout"),
+		HTTP2HealthCheckInterval: config.Datadog.GetInt("forwarder_http2_health_check_interval"),
+	}
+
+	var overrides []domainHTTPSettings
+	if err := config.Datadog.UnmarshalKey("forwarder_domain_http_settings", &overrides); err != nil {
+		log.Errorf("Ignoring invalid forwarder_domain_http_settings: %v", err)
+		return settings.validate()
+	}
+	for _, o := range overrides {
+		if o.Domain != domain {
+			continue
+		}
+		if o.Protocol != "" {
+			settings.Protocol = o.Protocol
+		}
+		if o.MaxIdleConnsPerHost != 0 {
+			settings.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+		}
+		if o.MaxConnsPerHost != 0 {
+			settings.MaxConnsPerHost = o.MaxConnsPerHost
+		}
+		if o.IdleConnTimeout != 0 {
+			settings.IdleConnTimeout = o.IdleConnTimeout
+		}
+		if o.HTTP2HealthCheckInterval != 0 {
+			settings.HTTP2HealthCheckInterval = o.HTTP2HealthCheckInterval
+		}
+	}
+	return settings.validate()
+}
+
+func (s domainHTTPSettings) validate() domainHTTPSettings {
+	if s.Protocol != http1Protocol && s.Protocol != autoProtocol {
+		log.Warnf("Unknown HTTP protocol %q for domain %q, using %q", s.Protocol, s.Domain, http1Protocol)
+		s.Protocol = http1Protocol
+	}
+	if s.MaxIdleConnsPerHost < 0 {
+		s.MaxIdleConnsPerHost = 0
+	}
+	if s.MaxConnsPerHost < 0 {
+		s.MaxConnsPerHost = 0
+	}
+	return s
+}
+
+// newTransport returns a transport configured with the settings.
+func (s domainHTTPSettings) newTransport() *http.Transport {
+	transport := httputils.CreateHTTPTransport()
+	transport.MaxIdleConnsPerHost = s.MaxIdleConnsPerHost
+	transport.MaxConnsPerHost = s.MaxConnsPerHost
+	if s.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = time.Duration(s.IdleConnTimeout) * time.Second
+	}
+
+	if s.Protocol == autoProtocol {
+		// The custom DialContext and TLSClientConfig of the agent transports disable
+		// the automatic HTTP/2 support of net/http, so it is configured explicitly.
+		t2, err := http2.ConfigureTransports(transport)
+		if err != nil {
+			log.Warnf("Cannot enable HTTP/2 for domain %q, using HTTP/1.1: %v", s.Domain, err)
+			return transport
+		}
+		// Ping the connections without activity to detect and close the broken
+		// ones: as many requests share an HTTP/2 connection, a dead one hurts more.
+		t2.ReadIdleTimeout = time.Duration(s.HTTP2HealthCheckInterval) * time.Second
+	}
+	return transport
+}
+
+// httpClientFactory creates the HTTP clients of the workers of a domain. With HTTP/1.1
+// each worker has its own transport as a connection carries one request at a time.
+// With HTTP/2 the workers share a transport, so that their requests are multiplexed
+// on the same connections.
+type httpClientFactory struct {
+	settings domainHTTPSettings
+	timeout  time.Duration
+
+	m         sync.Mutex
+	transport *http.Transport
+}
+
+func newHTTPClientFactory(settings domainHTTPSettings) *httpClientFactory {
+	return &httpClientFactory{
+		settings: settings,
+		timeout:  config.Datadog.GetDuration("forwarder_timeout") * time.Second,
+	}
+}
+
+// newClient returns a new client for a worker.
+func (f *httpClientFactory) newClient() *http.Client {
+	var transport *http.Transport
+	if f.settings.Protocol == http1Protocol {
+		transport = f.settings.newTransport()
+	} else {
+		f.m.Lock()
+		if f.transport == nil {
+			f.transport = f.settings.newTransport()
+		}
+		transport = f.transport
+		f.m.Unlock()
+	}
+
+	return &http.Client{
+		Timeout:   f.timeout,
+		Transport: transport,
+	}
+}
+
+// resetSharedTransport drops the shared transport, if any, so that the clients created
+// afterwards open new connections. The connections of the previous transport are closed
+// once idle.
+func (f *httpClientFactory) resetSharedTransport() {
+	f.m.Lock()
+	defer f.m.Unlock()
+	if f.transport != nil {
+		f.transport.CloseIdleConnections()
+		f.transport = nil
+	}
+}
@@ -0,0 +1,101 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetDomainHTTPSettings(t *testing.T) {
+	mockConfig := config.Mock()
+	defer mockConfig.Set("forwarder_http_protocol", "http1")
+	defer mockConfig.Set("forwarder_domain_http_settings", nil)
+
+	settings := getDomainHTTPSettings("https://app.datadoghq.com")
+	assert.Equal(t, http1Protocol, settings.Protocol)
+	assert.Equal(t, 5, settings.MaxIdleConnsPerHost)
+	assert.Equal(t, 0, settings.MaxConnsPerHost)
+	assert.Equal(t, 90, settings.IdleConnTimeout)
+
+	mockConfig.Set("forwarder_http_protocol", "http3")
+	assert.Equal(t, http1Protocol, getDomainHTTPSettings("https://app.datadoghq.com").Protocol)
+
+	mockConfig.Set("forwarder_http_protocol", autoProtocol)
+	mockConfig.Set("forwarder_domain_http_settings", []map[string]interface{}{
+		{"domain": "https://app.datadoghq.eu", "http_protocol": "http1", "max_conns_per_host": 4},
+	})
+	settings = getDomainHTTPSettings("https://app.datadoghq.com")
+	assert.Equal(t, autoProtocol, settings.Protocol)
+	assert.Equal(t, 0, settings.MaxConnsPerHost)
+
+	settings = getDomainHTTPSettings("https://app.datadoghq.eu")
+	assert.Equal(t, http1Protocol, settings.Protocol)
+	assert.Equal(t, 4, settings.MaxConnsPerHost)
+	assert.Equal(t, 5, settings.MaxIdleConnsPerHost)
+}
+
+func TestHTTPClientFactoryHTTP1(t *testing.T) {
+	f := newHTTPClientFactory(domainHTTPSettings{Protocol: http1Protocol, MaxIdleConnsPerHost: 2, MaxConnsPerHost: 3})
+
+	c1, c2 := f.newClient(), f.newClient()
+	assert.NotSame(t, c1.Transport, c2.Transport)
+	transport := c1.Transport.(*http.Transport)
+	assert.Equal(t, 2, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, 3, transport.MaxConnsPerHost)
+	assert.Empty(t, transport.TLSNextProto)
+}
+
+func TestHTTPClientFactoryHTTP2(t *testing.T) {
+	mockConfig := config.Mock()
+	defer mockConfig.Set("skip_ssl_validation", false)
+	mockConfig.Set("skip_ssl_validation", true)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	f := newHTTPClientFactory(domainHTTPSettings{Protocol: autoProtocol, HTTP2HealthCheckInterval: 30})
+	c1, c2 := f.newClient(), f.newClient()
+	assert.Same(t, c1.Transport, c2.Transport, "the workers share the HTTP/2 connections")
+
+	resp, err := c1.Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/2.0", resp.Proto)
+
+	f.resetSharedTransport()
+	c3 := f.newClient()
+	assert.NotSame(t, c1.Transport, c3.Transport)
+	assert.Same(t, c3.Transport, f.newClient().Transport)
+}
+
+func TestHTTPClientFactoryHTTP2Fallback(t *testing.T) {
+	mockConfig := config.Mock()
+	defer mockConfig.Set("skip_ssl_validation", false)
+	mockConfig.Set("skip_ssl_validation", true)
+
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	f := newHTTPClientFactory(domainHTTPSettings{Protocol: autoProtocol})
+	resp, err := f.newClient().Get(ts.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, "HTTP/1.1", resp.Proto)
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// encryptedFileMagic starts every encrypted retry file. The version allows changing
+// the format of the files later on.
+var encryptedFileMagic = []byte("DDRETRY\x01")
+
+const keyIDSize = 8
+
+// errTamperedFile is returned when a retry file cannot be authenticated.
+var errTamperedFile = errors.New("the retry file cannot be authenticated and was discarded: it is corrupted, was modified or was encrypted with an unknown key")
+
+// FileEncryption encrypts and authenticates the retry files with AES-GCM.
+//
+// An encrypted file is made of:
+// - encryptedFileMagic
+// - the identifier of the key, which is the truncated SHA-256 of the key
+// - the nonce
+// - the encrypted transactions followed by the GCM tag
+// The magic and the key identifier are authenticated as additional data.
+//
+// Files are always encrypted with the current key. Previous keys are only used to
+// decrypt files written before a key rotation. Plaintext files are discarded, unless
+// their migration is enabled with AllowPlaintextMigration.
+type FileEncryption struct {
+	currentKeyID     [keyIDSize]byte
+	aeads            map[[keyIDSize]byte]cipher.AEAD
+	plaintextAllowed bool
+}
+
+// NewFileEncryption creates a new instance of FileEncryption. Keys are base64 encoded
+// AES keys of 16, 24 or 32 bytes.
+func NewFileEncryption(currentKey string, previousKeys []string) (*FileEncryption, error) {
+	e := &FileEncryption{
+		aeads: make(map[[keyIDSize]byte]cipher.AEAD),
+	}
+	currentKeyID, err := e.addKey(currentKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %v", err)
+	}
+	e.currentKeyID = currentKeyID
+	for i, key := range previousKeys {
+		if _, err := e.addKey(key); err != nil {
+			return nil, fmt.Errorf("invalid previous encryption key #%d: %v", i, err)
+		}
+	}
+	return e, nil
+}
+
+// AllowPlaintextMigration allows reading the plaintext files found on disk when the retry
+// queues are created, which were written before the encryption was enabled. The files
+// written later on must always be encrypted.
+func (e *FileEncryption) AllowPlaintextMigration() {
+	e.plaintextAllowed = true
+}
+
+func (e *FileEncryption) addKey(encodedKey string) ([keyIDSize]byte, error) {
+	var keyID [keyIDSize]byte
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return keyID, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return keyID, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return keyID, err
+	}
+	hash := sha256.Sum256(key)
+	copy(keyID[:], hash[:keyIDSize])
+	e.aeads[keyID] = aead
+	return keyID, nil
+}
+
+func (e *FileEncryption) encrypt(plaintext []byte) ([]byte, error) {
+	aead := e.aeads[e.currentKeyID]
+	headerSize := len(encryptedFileMagic) + keyIDSize
+	out := make([]byte, headerSize+aead.NonceSize(), headerSize+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	copy(out, encryptedFileMagic)
+	copy(out[len(encryptedFileMagic):], e.currentKeyID[:])
+	nonce := out[headerSize:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, plaintext, out[:headerSize]), nil
+}
+
+func (e *FileEncryption) decrypt(data []byte) ([]byte, error) {
+	headerSize := len(encryptedFileMagic) + keyIDSize
+	if !isEncryptedFile(data) || len(data) < headerSize {
+		return nil, errTamperedFile
+	}
+	var keyID [keyIDSize]byte
+	copy(keyID[:], data[len(encryptedFileMagic):headerSize])
+	aead, ok := e.aeads[keyID]
+	if !ok || len(data) < headerSize+aead.NonceSize() {
+		return nil, errTamperedFile
+	}
+	nonce := data[headerSize : headerSize+aead.NonceSize()]
+	plaintext, err := aead.Open(nil, nonce, data[headerSize+aead.NonceSize():], data[:headerSize])
+	if err != nil {
+		return nil, errTamperedFile
+	}
+	return plaintext, nil
+}
+
+func isEncryptedFile(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedFileMagic)
+}
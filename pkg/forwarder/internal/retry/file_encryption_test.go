@@ -0,0 +1,204 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package retry
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/config/resolver"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testKey1 = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	testKey2 = base64.StdEncoding.EncodeToString([]byte("fedcba9876543210"))
+)
+
+func TestNewFileEncryption(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := NewFileEncryption(testKey1, []string{testKey2})
+	a.NoError(err)
+
+	_, err = NewFileEncryption("not base64", nil)
+	a.Error(err)
+	_, err = NewFileEncryption(base64.StdEncoding.EncodeToString([]byte("too short")), nil)
+	a.Error(err)
+	_, err = NewFileEncryption(testKey1, []string{""})
+	a.Error(err)
+}
+
+func TestFileEncryption(t *testing.T) {
+	a := assert.New(t)
+	e, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+
+	plaintext := []byte("transactions")
+	encrypted, err := e.encrypt(plaintext)
+	a.NoError(err)
+	a.True(isEncryptedFile(encrypted))
+	a.NotContains(string(encrypted), string(plaintext))
+
+	decrypted, err := e.decrypt(encrypted)
+	a.NoError(err)
+	a.Equal(plaintext, decrypted)
+
+	// Nonces are random
+	other, err := e.encrypt(plaintext)
+	a.NoError(err)
+	a.NotEqual(encrypted, other)
+
+	// Any modification is detected
+	for _, i := range []int{0, len(encryptedFileMagic), len(encryptedFileMagic) + keyIDSize, len(encrypted) - 1} {
+		tampered := append([]byte{}, encrypted...)
+		tampered[i] ^= 1
+		_, err = e.decrypt(tampered)
+		a.Equal(errTamperedFile, err, "byte %d", i)
+	}
+	_, err = e.decrypt(encrypted[:len(encryptedFileMagic)+keyIDSize+2])
+	a.Equal(errTamperedFile, err)
+	_, err = e.decrypt(plaintext)
+	a.Equal(errTamperedFile, err)
+}
+
+func TestFileEncryptionKeyRotation(t *testing.T) {
+	a := assert.New(t)
+	old, err := NewFileEncryption(testKey2, nil)
+	a.NoError(err)
+	encrypted, err := old.encrypt([]byte("transactions"))
+	a.NoError(err)
+
+	rotated, err := NewFileEncryption(testKey1, []string{testKey2})
+	a.NoError(err)
+	decrypted, err := rotated.decrypt(encrypted)
+	a.NoError(err)
+	a.Equal([]byte("transactions"), decrypted)
+
+	// Files are written with the current key only
+	encrypted, err = rotated.encrypt([]byte("transactions"))
+	a.NoError(err)
+	_, err = old.decrypt(encrypted)
+	a.Equal(errTamperedFile, err)
+
+	// Without the previous key, old files cannot be read
+	current, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+	encrypted, err = old.encrypt([]byte("transactions"))
+	a.NoError(err)
+	_, err = current.decrypt(encrypted)
+	a.Equal(errTamperedFile, err)
+}
+
+func TestOnDiskRetryQueueEncryption(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	encryption, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+	q := newTestEncryptedOnDiskRetryQueue(a, path, encryption)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint3")))
+
+	for _, f := range q.files {
+		content, err := ioutil.ReadFile(f.name)
+		a.NoError(err)
+		a.True(isEncryptedFile(content))
+		a.NotContains(string(content), "endpoint")
+	}
+
+	// Tamper the newest file
+	content, err := ioutil.ReadFile(q.files[1].name)
+	a.NoError(err)
+	content[len(content)-1] ^= 1
+	a.NoError(ioutil.WriteFile(q.files[1].name, content, 0600))
+
+	_, err = q.Deserialize()
+	a.Error(err)
+	a.Equal(1, q.GetFilesCount(), "the tampered file is discarded")
+
+	// Files are read back after a key rotation
+	rotated, err := NewFileEncryption(testKey2, []string{testKey1})
+	a.NoError(err)
+	q = newTestEncryptedOnDiskRetryQueue(a, path, rotated)
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
+}
+
+func TestOnDiskRetryQueueEncryptedFileWithoutKey(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	encryption, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+	q := newTestEncryptedOnDiskRetryQueue(a, path, encryption)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1")))
+
+	q = newTestEncryptedOnDiskRetryQueue(a, path, nil)
+	_, err = q.Deserialize()
+	a.Error(err)
+	a.Equal(0, q.GetFilesCount())
+}
+
+func TestOnDiskRetryQueuePlaintextFileWithKey(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	// A file written before the encryption was enabled, or injected
+	q := newTestEncryptedOnDiskRetryQueue(a, path, nil)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+
+	encryption, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+	q = newTestEncryptedOnDiskRetryQueue(a, path, encryption)
+	_, err = q.Deserialize()
+	a.Error(err)
+	a.Equal(0, q.GetFilesCount(), "the plaintext file is discarded")
+}
+
+func TestOnDiskRetryQueuePlaintextMigration(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	// A file written before the encryption was enabled
+	q := newTestEncryptedOnDiskRetryQueue(a, path, nil)
+	a.NoError(q.Serialize(createHTTPTransactionCollectionTests("endpoint1", "endpoint2")))
+
+	encryption, err := NewFileEncryption(testKey1, nil)
+	a.NoError(err)
+	encryption.AllowPlaintextMigration()
+	q = newTestEncryptedOnDiskRetryQueue(a, path, encryption)
+	transactions, err := q.Deserialize()
+	a.NoError(err)
+	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
+	a.Equal(0, q.GetFilesCount())
+
+	// A plaintext file written once the queue is created is still discarded
+	plaintext := newTestEncryptedOnDiskRetryQueue(a, path, nil)
+	a.NoError(plaintext.Serialize(createHTTPTransactionCollectionTests("endpoint3")))
+	q.files = append(q.files, plaintext.files...)
+	_, err = q.Deserialize()
+	a.Error(err)
+}
+
+func newTestEncryptedOnDiskRetryQueue(a *assert.Assertions, path string, encryption *FileEncryption) *onDiskRetryQueue {
+	disk := diskUsageRetrieverMock{
+		diskUsage: &filesystem.DiskUsage{
+			Available: 10000,
+			Total:     10000,
+		}}
+	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
+	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, encryption, newOnDiskRetryQueueTelemetry("domain"))
+	a.NoError(err)
+	return q
+}
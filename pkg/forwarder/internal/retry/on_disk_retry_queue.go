@@ -12,6 +12,7 @@ import (
 	"path"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
@@ -22,19 +23,35 @@ import (
 const retryTransactionsExtension = ".retry"
 const retryFileFormat = "2006_01_02__15_04_05_"
 
+// highPriorityFileSuffix marks the files containing at least one transaction with
+// the priority `TransactionPriorityHigh`. Such files are removed last when the disk
+// limit is reached.
+const highPriorityFileSuffix = "_high"
+
 type onDiskRetryQueue struct {
 	serializer         *HTTPTransactionsSerializer
 	storagePath        string
 	diskUsageLimit     *DiskUsageLimit
-	filenames          []string
+	files              []retryFile
 	currentSizeInBytes int64
+	optionalEncryption *FileEncryption
 	telemetry          onDiskRetryQueueTelemetry
 }
 
+type retryFile struct {
+	name     string
+	priority transaction.Priority
+	// oldest is the creation time of the oldest transaction stored in the file.
+	oldest time.Time
+	// plaintextAllowed is true when the file can be read unencrypted although an encryption key is configured.
+	plaintextAllowed bool
+}
+
 func newOnDiskRetryQueue(
 	serializer *HTTPTransactionsSerializer,
 	storagePath string,
 	diskUsageLimit *DiskUsageLimit,
+	optionalEncryption *FileEncryption,
 	telemetry onDiskRetryQueueTelemetry) (*onDiskRetryQueue, error) {
 
 	if err := os.MkdirAll(storagePath, 0700); err != nil {
@@ -42,10 +59,11 @@ func newOnDiskRetryQueue(
 	}
 
 	storage := &onDiskRetryQueue{
-		serializer:     serializer,
-		storagePath:    storagePath,
-		diskUsageLimit: diskUsageLimit,
-		telemetry:      telemetry,
+		serializer:         serializer,
+		storagePath:        storagePath,
+		diskUsageLimit:     diskUsageLimit,
+		optionalEncryption: optionalEncryption,
+		telemetry:          telemetry,
 	}
 
 	if err := storage.reloadExistingRetryFiles(); err != nil {
@@ -67,24 +85,40 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 	// but `GetBytesAndReset` was not called because of an error.
 	_, _ = s.serializer.GetBytesAndReset()
 
+	priority := transaction.TransactionPriorityNormal
+	var oldest time.Time
 	for _, t := range transactions {
 		if err := t.SerializeTo(s.serializer); err != nil {
 			return err
 		}
+		if t.GetPriority() > priority {
+			priority = t.GetPriority()
+		}
+		if oldest.IsZero() || t.GetCreatedAt().Before(oldest) {
+			oldest = t.GetCreatedAt()
+		}
 	}
 
 	bytes, err := s.serializer.GetBytesAndReset()
 	if err != nil {
 		return err
 	}
+	if s.optionalEncryption != nil {
+		if bytes, err = s.optionalEncryption.encrypt(bytes); err != nil {
+			return err
+		}
+	}
 	bufferSize := int64(len(bytes))
 
-	if err := s.makeRoomFor(bufferSize); err != nil {
+	if err := s.makeRoomFor(bufferSize, priority); err != nil {
 		return err
 	}
 
-	filename := time.Now().UTC().Format(retryFileFormat)
-	file, err := ioutil.TempFile(s.storagePath, filename+"*"+retryTransactionsExtension)
+	filename := time.Now().UTC().Format(retryFileFormat) + "*"
+	if priority >= transaction.TransactionPriorityHigh {
+		filename += highPriorityFileSuffix
+	}
+	file, err := ioutil.TempFile(s.storagePath, filename+retryTransactionsExtension)
 	if err != nil {
 		return err
 	}
@@ -96,21 +130,22 @@ func (s *onDiskRetryQueue) Serialize(transactions []transaction.Transaction) err
 	defer file.Close()
 
 	s.currentSizeInBytes += bufferSize
-	s.filenames = append(s.filenames, file.Name())
+	s.files = append(s.files, retryFile{name: file.Name(), priority: priority, oldest: oldest})
 	s.telemetry.setFileSize(bufferSize)
 	s.telemetry.setCurrentSizeInBytes(s.GetDiskSpaceUsed())
-	s.telemetry.setFilesCount(s.getFilesCount())
+	s.telemetry.setFilesCount(s.GetFilesCount())
 	return nil
 }
 
 // Deserialize deserializes a transactions from the file system.
 func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
-	if len(s.filenames) == 0 {
+	if len(s.files) == 0 {
 		return nil, nil
 	}
 	s.telemetry.addDeserializeCount()
-	index := len(s.filenames) - 1
-	path := s.filenames[index]
+	index := len(s.files) - 1
+	path := s.files[index].name
+	plaintextAllowed := s.files[index].plaintextAllowed
 	bytes, err := ioutil.ReadFile(path)
 
 	// Remove the file even in case of a read failure.
@@ -122,6 +157,21 @@ func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
 		return nil, err
 	}
 
+	if isEncryptedFile(bytes) {
+		if s.optionalEncryption == nil {
+			s.telemetry.addTamperedFilesCount()
+			return nil, fmt.Errorf("the retry file %s is encrypted but no encryption key is configured, it was discarded", path)
+		}
+		if bytes, err = s.optionalEncryption.decrypt(bytes); err != nil {
+			s.telemetry.addTamperedFilesCount()
+			return nil, fmt.Errorf("%v: %s", err, path)
+		}
+	} else if s.optionalEncryption != nil && !plaintextAllowed {
+		// An unencrypted file cannot be authenticated: it may have been injected or modified.
+		s.telemetry.addTamperedFilesCount()
+		return nil, fmt.Errorf("the retry file %s is not encrypted but an encryption key is configured, it was discarded", path)
+	}
+
 	transactions, errorsCount, err := s.serializer.Deserialize(bytes)
 	if err != nil {
 		return nil, err
@@ -129,13 +179,25 @@ func (s *onDiskRetryQueue) Deserialize() ([]transaction.Transaction, error) {
 	s.telemetry.addDeserializeErrorsCount(errorsCount)
 	s.telemetry.addDeserializeTransactionsCount(len(transactions))
 	s.telemetry.setCurrentSizeInBytes(s.GetDiskSpaceUsed())
-	s.telemetry.setFilesCount(s.getFilesCount())
+	s.telemetry.setFilesCount(s.GetFilesCount())
 	return transactions, err
 }
 
-// GetFileCount returns the current files count.
-func (s *onDiskRetryQueue) getFilesCount() int {
-	return len(s.filenames)
+// GetFilesCount returns the current files count.
+func (s *onDiskRetryQueue) GetFilesCount() int {
+	return len(s.files)
+}
+
+// GetOldestTransactionTime returns the creation time of the oldest transaction stored on disk
+// or the zero time if there is no file.
+func (s *onDiskRetryQueue) GetOldestTransactionTime() time.Time {
+	var oldest time.Time
+	for _, f := range s.files {
+		if oldest.IsZero() || f.oldest.Before(oldest) {
+			oldest = f.oldest
+		}
+	}
+	return oldest
 }
 
 // GetDiskSpaceUsed() returns the current disk space used.
@@ -143,7 +205,10 @@ func (s *onDiskRetryQueue) GetDiskSpaceUsed() int64 {
 	return s.currentSizeInBytes
 }
 
-func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
+// makeRoomFor removes files until `bufferSize` bytes can be stored. The oldest files
+// with the lowest priority are removed first. Files with a priority higher than `priority`
+// are never removed to store lower priority transactions.
+func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64, priority transaction.Priority) error {
 	maxSizeInBytes := s.diskUsageLimit.getMaxSizeInBytes()
 	if bufferSize > maxSizeInBytes {
 		return fmt.Errorf("The payload is too big. Current:%v Maximum:%v", bufferSize, maxSizeInBytes)
@@ -153,9 +218,12 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	if err != nil {
 		return err
 	}
-	for len(s.filenames) > 0 && s.currentSizeInBytes+bufferSize > maxStorageInBytes {
-		index := 0
-		filename := s.filenames[index]
+	for len(s.files) > 0 && s.currentSizeInBytes+bufferSize > maxStorageInBytes {
+		index := s.lowestPriorityFileIndex()
+		if s.files[index].priority > priority {
+			return fmt.Errorf("Maximum disk space for retry transactions is reached by transactions with a higher priority")
+		}
+		filename := s.files[index].name
 		log.Errorf("Maximum disk space for retry transactions is reached. Removing %s", filename)
 		if err := s.removeFileAt(index); err != nil {
 			return err
@@ -166,12 +234,24 @@ func (s *onDiskRetryQueue) makeRoomFor(bufferSize int64) error {
 	return nil
 }
 
+// lowestPriorityFileIndex returns the index of the oldest file with the lowest priority.
+// There must be at least one file.
+func (s *onDiskRetryQueue) lowestPriorityFileIndex() int {
+	index := 0
+	for i, f := range s.files {
+		if f.priority < s.files[index].priority {
+			index = i
+		}
+	}
+	return index
+}
+
 func (s *onDiskRetryQueue) removeFileAt(index int) error {
-	filename := s.filenames[index]
+	filename := s.files[index].name
 
-	// Remove the file from s.filenames also in case of error to not
+	// Remove the file from s.files also in case of error to not
 	// fail on the next call.
-	s.filenames = append(s.filenames[:index], s.filenames[index+1:]...)
+	s.files = append(s.files[:index], s.files[index+1:]...)
 
 	size, err := util.GetFileSize(filename)
 	if err != nil {
@@ -196,13 +276,22 @@ func (s *onDiskRetryQueue) reloadExistingRetryFiles() error {
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].ModTime().Before(files[j].ModTime())
 	})
-	var filenames []string
+	var retryFiles []retryFile
 	for _, file := range files {
-		fullPath := path.Join(s.storagePath, file.Name())
-		filenames = append(filenames, fullPath)
+		priority := transaction.TransactionPriorityNormal
+		if strings.HasSuffix(file.Name(), highPriorityFileSuffix+retryTransactionsExtension) {
+			priority = transaction.TransactionPriorityHigh
+		}
+		retryFiles = append(retryFiles, retryFile{
+			name:     path.Join(s.storagePath, file.Name()),
+			priority: priority,
+			oldest:   file.ModTime(),
+			// Only the files written before the agent started can be migrated from plaintext.
+			plaintextAllowed: s.optionalEncryption != nil && s.optionalEncryption.plaintextAllowed,
+		})
 	}
-	s.telemetry.setReloadedRetryFilesCount(len(filenames))
-	s.filenames = append(s.filenames, filenames...)
+	s.telemetry.setReloadedRetryFilesCount(len(retryFiles))
+	s.files = append(s.files, retryFiles...)
 	return nil
 }
 
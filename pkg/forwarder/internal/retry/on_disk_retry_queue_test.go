@@ -29,7 +29,7 @@ func TestOnDiskRetryQueue(t *testing.T) {
 	a.NoError(err)
 	err = q.Serialize(createHTTPTransactionCollectionTests("endpoint3", "endpoint4"))
 	a.NoError(err)
-	a.Equal(2, q.getFilesCount())
+	a.Equal(2, q.GetFilesCount())
 
 	transactions, err := q.Deserialize()
 	a.NoError(err)
@@ -39,7 +39,7 @@ func TestOnDiskRetryQueue(t *testing.T) {
 	transactions, err = q.Deserialize()
 	a.NoError(err)
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
-	a.Equal(0, q.getFilesCount())
+	a.Equal(0, q.GetFilesCount())
 	a.Equal(int64(0), q.GetDiskSpaceUsed())
 }
 
@@ -63,7 +63,7 @@ func TestOnDiskRetryQueueMaxSize(t *testing.T) {
 		a.NoError(err)
 	}
 	a.LessOrEqual(q.GetDiskSpaceUsed(), maxSizeInBytes)
-	a.Equal(maxNumberOfFiles, q.getFilesCount())
+	a.Equal(maxNumberOfFiles, q.GetFilesCount())
 
 	for i--; i >= fileToDrop; i-- {
 		transactions, err := q.Deserialize()
@@ -71,7 +71,7 @@ func TestOnDiskRetryQueueMaxSize(t *testing.T) {
 		a.Equal([]string{strconv.Itoa(i)}, getEndpointsFromTransactions(transactions))
 	}
 
-	a.Equal(0, q.getFilesCount())
+	a.Equal(0, q.GetFilesCount())
 }
 
 func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
@@ -85,12 +85,55 @@ func TestOnDiskRetryQueueReloadExistingRetryFiles(t *testing.T) {
 
 	newRetryQueue := newTestOnDiskRetryQueue(a, path, 1000)
 	a.Equal(retryQueue.GetDiskSpaceUsed(), newRetryQueue.GetDiskSpaceUsed())
-	a.Equal(retryQueue.getFilesCount(), newRetryQueue.getFilesCount())
+	a.Equal(retryQueue.GetFilesCount(), newRetryQueue.GetFilesCount())
 	transactions, err := newRetryQueue.Deserialize()
 	a.NoError(err)
 	a.Equal([]string{"endpoint1", "endpoint2"}, getEndpointsFromTransactions(transactions))
 }
 
+func TestOnDiskRetryQueueMaxSizeHonorsPriority(t *testing.T) {
+	a := assert.New(t)
+	path, clean := createTmpFolder(a)
+	defer clean()
+
+	maxSizeInBytes := int64(100)
+	q := newTestOnDiskRetryQueue(a, path, maxSizeInBytes)
+
+	highPriority := func(endpoint string) []transaction.Transaction {
+		transactions := createHTTPTransactionCollectionTests(endpoint)
+		transactions[0].(*transaction.HTTPTransaction).Priority = transaction.TransactionPriorityHigh
+		return transactions
+	}
+	a.NoError(q.Serialize(highPriority("high")))
+	maxNumberOfFiles := int(maxSizeInBytes / q.GetDiskSpaceUsed())
+	a.Greaterf(maxNumberOfFiles, 2, "Not enough files for this test, increase maxSizeInBytes")
+
+	for i := 1; i < 2*maxNumberOfFiles; i++ {
+		a.NoError(q.Serialize(createHTTPTransactionCollectionTests(strconv.Itoa(i))))
+	}
+	// The oldest normal priority files were removed instead of the high priority one.
+	a.LessOrEqual(q.GetDiskSpaceUsed(), maxSizeInBytes)
+	a.Less(q.GetFilesCount(), 2*maxNumberOfFiles)
+	a.Equal(transaction.TransactionPriorityHigh, q.files[0].priority)
+
+	// The high priority file is kept when reloading files.
+	reloaded := newTestOnDiskRetryQueue(a, path, maxSizeInBytes)
+	a.Equal(q.GetFilesCount(), reloaded.GetFilesCount())
+	highCount := 0
+	for _, f := range reloaded.files {
+		if f.priority == transaction.TransactionPriorityHigh {
+			highCount++
+		}
+	}
+	a.Equal(1, highCount)
+
+	// When the disk is full of high priority files, normal priority transactions are not stored.
+	for i := 0; i < maxNumberOfFiles; i++ {
+		a.NoError(q.Serialize(highPriority("high")))
+	}
+	a.Error(q.Serialize(createHTTPTransactionCollectionTests("normal")))
+}
+
 func createHTTPTransactionCollectionTests(endpoints ...string) []transaction.Transaction {
 	var transactions []transaction.Transaction
 
@@ -126,7 +169,7 @@ func newTestOnDiskRetryQueue(a *assert.Assertions, path string, maxSizeInBytes i
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, maxSizeInBytes, 1)
-	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, telemetry)
+	storage, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver(domainName, nil)), path, diskUsageLimit, nil, telemetry)
 	a.NoError(err)
 	return storage
 }
@@ -7,7 +7,10 @@ package retry
 
 import (
 	"expvar"
+	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
@@ -64,6 +67,8 @@ var (
 	transactionsDroppedCountTelemetry *counterExpvar
 	errorsCountTelemetry              *counterExpvar
 
+	retryQueues = &retryQueueStatusRegistry{}
+
 	fileStorageExpvar                       = expvar.Map{}
 	serializeCountTelemetry                 *counterExpvar
 	deserializeCountTelemetry               *counterExpvar
@@ -74,6 +79,7 @@ var (
 	filesRemovedCountTelemetry              *counterExpvar
 	deserializeErrorsCountTelemetry         *counterExpvar
 	deserializeTransactionsCountTelemetry   *counterExpvar
+	tamperedFilesCountTelemetry             *counterExpvar
 )
 
 func init() {
@@ -130,6 +136,10 @@ func init() {
 		"The number of errors",
 		&transactionContainerExpvar)
 
+	transaction.ForwarderExpvars.Set("RetryQueues", expvar.Func(func() interface{} {
+		return retryQueues.status(time.Now())
+	}))
+
 	transaction.ForwarderExpvars.Set("FileStorage", &fileStorageExpvar)
 	serializeCountTelemetry = newCounterExpvar(
 		"file_storage",
@@ -185,6 +195,77 @@ func init() {
 		domainTag,
 		"The number of transactions read from the disk",
 		&fileStorageExpvar)
+	tamperedFilesCountTelemetry = newCounterExpvar(
+		"file_storage",
+		"tampered_files_count",
+		domainTag,
+		"The number of files discarded because they cannot be decrypted or authenticated",
+		&fileStorageExpvar)
+}
+
+// retryQueueStatusRegistry holds the retry queues reported in the `RetryQueues` expvar.
+// Each queue belongs to the domain forwarder of a forwarder instance.
+type retryQueueStatusRegistry struct {
+	mutex   sync.Mutex
+	entries []retryQueueStatusEntry
+}
+
+type retryQueueStatusEntry struct {
+	key   string
+	queue *TransactionRetryQueue
+}
+
+// RegisterTransactionRetryQueueStatus reports the status of a retry queue in the `RetryQueues`
+// expvar under `key` until it is unregistered. When several forwarders register a queue with
+// the same key, the key is suffixed with the registration order.
+func RegisterTransactionRetryQueueStatus(key string, queue *TransactionRetryQueue) {
+	retryQueues.register(key, queue)
+}
+
+// UnregisterTransactionRetryQueueStatus removes a retry queue from the `RetryQueues` expvar.
+func UnregisterTransactionRetryQueueStatus(queue *TransactionRetryQueue) {
+	retryQueues.unregister(queue)
+}
+
+func (r *retryQueueStatusRegistry) register(key string, queue *TransactionRetryQueue) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.entries {
+		if r.entries[i].queue == queue {
+			r.entries[i].key = key
+			return
+		}
+	}
+	r.entries = append(r.entries, retryQueueStatusEntry{key: key, queue: queue})
+}
+
+func (r *retryQueueStatusRegistry) unregister(queue *TransactionRetryQueue) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i, e := range r.entries {
+		if e.queue == queue {
+			r.entries = append(r.entries[:i], r.entries[i+1:]...)
+			return
+		}
+	}
+}
+
+func (r *retryQueueStatusRegistry) status(now time.Time) map[string]TransactionRetryQueueStatus {
+	r.mutex.Lock()
+	entries := append([]retryQueueStatusEntry(nil), r.entries...)
+	r.mutex.Unlock()
+
+	status := make(map[string]TransactionRetryQueueStatus, len(entries))
+	seen := make(map[string]int, len(entries))
+	for _, e := range entries {
+		seen[e.key]++
+		key := e.key
+		if seen[e.key] > 1 {
+			key = fmt.Sprintf("%s #%d", e.key, seen[e.key])
+		}
+		status[key] = e.queue.GetStatus(now)
+	}
+	return status
 }
 
 // FileRemovalPolicyTelemetry handles the telemetry for FileRemovalPolicy.
@@ -279,6 +360,10 @@ func (t onDiskRetryQueueTelemetry) addDeserializeTransactionsCount(count int) {
 	deserializeTransactionsCountTelemetry.add(float64(count), t.domainName)
 }
 
+func (t onDiskRetryQueueTelemetry) addTamperedFilesCount() {
+	tamperedFilesCountTelemetry.add(1, t.domainName)
+}
+
 func toCamelCase(s string) string {
 	parts := strings.Split(s, "_")
 	var camelCase string
@@ -8,6 +8,7 @@ package retry
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config/resolver"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
@@ -20,6 +21,8 @@ type DiskTransactionSerializer interface {
 	Serialize([]transaction.Transaction) error
 	Deserialize() ([]transaction.Transaction, error)
 	GetDiskSpaceUsed() int64
+	GetFilesCount() int
+	GetOldestTransactionTime() time.Time
 }
 
 // TransactionPrioritySorter is an interface to sort transactions.
@@ -46,6 +49,7 @@ func BuildTransactionRetryQueue(
 	flushToStorageRatio float64,
 	optionalDomainFolderPath string,
 	optionalDiskUsageLimit *DiskUsageLimit,
+	optionalEncryption *FileEncryption,
 	dropPrioritySorter TransactionPrioritySorter,
 	resolver resolver.DomainResolver) *TransactionRetryQueue {
 	var storage DiskTransactionSerializer
@@ -53,7 +57,7 @@ func BuildTransactionRetryQueue(
 
 	if optionalDomainFolderPath != "" && optionalDiskUsageLimit != nil {
 		serializer := NewHTTPTransactionsSerializer(resolver)
-		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()))
+		storage, err = newOnDiskRetryQueue(serializer, optionalDomainFolderPath, optionalDiskUsageLimit, optionalEncryption, newOnDiskRetryQueueTelemetry(resolver.GetBaseDomain()))
 
 		// If the storage on disk cannot be used, log the error and continue.
 		// Returning `nil, err` would mean not using `TransactionRetryQueue` and so not using `forwarder_retry_queue_payloads_max_size` config.
@@ -62,12 +66,13 @@ func BuildTransactionRetryQueue(
 		}
 	}
 
-	return NewTransactionRetryQueue(
+	queue := NewTransactionRetryQueue(
 		dropPrioritySorter,
 		storage,
 		maxMemSizeInBytes,
 		flushToStorageRatio,
 		NewTransactionRetryQueueTelemetry(resolver.GetBaseDomain()))
+	return queue
 }
 
 // NewTransactionRetryQueue creates a new instance of NewTransactionRetryQueue
@@ -95,7 +100,8 @@ func NewTransactionRetryQueue(
 // 100*0.6=60 bytes must be flushed on disk.
 // The first 3 transactions are flushed to the disk as 10 + 20 + 30 >= 60
 // If disk serialization failed or is not enabled, remove old transactions such as
-// `currentMemSizeInBytes` <= `maxMemSizeInBytes`
+// `currentMemSizeInBytes` <= `maxMemSizeInBytes`. Transactions with a higher priority than `t`
+// are never removed to make room for `t`: `t` is dropped instead.
 func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 	tc.mutex.Lock()
 	defer tc.mutex.Unlock()
@@ -119,6 +125,10 @@ func (tc *TransactionRetryQueue) Add(t transaction.Transaction) (int, error) {
 	payloadSizeInBytesToDrop := (tc.currentMemSizeInBytes + payloadSize) - tc.maxMemSizeInBytes
 	inMemTransactionDroppedCount := 0
 	if payloadSizeInBytesToDrop > 0 {
+		if tc.mustDropInsteadOfEvicting(t, payloadSizeInBytesToDrop) {
+			tc.telemetry.addTransactionsDroppedCount(1)
+			return 1, diskErr
+		}
 		transactions := tc.extractTransactionsFromMemory(payloadSizeInBytesToDrop)
 		inMemTransactionDroppedCount = len(transactions)
 		tc.telemetry.addTransactionsDroppedCount(inMemTransactionDroppedCount)
@@ -158,6 +168,67 @@ func (tc *TransactionRetryQueue) ExtractTransactions() ([]transaction.Transactio
 	return transactions, nil
 }
 
+// mustDropInsteadOfEvicting returns true if making room for `t` would remove
+// transactions with a higher priority than `t`.
+func (tc *TransactionRetryQueue) mustDropInsteadOfEvicting(t transaction.Transaction, payloadSizeInBytesToDrop int) bool {
+	evictableSizeInBytes := 0
+	hasHigherPriority := false
+	for _, tr := range tc.transactions {
+		if tr.GetPriority() > t.GetPriority() {
+			hasHigherPriority = true
+		} else {
+			evictableSizeInBytes += tr.GetPayloadSize()
+		}
+	}
+	return hasHigherPriority && evictableSizeInBytes < payloadSizeInBytesToDrop
+}
+
+// TransactionRetryQueueStatus describes the content of a retry queue, as reported on the status page.
+type TransactionRetryQueueStatus struct {
+	InMemoryTransactionsCount  int
+	InMemorySizeInBytes        int
+	InMemoryOldestAgeInSeconds int64
+	OnDiskFilesCount           int
+	OnDiskSizeInBytes          int64
+	OnDiskOldestAgeInSeconds   int64
+	MaxInMemorySizeInBytes     int
+	OnDiskStorageEnabled       bool
+}
+
+// GetStatus returns the current content of the retry queue. Ages are relative to `now`.
+func (tc *TransactionRetryQueue) GetStatus(now time.Time) TransactionRetryQueueStatus {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+
+	status := TransactionRetryQueueStatus{
+		InMemoryTransactionsCount: len(tc.transactions),
+		InMemorySizeInBytes:       tc.currentMemSizeInBytes,
+		MaxInMemorySizeInBytes:    tc.maxMemSizeInBytes,
+	}
+	var oldest time.Time
+	for _, t := range tc.transactions {
+		if oldest.IsZero() || t.GetCreatedAt().Before(oldest) {
+			oldest = t.GetCreatedAt()
+		}
+	}
+	status.InMemoryOldestAgeInSeconds = ageInSeconds(now, oldest)
+
+	if tc.optionalSerializer != nil {
+		status.OnDiskStorageEnabled = true
+		status.OnDiskFilesCount = tc.optionalSerializer.GetFilesCount()
+		status.OnDiskSizeInBytes = tc.optionalSerializer.GetDiskSpaceUsed()
+		status.OnDiskOldestAgeInSeconds = ageInSeconds(now, tc.optionalSerializer.GetOldestTransactionTime())
+	}
+	return status
+}
+
+func ageInSeconds(now time.Time, t time.Time) int64 {
+	if t.IsZero() || t.After(now) {
+		return 0
+	}
+	return int64(now.Sub(t) / time.Second)
+}
+
 // GetCurrentMemSizeInBytes gets the current memory usage in bytes
 func (tc *TransactionRetryQueue) getCurrentMemSizeInBytes() int {
 	tc.mutex.RLock()
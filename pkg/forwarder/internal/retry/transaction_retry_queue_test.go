@@ -7,6 +7,7 @@ package retry
 
 import (
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/config/resolver"
 	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
@@ -54,13 +55,13 @@ func TestTransactionRetryQueueSeveralFlushToDisk(t *testing.T) {
 		container.Add(createTransactionWithPayloadSize(payloadSize))
 	}
 	a.Equal(40, container.getCurrentMemSizeInBytes())
-	a.Equal(3, q.getFilesCount())
+	a.Equal(3, q.GetFilesCount())
 
 	assertPayloadSizeFromExtractTransactions(a, container, []int{40})
 	assertPayloadSizeFromExtractTransactions(a, container, []int{11})
 	assertPayloadSizeFromExtractTransactions(a, container, []int{10})
 	assertPayloadSizeFromExtractTransactions(a, container, []int{9})
-	a.Equal(0, q.getFilesCount())
+	a.Equal(0, q.GetFilesCount())
 	a.Equal(int64(0), q.GetDiskSpaceUsed())
 }
 
@@ -102,6 +103,94 @@ func TestTransactionRetryQueueZeroMaxMemSizeInBytes(t *testing.T) {
 	a.Equal(1, inMemTrDropped)
 }
 
+func TestTransactionRetryQueueDropHonorsPriority(t *testing.T) {
+	a := assert.New(t)
+	container := NewTransactionRetryQueue(createDropPrioritySorter(), nil, 50, 0.1, NewTransactionRetryQueueTelemetry("domain"))
+
+	for _, payloadSize := range []int{20, 20} {
+		tr := createTransactionWithPayloadSize(payloadSize)
+		tr.Priority = transaction.TransactionPriorityHigh
+		_, err := container.Add(tr)
+		a.NoError(err)
+	}
+
+	// The normal priority transaction is dropped instead of evicting high priority ones.
+	dropCount, err := container.Add(createTransactionWithPayloadSize(15))
+	a.NoError(err)
+	a.Equal(1, dropCount)
+	a.Equal(2, container.GetTransactionCount())
+
+	// A high priority transaction evicts the oldest one.
+	tr := createTransactionWithPayloadSize(15)
+	tr.Priority = transaction.TransactionPriorityHigh
+	dropCount, err = container.Add(tr)
+	a.NoError(err)
+	a.Equal(1, dropCount)
+	assertPayloadSizeFromExtractTransactions(a, container, []int{20, 15})
+}
+
+func TestTransactionRetryQueueGetStatus(t *testing.T) {
+	a := assert.New(t)
+	q, clean := newOnDiskRetryQueueTest(a)
+	defer clean()
+
+	container := NewTransactionRetryQueue(createDropPrioritySorter(), q, 50, 0.1, NewTransactionRetryQueueTelemetry("domain"))
+	now := time.Now()
+	for i, payloadSize := range []int{30, 10, 20} {
+		tr := createTransactionWithPayloadSize(payloadSize)
+		tr.CreatedAt = now.Add(-time.Duration(10*(3-i)) * time.Second)
+		_, err := container.Add(tr)
+		a.NoError(err)
+	}
+
+	// The first transaction is flushed on disk when adding the last one.
+	a.Equal(TransactionRetryQueueStatus{
+		InMemoryTransactionsCount:  2,
+		InMemorySizeInBytes:        30,
+		InMemoryOldestAgeInSeconds: 20,
+		OnDiskFilesCount:           1,
+		OnDiskSizeInBytes:          q.GetDiskSpaceUsed(),
+		OnDiskOldestAgeInSeconds:   30,
+		MaxInMemorySizeInBytes:     50,
+		OnDiskStorageEnabled:       true,
+	}, container.GetStatus(now))
+
+	assertPayloadSizeFromExtractTransactions(a, container, []int{10, 20})
+	assertPayloadSizeFromExtractTransactions(a, container, []int{30})
+	a.Equal(TransactionRetryQueueStatus{
+		MaxInMemorySizeInBytes: 50,
+		OnDiskStorageEnabled:   true,
+	}, container.GetStatus(now))
+}
+
+func TestRetryQueueStatusRegistry(t *testing.T) {
+	a := assert.New(t)
+	registry := &retryQueueStatusRegistry{}
+	newQueue := func() *TransactionRetryQueue {
+		return NewTransactionRetryQueue(createDropPrioritySorter(), nil, 50, 0.1, NewTransactionRetryQueueTelemetry("domain"))
+	}
+	first, second, tenant := newQueue(), newQueue(), newQueue()
+	_, _ = first.Add(createTransactionWithPayloadSize(10))
+
+	registry.register("domain", first)
+	registry.register("domain", second)
+	registry.register("domain (tenant:a)", tenant)
+	status := registry.status(time.Now())
+	a.Len(status, 3)
+	a.Equal(1, status["domain"].InMemoryTransactionsCount)
+	a.Equal(0, status["domain #2"].InMemoryTransactionsCount)
+	a.Contains(status, "domain (tenant:a)")
+
+	registry.unregister(first)
+	registry.unregister(tenant)
+	status = registry.status(time.Now())
+	a.Len(status, 1)
+	a.Equal(0, status["domain"].InMemoryTransactionsCount)
+
+	registry.unregister(second)
+	a.Empty(registry.status(time.Now()))
+}
+
 func createTransactionWithPayloadSize(payloadSize int) *transaction.HTTPTransaction {
 	tr := transaction.NewHTTPTransaction()
 	payload := make([]byte, payloadSize)
@@ -137,7 +226,7 @@ func newOnDiskRetryQueueTest(a *assert.Assertions) (*onDiskRetryQueue, func()) {
 			Total:     10000,
 		}}
 	diskUsageLimit := NewDiskUsageLimit("", disk, 1000, 1)
-	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver("", nil)), path, diskUsageLimit, newOnDiskRetryQueueTelemetry("domain"))
+	q, err := newOnDiskRetryQueue(NewHTTPTransactionsSerializer(resolver.NewSingleDomainResolver("", nil)), path, diskUsageLimit, nil, newOnDiskRetryQueueTelemetry("domain"))
 	a.NoError(err)
 	return q, clean
 }
@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/endpoints"
+	"github.com/DataDog/datadog-agent/pkg/forwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	shadowRecordVersion       = 1
+	shadowRecordMetadataExt   = ".json"
+	shadowRecordBodyExt       = ".body"
+	contentEncodingHTTPHeader = "Content-Encoding"
+)
+
+// shadowRecord describes a payload recorded by the ShadowForwarder. It is stored
+// next to the payload body, which is kept decompressed when possible so that the
+// recordings of two Agent versions can be compared.
+type shadowRecord struct {
+	Version             int         `json:"version"`
+	CreatedAt           time.Time   `json:"created_at"`
+	EndpointName        string      `json:"endpoint_name"`
+	Route               string      `json:"route"`
+	APIKeyInQueryString bool        `json:"api_key_in_query_string"`
+	Headers             http.Header `json:"headers"`
+	// Decompressed is true when the body was decompressed according to the
+	// Content-Encoding header. It is compressed again when replayed.
+	Decompressed bool `json:"decompressed"`
+}
+
+// shadowRecordFiles are the files of a record, named after the record without extension.
+type shadowRecordFiles struct {
+	name        string
+	sizeInBytes int64
+}
+
+// ShadowForwarder is a Forwarder writing every payload to a directory instead of
+// sending it. API keys are never written. The recorded payloads can be sent to an
+// intake with ReplayShadowRecords.
+//
+// The records take at most maxSizeInBytes on disk: the oldest records are removed to
+// make room for the new ones.
+type ShadowForwarder struct {
+	path           string
+	maxSizeInBytes int64
+	m              sync.Mutex
+	seq            uint64
+	records        []shadowRecordFiles // the oldest first
+	sizeInBytes    int64
+}
+
+// NewShadowForwarder returns a new shadow forwarder recording payloads in path, using at
+// most maxSizeInBytes. 0 means unlimited.
+func NewShadowForwarder(path string, maxSizeInBytes int64) *ShadowForwarder {
+	return &ShadowForwarder{path: path, maxSizeInBytes: maxSizeInBytes}
+}
+
+// Start creates the directory where the payloads are recorded. The records already in the
+// directory count in its size limit.
+func (f *ShadowForwarder) Start() error {
+	if err := os.MkdirAll(f.path, 0700); err != nil {
+		return fmt.Errorf("cannot create the shadow forwarder directory %q: %v", f.path, err)
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	if err := f.reloadExistingRecords(); err != nil {
+		return fmt.Errorf("cannot read the shadow forwarder directory %q: %v", f.path, err)
+	}
+	if err := f.makeRoomFor(0); err != nil {
+		return err
+	}
+	log.Warnf("The forwarder is in shadow mode: payloads are written to %q and are NOT sent to Datadog", f.path)
+	return nil
+}
+
+func (f *ShadowForwarder) reloadExistingRecords() error {
+	metadataFiles, err := filepath.Glob(filepath.Join(f.path, "*"+shadowRecordMetadataExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(metadataFiles)
+
+	f.records, f.sizeInBytes = nil, 0
+	for _, metadataPath := range metadataFiles {
+		record := shadowRecordFiles{name: strings.TrimSuffix(metadataPath, shadowRecordMetadataExt)}
+		for _, ext := range []string{shadowRecordBodyExt, shadowRecordMetadataExt} {
+			if info, err := os.Stat(record.name + ext); err == nil {
+				record.sizeInBytes += info.Size()
+			}
+		}
+		f.records = append(f.records, record)
+		f.sizeInBytes += record.sizeInBytes
+	}
+	return nil
+}
+
+// makeRoomFor removes the oldest records until a record of sizeInBytes can be written.
+func (f *ShadowForwarder) makeRoomFor(sizeInBytes int64) error {
+	if f.maxSizeInBytes <= 0 {
+		return nil
+	}
+	if sizeInBytes > f.maxSizeInBytes {
+		return fmt.Errorf("the payload is too big to be recorded. Current:%v Maximum:%v", sizeInBytes, f.maxSizeInBytes)
+	}
+	for len(f.records) > 0 && f.sizeInBytes+sizeInBytes > f.maxSizeInBytes {
+		oldest := f.records[0]
+		log.Debugf("Maximum size of the shadow forwarder records is reached. Removing %s", oldest.name)
+		for _, ext := range []string{shadowRecordMetadataExt, shadowRecordBodyExt} {
+			if err := os.Remove(oldest.name + ext); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("cannot remove the record %s: %v", oldest.name, err)
+			}
+		}
+		f.records = f.records[1:]
+		f.sizeInBytes -= oldest.sizeInBytes
+	}
+	return nil
+}
+
+// Stop stops the shadow forwarder: nothing to do.
+func (f *ShadowForwarder) Stop() {
+}
+
+func (f *ShadowForwarder) record(endpoint transaction.Endpoint, apiKeyInQueryString bool, payloads Payloads, extra http.Header) error {
+	headers := make(http.Header)
+	headers.Set(versionHTTPHeaderKey, version.AgentVersion)
+	headers.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+	if config.Datadog.GetBool("allow_arbitrary_tags") {
+		headers.Set(arbitraryTagHTTPHeaderKey, "true")
+	}
+	for key := range extra {
+		headers.Set(key, extra.Get(key))
+	}
+	headers.Del(apiHTTPHeaderKey)
+
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	for _, payload := range payloads {
+		r := shadowRecord{
+			Version:             shadowRecordVersion,
+			CreatedAt:           time.Now(),
+			EndpointName:        endpoint.Name,
+			Route:               endpoint.Route,
+			APIKeyInQueryString: apiKeyInQueryString,
+			Headers:             headers,
+		}
+
+		body := *payload
+		if c, err := compression.NewCompressorForContentEncoding(headers.Get(contentEncodingHTTPHeader)); err == nil {
+			if decompressed, err := c.Decompress(body); err == nil {
+				body = decompressed
+				r.Decompressed = true
+			} else {
+				log.Debugf("Recording the compressed payload for %s: %v", endpoint.Name, err)
+			}
+		}
+
+		metadata, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		size := int64(len(body) + len(metadata))
+		if err := f.makeRoomFor(size); err != nil {
+			return fmt.Errorf("cannot record the payload for %s: %v", endpoint.Name, err)
+		}
+
+		f.seq++
+		name := filepath.Join(f.path, fmt.Sprintf("%d-%06d-%s", r.CreatedAt.UnixNano(), f.seq, endpoint.Name))
+		// The metadata is written last: a record without metadata is incomplete and ignored.
+		if err := ioutil.WriteFile(name+shadowRecordBodyExt, body, 0600); err != nil {
+			_ = os.Remove(name + shadowRecordBodyExt)
+			return fmt.Errorf("cannot record the payload for %s: %v", endpoint.Name, err)
+		}
+		if err := ioutil.WriteFile(name+shadowRecordMetadataExt, metadata, 0600); err != nil {
+			_ = os.Remove(name + shadowRecordBodyExt)
+			_ = os.Remove(name + shadowRecordMetadataExt)
+			return fmt.Errorf("cannot record the payload for %s: %v", endpoint.Name, err)
+		}
+		f.records = append(f.records, shadowRecordFiles{name: name, sizeInBytes: size})
+		f.sizeInBytes += size
+	}
+	log.Debugf("ShadowForwarder has recorded %d payloads for %s", len(payloads), endpoint.Name)
+	return nil
+}
+
+// recordProcessLikePayload records the payloads and returns a closed channel: there
+// is no response from the intake.
+func (f *ShadowForwarder) recordProcessLikePayload(endpoint transaction.Endpoint, payload Payloads, extra http.Header) (chan Response, error) {
+	if err := f.record(endpoint, false, payload, extra); err != nil {
+		return nil, err
+	}
+	results := make(chan Response)
+	close(results)
+	return results, nil
+}
+
+// SubmitV1Series records timeseries for the v1 endpoint.
+func (f *ShadowForwarder) SubmitV1Series(payload Payloads, extra http.Header) error {
+	return f.record(endpoints.V1SeriesEndpoint, true, payload, extra)
+}
+
+// SubmitSeries records timeseries for the v2 endpoint.
+func (f *ShadowForwarder) SubmitSeries(payload Payloads, extra http.Header) error {
+	return f.record(endpoints.SeriesEndpoint, true, payload, extra)
+}
+
+// SubmitV1Intake records payloads for the universal `/intake/` endpoint.
+func (f *ShadowForwarder) SubmitV1Intake(payload Payloads, extra http.Header) error {
+	// the intake endpoint requires the Content-Type header to be set
+	headers := extra.Clone()
+	if headers == nil {
+		headers = make(http.Header)
+	}
+	headers.Set("Content-Type", "application/json")
+	return f.record(endpoints.V1IntakeEndpoint, true, payload, headers)
+}
+
+// SubmitV1CheckRuns records service checks for the v1 endpoint.
+func (f *ShadowForwarder) SubmitV1CheckRuns(payload Payloads, extra http.Header) error {
+	return f.record(endpoints.V1CheckRunsEndpoint, true, payload, extra)
+}
+
+// SubmitSketchSeries records sketches.
+func (f *ShadowForwarder) SubmitSketchSeries(payload Payloads, extra http.Header) error {
+	return f.record(endpoints.SketchSeriesEndpoint, true, payload, extra)
+}
+
+// SubmitHostMetadata records a host_metadata tag type payload.
+func (f *ShadowForwarder) SubmitHostMetadata(payload Payloads, extra http.Header) error {
+	return f.SubmitV1Intake(payload, extra)
+}
+
+// SubmitMetadata records a metadata type payload.
+func (f *ShadowForwarder) SubmitMetadata(payload Payloads, extra http.Header) error {
+	return f.SubmitV1Intake(payload, extra)
+}
+
+// SubmitAgentChecksMetadata records a agentchecks_metadata tag type payload.
+func (f *ShadowForwarder) SubmitAgentChecksMetadata(payload Payloads, extra http.Header) error {
+	return f.SubmitV1Intake(payload, extra)
+}
+
+// SubmitProcessChecks records process checks
+func (f *ShadowForwarder) SubmitProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.ProcessesEndpoint, payload, extra)
+}
+
+// SubmitProcessDiscoveryChecks records process discovery checks
+func (f *ShadowForwarder) SubmitProcessDiscoveryChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.ProcessDiscoveryEndpoint, payload, extra)
+}
+
+// SubmitRTProcessChecks records real time process checks
+func (f *ShadowForwarder) SubmitRTProcessChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.RtProcessesEndpoint, payload, extra)
+}
+
+// SubmitContainerChecks records container checks
+func (f *ShadowForwarder) SubmitContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.ContainerEndpoint, payload, extra)
+}
+
+// SubmitRTContainerChecks records real time container checks
+func (f *ShadowForwarder) SubmitRTContainerChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.RtContainerEndpoint, payload, extra)
+}
+
+// SubmitConnectionChecks records connection checks
+func (f *ShadowForwarder) SubmitConnectionChecks(payload Payloads, extra http.Header) (chan Response, error) {
+	return f.recordProcessLikePayload(endpoints.ConnectionsEndpoint, payload, extra)
+}
+
+// SubmitOrchestratorChecks records orchestrator checks
+func (f *ShadowForwarder) SubmitOrchestratorChecks(payload Payloads, extra http.Header, payloadType int) (chan Response, error) {
+	endpoint := endpoints.OrchestratorEndpoint
+	if config.Datadog.IsSet("orchestrator_explorer.use_legacy_endpoint") {
+		endpoint = endpoints.LegacyOrchestratorEndpoint
+	}
+	return f.recordProcessLikePayload(endpoint, payload, extra)
+}
+
+// SubmitContainerLifecycleEvents records container lifecycle events
+func (f *ShadowForwarder) SubmitContainerLifecycleEvents(payload Payloads, extra http.Header) error {
+	return f.record(endpoints.ContainerLifecycleEndpoint, false, payload, extra)
+}
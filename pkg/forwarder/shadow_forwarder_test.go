@@ -0,0 +1,154 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package forwarder
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+var _ Forwarder = &ShadowForwarder{}
+
+func TestShadowForwarderRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shadow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	zlib, err := compression.NewCompressor(compression.ZlibKind)
+	require.NoError(t, err)
+	series := []byte(`{"series":[]}`)
+	compressedSeries, err := zlib.Compress(series)
+	require.NoError(t, err)
+	intake := []byte(`{"host":"foo"}`)
+
+	f := NewShadowForwarder(dir, 0)
+	require.NoError(t, f.Start())
+	seriesHeaders := http.Header{}
+	seriesHeaders.Set("Content-Encoding", zlib.ContentEncoding())
+	seriesHeaders.Set(apiHTTPHeaderKey, "should_not_be_recorded")
+	require.NoError(t, f.SubmitSeries(Payloads{&compressedSeries}, seriesHeaders))
+	require.NoError(t, f.SubmitV1Intake(Payloads{&intake}, nil))
+	responses, err := f.SubmitProcessChecks(Payloads{&intake}, nil)
+	require.NoError(t, err)
+	_, open := <-responses
+	assert.False(t, open)
+	f.Stop()
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Len(t, files, 6)
+	bodies, err := filepath.Glob(filepath.Join(dir, "*"+shadowRecordBodyExt))
+	require.NoError(t, err)
+	sort.Strings(bodies)
+	body, err := ioutil.ReadFile(bodies[0])
+	require.NoError(t, err)
+	assert.Equal(t, series, body, "payloads are recorded decompressed")
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "should_not_be_recorded")
+	}
+
+	var m sync.Mutex
+	received := map[string][]byte{}
+	// the key is escaped in the query string
+	replayKey := "replay&key=#1"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Lock()
+		defer m.Unlock()
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("Content-Encoding") != "" {
+			body, _ = zlib.Decompress(body)
+		}
+		assert.Equal(t, replayKey, r.Header.Get(apiHTTPHeaderKey))
+		if r.URL.Path == "/intake/" {
+			assert.Equal(t, replayKey, r.URL.Query().Get("api_key"))
+		}
+		received[r.URL.Path] = body
+		if strings.HasPrefix(r.URL.Path, "/api/v1/collector") {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer ts.Close()
+
+	results, err := ReplayShadowRecords(context.Background(), dir, ts.URL, replayKey, &http.Client{})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "series_v2", results[0].EndpointName)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "intake", results[1].EndpointName)
+	assert.NoError(t, results[1].Err)
+	assert.Equal(t, "process", results[2].EndpointName)
+	assert.Equal(t, http.StatusForbidden, results[2].StatusCode)
+	assert.Error(t, results[2].Err)
+
+	assert.Equal(t, series, received["/api/v2/series"])
+	assert.Equal(t, intake, received["/intake/"])
+}
+
+func TestShadowForwarderMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shadow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	payload := make([]byte, 1000)
+	recordedBodies := func() []string {
+		bodies, err := filepath.Glob(filepath.Join(dir, "*"+shadowRecordBodyExt))
+		require.NoError(t, err)
+		sort.Strings(bodies)
+		return bodies
+	}
+
+	// Each record takes more than 1000 bytes: only two records fit.
+	f := NewShadowForwarder(dir, 2900)
+	require.NoError(t, f.Start())
+	require.NoError(t, f.SubmitSeries(Payloads{&payload}, nil))
+	first := recordedBodies()
+	require.Len(t, first, 1)
+	require.NoError(t, f.SubmitSeries(Payloads{&payload}, nil))
+	require.NoError(t, f.SubmitSeries(Payloads{&payload}, nil))
+	bodies := recordedBodies()
+	assert.Len(t, bodies, 2)
+	assert.NotContains(t, bodies, first[0], "the oldest record is removed")
+	metadata, err := filepath.Glob(filepath.Join(dir, "*"+shadowRecordMetadataExt))
+	require.NoError(t, err)
+	assert.Len(t, metadata, 2)
+
+	big := make([]byte, 3000)
+	assert.Error(t, f.SubmitSeries(Payloads{&big}, nil))
+	assert.Len(t, recordedBodies(), 2)
+	f.Stop()
+
+	// The records of a previous run count in the limit.
+	f = NewShadowForwarder(dir, 2000)
+	require.NoError(t, f.Start())
+	assert.Equal(t, bodies[1:], recordedBodies())
+}
+
+func TestReplayShadowRecordsEmptyDirectory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shadow")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = ReplayShadowRecords(context.Background(), dir, "http://localhost", "key", &http.Client{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package forwarder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+// ShadowReplayResult is the outcome of the replay of a payload recorded by the ShadowForwarder.
+type ShadowReplayResult struct {
+	// Record is the path of the record, without extension.
+	Record       string
+	EndpointName string
+	StatusCode   int
+	Err          error
+}
+
+// ReplayShadowRecords sends the payloads recorded by a ShadowForwarder in path to domain,
+// in the order they were recorded, using apiKey. A payload failing to be sent does not
+// stop the replay: its error is reported in its result.
+func ReplayShadowRecords(ctx context.Context, path string, domain string, apiKey string, client *http.Client) ([]ShadowReplayResult, error) {
+	records, err := filepath.Glob(filepath.Join(path, "*"+shadowRecordMetadataExt))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no payload recorded in %q", path)
+	}
+	sort.Strings(records)
+
+	results := make([]ShadowReplayResult, 0, len(records))
+	for _, metadataPath := range records {
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+		name := strings.TrimSuffix(metadataPath, shadowRecordMetadataExt)
+		result := ShadowReplayResult{Record: name}
+		result.EndpointName, result.StatusCode, result.Err = replayShadowRecord(ctx, name, domain, apiKey, client)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func replayShadowRecord(ctx context.Context, name string, domain string, apiKey string, client *http.Client) (string, int, error) {
+	metadata, err := ioutil.ReadFile(name + shadowRecordMetadataExt)
+	if err != nil {
+		return "", 0, err
+	}
+	var r shadowRecord
+	if err := json.Unmarshal(metadata, &r); err != nil {
+		return "", 0, fmt.Errorf("invalid record: %v", err)
+	}
+	if r.Version != shadowRecordVersion {
+		return r.EndpointName, 0, fmt.Errorf("unsupported record version %d", r.Version)
+	}
+
+	body, err := ioutil.ReadFile(name + shadowRecordBodyExt)
+	if err != nil {
+		return r.EndpointName, 0, err
+	}
+	if r.Decompressed {
+		c, err := compression.NewCompressorForContentEncoding(r.Headers.Get(contentEncodingHTTPHeader))
+		if err != nil {
+			return r.EndpointName, 0, err
+		}
+		if body, err = c.Compress(body); err != nil {
+			return r.EndpointName, 0, err
+		}
+	}
+
+	u, err := url.Parse(domain + r.Route)
+	if err != nil {
+		return r.EndpointName, 0, err
+	}
+	if r.APIKeyInQueryString {
+		q := u.Query()
+		q.Set("api_key", apiKey)
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return r.EndpointName, 0, err
+	}
+	req.Header = r.Headers
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set(apiHTTPHeaderKey, apiKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return r.EndpointName, 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode >= 400 {
+		return r.EndpointName, resp.StatusCode, fmt.Errorf("error %q while replaying the payload", resp.Status)
+	}
+	return r.EndpointName, resp.StatusCode, nil
+}
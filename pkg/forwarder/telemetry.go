@@ -46,6 +46,7 @@ func init() {
 	initTransactionsExpvars()
 	initForwarderHealthExpvars()
 	initEndpointExpvars()
+	initCircuitBreakerExpvars()
 }
 
 func initEndpointExpvars() {
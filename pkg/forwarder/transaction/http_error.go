@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package transaction
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPError is the error returned when the intake answers a transaction with an
+// error status code and the transaction must be retried.
+type HTTPError struct {
+	StatusCode int
+	// RetryAfter is the delay requested by the intake with the Retry-After header,
+	// 0 when the header is missing or invalid.
+	RetryAfter time.Duration
+	message    string
+}
+
+func (e *HTTPError) Error() string {
+	return e.message
+}
+
+// IsServerError returns whether the error is caused by the intake being unavailable
+// or overloaded rather than by the payload: 5xx, 408 (Request Timeout) and 429
+// (Too Many Requests) status codes.
+func (e *HTTPError) IsServerError() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
+// parseRetryAfter parses a Retry-After header, either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -32,6 +32,9 @@ var (
 
 	connectionDNSSuccess         = expvar.Int{}
 	connectionConnectSuccess     = expvar.Int{}
+	connectionReused             = expvar.Int{}
+	connectionNew                = expvar.Int{}
+	connectionRequestsByProtocol = expvar.Map{}
 	transactionsConnectionEvents = expvar.Map{}
 
 	// TransactionsDropped is the number of transaction dropped.
@@ -57,6 +60,8 @@ var (
 
 	tlmConnectEvents = telemetry.NewCounter("transactions", "connection_events",
 		[]string{"connection_event_type"}, "Count of new connection events grouped by type of event")
+	tlmTxProtocol = telemetry.NewCounter("transactions", "requests_by_protocol",
+		[]string{"domain", "protocol"}, "Count of requests grouped by negotiated HTTP protocol")
 
 	// TlmTxDropped is a telemetry counter that counts the number transaction dropped.
 	TlmTxDropped = telemetry.NewCounter("transactions", "dropped",
@@ -102,6 +107,15 @@ var Trace = &httptrace.ClientTrace{
 		tlmConnectEvents.Inc("connection_success")
 		log.Tracef("New successful connection to address: %q", addr)
 	},
+	GotConn: func(connInfo httptrace.GotConnInfo) {
+		if connInfo.Reused {
+			connectionReused.Add(1)
+			tlmConnectEvents.Inc("connection_reused")
+			return
+		}
+		connectionNew.Add(1)
+		tlmConnectEvents.Inc("connection_new")
+	},
 	TLSHandshakeDone: func(tlsState tls.ConnectionState, err error) {
 		if err != nil {
 			transactionsTLSErrors.Add(1)
@@ -126,6 +140,7 @@ var defaultCompletionHandler = func(transaction *HTTPTransaction, statusCode int
 func init() {
 	TransactionsExpvars.Init()
 	transactionsConnectionEvents.Init()
+	connectionRequestsByProtocol.Init()
 	TransactionsDroppedByEndpoint.Init()
 	TransactionsSuccessByEndpoint.Init()
 	transactionsSuccessBytesByEndpoint.Init()
@@ -134,6 +149,9 @@ func init() {
 	ForwarderExpvars.Set("Transactions", &TransactionsExpvars)
 	transactionsConnectionEvents.Set("DNSSuccess", &connectionDNSSuccess)
 	transactionsConnectionEvents.Set("ConnectSuccess", &connectionConnectSuccess)
+	transactionsConnectionEvents.Set("Reused", &connectionReused)
+	transactionsConnectionEvents.Set("New", &connectionNew)
+	transactionsConnectionEvents.Set("RequestsByProtocol", &connectionRequestsByProtocol)
 	TransactionsExpvars.Set("ConnectionEvents", &transactionsConnectionEvents)
 	TransactionsExpvars.Set("Dropped", &TransactionsDropped)
 	TransactionsExpvars.Set("DroppedByEndpoint", &TransactionsDroppedByEndpoint)
@@ -314,6 +332,8 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, client *http.Clie
 		return 0, nil, fmt.Errorf("error while sending transaction, rescheduling it: %s", scrubber.ScrubLine(err.Error()))
 	}
 	defer func() { _ = resp.Body.Close() }()
+	connectionRequestsByProtocol.Add(resp.Proto, 1)
+	tlmTxProtocol.Inc(t.Domain, resp.Proto)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -354,7 +374,11 @@ func (t *HTTPTransaction) internalProcess(ctx context.Context, client *http.Clie
 		t.ErrorCount++
 		transactionsErrors.Add(1)
 		tlmTxErrors.Inc(t.Domain, transactionEndpointName, "gt_400")
-		return resp.StatusCode, body, fmt.Errorf("error %q while sending transaction to %q, rescheduling it: %q", resp.Status, logURL, truncateBodyForLog(body))
+		return resp.StatusCode, body, &HTTPError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			message:    fmt.Sprintf("error %q while sending transaction to %q, rescheduling it: %q", resp.Status, logURL, truncateBodyForLog(body)),
+		}
 	}
 
 	tlmTxSuccessCount.Inc(t.Domain, transactionEndpointName)
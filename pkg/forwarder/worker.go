@@ -36,6 +36,9 @@ type Worker struct {
 	stopChan            chan struct{}
 	stopped             chan struct{}
 	blockedList         *blockedEndpoints
+	bandwidth           *bandwidthLimiter
+	// newClient creates the clients replacing Client when the connections are reset.
+	newClient func() *http.Client
 }
 
 // NewWorker returns a new worker to consume Transaction from inputChan
@@ -45,6 +48,16 @@ func NewWorker(
 	lowPrioChan <-chan transaction.Transaction,
 	requeueChan chan<- transaction.Transaction,
 	blocked *blockedEndpoints) *Worker {
+	return newWorker(highPrioChan, lowPrioChan, requeueChan, blocked, NewHTTPClient)
+}
+
+// newWorker returns a new worker sending the transactions with clients created by newClient.
+func newWorker(
+	highPrioChan <-chan transaction.Transaction,
+	lowPrioChan <-chan transaction.Transaction,
+	requeueChan chan<- transaction.Transaction,
+	blocked *blockedEndpoints,
+	newClient func() *http.Client) *Worker {
 	return &Worker{
 		HighPrio:            highPrioChan,
 		LowPrio:             lowPrioChan,
@@ -52,7 +65,8 @@ func NewWorker(
 		resetConnectionChan: make(chan struct{}, 1),
 		stopChan:            make(chan struct{}),
 		stopped:             make(chan struct{}),
-		Client:              NewHTTPClient(),
+		Client:              newClient(),
+		newClient:           newClient,
 		blockedList:         blocked,
 	}
 }
@@ -175,11 +189,15 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 
 	// Run the endpoint through our blockedEndpoints circuit breaker
 	target := t.GetTarget()
-	if w.blockedList.isBlock(target) {
+	if !w.blockedList.allow(target) {
 		requeue()
 		log.Errorf("Too many errors for endpoint '%s': retrying later", target)
+	} else if !w.bandwidth.wait(ctx, t) {
+		w.bandwidth.deferTransaction()
+		requeue()
+		log.Debugf("Deferring transaction to '%s': the forwarder bandwidth budget is exhausted", target)
 	} else if err := t.Process(ctx, w.Client); err != nil {
-		w.blockedList.close(target)
+		w.blockedList.fail(target, err)
 		requeue()
 		log.Errorf("Error while processing transaction: %v", err)
 	} else {
@@ -193,5 +211,5 @@ func (w *Worker) process(ctx context.Context, t transaction.Transaction) {
 func (w *Worker) resetConnections() {
 	log.Debug("Resetting worker's connections")
 	w.Client.CloseIdleConnections()
-	w.Client = NewHTTPClient()
+	w.Client = w.newClient()
 }
@@ -93,6 +93,30 @@ func TestWorkerRetry(t *testing.T) {
 	assert.True(t, w.blockedList.isBlock("error_url"))
 }
 
+func TestWorkerRetryClientError(t *testing.T) {
+	highPrio := make(chan transaction.Transaction)
+	lowPrio := make(chan transaction.Transaction)
+	requeue := make(chan transaction.Transaction, 1)
+	w := NewWorker(highPrio, lowPrio, requeue, newBlockedEndpoints())
+
+	mock := newTestTransaction()
+	mock.On("Process", w.Client).Return(&transaction.HTTPError{StatusCode: http.StatusNotFound}).Times(1)
+	mock.On("GetTarget").Return("error_url").Times(2)
+
+	// The transaction rejected with a 404 is not sent again on the next retry tick
+	w.Start()
+	highPrio <- mock
+	<-requeue
+	highPrio <- mock
+	retryTransaction := <-requeue
+	w.Stop(false)
+	mock.AssertExpectations(t)
+	mock.AssertNumberOfCalls(t, "Process", 1)
+	assert.Equal(t, mock, retryTransaction)
+	assert.True(t, w.blockedList.isBlock("error_url"))
+	assert.Equal(t, circuitClosed, w.blockedList.errorPerEndpoint["error_url"].state)
+}
+
 func TestWorkerRetryBlockedTransaction(t *testing.T) {
 	highPrio := make(chan transaction.Transaction)
 	lowPrio := make(chan transaction.Transaction)
@@ -41,6 +41,7 @@ type Serie struct {
 	SourceTypeName string               `json:"source_type_name,omitempty"`
 	ContextKey     ckey.ContextKey      `json:"-"`
 	NameSuffix     string               `json:"-"`
+	CheckID        string               `json:"-"` // ID of the check which sent the serie, if any
 }
 
 // SeriesAPIV2Enum returns the enumeration value for MetricPayload.MetricType in
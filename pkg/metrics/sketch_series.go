@@ -22,6 +22,7 @@ type SketchSeries struct {
 	Interval   int64                `json:"interval"`
 	Points     []SketchPoint        `json:"points"`
 	ContextKey ckey.ContextKey      `json:"-"`
+	CheckID    string               `json:"-"` // ID of the check which sent the sketch, if any
 }
 
 // A SketchPoint represents a quantile sketch at a specific time
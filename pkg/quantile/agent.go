@@ -35,6 +35,12 @@ func (a *Agent) Finish() *Sketch {
 	return a.Sketch.Copy()
 }
 
+// AgentKeyValue returns the value represented by a key of a sketch built by an
+// Agent sketch, as returned by Sketch.Cols.
+func AgentKeyValue(k int32) float64 {
+	return agentConfig.f64(Key(k))
+}
+
 // flush buffered values into the sketch.
 func (a *Agent) flush() {
 	if len(a.Buf) != 0 {
@@ -191,3 +191,16 @@ func TestAgentInterpolation(t *testing.T) {
 		check(t, tt)
 	}
 }
+
+func TestAgentKeyValue(t *testing.T) {
+	var a Agent
+	for _, v := range []float64{-1e6, -3.5, 1e-3, 1, 42, 1e9} {
+		a.Insert(v, 1)
+	}
+	keys, _ := a.Finish().Cols()
+	require.Len(t, keys, 6)
+	for i, v := range []float64{-1e6, -3.5, 1e-3, 1, 42, 1e9} {
+		require.InEpsilon(t, v, AgentKeyValue(keys[i]), 2*defaultEps)
+	}
+	require.Equal(t, float64(0), AgentKeyValue(0))
+}
@@ -16,6 +16,7 @@ import (
 	aconfig "github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/ebpf"
 	"github.com/DataDog/datadog-agent/pkg/process/util"
+	"github.com/DataDog/datadog-agent/pkg/security/exporter"
 	"github.com/DataDog/datadog-agent/pkg/security/utils"
 )
 
@@ -136,6 +137,12 @@ type Config struct {
 	EventMonitoring bool
 	// RemoteConfigurationEnabled defines whether to use remote monitoring
 	RemoteConfigurationEnabled bool
+	// KillActionDryRun defines if the kill actions of the rules only report the processes they would kill
+	KillActionDryRun bool
+	// KillActionAllowlist is the list of the path patterns of the processes the kill actions must not kill
+	KillActionAllowlist []string
+	// EventExport defines the export of the events to local destinations, alongside the events sent to Datadog
+	EventExport exporter.Config
 }
 
 // IsEnabled returns true if any feature is enabled. Has to be applied in config package too
@@ -205,6 +212,34 @@ func NewConfig(cfg *config.Config) (*Config, error) {
 		RuntimeCompiledConstantsEnabled: aconfig.Datadog.GetBool("runtime_security_config.runtime_compilation.compiled_constants_enabled"),
 		RuntimeCompiledConstantsIsSet:   aconfig.Datadog.IsSet("runtime_security_config.runtime_compilation.compiled_constants_enabled"),
 		RemoteConfigurationEnabled:      aconfig.Datadog.GetBool("runtime_security_config.remote_configuration.enabled"),
+		// rule actions
+		KillActionDryRun:    aconfig.Datadog.GetBool("runtime_security_config.actions.kill.dry_run"),
+		KillActionAllowlist: aconfig.Datadog.GetStringSlice("runtime_security_config.actions.kill.allowlist"),
+		// event export
+		EventExport: exporter.Config{
+			RuleIDs:     aconfig.Datadog.GetStringSlice("runtime_security_config.event_export.rule_ids"),
+			MinSeverity: aconfig.Datadog.GetString("runtime_security_config.event_export.min_severity"),
+			QueueSize:   aconfig.Datadog.GetInt("runtime_security_config.event_export.queue_size"),
+			File: exporter.FileConfig{
+				Enabled:    aconfig.Datadog.GetBool("runtime_security_config.event_export.file.enabled"),
+				Path:       aconfig.Datadog.GetString("runtime_security_config.event_export.file.path"),
+				MaxSize:    aconfig.Datadog.GetInt64("runtime_security_config.event_export.file.max_size") * 1024 * 1024,
+				MaxBackups: aconfig.Datadog.GetInt("runtime_security_config.event_export.file.max_backups"),
+			},
+			Syslog: exporter.SyslogConfig{
+				Enabled:  aconfig.Datadog.GetBool("runtime_security_config.event_export.syslog.enabled"),
+				Network:  aconfig.Datadog.GetString("runtime_security_config.event_export.syslog.network"),
+				Address:  aconfig.Datadog.GetString("runtime_security_config.event_export.syslog.address"),
+				AppName:  aconfig.Datadog.GetString("runtime_security_config.event_export.syslog.app_name"),
+				Facility: aconfig.Datadog.GetInt("runtime_security_config.event_export.syslog.facility"),
+			},
+			Webhook: exporter.WebhookConfig{
+				Enabled: aconfig.Datadog.GetBool("runtime_security_config.event_export.webhook.enabled"),
+				URL:     aconfig.Datadog.GetString("runtime_security_config.event_export.webhook.url"),
+				Headers: aconfig.Datadog.GetStringMapString("runtime_security_config.event_export.webhook.headers"),
+				Timeout: time.Duration(aconfig.Datadog.GetInt("runtime_security_config.event_export.webhook.timeout")) * time.Second,
+			},
+		},
 	}
 
 	// if runtime is enabled then we force fim
@@ -0,0 +1,300 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package exporter exports the runtime security events to local destinations, such as a rotating file, a syslog
+// server or a webhook, so that they can be collected by a SIEM. The events are still sent to Datadog.
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"go.uber.org/atomic"
+
+	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
+	"github.com/DataDog/datadog-agent/pkg/security/metrics"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+const (
+	defaultQueueSize = 1000
+	// flushTimeout is the time given to the outputs to write their queued events once the exporter is stopped
+	flushTimeout = 10 * time.Second
+)
+
+// severities lists the rule severities, from the lowest to the highest
+var severities = []rules.Severity{
+	rules.SeverityInfo,
+	rules.SeverityLow,
+	rules.SeverityMedium,
+	rules.SeverityHigh,
+	rules.SeverityCritical,
+}
+
+// severityLevel returns the rank of a severity, -1 for an unknown or empty severity
+func severityLevel(severity string) int {
+	for level, s := range severities {
+		if string(s) == severity {
+			return level
+		}
+	}
+	return -1
+}
+
+// Event describes an exported runtime security event
+type Event struct {
+	Timestamp time.Time       `json:"timestamp"`
+	RuleID    string          `json:"rule_id"`
+	Severity  string          `json:"severity,omitempty"`
+	Service   string          `json:"service,omitempty"`
+	Tags      []string        `json:"tags,omitempty"`
+	Data      json.RawMessage `json:"event"`
+}
+
+// Output describes a destination of the exported events
+type Output interface {
+	Name() string
+	Write(event *Event) error
+	Close() error
+}
+
+// FileConfig describes the export of the events to a rotating file, one JSON event per line
+type FileConfig struct {
+	Enabled bool
+	Path    string
+	// MaxSize is the size in bytes after which the file is rotated, 0 to never rotate it
+	MaxSize int64
+	// MaxBackups is the number of rotated files kept next to the current one
+	MaxBackups int
+}
+
+// SyslogConfig describes the export of the events to a syslog server, formatted as per RFC 5424
+type SyslogConfig struct {
+	Enabled bool
+	// Network is one of udp, tcp, unix or unixgram
+	Network  string
+	Address  string
+	AppName  string
+	Facility int
+}
+
+// WebhookConfig describes the export of the events to a webhook, posted as JSON
+type WebhookConfig struct {
+	Enabled bool
+	URL     string
+	Headers map[string]string
+	Timeout time.Duration
+}
+
+// Config describes the events to export and their destinations
+type Config struct {
+	// RuleIDs is the list of the rules of the exported events, all the rules when empty
+	RuleIDs []string
+	// MinSeverity is the minimum severity of the exported events, all the events when empty. The events of the
+	// rules without severity are always exported.
+	MinSeverity string
+	// QueueSize is the number of events waiting to be exported to an output after which its events are dropped
+	QueueSize int
+	File      FileConfig
+	Syslog    SyslogConfig
+	Webhook   WebhookConfig
+}
+
+// IsEnabled returns true if an output is enabled
+func (c *Config) IsEnabled() bool {
+	return c.File.Enabled || c.Syslog.Enabled || c.Webhook.Enabled
+}
+
+// queuedOutput is an output with its own queue of events, written from its own goroutine so that a slow or
+// unavailable output doesn't delay nor drop the events of the others
+type queuedOutput struct {
+	Output
+	events  chan *Event
+	dropped *atomic.Int64
+	errors  *atomic.Int64
+}
+
+// Exporter filters the events and exports them to its outputs, from their own goroutines so that a slow output
+// doesn't slow down the event server
+type Exporter struct {
+	ruleIDs     map[string]bool
+	minSeverity int
+	queueSize   int
+	outputs     []*queuedOutput
+}
+
+// NewExporter returns a new exporter to the enabled outputs of the config
+func NewExporter(cfg Config) (*Exporter, error) {
+	e := &Exporter{
+		minSeverity: -1,
+	}
+
+	if len(cfg.MinSeverity) != 0 {
+		if e.minSeverity = severityLevel(cfg.MinSeverity); e.minSeverity < 0 {
+			return nil, fmt.Errorf("invalid minimum severity `%s`, expected one of %v", cfg.MinSeverity, severities)
+		}
+	}
+
+	if len(cfg.RuleIDs) != 0 {
+		e.ruleIDs = make(map[string]bool, len(cfg.RuleIDs))
+		for _, id := range cfg.RuleIDs {
+			e.ruleIDs[id] = true
+		}
+	}
+
+	e.queueSize = cfg.QueueSize
+	if e.queueSize <= 0 {
+		e.queueSize = defaultQueueSize
+	}
+
+	if cfg.File.Enabled {
+		output, err := newFileOutput(cfg.File)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.addOutput(output)
+	}
+
+	if cfg.Syslog.Enabled {
+		output, err := newSyslogOutput(cfg.Syslog)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.addOutput(output)
+	}
+
+	if cfg.Webhook.Enabled {
+		output, err := newWebhookOutput(cfg.Webhook)
+		if err != nil {
+			e.Close()
+			return nil, err
+		}
+		e.addOutput(output)
+	}
+
+	return e, nil
+}
+
+func (e *Exporter) addOutput(output Output) {
+	e.outputs = append(e.outputs, &queuedOutput{
+		Output:  output,
+		events:  make(chan *Event, e.queueSize),
+		dropped: atomic.NewInt64(0),
+		errors:  atomic.NewInt64(0),
+	})
+}
+
+// Match returns true if the event of a rule with the given severity has to be exported. The events without
+// severity are not filtered by the minimum severity.
+func (e *Exporter) Match(ruleID string, severity string) bool {
+	if e.ruleIDs != nil && !e.ruleIDs[ruleID] {
+		return false
+	}
+	level := severityLevel(severity)
+	return e.minSeverity < 0 || level < 0 || level >= e.minSeverity
+}
+
+// Export queues an event for each output, if it matches the filters. The event is dropped for the outputs whose
+// queue is full.
+func (e *Exporter) Export(event *Event) {
+	if !e.Match(event.RuleID, event.Severity) {
+		return
+	}
+
+	for _, output := range e.outputs {
+		select {
+		case output.events <- event:
+		default:
+			output.dropped.Inc()
+			seclog.Tracef("the %s event export queue is full, an event of ID %v was dropped", output.Name(), event.RuleID)
+		}
+	}
+}
+
+// Run exports the queued events until the context is done, then writes the events still queued, for up to
+// flushTimeout, and closes the outputs
+func (e *Exporter) Run(ctx context.Context) {
+	defer e.Close()
+
+	var wg sync.WaitGroup
+	for _, output := range e.outputs {
+		wg.Add(1)
+		go func(output *queuedOutput) {
+			defer wg.Done()
+			output.run(ctx)
+		}(output)
+	}
+	wg.Wait()
+}
+
+func (o *queuedOutput) run(ctx context.Context) {
+	for {
+		select {
+		case event := <-o.events:
+			o.write(event)
+		case <-ctx.Done():
+			o.flush()
+			return
+		}
+	}
+}
+
+// flush writes the queued events, the ones still queued after flushTimeout are dropped
+func (o *queuedOutput) flush() {
+	deadline := time.Now().Add(flushTimeout)
+	for {
+		select {
+		case event := <-o.events:
+			if time.Now().After(deadline) {
+				o.dropped.Inc()
+				continue
+			}
+			o.write(event)
+		default:
+			return
+		}
+	}
+}
+
+func (o *queuedOutput) write(event *Event) {
+	if err := o.Write(event); err != nil {
+		o.errors.Inc()
+		seclog.Debugf("failed to export event of rule `%s` to %s: %v", event.RuleID, o.Name(), err)
+	}
+}
+
+// Close closes the outputs
+func (e *Exporter) Close() {
+	for _, output := range e.outputs {
+		if err := output.Close(); err != nil {
+			seclog.Warnf("failed to close the %s event export: %v", output.Name(), err)
+		}
+	}
+}
+
+// SendStats sends the number of events dropped because the queue of an output was full, and the number of export
+// errors, by output
+func (e *Exporter) SendStats(client statsd.ClientInterface) error {
+	for _, output := range e.outputs {
+		tags := []string{"output:" + output.Name()}
+		if count := output.dropped.Swap(0); count > 0 {
+			if err := client.Count(metrics.MetricEventExportDropped, count, tags, 1.0); err != nil {
+				return err
+			}
+		}
+		if count := output.errors.Swap(0); count > 0 {
+			if err := client.Count(metrics.MetricEventExportErrors, count, tags, 1.0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
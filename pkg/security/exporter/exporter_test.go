@@ -0,0 +1,296 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEvent(ruleID string, severity string) *Event {
+	return &Event{
+		Timestamp: time.Date(2022, 3, 1, 10, 30, 0, 123456000, time.UTC),
+		RuleID:    ruleID,
+		Severity:  severity,
+		Service:   "nginx",
+		Tags:      []string{"rule_id:" + ruleID},
+		Data:      json.RawMessage(`{"evt":{"name":"exec"}}`),
+	}
+}
+
+func TestMatch(t *testing.T) {
+	e, err := NewExporter(Config{})
+	require.NoError(t, err)
+	assert.True(t, e.Match("rule_a", ""))
+	assert.True(t, e.Match("rule_a", "info"))
+
+	e, err = NewExporter(Config{RuleIDs: []string{"rule_a", "rule_b"}, MinSeverity: "high"})
+	require.NoError(t, err)
+	assert.True(t, e.Match("rule_a", "high"))
+	assert.True(t, e.Match("rule_b", "critical"))
+	assert.False(t, e.Match("rule_a", "medium"))
+	assert.True(t, e.Match("rule_a", ""), "events without severity are exported")
+	assert.False(t, e.Match("rule_c", "critical"))
+
+	_, err = NewExporter(Config{MinSeverity: "urgent"})
+	assert.Error(t, err)
+}
+
+// testOutput records the written events, each write waiting for the release of the output
+type testOutput struct {
+	name    string
+	release chan struct{}
+	written chan *Event
+}
+
+func newTestOutput(name string, blocked bool) *testOutput {
+	o := &testOutput{name: name, release: make(chan struct{}), written: make(chan *Event, 10)}
+	if !blocked {
+		close(o.release)
+	}
+	return o
+}
+
+func (o *testOutput) Name() string {
+	return o.name
+}
+
+func (o *testOutput) Write(event *Event) error {
+	<-o.release
+	o.written <- event
+	return nil
+}
+
+func (o *testOutput) Close() error {
+	return nil
+}
+
+func TestExportDropped(t *testing.T) {
+	e, err := NewExporter(Config{QueueSize: 1, MinSeverity: "low"})
+	require.NoError(t, err)
+	e.addOutput(newTestOutput("test", true))
+
+	e.Export(newTestEvent("rule_a", "info"))
+	e.Export(newTestEvent("rule_a", "low"))
+	e.Export(newTestEvent("rule_a", "high"))
+
+	assert.Len(t, e.outputs[0].events, 1)
+	assert.Equal(t, int64(1), e.outputs[0].dropped.Load())
+}
+
+func TestExportSlowOutput(t *testing.T) {
+	e, err := NewExporter(Config{QueueSize: 1})
+	require.NoError(t, err)
+	slow := newTestOutput("slow", true)
+	fast := newTestOutput("fast", false)
+	e.addOutput(slow)
+	e.addOutput(fast)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	// the fast output writes its events while the slow one is blocked
+	for i := 0; i < 3; i++ {
+		e.Export(newTestEvent("rule_a", "high"))
+		select {
+		case <-fast.written:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event written by the fast output")
+		}
+	}
+	assert.Equal(t, int64(0), e.outputs[1].dropped.Load())
+	assert.NotZero(t, e.outputs[0].dropped.Load())
+
+	close(slow.release)
+	cancel()
+	<-done
+}
+
+func TestRunFlush(t *testing.T) {
+	e, err := NewExporter(Config{})
+	require.NoError(t, err)
+	output := newTestOutput("test", false)
+	e.addOutput(output)
+
+	for i := 0; i < 5; i++ {
+		e.Export(newTestEvent("rule_a", "high"))
+	}
+
+	// the events queued when the exporter is stopped are written before the outputs are closed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e.Run(ctx)
+
+	assert.Len(t, output.written, 5)
+	assert.Equal(t, int64(0), e.outputs[0].dropped.Load())
+}
+
+func readLines(t *testing.T, path string) []string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func TestFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events", "runtime-security-events.json")
+
+	line, err := json.Marshal(newTestEvent("rule_a", "high"))
+	require.NoError(t, err)
+
+	// two events by file
+	output, err := newFileOutput(FileConfig{Enabled: true, Path: path, MaxSize: int64(2 * (len(line) + 1)), MaxBackups: 2})
+	require.NoError(t, err)
+
+	for i := 0; i < 7; i++ {
+		require.NoError(t, output.Write(newTestEvent("rule_a", "high")))
+	}
+	require.NoError(t, output.Close())
+
+	assert.Len(t, readLines(t, path), 1)
+	assert.Len(t, readLines(t, path+".1"), 2)
+	assert.Len(t, readLines(t, path+".2"), 2)
+	assert.NoFileExists(t, path+".3")
+
+	var event Event
+	require.NoError(t, json.Unmarshal([]byte(readLines(t, path)[0]), &event))
+	assert.Equal(t, "rule_a", event.RuleID)
+	assert.Equal(t, "high", event.Severity)
+	assert.JSONEq(t, `{"evt":{"name":"exec"}}`, string(event.Data))
+
+	// the file is appended to on restart
+	output, err = newFileOutput(FileConfig{Enabled: true, Path: path})
+	require.NoError(t, err)
+	require.NoError(t, output.Write(newTestEvent("rule_b", "low")))
+	require.NoError(t, output.Close())
+	assert.Len(t, readLines(t, path), 2)
+}
+
+func TestSyslogOutput(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	output, err := newSyslogOutput(SyslogConfig{Enabled: true, Network: "udp", Address: conn.LocalAddr().String(), Facility: 10})
+	require.NoError(t, err)
+	defer output.Close()
+
+	event := newTestEvent("rule_a", "high")
+	require.NoError(t, output.Write(event))
+
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+
+	msg, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	expected := fmt.Sprintf("<83>1 2022-03-01T10:30:00.123456Z %s datadog-cws %d rule_a - %s", output.hostname, os.Getpid(), msg)
+	assert.Equal(t, expected, string(buf[:n]))
+
+	_, err = newSyslogOutput(SyslogConfig{Enabled: true, Network: "http", Address: "localhost:514"})
+	assert.Error(t, err)
+}
+
+func TestSyslogOutputStream(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	output, err := newSyslogOutput(SyslogConfig{Enabled: true, Network: "tcp", Address: ln.Addr().String(), AppName: "siem", Facility: 13})
+	require.NoError(t, err)
+	defer output.Close()
+
+	require.NoError(t, output.Write(newTestEvent("rule_a", "")))
+
+	conn, err := ln.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	reader := bufio.NewReader(conn)
+	length, err := reader.ReadString(' ')
+	require.NoError(t, err)
+
+	var size int
+	_, err = fmt.Sscanf(length, "%d ", &size)
+	require.NoError(t, err)
+
+	msg := make([]byte, size)
+	_, err = io.ReadFull(reader, msg)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(msg), "<110>1 "))
+	assert.Contains(t, string(msg), " siem ")
+	assert.True(t, strings.HasSuffix(string(msg), "}"))
+}
+
+func TestWebhookOutput(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer token" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var event Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	e, err := NewExporter(Config{
+		Webhook: WebhookConfig{Enabled: true, URL: server.URL, Headers: map[string]string{"Authorization": "Bearer token"}},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	e.Export(newTestEvent("rule_a", "medium"))
+
+	select {
+	case event := <-received:
+		assert.Equal(t, "rule_a", event.RuleID)
+		assert.Equal(t, "nginx", event.Service)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received by the webhook")
+	}
+
+	cancel()
+	<-done
+
+	output, err := newWebhookOutput(WebhookConfig{Enabled: true, URL: server.URL})
+	require.NoError(t, err)
+	assert.Error(t, output.Write(newTestEvent("rule_a", "medium")))
+
+	_, err = newWebhookOutput(WebhookConfig{Enabled: true, URL: "ftp://localhost"})
+	assert.Error(t, err)
+}
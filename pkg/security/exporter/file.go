@@ -0,0 +1,132 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// fileOutput writes the events to a file, one JSON event per line. The file is rotated to <path>.1, <path>.2, ...
+// once it reaches its maximum size.
+type fileOutput struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileOutput(cfg FileConfig) (*fileOutput, error) {
+	if len(cfg.Path) == 0 {
+		return nil, errors.New("no path defined for the file event export")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create the event export directory: %w", err)
+	}
+
+	o := &fileOutput{
+		path:       cfg.Path,
+		maxSize:    cfg.MaxSize,
+		maxBackups: cfg.MaxBackups,
+	}
+
+	if err := o.open(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Name returns the name of the output
+func (o *fileOutput) Name() string {
+	return "file"
+}
+
+func (o *fileOutput) open() error {
+	file, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("failed to open the event export file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	o.file = file
+	o.size = info.Size()
+	return nil
+}
+
+func (o *fileOutput) backupPath(index int) string {
+	return fmt.Sprintf("%s.%d", o.path, index)
+}
+
+func (o *fileOutput) rotate() error {
+	if err := o.file.Close(); err != nil {
+		return err
+	}
+	o.file = nil
+
+	if o.maxBackups <= 0 {
+		if err := os.Remove(o.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return o.open()
+	}
+
+	for i := o.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(o.backupPath(i), o.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(o.path, o.backupPath(1)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return o.open()
+}
+
+// Write writes the event, rotating the file first if the event doesn't fit in it
+func (o *fileOutput) Write(event *Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	// the file could not be reopened after the last rotation
+	if o.file == nil {
+		if err := o.open(); err != nil {
+			return err
+		}
+	}
+
+	if o.maxSize > 0 && o.size > 0 && o.size+int64(len(line)) > o.maxSize {
+		if err := o.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate the event export file: %w", err)
+		}
+	}
+
+	n, err := o.file.Write(line)
+	o.size += int64(n)
+	return err
+}
+
+// Close closes the file
+func (o *fileOutput) Close() error {
+	if o.file == nil {
+		return nil
+	}
+	err := o.file.Close()
+	o.file = nil
+	return err
+}
@@ -0,0 +1,184 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+const (
+	defaultSyslogAppName = "datadog-cws"
+	syslogDialTimeout    = 5 * time.Second
+	syslogWriteTimeout   = 5 * time.Second
+	syslogTimestamp      = "2006-01-02T15:04:05.000000Z07:00"
+	syslogNilValue       = "-"
+
+	// syslog severities, as per RFC 5424
+	syslogCritical      = 2
+	syslogError         = 3
+	syslogWarning       = 4
+	syslogNotice        = 5
+	syslogInformational = 6
+)
+
+// syslogSeverities maps the rule severities to the syslog ones
+var syslogSeverities = map[string]int{
+	string(rules.SeverityCritical): syslogCritical,
+	string(rules.SeverityHigh):     syslogError,
+	string(rules.SeverityMedium):   syslogWarning,
+	string(rules.SeverityLow):      syslogNotice,
+	string(rules.SeverityInfo):     syslogInformational,
+}
+
+// syslogOutput sends the events to a syslog server, formatted as per RFC 5424 with the JSON event as message. The
+// messages are framed with their length over stream connections, as per RFC 6587.
+type syslogOutput struct {
+	network  string
+	address  string
+	appName  string
+	facility int
+	hostname string
+	procID   string
+	conn     net.Conn
+}
+
+func newSyslogOutput(cfg SyslogConfig) (*syslogOutput, error) {
+	switch cfg.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("invalid syslog network `%s`, expected one of udp, tcp, unix or unixgram", cfg.Network)
+	}
+
+	if len(cfg.Address) == 0 {
+		return nil, errors.New("no address defined for the syslog event export")
+	}
+
+	if cfg.Facility < 0 || cfg.Facility > 23 {
+		return nil, fmt.Errorf("invalid syslog facility %d, expected a value between 0 and 23", cfg.Facility)
+	}
+
+	appName := cfg.AppName
+	if len(appName) == 0 {
+		appName = defaultSyslogAppName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = ""
+	}
+
+	// the connection is established on the first event so that an unavailable server doesn't prevent the start
+	return &syslogOutput{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		appName:  syslogHeaderField(appName, 48),
+		facility: cfg.Facility,
+		hostname: syslogHeaderField(hostname, 255),
+		procID:   strconv.Itoa(os.Getpid()),
+	}, nil
+}
+
+// Name returns the name of the output
+func (o *syslogOutput) Name() string {
+	return "syslog"
+}
+
+// syslogHeaderField returns a header field made of printable ASCII characters, truncated to its maximum length
+func syslogHeaderField(value string, maxLen int) string {
+	if len(value) == 0 {
+		return syslogNilValue
+	}
+
+	if len(value) > maxLen {
+		value = value[:maxLen]
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, value)
+}
+
+// format returns the RFC 5424 message of an event
+func (o *syslogOutput) format(event *Event) ([]byte, error) {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	severity, ok := syslogSeverities[event.Severity]
+	if !ok {
+		severity = syslogInformational
+	}
+
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s %s ",
+		o.facility*8+severity,
+		event.Timestamp.UTC().Format(syslogTimestamp),
+		o.hostname,
+		o.appName,
+		o.procID,
+		syslogHeaderField(event.RuleID, 32),
+		syslogNilValue,
+	)
+
+	return append([]byte(header), msg...), nil
+}
+
+func (o *syslogOutput) isStream() bool {
+	return o.network == "tcp" || o.network == "unix"
+}
+
+// Write sends the event, connecting to the server first if needed
+func (o *syslogOutput) Write(event *Event) error {
+	msg, err := o.format(event)
+	if err != nil {
+		return err
+	}
+
+	if o.isStream() {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+
+	if o.conn == nil {
+		conn, err := net.DialTimeout(o.network, o.address, syslogDialTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog server %s: %w", o.address, err)
+		}
+		o.conn = conn
+	}
+
+	if err := o.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err != nil {
+		return err
+	}
+
+	if _, err := o.conn.Write(msg); err != nil {
+		// reconnect on the next event
+		o.Close()
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the server
+func (o *syslogOutput) Close() error {
+	if o.conn == nil {
+		return nil
+	}
+	err := o.conn.Close()
+	o.conn = nil
+	return err
+}
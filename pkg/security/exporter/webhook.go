@@ -0,0 +1,96 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+)
+
+const defaultWebhookTimeout = 10 * time.Second
+
+// webhookOutput posts the events to a webhook, one JSON event per request
+type webhookOutput struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newWebhookOutput(cfg WebhookConfig) (*webhookOutput, error) {
+	if len(cfg.URL) == 0 {
+		return nil, errors.New("no URL defined for the webhook event export")
+	}
+
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid webhook URL scheme `%s`, expected http or https", u.Scheme)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+
+	return &webhookOutput{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: timeout, Transport: httputils.CreateHTTPTransport()},
+	}, nil
+}
+
+// Name returns the name of the output
+func (o *webhookOutput) Name() string {
+	return "webhook"
+}
+
+// Write posts the event
+func (o *webhookOutput) Write(event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range o.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %s", resp.Status)
+	}
+	return nil
+}
+
+// Close closes the idle connections to the webhook
+func (o *webhookOutput) Close() error {
+	o.client.CloseIdleConnections()
+	return nil
+}
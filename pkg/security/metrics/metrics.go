@@ -29,6 +29,16 @@ var (
 	// Tags: -
 	MetricProcessEventsServerExpired = newRuntimeMetric(".event_server.process_events_expired")
 
+	// Event export metrics
+
+	// MetricEventExportDropped is the name of the metric used to count the number of events that were not exported
+	// because the export queue of an output was full
+	// Tags: output
+	MetricEventExportDropped = newRuntimeMetric(".event_export.dropped")
+	// MetricEventExportErrors is the name of the metric used to count the number of events that failed to be exported
+	// Tags: output
+	MetricEventExportErrors = newRuntimeMetric(".event_export.errors")
+
 	// Load controller metrics
 
 	// MetricLoadControllerPidDiscarder is the name of the metric used to count the number of pid discarders
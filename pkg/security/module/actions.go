@@ -0,0 +1,77 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// maxSuppressedKeys bounds the number of keys tracked by the rate limit actions
+const maxSuppressedKeys = 10000
+
+// ActionSuppressor implements the rate limit actions of the rules: once an event was sent
+// for a rule, the next events with the same key are suppressed for the duration of the action.
+type ActionSuppressor struct {
+	sync.Mutex
+	expirations *simplelru.LRU
+}
+
+// NewActionSuppressor returns a new ActionSuppressor
+func NewActionSuppressor() *ActionSuppressor {
+	expirations, _ := simplelru.NewLRU(maxSuppressedKeys, nil)
+	return &ActionSuppressor{expirations: expirations}
+}
+
+// IsSuppressed returns whether the event must be suppressed by a rate limit action of the
+// rule. Otherwise, the keys of the event are suppressed from now on.
+func (s *ActionSuppressor) IsSuppressed(rule *rules.Rule, event eval.Event, now time.Time) bool {
+	var keys []string
+	var durations []time.Duration
+
+	for _, action := range rule.Definition.Actions {
+		if action.RateLimit == nil {
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString(rule.Definition.ID)
+		for _, field := range action.RateLimit.Fields {
+			value, _ := event.GetFieldValue(field)
+			fmt.Fprintf(&b, "|%v", value)
+		}
+		keys = append(keys, b.String())
+		durations = append(durations, action.RateLimit.Duration)
+	}
+
+	if len(keys) == 0 {
+		return false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, key := range keys {
+		if expiration, found := s.expirations.Get(key); found && now.Before(expiration.(time.Time)) {
+			return true
+		}
+	}
+
+	for i, key := range keys {
+		s.expirations.Add(key, now.Add(durations[i]))
+	}
+	return false
+}
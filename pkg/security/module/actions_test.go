@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+func TestActionSuppressor(t *testing.T) {
+	rule := &rules.Rule{
+		Definition: &rules.RuleDefinition{
+			ID: "test_rule",
+			Actions: []rules.ActionDefinition{
+				{RateLimit: &rules.RateLimitDefinition{Fields: []string{"container.id"}, Duration: time.Minute}},
+			},
+		},
+	}
+
+	newEvent := func(containerID string) *model.Event {
+		event := &model.Event{}
+		event.ContainerContext.ID = containerID
+		return event
+	}
+
+	suppressor := NewActionSuppressor()
+	now := time.Now()
+
+	if suppressor.IsSuppressed(rule, newEvent("abc"), now) {
+		t.Error("the first event must not be suppressed")
+	}
+	if !suppressor.IsSuppressed(rule, newEvent("abc"), now.Add(30*time.Second)) {
+		t.Error("a repeated event must be suppressed")
+	}
+	if suppressor.IsSuppressed(rule, newEvent("def"), now.Add(30*time.Second)) {
+		t.Error("an event with another key must not be suppressed")
+	}
+	if suppressor.IsSuppressed(rule, newEvent("abc"), now.Add(2*time.Minute)) {
+		t.Error("an event must not be suppressed once the duration expired")
+	}
+
+	if suppressor.IsSuppressed(&rules.Rule{Definition: &rules.RuleDefinition{ID: "other_rule"}}, newEvent("abc"), now) {
+		t.Error("an event of a rule without rate limit action must not be suppressed")
+	}
+}
@@ -22,4 +22,5 @@ type AgentContext struct {
 type Signal struct {
 	AgentContext `json:"agent"`
 	Title        string `json:"title"`
+	Severity     string `json:"severity,omitempty"`
 }
@@ -0,0 +1,51 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package module
+
+import (
+	"encoding/json"
+
+	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// matchedEvent is an event matching a rule, serialized with the sections added by the
+// rule: the chain of the events of a sequence rule and the reports of the rule actions
+type matchedEvent struct {
+	*sprobe.Event
+	sequence *rules.SequenceMatch
+	actions  []*sprobe.ActionReport
+}
+
+// MarshalJSON adds the sections of the rule to the serialization of the event
+func (e *matchedEvent) MarshalJSON() ([]byte, error) {
+	data, err := e.Event.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.sequence == nil && len(e.actions) == 0 {
+		return data, nil
+	}
+
+	sections, err := json.Marshal(struct {
+		Sequence *rules.SequenceMatch   `json:"sequence,omitempty"`
+		Actions  []*sprobe.ActionReport `json:"rule_actions,omitempty"`
+	}{
+		Sequence: e.sequence,
+		Actions:  e.actions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// both are serialized as non empty objects, the event having at least the `evt` section
+	data = append(data[:len(data)-1], ',')
+	return append(data, sections[1:]...), nil
+}
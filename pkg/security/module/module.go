@@ -63,6 +63,8 @@ type Module struct {
 	grpcServer       *grpc.Server
 	listener         net.Listener
 	rateLimiter      *RateLimiter
+	suppressor       *ActionSuppressor
+	processKiller    *sprobe.ProcessKiller
 	sigupChan        chan os.Signal
 	ctx              context.Context
 	cancelFnc        context.CancelFunc
@@ -149,10 +151,6 @@ func (m *Module) Start() error {
 		return nil
 	}
 
-	if m.config.SelfTestEnabled && m.selfTester != nil {
-		_ = m.RunSelfTest(true)
-	}
-
 	var policyProviders []rules.PolicyProvider
 
 	// directory policy provider
@@ -177,6 +175,11 @@ func (m *Module) Start() error {
 		log.Errorf("failed to load policies: %s", err)
 	}
 
+	// run the self tests once the policies are loaded so that the actions in use are known
+	if m.config.SelfTestEnabled && m.selfTester != nil {
+		_ = m.RunSelfTest(true)
+	}
+
 	m.wg.Add(1)
 	go m.metricsSender()
 
@@ -441,14 +444,32 @@ func (m *Module) HandleCustomEvent(rule *rules.Rule, event *sprobe.CustomEvent)
 
 // RuleMatch is called by the ruleset when a rule matches
 func (m *Module) RuleMatch(rule *rules.Rule, event eval.Event) {
+	ev := event.(*sprobe.Event)
+	m.ruleMatch(rule, ev, nil)
+}
+
+// SequenceMatch is called by the ruleset when an event completes a sequence rule
+func (m *Module) SequenceMatch(rule *rules.Rule, event eval.Event, match *rules.SequenceMatch) {
+	ev := event.(*sprobe.Event)
+	m.ruleMatch(rule, ev, match)
+}
+
+func (m *Module) ruleMatch(rule *rules.Rule, event *sprobe.Event, sequence *rules.SequenceMatch) {
 	// prepare the event
-	m.probe.OnRuleMatch(rule, event.(*sprobe.Event))
+	m.probe.OnRuleMatch(rule, event)
+
+	// the kill actions are executed even if the event is suppressed by a rate limit action
+	actions := m.runRuleActions(rule, event)
+	if m.suppressor.IsSuppressed(rule, event, time.Now()) {
+		seclog.Tracef("Event on rule %s was suppressed by a rate limit action", rule.ID)
+		return
+	}
 
 	// needs to be resolved here, outside of the callback as using process tree
 	// which can be modified during queuing
-	service := event.(*sprobe.Event).GetProcessServiceTag()
+	service := event.GetProcessServiceTag()
 
-	id := event.(*sprobe.Event).ContainerContext.ID
+	id := event.ContainerContext.ID
 
 	extTagsCb := func() []string {
 		var tags []string
@@ -466,11 +487,27 @@ func (m *Module) RuleMatch(rule *rules.Rule, event eval.Event) {
 	}
 
 	// send if not selftest related events
-	if m.selfTester == nil || !m.selfTester.IsExpectedEvent(rule, event) {
-		m.SendEvent(rule, event, extTagsCb, service)
+	if m.selfTester == nil || !m.selfTester.IsExpectedEvent(rule, event, actions) {
+		m.SendEvent(rule, &matchedEvent{Event: event, sequence: sequence, actions: actions}, extTagsCb, service)
 	}
 }
 
+// runRuleActions executes the kill actions of the rule, the other actions being applied
+// by the rule set or when the event is sent
+func (m *Module) runRuleActions(rule *rules.Rule, event *sprobe.Event) []*sprobe.ActionReport {
+	var reports []*sprobe.ActionReport
+	for _, action := range rule.Definition.Actions {
+		if action.Kill != nil {
+			report := m.processKiller.KillProcess(event, action.Kill)
+			if report.Status == sprobe.ActionFailed {
+				seclog.Warnf("failed to execute the kill action of rule %s: %s", rule.ID, report.Error)
+			}
+			reports = append(reports, report)
+		}
+	}
+	return reports
+}
+
 // SendEvent sends an event to the backend after checking that the rate limiter allows it for the provided rule
 func (m *Module) SendEvent(rule *rules.Rule, event Event, extTagsCb func() []string, service string) {
 	if m.rateLimiter.Allow(rule.ID) {
@@ -586,25 +623,37 @@ func NewModule(cfg *sconfig.Config, opts ...Opts) (module.Module, error) {
 		return nil, err
 	}
 
+	apiServer, err := NewAPIServer(cfg, probe, statsdClient)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancelFnc := context.WithCancel(context.Background())
 
 	// custom limiters
 	limits := make(map[rules.RuleID]Limit)
 
-	selfTester, err := selftests.NewSelfTester()
+	selfTester, err := selftests.NewSelfTester(cfg)
 	if err != nil {
 		log.Errorf("unable to instantiate self tests: %s", err)
 	}
 
+	processKiller, err := sprobe.NewProcessKiller(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	m := &Module{
 		config:         cfg,
 		probe:          probe,
 		currentRuleSet: new(atomic.Value),
 		reloading:      atomic.NewBool(false),
 		statsdClient:   statsdClient,
-		apiServer:      NewAPIServer(cfg, probe, statsdClient),
+		apiServer:      apiServer,
 		grpcServer:     grpc.NewServer(),
 		rateLimiter:    NewRateLimiter(statsdClient, LimiterOpts{Limits: limits}),
+		suppressor:     NewActionSuppressor(),
+		processKiller:  processKiller,
 		sigupChan:      make(chan os.Signal, 1),
 		ctx:            ctx,
 		cancelFnc:      cancelFnc,
@@ -624,6 +673,9 @@ func NewModule(cfg *sconfig.Config, opts ...Opts) (module.Module, error) {
 func (m *Module) RunSelfTest(sendLoadedReport bool) error {
 	prevProviders, providers := m.policyProviders, m.policyProviders
 
+	// only test the kill action if the loaded rules use it
+	m.selfTester.SetKillActionsInUse(hasKillAction(m.GetRuleSet()))
+
 	// add selftests as provider
 	providers = append(providers, m.selfTester)
 	defer func() {
@@ -647,6 +699,22 @@ func (m *Module) RunSelfTest(sendLoadedReport bool) error {
 	return err
 }
 
+// hasKillAction returns whether a rule of the rule set has a kill action
+func hasKillAction(rs *rules.RuleSet) bool {
+	if rs == nil {
+		return false
+	}
+
+	for _, rule := range rs.GetRules() {
+		for _, action := range rule.Definition.Actions {
+			if action.Kill != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func logLoadingErrors(msg string, m *multierror.Error) {
 	var errorLevel bool
 	for _, err := range m.Errors {
@@ -24,6 +24,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/pkg/security/api"
 	"github.com/DataDog/datadog-agent/pkg/security/config"
+	"github.com/DataDog/datadog-agent/pkg/security/exporter"
 	seclog "github.com/DataDog/datadog-agent/pkg/security/log"
 	"github.com/DataDog/datadog-agent/pkg/security/metrics"
 	sprobe "github.com/DataDog/datadog-agent/pkg/security/probe"
@@ -35,6 +36,8 @@ import (
 
 type pendingMsg struct {
 	ruleID    string
+	severity  string
+	timestamp time.Time
 	data      []byte
 	tags      map[string]bool
 	service   string
@@ -58,6 +61,7 @@ type APIServer struct {
 	retention            time.Duration
 	cfg                  *config.Config
 	module               *Module
+	exporter             *exporter.Exporter
 }
 
 // GetEvents waits for security events
@@ -316,6 +320,18 @@ func (a *APIServer) start(ctx context.Context) {
 					Tags:    tags,
 				}
 
+				// export the event locally once its tags are resolved, in addition to sending it to Datadog
+				if a.exporter != nil {
+					a.exporter.Export(&exporter.Event{
+						Timestamp: msg.timestamp,
+						RuleID:    msg.ruleID,
+						Severity:  msg.severity,
+						Service:   msg.service,
+						Tags:      tags,
+						Data:      msg.data,
+					})
+				}
+
 				select {
 				case a.msgs <- m:
 					break
@@ -344,6 +360,10 @@ func (a *APIServer) start(ctx context.Context) {
 // Start the api server, starts to consume the msg queue
 func (a *APIServer) Start(ctx context.Context) {
 	go a.start(ctx)
+
+	if a.exporter != nil {
+		go a.exporter.Run(ctx)
+	}
 }
 
 // GetConfig returns config of the runtime security module required by the security agent
@@ -391,9 +411,12 @@ func (a *APIServer) SendEvent(rule *rules.Rule, event Event, extTagsCb func() []
 		Version:     version.AgentVersion,
 	}
 
+	enrichTags, severity := rule.Definition.GetEnrichment()
+
 	ruleEvent := &Signal{
 		Title:        rule.Definition.Description,
 		AgentContext: agentContext,
+		Severity:     string(severity),
 	}
 
 	if policy := rule.Definition.Policy; policy != nil {
@@ -417,13 +440,16 @@ func (a *APIServer) SendEvent(rule *rules.Rule, event Event, extTagsCb func() []
 	data = append(data, ruleEventJSON[1:]...)
 	seclog.Tracef("Sending event message for rule `%s` to security-agent `%s`", rule.ID, string(data))
 
+	now := time.Now()
 	msg := &pendingMsg{
 		ruleID:    rule.Definition.ID,
+		severity:  string(severity),
+		timestamp: now,
 		data:      data,
 		extTagsCb: extTagsCb,
 		tags:      make(map[string]bool),
 		service:   service,
-		sendAfter: time.Now().Add(a.retention),
+		sendAfter: now.Add(a.retention),
 	}
 
 	msg.tags["rule_id:"+rule.Definition.ID] = true
@@ -432,6 +458,10 @@ func (a *APIServer) SendEvent(rule *rules.Rule, event Event, extTagsCb func() []
 		msg.tags[tag] = true
 	}
 
+	for _, tag := range enrichTags {
+		msg.tags[tag] = true
+	}
+
 	for _, tag := range event.GetTags() {
 		msg.tags[tag] = true
 	}
@@ -481,6 +511,10 @@ func (a *APIServer) SendStats() error {
 			return err
 		}
 	}
+
+	if a.exporter != nil {
+		return a.exporter.SendStats(a.statsdClient)
+	}
 	return nil
 }
 
@@ -531,7 +565,7 @@ func (a *APIServer) SendProcessEvent(data []byte) {
 }
 
 // NewAPIServer returns a new gRPC event server
-func NewAPIServer(cfg *config.Config, probe *sprobe.Probe, client statsd.ClientInterface) *APIServer {
+func NewAPIServer(cfg *config.Config, probe *sprobe.Probe, client statsd.ClientInterface) (*APIServer, error) {
 	es := &APIServer{
 		msgs:                 make(chan *api.SecurityEventMessage, cfg.EventServerBurst*3),
 		processMsgs:          make(chan *api.SecurityProcessEventMessage, cfg.EventServerBurst*3),
@@ -543,5 +577,15 @@ func NewAPIServer(cfg *config.Config, probe *sprobe.Probe, client statsd.ClientI
 		retention:            time.Duration(cfg.EventServerRetention) * time.Second,
 		cfg:                  cfg,
 	}
-	return es
+
+	if cfg.EventExport.IsEnabled() {
+		// the local export is optional, the events are still sent to Datadog without it
+		if eventExporter, err := exporter.NewExporter(cfg.EventExport); err != nil {
+			seclog.Errorf("failed to create the event exporter, the events won't be exported locally: %v", err)
+		} else {
+			es.exporter = eventExporter
+		}
+	}
+
+	return es, nil
 }
@@ -308,6 +308,21 @@ func (ad *ActivityDump) dump() {
 	seclog.Infof("activity dump for [%s] written at: %s", ad.GetSelectorStr(), ad.OutputFile)
 }
 
+// LoadActivityDump loads an activity dump written in the message pack format
+func LoadActivityDump(inputFile string) (*ActivityDump, error) {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't open activity dump file: %w", err)
+	}
+	defer f.Close()
+
+	var dump ActivityDump
+	if err = dump.DecodeMsg(msgp.NewReader(f)); err != nil {
+		return nil, fmt.Errorf("couldn't parse activity dump file: %w", err)
+	}
+	return &dump, nil
+}
+
 // nolint: unused
 func (ad *ActivityDump) debug() {
 	for _, root := range ad.ProcessActivityTree {
@@ -363,6 +378,8 @@ func (ad *ActivityDump) Insert(event *Event) (newEntry bool) {
 	switch event.GetEventType() {
 	case model.FileOpenEventType:
 		return node.InsertFileEvent(&event.Open.File, event, Runtime)
+	case model.DNSEventType:
+		return node.InsertDNSEvent(event, Runtime)
 	}
 	return false
 }
@@ -662,6 +679,7 @@ type ProcessActivityNode struct {
 	GenerationType NodeGenerationType `msg:"generation_type"`
 
 	Files    map[string]*FileActivityNode `msg:"files,omitempty"`
+	DNSNames map[string]*DNSNode          `msg:"dns_names,omitempty"`
 	Children []*ProcessActivityNode       `msg:"children,omitempty"`
 }
 
@@ -679,6 +697,7 @@ func NewProcessActivityNode(entry *model.ProcessCacheEntry, generationType NodeG
 		Process:        entry.Process,
 		GenerationType: generationType,
 		Files:          make(map[string]*FileActivityNode),
+		DNSNames:       make(map[string]*DNSNode),
 	}
 	_ = pan.GetID()
 	pan.retain()
@@ -811,6 +830,25 @@ func (pan *ProcessActivityNode) InsertFileEvent(fileEvent *model.FileEvent, even
 	return true
 }
 
+// InsertDNSEvent inserts the provided DNS event in the current node. This function returns true if a new entry was
+// added, false if the event was dropped.
+func (pan *ProcessActivityNode) InsertDNSEvent(event *Event, generationType NodeGenerationType) bool {
+	if len(event.DNS.Name) == 0 {
+		return false
+	}
+
+	if pan.DNSNames == nil {
+		pan.DNSNames = make(map[string]*DNSNode)
+	}
+
+	if dnsNode, ok := pan.DNSNames[event.DNS.Name]; ok {
+		return dnsNode.insertType(event.DNS.Type)
+	}
+
+	pan.DNSNames[event.DNS.Name] = NewDNSNode(event, generationType)
+	return true
+}
+
 // snapshot uses procfs to retrieve information about the current process
 func (pan *ProcessActivityNode) snapshot(ad *ActivityDump) error {
 	// call snapshot for all the children of the current node
@@ -1015,3 +1053,42 @@ func (fan *FileActivityNode) debug(prefix string) {
 		child.debug("\t" + prefix)
 	}
 }
+
+// DNSNode holds the DNS requests of a process for a domain name
+type DNSNode struct {
+	id             string
+	GenerationType NodeGenerationType `msg:"generation_type"`
+	FirstSeen      time.Time          `msg:"first_seen,omitempty"`
+	// Types lists the question types of the requests
+	Types []uint16 `msg:"types,omitempty"`
+}
+
+// NewDNSNode returns a new DNSNode instance
+func NewDNSNode(event *Event, generationType NodeGenerationType) *DNSNode {
+	dn := &DNSNode{
+		GenerationType: generationType,
+		FirstSeen:      event.ResolveEventTimestamp(),
+		Types:          []uint16{event.DNS.Type},
+	}
+	_ = dn.GetID()
+	return dn
+}
+
+// GetID returns a unique ID to identify the current node
+func (dn *DNSNode) GetID() string {
+	if len(dn.id) == 0 {
+		dn.id = eval.RandString(5)
+	}
+	return dn.id
+}
+
+// insertType adds a question type to the node, it returns false if the type was already known
+func (dn *DNSNode) insertType(qtype uint16) bool {
+	for _, t := range dn.Types {
+		if t == qtype {
+			return false
+		}
+	}
+	dn.Types = append(dn.Types, qtype)
+	return true
+}
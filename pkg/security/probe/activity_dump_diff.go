@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ActivitySummary holds the sets of executables, file paths and DNS names recorded in an activity dump
+type ActivitySummary struct {
+	Executables map[string]bool
+	Files       map[string]bool
+	DNSNames    map[string]bool
+}
+
+// Summarize returns the executables, file paths and DNS names of the activity dump
+func (ad *ActivityDump) Summarize() *ActivitySummary {
+	summary := &ActivitySummary{
+		Executables: make(map[string]bool),
+		Files:       make(map[string]bool),
+		DNSNames:    make(map[string]bool),
+	}
+
+	for _, node := range ad.ProcessActivityTree {
+		summary.addProcessNode(node)
+	}
+	return summary
+}
+
+func (s *ActivitySummary) addProcessNode(node *ProcessActivityNode) {
+	if path := node.Process.FileEvent.PathnameStr; len(path) > 0 {
+		s.Executables[path] = true
+	}
+
+	for _, file := range node.Files {
+		s.addFileNode(file)
+	}
+
+	for name := range node.DNSNames {
+		s.DNSNames[name] = true
+	}
+
+	for _, child := range node.Children {
+		s.addProcessNode(child)
+	}
+}
+
+func (s *ActivitySummary) addFileNode(node *FileActivityNode) {
+	// the intermediate nodes of the tree have no file, the snapshot may insert memory
+	// mappings which aren't files, such as [heap]
+	if node.File != nil && strings.HasPrefix(node.File.PathnameStr, "/") {
+		s.Files[node.File.PathnameStr] = true
+	}
+
+	for _, child := range node.Children {
+		s.addFileNode(child)
+	}
+}
+
+// ActivityDiff lists the entries added and removed from one activity dump to another
+type ActivityDiff struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// IsEmpty returns whether no entry was added or removed
+func (d ActivityDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+func diffSets(base, other map[string]bool) ActivityDiff {
+	var diff ActivityDiff
+	for entry := range other {
+		if !base[entry] {
+			diff.Added = append(diff.Added, entry)
+		}
+	}
+	for entry := range base {
+		if !other[entry] {
+			diff.Removed = append(diff.Removed, entry)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	return diff
+}
+
+// ActivityDumpDiff holds the differences between the activity of two dumps
+type ActivityDumpDiff struct {
+	Executables ActivityDiff `json:"executables"`
+	Files       ActivityDiff `json:"files"`
+	DNSNames    ActivityDiff `json:"dns_names"`
+}
+
+// IsEmpty returns whether the dumps have the same activity
+func (d *ActivityDumpDiff) IsEmpty() bool {
+	return d.Executables.IsEmpty() && d.Files.IsEmpty() && d.DNSNames.IsEmpty()
+}
+
+// DiffActivityDumps returns the executables, file paths and DNS names added and removed
+// in an activity dump compared to a base dump
+func DiffActivityDumps(base, other *ActivityDump) *ActivityDumpDiff {
+	baseSummary, otherSummary := base.Summarize(), other.Summarize()
+
+	return &ActivityDumpDiff{
+		Executables: diffSets(baseSummary.Executables, otherSummary.Executables),
+		Files:       diffSets(baseSummary.Files, otherSummary.Files),
+		DNSNames:    diffSets(baseSummary.DNSNames, otherSummary.DNSNames),
+	}
+}
+
+// WriteText writes the differences in a human readable format
+func (d *ActivityDumpDiff) WriteText(w io.Writer) error {
+	var b strings.Builder
+
+	for _, section := range []struct {
+		name string
+		diff ActivityDiff
+	}{
+		{name: "executables", diff: d.Executables},
+		{name: "files", diff: d.Files},
+		{name: "dns names", diff: d.DNSNames},
+	} {
+		if section.diff.IsEmpty() {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%s:\n", section.name)
+		for _, entry := range section.diff.Added {
+			fmt.Fprintf(&b, "  + %s\n", entry)
+		}
+		for _, entry := range section.diff.Removed {
+			fmt.Fprintf(&b, "  - %s\n", entry)
+		}
+	}
+
+	if d.IsEmpty() {
+		b.WriteString("no difference\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
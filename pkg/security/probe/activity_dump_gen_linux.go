@@ -507,6 +507,244 @@ func (z *ActivityDump) Msgsize() (s int) {
 	return
 }
 
+// DecodeMsg implements msgp.Decodable
+func (z *DNSNode) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "generation_type":
+			{
+				var zb0002 string
+				zb0002, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "GenerationType")
+					return
+				}
+				z.GenerationType = NodeGenerationType(zb0002)
+			}
+		case "first_seen":
+			z.FirstSeen, err = dc.ReadTime()
+			if err != nil {
+				err = msgp.WrapError(err, "FirstSeen")
+				return
+			}
+		case "types":
+			var zb0003 uint32
+			zb0003, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Types")
+				return
+			}
+			if cap(z.Types) >= int(zb0003) {
+				z.Types = (z.Types)[:zb0003]
+			} else {
+				z.Types = make([]uint16, zb0003)
+			}
+			for za0001 := range z.Types {
+				z.Types[za0001], err = dc.ReadUint16()
+				if err != nil {
+					err = msgp.WrapError(err, "Types", za0001)
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *DNSNode) EncodeMsg(en *msgp.Writer) (err error) {
+	// omitempty: check for empty values
+	zb0001Len := uint32(3)
+	var zb0001Mask uint8 /* 3 bits */
+	if z.FirstSeen == (time.Time{}) {
+		zb0001Len--
+		zb0001Mask |= 0x2
+	}
+	if z.Types == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	// variable map header, size zb0001Len
+	err = en.Append(0x80 | uint8(zb0001Len))
+	if err != nil {
+		return
+	}
+	if zb0001Len == 0 {
+		return
+	}
+	// write "generation_type"
+	err = en.Append(0xaf, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(string(z.GenerationType))
+	if err != nil {
+		err = msgp.WrapError(err, "GenerationType")
+		return
+	}
+	if (zb0001Mask & 0x2) == 0 { // if not empty
+		// write "first_seen"
+		err = en.Append(0xaa, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e)
+		if err != nil {
+			return
+		}
+		err = en.WriteTime(z.FirstSeen)
+		if err != nil {
+			err = msgp.WrapError(err, "FirstSeen")
+			return
+		}
+	}
+	if (zb0001Mask & 0x4) == 0 { // if not empty
+		// write "types"
+		err = en.Append(0xa5, 0x74, 0x79, 0x70, 0x65, 0x73)
+		if err != nil {
+			return
+		}
+		err = en.WriteArrayHeader(uint32(len(z.Types)))
+		if err != nil {
+			err = msgp.WrapError(err, "Types")
+			return
+		}
+		for za0001 := range z.Types {
+			err = en.WriteUint16(z.Types[za0001])
+			if err != nil {
+				err = msgp.WrapError(err, "Types", za0001)
+				return
+			}
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *DNSNode) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// omitempty: check for empty values
+	zb0001Len := uint32(3)
+	var zb0001Mask uint8 /* 3 bits */
+	if z.FirstSeen == (time.Time{}) {
+		zb0001Len--
+		zb0001Mask |= 0x2
+	}
+	if z.Types == nil {
+		zb0001Len--
+		zb0001Mask |= 0x4
+	}
+	// variable map header, size zb0001Len
+	o = append(o, 0x80|uint8(zb0001Len))
+	if zb0001Len == 0 {
+		return
+	}
+	// string "generation_type"
+	o = append(o, 0xaf, 0x67, 0x65, 0x6e, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65)
+	o = msgp.AppendString(o, string(z.GenerationType))
+	if (zb0001Mask & 0x2) == 0 { // if not empty
+		// string "first_seen"
+		o = append(o, 0xaa, 0x66, 0x69, 0x72, 0x73, 0x74, 0x5f, 0x73, 0x65, 0x65, 0x6e)
+		o = msgp.AppendTime(o, z.FirstSeen)
+	}
+	if (zb0001Mask & 0x4) == 0 { // if not empty
+		// string "types"
+		o = append(o, 0xa5, 0x74, 0x79, 0x70, 0x65, 0x73)
+		o = msgp.AppendArrayHeader(o, uint32(len(z.Types)))
+		for za0001 := range z.Types {
+			o = msgp.AppendUint16(o, z.Types[za0001])
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *DNSNode) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "generation_type":
+			{
+				var zb0002 string
+				zb0002, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "GenerationType")
+					return
+				}
+				z.GenerationType = NodeGenerationType(zb0002)
+			}
+		case "first_seen":
+			z.FirstSeen, bts, err = msgp.ReadTimeBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "FirstSeen")
+				return
+			}
+		case "types":
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Types")
+				return
+			}
+			if cap(z.Types) >= int(zb0003) {
+				z.Types = (z.Types)[:zb0003]
+			} else {
+				z.Types = make([]uint16, zb0003)
+			}
+			for za0001 := range z.Types {
+				z.Types[za0001], bts, err = msgp.ReadUint16Bytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Types", za0001)
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *DNSNode) Msgsize() (s int) {
+	s = 1 + 16 + msgp.StringPrefixSize + len(string(z.GenerationType)) + 11 + msgp.TimeSize + 6 + msgp.ArrayHeaderSize + (len(z.Types) * (msgp.Uint16Size))
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *FileActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
@@ -1451,33 +1689,75 @@ func (z *ProcessActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 				}
 				z.Files[za0001] = za0002
 			}
-		case "children":
+		case "dns_names":
 			var zb0004 uint32
-			zb0004, err = dc.ReadArrayHeader()
+			zb0004, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if z.DNSNames == nil {
+				z.DNSNames = make(map[string]*DNSNode, zb0004)
+			} else if len(z.DNSNames) > 0 {
+				for key := range z.DNSNames {
+					delete(z.DNSNames, key)
+				}
+			}
+			for zb0004 > 0 {
+				zb0004--
+				var za0003 string
+				var za0004 *DNSNode
+				za0003, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames")
+					return
+				}
+				if dc.IsNil() {
+					err = dc.ReadNil()
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+					za0004 = nil
+				} else {
+					if za0004 == nil {
+						za0004 = new(DNSNode)
+					}
+					err = za0004.DecodeMsg(dc)
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+				}
+				z.DNSNames[za0003] = za0004
+			}
+		case "children":
+			var zb0005 uint32
+			zb0005, err = dc.ReadArrayHeader()
 			if err != nil {
 				err = msgp.WrapError(err, "Children")
 				return
 			}
-			if cap(z.Children) >= int(zb0004) {
-				z.Children = (z.Children)[:zb0004]
+			if cap(z.Children) >= int(zb0005) {
+				z.Children = (z.Children)[:zb0005]
 			} else {
-				z.Children = make([]*ProcessActivityNode, zb0004)
+				z.Children = make([]*ProcessActivityNode, zb0005)
 			}
-			for za0003 := range z.Children {
+			for za0005 := range z.Children {
 				if dc.IsNil() {
 					err = dc.ReadNil()
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
-					z.Children[za0003] = nil
+					z.Children[za0005] = nil
 				} else {
-					if z.Children[za0003] == nil {
-						z.Children[za0003] = new(ProcessActivityNode)
+					if z.Children[za0005] == nil {
+						z.Children[za0005] = new(ProcessActivityNode)
 					}
-					err = z.Children[za0003].DecodeMsg(dc)
+					err = z.Children[za0005].DecodeMsg(dc)
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
 				}
@@ -1496,16 +1776,20 @@ func (z *ProcessActivityNode) DecodeMsg(dc *msgp.Reader) (err error) {
 // EncodeMsg implements msgp.Encodable
 func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 	// omitempty: check for empty values
-	zb0001Len := uint32(4)
-	var zb0001Mask uint8 /* 4 bits */
+	zb0001Len := uint32(5)
+	var zb0001Mask uint8 /* 5 bits */
 	if z.Files == nil {
 		zb0001Len--
 		zb0001Mask |= 0x4
 	}
-	if z.Children == nil {
+	if z.DNSNames == nil {
 		zb0001Len--
 		zb0001Mask |= 0x8
 	}
+	if z.Children == nil {
+		zb0001Len--
+		zb0001Mask |= 0x10
+	}
 	// variable map header, size zb0001Len
 	err = en.Append(0x80 | uint8(zb0001Len))
 	if err != nil {
@@ -1566,6 +1850,37 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 		}
 	}
 	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// write "dns_names"
+		err = en.Append(0xa9, 0x64, 0x6e, 0x73, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73)
+		if err != nil {
+			return
+		}
+		err = en.WriteMapHeader(uint32(len(z.DNSNames)))
+		if err != nil {
+			err = msgp.WrapError(err, "DNSNames")
+			return
+		}
+		for za0003, za0004 := range z.DNSNames {
+			err = en.WriteString(za0003)
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if za0004 == nil {
+				err = en.WriteNil()
+				if err != nil {
+					return
+				}
+			} else {
+				err = za0004.EncodeMsg(en)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames", za0003)
+					return
+				}
+			}
+		}
+	}
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// write "children"
 		err = en.Append(0xa8, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65, 0x6e)
 		if err != nil {
@@ -1576,16 +1891,16 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 			err = msgp.WrapError(err, "Children")
 			return
 		}
-		for za0003 := range z.Children {
-			if z.Children[za0003] == nil {
+		for za0005 := range z.Children {
+			if z.Children[za0005] == nil {
 				err = en.WriteNil()
 				if err != nil {
 					return
 				}
 			} else {
-				err = z.Children[za0003].EncodeMsg(en)
+				err = z.Children[za0005].EncodeMsg(en)
 				if err != nil {
-					err = msgp.WrapError(err, "Children", za0003)
+					err = msgp.WrapError(err, "Children", za0005)
 					return
 				}
 			}
@@ -1598,16 +1913,20 @@ func (z *ProcessActivityNode) EncodeMsg(en *msgp.Writer) (err error) {
 func (z *ProcessActivityNode) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
 	// omitempty: check for empty values
-	zb0001Len := uint32(4)
-	var zb0001Mask uint8 /* 4 bits */
+	zb0001Len := uint32(5)
+	var zb0001Mask uint8 /* 5 bits */
 	if z.Files == nil {
 		zb0001Len--
 		zb0001Mask |= 0x4
 	}
-	if z.Children == nil {
+	if z.DNSNames == nil {
 		zb0001Len--
 		zb0001Mask |= 0x8
 	}
+	if z.Children == nil {
+		zb0001Len--
+		zb0001Mask |= 0x10
+	}
 	// variable map header, size zb0001Len
 	o = append(o, 0x80|uint8(zb0001Len))
 	if zb0001Len == 0 {
@@ -1641,16 +1960,33 @@ func (z *ProcessActivityNode) MarshalMsg(b []byte) (o []byte, err error) {
 		}
 	}
 	if (zb0001Mask & 0x8) == 0 { // if not empty
+		// string "dns_names"
+		o = append(o, 0xa9, 0x64, 0x6e, 0x73, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x73)
+		o = msgp.AppendMapHeader(o, uint32(len(z.DNSNames)))
+		for za0003, za0004 := range z.DNSNames {
+			o = msgp.AppendString(o, za0003)
+			if za0004 == nil {
+				o = msgp.AppendNil(o)
+			} else {
+				o, err = za0004.MarshalMsg(o)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames", za0003)
+					return
+				}
+			}
+		}
+	}
+	if (zb0001Mask & 0x10) == 0 { // if not empty
 		// string "children"
 		o = append(o, 0xa8, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65, 0x6e)
 		o = msgp.AppendArrayHeader(o, uint32(len(z.Children)))
-		for za0003 := range z.Children {
-			if z.Children[za0003] == nil {
+		for za0005 := range z.Children {
+			if z.Children[za0005] == nil {
 				o = msgp.AppendNil(o)
 			} else {
-				o, err = z.Children[za0003].MarshalMsg(o)
+				o, err = z.Children[za0005].MarshalMsg(o)
 				if err != nil {
-					err = msgp.WrapError(err, "Children", za0003)
+					err = msgp.WrapError(err, "Children", za0005)
 					return
 				}
 			}
@@ -1734,32 +2070,73 @@ func (z *ProcessActivityNode) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				}
 				z.Files[za0001] = za0002
 			}
-		case "children":
+		case "dns_names":
 			var zb0004 uint32
-			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			zb0004, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "DNSNames")
+				return
+			}
+			if z.DNSNames == nil {
+				z.DNSNames = make(map[string]*DNSNode, zb0004)
+			} else if len(z.DNSNames) > 0 {
+				for key := range z.DNSNames {
+					delete(z.DNSNames, key)
+				}
+			}
+			for zb0004 > 0 {
+				var za0003 string
+				var za0004 *DNSNode
+				zb0004--
+				za0003, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "DNSNames")
+					return
+				}
+				if msgp.IsNil(bts) {
+					bts, err = msgp.ReadNilBytes(bts)
+					if err != nil {
+						return
+					}
+					za0004 = nil
+				} else {
+					if za0004 == nil {
+						za0004 = new(DNSNode)
+					}
+					bts, err = za0004.UnmarshalMsg(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "DNSNames", za0003)
+						return
+					}
+				}
+				z.DNSNames[za0003] = za0004
+			}
+		case "children":
+			var zb0005 uint32
+			zb0005, bts, err = msgp.ReadArrayHeaderBytes(bts)
 			if err != nil {
 				err = msgp.WrapError(err, "Children")
 				return
 			}
-			if cap(z.Children) >= int(zb0004) {
-				z.Children = (z.Children)[:zb0004]
+			if cap(z.Children) >= int(zb0005) {
+				z.Children = (z.Children)[:zb0005]
 			} else {
-				z.Children = make([]*ProcessActivityNode, zb0004)
+				z.Children = make([]*ProcessActivityNode, zb0005)
 			}
-			for za0003 := range z.Children {
+			for za0005 := range z.Children {
 				if msgp.IsNil(bts) {
 					bts, err = msgp.ReadNilBytes(bts)
 					if err != nil {
 						return
 					}
-					z.Children[za0003] = nil
+					z.Children[za0005] = nil
 				} else {
-					if z.Children[za0003] == nil {
-						z.Children[za0003] = new(ProcessActivityNode)
+					if z.Children[za0005] == nil {
+						z.Children[za0005] = new(ProcessActivityNode)
 					}
-					bts, err = z.Children[za0003].UnmarshalMsg(bts)
+					bts, err = z.Children[za0005].UnmarshalMsg(bts)
 					if err != nil {
-						err = msgp.WrapError(err, "Children", za0003)
+						err = msgp.WrapError(err, "Children", za0005)
 						return
 					}
 				}
@@ -1790,12 +2167,24 @@ func (z *ProcessActivityNode) Msgsize() (s int) {
 			}
 		}
 	}
+	s += 10 + msgp.MapHeaderSize
+	if z.DNSNames != nil {
+		for za0003, za0004 := range z.DNSNames {
+			_ = za0004
+			s += msgp.StringPrefixSize + len(za0003)
+			if za0004 == nil {
+				s += msgp.NilSize
+			} else {
+				s += za0004.Msgsize()
+			}
+		}
+	}
 	s += 9 + msgp.ArrayHeaderSize
-	for za0003 := range z.Children {
-		if z.Children[za0003] == nil {
+	for za0005 := range z.Children {
+		if z.Children[za0005] == nil {
 			s += msgp.NilSize
 		} else {
-			s += z.Children[za0003].Msgsize()
+			s += z.Children[za0005].Msgsize()
 		}
 	}
 	return
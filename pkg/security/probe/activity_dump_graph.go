@@ -13,8 +13,6 @@ import (
 	"os"
 	"strings"
 	"text/template"
-
-	"github.com/tinylib/msgp/msgp"
 )
 
 var (
@@ -25,6 +23,9 @@ var (
 	fileColor         = "#77bf77"
 	fileRuntimeColor  = "#e9f3e7"
 	fileSnapshotColor = "white"
+
+	dnsColor        = "#c99df2"
+	dnsRuntimeColor = "#f4ebfc"
 )
 
 type node struct {
@@ -116,6 +117,13 @@ func (ad *ActivityDump) prepareProcessActivityNode(p *ProcessActivityNode, data
 		})
 		ad.prepareFileNode(f, data, "", p.GetID())
 	}
+	for name, dn := range p.DNSNames {
+		data.Edges = append(data.Edges, edge{
+			Link:  p.GetID() + " -> " + p.GetID() + dn.GetID(),
+			Color: dnsColor,
+		})
+		ad.prepareDNSNode(name, dn, data, p.GetID())
+	}
 	for _, child := range p.Children {
 		data.Edges = append(data.Edges, edge{
 			Link:  p.GetID() + " -> " + child.GetID(),
@@ -150,19 +158,22 @@ func (ad *ActivityDump) prepareFileNode(f *FileActivityNode, data *graph, prefix
 	}
 }
 
-// GenerateGraph creates a graph from the input activity dump
-func GenerateGraph(inputFile string) (string, error) {
-	// open and parse activity dump file
-	f, err := os.Open(inputFile)
-	if err != nil {
-		return "", fmt.Errorf("couldn't open activity dump file: %w", err)
+func (ad *ActivityDump) prepareDNSNode(name string, dn *DNSNode, data *graph, processID string) {
+	mergedID := processID + dn.GetID()
+	data.Nodes[mergedID] = node{
+		ID:        mergedID,
+		Label:     name + " [dns]",
+		Size:      30,
+		Color:     dnsColor,
+		FillColor: dnsRuntimeColor,
 	}
+}
 
-	var dump ActivityDump
-	msgpReader := msgp.NewReader(f)
-	err = dump.DecodeMsg(msgpReader)
+// GenerateGraph creates a graph from the input activity dump
+func GenerateGraph(inputFile string) (string, error) {
+	dump, err := LoadActivityDump(inputFile)
 	if err != nil {
-		return "", fmt.Errorf("couldn't parse activity dump file: %w", err)
+		return "", err
 	}
 
 	// create profile output file
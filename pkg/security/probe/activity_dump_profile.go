@@ -9,11 +9,13 @@
 package probe
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"sort"
 	"text/template"
-
-	"github.com/tinylib/msgp/msgp"
 )
 
 var profileTmpl = `---
@@ -29,17 +31,9 @@ rules:{{ range .Rules }}
 
 // GenerateProfile creates a profile from the input activity dump
 func GenerateProfile(inputFile string) (string, error) {
-	// open and parse activity dump file
-	f, err := os.Open(inputFile)
+	dump, err := LoadActivityDump(inputFile)
 	if err != nil {
-		return "", fmt.Errorf("couldn't open activity dump file: %w", err)
-	}
-
-	var dump ActivityDump
-	msgpReader := msgp.NewReader(f)
-	err = dump.DecodeMsg(msgpReader)
-	if err != nil {
-		return "", fmt.Errorf("couldn't parse activity dump file: %w", err)
+		return "", err
 	}
 
 	// create profile output file
@@ -61,3 +55,108 @@ func GenerateProfile(inputFile string) (string, error) {
 
 	return profile.Name(), nil
 }
+
+var allowListPolicyTmpl = `---
+macros:{{ range .Macros }}
+  - id: {{ .ID }}
+    values:{{ range .Values }}
+      - {{ printf "%q" . }}{{ end }}
+{{ end }}
+rules:{{ range .Rules }}
+  - id: {{ .ID }}
+    description: {{ printf "%q" .Description }}
+    expression: {{ printf "%q" .Expression }}
+{{ end }}`
+
+type allowListMacro struct {
+	ID     string
+	Values []string
+}
+
+type allowListRule struct {
+	ID          string
+	Description string
+	Expression  string
+}
+
+type allowListPolicy struct {
+	Macros []allowListMacro
+	Rules  []allowListRule
+}
+
+// allowListNamePattern matches the characters which can't be used in rule and macro IDs
+var allowListNamePattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// getSelectorExpression returns the SECL expression matching the workload of the activity dump. The values are
+// quoted and escaped as SECL strings.
+func (ad *ActivityDump) getSelectorExpression() (string, error) {
+	switch {
+	case len(ad.ContainerID) > 0:
+		return fmt.Sprintf("container.id == %q", ad.ContainerID), nil
+	case len(ad.Comm) > 0:
+		return fmt.Sprintf("(process.comm == %q || process.ancestors.comm == %q)", ad.Comm, ad.Comm), nil
+	}
+	return "", errors.New("the activity dump has no selector")
+}
+
+// GetAllowListName returns the default prefix of the IDs of the allow-list policy of the activity dump
+func (ad *ActivityDump) GetAllowListName() string {
+	name := ad.Comm
+	if len(ad.ContainerID) > 0 {
+		name = ad.ContainerID
+		if len(name) > 12 {
+			name = name[:12]
+		}
+	}
+	return "allowlist_" + allowListNamePattern.ReplaceAllString(name, "_")
+}
+
+// WriteAllowListPolicy writes a policy flagging the activity of the workload of the dump which deviates from the
+// recorded activity: the executables, the opened files and the DNS names the dump doesn't contain. The rule and
+// macro IDs are prefixed by the given name. No rule is generated for a kind of activity which wasn't recorded.
+func (ad *ActivityDump) WriteAllowListPolicy(w io.Writer, name string) error {
+	name = allowListNamePattern.ReplaceAllString(name, "_")
+
+	selector, err := ad.getSelectorExpression()
+	if err != nil {
+		return err
+	}
+
+	summary := ad.Summarize()
+
+	var policy allowListPolicy
+	for _, activity := range []struct {
+		kind        string
+		field       string
+		description string
+		entries     map[string]bool
+	}{
+		{kind: "executables", field: "exec.file.path", description: "executable", entries: summary.Executables},
+		{kind: "files", field: "open.file.path", description: "file opened", entries: summary.Files},
+		{kind: "dns_names", field: "dns.question.name", description: "DNS name requested", entries: summary.DNSNames},
+	} {
+		if len(activity.entries) == 0 {
+			continue
+		}
+
+		macro := allowListMacro{ID: name + "_" + activity.kind}
+		for entry := range activity.entries {
+			macro.Values = append(macro.Values, entry)
+		}
+		sort.Strings(macro.Values)
+		policy.Macros = append(policy.Macros, macro)
+
+		policy.Rules = append(policy.Rules, allowListRule{
+			ID:          name + "_unexpected_" + activity.kind,
+			Description: fmt.Sprintf("Unexpected %s for %s", activity.description, ad.GetSelectorStr()),
+			Expression:  fmt.Sprintf("%s && %s not in %s", selector, activity.field, macro.ID),
+		})
+	}
+
+	if len(policy.Rules) == 0 {
+		return errors.New("the activity dump has no activity")
+	}
+
+	t := template.Must(template.New("tmpl").Parse(allowListPolicyTmpl))
+	return t.Execute(w, policy)
+}
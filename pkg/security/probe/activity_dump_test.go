@@ -9,9 +9,15 @@
 package probe
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/ast"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
 )
 
 func Test_extractFirstParent(t *testing.T) {
@@ -81,3 +87,104 @@ func Test_extractFirstParent(t *testing.T) {
 		})
 	}
 }
+
+func newTestProcessNode(path string, files []string, dnsNames []string, children ...*ProcessActivityNode) *ProcessActivityNode {
+	node := &ProcessActivityNode{
+		Files:    make(map[string]*FileActivityNode),
+		DNSNames: make(map[string]*DNSNode),
+		Children: children,
+	}
+	node.Process.FileEvent.PathnameStr = path
+
+	for _, file := range files {
+		event := NewEvent(nil, nil, nil)
+		event.Type = uint32(model.FileOpenEventType)
+		event.Timestamp = time.Now()
+		event.Open.File.PathnameStr = file
+		node.InsertFileEvent(&event.Open.File, event, Runtime)
+	}
+
+	for _, name := range dnsNames {
+		event := NewEvent(nil, nil, nil)
+		event.Type = uint32(model.DNSEventType)
+		event.Timestamp = time.Now()
+		event.DNS.Name = name
+		node.InsertDNSEvent(event, Runtime)
+	}
+
+	return node
+}
+
+func TestDiffActivityDumps(t *testing.T) {
+	base := &ActivityDump{
+		ProcessActivityTree: []*ProcessActivityNode{
+			newTestProcessNode("/usr/bin/bash", []string{"/etc/passwd"}, nil,
+				newTestProcessNode("/usr/bin/curl", []string{"/etc/hosts", "/etc/resolv.conf"}, []string{"example.com"}),
+			),
+		},
+	}
+	other := &ActivityDump{
+		ProcessActivityTree: []*ProcessActivityNode{
+			newTestProcessNode("/usr/bin/bash", []string{"/etc/passwd", "/etc/shadow"}, nil,
+				newTestProcessNode("/usr/bin/wget", []string{"/etc/hosts"}, []string{"example.com", "evil.com"}),
+			),
+		},
+	}
+
+	diff := DiffActivityDumps(base, other)
+	assert.Equal(t, ActivityDiff{Added: []string{"/usr/bin/wget"}, Removed: []string{"/usr/bin/curl"}}, diff.Executables)
+	assert.Equal(t, ActivityDiff{Added: []string{"/etc/shadow"}, Removed: []string{"/etc/resolv.conf"}}, diff.Files)
+	assert.Equal(t, ActivityDiff{Added: []string{"evil.com"}}, diff.DNSNames)
+
+	var b strings.Builder
+	assert.NoError(t, diff.WriteText(&b))
+	assert.Equal(t, "executables:\n  + /usr/bin/wget\n  - /usr/bin/curl\nfiles:\n  + /etc/shadow\n  - /etc/resolv.conf\ndns names:\n  + evil.com\n", b.String())
+
+	assert.True(t, DiffActivityDumps(base, base).IsEmpty())
+}
+
+func TestWriteAllowListPolicy(t *testing.T) {
+	dump := &ActivityDump{
+		ContainerID: "0123456789abcdef",
+		ProcessActivityTree: []*ProcessActivityNode{
+			newTestProcessNode("/usr/bin/curl", []string{"/etc/hosts", "[heap]"}, nil),
+		},
+	}
+	assert.Equal(t, "allowlist_0123456789ab", dump.GetAllowListName())
+
+	var b strings.Builder
+	assert.NoError(t, dump.WriteAllowListPolicy(&b, dump.GetAllowListName()))
+
+	policy, err := rules.LoadPolicy("allowlist.policy", "file", strings.NewReader(b.String()))
+	assert.NoError(t, err)
+	if assert.Len(t, policy.Rules, 2) {
+		assert.Equal(t, "allowlist_0123456789ab_unexpected_executables", policy.Rules[0].ID)
+		assert.Equal(t, `container.id == "0123456789abcdef" && exec.file.path not in allowlist_0123456789ab_executables`, policy.Rules[0].Expression)
+		assert.Equal(t, `container.id == "0123456789abcdef" && open.file.path not in allowlist_0123456789ab_files`, policy.Rules[1].Expression)
+	}
+	if assert.Len(t, policy.Macros, 2) {
+		assert.Equal(t, []string{"/usr/bin/curl"}, policy.Macros[0].Values)
+		assert.Equal(t, []string{"/etc/hosts"}, policy.Macros[1].Values)
+	}
+
+	assert.Error(t, (&ActivityDump{Comm: "curl"}).WriteAllowListPolicy(&b, "empty"))
+
+	// the selector values are escaped
+	dump = &ActivityDump{
+		Comm: `a" || true || "\`,
+		ProcessActivityTree: []*ProcessActivityNode{
+			newTestProcessNode("/usr/bin/curl", nil, nil),
+		},
+	}
+	b.Reset()
+	assert.NoError(t, dump.WriteAllowListPolicy(&b, dump.GetAllowListName()))
+
+	policy, err = rules.LoadPolicy("allowlist.policy", "file", strings.NewReader(b.String()))
+	assert.NoError(t, err)
+	if assert.Len(t, policy.Rules, 1) {
+		rule, err := ast.ParseRule(policy.Rules[0].Expression)
+		assert.NoError(t, err)
+		assert.NotNil(t, rule)
+		assert.Equal(t, `(process.comm == "a\" || true || \"\\" || process.ancestors.comm == "a\" || true || \"\\") && exec.file.path not in allowlist_a_____true_______executables`, policy.Rules[0].Expression)
+	}
+}
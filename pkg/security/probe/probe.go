@@ -10,6 +10,7 @@ package probe
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -1072,6 +1073,21 @@ func (p *Probe) NewRuleSet(opts *rules.Opts) *rules.RuleSet {
 	}
 	opts.WithLogger(&seclog.PatternLogger{})
 
+	// the events of sequence rules are serialized when matching a step, as they are reused
+	opts.WithSequenceOpts(rules.SequenceOpts{
+		Timestamp: func(event eval.Event) time.Time {
+			return event.(*Event).ResolveEventTimestamp()
+		},
+		Snapshot: func(event eval.Event) interface{} {
+			data, err := event.(*Event).MarshalJSON()
+			if err != nil {
+				seclog.Errorf("failed to serialize the event of a sequence step: %s", err)
+				return nil
+			}
+			return json.RawMessage(data)
+		},
+	})
+
 	return rules.NewRuleSet(&Model{probe: p}, eventCtor, opts)
 }
 
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/gopsutil/process"
+	"golang.org/x/sys/unix"
+
+	"github.com/DataDog/datadog-agent/pkg/security/config"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// Status of the actions executed on a rule match
+const (
+	// ActionPerformed is the status of an action which was executed
+	ActionPerformed = "performed"
+	// ActionDryRun is the status of an action which would have been executed without the dry-run mode
+	ActionDryRun = "dry_run"
+	// ActionAllowlisted is the status of an action skipped because its target is allowed
+	ActionAllowlisted = "allowlisted"
+	// ActionFailed is the status of an action which couldn't be executed
+	ActionFailed = "failed"
+)
+
+// processStartTimeTolerance is the difference allowed between the fork time of a process
+// recorded by the probe and its start time read from procfs, which is less precise. A process
+// started later than that is another process which reused the PID.
+const processStartTimeTolerance = 2 * time.Second
+
+// ActionReport is the report of an action executed on a rule match, added to the event
+type ActionReport struct {
+	Type   string `json:"type"`
+	Signal string `json:"signal,omitempty"`
+	PID    uint32 `json:"pid,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ProcessKiller executes the kill actions of the rules, unless the process is part of the
+// allowlist. The agent itself and init are never killed.
+type ProcessKiller struct {
+	dryRun    bool
+	allowlist []*eval.Glob
+}
+
+// NewProcessKiller returns a new ProcessKiller
+func NewProcessKiller(cfg *config.Config) (*ProcessKiller, error) {
+	p := &ProcessKiller{
+		dryRun: cfg.KillActionDryRun,
+	}
+
+	for _, pattern := range cfg.KillActionAllowlist {
+		glob, err := eval.NewGlob(pattern, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid kill action allowlist pattern `%s`: %w", pattern, err)
+		}
+		p.allowlist = append(p.allowlist, glob)
+	}
+
+	return p, nil
+}
+
+// KillProcess sends the signal of a kill action to the process of the event. The process is
+// not killed if its PID now belongs to another process.
+func (p *ProcessKiller) KillProcess(event *Event, kill *rules.KillDefinition) *ActionReport {
+	entry := event.ResolveProcessCacheEntry()
+	if entry == nil {
+		return &ActionReport{Type: "kill", Signal: kill.GetSignal(), Status: ActionFailed, Error: "process not resolved"}
+	}
+
+	startTime := entry.ForkTime
+	if startTime.IsZero() {
+		startTime = entry.ExecTime
+	}
+	return p.killProcess(entry.Pid, startTime, event.ResolveFilePath(&entry.FileEvent), kill.GetSignal())
+}
+
+func (p *ProcessKiller) isAllowlisted(pid uint32, path string) bool {
+	if pid <= 1 || int(pid) == os.Getpid() {
+		return true
+	}
+
+	for _, glob := range p.allowlist {
+		if glob.Matches(path) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProcessKiller) killProcess(pid uint32, startTime time.Time, path string, signal string) *ActionReport {
+	report := &ActionReport{Type: "kill", Signal: signal, PID: pid}
+
+	switch {
+	case p.isAllowlisted(pid, path):
+		report.Status = ActionAllowlisted
+	case p.dryRun:
+		report.Status = ActionDryRun
+	default:
+		value, ok := model.SECLConstants[signal].(*eval.IntEvaluator)
+		if !ok {
+			report.Status, report.Error = ActionFailed, "unknown signal"
+			break
+		}
+
+		if err := sendSignal(pid, startTime, syscall.Signal(value.Value)); err != nil {
+			report.Status, report.Error = ActionFailed, err.Error()
+			break
+		}
+		report.Status = ActionPerformed
+	}
+
+	return report
+}
+
+// sendSignal sends a signal to the process `pid` if it is the process started at `startTime`.
+// The process is held with a pidfd, when the kernel supports it, so that its PID can't be
+// reused between the check of its start time and the signal.
+func sendSignal(pid uint32, startTime time.Time, signal syscall.Signal) error {
+	pidfd, err := unix.PidfdOpen(int(pid), 0)
+	hasPidfd := err == nil
+	if err != nil && err != unix.ENOSYS {
+		return err
+	}
+	if hasPidfd {
+		defer unix.Close(pidfd)
+	}
+
+	if err := checkProcessStartTime(pid, startTime); err != nil {
+		return err
+	}
+
+	if hasPidfd {
+		return unix.PidfdSendSignal(pidfd, signal, nil, 0)
+	}
+	return unix.Kill(int(pid), signal)
+}
+
+// checkProcessStartTime returns an error if the process `pid` started after `startTime`,
+// meaning that the PID was reused.
+func checkProcessStartTime(pid uint32, startTime time.Time) error {
+	if startTime.IsZero() {
+		return errors.New("unknown process start time, the PID can't be checked")
+	}
+
+	proc, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return err
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return err
+	}
+	if created := time.Unix(0, createTime*int64(time.Millisecond)); created.After(startTime.Add(processStartTimeTolerance)) {
+		return fmt.Errorf("the PID was reused by a process started at %s", created.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package probe
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/config"
+)
+
+func TestProcessKiller(t *testing.T) {
+	killer, err := NewProcessKiller(&config.Config{
+		KillActionDryRun:    true,
+		KillActionAllowlist: []string{"/usr/sbin/*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		pid    uint32
+		path   string
+		status string
+	}{
+		{pid: 1, path: "/sbin/init", status: ActionAllowlisted},
+		{pid: uint32(os.Getpid()), path: "/opt/datadog-agent/embedded/bin/system-probe", status: ActionAllowlisted},
+		{pid: 4242, path: "/usr/sbin/sshd", status: ActionAllowlisted},
+		{pid: 4242, path: "/usr/bin/curl", status: ActionDryRun},
+	} {
+		report := killer.killProcess(tc.pid, time.Now(), tc.path, "SIGKILL")
+		if report.Status != tc.status {
+			t.Errorf("expected status %s for %s, got %s", tc.status, tc.path, report.Status)
+		}
+	}
+
+	if _, err := NewProcessKiller(&config.Config{KillActionAllowlist: []string{"/usr/**/sbin"}}); err == nil {
+		t.Error("expected an invalid allowlist pattern to be rejected")
+	}
+}
+
+func TestProcessKillerReusedPID(t *testing.T) {
+	killer, err := NewProcessKiller(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The child process started after the fork time of the cached process: its PID is
+	// considered reused and it must not be killed.
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+
+	report := killer.killProcess(uint32(cmd.Process.Pid), time.Now().Add(-time.Hour), "/usr/bin/sleep", "SIGKILL")
+	if report.Status != ActionFailed {
+		t.Fatalf("expected the kill of a reused PID to fail, got %s", report.Status)
+	}
+	if err := cmd.Process.Signal(syscall.Signal(0)); err != nil {
+		t.Fatalf("the process using the reused PID was killed: %v", err)
+	}
+
+	report = killer.killProcess(uint32(cmd.Process.Pid), time.Now(), "/usr/bin/sleep", "SIGKILL")
+	if report.Status != ActionPerformed {
+		t.Fatalf("expected the process to be killed, got %s: %s", report.Status, report.Error)
+	}
+}
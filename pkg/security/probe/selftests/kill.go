@@ -0,0 +1,61 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build linux
+// +build linux
+
+package selftests
+
+import (
+	"fmt"
+	"os/exec"
+
+	"github.com/DataDog/datadog-agent/pkg/security/probe"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// KillSelfTest defines a kill action self test
+type KillSelfTest struct {
+}
+
+// GetRuleDefinition returns the rule
+func (k *KillSelfTest) GetRuleDefinition(filename string) *rules.RuleDefinition {
+	return &rules.RuleDefinition{
+		ID:         fmt.Sprintf("%s_kill", ruleIDPrefix),
+		Expression: fmt.Sprintf(`exec.file.name == "sleep" && exec.argv0 == "%s"`, filename),
+		Actions: []rules.ActionDefinition{
+			{Kill: &rules.KillDefinition{}},
+		},
+	}
+}
+
+// GenerateEvent generate an event
+func (k *KillSelfTest) GenerateEvent(filename string) (EventPredicate, error) {
+	// the target file is used as argv0 to only match the process of the self test
+	cmd := exec.Command("sleep", "2")
+	cmd.Args[0] = filename
+	if err := cmd.Start(); err != nil {
+		log.Debugf("error running sleep: %v", err)
+		return nil, err
+	}
+
+	// reap the process, killed by the action or not in dry-run mode
+	go func() {
+		_ = cmd.Wait()
+	}()
+
+	return func(event selfTestEvent) bool {
+		if event.Type != "exec" {
+			return false
+		}
+		for _, action := range event.Actions {
+			if action.Type == "kill" && action.Status != probe.ActionFailed {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
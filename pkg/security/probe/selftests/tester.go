@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/security/api"
+	"github.com/DataDog/datadog-agent/pkg/security/config"
 	"github.com/DataDog/datadog-agent/pkg/security/probe"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
 	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
@@ -45,6 +46,11 @@ var FileSelfTests = []FileSelfTest{
 	&ChownSelfTest{},
 }
 
+// KillSelfTests slice of self test functions of the kill action, run when the loaded rules use kill actions
+var KillSelfTests = []FileSelfTest{
+	&KillSelfTest{},
+}
+
 // SelfTester represents all the state needed to conduct rule injection test at startup
 type SelfTester struct {
 	waitingForEvent uint32 // atomic bool
@@ -52,6 +58,8 @@ type SelfTester struct {
 	success         []string
 	fails           []string
 	lastTimestamp   time.Time
+	selfTests       []FileSelfTest
+	runtimeEnabled  bool
 
 	// file tests
 	targetFilePath string
@@ -59,9 +67,11 @@ type SelfTester struct {
 }
 
 // NewSelfTester returns a new SelfTester, enabled or not
-func NewSelfTester() (*SelfTester, error) {
+func NewSelfTester(cfg *config.Config) (*SelfTester, error) {
 	s := &SelfTester{
-		eventChan: make(chan selfTestEvent, 10),
+		eventChan:      make(chan selfTestEvent, 10),
+		selfTests:      FileSelfTests,
+		runtimeEnabled: cfg.RuntimeEnabled,
 	}
 
 	if err := s.createTargetFile(); err != nil {
@@ -71,6 +81,14 @@ func NewSelfTester() (*SelfTester, error) {
 	return s, nil
 }
 
+// SetKillActionsInUse selects the kill self tests when the loaded rules use kill actions and runtime is enabled
+func (t *SelfTester) SetKillActionsInUse(inUse bool) {
+	t.selfTests = FileSelfTests
+	if inUse && t.runtimeEnabled {
+		t.selfTests = append(append([]FileSelfTest{}, FileSelfTests...), KillSelfTests...)
+	}
+}
+
 // GetStatus returns the result of the last performed self tests
 func (t *SelfTester) GetStatus() *api.SelfTestsStatus {
 	return &api.SelfTestsStatus{
@@ -88,7 +106,7 @@ func (t *SelfTester) LoadPolicies() ([]*rules.Policy, *multierror.Error) {
 		Version: policyVersion,
 	}
 
-	for _, selftest := range FileSelfTests {
+	for _, selftest := range t.selfTests {
 		p.AddRule(selftest.GetRuleDefinition(t.targetFilePath))
 	}
 
@@ -129,7 +147,7 @@ func (t *SelfTester) RunSelfTest() ([]string, []string, error) {
 	// launch the self tests
 	var success []string
 	var fails []string
-	for _, selftest := range FileSelfTests {
+	for _, selftest := range t.selfTests {
 		def := selftest.GetRuleDefinition(t.targetFilePath)
 
 		predicate, err := selftest.GenerateEvent(t.targetFilePath)
@@ -178,10 +196,11 @@ func (t *SelfTester) EndWaitingForEvent() {
 type selfTestEvent struct {
 	Type     string
 	Filepath string
+	Actions  []*probe.ActionReport
 }
 
-// IsExpectedEvent sends an event to the tester
-func (t *SelfTester) IsExpectedEvent(rule *rules.Rule, event eval.Event) bool {
+// IsExpectedEvent sends an event to the tester, with the reports of the actions of the rule
+func (t *SelfTester) IsExpectedEvent(rule *rules.Rule, event eval.Event, actions []*probe.ActionReport) bool {
 	if atomic.LoadUint32(&t.waitingForEvent) != 0 && rule.Definition.Policy.Source == policySource {
 		ev, ok := event.(*probe.Event)
 		if !ok {
@@ -196,6 +215,7 @@ func (t *SelfTester) IsExpectedEvent(rule *rules.Rule, event eval.Event) bool {
 		selfTestEvent := selfTestEvent{
 			Type:     event.GetType(),
 			Filepath: s.FileEventSerializer.Path,
+			Actions:  actions,
 		}
 		t.eventChan <- selfTestEvent
 		return true
@@ -0,0 +1,415 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package policylint statically analyzes runtime security policies against the
+// SECL model and the approver capabilities of the probe. On top of the errors
+// reported when the policies are loaded, it warns about the rules which can never
+// match, the rules preventing in-kernel filtering, the conflicting rule and macro
+// IDs and the macros used by no rule.
+package policylint
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/policytest"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// FindingType describes the kind of issue reported by the linter
+type FindingType string
+
+const (
+	// LoadError is reported for the errors the agent would report when loading the policies
+	LoadError FindingType = "load_error"
+	// NeverMatch is reported for the rules that no event can match
+	NeverMatch FindingType = "never_match"
+	// NoApprover is reported for the rules with no approver, which disable the in-kernel
+	// filtering of their event type
+	NoApprover FindingType = "no_approver"
+	// DuplicateID is reported for the rules and macros defined several times without
+	// a combine policy allowing it
+	DuplicateID FindingType = "duplicate_id"
+	// UnusedMacro is reported for the macros used by no rule
+	UnusedMacro FindingType = "unused_macro"
+)
+
+// Opts defines the options of the linter
+type Opts struct {
+	// FieldCapabilities lists, by event type, the fields which can be filtered
+	// in kernel. Approvers are only checked for the event types with capabilities.
+	FieldCapabilities map[eval.EventType]rules.FieldCapabilities
+}
+
+// Finding is an issue found in the policies
+type Finding struct {
+	Type FindingType `json:"type"`
+	// Policies lists the files of the policies concerned by the finding
+	Policies []string     `json:"policies,omitempty"`
+	RuleID   eval.RuleID  `json:"rule_id,omitempty"`
+	MacroID  eval.MacroID `json:"macro_id,omitempty"`
+	Message  string       `json:"message"`
+}
+
+// dirProvider records the file of each policy loaded from a directory
+type dirProvider struct {
+	*rules.PoliciesDirProvider
+	files map[*rules.Policy]string
+}
+
+// LoadPolicies implements the policy provider interface
+func (p *dirProvider) LoadPolicies() ([]*rules.Policy, *multierror.Error) {
+	policies, errs := p.PoliciesDirProvider.LoadPolicies()
+	for _, policy := range policies {
+		p.files[policy] = filepath.Join(p.PoliciesDir, policy.Name)
+	}
+	return policies, errs
+}
+
+// linter holds the state of the analysis of a set of policies
+type linter struct {
+	opts     Opts
+	files    map[*rules.Policy]string
+	findings []Finding
+
+	// macro IDs in order of definition, and the references of their first definition
+	macroIDs   []eval.MacroID
+	macroFiles map[eval.MacroID]string
+	macroRefs  map[eval.MacroID]*references
+}
+
+func (l *linter) policyFile(policy *rules.Policy) string {
+	if file, found := l.files[policy]; found {
+		return file
+	}
+	if policy != nil {
+		return policy.Name
+	}
+	return ""
+}
+
+func (l *linter) report(finding Finding) {
+	l.findings = append(l.findings, finding)
+}
+
+// Lint analyzes the policies of the given directories, loaded in order as the agent
+// would load them. An error is only returned if a directory can't be read.
+func Lint(dirs []string, opts Opts) ([]Finding, error) {
+	l := &linter{
+		opts:       opts,
+		files:      make(map[*rules.Policy]string),
+		macroFiles: make(map[eval.MacroID]string),
+		macroRefs:  make(map[eval.MacroID]*references),
+	}
+
+	providers := make([]rules.PolicyProvider, 0, len(dirs))
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err != nil {
+			return nil, err
+		} else if !info.IsDir() {
+			return nil, fmt.Errorf("`%s` isn't a directory", dir)
+		}
+
+		provider, err := rules.NewPoliciesDirProvider(dir, false)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, &dirProvider{PoliciesDirProvider: provider, files: l.files})
+	}
+
+	loader := rules.NewPolicyLoader(providers...)
+	defer loader.Close()
+
+	// the definitions are analyzed before being loaded in a rule set, which merges them
+	policies, _ := loader.LoadPolicies()
+	l.indexMacros(policies)
+	l.lintDuplicates(policies)
+	l.lintUnusedMacros(policies)
+
+	ruleSet := policytest.NewRuleSet(nil)
+	if errs := ruleSet.LoadPolicies(loader); errs != nil {
+		l.lintLoadErrors(errs)
+	}
+
+	for _, eventType := range ruleSet.GetEventTypes() {
+		bucket := ruleSet.GetBucket(eventType)
+		if bucket == nil {
+			continue
+		}
+
+		// the rules which can never match are only reported once
+		var bucketRules []*rules.Rule
+		for _, rule := range bucket.GetRules() {
+			if !l.lintNeverMatch(rule) {
+				bucketRules = append(bucketRules, rule)
+			}
+		}
+
+		if caps, found := opts.FieldCapabilities[eventType]; found {
+			l.lintApprovers(eventType, bucketRules, caps)
+		}
+	}
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		a, b := l.findings[i], l.findings[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.MacroID < b.MacroID
+	})
+
+	return l.findings, nil
+}
+
+func (l *linter) lintLoadErrors(errs *multierror.Error) {
+	for _, err := range errs.Errors {
+		finding := Finding{Type: LoadError, Message: err.Error()}
+
+		switch err := err.(type) {
+		case *rules.ErrRuleLoad:
+			// conflicting IDs are reported with all their definitions
+			if err.Err == rules.ErrInternalIDConflict {
+				continue
+			}
+			finding.RuleID = err.Definition.ID
+			if err.Definition.Policy != nil {
+				finding.Policies = []string{l.policyFile(err.Definition.Policy)}
+			}
+		case *rules.ErrMacroLoad:
+			if err.Err == rules.ErrInternalIDConflict || err.Err == rules.ErrCannotMergeExpression {
+				continue
+			}
+			finding.MacroID = err.Definition.ID
+		case *rules.ErrPolicyLoad:
+			finding.Policies = []string{err.Name}
+		}
+
+		l.report(finding)
+	}
+}
+
+// lintDuplicates reports the IDs defined several times, in one or several policies,
+// without a combine policy. Only the first definition of such an ID is loaded.
+func (l *linter) lintDuplicates(policies []*rules.Policy) {
+	var (
+		ruleIDs   []eval.RuleID
+		ruleFiles = make(map[eval.RuleID][]string)
+		ruleDups  = make(map[eval.RuleID]bool)
+
+		macroIDs   []eval.MacroID
+		macroFiles = make(map[eval.MacroID][]string)
+		macroDefs  = make(map[eval.MacroID]*rules.MacroDefinition)
+		macroDups  = make(map[eval.MacroID]bool)
+	)
+
+	for _, policy := range policies {
+		file := l.policyFile(policy)
+
+		for _, macro := range policy.Macros {
+			if first, found := macroDefs[macro.ID]; !found {
+				macroIDs = append(macroIDs, macro.ID)
+				macroDefs[macro.ID] = macro
+			} else {
+				switch macro.Combine {
+				case rules.MergePolicy:
+					macroDups[macro.ID] = macroDups[macro.ID] || first.Expression != "" || macro.Expression != ""
+				case rules.OverridePolicy:
+				default:
+					macroDups[macro.ID] = true
+				}
+			}
+			macroFiles[macro.ID] = append(macroFiles[macro.ID], file)
+		}
+
+		for _, rule := range policy.Rules {
+			if _, found := ruleFiles[rule.ID]; !found {
+				ruleIDs = append(ruleIDs, rule.ID)
+			} else if rule.Combine != rules.OverridePolicy && !rule.Disabled {
+				ruleDups[rule.ID] = true
+			}
+			ruleFiles[rule.ID] = append(ruleFiles[rule.ID], file)
+		}
+	}
+
+	for _, id := range ruleIDs {
+		if ruleDups[id] {
+			l.report(Finding{
+				Type:     DuplicateID,
+				Policies: ruleFiles[id],
+				RuleID:   id,
+				Message:  fmt.Sprintf("rule defined %d times without `combine: override`, only its first definition is loaded", len(ruleFiles[id])),
+			})
+		}
+	}
+
+	for _, id := range macroIDs {
+		if macroDups[id] {
+			l.report(Finding{
+				Type:     DuplicateID,
+				Policies: macroFiles[id],
+				MacroID:  id,
+				Message:  fmt.Sprintf("macro defined %d times without a valid combine policy, only its first definition is loaded", len(macroFiles[id])),
+			})
+		}
+	}
+}
+
+func (l *linter) indexMacros(policies []*rules.Policy) {
+	for _, policy := range policies {
+		for _, macro := range policy.Macros {
+			if _, found := l.macroRefs[macro.ID]; found {
+				continue
+			}
+			l.macroIDs = append(l.macroIDs, macro.ID)
+			l.macroFiles[macro.ID] = l.policyFile(policy)
+			l.macroRefs[macro.ID] = macroReferences(macro)
+		}
+	}
+}
+
+// usedMacros adds to used the macros referenced, directly or through other macros
+func (l *linter) usedMacros(refs *references, used map[eval.MacroID]bool) {
+	for ident := range refs.idents {
+		if macro, found := l.macroRefs[ident]; found && !used[ident] {
+			used[ident] = true
+			l.usedMacros(macro, used)
+		}
+	}
+}
+
+// usesVariables returns whether an expression uses variables, directly or through its macros
+func (l *linter) usesVariables(refs *references) bool {
+	used := make(map[eval.MacroID]bool)
+	l.usedMacros(refs, used)
+
+	variables := refs.variables
+	for id := range used {
+		variables = variables || l.macroRefs[id].variables
+	}
+	return variables
+}
+
+// lintUnusedMacros reports the macros used neither by a rule nor by a macro used by a rule
+func (l *linter) lintUnusedMacros(policies []*rules.Policy) {
+	used := make(map[eval.MacroID]bool)
+	for _, policy := range policies {
+		for _, rule := range policy.Rules {
+			l.usedMacros(ruleReferences(rule), used)
+		}
+	}
+
+	for _, id := range l.macroIDs {
+		if !used[id] {
+			l.report(Finding{
+				Type:     UnusedMacro,
+				Policies: []string{l.macroFiles[id]},
+				MacroID:  id,
+				Message:  "macro used by no rule",
+			})
+		}
+	}
+}
+
+// ruleLabel returns how a rule is designated in the messages, sequence steps are
+// compiled as rules of their own
+func ruleLabel(rule *rules.Rule) string {
+	if rule.Definition.Sequence != nil && rule.ID != rule.Definition.ID {
+		return "step " + strings.TrimPrefix(rule.ID, rule.Definition.ID+"_step_") + " of the sequence"
+	}
+	return "rule"
+}
+
+// lintNeverMatch reports the rules for which a field can take no value satisfying
+// the rule, such as `open.file.path == "/etc/passwd" && open.file.path == "/etc/shadow"`.
+// It returns whether the rule was reported.
+func (l *linter) lintNeverMatch(rule *rules.Rule) bool {
+	// the value of the variables depends on the previous events
+	if refs, err := parseRule(rule.Expression); err != nil || l.usesVariables(refs) {
+		return false
+	}
+
+	fields := rule.GetEvaluator().GetFields()
+	sort.Strings(fields)
+
+	seen := make(map[eval.Field]bool)
+	for _, field := range fields {
+		if seen[field] {
+			continue
+		}
+		seen[field] = true
+
+		if satisfiable(rule, field) {
+			continue
+		}
+
+		l.report(Finding{
+			Type:     NeverMatch,
+			Policies: []string{l.policyFile(rule.Definition.Policy)},
+			RuleID:   rule.Definition.ID,
+			Message:  fmt.Sprintf("%s can never match, no value of `%s` satisfies it", ruleLabel(rule), field),
+		})
+		return true
+	}
+
+	return false
+}
+
+// lintApprovers reports the rules of an event type for which no approver can be found.
+// A single rule without approver disables the in-kernel filtering of the event type.
+func (l *linter) lintApprovers(eventType eval.EventType, bucketRules []*rules.Rule, caps rules.FieldCapabilities) {
+	fields := make([]string, 0, len(caps))
+	for _, fieldCap := range caps {
+		fields = append(fields, fieldCap.Field)
+	}
+
+	for _, rule := range bucketRules {
+		approvers, err := rules.GetApprovers([]*rules.Rule{rule}, newEvent(), caps)
+		if err == nil && len(approvers) > 0 {
+			continue
+		}
+
+		l.report(Finding{
+			Type:     NoApprover,
+			Policies: []string{l.policyFile(rule.Definition.Policy)},
+			RuleID:   rule.Definition.ID,
+			Message: fmt.Sprintf("%s has no approver on `%s`, all the `%s` events are sent to user space",
+				ruleLabel(rule), strings.Join(fields, "`, `"), eventType),
+		})
+	}
+}
+
+// WriteText writes the findings in a human readable format
+func WriteText(w io.Writer, findings []Finding) error {
+	var b strings.Builder
+
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "[%s]", finding.Type)
+		if len(finding.Policies) > 0 {
+			fmt.Fprintf(&b, " %s:", strings.Join(finding.Policies, ", "))
+		}
+		switch {
+		case finding.RuleID != "":
+			fmt.Fprintf(&b, " rule `%s`:", finding.RuleID)
+		case finding.MacroID != "":
+			fmt.Fprintf(&b, " macro `%s`:", finding.MacroID)
+		}
+		fmt.Fprintf(&b, " %s\n", finding.Message)
+	}
+
+	fmt.Fprintf(&b, "%d finding(s)\n", len(findings))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policylint
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+const defaultPolicy = `---
+macros:
+  - id: shells
+    values: ["bash", "sh"]
+  - id: shell_process
+    expression: process.file.name in shells
+  - id: unused
+    expression: process.file.name == "curl"
+rules:
+  - id: passwd_open
+    expression: open.file.path == "/etc/passwd"
+  - id: etc_open
+    expression: open.file.path =~ "/etc/*" && open.file.path != "/etc/passwd" && process.uid != 0
+  - id: conflicting_paths
+    expression: open.file.path == "/etc/passwd" && open.file.path == "/etc/shadow"
+  - id: conflicting_uids
+    expression: exec.file.name == "ls" && process.uid == 0 && process.uid != 0
+  - id: shell
+    expression: exec.file.name == "sh" && shell_process
+  - id: variable
+    expression: open.file.path == "/etc/passwd" && open.file.path != "${process.pid}"
+  - id: unknown_field
+    expression: open.file.unknown == "/etc/passwd"
+  - id: sequence
+    sequence:
+      within: 1m
+      steps:
+        - exec.file.name == "curl"
+        - open.file.name == "a" && open.file.name == "b"
+`
+
+const customPolicy = `---
+macros:
+  - id: shells
+    values: ["zsh"]
+    combine: merge
+rules:
+  - id: passwd_open
+    expression: open.file.path == "/etc/passwd"
+  - id: shell
+    expression: exec.file.name == "zsh"
+    combine: override
+`
+
+func writePolicy(t *testing.T, dir, name, content string) {
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+}
+
+func findingsOf(findings []Finding, findingType FindingType) map[string]Finding {
+	result := make(map[string]Finding)
+	for _, finding := range findings {
+		if finding.Type == findingType {
+			result[finding.RuleID+finding.MacroID] = finding
+		}
+	}
+	return result
+}
+
+func TestLint(t *testing.T) {
+	defaultDir, customDir := t.TempDir(), t.TempDir()
+	writePolicy(t, defaultDir, "default.policy", defaultPolicy)
+	writePolicy(t, customDir, "custom.policy", customPolicy)
+
+	findings, err := Lint([]string{defaultDir, customDir}, Opts{
+		FieldCapabilities: map[eval.EventType]rules.FieldCapabilities{
+			"open": {
+				{Field: "open.file.path", Types: eval.ScalarValueType | eval.GlobValueType},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("never match", func(t *testing.T) {
+		neverMatch := findingsOf(findings, NeverMatch)
+		assert.Len(t, neverMatch, 3, "%+v", neverMatch)
+		assert.Contains(t, neverMatch["conflicting_paths"].Message, "`open.file.path`")
+		assert.Contains(t, neverMatch["conflicting_uids"].Message, "`process.uid`")
+		assert.Contains(t, neverMatch["sequence"].Message, "step 2 of the sequence")
+		assert.Equal(t, []string{filepath.Join(defaultDir, "default.policy")}, neverMatch["sequence"].Policies)
+	})
+
+	t.Run("no approver", func(t *testing.T) {
+		noApprover := findingsOf(findings, NoApprover)
+		assert.Len(t, noApprover, 1, "%+v", noApprover)
+		assert.Contains(t, noApprover["etc_open"].Message, "all the `open` events are sent to user space")
+	})
+
+	t.Run("duplicate id", func(t *testing.T) {
+		duplicates := findingsOf(findings, DuplicateID)
+		assert.Len(t, duplicates, 1, "%+v", duplicates)
+		assert.Equal(t, []string{
+			filepath.Join(defaultDir, "default.policy"),
+			filepath.Join(customDir, "custom.policy"),
+		}, duplicates["passwd_open"].Policies)
+	})
+
+	t.Run("unused macro", func(t *testing.T) {
+		unused := findingsOf(findings, UnusedMacro)
+		assert.Len(t, unused, 1, "%+v", unused)
+		assert.Contains(t, unused, "unused")
+	})
+
+	t.Run("load error", func(t *testing.T) {
+		loadErrors := findingsOf(findings, LoadError)
+		assert.Len(t, loadErrors, 1, "%+v", loadErrors)
+		assert.Contains(t, loadErrors, "unknown_field")
+	})
+
+	var b bytes.Buffer
+	require.NoError(t, WriteText(&b, findings))
+	assert.Contains(t, b.String(), "[unused_macro] "+filepath.Join(defaultDir, "default.policy")+": macro `unused`: macro used by no rule\n")
+	assert.Contains(t, b.String(), "7 finding(s)\n")
+}
+
+func TestLintUnknownDir(t *testing.T) {
+	_, err := Lint([]string{filepath.Join(t.TempDir(), "unknown")}, Opts{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,155 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policylint
+
+import (
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/ast"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// references holds the identifiers, fields or macros, used by an expression
+type references struct {
+	idents    map[string]bool
+	variables bool
+}
+
+func newReferences() *references {
+	return &references{idents: make(map[string]bool)}
+}
+
+// parseRule returns the references of a rule expression
+func parseRule(expression string) (*references, error) {
+	rule, err := ast.ParseRule(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := newReferences()
+	refs.walkExpression(rule.BooleanExpression.Expression)
+	return refs, nil
+}
+
+// ruleReferences returns the references of the expression or of the sequence steps
+// of a rule, the expressions which can't be parsed are ignored
+func ruleReferences(rule *rules.RuleDefinition) *references {
+	expressions := []string{rule.Expression}
+	if rule.Sequence != nil {
+		expressions = append(expressions, rule.Sequence.Steps...)
+	}
+
+	refs := newReferences()
+	for _, expression := range expressions {
+		if expression == "" {
+			continue
+		}
+		if parsed, err := parseRule(expression); err == nil {
+			refs.merge(parsed)
+		}
+	}
+	return refs
+}
+
+// macroReferences returns the references of a macro, none for the macros defined by a list of values
+func macroReferences(macro *rules.MacroDefinition) *references {
+	refs := newReferences()
+	if macro.Expression == "" {
+		return refs
+	}
+
+	parsed, err := ast.ParseMacro(macro.Expression)
+	if err != nil {
+		return refs
+	}
+
+	refs.walkExpression(parsed.Expression)
+	refs.walkArray(parsed.Array)
+	refs.walkPrimary(parsed.Primary)
+	return refs
+}
+
+func (r *references) merge(other *references) {
+	for ident := range other.idents {
+		r.idents[ident] = true
+	}
+	r.variables = r.variables || other.variables
+}
+
+func (r *references) walkString(str *string) {
+	if str != nil && strings.Contains(*str, "${") {
+		r.variables = true
+	}
+}
+
+func (r *references) walkExpression(expression *ast.Expression) {
+	for expression != nil {
+		r.walkComparison(expression.Comparison)
+		if expression.Next == nil {
+			return
+		}
+		expression = expression.Next.Expression
+	}
+}
+
+func (r *references) walkComparison(comparison *ast.Comparison) {
+	if comparison == nil {
+		return
+	}
+
+	r.walkBitOperation(comparison.BitOperation)
+	if comparison.ScalarComparison != nil {
+		r.walkComparison(comparison.ScalarComparison.Next)
+	}
+	if comparison.ArrayComparison != nil {
+		r.walkArray(comparison.ArrayComparison.Array)
+	}
+}
+
+func (r *references) walkBitOperation(operation *ast.BitOperation) {
+	for ; operation != nil; operation = operation.Next {
+		r.walkUnary(operation.Unary)
+	}
+}
+
+func (r *references) walkUnary(unary *ast.Unary) {
+	for ; unary != nil; unary = unary.Unary {
+		r.walkPrimary(unary.Primary)
+	}
+}
+
+func (r *references) walkPrimary(primary *ast.Primary) {
+	if primary == nil {
+		return
+	}
+
+	if primary.Ident != nil {
+		r.idents[*primary.Ident] = true
+	}
+	if primary.Variable != nil {
+		r.variables = true
+	}
+	r.walkString(primary.String)
+	r.walkString(primary.Pattern)
+	r.walkExpression(primary.SubExpression)
+}
+
+func (r *references) walkArray(array *ast.Array) {
+	if array == nil {
+		return
+	}
+
+	if array.Ident != nil {
+		r.idents[*array.Ident] = true
+	}
+	if array.Variable != nil {
+		r.variables = true
+	}
+	for _, member := range array.StringMembers {
+		r.walkString(member.String)
+		r.walkString(member.Pattern)
+	}
+}
@@ -0,0 +1,113 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policylint
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// newEvent returns an event with the process contexts allocated, as they are by the probe
+func newEvent() eval.Event {
+	event := &model.Event{
+		ProcessContext: &model.ProcessContext{},
+	}
+	event.Exec.Process = &event.ProcessContext.Process
+	event.Signal.Target = &model.ProcessContext{}
+	event.PTrace.Tracee = &model.ProcessContext{}
+	return event
+}
+
+// globSample returns a string matched by a glob or a pattern
+func globSample(pattern string) string {
+	sample := strings.ReplaceAll(pattern, "**", "x/x")
+	sample = strings.ReplaceAll(sample, "*", "x")
+	return strings.ReplaceAll(sample, "?", "x")
+}
+
+// candidateValues returns values of a field likely to satisfy the comparisons of a rule:
+// the values the rule compares the field to and values close to them. It returns false
+// when the comparisons can't be analyzed, with regular expressions or variables.
+func candidateValues(kind reflect.Kind, values []eval.FieldValue) ([]interface{}, bool) {
+	switch kind {
+	case reflect.Bool:
+		return []interface{}{true, false}, true
+	case reflect.Int:
+		candidates := []interface{}{0}
+		var bitmasks int
+		for _, value := range values {
+			v, ok := value.Value.(int)
+			if !ok {
+				return nil, false
+			}
+			switch value.Type {
+			case eval.ScalarValueType:
+				candidates = append(candidates, v, v-1, v+1)
+			case eval.BitmaskValueType:
+				candidates = append(candidates, v)
+				bitmasks |= v
+			default:
+				return nil, false
+			}
+		}
+		return append(candidates, bitmasks, ^0), true
+	case reflect.String:
+		candidates := []interface{}{"", eval.RandString(16)}
+		for _, value := range values {
+			v, ok := value.Value.(string)
+			if !ok {
+				return nil, false
+			}
+			switch value.Type {
+			case eval.ScalarValueType:
+				candidates = append(candidates, v)
+			case eval.GlobValueType, eval.PatternValueType:
+				candidates = append(candidates, globSample(v))
+			default:
+				return nil, false
+			}
+		}
+		return candidates, true
+	}
+	return nil, false
+}
+
+// satisfiable returns whether a value of the field satisfies the rule, considering the
+// comparisons of the other fields satisfied. It returns true when it can't tell.
+func satisfiable(rule *rules.Rule, field eval.Field) bool {
+	kind, err := newEvent().GetFieldType(field)
+	if err != nil {
+		return true
+	}
+
+	candidates, ok := candidateValues(kind, rule.GetFieldValues(field))
+	if !ok {
+		return true
+	}
+
+	for _, candidate := range candidates {
+		event := newEvent()
+		if err := event.SetFieldValue(field, candidate); err != nil {
+			return true
+		}
+
+		// the combinations of the values of array fields aren't analyzed
+		if value, err := event.GetFieldValue(field); err != nil || value == nil || reflect.TypeOf(value).Kind() == reflect.Slice {
+			return true
+		}
+
+		matches, err := rule.PartialEval(eval.NewContext(event.GetPointer()), field)
+		if err != nil || matches {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,768 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+// The types below mirror the JSON serializers of pkg/security/probe, which can't be
+// imported from this module.
+
+type fileJSON struct {
+	Path           string     `json:"path"`
+	Name           string     `json:"name"`
+	Inode          uint64     `json:"inode"`
+	Mode           uint32     `json:"mode"`
+	InUpperLayer   bool       `json:"in_upper_layer"`
+	MountID        uint32     `json:"mount_id"`
+	Filesystem     string     `json:"filesystem"`
+	UID            int64      `json:"uid"`
+	GID            int64      `json:"gid"`
+	User           string     `json:"user"`
+	Group          string     `json:"group"`
+	XAttrName      string     `json:"attribute_name"`
+	XAttrNamespace string     `json:"attribute_namespace"`
+	Flags          []string   `json:"flags"`
+	Atime          *time.Time `json:"access_time"`
+	Mtime          *time.Time `json:"modification_time"`
+	Ctime          *time.Time `json:"change_time"`
+}
+
+type fileEventJSON struct {
+	fileJSON
+	Destination *fileJSON `json:"destination"`
+}
+
+type credentialsJSON struct {
+	UID          int      `json:"uid"`
+	User         string   `json:"user"`
+	GID          int      `json:"gid"`
+	Group        string   `json:"group"`
+	EUID         int      `json:"euid"`
+	EUser        string   `json:"euser"`
+	EGID         int      `json:"egid"`
+	EGroup       string   `json:"egroup"`
+	FSUID        int      `json:"fsuid"`
+	FSUser       string   `json:"fsuser"`
+	FSGID        int      `json:"fsgid"`
+	FSGroup      string   `json:"fsgroup"`
+	CapEffective []string `json:"cap_effective"`
+	CapPermitted []string `json:"cap_permitted"`
+}
+
+type processCredentialsJSON struct {
+	credentialsJSON
+	// Destination holds the credentials set by setuid, setgid and capset events
+	Destination *credentialsJSON `json:"destination"`
+}
+
+type containerJSON struct {
+	ID string `json:"id"`
+}
+
+type processJSON struct {
+	Pid           uint32                  `json:"pid"`
+	PPid          uint32                  `json:"ppid"`
+	Tid           uint32                  `json:"tid"`
+	UID           int                     `json:"uid"`
+	GID           int                     `json:"gid"`
+	User          string                  `json:"user"`
+	Group         string                  `json:"group"`
+	Comm          string                  `json:"comm"`
+	TTY           string                  `json:"tty"`
+	ForkTime      *time.Time              `json:"fork_time"`
+	ExecTime      *time.Time              `json:"exec_time"`
+	Credentials   *processCredentialsJSON `json:"credentials"`
+	Executable    *fileJSON               `json:"executable"`
+	Container     *containerJSON          `json:"container"`
+	Argv0         string                  `json:"argv0"`
+	Args          []string                `json:"args"`
+	ArgsTruncated bool                    `json:"args_truncated"`
+	Envs          []string                `json:"envs"`
+	EnvsTruncated bool                    `json:"envs_truncated"`
+}
+
+type processContextJSON struct {
+	processJSON
+	Parent    *processJSON   `json:"parent"`
+	Ancestors []*processJSON `json:"ancestors"`
+}
+
+type evtJSON struct {
+	Name    string `json:"name"`
+	Outcome string `json:"outcome"`
+	Async   bool   `json:"async"`
+	// Retval is not part of the serialized events. It can be set in hand-written
+	// events to test rules on precise errors, it takes precedence over the outcome.
+	Retval *int64 `json:"retval"`
+}
+
+type selinuxJSON struct {
+	BoolChange *struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	} `json:"bool"`
+	EnforceStatus *struct {
+		Status string `json:"status"`
+	} `json:"enforce"`
+	BoolCommit *struct {
+		State bool `json:"state"`
+	} `json:"bool_commit"`
+}
+
+type bpfJSON struct {
+	Cmd string `json:"cmd"`
+	Map *struct {
+		Name    string `json:"name"`
+		MapType string `json:"map_type"`
+	} `json:"map"`
+	Program *struct {
+		Name        string   `json:"name"`
+		Tag         string   `json:"tag"`
+		ProgramType string   `json:"program_type"`
+		AttachType  string   `json:"attach_type"`
+		Helpers     []string `json:"helpers"`
+	} `json:"program"`
+}
+
+type mmapJSON struct {
+	Address    string `json:"address"`
+	Offset     uint64 `json:"offset"`
+	Len        uint32 `json:"length"`
+	Protection string `json:"protection"`
+	Flags      string `json:"flags"`
+}
+
+type mprotectJSON struct {
+	VMProtection  string `json:"vm_protection"`
+	ReqProtection string `json:"req_protection"`
+}
+
+type ptraceJSON struct {
+	Request string              `json:"request"`
+	Address string              `json:"address"`
+	Tracee  *processContextJSON `json:"tracee"`
+}
+
+type moduleJSON struct {
+	Name             string `json:"name"`
+	LoadedFromMemory bool   `json:"loaded_from_memory"`
+}
+
+type signalJSON struct {
+	Type   string              `json:"type"`
+	PID    uint32              `json:"pid"`
+	Target *processContextJSON `json:"target"`
+}
+
+type spliceJSON struct {
+	PipeEntryFlag string `json:"pipe_entry_flag"`
+	PipeExitFlag  string `json:"pipe_exit_flag"`
+}
+
+type dnsJSON struct {
+	ID       uint16 `json:"id"`
+	Question *struct {
+		Class string `json:"class"`
+		Type  string `json:"type"`
+		Name  string `json:"name"`
+		Size  uint16 `json:"size"`
+		Count uint16 `json:"count"`
+	} `json:"question"`
+}
+
+type ipPortJSON struct {
+	Family string `json:"family"`
+	IP     string `json:"ip"`
+	Port   uint16 `json:"port"`
+}
+
+type networkJSON struct {
+	Device *struct {
+		IfIndex uint32 `json:"ifindex"`
+		IfName  string `json:"ifname"`
+	} `json:"device"`
+	L3Protocol  string      `json:"l3_protocol"`
+	L4Protocol  string      `json:"l4_protocol"`
+	Source      *ipPortJSON `json:"source"`
+	Destination *ipPortJSON `json:"destination"`
+	Size        uint32      `json:"size"`
+}
+
+type bindJSON struct {
+	Addr *ipPortJSON `json:"addr"`
+}
+
+type eventJSON struct {
+	Evt       evtJSON             `json:"evt"`
+	File      *fileEventJSON      `json:"file"`
+	SELinux   *selinuxJSON        `json:"selinux"`
+	BPF       *bpfJSON            `json:"bpf"`
+	MMap      *mmapJSON           `json:"mmap"`
+	MProtect  *mprotectJSON       `json:"mprotect"`
+	PTrace    *ptraceJSON         `json:"ptrace"`
+	Module    *moduleJSON         `json:"module"`
+	Signal    *signalJSON         `json:"signal"`
+	Splice    *spliceJSON         `json:"splice"`
+	DNS       *dnsJSON            `json:"dns"`
+	Network   *networkJSON        `json:"network"`
+	Bind      *bindJSON           `json:"bind"`
+	Process   *processContextJSON `json:"process"`
+	Container *containerJSON      `json:"container"`
+	Date      time.Time           `json:"date"`
+}
+
+// UnmarshalEvent builds an event of the model from its JSON serialization, as sent by
+// the runtime security module. The fields computed by the resolvers of the probe,
+// like the paths or the users, are read from the JSON document and not resolved.
+func UnmarshalEvent(data []byte) (*model.Event, error) {
+	var ej eventJSON
+	if err := json.Unmarshal(data, &ej); err != nil {
+		return nil, err
+	}
+	return ej.toModel()
+}
+
+// constant returns the value of a SECL constant, 0 if the name is empty.
+func constant(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	if c, ok := model.SECLConstants[name].(*eval.IntEvaluator); ok {
+		return c.Value, nil
+	}
+	if value, err := strconv.Atoi(name); err == nil {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown constant `%s`", name)
+}
+
+// bitmask returns the value of a bitmask serialized as `A | B`.
+func bitmask(str string) (int, error) {
+	return bitmaskArray(strings.Split(str, "|"))
+}
+
+// bitmaskArray returns the value of a bitmask serialized as a list of constants.
+func bitmaskArray(names []string) (int, error) {
+	var value int
+	for _, name := range names {
+		v, err := constant(strings.TrimSpace(name))
+		if err != nil {
+			return 0, err
+		}
+		value |= v
+	}
+	return value, nil
+}
+
+func address(str string) uint64 {
+	addr, _ := strconv.ParseUint(strings.TrimPrefix(str, "0x"), 16, 64)
+	return addr
+}
+
+func unixNano(t *time.Time) uint64 {
+	if t == nil {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+func outcomeRetval(evt *evtJSON) int64 {
+	if evt.Retval != nil {
+		return *evt.Retval
+	}
+	switch strings.ToLower(evt.Outcome) {
+	case "refused":
+		return -int64(syscall.EACCES)
+	case "error":
+		return -int64(syscall.EINVAL)
+	default:
+		return 0
+	}
+}
+
+func (f *fileJSON) toModel(fe *model.FileEvent) {
+	if f == nil {
+		return
+	}
+	fe.SetPathnameStr(f.Path)
+	name := f.Name
+	if name == "" && f.Path != "" {
+		name = path.Base(f.Path)
+	}
+	fe.SetBasenameStr(name)
+	fe.Filesystem = f.Filesystem
+	fe.Inode = f.Inode
+	fe.Mode = uint16(f.Mode)
+	fe.InUpperLayer = f.InUpperLayer
+	fe.MountID = f.MountID
+	fe.UID = uint32(f.UID)
+	fe.GID = uint32(f.GID)
+	fe.User = f.User
+	fe.Group = f.Group
+	fe.MTime = unixNano(f.Mtime)
+	fe.CTime = unixNano(f.Ctime)
+}
+
+func (c *credentialsJSON) toModel(creds *model.Credentials) error {
+	creds.UID, creds.User = uint32(c.UID), c.User
+	creds.GID, creds.Group = uint32(c.GID), c.Group
+	creds.EUID, creds.EUser = uint32(c.EUID), c.EUser
+	creds.EGID, creds.EGroup = uint32(c.EGID), c.EGroup
+	creds.FSUID, creds.FSUser = uint32(c.FSUID), c.FSUser
+	creds.FSGID, creds.FSGroup = uint32(c.FSGID), c.FSGroup
+
+	capEffective, err := bitmaskArray(c.CapEffective)
+	if err != nil {
+		return err
+	}
+	capPermitted, err := bitmaskArray(c.CapPermitted)
+	if err != nil {
+		return err
+	}
+	creds.CapEffective, creds.CapPermitted = uint64(capEffective), uint64(capPermitted)
+	return nil
+}
+
+func (p *processJSON) toModel(process *model.Process) error {
+	process.Pid = p.Pid
+	process.Tid = p.Tid
+	process.PPid = p.PPid
+	process.Comm = p.Comm
+	process.TTYName = p.TTY
+	p.Executable.toModel(&process.FileEvent)
+	if p.Container != nil {
+		process.ContainerID = p.Container.ID
+	}
+
+	if p.ForkTime != nil {
+		process.ForkTime = *p.ForkTime
+		process.CreatedAt = unixNano(p.ForkTime)
+	}
+	if p.ExecTime != nil {
+		process.ExecTime = *p.ExecTime
+		process.CreatedAt = unixNano(p.ExecTime)
+	}
+
+	if p.Credentials != nil {
+		if err := p.Credentials.toModel(&process.Credentials); err != nil {
+			return err
+		}
+	} else {
+		process.UID, process.User = uint32(p.UID), p.User
+		process.GID, process.Group = uint32(p.GID), p.Group
+	}
+
+	// args don't include argv0, as in the serializers
+	process.Argv0 = p.Argv0
+	process.Argv = p.Args
+	process.Args = strings.Join(p.Args, " ")
+	process.ArgsTruncated = p.ArgsTruncated
+
+	// the serializers only report the names of the environment variables, values
+	// can be given in hand-written events with the `NAME=value` format
+	for _, env := range p.Envs {
+		name := env
+		if i := strings.IndexByte(env, '='); i >= 0 {
+			name = env[:i]
+			process.Envp = append(process.Envp, env)
+		}
+		process.Envs = append(process.Envs, name)
+	}
+	process.EnvsTruncated = p.EnvsTruncated
+
+	return nil
+}
+
+func (p *processContextJSON) toModel() (*model.ProcessContext, error) {
+	pc := &model.ProcessContext{}
+	if p == nil {
+		return pc, nil
+	}
+	if err := p.processJSON.toModel(&pc.Process); err != nil {
+		return nil, err
+	}
+
+	ancestors := p.Ancestors
+	if len(ancestors) == 0 && p.Parent != nil {
+		ancestors = []*processJSON{p.Parent}
+	}
+
+	child := pc
+	for _, ancestor := range ancestors {
+		entry := &model.ProcessCacheEntry{}
+		if err := ancestor.toModel(&entry.Process); err != nil {
+			return nil, err
+		}
+		child.Ancestor = entry
+		child = &entry.ProcessContext
+	}
+
+	return pc, nil
+}
+
+func ip(ipPort *ipPortJSON) net.IPNet {
+	addr := net.ParseIP(ipPort.IP)
+	if addr == nil {
+		return net.IPNet{}
+	}
+	if v4 := addr.To4(); v4 != nil {
+		return net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}
+	}
+	return net.IPNet{IP: addr, Mask: net.CIDRMask(128, 128)}
+}
+
+func (n *networkJSON) toModel(nc *model.NetworkContext) error {
+	if n.Device != nil {
+		nc.Device.IfIndex = n.Device.IfIndex
+		nc.Device.IfName = n.Device.IfName
+	}
+	l3, err := constant(n.L3Protocol)
+	if err != nil {
+		return err
+	}
+	l4, err := constant(n.L4Protocol)
+	if err != nil {
+		return err
+	}
+	nc.L3Protocol, nc.L4Protocol = uint16(l3), uint16(l4)
+	if n.Source != nil {
+		nc.Source = model.IPPortContext{IPNet: ip(n.Source), Port: n.Source.Port}
+	}
+	if n.Destination != nil {
+		nc.Destination = model.IPPortContext{IPNet: ip(n.Destination), Port: n.Destination.Port}
+	}
+	nc.Size = n.Size
+	return nil
+}
+
+// destination returns the destination of the file of the event, empty if none.
+func (ej *eventJSON) destination() *fileJSON {
+	if ej.File == nil || ej.File.Destination == nil {
+		return &fileJSON{}
+	}
+	return ej.File.Destination
+}
+
+func (ej *eventJSON) file() *fileJSON {
+	if ej.File == nil {
+		return nil
+	}
+	return &ej.File.fileJSON
+}
+
+// credentialsDestination returns the credentials set by the event, empty if none.
+func (ej *eventJSON) credentialsDestination() *credentialsJSON {
+	if ej.Process == nil || ej.Process.Credentials == nil || ej.Process.Credentials.Destination == nil {
+		return &credentialsJSON{}
+	}
+	return ej.Process.Credentials.Destination
+}
+
+func (ej *eventJSON) toModel() (*model.Event, error) {
+	eventType := model.ParseEvalEventType(ej.Evt.Name)
+	if eventType == model.UnknownEventType {
+		return nil, fmt.Errorf("unknown event type `%s`", ej.Evt.Name)
+	}
+
+	var err error
+	event := &model.Event{
+		Type:      uint32(eventType),
+		Async:     ej.Evt.Async,
+		Timestamp: ej.Date,
+	}
+	if event.ProcessContext, err = ej.Process.toModel(); err != nil {
+		return nil, fmt.Errorf("invalid process: %w", err)
+	}
+	if ej.Container != nil {
+		event.ContainerContext.ID = ej.Container.ID
+	} else {
+		event.ContainerContext.ID = event.ProcessContext.ContainerID
+	}
+	if ej.Network != nil {
+		if err := ej.Network.toModel(&event.NetworkContext); err != nil {
+			return nil, fmt.Errorf("invalid network context: %w", err)
+		}
+	}
+
+	// the process contexts of the signal and ptrace events are always set
+	event.Signal.Target = &model.ProcessContext{}
+	event.PTrace.Tracee = &model.ProcessContext{}
+
+	if err := ej.eventToModel(event, eventType, outcomeRetval(&ej.Evt)); err != nil {
+		return nil, fmt.Errorf("invalid %s event: %w", ej.Evt.Name, err)
+	}
+	return event, nil
+}
+
+func (ej *eventJSON) eventToModel(event *model.Event, eventType model.EventType, retval int64) error {
+	var err error
+
+	switch eventType {
+	case model.FileChmodEventType:
+		event.Chmod.Retval = retval
+		ej.file().toModel(&event.Chmod.File)
+		event.Chmod.Mode = ej.destination().Mode
+	case model.FileChownEventType:
+		event.Chown.Retval = retval
+		ej.file().toModel(&event.Chown.File)
+		dst := ej.destination()
+		event.Chown.UID, event.Chown.User = dst.UID, dst.User
+		event.Chown.GID, event.Chown.Group = dst.GID, dst.Group
+	case model.FileLinkEventType:
+		event.Link.Retval = retval
+		ej.file().toModel(&event.Link.Source)
+		ej.destination().toModel(&event.Link.Target)
+	case model.FileOpenEventType:
+		event.Open.Retval = retval
+		ej.file().toModel(&event.Open.File)
+		event.Open.Mode = ej.destination().Mode
+		var flags int
+		if ej.File != nil {
+			if flags, err = bitmaskArray(ej.File.Flags); err != nil {
+				return err
+			}
+		}
+		event.Open.Flags = uint32(flags)
+	case model.FileMkdirEventType:
+		event.Mkdir.Retval = retval
+		ej.file().toModel(&event.Mkdir.File)
+		event.Mkdir.Mode = ej.destination().Mode
+	case model.FileRmdirEventType:
+		event.Rmdir.Retval = retval
+		ej.file().toModel(&event.Rmdir.File)
+	case model.FileUnlinkEventType:
+		event.Unlink.Retval = retval
+		ej.file().toModel(&event.Unlink.File)
+		var flags int
+		if ej.File != nil {
+			if flags, err = bitmaskArray(ej.File.Flags); err != nil {
+				return err
+			}
+		}
+		event.Unlink.Flags = uint32(flags)
+	case model.FileRenameEventType:
+		event.Rename.Retval = retval
+		ej.file().toModel(&event.Rename.Old)
+		ej.destination().toModel(&event.Rename.New)
+	case model.FileSetXAttrEventType, model.FileRemoveXAttrEventType:
+		xattr := &event.SetXAttr
+		if eventType == model.FileRemoveXAttrEventType {
+			xattr = &event.RemoveXAttr
+		}
+		xattr.Retval = retval
+		ej.file().toModel(&xattr.File)
+		xattr.Name = ej.destination().XAttrName
+		xattr.Namespace = ej.destination().XAttrNamespace
+	case model.FileUtimesEventType:
+		event.Utimes.Retval = retval
+		ej.file().toModel(&event.Utimes.File)
+		if t := ej.destination().Atime; t != nil {
+			event.Utimes.Atime = *t
+		}
+		if t := ej.destination().Mtime; t != nil {
+			event.Utimes.Mtime = *t
+		}
+	case model.SetuidEventType:
+		dst := ej.credentialsDestination()
+		event.SetUID = model.SetuidEvent{
+			UID: uint32(dst.UID), User: dst.User,
+			EUID: uint32(dst.EUID), EUser: dst.EUser,
+			FSUID: uint32(dst.FSUID), FSUser: dst.FSUser,
+		}
+	case model.SetgidEventType:
+		dst := ej.credentialsDestination()
+		event.SetGID = model.SetgidEvent{
+			GID: uint32(dst.GID), Group: dst.Group,
+			EGID: uint32(dst.EGID), EGroup: dst.EGroup,
+			FSGID: uint32(dst.FSGID), FSGroup: dst.FSGroup,
+		}
+	case model.CapsetEventType:
+		var creds model.Credentials
+		if err := ej.credentialsDestination().toModel(&creds); err != nil {
+			return err
+		}
+		event.Capset.CapEffective, event.Capset.CapPermitted = creds.CapEffective, creds.CapPermitted
+	case model.ExecEventType:
+		// the file of an exec event is the executable of the process
+		if ej.Process == nil || ej.Process.Executable == nil {
+			ej.file().toModel(&event.ProcessContext.Process.FileEvent)
+		}
+		event.Exec.Process = &event.ProcessContext.Process
+	case model.SELinuxEventType:
+		ej.file().toModel(&event.SELinux.File)
+		if s := ej.SELinux; s != nil {
+			switch {
+			case s.BoolChange != nil:
+				event.SELinux.EventKind = model.SELinuxBoolChangeEventKind
+				event.SELinux.BoolName, event.SELinux.BoolChangeValue = s.BoolChange.Name, s.BoolChange.State
+			case s.EnforceStatus != nil:
+				event.SELinux.EventKind = model.SELinuxStatusChangeEventKind
+				event.SELinux.EnforceStatus = s.EnforceStatus.Status
+			case s.BoolCommit != nil:
+				event.SELinux.EventKind = model.SELinuxBoolCommitEventKind
+				event.SELinux.BoolCommitValue = s.BoolCommit.State
+			}
+		}
+	case model.BPFEventType:
+		return ej.bpfToModel(&event.BPF)
+	case model.MMapEventType:
+		event.MMap.Retval = retval
+		ej.file().toModel(&event.MMap.File)
+		if m := ej.MMap; m != nil {
+			event.MMap.Addr, event.MMap.Offset, event.MMap.Len = address(m.Address), m.Offset, m.Len
+			if event.MMap.Protection, err = bitmask(m.Protection); err != nil {
+				return err
+			}
+			if event.MMap.Flags, err = bitmask(m.Flags); err != nil {
+				return err
+			}
+		}
+	case model.MProtectEventType:
+		event.MProtect.Retval = retval
+		if m := ej.MProtect; m != nil {
+			if event.MProtect.VMProtection, err = bitmask(m.VMProtection); err != nil {
+				return err
+			}
+			if event.MProtect.ReqProtection, err = bitmask(m.ReqProtection); err != nil {
+				return err
+			}
+		}
+	case model.PTraceEventType:
+		event.PTrace.Retval = retval
+		if p := ej.PTrace; p != nil {
+			request, err := constant(p.Request)
+			if err != nil {
+				return err
+			}
+			event.PTrace.Request = uint32(request)
+			event.PTrace.Address = address(p.Address)
+			if event.PTrace.Tracee, err = p.Tracee.toModel(); err != nil {
+				return err
+			}
+			event.PTrace.PID = event.PTrace.Tracee.Pid
+		}
+	case model.LoadModuleEventType:
+		event.LoadModule.Retval = retval
+		ej.file().toModel(&event.LoadModule.File)
+		if m := ej.Module; m != nil {
+			event.LoadModule.Name, event.LoadModule.LoadedFromMemory = m.Name, m.LoadedFromMemory
+		}
+	case model.UnloadModuleEventType:
+		event.UnloadModule.Retval = retval
+		if m := ej.Module; m != nil {
+			event.UnloadModule.Name = m.Name
+		}
+	case model.SignalEventType:
+		event.Signal.Retval = retval
+		if s := ej.Signal; s != nil {
+			signal, err := constant(s.Type)
+			if err != nil {
+				return err
+			}
+			event.Signal.Type, event.Signal.PID = uint32(signal), s.PID
+			if event.Signal.Target, err = s.Target.toModel(); err != nil {
+				return err
+			}
+		}
+	case model.SpliceEventType:
+		event.Splice.Retval = retval
+		ej.file().toModel(&event.Splice.File)
+		if s := ej.Splice; s != nil {
+			entry, err := bitmask(s.PipeEntryFlag)
+			if err != nil {
+				return err
+			}
+			exit, err := bitmask(s.PipeExitFlag)
+			if err != nil {
+				return err
+			}
+			event.Splice.PipeEntryFlag, event.Splice.PipeExitFlag = uint32(entry), uint32(exit)
+		}
+	case model.DNSEventType:
+		if d := ej.DNS; d != nil {
+			event.DNS.ID = d.ID
+			if q := d.Question; q != nil {
+				qclass, err := constant(q.Class)
+				if err != nil {
+					return err
+				}
+				qtype, err := constant(q.Type)
+				if err != nil {
+					return err
+				}
+				event.DNS.Name, event.DNS.Class, event.DNS.Type = q.Name, uint16(qclass), uint16(qtype)
+				event.DNS.Size, event.DNS.Count = q.Size, q.Count
+			}
+		}
+	case model.BindEventType:
+		event.Bind.Retval = retval
+		if b := ej.Bind; b != nil && b.Addr != nil {
+			family, err := constant(b.Addr.Family)
+			if err != nil {
+				return err
+			}
+			event.Bind.AddrFamily = uint16(family)
+			event.Bind.Addr = model.IPPortContext{IPNet: ip(b.Addr), Port: b.Addr.Port}
+		}
+	}
+
+	return nil
+}
+
+func (ej *eventJSON) bpfToModel(bpf *model.BPFEvent) error {
+	b := ej.BPF
+	if b == nil {
+		return nil
+	}
+
+	cmd, err := constant(b.Cmd)
+	if err != nil {
+		return err
+	}
+	bpf.Cmd = uint32(cmd)
+
+	if m := b.Map; m != nil {
+		mapType, err := constant(m.MapType)
+		if err != nil {
+			return err
+		}
+		bpf.Map.Name, bpf.Map.Type = m.Name, uint32(mapType)
+	}
+
+	if p := b.Program; p != nil {
+		programType, err := constant(p.ProgramType)
+		if err != nil {
+			return err
+		}
+		bpf.Program.Name, bpf.Program.Tag, bpf.Program.Type = p.Name, p.Tag, uint32(programType)
+		attachType, err := constant(p.AttachType)
+		if err != nil {
+			return err
+		}
+		bpf.Program.AttachType = uint32(attachType)
+		for _, helper := range p.Helpers {
+			value, err := constant(helper)
+			if err != nil {
+				return err
+			}
+			bpf.Program.Helpers = append(bpf.Program.Helpers, uint32(value))
+		}
+	}
+
+	return nil
+}
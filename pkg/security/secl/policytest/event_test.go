@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+func fieldValue(t *testing.T, event *model.Event, field string) interface{} {
+	t.Helper()
+	value, err := event.GetFieldValue(field)
+	require.NoError(t, err)
+	return value
+}
+
+func TestUnmarshalOpenEvent(t *testing.T) {
+	event, err := UnmarshalEvent([]byte(`{
+		"evt": {"name": "open", "category": "File Activity", "outcome": "Refused"},
+		"file": {
+			"path": "/etc/shadow", "inode": 42, "mode": 33184, "uid": 0, "gid": 42, "group": "shadow",
+			"flags": ["O_WRONLY", "O_CREAT"],
+			"destination": {"mode": 420}
+		},
+		"process": {
+			"pid": 3, "ppid": 2, "comm": "vi", "tty": "pts/0",
+			"credentials": {"uid": 1000, "user": "bob", "gid": 1000, "euid": 0, "cap_effective": ["CAP_CHOWN", "CAP_KILL"], "cap_permitted": []},
+			"executable": {"path": "/usr/bin/vi", "name": "vi"},
+			"container": {"id": "abc"},
+			"argv0": "vi",
+			"args": ["-n", "/etc/shadow"],
+			"envs": ["HOME", "TERM=xterm"],
+			"ancestors": [
+				{"pid": 2, "executable": {"path": "/bin/bash"}},
+				{"pid": 1, "executable": {"path": "/sbin/init"}}
+			]
+		},
+		"date": "2022-06-01T10:00:00Z"
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "open", event.GetType())
+	assert.Equal(t, "/etc/shadow", fieldValue(t, event, "open.file.path"))
+	assert.Equal(t, "shadow", fieldValue(t, event, "open.file.name"))
+	assert.Equal(t, "shadow", fieldValue(t, event, "open.file.group"))
+	assert.Equal(t, syscall.O_WRONLY|syscall.O_CREAT, fieldValue(t, event, "open.flags"))
+	assert.Equal(t, 420, fieldValue(t, event, "open.file.destination.mode"))
+	assert.Equal(t, -int(syscall.EACCES), fieldValue(t, event, "open.retval"))
+
+	assert.Equal(t, "vi", fieldValue(t, event, "process.comm"))
+	assert.Equal(t, "bob", fieldValue(t, event, "process.user"))
+	assert.Equal(t, 0, fieldValue(t, event, "process.euid"))
+	assert.Equal(t, int(model.KernelCapabilityConstants["CAP_CHOWN"]|model.KernelCapabilityConstants["CAP_KILL"]), fieldValue(t, event, "process.cap_effective"))
+	assert.Equal(t, "-n /etc/shadow", fieldValue(t, event, "process.args"))
+	assert.Equal(t, []string{"HOME", "TERM"}, fieldValue(t, event, "process.envs"))
+	assert.Equal(t, []string{"TERM=xterm"}, fieldValue(t, event, "process.envp"))
+	assert.Equal(t, "abc", fieldValue(t, event, "container.id"))
+	assert.Equal(t, []string{"bash", "init"}, fieldValue(t, event, "process.ancestors.file.name"))
+	assert.Equal(t, []int{2, 1}, fieldValue(t, event, "process.ancestors.pid"))
+}
+
+func TestUnmarshalExecEvent(t *testing.T) {
+	event, err := UnmarshalEvent([]byte(`{
+		"evt": {"name": "exec"},
+		"file": {"path": "/usr/bin/curl", "name": "curl"},
+		"process": {
+			"pid": 4,
+			"executable": {"path": "/usr/bin/curl", "name": "curl"},
+			"args": ["http://example.com"],
+			"parent": {"pid": 3, "executable": {"path": "/usr/sbin/nginx"}}
+		}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "/usr/bin/curl", fieldValue(t, event, "exec.file.path"))
+	assert.Equal(t, "http://example.com", fieldValue(t, event, "exec.args"))
+	assert.Equal(t, []string{"nginx"}, fieldValue(t, event, "process.ancestors.file.name"))
+}
+
+func TestUnmarshalNetworkEvents(t *testing.T) {
+	event, err := UnmarshalEvent([]byte(`{
+		"evt": {"name": "dns"},
+		"network": {
+			"l3_protocol": "ETH_P_IP", "l4_protocol": "IP_PROTO_UDP",
+			"source": {"ip": "10.0.0.2", "port": 4242},
+			"destination": {"ip": "8.8.8.8", "port": 53}
+		},
+		"dns": {"id": 1, "question": {"class": "CLASS_INET", "type": "A", "name": "example.com", "size": 29, "count": 1}}
+	}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com", fieldValue(t, event, "dns.question.name"))
+	assert.Equal(t, int(model.DNSQTypeConstants["A"]), fieldValue(t, event, "dns.question.type"))
+	assert.Equal(t, 53, fieldValue(t, event, "network.destination.port"))
+	assert.Equal(t, net.IPNet{IP: net.IPv4(8, 8, 8, 8).To4(), Mask: net.CIDRMask(32, 32)}, fieldValue(t, event, "network.destination.ip"))
+
+	event, err = UnmarshalEvent([]byte(`{"evt": {"name": "bind", "retval": -98}, "bind": {"addr": {"family": "AF_INET6", "ip": "::1", "port": 80}}}`))
+	require.NoError(t, err)
+	assert.Equal(t, syscall.AF_INET6, fieldValue(t, event, "bind.addr.family"))
+	assert.Equal(t, -98, fieldValue(t, event, "bind.retval"))
+}
+
+func TestUnmarshalInvalidEvent(t *testing.T) {
+	_, err := UnmarshalEvent([]byte(`{"evt": {"name": "unknown"}}`))
+	assert.EqualError(t, err, "unknown event type `unknown`")
+
+	_, err = UnmarshalEvent([]byte(`{"evt": {"name": "signal"}, "signal": {"type": "SIGFOO"}}`))
+	assert.EqualError(t, err, "invalid signal event: unknown constant `SIGFOO`")
+
+	_, err = UnmarshalEvent([]byte(`{"evt": "open"}`))
+	assert.Error(t, err)
+}
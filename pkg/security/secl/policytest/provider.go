@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// PolicyFileProvider provides the policy of a single file
+type PolicyFileProvider struct {
+	Filename string
+}
+
+// NewPolicyFileProvider returns a provider of the policy of the given file
+func NewPolicyFileProvider(filename string) *PolicyFileProvider {
+	return &PolicyFileProvider{Filename: filename}
+}
+
+// SetOnNewPoliciesReadyCb implements the policy provider interface
+func (p *PolicyFileProvider) SetOnNewPoliciesReadyCb(cb func()) {}
+
+// LoadPolicies implements the policy provider interface
+func (p *PolicyFileProvider) LoadPolicies() ([]*rules.Policy, *multierror.Error) {
+	f, err := os.Open(p.Filename)
+	if err != nil {
+		return nil, multierror.Append(nil, &rules.ErrPolicyLoad{Name: p.Filename, Err: err})
+	}
+	defer f.Close()
+
+	policy, err := rules.LoadPolicy(filepath.Base(p.Filename), "file", f)
+	if err != nil {
+		return nil, multierror.Append(nil, err)
+	}
+	return []*rules.Policy{policy}, nil
+}
@@ -0,0 +1,217 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+)
+
+// TestCase is an event to evaluate, with the rules it is expected to match
+type TestCase struct {
+	Name  string          `json:"name"`
+	Event json.RawMessage `json:"event"`
+	// ExpectedRules are the rules the event must match, no other rule must match it.
+	// The matches are not checked when nil.
+	ExpectedRules []eval.RuleID `json:"expected_rules"`
+}
+
+// ReadTestCases reads test cases from a stream of JSON documents, like a JSON array or
+// JSON lines. Each document is either a test case or an event to evaluate without any
+// expectation, as serialized by the runtime security module.
+func ReadTestCases(r io.Reader) ([]TestCase, error) {
+	var testCases []TestCase
+
+	add := func(doc json.RawMessage) error {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(doc, &fields); err != nil {
+			return fmt.Errorf("test case #%d: %w", len(testCases), err)
+		}
+
+		var tc TestCase
+		if _, isTestCase := fields["event"]; isTestCase {
+			if err := json.Unmarshal(doc, &tc); err != nil {
+				return fmt.Errorf("test case #%d: %w", len(testCases), err)
+			}
+		} else {
+			tc.Event = doc
+		}
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("#%d", len(testCases))
+		}
+		testCases = append(testCases, tc)
+		return nil
+	}
+
+	decoder := json.NewDecoder(r)
+	for {
+		var doc json.RawMessage
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			return testCases, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		if doc = bytes.TrimSpace(doc); len(doc) > 0 && doc[0] == '[' {
+			var docs []json.RawMessage
+			if err := json.Unmarshal(doc, &docs); err != nil {
+				return nil, err
+			}
+			for _, d := range docs {
+				if err := add(d); err != nil {
+					return nil, err
+				}
+			}
+		} else if err := add(doc); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Report is the report of a test case
+type Report struct {
+	Name   string  `json:"name"`
+	Result *Result `json:"result,omitempty"`
+	// Error is set when the event of the test case is invalid
+	Error string `json:"error,omitempty"`
+	// Failures lists the expectations of the test case which are not met
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Failed returns whether the test case failed
+func (r *Report) Failed() bool {
+	return r.Error != "" || len(r.Failures) > 0
+}
+
+// Run evaluates the event of the test case and checks the expected rules
+func (t *Tester) Run(tc TestCase) *Report {
+	report := &Report{Name: tc.Name}
+
+	event, err := UnmarshalEvent(tc.Event)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+
+	report.Result = t.Evaluate(event)
+	if tc.ExpectedRules == nil {
+		return report
+	}
+
+	matched := make(map[eval.RuleID]bool)
+	for _, ruleID := range report.Result.MatchedRules {
+		matched[ruleID] = true
+	}
+
+	for _, ruleID := range tc.ExpectedRules {
+		if matched[ruleID] {
+			delete(matched, ruleID)
+			continue
+		}
+
+		failure := fmt.Sprintf("rule `%s` didn't match", ruleID)
+		if eventType, err := t.RuleEventType(ruleID); err != nil {
+			failure += ": " + err.Error()
+		} else if eventType != report.Result.EventType {
+			failure += fmt.Sprintf(": it applies to `%s` events, not to `%s` events", eventType, report.Result.EventType)
+		} else if reasons := report.Result.Mismatch(ruleID); len(reasons) > 0 {
+			failure += ": " + strings.Join(reasons, "; ")
+		}
+		report.Failures = append(report.Failures, failure)
+	}
+
+	unexpected := make([]eval.RuleID, 0, len(matched))
+	for ruleID := range matched {
+		unexpected = append(unexpected, ruleID)
+	}
+	sort.Strings(unexpected)
+	for _, ruleID := range unexpected {
+		report.Failures = append(report.Failures, fmt.Sprintf("rule `%s` matched unexpectedly", ruleID))
+	}
+
+	return report
+}
+
+// RunAll runs the test cases in order, so that the variables set by the rules
+// of an event are visible to the next ones.
+func (t *Tester) RunAll(testCases []TestCase) []*Report {
+	reports := make([]*Report, 0, len(testCases))
+	for _, tc := range testCases {
+		reports = append(reports, t.Run(tc))
+	}
+	return reports
+}
+
+// WriteText writes the reports in a human readable format
+func WriteText(w io.Writer, reports []*Report) error {
+	var b strings.Builder
+	var failed int
+
+	for _, report := range reports {
+		status := "PASS"
+		if report.Failed() {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(&b, "=== %s: %s\n", status, report.Name)
+
+		if report.Error != "" {
+			fmt.Fprintf(&b, "  invalid event: %s\n", report.Error)
+			continue
+		}
+
+		result := report.Result
+		fmt.Fprintf(&b, "  event type: %s\n", result.EventType)
+		if len(result.MatchedRules) > 0 {
+			fmt.Fprintf(&b, "  matched rules: %s\n", strings.Join(result.MatchedRules, ", "))
+		} else {
+			fmt.Fprintf(&b, "  matched rules: none\n")
+		}
+
+		if len(result.Approvers) > 0 {
+			if result.Approved {
+				fmt.Fprintf(&b, "  approvers: the event is approved by\n")
+			} else {
+				fmt.Fprintf(&b, "  approvers: the event would be filtered out in kernel, none of them matches\n")
+			}
+			for _, approver := range result.Approvers {
+				if approver.Matches || !result.Approved {
+					fmt.Fprintf(&b, "    - %s == %s\n", approver.Field, formatValue(approver.Value))
+				}
+			}
+		}
+
+		if len(result.Discarders) > 0 {
+			fmt.Fprintf(&b, "  discarders:\n")
+			for _, discarder := range result.Discarders {
+				fmt.Fprintf(&b, "    - %s == %s\n", discarder.Field, formatValue(discarder.Value))
+			}
+		}
+
+		for _, mismatch := range result.Mismatches {
+			fmt.Fprintf(&b, "  rule %s didn't match:\n", mismatch.RuleID)
+			for _, reason := range mismatch.Reasons {
+				fmt.Fprintf(&b, "    - %s\n", reason)
+			}
+		}
+
+		for _, failure := range report.Failures {
+			fmt.Fprintf(&b, "  FAILURE: %s\n", failure)
+		}
+	}
+
+	fmt.Fprintf(&b, "\n%d test case(s), %d failed\n", len(reports), failed)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
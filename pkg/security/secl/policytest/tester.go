@@ -0,0 +1,313 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package policytest evaluates runtime security policies against recorded or
+// hand-written events, without any probe, so that detection content can be unit
+// tested. It reports the rules matched by each event, the approvers and the
+// discarders the probe would apply, and why the other rules did not match.
+package policytest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+// Opts defines the options of a Tester
+type Opts struct {
+	// FieldCapabilities lists, by event type, the fields which can be filtered
+	// in kernel. No approver is reported for the event types without capabilities.
+	FieldCapabilities map[eval.EventType]rules.FieldCapabilities
+	// SupportedDiscarders lists the fields which support discarders. All the fields
+	// of the rules are considered when nil.
+	SupportedDiscarders map[eval.Field]bool
+}
+
+// Approver is an approver of the event type of a tested event
+type Approver struct {
+	Field eval.Field  `json:"field"`
+	Value interface{} `json:"value"`
+	// Matches is true when the value of the field in the event satisfies the approver
+	Matches bool `json:"matches"`
+}
+
+// Discarder is a discarder the probe would push for a tested event
+type Discarder struct {
+	Field eval.Field  `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// Mismatch explains why a rule did not match an event
+type Mismatch struct {
+	RuleID  eval.RuleID `json:"rule_id"`
+	Reasons []string    `json:"reasons"`
+}
+
+// Result is the result of the evaluation of an event against the rule set
+type Result struct {
+	EventType    eval.EventType `json:"event_type"`
+	MatchedRules []eval.RuleID  `json:"matched_rules"`
+	// Approved is false when the event type has approvers and none of them matches
+	// the event, which would be filtered out in kernel.
+	Approved   bool        `json:"approved"`
+	Approvers  []Approver  `json:"approvers,omitempty"`
+	Discarders []Discarder `json:"discarders,omitempty"`
+	// Mismatches lists the rules of the event type which didn't match the event
+	Mismatches []Mismatch `json:"mismatches,omitempty"`
+}
+
+// Mismatch returns the reasons why the rule didn't match the event, nil if it matched
+// or if it applies to another event type.
+func (r *Result) Mismatch(ruleID eval.RuleID) []string {
+	for _, m := range r.Mismatches {
+		if m.RuleID == ruleID {
+			return m.Reasons
+		}
+	}
+	return nil
+}
+
+// listener records the matches and the discarders notified by the rule set
+type listener struct {
+	matches    []eval.RuleID
+	discarders []eval.Field
+}
+
+func (l *listener) RuleMatch(rule *rules.Rule, event eval.Event) {
+	l.matches = append(l.matches, rule.ID)
+}
+
+func (l *listener) EventDiscarderFound(rs *rules.RuleSet, event eval.Event, field eval.Field, eventType eval.EventType) {
+	l.discarders = append(l.discarders, field)
+}
+
+// Tester evaluates events against a rule set using the runtime security model
+type Tester struct {
+	ruleSet   *rules.RuleSet
+	approvers map[eval.EventType]rules.Approvers
+	listener  *listener
+}
+
+// NewRuleSet returns a rule set using the runtime security model, without any probe
+func NewRuleSet(supportedDiscarders map[eval.Field]bool) *rules.RuleSet {
+	variables := make(map[string]eval.VariableValue, len(model.SECLVariables))
+	for name, value := range model.SECLVariables {
+		variables[name] = value
+	}
+
+	var opts rules.Opts
+	opts.
+		WithConstants(model.SECLConstants).
+		WithVariables(variables).
+		WithSupportedDiscarders(supportedDiscarders).
+		WithEventTypeEnabled(map[eval.EventType]bool{"*": true}).
+		WithLegacyFields(model.SECLLegacyFields).
+		WithStateScopes(map[rules.Scope]rules.VariableProviderFactory{
+			"process": func() rules.VariableProvider {
+				return eval.NewScopedVariables(func(ctx *eval.Context) unsafe.Pointer {
+					return unsafe.Pointer(&(*model.Event)(ctx.Object).ProcessContext)
+				}, nil)
+			},
+		}).
+		WithSequenceOpts(rules.SequenceOpts{
+			// sequences are evaluated with the date of the events, the events without
+			// a date are all considered to happen at the same time
+			Timestamp: func(event eval.Event) time.Time {
+				return event.(*model.Event).Timestamp
+			},
+		})
+
+	m := &model.Model{}
+	return rules.NewRuleSet(m, m.NewEvent, &opts)
+}
+
+// NewTester returns a Tester for the policies of the given loader. Any error while
+// loading the policies is returned, as the rules wouldn't be tested.
+func NewTester(loader *rules.PolicyLoader, testerOpts Opts) (*Tester, error) {
+	ruleSet := NewRuleSet(testerOpts.SupportedDiscarders)
+	if err := ruleSet.LoadPolicies(loader); err.ErrorOrNil() != nil {
+		return nil, err
+	}
+
+	approvers, err := ruleSet.GetApprovers(testerOpts.FieldCapabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{}
+	ruleSet.AddListener(l)
+
+	return &Tester{
+		ruleSet:   ruleSet,
+		approvers: approvers,
+		listener:  l,
+	}, nil
+}
+
+// RuleSet returns the rule set of the tester
+func (t *Tester) RuleSet() *rules.RuleSet {
+	return t.ruleSet
+}
+
+// Evaluate evaluates the event against the rule set. The rule actions are run, so the
+// variables they set are kept for the next events.
+func (t *Tester) Evaluate(event *model.Event) *Result {
+	t.listener.matches, t.listener.discarders = nil, nil
+
+	eventType := event.GetType()
+	result := &Result{
+		EventType: eventType,
+		Approved:  true,
+	}
+
+	// get the reasons of the mismatches before the evaluation, the actions of the
+	// matching rules may change the variables
+	ctx := eval.NewContext(event.GetPointer())
+	if bucket := t.ruleSet.GetBucket(eventType); bucket != nil {
+		for _, rule := range bucket.GetRules() {
+			if rule.GetEvaluator().Eval(ctx) {
+				continue
+			}
+			result.Mismatches = append(result.Mismatches, Mismatch{
+				RuleID:  rule.ID,
+				Reasons: mismatchReasons(ctx, rule, event),
+			})
+		}
+	}
+
+	t.ruleSet.Evaluate(event)
+	result.MatchedRules = t.listener.matches
+
+	for _, field := range t.listener.discarders {
+		value, _ := event.GetFieldValue(field)
+		result.Discarders = append(result.Discarders, Discarder{Field: field, Value: value})
+	}
+
+	if approvers := t.approvers[eventType]; len(approvers) > 0 {
+		result.Approved = false
+		for _, field := range sortedFields(approvers) {
+			value, _ := event.GetFieldValue(field)
+			for _, approver := range approvers[field] {
+				matches := approverMatches(approver, value)
+				result.Approved = result.Approved || matches
+				result.Approvers = append(result.Approvers, Approver{Field: field, Value: approver.Value, Matches: matches})
+			}
+		}
+	}
+
+	return result
+}
+
+// RuleEventType returns the event type of a rule, or an error if the rule is unknown
+func (t *Tester) RuleEventType(ruleID eval.RuleID) (eval.EventType, error) {
+	rule, found := t.ruleSet.GetRules()[ruleID]
+	if !found {
+		return "", fmt.Errorf("unknown rule `%s`", ruleID)
+	}
+	return rules.GetRuleEventType(rule.Rule)
+}
+
+func sortedFields(approvers rules.Approvers) []eval.Field {
+	fields := make([]eval.Field, 0, len(approvers))
+	for field := range approvers {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// approverMatches returns whether a value satisfies an approver, following the
+// semantic of the operators of the rules.
+func approverMatches(approver rules.FilterValue, value interface{}) bool {
+	switch approver.Type {
+	case eval.ScalarValueType:
+		return approver.Value == value
+	case eval.GlobValueType, eval.PatternValueType:
+		pattern, ok1 := approver.Value.(string)
+		str, ok2 := value.(string)
+		if !ok1 || !ok2 {
+			return false
+		}
+		glob, err := eval.NewGlob(pattern, false)
+		if err != nil {
+			return false
+		}
+		return glob.Matches(str)
+	case eval.BitmaskValueType:
+		mask, ok1 := approver.Value.(int)
+		bits, ok2 := value.(int)
+		return ok1 && ok2 && bits&mask != 0
+	}
+	return false
+}
+
+// mismatchReasons returns, for each field of the rule, why its value prevents the rule
+// from matching the event.
+func mismatchReasons(ctx *eval.Context, rule *rules.Rule, event *model.Event) []string {
+	fields := rule.GetEvaluator().GetFields()
+	sort.Strings(fields)
+
+	var reasons []string
+	for _, field := range fields {
+		isTrue, err := rule.PartialEval(ctx, field)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("`%s` can't be evaluated: %s", field, err))
+			continue
+		}
+		if isTrue {
+			continue
+		}
+
+		value, _ := event.GetFieldValue(field)
+		reason := fmt.Sprintf("`%s` is %s", field, formatValue(value))
+		if expected := formatFieldValues(rule.GetFieldValues(field)); expected != "" {
+			reason += ", the rule compares it to " + expected
+		}
+		reasons = append(reasons, reason)
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "each field satisfies the rule on its own but not their combination, or the variables of the rule")
+	}
+	return reasons
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = fmt.Sprintf("%q", s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func formatFieldValues(values []eval.FieldValue) string {
+	formatted := make([]string, 0, len(values))
+	for _, v := range values {
+		switch v.Type {
+		case eval.GlobValueType, eval.PatternValueType:
+			formatted = append(formatted, fmt.Sprintf("~%q", v.Value))
+		case eval.RegexpValueType:
+			formatted = append(formatted, fmt.Sprintf("r%q", v.Value))
+		case eval.VariableValueType:
+			formatted = append(formatted, "a variable")
+		default:
+			formatted = append(formatted, formatValue(v.Value))
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
@@ -0,0 +1,181 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package policytest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/rules"
+)
+
+const testPolicy = `---
+macros:
+  - id: shells
+    expression: '["bash", "sh"]'
+rules:
+  - id: passwd_write
+    expression: open.file.path == "/etc/passwd" && open.flags & O_WRONLY > 0
+  - id: shadow_open
+    expression: open.file.path =~ "*/shadow"
+  - id: shell_curl
+    expression: exec.file.name == "curl" && process.ancestors.file.name in shells
+`
+
+const testCases = `[
+	{
+		"name": "passwd write",
+		"event": {"evt": {"name": "open"}, "file": {"path": "/etc/passwd", "flags": ["O_WRONLY"]}},
+		"expected_rules": ["passwd_write"]
+	},
+	{
+		"name": "passwd read",
+		"event": {"evt": {"name": "open"}, "file": {"path": "/etc/passwd", "flags": ["O_RDONLY"]}},
+		"expected_rules": ["passwd_write", "shell_curl"]
+	}
+]
+{"evt": {"name": "open"}, "file": {"path": "/tmp/shadow"}}
+{"evt": {"name": "exec"}, "process": {"executable": {"path": "/usr/bin/curl"}, "parent": {"executable": {"path": "/usr/sbin/nginx"}}}}
+`
+
+var testCapabilities = map[eval.EventType]rules.FieldCapabilities{
+	"open": {
+		{Field: "open.file.path", Types: eval.ScalarValueType | eval.GlobValueType},
+		{Field: "open.file.name", Types: eval.ScalarValueType},
+	},
+}
+
+func newTestTester(t *testing.T) *Tester {
+	filename := filepath.Join(t.TempDir(), "test.policy")
+	require.NoError(t, os.WriteFile(filename, []byte(testPolicy), 0644))
+
+	tester, err := NewTester(rules.NewPolicyLoader(NewPolicyFileProvider(filename)), Opts{
+		FieldCapabilities:   testCapabilities,
+		SupportedDiscarders: map[eval.Field]bool{"open.file.path": true},
+	})
+	require.NoError(t, err)
+	return tester
+}
+
+func TestTesterRun(t *testing.T) {
+	tester := newTestTester(t)
+
+	testCases, err := ReadTestCases(strings.NewReader(testCases))
+	require.NoError(t, err)
+	require.Len(t, testCases, 4)
+	assert.Equal(t, "#2", testCases[2].Name)
+	assert.Nil(t, testCases[2].ExpectedRules)
+
+	reports := tester.RunAll(testCases)
+
+	// passwd write
+	assert.False(t, reports[0].Failed())
+	result := reports[0].Result
+	assert.Equal(t, []eval.RuleID{"passwd_write"}, result.MatchedRules)
+	assert.True(t, result.Approved)
+	assert.Contains(t, result.Approvers, Approver{Field: "open.file.path", Value: "/etc/passwd", Matches: true})
+	assert.Contains(t, result.Approvers, Approver{Field: "open.file.path", Value: "*/shadow", Matches: false})
+	assert.Equal(t, []Mismatch{{RuleID: "shadow_open", Reasons: []string{"`open.file.path` is \"/etc/passwd\", the rule compares it to ~\"*/shadow\""}}}, result.Mismatches)
+
+	// passwd read
+	assert.True(t, reports[1].Failed())
+	assert.Empty(t, reports[1].Result.MatchedRules)
+	assert.Equal(t, []string{
+		"rule `passwd_write` didn't match: `open.flags` is 0, the rule compares it to 1, 0",
+		"rule `shell_curl` didn't match: it applies to `exec` events, not to `open` events",
+	}, reports[1].Failures)
+
+	// any shadow file
+	assert.False(t, reports[2].Failed())
+	result = reports[2].Result
+	assert.Equal(t, []eval.RuleID{"shadow_open"}, result.MatchedRules)
+	assert.True(t, result.Approved)
+	assert.Empty(t, result.Discarders)
+
+	// curl run by nginx
+	result = reports[3].Result
+	assert.Empty(t, result.MatchedRules)
+	assert.Empty(t, result.Approvers)
+	require.Len(t, result.Mismatches, 1)
+	assert.Equal(t, []string{"`process.ancestors.file.name` is [\"nginx\"], the rule compares it to \"bash\", \"sh\""}, result.Mismatches[0].Reasons)
+
+	var b bytes.Buffer
+	require.NoError(t, WriteText(&b, reports))
+	assert.Contains(t, b.String(), "=== FAIL: passwd read\n")
+	assert.Contains(t, b.String(), "  FAILURE: rule `shell_curl` didn't match: it applies to `exec` events, not to `open` events\n")
+	assert.Contains(t, b.String(), "\n4 test case(s), 1 failed\n")
+}
+
+func TestTesterApproversAndDiscarders(t *testing.T) {
+	tester := newTestTester(t)
+
+	event, err := UnmarshalEvent([]byte(`{"evt": {"name": "open"}, "file": {"path": "/etc/hosts"}}`))
+	require.NoError(t, err)
+
+	result := tester.Evaluate(event)
+	assert.Empty(t, result.MatchedRules)
+	assert.False(t, result.Approved)
+	assert.Len(t, result.Approvers, 2)
+	assert.Equal(t, []Discarder{{Field: "open.file.path", Value: "/etc/hosts"}}, result.Discarders)
+	assert.Len(t, result.Mismatches, 2)
+}
+
+func TestTesterInvalidPolicy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.policy")
+	require.NoError(t, os.WriteFile(filename, []byte("rules:\n  - id: invalid\n    expression: open.file.unknown == 1\n"), 0644))
+
+	_, err := NewTester(rules.NewPolicyLoader(NewPolicyFileProvider(filename)), Opts{})
+	assert.Error(t, err)
+
+	_, err = NewTester(rules.NewPolicyLoader(NewPolicyFileProvider(filepath.Join(t.TempDir(), "missing.policy"))), Opts{})
+	assert.Error(t, err)
+}
+
+func TestTesterInvalidEvent(t *testing.T) {
+	tester := newTestTester(t)
+
+	report := tester.Run(TestCase{Name: "invalid", Event: []byte(`{"evt": {"name": "unknown"}}`)})
+	assert.True(t, report.Failed())
+	assert.Equal(t, "unknown event type `unknown`", report.Error)
+	assert.Nil(t, report.Result)
+}
+
+const testSequencePolicy = `---
+rules:
+  - id: curl_then_shadow
+    sequence:
+      within: 30s
+      steps:
+        - exec.file.name == "curl"
+        - open.file.path == "/etc/shadow"
+`
+
+func TestTesterSequence(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.policy")
+	require.NoError(t, os.WriteFile(filename, []byte(testSequencePolicy), 0644))
+
+	tester, err := NewTester(rules.NewPolicyLoader(NewPolicyFileProvider(filename)), Opts{})
+	require.NoError(t, err)
+
+	testCases, err := ReadTestCases(strings.NewReader(`
+{"name": "curl", "event": {"evt": {"name": "exec"}, "process": {"pid": 2, "executable": {"path": "/usr/bin/curl"}}, "date": "2022-06-01T10:00:00Z"}}
+{"name": "late read", "event": {"evt": {"name": "open"}, "file": {"path": "/etc/shadow"}, "process": {"pid": 3, "parent": {"pid": 2}}, "date": "2022-06-01T10:01:00Z"}, "expected_rules": []}
+{"name": "curl again", "event": {"evt": {"name": "exec"}, "process": {"pid": 2, "executable": {"path": "/usr/bin/curl"}}, "date": "2022-06-01T10:02:00Z"}}
+{"name": "read", "event": {"evt": {"name": "open"}, "file": {"path": "/etc/shadow"}, "process": {"pid": 3, "parent": {"pid": 2}}, "date": "2022-06-01T10:02:10Z"}, "expected_rules": ["curl_then_shadow"]}
+`))
+	require.NoError(t, err)
+
+	for _, report := range tester.RunAll(testCases) {
+		assert.False(t, report.Failed(), "%s: %v", report.Name, report.Failures)
+	}
+}
@@ -0,0 +1,138 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+)
+
+// DefaultKillSignal is the signal sent by a kill action without signal
+const DefaultKillSignal = "SIGKILL"
+
+// KillDefinition describes the 'kill' section of a rule action, sending a signal to the
+// process of the event
+type KillDefinition struct {
+	Signal string `yaml:"signal"`
+}
+
+// Check returns an error if the kill action is invalid
+func (k *KillDefinition) Check() error {
+	if signal := k.GetSignal(); !strings.HasPrefix(signal, "SIG") {
+		return fmt.Errorf("invalid signal `%s`", signal)
+	}
+	return nil
+}
+
+// GetSignal returns the name of the signal to send, SIGKILL by default
+func (k *KillDefinition) GetSignal() string {
+	if k.Signal == "" {
+		return DefaultKillSignal
+	}
+	return k.Signal
+}
+
+// Severity describes the severity of the events of a rule
+type Severity string
+
+// Severities of the events of a rule
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// EnrichDefinition describes the 'enrich' section of a rule action, adding tags and a
+// severity to the events sent for the rule
+type EnrichDefinition struct {
+	Tags     map[string]string `yaml:"tags"`
+	Severity Severity          `yaml:"severity"`
+}
+
+// Check returns an error if the enrich action is invalid
+func (e *EnrichDefinition) Check() error {
+	if len(e.Tags) == 0 && e.Severity == "" {
+		return errors.New("either 'tags' or 'severity' must be specified")
+	}
+
+	switch e.Severity {
+	case "", SeverityInfo, SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical:
+	default:
+		return fmt.Errorf("unknown severity `%s`", e.Severity)
+	}
+
+	return nil
+}
+
+// RateLimitDefinition describes the 'rate_limit' section of a rule action. Once an event
+// is sent for the rule, the next events with the same values for the key fields are
+// suppressed for the given duration.
+type RateLimitDefinition struct {
+	Fields   []eval.Field  `yaml:"fields"`
+	Duration time.Duration `yaml:"duration"`
+}
+
+// Check returns an error if the rate limit action is invalid
+func (r *RateLimitDefinition) Check() error {
+	if len(r.Fields) == 0 {
+		return errors.New("no key field defined")
+	}
+
+	if r.Duration <= 0 {
+		return errors.New("'duration' must be a positive duration")
+	}
+
+	return nil
+}
+
+// checkActionWithModel returns an error if an action refers to unknown fields or constants
+func (rs *RuleSet) checkActionWithModel(action ActionDefinition) error {
+	switch {
+	case action.Kill != nil:
+		if _, found := rs.opts.Constants[action.Kill.GetSignal()]; !found {
+			return fmt.Errorf("unknown signal `%s`", action.Kill.GetSignal())
+		}
+	case action.RateLimit != nil:
+		for _, field := range action.RateLimit.Fields {
+			if _, err := rs.eventCtor().GetFieldType(field); err != nil {
+				return fmt.Errorf("failed to get field '%s': %w", field, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetEnrichment returns the tags and the severity set by the enrich actions of the rule.
+// When several actions define a severity, the last one is used.
+func (rd *RuleDefinition) GetEnrichment() ([]string, Severity) {
+	var tags []string
+	var severity Severity
+
+	for _, action := range rd.Actions {
+		if action.Enrich == nil {
+			continue
+		}
+
+		for k, v := range action.Enrich.Tags {
+			tags = append(tags, k+":"+v)
+		}
+
+		if action.Enrich.Severity != "" {
+			severity = action.Enrich.Severity
+		}
+	}
+	sort.Strings(tags)
+
+	return tags, severity
+}
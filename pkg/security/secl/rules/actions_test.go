@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRuleActions(t *testing.T) {
+	rs, errs := loadModelPolicy(t, `---
+rules:
+  - id: kill_curl
+    expression: exec.file.name == "curl"
+    actions:
+      - kill:
+          signal: SIGTERM
+      - enrich:
+          tags:
+            team: secops
+          severity: high
+      - enrich:
+          tags:
+            mitre: T1005
+      - rate_limit:
+          fields:
+            - process.file.path
+            - container.id
+          duration: 5m
+`)
+	if errs.ErrorOrNil() != nil {
+		t.Fatal(errs)
+	}
+
+	rule := rs.GetRules()["kill_curl"]
+	if rule == nil {
+		t.Fatal("failed to find kill_curl in ruleset")
+	}
+
+	if signal := rule.Definition.Actions[0].Kill.GetSignal(); signal != "SIGTERM" {
+		t.Errorf("unexpected signal: %s", signal)
+	}
+
+	tags, severity := rule.Definition.GetEnrichment()
+	if !reflect.DeepEqual(tags, []string{"mitre:T1005", "team:secops"}) || severity != SeverityHigh {
+		t.Errorf("unexpected enrichment: %v, %s", tags, severity)
+	}
+
+	if rateLimit := rule.Definition.Actions[3].RateLimit; rateLimit.Duration != 5*time.Minute || len(rateLimit.Fields) != 2 {
+		t.Errorf("unexpected rate limit: %+v", rateLimit)
+	}
+
+	if (&KillDefinition{}).GetSignal() != "SIGKILL" {
+		t.Error("expected SIGKILL to be the default signal")
+	}
+}
+
+func TestRuleActionsInvalid(t *testing.T) {
+	for name, action := range map[string]string{
+		"empty":            "{}",
+		"multiple":         "kill: {}\n        enrich:\n          severity: low",
+		"unknown signal":   "kill:\n          signal: SIGFOO",
+		"not a signal":     "kill:\n          signal: O_RDONLY",
+		"empty enrich":     "enrich: {}",
+		"unknown severity": "enrich:\n          severity: urgent",
+		"no key field":     "rate_limit:\n          duration: 1m",
+		"unknown field":    "rate_limit:\n          fields: [process.unknown]\n          duration: 1m",
+		"no duration":      "rate_limit:\n          fields: [process.file.path]",
+	} {
+		t.Run(name, func(t *testing.T) {
+			policy := "rules:\n  - id: invalid\n    expression: exec.file.name == \"curl\"\n    actions:\n      - " + action + "\n"
+			if _, errs := loadModelPolicy(t, policy); errs.ErrorOrNil() == nil {
+				t.Error("expected the policy to fail to load")
+			}
+		})
+	}
+}
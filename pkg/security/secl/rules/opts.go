@@ -6,6 +6,8 @@
 package rules
 
 import (
+	"time"
+
 	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
 )
 
@@ -25,6 +27,17 @@ type Opts struct {
 	EventTypeEnabled    map[eval.EventType]bool
 	StateScopes         map[Scope]VariableProviderFactory
 	Logger              Logger
+	Sequence            SequenceOpts
+}
+
+// SequenceOpts defines how the events matching the steps of sequence rules are tracked
+type SequenceOpts struct {
+	// Timestamp returns the time of an event, the current time is used when nil
+	Timestamp func(event eval.Event) time.Time
+	// Snapshot returns what is kept of an event added to the chain of a sequence rule,
+	// as the events are usually reused once evaluated. Only the time of the events is
+	// kept when nil.
+	Snapshot func(event eval.Event) interface{}
 }
 
 // WithConstants set constants
@@ -86,3 +99,9 @@ func (o *Opts) WithStateScopes(stateScopes map[Scope]VariableProviderFactory) *O
 	o.StateScopes = stateScopes
 	return o
 }
+
+// WithSequenceOpts set sequence options
+func (o *Opts) WithSequenceOpts(sequenceOpts SequenceOpts) *Opts {
+	o.Sequence = sequenceOpts
+	return o
+}
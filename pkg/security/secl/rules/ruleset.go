@@ -67,14 +67,15 @@ var ruleIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_]*$`)
 
 // RuleDefinition holds the definition of a rule
 type RuleDefinition struct {
-	ID          RuleID             `yaml:"id"`
-	Version     string             `yaml:"version"`
-	Expression  string             `yaml:"expression"`
-	Description string             `yaml:"description"`
-	Tags        map[string]string  `yaml:"tags"`
-	Disabled    bool               `yaml:"disabled"`
-	Combine     CombinePolicy      `yaml:"combine"`
-	Actions     []ActionDefinition `yaml:"actions"`
+	ID          RuleID              `yaml:"id"`
+	Version     string              `yaml:"version"`
+	Expression  string              `yaml:"expression"`
+	Description string              `yaml:"description"`
+	Tags        map[string]string   `yaml:"tags"`
+	Disabled    bool                `yaml:"disabled"`
+	Combine     CombinePolicy       `yaml:"combine"`
+	Actions     []ActionDefinition  `yaml:"actions"`
+	Sequence    *SequenceDefinition `yaml:"sequence"`
 	Policy      *Policy
 }
 
@@ -98,6 +99,7 @@ func (rd *RuleDefinition) MergeWith(rd2 *RuleDefinition) error {
 	switch rd2.Combine {
 	case OverridePolicy:
 		rd.Expression = rd2.Expression
+		rd.Sequence = rd2.Sequence
 	default:
 		if !rd2.Disabled {
 			return &ErrRuleLoad{Definition: rd2, Err: ErrInternalIDConflict}
@@ -109,13 +111,32 @@ func (rd *RuleDefinition) MergeWith(rd2 *RuleDefinition) error {
 
 // ActionDefinition describes a rule action section
 type ActionDefinition struct {
-	Set *SetDefinition `yaml:"set"`
+	Set       *SetDefinition       `yaml:"set"`
+	Kill      *KillDefinition      `yaml:"kill"`
+	Enrich    *EnrichDefinition    `yaml:"enrich"`
+	RateLimit *RateLimitDefinition `yaml:"rate_limit"`
 }
 
 // Check returns an error if the action in invalid
 func (a *ActionDefinition) Check() error {
-	if a.Set == nil {
-		return errors.New("missing 'set' section in action")
+	sections := 0
+	for _, defined := range []bool{a.Set != nil, a.Kill != nil, a.Enrich != nil, a.RateLimit != nil} {
+		if defined {
+			sections++
+		}
+	}
+
+	switch {
+	case sections == 0:
+		return errors.New("missing 'set', 'kill', 'enrich' or 'rate_limit' section in action")
+	case sections > 1:
+		return errors.New("an action can only have one of the 'set', 'kill', 'enrich' or 'rate_limit' sections")
+	case a.Kill != nil:
+		return a.Kill.Check()
+	case a.Enrich != nil:
+		return a.Enrich.Check()
+	case a.RateLimit != nil:
+		return a.RateLimit.Check()
 	}
 
 	if a.Set.Name == "" {
@@ -145,6 +166,10 @@ type SetDefinition struct {
 type Rule struct {
 	*eval.Rule
 	Definition *RuleDefinition
+
+	// sequence is set for the steps of a sequence rule
+	sequence *sequence
+	step     int
 }
 
 // RuleSetListener describes the methods implemented by an object used to be
@@ -303,6 +328,10 @@ func (rs *RuleSet) AddRule(ruleDef *RuleDefinition) (*eval.Rule, error) {
 		Definition: ruleDef,
 	}
 
+	if ruleDef.Sequence != nil {
+		return rs.addSequenceRule(rule)
+	}
+
 	if err := rule.Parse(); err != nil {
 		return nil, &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrap(err, "syntax error")}
 	}
@@ -317,22 +346,12 @@ func (rs *RuleSet) AddRule(ruleDef *RuleDefinition) (*eval.Rule, error) {
 	}
 
 	// ignore event types not supported
-	if _, exists := rs.opts.EventTypeEnabled["*"]; !exists {
-		if _, exists := rs.opts.EventTypeEnabled[eventType]; !exists {
-			return nil, &ErrRuleLoad{Definition: ruleDef, Err: ErrEventTypeNotEnabled}
-		}
+	if !rs.isEventTypeEnabled(eventType) {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: ErrEventTypeNotEnabled}
 	}
 
-	for _, event := range rule.GetEvaluator().EventTypes {
-		bucket, exists := rs.eventRuleBuckets[event]
-		if !exists {
-			bucket = &RuleBucket{}
-			rs.eventRuleBuckets[event] = bucket
-		}
-
-		if err := bucket.AddRule(rule); err != nil {
-			return nil, err
-		}
+	if err := rs.addToBuckets(rule); err != nil {
+		return nil, err
 	}
 
 	// Merge the fields of the new rule with the existing list of fields of the ruleset
@@ -356,6 +375,30 @@ func (rs *RuleSet) AddRule(ruleDef *RuleDefinition) (*eval.Rule, error) {
 	return rule.Rule, nil
 }
 
+func (rs *RuleSet) isEventTypeEnabled(eventType eval.EventType) bool {
+	if _, exists := rs.opts.EventTypeEnabled["*"]; exists {
+		return true
+	}
+	_, exists := rs.opts.EventTypeEnabled[eventType]
+	return exists
+}
+
+// addToBuckets adds a rule to the buckets of its event types
+func (rs *RuleSet) addToBuckets(rule *Rule) error {
+	for _, event := range rule.GetEvaluator().EventTypes {
+		bucket, exists := rs.eventRuleBuckets[event]
+		if !exists {
+			bucket = &RuleBucket{}
+			rs.eventRuleBuckets[event] = bucket
+		}
+
+		if err := bucket.AddRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // NotifyRuleMatch notifies all the ruleset listeners that an event matched a rule
 func (rs *RuleSet) NotifyRuleMatch(rule *Rule, event eval.Event) {
 	for _, listener := range rs.listeners {
@@ -514,6 +557,17 @@ func (rs *RuleSet) Evaluate(event eval.Event) bool {
 
 	for _, rule := range bucket.rules {
 		if rule.GetEvaluator().Eval(ctx) {
+			if rule.sequence != nil {
+				if rs.evaluateSequenceStep(ctx, rule, event) {
+					result = true
+
+					if err := rs.runRuleActions(ctx, rule.sequence.rule); err != nil {
+						rs.logger.Errorf("Error while executing rule actions: %s", err)
+					}
+				}
+				continue
+			}
+
 			rs.logger.Tracef("Rule `%s` matches with event `%s`\n", rule.ID, event)
 
 			rs.NotifyRuleMatch(rule, event)
@@ -639,6 +693,11 @@ func (rs *RuleSet) LoadPolicies(loader *PolicyLoader) *multierror.Error {
 				continue
 			}
 
+			if err := rs.checkActionWithModel(action); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("invalid action: %w", err))
+				continue
+			}
+
 			if action.Set != nil {
 				varName := action.Set.Name
 				if action.Set.Scope != "" {
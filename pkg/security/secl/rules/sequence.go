@@ -0,0 +1,351 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+	"github.com/pkg/errors"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+)
+
+// Correlation describes how the events of a sequence rule are related
+type Correlation string
+
+const (
+	// ProcessCorrelation correlates the events of a process and of its descendants
+	ProcessCorrelation Correlation = "process"
+	// ContainerCorrelation correlates the events of a container, the events outside
+	// of any container are ignored
+	ContainerCorrelation Correlation = "container"
+	// UserCorrelation correlates the events of the processes of a user
+	UserCorrelation Correlation = "user"
+)
+
+// DefaultSequenceMaxKeys is the default number of correlation keys tracked by a sequence rule
+const DefaultSequenceMaxKeys = 1000
+
+// fields of the events used to correlate the steps of a sequence
+var correlationFields = map[Correlation]eval.Field{
+	ProcessCorrelation:   "process.pid",
+	ContainerCorrelation: "container.id",
+	UserCorrelation:      "process.uid",
+}
+
+// processAncestorsField is used to match the events of the descendants of a process
+const processAncestorsField = "process.ancestors.pid"
+
+// SequenceDefinition describes the 'sequence' section of a rule. A sequence rule matches
+// when events satisfy each of its steps, in order, for the same correlation key, in
+// the given time window.
+type SequenceDefinition struct {
+	Steps       []string      `yaml:"steps"`
+	Within      time.Duration `yaml:"within"`
+	CorrelateBy Correlation   `yaml:"correlate_by"`
+	MaxKeys     int           `yaml:"max_keys"`
+}
+
+// Check returns an error if the sequence is invalid
+func (s *SequenceDefinition) Check() error {
+	if len(s.Steps) < 2 {
+		return errors.New("a sequence requires at least 2 steps")
+	}
+
+	for i, step := range s.Steps {
+		if step == "" {
+			return fmt.Errorf("no expression defined for step %d", i+1)
+		}
+	}
+
+	if s.Within <= 0 {
+		return errors.New("'within' must be a positive duration")
+	}
+
+	if s.MaxKeys < 0 {
+		return errors.New("'max_keys' can't be negative")
+	}
+
+	switch s.CorrelateBy {
+	case "", ProcessCorrelation, ContainerCorrelation, UserCorrelation:
+	default:
+		return fmt.Errorf("unknown correlation `%s`", s.CorrelateBy)
+	}
+
+	return nil
+}
+
+// GetCorrelation returns the correlation of the sequence, process by default
+func (s *SequenceDefinition) GetCorrelation() Correlation {
+	if s.CorrelateBy == "" {
+		return ProcessCorrelation
+	}
+	return s.CorrelateBy
+}
+
+// SequenceEvent is an event which matched a step of a sequence rule
+type SequenceEvent struct {
+	Step      int       `json:"step"`
+	Timestamp time.Time `json:"timestamp"`
+	// Event is the snapshot of the event returned by the Snapshot function of the
+	// sequence options, nil when there is none
+	Event interface{} `json:"event,omitempty"`
+}
+
+// SequenceMatch describes the chain of events which matched a sequence rule
+type SequenceMatch struct {
+	Correlation Correlation     `json:"correlation"`
+	Key         interface{}     `json:"key"`
+	Events      []SequenceEvent `json:"events"`
+}
+
+// SequenceRuleSetListener is implemented by the rule set listeners which need the chain of
+// the events matching a sequence rule. The listeners which don't implement it are notified
+// of the last event of the chain through RuleMatch.
+type SequenceRuleSetListener interface {
+	SequenceMatch(rule *Rule, event eval.Event, match *SequenceMatch)
+}
+
+// sequenceState holds the steps of a sequence matched for a correlation key
+type sequenceState struct {
+	start  time.Time
+	events []SequenceEvent
+}
+
+// sequence tracks the steps matched by the events of a sequence rule
+type sequence struct {
+	rule       *Rule
+	definition *SequenceDefinition
+	key        eval.Evaluator
+	ancestors  eval.Evaluator
+	states     *simplelru.LRU
+}
+
+func newSequence(rule *Rule, model eval.Model) (*sequence, error) {
+	definition := rule.Definition.Sequence
+	correlation := definition.GetCorrelation()
+
+	key, err := model.GetEvaluator(correlationFields[correlation], "")
+	if err != nil {
+		return nil, fmt.Errorf("correlation `%s` not supported: %w", correlation, err)
+	}
+
+	var ancestors eval.Evaluator
+	if correlation == ProcessCorrelation {
+		if ancestors, err = model.GetEvaluator(processAncestorsField, ""); err != nil {
+			return nil, fmt.Errorf("correlation `%s` not supported: %w", correlation, err)
+		}
+	}
+
+	maxKeys := definition.MaxKeys
+	if maxKeys == 0 {
+		maxKeys = DefaultSequenceMaxKeys
+	}
+
+	states, err := simplelru.NewLRU(maxKeys, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sequence{
+		rule:       rule,
+		definition: definition,
+		key:        key,
+		ancestors:  ancestors,
+		states:     states,
+	}, nil
+}
+
+// keys returns the correlation keys of an event, starting with its own key. With the
+// process correlation, the keys of the ancestors of the process follow.
+func (s *sequence) keys(ctx *eval.Context) []interface{} {
+	key := s.key.Eval(ctx)
+	if key == "" {
+		return nil
+	}
+
+	keys := []interface{}{key}
+	if s.ancestors != nil {
+		if pids, ok := s.ancestors.Eval(ctx).([]int); ok {
+			for _, pid := range pids {
+				keys = append(keys, pid)
+			}
+		}
+	}
+	return keys
+}
+
+func (s *sequence) expired(state *sequenceState, now time.Time) bool {
+	return now.Sub(state.start) > s.definition.Within
+}
+
+// advance records that an event matched the given step. It returns the chain of events
+// when the step completes the sequence. The snapshot of the event is only taken when
+// the event is added to a chain.
+func (s *sequence) advance(ctx *eval.Context, step int, now time.Time, snapshot func() interface{}) *SequenceMatch {
+	keys := s.keys(ctx)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	newEvent := func() SequenceEvent {
+		return SequenceEvent{Step: step + 1, Timestamp: now, Event: snapshot()}
+	}
+
+	if step == 0 {
+		// a key holds a single chain, only restarted once expired or if it only matched the first step
+		if value, found := s.states.Peek(keys[0]); found {
+			if state := value.(*sequenceState); !s.expired(state, now) && len(state.events) > 1 {
+				return nil
+			}
+		}
+		s.states.Add(keys[0], &sequenceState{start: now, events: []SequenceEvent{newEvent()}})
+		return nil
+	}
+
+	for _, key := range keys {
+		value, found := s.states.Get(key)
+		if !found {
+			continue
+		}
+
+		state := value.(*sequenceState)
+		if s.expired(state, now) {
+			s.states.Remove(key)
+			continue
+		}
+
+		if len(state.events) != step {
+			continue
+		}
+
+		state.events = append(state.events, newEvent())
+		if len(state.events) < len(s.definition.Steps) {
+			return nil
+		}
+
+		s.states.Remove(key)
+		return &SequenceMatch{
+			Correlation: s.definition.GetCorrelation(),
+			Key:         key,
+			Events:      state.events,
+		}
+	}
+
+	return nil
+}
+
+// addSequenceRule adds a sequence rule. Its steps are added to the buckets of their event
+// type, so that approvers and discarders take them into account, in reverse order so that
+// an event matching consecutive steps advances a sequence by one step only.
+func (rs *RuleSet) addSequenceRule(rule *Rule) (*eval.Rule, error) {
+	ruleDef := rule.Definition
+
+	if ruleDef.Expression != "" {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: errors.New("a sequence rule can't have an expression")}
+	}
+
+	if err := ruleDef.Sequence.Check(); err != nil {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: err}
+	}
+
+	seq, err := newSequence(rule, rs.model)
+	if err != nil {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: err}
+	}
+
+	steps := make([]*Rule, len(ruleDef.Sequence.Steps))
+	for i, expression := range ruleDef.Sequence.Steps {
+		step := &Rule{
+			Rule: &eval.Rule{
+				ID:         fmt.Sprintf("%s_step_%d", ruleDef.ID, i+1),
+				Expression: expression,
+				Tags:       rule.Tags,
+			},
+			Definition: ruleDef,
+			sequence:   seq,
+			step:       i,
+		}
+
+		if err := step.Parse(); err != nil {
+			return nil, &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrapf(err, "syntax error in step %d", i+1)}
+		}
+
+		if err := step.GenEvaluator(rs.model, &rs.opts.Opts); err != nil {
+			return nil, &ErrRuleLoad{Definition: ruleDef, Err: fmt.Errorf("step %d: %w", i+1, err)}
+		}
+
+		eventType, err := GetRuleEventType(step.Rule)
+		if err != nil {
+			return nil, &ErrRuleLoad{Definition: ruleDef, Err: fmt.Errorf("step %d: %w", i+1, err)}
+		}
+
+		if !rs.isEventTypeEnabled(eventType) {
+			return nil, &ErrRuleLoad{Definition: ruleDef, Err: ErrEventTypeNotEnabled}
+		}
+
+		steps[i] = step
+	}
+
+	// the rule itself evaluates as its last step
+	rule.Expression = ruleDef.Sequence.Steps[len(steps)-1]
+	if err := rule.Parse(); err != nil {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: errors.Wrap(err, "syntax error")}
+	}
+	if err := rule.GenEvaluator(rs.model, &rs.opts.Opts); err != nil {
+		return nil, &ErrRuleLoad{Definition: ruleDef, Err: err}
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := rs.addToBuckets(steps[i]); err != nil {
+			return nil, err
+		}
+		rs.AddFields(steps[i].GetEvaluator().GetFields())
+	}
+
+	rs.rules[ruleDef.ID] = rule
+
+	return rule.Rule, nil
+}
+
+// evaluateSequenceStep handles an event matching a step of a sequence rule, the listeners
+// are notified when the step completes the sequence.
+func (rs *RuleSet) evaluateSequenceStep(ctx *eval.Context, step *Rule, event eval.Event) bool {
+	now := time.Now()
+	if rs.opts.Sequence.Timestamp != nil {
+		now = rs.opts.Sequence.Timestamp(event)
+	}
+
+	snapshot := func() interface{} {
+		if rs.opts.Sequence.Snapshot != nil {
+			return rs.opts.Sequence.Snapshot(event)
+		}
+		return nil
+	}
+
+	match := step.sequence.advance(ctx, step.step, now, snapshot)
+	if match == nil {
+		return false
+	}
+
+	rs.logger.Tracef("Sequence rule `%s` matches with event `%s`\n", step.sequence.rule.ID, event)
+	rs.NotifySequenceMatch(step.sequence.rule, event, match)
+
+	return true
+}
+
+// NotifySequenceMatch notifies all the ruleset listeners that an event completed a sequence rule
+func (rs *RuleSet) NotifySequenceMatch(rule *Rule, event eval.Event, match *SequenceMatch) {
+	for _, listener := range rs.listeners {
+		if sequenceListener, ok := listener.(SequenceRuleSetListener); ok {
+			sequenceListener.SequenceMatch(rule, event, match)
+		} else {
+			listener.RuleMatch(rule, event)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+
+	"github.com/DataDog/datadog-agent/pkg/security/secl/compiler/eval"
+	"github.com/DataDog/datadog-agent/pkg/security/secl/model"
+)
+
+type testSequenceHandler struct {
+	matches []*SequenceMatch
+}
+
+func (h *testSequenceHandler) RuleMatch(rule *Rule, event eval.Event) {}
+
+func (h *testSequenceHandler) EventDiscarderFound(rs *RuleSet, event eval.Event, field string, eventType eval.EventType) {
+}
+
+func (h *testSequenceHandler) SequenceMatch(rule *Rule, event eval.Event, match *SequenceMatch) {
+	h.matches = append(h.matches, match)
+}
+
+type testRuleMatchHandler struct {
+	matches []eval.RuleID
+}
+
+func (h *testRuleMatchHandler) RuleMatch(rule *Rule, event eval.Event) {
+	h.matches = append(h.matches, rule.ID)
+}
+
+func (h *testRuleMatchHandler) EventDiscarderFound(rs *RuleSet, event eval.Event, field string, eventType eval.EventType) {
+}
+
+func loadModelPolicy(t *testing.T, policy string) (*RuleSet, *multierror.Error) {
+	var opts Opts
+	opts.
+		WithConstants(model.SECLConstants).
+		WithVariables(make(map[string]eval.VariableValue)).
+		WithEventTypeEnabled(map[eval.EventType]bool{"*": true}).
+		WithSequenceOpts(SequenceOpts{
+			Timestamp: func(event eval.Event) time.Time {
+				return event.(*model.Event).Timestamp
+			},
+			Snapshot: func(event eval.Event) interface{} {
+				return event.(*model.Event).ProcessContext.Pid
+			},
+		})
+
+	m := &model.Model{}
+	rs := NewRuleSet(m, m.NewEvent, &opts)
+
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "test.policy"), []byte(policy), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewPoliciesDirProvider(tmpDir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rs, rs.LoadPolicies(NewPolicyLoader(provider))
+}
+
+func newSequenceTestEvent(eventType model.EventType, timestamp time.Time, pid uint32, ancestors ...uint32) *model.Event {
+	event := &model.Event{
+		Type:           uint32(eventType),
+		Timestamp:      timestamp,
+		ProcessContext: &model.ProcessContext{},
+	}
+	event.ProcessContext.Pid = pid
+
+	ctx := event.ProcessContext
+	for _, ancestor := range ancestors {
+		ctx.Ancestor = &model.ProcessCacheEntry{}
+		ctx.Ancestor.Pid = ancestor
+		ctx = &ctx.Ancestor.ProcessContext
+	}
+
+	return event
+}
+
+func newExecTestEvent(timestamp time.Time, name string, pid uint32, ancestors ...uint32) *model.Event {
+	event := newSequenceTestEvent(model.ExecEventType, timestamp, pid, ancestors...)
+	event.Exec.Process = &event.ProcessContext.Process
+	event.Exec.Process.FileEvent.SetBasenameStr(name)
+	return event
+}
+
+func newOpenTestEvent(timestamp time.Time, path string, pid uint32, ancestors ...uint32) *model.Event {
+	event := newSequenceTestEvent(model.FileOpenEventType, timestamp, pid, ancestors...)
+	event.Open.File.SetPathnameStr(path)
+	return event
+}
+
+const testSequencePolicy = `---
+rules:
+  - id: curl_then_shadow
+    sequence:
+      within: 30s
+      max_keys: 2
+      steps:
+        - exec.file.name == "curl"
+        - open.file.path == "/etc/shadow"
+`
+
+func TestSequenceRule(t *testing.T) {
+	rs, errs := loadModelPolicy(t, testSequencePolicy)
+	if errs.ErrorOrNil() != nil {
+		t.Fatal(errs)
+	}
+
+	handler := &testSequenceHandler{}
+	rs.AddListener(handler)
+
+	now := time.Now()
+
+	// the second step is matched by a child of the process matching the first step
+	rs.Evaluate(newOpenTestEvent(now, "/etc/shadow", 100))
+	rs.Evaluate(newExecTestEvent(now, "curl", 100, 1))
+	if !rs.Evaluate(newOpenTestEvent(now.Add(10*time.Second), "/etc/shadow", 101, 100, 1)) {
+		t.Fatal("expected the sequence to match")
+	}
+
+	if len(handler.matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(handler.matches))
+	}
+	match := handler.matches[0]
+	if match.Correlation != ProcessCorrelation || match.Key != 100 || len(match.Events) != 2 {
+		t.Fatalf("unexpected match: %+v", match)
+	}
+	if match.Events[0].Event != uint32(100) || match.Events[1].Event != uint32(101) || match.Events[1].Step != 2 {
+		t.Errorf("unexpected chain: %+v", match.Events)
+	}
+
+	// the chain is reset once matched
+	if rs.Evaluate(newOpenTestEvent(now.Add(11*time.Second), "/etc/shadow", 100, 1)) {
+		t.Error("unexpected match of a completed sequence")
+	}
+
+	// expired sequence
+	rs.Evaluate(newExecTestEvent(now, "curl", 200, 1))
+	if rs.Evaluate(newOpenTestEvent(now.Add(31*time.Second), "/etc/shadow", 200, 1)) {
+		t.Error("unexpected match of an expired sequence")
+	}
+
+	// unrelated process
+	rs.Evaluate(newExecTestEvent(now, "curl", 300, 1))
+	if rs.Evaluate(newOpenTestEvent(now, "/etc/shadow", 400, 1)) {
+		t.Error("unexpected match of an unrelated process")
+	}
+
+	// only 2 keys are tracked, the oldest one is evicted
+	rs.Evaluate(newExecTestEvent(now, "curl", 500, 1))
+	rs.Evaluate(newExecTestEvent(now, "curl", 501, 1))
+	rs.Evaluate(newExecTestEvent(now, "curl", 502, 1))
+	if rs.Evaluate(newOpenTestEvent(now, "/etc/shadow", 500, 1)) {
+		t.Error("unexpected match of an evicted key")
+	}
+	if !rs.Evaluate(newOpenTestEvent(now, "/etc/shadow", 502, 1)) {
+		t.Error("expected the sequence to match")
+	}
+
+	if len(handler.matches) != 2 {
+		t.Errorf("expected 2 matches, got %d", len(handler.matches))
+	}
+}
+
+func TestSequenceRuleCorrelation(t *testing.T) {
+	rs, errs := loadModelPolicy(t, `---
+rules:
+  - id: container_sequence
+    sequence:
+      correlate_by: container
+      within: 1m
+      steps:
+        - exec.file.name == "curl"
+        - open.file.path == "/etc/shadow"
+`)
+	if errs.ErrorOrNil() != nil {
+		t.Fatal(errs)
+	}
+
+	handler := &testRuleMatchHandler{}
+	rs.AddListener(handler)
+
+	now := time.Now()
+
+	// events outside of containers are ignored
+	rs.Evaluate(newExecTestEvent(now, "curl", 100))
+	if rs.Evaluate(newOpenTestEvent(now, "/etc/shadow", 100)) {
+		t.Error("unexpected match outside of a container")
+	}
+
+	exec := newExecTestEvent(now, "curl", 100)
+	exec.ContainerContext.ID = "abc"
+	rs.Evaluate(exec)
+
+	open := newOpenTestEvent(now, "/etc/shadow", 200)
+	open.ContainerContext.ID = "abc"
+	if !rs.Evaluate(open) {
+		t.Fatal("expected the sequence to match")
+	}
+
+	// listeners without sequence support are notified of the last event
+	if len(handler.matches) != 1 || handler.matches[0] != "container_sequence" {
+		t.Errorf("unexpected matches: %v", handler.matches)
+	}
+}
+
+func TestSequenceRuleInvalid(t *testing.T) {
+	for name, sequence := range map[string]string{
+		"single step":         "within: 1m\n      steps:\n        - exec.file.name == \"curl\"",
+		"no window":           "steps:\n        - exec.file.name == \"curl\"\n        - open.file.path == \"/etc/shadow\"",
+		"unknown correlation": "correlate_by: host\n      within: 1m\n      steps:\n        - exec.file.name == \"curl\"\n        - open.file.path == \"/etc/shadow\"",
+		"invalid step":        "within: 1m\n      steps:\n        - exec.file.name == \"curl\"\n        - open.file.unknown == 1",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, errs := loadModelPolicy(t, "rules:\n  - id: invalid\n    sequence:\n      "+sequence+"\n"); errs.ErrorOrNil() == nil {
+				t.Error("expected the policy to fail to load")
+			}
+		})
+	}
+
+	_, errs := loadModelPolicy(t, `---
+rules:
+  - id: invalid
+    expression: exec.file.name == "curl"
+    sequence:
+      within: 1m
+      steps:
+        - exec.file.name == "curl"
+        - open.file.path == "/etc/shadow"
+`)
+	if errs.ErrorOrNil() == nil {
+		t.Error("expected a sequence rule with an expression to fail to load")
+	}
+}
@@ -3,9 +3,6 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package metrics
 
 import (
@@ -330,7 +327,7 @@ func benchmarkCreateSingleMarshaler(b *testing.B, createEvents func(numberOfItem
 		b.ResetTimer()
 
 		for n := 0; n < b.N; n++ {
-			payloadBuilder.Build(events.CreateSingleMarshaler())
+			payloadBuilder.Build(events.CreateSingleMarshaler(), zlibCompressor)
 		}
 	})
 }
@@ -344,7 +341,7 @@ func BenchmarkCreateMarshalersBySourceType(b *testing.B) {
 
 		for n := 0; n < b.N; n++ {
 			for _, m := range events.CreateMarshalersBySourceType() {
-				payloadBuilder.Build(m)
+				payloadBuilder.Build(m, zlibCompressor)
 			}
 		}
 	})
@@ -369,9 +366,9 @@ func BenchmarkCreateMarshalersSeveralSourceTypes(b *testing.B) {
 		for n := 0; n < b.N; n++ {
 			// As CreateMarshalersBySourceType is called only after CreateSingleMarshaler,
 			// we also call CreateSingleMarshaler in this benchmark.
-			payloadBuilder.Build(events.CreateSingleMarshaler())
+			payloadBuilder.Build(events.CreateSingleMarshaler(), zlibCompressor)
 			for _, m := range events.CreateMarshalersBySourceType() {
-				payloadBuilder.Build(m)
+				payloadBuilder.Build(m, zlibCompressor)
 			}
 		}
 	})
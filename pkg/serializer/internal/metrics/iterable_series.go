@@ -18,6 +18,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 // IterableSeries is a serializer for metrics.IterableSeries
@@ -76,19 +78,28 @@ func describeItem(serie *metrics.Serie) string {
 	return fmt.Sprintf("name %q, %d points", serie.Name, len(serie.Points))
 }
 
+// CurrentItemSource returns the check and the metric prefix of the current item
+func (series IterableSeries) CurrentItemSource() sizereport.Source {
+	current := series.Current()
+	if current == nil {
+		return sizereport.Source{}
+	}
+	return sizereport.MetricSource(current.CheckID, current.Name)
+}
+
 // MarshalSplitCompress uses the stream compressor to marshal and compress series payloads.
 // If a compressed payload is larger than the max, a new payload will be generated. This method returns a slice of
 // compressed protobuf marshaled MetricPayload objects.
-func (series IterableSeries) MarshalSplitCompress(bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
-	return marshalSplitCompress(series, bufferContext)
+func (series IterableSeries) MarshalSplitCompress(bufferContext *marshaler.BufferContext, compressor compression.Compressor) ([]*[]byte, error) {
+	return marshalSplitCompress(series, bufferContext, compressor)
 }
 
 // MarshalSplitCompress uses the stream compressor to marshal and compress series payloads.
 // If a compressed payload is larger than the max, a new payload will be generated. This method returns a slice of
 // compressed protobuf marshaled MetricPayload objects.
-func marshalSplitCompress(iterator metrics.SerieSource, bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
+func marshalSplitCompress(iterator metrics.SerieSource, bufferContext *marshaler.BufferContext, compressor compression.Compressor) ([]*[]byte, error) {
 	var err error
-	var compressor *stream.Compressor
+	var payloadCompressor *stream.Compressor
 	buf := bufferContext.PrecompressionBuf
 	ps := molecule.NewProtoStream(buf)
 	payloads := []*[]byte{}
@@ -128,19 +139,20 @@ func marshalSplitCompress(iterator metrics.SerieSource, bufferContext *marshaler
 		bufferContext.CompressorInput.Reset()
 		bufferContext.CompressorOutput.Reset()
 
-		compressor, err = stream.NewCompressor(
+		payloadCompressor, err = stream.NewCompressor(
 			bufferContext.CompressorInput, bufferContext.CompressorOutput,
 			maxPayloadSize, maxUncompressedSize,
-			[]byte{}, []byte{}, []byte{})
+			[]byte{}, []byte{}, []byte{}, compressor)
 		if err != nil {
 			return err
 		}
+		payloadCompressor.ReportSizes(bufferContext.SizeReport)
 
 		return nil
 	}
 
 	addToPayload := func() error {
-		err = compressor.AddItem(buf.Bytes())
+		err = payloadCompressor.AddItemFrom(buf.Bytes(), sizereport.MetricSource(serie.CheckID, serie.Name))
 		if err != nil {
 			return err
 		}
@@ -152,7 +164,7 @@ func marshalSplitCompress(iterator metrics.SerieSource, bufferContext *marshaler
 	finishPayload := func() error {
 		var payload []byte
 		// Since the compression buffer is full - flush it and rotate
-		payload, err = compressor.Close()
+		payload, err = payloadCompressor.Close()
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,37 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package metrics
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// SerieSliceSource is a metrics.SerieSource iterating over a slice of series.
+type SerieSliceSource struct {
+	series []*metrics.Serie
+	index  int
+}
+
+// NewSerieSliceSource returns a new SerieSliceSource iterating over series.
+func NewSerieSliceSource(series []*metrics.Serie) *SerieSliceSource {
+	return &SerieSliceSource{series: series}
+}
+
+// MoveNext moves to the next serie, returning false once all the series were iterated.
+func (s *SerieSliceSource) MoveNext() bool {
+	s.index++
+	return s.index <= len(s.series)
+}
+
+// Current returns the current serie.
+func (s *SerieSliceSource) Current() *metrics.Serie {
+	return s.series[s.index-1]
+}
+
+// SeriesCount returns the number of series.
+func (s *SerieSliceSource) SeriesCount() uint64 {
+	return uint64(len(s.series))
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+func TestSerieSliceSource(t *testing.T) {
+	source := NewSerieSliceSource([]*metrics.Serie{{Name: "a"}, {Name: "b"}})
+	assert.Equal(t, uint64(2), source.SeriesCount())
+
+	var names []string
+	for source.MoveNext() {
+		names = append(names, source.Current().Name)
+	}
+	assert.Equal(t, []string{"a", "b"}, names)
+	assert.False(t, source.MoveNext())
+
+	assert.False(t, NewSerieSliceSource(nil).MoveNext())
+}
@@ -3,16 +3,10 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package metrics
 
 import (
-	"bytes"
-	"compress/zlib"
 	"fmt"
-	"io/ioutil"
 	"strings"
 	"testing"
 
@@ -24,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 func TestMarshalJSONServiceChecks(t *testing.T) {
@@ -78,7 +73,7 @@ func createServiceCheck(checkName string) *metrics.ServiceCheck {
 
 func buildPayload(t *testing.T, m marshaler.StreamJSONMarshaler) [][]byte {
 	builder := stream.NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, zlibCompressor)
 	assert.NoError(t, err)
 	var uncompressedPayloads [][]byte
 
@@ -154,18 +149,10 @@ func createServiceChecks(numberOfItem int) ServiceChecks {
 	return ServiceChecks(serviceCheckCollections)
 }
 
-func decompressPayload(payload []byte) ([]byte, error) {
-	r, err := zlib.NewReader(bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
+var zlibCompressor, _ = compression.NewCompressor(compression.ZlibKind)
 
-	dst, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return dst, nil
+func decompressPayload(payload []byte) ([]byte, error) {
+	return zlibCompressor.Decompress(payload)
 }
 
 func benchmarkJSONPayloadBuilderServiceCheck(b *testing.B, numberOfItem int) {
@@ -175,7 +162,7 @@ func benchmarkJSONPayloadBuilderServiceCheck(b *testing.B, numberOfItem int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		payloadBuilder.Build(serviceChecks)
+		payloadBuilder.Build(serviceChecks, zlibCompressor)
 	}
 }
 
@@ -210,7 +197,7 @@ func benchmarkPayloadsServiceCheck(b *testing.B, numberOfItem int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		split.Payloads(serviceChecks, true, split.JSONMarshalFct)
+		split.Payloads(serviceChecks, zlibCompressor, split.JSONMarshalFct)
 	}
 }
 
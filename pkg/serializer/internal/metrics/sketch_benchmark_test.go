@@ -9,10 +9,12 @@
 package metrics
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -26,11 +28,15 @@ func benchmarkSplitPayloadsSketchesSplit(b *testing.B, numPoints int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		split.Payloads(testSketchSeries, true, split.ProtoMarshalFct)
+		split.Payloads(testSketchSeries, zlibCompressor, split.ProtoMarshalFct)
 	}
 }
 
 func benchmarkSplitPayloadsSketchesNew(b *testing.B, numPoints int) {
+	benchmarkSplitPayloadsSketchesNewWithCompressor(b, numPoints, zlibCompressor)
+}
+
+func benchmarkSplitPayloadsSketchesNewWithCompressor(b *testing.B, numPoints int, compressor compression.Compressor) {
 	testSketchSeries := make(SketchSeriesList, numPoints)
 	for i := 0; i < numPoints; i++ {
 		testSketchSeries[i] = Makeseries(200)
@@ -40,7 +46,7 @@ func benchmarkSplitPayloadsSketchesNew(b *testing.B, numPoints int) {
 	b.ResetTimer()
 
 	for n := 0; n < b.N; n++ {
-		payloads, err := testSketchSeries.MarshalSplitCompress(marshaler.DefaultBufferContext())
+		payloads, err := testSketchSeries.MarshalSplitCompress(marshaler.DefaultBufferContext(), compressor)
 		require.NoError(b, err)
 		var pb int
 		for _, p := range payloads {
@@ -62,3 +68,16 @@ func BenchmarkMarshalSplitCompress10(b *testing.B)    { benchmarkSplitPayloadsSk
 func BenchmarkMarshalSplitCompress100(b *testing.B)   { benchmarkSplitPayloadsSketchesNew(b, 100) }
 func BenchmarkMarshalSplitCompress1000(b *testing.B)  { benchmarkSplitPayloadsSketchesNew(b, 1000) }
 func BenchmarkMarshalSplitCompress10000(b *testing.B) { benchmarkSplitPayloadsSketchesNew(b, 10000) }
+
+// BenchmarkMarshalSplitCompressKinds compares the CPU usage and the size of the payloads of
+// each kind of compression.
+func BenchmarkMarshalSplitCompressKinds(b *testing.B) {
+	for _, kind := range compression.AvailableKinds() {
+		compressor, _ := compression.NewCompressor(kind)
+		for _, numPoints := range []int{100, 1000} {
+			b.Run(fmt.Sprintf("%s/%d", kind, numPoints), func(b *testing.B) {
+				benchmarkSplitPayloadsSketchesNewWithCompressor(b, numPoints, compressor)
+			})
+		}
+	}
+}
@@ -15,8 +15,10 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/common"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/richardartoul/molecule"
 )
 
@@ -95,9 +97,9 @@ func (sl SketchSeriesList) MarshalJSON() ([]byte, error) {
 // compressed protobuf marshaled gogen.SketchPayload objects. gogen.SketchPayload is not directly marshaled - instead
 // it's contents are marshaled individually, packed with the appropriate protobuf metadata, and compressed in stream.
 // The resulting payloads (when decompressed) are binary equal to the result of marshaling the whole object at once.
-func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
+func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferContext, compressor compression.Compressor) ([]*[]byte, error) {
 	var err error
-	var compressor *stream.Compressor
+	var payloadCompressor *stream.Compressor
 	buf := bufferContext.PrecompressionBuf
 	ps := molecule.NewProtoStream(buf)
 	payloads := []*[]byte{}
@@ -157,20 +159,21 @@ func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferC
 		bufferContext.CompressorInput.Reset()
 		bufferContext.CompressorOutput.Reset()
 
-		compressor, err = stream.NewCompressor(
+		payloadCompressor, err = stream.NewCompressor(
 			bufferContext.CompressorInput, bufferContext.CompressorOutput,
 			maxPayloadSize, maxUncompressedSize,
-			[]byte{}, footer, []byte{})
+			[]byte{}, footer, []byte{}, compressor)
 		if err != nil {
 			return err
 		}
+		payloadCompressor.ReportSizes(bufferContext.SizeReport)
 
 		return nil
 	}
 
 	finishPayload := func() error {
 		var payload []byte
-		payload, err = compressor.Close()
+		payload, err = payloadCompressor.Close()
 		if err != nil {
 			return err
 		}
@@ -267,7 +270,7 @@ func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferC
 		}
 
 		// Compress the protobuf metadata and the marshaled sketch
-		err = compressor.AddItem(buf.Bytes())
+		err = payloadCompressor.AddItemFrom(buf.Bytes(), sizereport.MetricSource(ss.CheckID, ss.Name))
 		switch err {
 		case stream.ErrPayloadFull:
 			expvarsPayloadFull.Add(1)
@@ -285,7 +288,7 @@ func (sl SketchSeriesList) MarshalSplitCompress(bufferContext *marshaler.BufferC
 			}
 
 			// Add it to the new compression buffer
-			err = compressor.AddItem(buf.Bytes())
+			err = payloadCompressor.AddItemFrom(buf.Bytes(), sizereport.MetricSource(ss.CheckID, ss.Name))
 			if err == stream.ErrItemTooBig {
 				// Item was too big, drop it
 				expvarsItemTooBig.Add(1)
@@ -103,7 +103,7 @@ func TestSketchSeriesMarshalSplitCompressEmpty(t *testing.T) {
 
 	sl := SketchSeriesList{}
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext(), zlibCompressor)
 
 	assert.Nil(t, err)
 
@@ -134,7 +134,7 @@ func TestSketchSeriesMarshalSplitCompressItemTooBigIsDropped(t *testing.T) {
 		Interval: 0,
 	}
 
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext(), zlibCompressor)
 
 	assert.Nil(t, err)
 
@@ -160,7 +160,7 @@ func TestSketchSeriesMarshalSplitCompress(t *testing.T) {
 	}
 
 	payload, _ := sl.Marshal()
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext(), zlibCompressor)
 	require.NoError(t, err)
 
 	reader := bytes.NewReader(*payloads[0])
@@ -205,7 +205,7 @@ func TestSketchSeriesMarshalSplitCompressSplit(t *testing.T) {
 		sl[i] = Makeseries(i)
 	}
 
-	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext())
+	payloads, err := sl.MarshalSplitCompress(marshaler.DefaultBufferContext(), zlibCompressor)
 	assert.Nil(t, err)
 
 	recoveredSketches := []gogen.SketchPayload{}
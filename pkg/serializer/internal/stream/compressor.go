@@ -3,26 +3,18 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package stream
 
 import (
 	"bytes"
-	"compress/zlib"
 	"errors"
 	"expvar"
 
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-const (
-	// Available is true if the code is compiled in
-	Available = true
-)
-
 var (
 	compressorExpvars    = expvar.NewMap("compressor")
 	expvarsTotalPayloads = expvar.Int{}
@@ -63,7 +55,8 @@ func init() {
 type Compressor struct {
 	input               *bytes.Buffer // temporary buffer for data that has not been compressed yet
 	compressed          *bytes.Buffer // output buffer containing the compressed payload
-	zipper              *zlib.Writer
+	zipper              compression.StreamCompressor
+	compressor          compression.Compressor
 	header              []byte // json header to print at the beginning of the payload
 	footer              []byte // json footer to append at the end of the payload
 	uncompressedWritten int    // uncompressed bytes written
@@ -74,9 +67,11 @@ type Compressor struct {
 	maxPayloadSize      int
 	maxUncompressedSize int
 	separator           []byte
+	sizes               *sizereport.Payload // optional, attributes the bytes of the payload to its items
 }
 
-func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte) (*Compressor, error) {
+// NewCompressor returns a new instance of a Compressor compressing the payload with compressor
+func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedSize int, header, footer []byte, separator []byte, compressor compression.Compressor) (*Compressor, error) {
 	c := &Compressor{
 		header:              header,
 		footer:              footer,
@@ -86,11 +81,12 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 		maxPayloadSize:      maxPayloadSize,
 		maxUncompressedSize: maxUncompressedSize,
 		maxUnzippedItemSize: maxPayloadSize - len(footer) - len(header),
-		maxZippedItemSize:   maxUncompressedSize - compression.CompressBound(len(footer)+len(header)),
+		maxZippedItemSize:   maxUncompressedSize - compressor.CompressBound(len(footer)+len(header)),
 		separator:           separator,
+		compressor:          compressor,
 	}
 
-	c.zipper = zlib.NewWriter(c.compressed)
+	c.zipper = compressor.NewStreamCompressor(c.compressed)
 	n, err := c.zipper.Write(header)
 	c.uncompressedWritten += n
 
@@ -102,7 +98,7 @@ func NewCompressor(input, output *bytes.Buffer, maxPayloadSize, maxUncompressedS
 // that could actually fit after compression. That said it is probably impossible
 // to have a 2MB+ item that is valid for the backend.
 func (c *Compressor) checkItemSize(data []byte) bool {
-	return len(data) < c.maxUnzippedItemSize && compression.CompressBound(len(data)) < c.maxZippedItemSize
+	return len(data) < c.maxUnzippedItemSize && c.compressor.CompressBound(len(data)) < c.maxZippedItemSize
 }
 
 // hasRoomForItem checks if the current payload has enough room to store the given item
@@ -111,7 +107,7 @@ func (c *Compressor) hasRoomForItem(item []byte) bool {
 	if !c.firstItem {
 		uncompressedDataSize += len(c.separator)
 	}
-	return compression.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
+	return c.compressor.CompressBound(uncompressedDataSize) <= c.remainingSpace() && c.uncompressedWritten+uncompressedDataSize <= c.maxUncompressedSize
 }
 
 // pack flushes the temporary uncompressed buffer input to the compression writer
@@ -123,9 +119,14 @@ func (c *Compressor) pack() error {
 		return err
 	}
 	c.uncompressedWritten += int(n)
-	c.zipper.Flush()
+	err = c.zipper.Flush()
 	c.input.Reset()
-	return nil
+	return err
+}
+
+// ReportSizes records the sizes of the payload and of its items in p when it is closed.
+func (c *Compressor) ReportSizes(p *sizereport.Payload) {
+	c.sizes = p
 }
 
 func (c *Compressor) Write(data []byte) (int, error) {
@@ -169,6 +170,15 @@ func (c *Compressor) AddItem(data []byte) error {
 	return nil
 }
 
+// AddItemFrom will try to add the given item like AddItem, attributing its bytes to source
+func (c *Compressor) AddItemFrom(data []byte, source sizereport.Source) error {
+	err := c.AddItem(data)
+	if err == nil {
+		c.sizes.AddItem(source, len(data))
+	}
+	return err
+}
+
 func (c *Compressor) Close() ([]byte, error) {
 	// Flush remaining uncompressed data
 	if c.input.Len() > 0 {
@@ -184,7 +194,7 @@ func (c *Compressor) Close() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	// Add zlib footer and close
+	// Add the compression footer and close
 	err = c.zipper.Close()
 	if err != nil {
 		return nil, err
@@ -199,6 +209,7 @@ func (c *Compressor) Close() ([]byte, error) {
 	tlmBytesIn.Add(float64(c.uncompressedWritten))
 	expvarsBytesOut.Add(int64(c.compressed.Len()))
 	tlmBytesOut.Add(float64(c.compressed.Len()))
+	c.sizes.Done(c.uncompressedWritten, c.compressed.Len())
 
 	return payload, nil
 }
@@ -3,47 +3,32 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2018-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package stream
 
 import (
 	"bytes"
-	"compress/zlib"
-	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
 var (
 	maxPayloadSizeDefault = config.Datadog.GetInt("serializer_max_payload_size")
+	zlibCompressor, _     = compression.NewCompressor(compression.ZlibKind)
 )
 
 func resetDefaults() {
 	config.Datadog.SetDefault("serializer_max_payload_size", maxPayloadSizeDefault)
 }
 
-func decompressPayload(payload []byte) ([]byte, error) {
-	r, err := zlib.NewReader(bytes.NewReader(payload))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-
-	dst, err := ioutil.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-	return dst, nil
-}
-
 func payloadToString(payload []byte) string {
-	p, err := decompressPayload(payload)
+	p, err := zlibCompressor.Decompress(payload)
 	if err != nil {
 		return err.Error()
 	}
@@ -56,7 +41,7 @@ func TestCompressorSimple(t *testing.T) {
 	c, err := NewCompressor(
 		&bytes.Buffer{}, &bytes.Buffer{},
 		maxPayloadSize, maxUncompressedSize,
-		[]byte("{["), []byte("]}"), []byte(","))
+		[]byte("{["), []byte("]}"), []byte(","), zlibCompressor)
 	require.NoError(t, err)
 
 	for i := 0; i < 5; i++ {
@@ -76,7 +61,7 @@ func TestOnePayloadSimple(t *testing.T) {
 	}
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, zlibCompressor)
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
 
@@ -93,7 +78,7 @@ func TestMaxCompressedSizePayload(t *testing.T) {
 	defer resetDefaults()
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, zlibCompressor)
 	require.NoError(t, err)
 	require.Len(t, payloads, 1)
 
@@ -110,7 +95,7 @@ func TestTwoPayload(t *testing.T) {
 	defer resetDefaults()
 
 	builder := NewJSONPayloadBuilder(true)
-	payloads, err := builder.Build(m)
+	payloads, err := builder.Build(m, zlibCompressor)
 	require.NoError(t, err)
 	require.Len(t, payloads, 2)
 
@@ -128,10 +113,92 @@ func TestLockedCompressorProducesSamePayloads(t *testing.T) {
 
 	builderLocked := NewJSONPayloadBuilder(true)
 	builderUnLocked := NewJSONPayloadBuilder(false)
-	payloads1, err := builderLocked.Build(m)
+	payloads1, err := builderLocked.Build(m, zlibCompressor)
 	require.NoError(t, err)
-	payloads2, err := builderUnLocked.Build(m)
+	payloads2, err := builderUnLocked.Build(m, zlibCompressor)
 	require.NoError(t, err)
 
 	require.Equal(t, payloadToString(*payloads1[0]), payloadToString(*payloads2[0]))
 }
+
+func TestCompressorKinds(t *testing.T) {
+	m := &marshaler.DummyMarshaller{
+		Items:  []string{"A", "B", "C"},
+		Header: "{[",
+		Footer: "]}",
+	}
+
+	builder := NewJSONPayloadBuilder(true)
+	for _, kind := range compression.AvailableKinds() {
+		compressor, err := compression.NewCompressor(kind)
+		require.NoError(t, err)
+		payloads, err := builder.Build(m, compressor)
+		require.NoError(t, err)
+		require.Len(t, payloads, 1)
+
+		p, err := compressor.Decompress(*payloads[0])
+		require.NoError(t, err)
+		require.Equal(t, "{[A,B,C]}", string(p), kind)
+	}
+}
+
+// sourcedMarshaller attributes each item to a metric prefix named after the item.
+type sourcedMarshaller struct {
+	*marshaler.IterableStreamJSONMarshalerAdapter
+	items []string
+	index int
+}
+
+func (m *sourcedMarshaller) MoveNext() bool {
+	m.index++
+	return m.IterableStreamJSONMarshalerAdapter.MoveNext()
+}
+
+func (m *sourcedMarshaller) CurrentItemSource() sizereport.Source {
+	return sizereport.Source{CheckID: "check", MetricPrefix: m.items[m.index-1]}
+}
+
+func TestBuildWithSizeReport(t *testing.T) {
+	items := []string{"A", "B", "C", "D", "E", "F"}
+	m := &sourcedMarshaller{
+		IterableStreamJSONMarshalerAdapter: marshaler.NewIterableStreamJSONMarshalerAdapter(&marshaler.DummyMarshaller{
+			Items:  items,
+			Header: "{[",
+			Footer: "]}",
+		}),
+		items: items,
+	}
+	config.Datadog.SetDefault("serializer_max_payload_size", 22)
+	defer resetDefaults()
+
+	recorder := sizereport.NewRecorder(0)
+	builder := NewJSONPayloadBuilder(true)
+	payloads, err := builder.BuildWithSizeReport(m, DropItemOnErrItemTooBig, zlibCompressor, recorder.NewPayload("series"))
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+	recorder.StartFlush(time.Now())
+
+	report := recorder.LastFlush()
+	require.Len(t, report.PayloadTypes, 1)
+	series := report.PayloadTypes[0]
+	require.Equal(t, sizereport.Entry{
+		Name:              "series",
+		Items:             2,
+		UncompressedBytes: int64(2 * len("{[A,B,C]}")),
+		CompressedBytes:   int64(len(*payloads[0]) + len(*payloads[1])),
+	}, series)
+
+	require.Len(t, report.Checks, 1)
+	require.Equal(t, int64(6), report.Checks[0].Items)
+	require.Equal(t, int64(6), report.Checks[0].UncompressedBytes)
+	require.Len(t, report.MetricPrefixes, 6)
+	// The compressed bytes are shared in proportion to the uncompressed size of the items
+	for _, prefix := range report.MetricPrefixes {
+		payload := payloads[0]
+		if prefix.Name > "C" {
+			payload = payloads[1]
+		}
+		require.Equal(t, int64(1), prefix.Items)
+		require.Equal(t, int64(len(*payload)/len("{[A,B,C]}")), prefix.CompressedBytes, prefix.Name)
+	}
+}
@@ -3,9 +3,6 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2019-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package stream
 
 import (
@@ -19,7 +16,9 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/config"
 	"github.com/DataDog/datadog-agent/pkg/forwarder"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -99,15 +98,31 @@ const (
 )
 
 // Build serializes a metadata payload and sends it to the forwarder
-func (b *JSONPayloadBuilder) Build(m marshaler.StreamJSONMarshaler) (forwarder.Payloads, error) {
+func (b *JSONPayloadBuilder) Build(m marshaler.StreamJSONMarshaler, compressor compression.Compressor) (forwarder.Payloads, error) {
 	adapter := marshaler.NewIterableStreamJSONMarshalerAdapter(m)
-	return b.BuildWithOnErrItemTooBigPolicy(adapter, DropItemOnErrItemTooBig)
+	return b.BuildWithOnErrItemTooBigPolicy(adapter, DropItemOnErrItemTooBig, compressor)
 }
 
 // BuildWithOnErrItemTooBigPolicy serializes a metadata payload and sends it to the forwarder
 func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	m marshaler.IterableStreamJSONMarshaler,
-	policy OnErrItemTooBigPolicy) (forwarder.Payloads, error) {
+	policy OnErrItemTooBigPolicy,
+	compressor compression.Compressor) (forwarder.Payloads, error) {
+	return b.BuildWithSizeReport(m, policy, compressor, nil)
+}
+
+// itemSourcer is implemented by the marshalers which know the source of their items.
+type itemSourcer interface {
+	CurrentItemSource() sizereport.Source
+}
+
+// BuildWithSizeReport serializes a payload like BuildWithOnErrItemTooBigPolicy and
+// records the sizes of the payloads in sizes, if not nil.
+func (b *JSONPayloadBuilder) BuildWithSizeReport(
+	m marshaler.IterableStreamJSONMarshaler,
+	policy OnErrItemTooBigPolicy,
+	compressor compression.Compressor,
+	sizes *sizereport.Payload) (forwarder.Payloads, error) {
 	var input, output *bytes.Buffer
 
 	// the backend accepts payloads up to specific compressed / uncompressed
@@ -157,18 +172,20 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 		return nil, err
 	}
 
-	compressor, err := NewCompressor(
+	payloadCompressor, err := NewCompressor(
 		input, output,
 		maxPayloadSize, maxUncompressedSize,
-		header.Bytes(), footer.Bytes(), []byte(","))
+		header.Bytes(), footer.Bytes(), []byte(","), compressor)
 	if err != nil {
 		return nil, err
 	}
+	payloadCompressor.ReportSizes(sizes)
+	sourcer, _ := m.(itemSourcer)
 
 	ok := m.MoveNext()
 	for ok {
 		// We keep reusing the same small buffer in the jsoniter stream. Note that we can do so
-		// because compressor.addItem copies given buffer.
+		// because payloadCompressor.AddItem copies given buffer.
 		jsonStream.Reset(nil)
 		err := m.WriteCurrentItem(jsonStream)
 		if err != nil {
@@ -179,25 +196,31 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 			continue
 		}
 
-		switch compressor.AddItem(jsonStream.Buffer()) {
+		var source sizereport.Source
+		if sizes != nil && sourcer != nil {
+			source = sourcer.CurrentItemSource()
+		}
+
+		switch payloadCompressor.AddItemFrom(jsonStream.Buffer(), source) {
 		case ErrPayloadFull:
 			expvarsPayloadFulls.Add(1)
 			tlmPayloadFull.Inc()
 			// payload is full, we need to create a new one
-			payload, err := compressor.Close()
+			payload, err := payloadCompressor.Close()
 			if err != nil {
 				return payloads, err
 			}
 			payloads = append(payloads, &payload)
 			input.Reset()
 			output.Reset()
-			compressor, err = NewCompressor(
+			payloadCompressor, err = NewCompressor(
 				input, output,
 				maxPayloadSize, maxUncompressedSize,
-				header.Bytes(), footer.Bytes(), []byte(","))
+				header.Bytes(), footer.Bytes(), []byte(","), compressor)
 			if err != nil {
 				return nil, err
 			}
+			payloadCompressor.ReportSizes(sizes)
 		case nil:
 			// All good, continue to next item
 			ok = m.MoveNext()
@@ -220,7 +243,7 @@ func (b *JSONPayloadBuilder) BuildWithOnErrItemTooBigPolicy(
 	}
 
 	// Close last payload
-	payload, err := compressor.Close()
+	payload, err := payloadCompressor.Close()
 	if err != nil {
 		return payloads, err
 	}
@@ -3,8 +3,8 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zlib && optional_benchmarks
-// +build zlib,optional_benchmarks
+//go:build test && optional_benchmarks
+// +build test,optional_benchmarks
 
 package serializer
 
@@ -27,7 +27,7 @@ func benchmarkJSONPayloadBuilderThroughput(points int, items int, tags int, runs
 
 	for i := 0; i < runs; i++ {
 		start := time.Now()
-		payloadBuilder.Build(series)
+		payloadBuilder.Build(series, zlibCompressor)
 		totalTime += time.Since(start)
 	}
 	avgTime := int64(totalTime) / int64(runs)
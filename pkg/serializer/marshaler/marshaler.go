@@ -9,6 +9,8 @@ import (
 	"bytes"
 
 	jsoniter "github.com/json-iterator/go"
+
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 )
 
 // JSONMarshaler is a AbstractMarshaler that implement JSON marshaling.
@@ -64,13 +66,15 @@ type BufferContext struct {
 	CompressorInput   *bytes.Buffer
 	CompressorOutput  *bytes.Buffer
 	PrecompressionBuf *bytes.Buffer
+	// SizeReport, if not nil, records the sizes of the payloads built by MarshalSplitCompress
+	SizeReport *sizereport.Payload
 }
 
 // DefaultBufferContext initialize the default compression buffers
 func DefaultBufferContext() *BufferContext {
 	return &BufferContext{
-		bytes.NewBuffer(make([]byte, 0, 1024)),
-		bytes.NewBuffer(make([]byte, 0, 1024)),
-		bytes.NewBuffer(make([]byte, 0, 1024)),
+		CompressorInput:   bytes.NewBuffer(make([]byte, 0, 1024)),
+		CompressorOutput:  bytes.NewBuffer(make([]byte, 0, 1024)),
+		PrecompressionBuf: bytes.NewBuffer(make([]byte, 0, 1024)),
 	}
 }
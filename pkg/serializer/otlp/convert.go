@@ -0,0 +1,228 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package otlp converts the metrics aggregated by the agent to OTLP metrics and sends them
+// to an OpenTelemetry collector over OTLP/HTTP.
+package otlp
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+const (
+	scopeName = "datadog-agent"
+	// hostAttribute is the resource attribute holding the host of the metrics
+	hostAttribute = "host.name"
+	// deviceAttribute is the data point attribute holding the device of a series
+	deviceAttribute = "device"
+
+	// histogramScale is the scale of the exponential histograms built from sketches. The
+	// bucket base 2^(2^-6) ~= 1.011 is finer than the sketches relative accuracy.
+	histogramScale = 6
+)
+
+// metricsBuilder groups the metrics by host, a host being an OTLP resource.
+type metricsBuilder struct {
+	metrics    pmetric.Metrics
+	hosts      map[string]pmetric.MetricSlice
+	dataPoints int
+}
+
+func newMetricsBuilder() *metricsBuilder {
+	return &metricsBuilder{
+		metrics: pmetric.NewMetrics(),
+		hosts:   make(map[string]pmetric.MetricSlice),
+	}
+}
+
+func (b *metricsBuilder) appendMetric(host string) pmetric.Metric {
+	ms, ok := b.hosts[host]
+	if !ok {
+		rm := b.metrics.ResourceMetrics().AppendEmpty()
+		if host != "" {
+			rm.Resource().Attributes().UpsertString(hostAttribute, host)
+		}
+		sm := rm.ScopeMetrics().AppendEmpty()
+		sm.Scope().SetName(scopeName)
+		sm.Scope().SetVersion(version.AgentVersion)
+		ms = sm.Metrics()
+		b.hosts[host] = ms
+	}
+	return ms.AppendEmpty()
+}
+
+// SeriesToMetrics converts series to OTLP metrics:
+//   - gauges are converted to gauges,
+//   - counts are converted to delta sums,
+//   - rates are converted to delta sums of the rate times the interval. Rates without
+//     interval are converted to gauges.
+func SeriesToMetrics(series []*metrics.Serie) pmetric.Metrics {
+	b := newMetricsBuilder()
+	for _, serie := range series {
+		b.appendSerie(serie)
+	}
+	return b.metrics
+}
+
+// appendSerie converts serie to an OTLP metric, as described by SeriesToMetrics.
+func (b *metricsBuilder) appendSerie(serie *metrics.Serie) {
+	m := b.appendMetric(serie.Host)
+	m.SetName(serie.Name)
+
+	var dps pmetric.NumberDataPointSlice
+	factor := 1.0
+	switch {
+	case serie.MType == metrics.APICountType:
+		m.SetDataType(pmetric.MetricDataTypeSum)
+		m.Sum().SetAggregationTemporality(pmetric.MetricAggregationTemporalityDelta)
+		dps = m.Sum().DataPoints()
+	case serie.MType == metrics.APIRateType && serie.Interval > 0:
+		m.SetDataType(pmetric.MetricDataTypeSum)
+		m.Sum().SetAggregationTemporality(pmetric.MetricAggregationTemporalityDelta)
+		dps = m.Sum().DataPoints()
+		factor = float64(serie.Interval)
+	default:
+		m.SetDataType(pmetric.MetricDataTypeGauge)
+		dps = m.Gauge().DataPoints()
+	}
+
+	for _, p := range serie.Points {
+		dp := dps.AppendEmpty()
+		setTimestamps(dp.SetStartTimestamp, dp.SetTimestamp, int64(p.Ts), serie.Interval)
+		dp.SetDoubleVal(p.Value * factor)
+		setAttributes(dp.Attributes(), serie.Tags)
+		if serie.Device != "" {
+			dp.Attributes().UpsertString(deviceAttribute, serie.Device)
+		}
+	}
+	b.dataPoints += len(serie.Points)
+}
+
+// SketchesToMetrics converts sketches to OTLP delta exponential histograms.
+func SketchesToMetrics(sketches metrics.SketchSeriesList) pmetric.Metrics {
+	b := newMetricsBuilder()
+	for _, sketch := range sketches {
+		m := b.appendMetric(sketch.Host)
+		m.SetName(sketch.Name)
+		m.SetDataType(pmetric.MetricDataTypeExponentialHistogram)
+		m.ExponentialHistogram().SetAggregationTemporality(pmetric.MetricAggregationTemporalityDelta)
+		dps := m.ExponentialHistogram().DataPoints()
+
+		for _, p := range sketch.Points {
+			if p.Sketch == nil {
+				continue
+			}
+			dp := dps.AppendEmpty()
+			setTimestamps(dp.SetStartTimestamp, dp.SetTimestamp, p.Ts, sketch.Interval)
+			setAttributes(dp.Attributes(), sketch.Tags)
+			setExponentialHistogram(dp, p.Sketch)
+		}
+	}
+	return b.metrics
+}
+
+func setTimestamps(setStart, setEnd func(pcommon.Timestamp), ts int64, interval int64) {
+	end := time.Unix(ts, 0)
+	setEnd(pcommon.NewTimestampFromTime(end))
+	if interval > 0 {
+		setStart(pcommon.NewTimestampFromTime(end.Add(-time.Duration(interval) * time.Second)))
+	}
+}
+
+// setAttributes converts `key:value` tags to attributes. Tags without value are converted
+// to attributes with an empty value and the values of a key used by several tags are
+// converted to an array attribute.
+func setAttributes(attributes pcommon.Map, tags tagset.CompositeTags) {
+	tags.ForEach(func(tag string) {
+		key, value := tag, ""
+		if i := strings.IndexByte(tag, ':'); i > 0 {
+			key, value = tag[:i], tag[i+1:]
+		}
+		existing, ok := attributes.Get(key)
+		if !ok {
+			attributes.InsertString(key, value)
+			return
+		}
+		if existing.Type() == pcommon.ValueTypeString {
+			if existing.StringVal() == value {
+				return
+			}
+			values := pcommon.NewValueSlice()
+			values.SliceVal().AppendEmpty().SetStringVal(existing.StringVal())
+			values.SliceVal().AppendEmpty().SetStringVal(value)
+			attributes.Upsert(key, values)
+			return
+		}
+		values := existing.SliceVal()
+		for i := 0; i < values.Len(); i++ {
+			if values.At(i).StringVal() == value {
+				return
+			}
+		}
+		values.AppendEmpty().SetStringVal(value)
+	})
+}
+
+func setExponentialHistogram(dp pmetric.ExponentialHistogramDataPoint, sketch *quantile.Sketch) {
+	dp.SetCount(uint64(sketch.Basic.Cnt))
+	dp.SetSum(sketch.Basic.Sum)
+	dp.SetScale(histogramScale)
+
+	positive := make(map[int32]uint64)
+	negative := make(map[int32]uint64)
+	var zeroCount uint64
+	keys, counts := sketch.Cols()
+	for i, k := range keys {
+		v := quantile.AgentKeyValue(k)
+		switch {
+		case v > 0:
+			positive[bucketIndex(v)] += uint64(counts[i])
+		case v < 0:
+			negative[bucketIndex(-v)] += uint64(counts[i])
+		default:
+			zeroCount += uint64(counts[i])
+		}
+	}
+	dp.SetZeroCount(zeroCount)
+	setBuckets(dp.Positive(), positive)
+	setBuckets(dp.Negative(), negative)
+}
+
+// bucketIndex returns the index of the exponential histogram bucket holding v, with
+// base^index < v <= base^(index+1).
+func bucketIndex(v float64) int32 {
+	return int32(math.Ceil(math.Log2(v)*math.Exp2(histogramScale))) - 1
+}
+
+func setBuckets(buckets pmetric.Buckets, counts map[int32]uint64) {
+	if len(counts) == 0 {
+		return
+	}
+	min, max := int32(math.MaxInt32), int32(math.MinInt32)
+	for index := range counts {
+		if index < min {
+			min = index
+		}
+		if index > max {
+			max = index
+		}
+	}
+	bucketCounts := make([]uint64, max-min+1)
+	for index, count := range counts {
+		bucketCounts[index-min] = count
+	}
+	buckets.SetOffset(min)
+	buckets.SetMBucketCounts(bucketCounts)
+}
@@ -0,0 +1,137 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func TestSeriesToMetrics(t *testing.T) {
+	series := []*metrics.Serie{
+		{
+			Name:   "gauge",
+			Points: []metrics.Point{{Ts: 1000, Value: 1.5}},
+			Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "team:a", "team:b", "team:a", "team:c", "standalone"}),
+			Host:   "host1",
+			Device: "sda",
+			MType:  metrics.APIGaugeType,
+		},
+		{
+			Name:     "count",
+			Points:   []metrics.Point{{Ts: 1000, Value: 3}},
+			Host:     "host1",
+			MType:    metrics.APICountType,
+			Interval: 10,
+		},
+		{
+			Name:     "rate",
+			Points:   []metrics.Point{{Ts: 1000, Value: 0.5}, {Ts: 1010, Value: 2}},
+			Host:     "host2",
+			MType:    metrics.APIRateType,
+			Interval: 10,
+		},
+	}
+
+	m := SeriesToMetrics(series)
+	require.Equal(t, 2, m.ResourceMetrics().Len())
+	assert.Equal(t, 4, m.DataPointCount())
+
+	host1 := m.ResourceMetrics().At(0)
+	host, _ := host1.Resource().Attributes().Get(hostAttribute)
+	assert.Equal(t, "host1", host.StringVal())
+	assert.Equal(t, scopeName, host1.ScopeMetrics().At(0).Scope().Name())
+	ms := host1.ScopeMetrics().At(0).Metrics()
+	require.Equal(t, 2, ms.Len())
+
+	gauge := ms.At(0)
+	assert.Equal(t, "gauge", gauge.Name())
+	require.Equal(t, pmetric.MetricDataTypeGauge, gauge.DataType())
+	dp := gauge.Gauge().DataPoints().At(0)
+	assert.Equal(t, 1.5, dp.DoubleVal())
+	assert.Equal(t, int64(1000), dp.Timestamp().AsTime().Unix())
+	assert.Equal(t, map[string]interface{}{
+		"env":        "prod",
+		"team":       []interface{}{"a", "b", "c"},
+		"standalone": "",
+		"device":     "sda",
+	}, dp.Attributes().AsRaw())
+
+	count := ms.At(1)
+	require.Equal(t, pmetric.MetricDataTypeSum, count.DataType())
+	assert.Equal(t, pmetric.MetricAggregationTemporalityDelta, count.Sum().AggregationTemporality())
+	dp = count.Sum().DataPoints().At(0)
+	assert.Equal(t, float64(3), dp.DoubleVal())
+	assert.Equal(t, int64(990), dp.StartTimestamp().AsTime().Unix())
+
+	rate := m.ResourceMetrics().At(1).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, pmetric.MetricDataTypeSum, rate.DataType())
+	assert.Equal(t, float64(5), rate.Sum().DataPoints().At(0).DoubleVal())
+	assert.Equal(t, float64(20), rate.Sum().DataPoints().At(1).DoubleVal())
+}
+
+func TestSketchesToMetrics(t *testing.T) {
+	var a quantile.Agent
+	values := []float64{-10, 0, 1, 1, 2, 100}
+	for _, v := range values {
+		a.Insert(v, 1)
+	}
+	sketches := metrics.SketchSeriesList{{
+		Name:     "distribution",
+		Tags:     tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Host:     "host1",
+		Interval: 10,
+		Points:   []metrics.SketchPoint{{Sketch: a.Finish(), Ts: 1000}},
+	}}
+
+	m := SketchesToMetrics(sketches)
+	require.Equal(t, 1, m.DataPointCount())
+	metric := m.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0)
+	require.Equal(t, pmetric.MetricDataTypeExponentialHistogram, metric.DataType())
+	assert.Equal(t, pmetric.MetricAggregationTemporalityDelta, metric.ExponentialHistogram().AggregationTemporality())
+
+	dp := metric.ExponentialHistogram().DataPoints().At(0)
+	assert.Equal(t, uint64(6), dp.Count())
+	assert.Equal(t, float64(94), dp.Sum())
+	assert.Equal(t, int32(histogramScale), dp.Scale())
+	assert.Equal(t, uint64(1), dp.ZeroCount())
+
+	// Every value is in the bucket at the expected index, within the sketch accuracy
+	base := math.Exp2(math.Exp2(-histogramScale))
+	positive := dp.Positive()
+	counts := positive.MBucketCounts()
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	assert.Equal(t, uint64(4), total)
+	assert.Equal(t, uint64(2), counts[0], "the two values equal to 1")
+	assert.InEpsilon(t, 1, math.Pow(base, float64(positive.Offset()+1)), 0.02)
+	assert.InEpsilon(t, 100, math.Pow(base, float64(positive.Offset()+int32(len(counts)))), 0.02)
+
+	negative := dp.Negative()
+	assert.Equal(t, []uint64{1}, negative.MBucketCounts())
+	assert.InEpsilon(t, 10, math.Pow(base, float64(negative.Offset()+1)), 0.02)
+}
+
+func TestBucketIndex(t *testing.T) {
+	base := math.Exp2(math.Exp2(-histogramScale))
+	for _, v := range []float64{1e-9, 0.5, 1, 1.5, 2, 1e9} {
+		i := bucketIndex(v)
+		assert.Less(t, math.Pow(base, float64(i)), v*(1+1e-12), v)
+		assert.GreaterOrEqual(t, math.Pow(base, float64(i+1))*(1+1e-12), v, v)
+	}
+	assert.Equal(t, int32(-1), bucketIndex(1))
+	assert.Equal(t, int32(63), bucketIndex(2))
+}
@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	metricsPath = "/v1/metrics"
+
+	// maxRetries is the number of times a payload is sent again after a network error or
+	// a server error of the collector.
+	maxRetries = 3
+)
+
+var (
+	tlmPayloads = telemetry.NewCounter("otlp_export", "payloads",
+		[]string{"status"}, "Count of OTLP metrics payloads sent to the collector")
+	tlmBytes = telemetry.NewCounter("otlp_export", "bytes",
+		nil, "Count of bytes sent to the OTLP collector")
+
+	// retryBackoff is the delay before the first retry of a payload, doubled for each retry.
+	retryBackoff = time.Second
+)
+
+// Forwarder sends OTLP metrics to a collector with OTLP/HTTP, encoded with protobuf.
+//
+// The payloads are queued by SendAsync and sent by the goroutine started with Start, so
+// that a slow or unavailable collector doesn't delay the flushes of the aggregator. The
+// payloads are dropped when the queue is full.
+type Forwarder struct {
+	url        string
+	headers    http.Header
+	compressor compression.Compressor
+	client     *http.Client
+	timeout    time.Duration
+
+	payloads chan pmetric.Metrics
+	stop     chan struct{}
+	stopped  sync.WaitGroup
+}
+
+// NewForwarder returns a Forwarder sending metrics to the OTLP/HTTP endpoint. The metrics
+// path is added to the endpoint when missing. Payloads are compressed with compressor and
+// at most queueSize payloads are waiting to be sent.
+func NewForwarder(endpoint string, headers map[string]string, compressor compression.Compressor, timeout time.Duration, queueSize int) *Forwarder {
+	url := strings.TrimSuffix(endpoint, "/")
+	if !strings.HasSuffix(url, metricsPath) {
+		url += metricsPath
+	}
+
+	h := make(http.Header)
+	for key, value := range headers {
+		h.Set(key, value)
+	}
+	h.Set("Content-Type", "application/x-protobuf")
+	if encoding := compressor.ContentEncoding(); encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+
+	if queueSize <= 0 {
+		queueSize = 1
+	}
+
+	return &Forwarder{
+		url:        url,
+		headers:    h,
+		compressor: compressor,
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: httputils.CreateHTTPTransport(),
+		},
+		timeout:  timeout,
+		payloads: make(chan pmetric.Metrics, queueSize),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start starts sending the queued payloads.
+func (f *Forwarder) Start() {
+	f.stopped.Add(1)
+	go func() {
+		defer f.stopped.Done()
+		for {
+			select {
+			case metrics := <-f.payloads:
+				f.sendWithRetries(metrics)
+			case <-f.stop:
+				f.flush()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sending the payloads, once the queued ones are sent once more.
+func (f *Forwarder) Stop() {
+	close(f.stop)
+	f.stopped.Wait()
+}
+
+// flush sends the queued payloads, without retrying them, for at most the timeout of a request.
+func (f *Forwarder) flush() {
+	ctx, cancel := context.WithTimeout(context.Background(), f.timeout)
+	defer cancel()
+	for {
+		select {
+		case metrics := <-f.payloads:
+			if err := f.Send(ctx, metrics); err != nil {
+				log.Errorf("Dropping OTLP metrics payload: %v", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// SendAsync queues the metrics to be sent to the collector. They are dropped if the queue is full.
+func (f *Forwarder) SendAsync(metrics pmetric.Metrics) {
+	if metrics.DataPointCount() == 0 {
+		return
+	}
+	select {
+	case f.payloads <- metrics:
+	default:
+		tlmPayloads.Inc("dropped")
+		log.Errorf("Dropping OTLP metrics payload: the queue of the payloads to send to %s is full", f.url)
+	}
+}
+
+// sendWithRetries sends the metrics to the collector, retrying with an exponential backoff
+// after network errors and server errors.
+func (f *Forwarder) sendWithRetries(metrics pmetric.Metrics) {
+	payload, err := f.encode(metrics)
+	if err != nil {
+		log.Errorf("Dropping OTLP metrics payload: %v", err)
+		return
+	}
+
+	backoff := retryBackoff
+	for retry := 0; ; retry++ {
+		retryable, err := f.post(context.Background(), payload)
+		if err == nil {
+			return
+		}
+		if !retryable || retry == maxRetries {
+			log.Errorf("Dropping OTLP metrics payload: %v", err)
+			return
+		}
+		tlmPayloads.Inc("retry")
+		log.Debugf("Retrying OTLP metrics payload in %s: %v", backoff, err)
+		select {
+		case <-time.After(backoff):
+		case <-f.stop:
+			// sent once more by flush
+			f.SendAsync(metrics)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// Send sends the metrics to the collector.
+func (f *Forwarder) Send(ctx context.Context, metrics pmetric.Metrics) error {
+	if metrics.DataPointCount() == 0 {
+		return nil
+	}
+
+	payload, err := f.encode(metrics)
+	if err != nil {
+		return err
+	}
+	_, err = f.post(ctx, payload)
+	return err
+}
+
+func (f *Forwarder) encode(metrics pmetric.Metrics) ([]byte, error) {
+	payload, err := pmetricotlp.NewRequestFromMetrics(metrics).MarshalProto()
+	if err != nil {
+		tlmPayloads.Inc("error")
+		return nil, fmt.Errorf("cannot encode the OTLP metrics: %v", err)
+	}
+	if payload, err = f.compressor.Compress(payload); err != nil {
+		tlmPayloads.Inc("error")
+		return nil, fmt.Errorf("cannot compress the OTLP metrics: %v", err)
+	}
+	return payload, nil
+}
+
+// post sends the payload to the collector and returns whether it can be sent again in case of error.
+func (f *Forwarder) post(ctx context.Context, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", f.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header = f.headers.Clone()
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		tlmPayloads.Inc("error")
+		return ctx.Err() == nil, fmt.Errorf("cannot send the OTLP metrics to %s: %v", f.url, err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		tlmPayloads.Inc("error")
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("the OTLP collector %s answered %q", f.url, resp.Status)
+	}
+	tlmPayloads.Inc("success")
+	tlmBytes.Add(float64(len(payload)))
+	return false, nil
+}
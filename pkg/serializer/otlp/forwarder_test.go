@@ -0,0 +1,129 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func testMetrics() pmetric.Metrics {
+	return SeriesToMetrics([]*metrics.Serie{{
+		Name:   "gauge",
+		Points: []metrics.Point{{Ts: 1000, Value: 1}},
+		MType:  metrics.APIGaugeType,
+	}})
+}
+
+func TestForwarderSend(t *testing.T) {
+	gzip, err := compression.NewCompressor(compression.GzipKind)
+	require.NoError(t, err)
+
+	var received pmetric.Metrics
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/metrics", r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, "secret", r.Header.Get("X-Auth"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err = gzip.Decompress(body)
+		require.NoError(t, err)
+		req := pmetricotlp.NewRequest()
+		require.NoError(t, req.UnmarshalProto(body))
+		received = req.Metrics()
+	}))
+	defer ts.Close()
+
+	f := NewForwarder(ts.URL, map[string]string{"X-Auth": "secret"}, gzip, time.Second, 1)
+	require.NoError(t, f.Send(context.Background(), testMetrics()))
+	require.Equal(t, 1, received.DataPointCount())
+	assert.Equal(t, "gauge", received.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(0).Name())
+}
+
+func TestForwarderSendError(t *testing.T) {
+	none, err := compression.NewCompressor(compression.NoneKind)
+	require.NoError(t, err)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/custom/v1/metrics", r.URL.Path)
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	f := NewForwarder(ts.URL+"/custom/v1/metrics", nil, none, time.Second, 1)
+	assert.Error(t, f.Send(context.Background(), testMetrics()))
+
+	// Empty payloads are not sent
+	assert.NoError(t, f.Send(context.Background(), pmetric.NewMetrics()))
+}
+
+func TestForwarderSendAsyncRetry(t *testing.T) {
+	defer func(old time.Duration) { retryBackoff = old }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the collector is unavailable for the first two requests, then rejects the payloads
+		switch atomic.AddInt32(&requests, 1) {
+		case 1, 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer ts.Close()
+	none, err := compression.NewCompressor(compression.NoneKind)
+	require.NoError(t, err)
+
+	f := NewForwarder(ts.URL, nil, none, time.Second, 2)
+	f.Start()
+	f.SendAsync(testMetrics())
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 3 }, 5*time.Second, time.Millisecond)
+
+	// client errors are not retried
+	f.SendAsync(testMetrics())
+	f.Stop()
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestForwarderSendAsyncQueueFull(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer ts.Close()
+	none, err := compression.NewCompressor(compression.NoneKind)
+	require.NoError(t, err)
+
+	// the payloads are queued until the forwarder is started, the ones above the queue size are dropped
+	f := NewForwarder(ts.URL, nil, none, time.Second, 2)
+	for i := 0; i < 3; i++ {
+		f.SendAsync(testMetrics())
+	}
+	f.SendAsync(pmetric.NewMetrics())
+	assert.Len(t, f.payloads, 2)
+
+	// the queued payloads are sent on stop
+	f.Start()
+	f.Stop()
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
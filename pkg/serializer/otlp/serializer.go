@@ -0,0 +1,117 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package otlp
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+// maxPayloadDataPoints is the number of data points after which the series being converted
+// are queued as a payload, bounding the size of the payloads and the memory they use.
+var maxPayloadDataPoints = 10000
+
+// Serializer is a serializer.MetricSerializer sending the series and the sketches to an
+// OTLP collector. The other payloads are sent by the wrapped Datadog serializer, as well
+// as the series and the sketches when sendToDatadog is true.
+type Serializer struct {
+	serializer.MetricSerializer
+	forwarder     *Forwarder
+	sendToDatadog bool
+}
+
+// NewSerializer returns a new Serializer wrapping the datadog serializer.
+func NewSerializer(datadog serializer.MetricSerializer, forwarder *Forwarder, sendToDatadog bool) *Serializer {
+	return &Serializer{
+		MetricSerializer: datadog,
+		forwarder:        forwarder,
+		sendToDatadog:    sendToDatadog,
+	}
+}
+
+// NewSerializerFromConfig returns a new Serializer wrapping the datadog serializer,
+// configured with the `otlp_metrics_exporter` settings. Its forwarder must be started.
+func NewSerializerFromConfig(datadog serializer.MetricSerializer) (*Serializer, error) {
+	kind := config.Datadog.GetString("otlp_metrics_exporter.compression")
+	if kind != compression.GzipKind && kind != compression.NoneKind {
+		return nil, fmt.Errorf("unsupported otlp_metrics_exporter.compression %q: OTLP/HTTP supports %q and %q", kind, compression.GzipKind, compression.NoneKind)
+	}
+	compressor, err := compression.NewCompressor(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	forwarder := NewForwarder(
+		config.Datadog.GetString("otlp_metrics_exporter.endpoint"),
+		config.Datadog.GetStringMapString("otlp_metrics_exporter.headers"),
+		compressor,
+		config.Datadog.GetDuration("otlp_metrics_exporter.timeout")*time.Second,
+		config.Datadog.GetInt("otlp_metrics_exporter.queue_size"))
+	return NewSerializer(datadog, forwarder, config.Datadog.GetBool("otlp_metrics_exporter.send_to_datadog")), nil
+}
+
+// Forwarder returns the forwarder sending the metrics to the OTLP collector.
+func (s *Serializer) Forwarder() *Forwarder {
+	return s.forwarder
+}
+
+// convertingSerieSource is a metrics.SerieSource converting the series to OTLP metrics
+// as they are iterated over.
+type convertingSerieSource struct {
+	metrics.SerieSource
+	convert func(*metrics.Serie)
+}
+
+func (s *convertingSerieSource) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		return false
+	}
+	s.convert(s.SerieSource.Current())
+	return true
+}
+
+// SendIterableSeries queues the series to be sent to the OTLP collector. The series are
+// converted as they are iterated over, by the Datadog serializer when they are also sent to
+// Datadog. The errors of the OTLP collector are logged and only the Datadog error is returned.
+func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
+	b := newMetricsBuilder()
+	source := &convertingSerieSource{
+		SerieSource: serieSource,
+		convert: func(serie *metrics.Serie) {
+			b.appendSerie(serie)
+			if b.dataPoints >= maxPayloadDataPoints {
+				s.forwarder.SendAsync(b.metrics)
+				b = newMetricsBuilder()
+			}
+		},
+	}
+
+	var datadogErr error
+	if s.sendToDatadog {
+		datadogErr = s.MetricSerializer.SendIterableSeries(source)
+	}
+	for source.MoveNext() {
+		// converts the series which were not iterated over by the Datadog serializer
+	}
+	s.forwarder.SendAsync(b.metrics)
+	return datadogErr
+}
+
+// SendSketch queues the sketches to be sent to the OTLP collector. The errors of the OTLP
+// collector are logged and only the Datadog error is returned.
+func (s *Serializer) SendSketch(sketches metrics.SketchSeriesList) error {
+	var datadogErr error
+	if s.sendToDatadog {
+		datadogErr = s.MetricSerializer.SendSketch(sketches)
+	}
+	s.forwarder.SendAsync(SketchesToMetrics(sketches))
+	return datadogErr
+}
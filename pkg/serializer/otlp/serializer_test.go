@@ -0,0 +1,133 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package otlp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/quantile"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+func TestSerializerSendIterableSeries(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer ts.Close()
+	none, _ := compression.NewCompressor(compression.NoneKind)
+	forwarder := NewForwarder(ts.URL, nil, none, time.Second, 10)
+	forwarder.Start()
+	defer forwarder.Stop()
+
+	series := []*metrics.Serie{
+		{Name: "a", Points: []metrics.Point{{Ts: 1000, Value: 1}}, MType: metrics.APIGaugeType},
+		{Name: "b", Points: []metrics.Point{{Ts: 1000, Value: 2}}, MType: metrics.APIGaugeType},
+	}
+
+	datadog := &serializer.MockSerializer{}
+	datadog.On("SendIterableSeries", mock.MatchedBy(func(source metrics.SerieSource) bool {
+		var names []string
+		for source.MoveNext() {
+			names = append(names, source.Current().Name)
+		}
+		return assert.ObjectsAreEqual([]string{"a", "b"}, names)
+	})).Return(nil).Once()
+
+	s := NewSerializer(datadog, forwarder, true)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	datadog.AssertExpectations(t)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 1 }, 5*time.Second, time.Millisecond)
+
+	// Only sent to the OTLP collector
+	s = NewSerializer(datadog, forwarder, false)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	datadog.AssertNumberOfCalls(t, "SendIterableSeries", 1)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&requests) == 2 }, 5*time.Second, time.Millisecond)
+}
+
+func TestSerializerSendIterableSeriesPayloads(t *testing.T) {
+	defer func(old int) { maxPayloadDataPoints = old }(maxPayloadDataPoints)
+	maxPayloadDataPoints = 2
+
+	none, _ := compression.NewCompressor(compression.NoneKind)
+	forwarder := NewForwarder("http://localhost", nil, none, time.Second, 10)
+
+	var series []*metrics.Serie
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		series = append(series, &metrics.Serie{Name: name, Points: []metrics.Point{{Ts: 1000, Value: 1}}, MType: metrics.APIGaugeType})
+	}
+
+	// The series which are not iterated over by the Datadog serializer are still converted
+	datadog := &serializer.MockSerializer{}
+	datadog.On("SendIterableSeries", mock.MatchedBy(func(source metrics.SerieSource) bool {
+		return source.MoveNext()
+	})).Return(errors.New("datadog error")).Once()
+
+	s := NewSerializer(datadog, forwarder, true)
+	assert.Error(t, s.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	require.Len(t, forwarder.payloads, 3)
+	for _, count := range []int{2, 2, 1} {
+		assert.Equal(t, count, (<-forwarder.payloads).DataPointCount())
+	}
+}
+
+func TestSerializerSendSketchCollectorDown(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	none, _ := compression.NewCompressor(compression.NoneKind)
+
+	datadog := &serializer.MockSerializer{}
+	datadog.On("SendSketch", mock.Anything).Return(nil).Once()
+
+	sketches := metrics.SketchSeriesList{{Name: "distribution", Points: []metrics.SketchPoint{{Sketch: nil, Ts: 1000}}}}
+	s := NewSerializer(datadog, NewForwarder(ts.URL, nil, none, time.Second, 10), true)
+	assert.NoError(t, s.SendSketch(sketches), "the sketch has no data point: nothing is sent")
+
+	var a quantile.Agent
+	a.Insert(1, 1)
+	sketches[0].Points[0].Sketch = a.Finish()
+	datadog.On("SendSketch", mock.Anything).Return(nil).Once()
+	assert.NoError(t, s.SendSketch(sketches), "the OTLP errors are only logged")
+	datadog.AssertNumberOfCalls(t, "SendSketch", 2)
+
+	datadogErr := errors.New("datadog error")
+	datadog.On("SendSketch", mock.Anything).Return(datadogErr).Once()
+	assert.Equal(t, datadogErr, s.SendSketch(sketches))
+}
+
+func TestNewSerializerFromConfig(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("otlp_metrics_exporter.compression", "zlib")
+	_, err := NewSerializerFromConfig(&serializer.MockSerializer{})
+	assert.Error(t, err)
+
+	mockConfig.Set("otlp_metrics_exporter.compression", "gzip")
+	mockConfig.Set("otlp_metrics_exporter.send_to_datadog", false)
+	s, err := NewSerializerFromConfig(&serializer.MockSerializer{})
+	require.NoError(t, err)
+	assert.False(t, s.sendToDatadog)
+	assert.Equal(t, "http://localhost:4318/v1/metrics", s.forwarder.url)
+	assert.Equal(t, "gzip", s.forwarder.headers.Get("Content-Encoding"))
+}
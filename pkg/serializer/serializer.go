@@ -21,6 +21,7 @@ import (
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 	"github.com/DataDog/datadog-agent/pkg/serializer/split"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -41,10 +42,8 @@ var (
 	// used to serialize to protobuf
 	AgentPayloadVersion string
 
-	jsonExtraHeaders                    http.Header
-	protobufExtraHeaders                http.Header
-	jsonExtraHeadersWithCompression     http.Header
-	protobufExtraHeadersWithCompression http.Header
+	jsonExtraHeaders     http.Header
+	protobufExtraHeaders http.Header
 
 	expvars                                 = expvar.NewMap("serializer")
 	expvarsSendEventsErrItemTooBigs         = expvar.Int{}
@@ -63,24 +62,46 @@ func initExtraHeaders() {
 	jsonExtraHeaders = make(http.Header)
 	jsonExtraHeaders.Set("Content-Type", jsonContentType)
 
-	jsonExtraHeadersWithCompression = make(http.Header)
-	for k := range jsonExtraHeaders {
-		jsonExtraHeadersWithCompression.Set(k, jsonExtraHeaders.Get(k))
-	}
-
 	protobufExtraHeaders = make(http.Header)
 	protobufExtraHeaders.Set("Content-Type", protobufContentType)
 	protobufExtraHeaders.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
+}
+
+// payloadCompression holds the compressor used for a kind of payload and the extra headers
+// to send with the payloads it compresses.
+type payloadCompression struct {
+	compressor      compression.Compressor
+	jsonHeaders     http.Header
+	protobufHeaders http.Header
+}
 
-	protobufExtraHeadersWithCompression = make(http.Header)
-	for k := range protobufExtraHeaders {
-		protobufExtraHeadersWithCompression.Set(k, protobufExtraHeaders.Get(k))
+func newPayloadCompression(compressor compression.Compressor) *payloadCompression {
+	c := &payloadCompression{
+		compressor:      compressor,
+		jsonHeaders:     jsonExtraHeaders.Clone(),
+		protobufHeaders: protobufExtraHeaders.Clone(),
 	}
+	if encoding := compressor.ContentEncoding(); encoding != "" {
+		c.jsonHeaders.Set("Content-Encoding", encoding)
+		c.protobufHeaders.Set("Content-Encoding", encoding)
+	}
+	return c
+}
 
-	if compression.ContentEncoding != "" {
-		jsonExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
-		protobufExtraHeadersWithCompression.Set("Content-Encoding", compression.ContentEncoding)
+// payloadCompressionFromConfig returns the compression configured for a kind of payload:
+// `serializer_compressor_kinds.<payloadKind>` if set, `serializer_compressor_kind` otherwise.
+// It falls back to zlib if the configured compression is not available.
+func payloadCompressionFromConfig(payloadKind string) *payloadCompression {
+	kind := config.Datadog.GetString("serializer_compressor_kinds." + payloadKind)
+	if kind == "" {
+		kind = config.Datadog.GetString("serializer_compressor_kind")
 	}
+	compressor, err := compression.NewCompressor(kind)
+	if err != nil {
+		log.Errorf("Invalid compression for %s payloads, using %s instead: %v", payloadKind, compression.ZlibKind, err)
+		compressor, _ = compression.NewCompressor(compression.ZlibKind)
+	}
+	return newPayloadCompression(compressor)
 }
 
 // MetricSerializer represents the interface of method needed by the aggregator to serialize its data
@@ -105,6 +126,17 @@ type Serializer struct {
 
 	seriesJSONPayloadBuilder *stream.JSONPayloadBuilder
 
+	// Compression used for each kind of payload
+	eventsCompression        *payloadCompression
+	serviceChecksCompression *payloadCompression
+	seriesCompression        *payloadCompression
+	sketchesCompression      *payloadCompression
+	metadataCompression      *payloadCompression
+
+	// sizeReport attributes the bytes of the payloads to their checks and metric
+	// prefixes, nil unless `serializer_size_report.enabled` is true.
+	sizeReport *sizereport.Recorder
+
 	// Those variables allow users to blacklist any kind of payload
 	// from being sent by the agent. This was introduced for
 	// environment where, for example, events or serviceChecks
@@ -129,15 +161,21 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder, contlcy
 		orchestratorForwarder:         orchestratorForwarder,
 		contlcycleForwarder:           contlcycleForwarder,
 		seriesJSONPayloadBuilder:      stream.NewJSONPayloadBuilder(config.Datadog.GetBool("enable_json_stream_shared_compressor_buffers")),
+		eventsCompression:             payloadCompressionFromConfig("events"),
+		serviceChecksCompression:      payloadCompressionFromConfig("service_checks"),
+		seriesCompression:             payloadCompressionFromConfig("series"),
+		sketchesCompression:           payloadCompressionFromConfig("sketches"),
+		metadataCompression:           payloadCompressionFromConfig("metadata"),
+		sizeReport:                    sizereport.Get(),
 		enableEvents:                  config.Datadog.GetBool("enable_payloads.events"),
 		enableSeries:                  config.Datadog.GetBool("enable_payloads.series"),
 		enableServiceChecks:           config.Datadog.GetBool("enable_payloads.service_checks"),
 		enableSketches:                config.Datadog.GetBool("enable_payloads.sketches"),
 		enableJSONToV1Intake:          config.Datadog.GetBool("enable_payloads.json_to_v1_intake"),
-		enableJSONStream:              stream.Available && config.Datadog.GetBool("enable_stream_payload_serialization"),
-		enableServiceChecksJSONStream: stream.Available && config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
-		enableEventsJSONStream:        stream.Available && config.Datadog.GetBool("enable_events_stream_payload_serialization"),
-		enableSketchProtobufStream:    stream.Available && config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
+		enableJSONStream:              config.Datadog.GetBool("enable_stream_payload_serialization"),
+		enableServiceChecksJSONStream: config.Datadog.GetBool("enable_service_checks_stream_payload_serialization"),
+		enableEventsJSONStream:        config.Datadog.GetBool("enable_events_stream_payload_serialization"),
+		enableSketchProtobufStream:    config.Datadog.GetBool("enable_sketch_stream_payload_serialization"),
 	}
 
 	if !s.enableEvents {
@@ -162,38 +200,24 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder, contlcy
 func (s Serializer) serializePayload(
 	jsonMarshaler marshaler.JSONMarshaler,
 	protoMarshaler marshaler.ProtoMarshaler,
-	compress bool,
+	pc *payloadCompression,
 	useV1API bool) (forwarder.Payloads, http.Header, error) {
 	if useV1API {
-		return s.serializePayloadJSON(jsonMarshaler, compress)
+		return s.serializePayloadJSON(jsonMarshaler, pc)
 	}
-	return s.serializePayloadProto(protoMarshaler, compress)
+	return s.serializePayloadProto(protoMarshaler, pc)
 }
 
-func (s Serializer) serializePayloadJSON(payload marshaler.JSONMarshaler, compress bool) (forwarder.Payloads, http.Header, error) {
-	var extraHeaders http.Header
-
-	if compress {
-		extraHeaders = jsonExtraHeadersWithCompression
-	} else {
-		extraHeaders = jsonExtraHeaders
-	}
-
-	return s.serializePayloadInternal(payload, compress, extraHeaders, split.JSONMarshalFct)
+func (s Serializer) serializePayloadJSON(payload marshaler.JSONMarshaler, pc *payloadCompression) (forwarder.Payloads, http.Header, error) {
+	return s.serializePayloadInternal(payload, pc.compressor, pc.jsonHeaders, split.JSONMarshalFct)
 }
 
-func (s Serializer) serializePayloadProto(payload marshaler.ProtoMarshaler, compress bool) (forwarder.Payloads, http.Header, error) {
-	var extraHeaders http.Header
-	if compress {
-		extraHeaders = protobufExtraHeadersWithCompression
-	} else {
-		extraHeaders = protobufExtraHeaders
-	}
-	return s.serializePayloadInternal(payload, compress, extraHeaders, split.ProtoMarshalFct)
+func (s Serializer) serializePayloadProto(payload marshaler.ProtoMarshaler, pc *payloadCompression) (forwarder.Payloads, http.Header, error) {
+	return s.serializePayloadInternal(payload, pc.compressor, pc.protobufHeaders, split.ProtoMarshalFct)
 }
 
-func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler, compress bool, extraHeaders http.Header, marshalFct split.MarshalFct) (forwarder.Payloads, http.Header, error) {
-	payloads, err := split.Payloads(payload, compress, marshalFct)
+func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler, compressor compression.Compressor, extraHeaders http.Header, marshalFct split.MarshalFct) (forwarder.Payloads, http.Header, error) {
+	payloads, err := split.Payloads(payload, compressor, marshalFct)
 
 	if err != nil {
 		return nil, nil, fmt.Errorf("could not split payload into small enough chunks: %s", err)
@@ -202,14 +226,14 @@ func (s Serializer) serializePayloadInternal(payload marshaler.AbstractMarshaler
 	return payloads, extraHeaders, nil
 }
 
-func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
+func (s Serializer) serializeStreamablePayload(payload marshaler.StreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy, pc *payloadCompression, payloadType string) (forwarder.Payloads, http.Header, error) {
 	adapter := marshaler.NewIterableStreamJSONMarshalerAdapter(payload)
-	return s.serializeIterableStreamablePayload(adapter, policy)
+	return s.serializeIterableStreamablePayload(adapter, policy, pc, payloadType)
 }
 
-func (s Serializer) serializeIterableStreamablePayload(payload marshaler.IterableStreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy) (forwarder.Payloads, http.Header, error) {
-	payloads, err := s.seriesJSONPayloadBuilder.BuildWithOnErrItemTooBigPolicy(payload, policy)
-	return payloads, jsonExtraHeadersWithCompression, err
+func (s Serializer) serializeIterableStreamablePayload(payload marshaler.IterableStreamJSONMarshaler, policy stream.OnErrItemTooBigPolicy, pc *payloadCompression, payloadType string) (forwarder.Payloads, http.Header, error) {
+	payloads, err := s.seriesJSONPayloadBuilder.BuildWithSizeReport(payload, policy, pc.compressor, s.sizeReport.NewPayload(payloadType))
+	return payloads, pc.jsonHeaders, err
 }
 
 // As events are gathered by SourceType, the serialization logic is more complex than for the other serializations.
@@ -224,7 +248,7 @@ func (s Serializer) serializeIterableStreamablePayload(payload marshaler.Iterabl
 func (s Serializer) serializeEventsStreamJSONMarshalerPayload(
 	eventsSerializer metricsserializer.Events, useV1API bool) (forwarder.Payloads, http.Header, error) {
 	marshaler := eventsSerializer.CreateSingleMarshaler()
-	eventPayloads, extraHeaders, err := s.serializeStreamablePayload(marshaler, stream.FailOnErrItemTooBig)
+	eventPayloads, extraHeaders, err := s.serializeStreamablePayload(marshaler, stream.FailOnErrItemTooBig, s.eventsCompression, "events")
 
 	if err == stream.ErrItemTooBig {
 		expvarsSendEventsErrItemTooBigs.Add(1)
@@ -232,12 +256,12 @@ func (s Serializer) serializeEventsStreamJSONMarshalerPayload(
 		// Do not use CreateMarshalersBySourceType when there are too many source types (Performance issue).
 		if marshaler.Len() > maxItemCountForCreateMarshalersBySourceType {
 			expvarsSendEventsErrItemTooBigsFallback.Add(1)
-			eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, true, useV1API)
+			eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, s.eventsCompression, useV1API)
 		} else {
 			eventPayloads = nil
 			for _, v := range eventsSerializer.CreateMarshalersBySourceType() {
 				var eventPayloadsForSourceType forwarder.Payloads
-				eventPayloadsForSourceType, extraHeaders, err = s.serializeStreamablePayload(v, stream.DropItemOnErrItemTooBig)
+				eventPayloadsForSourceType, extraHeaders, err = s.serializeStreamablePayload(v, stream.DropItemOnErrItemTooBig, s.eventsCompression, "events")
 				if err != nil {
 					return nil, nil, err
 				}
@@ -263,7 +287,7 @@ func (s *Serializer) SendEvents(events metrics.Events) error {
 	if s.enableEventsJSONStream {
 		eventPayloads, extraHeaders, err = s.serializeEventsStreamJSONMarshalerPayload(eventsSerializer, true)
 	} else {
-		eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, true, true)
+		eventPayloads, extraHeaders, err = s.serializePayload(eventsSerializer, eventsSerializer, s.eventsCompression, true)
 	}
 	if err != nil {
 		return fmt.Errorf("dropping event payload: %s", err)
@@ -285,9 +309,9 @@ func (s *Serializer) SendServiceChecks(serviceChecks metrics.ServiceChecks) erro
 	var err error
 
 	if s.enableServiceChecksJSONStream {
-		serviceCheckPayloads, extraHeaders, err = s.serializeStreamablePayload(serviceChecksSerializer, stream.DropItemOnErrItemTooBig)
+		serviceCheckPayloads, extraHeaders, err = s.serializeStreamablePayload(serviceChecksSerializer, stream.DropItemOnErrItemTooBig, s.serviceChecksCompression, "service_checks")
 	} else {
-		serviceCheckPayloads, extraHeaders, err = s.serializePayloadJSON(serviceChecksSerializer, true)
+		serviceCheckPayloads, extraHeaders, err = s.serializePayloadJSON(serviceChecksSerializer, s.serviceChecksCompression)
 	}
 	if err != nil {
 		return fmt.Errorf("dropping service check payload: %s", err)
@@ -311,12 +335,14 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 	var err error
 
 	if useV1API && s.enableJSONStream {
-		seriesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig)
+		seriesPayloads, extraHeaders, err = s.serializeIterableStreamablePayload(seriesSerializer, stream.DropItemOnErrItemTooBig, s.seriesCompression, "series")
 	} else if useV1API && !s.enableJSONStream {
-		seriesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, true)
+		seriesPayloads, extraHeaders, err = s.serializePayloadJSON(seriesSerializer, s.seriesCompression)
 	} else {
-		seriesPayloads, err = seriesSerializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
-		extraHeaders = protobufExtraHeadersWithCompression
+		bufferContext := marshaler.DefaultBufferContext()
+		bufferContext.SizeReport = s.sizeReport.NewPayload("series")
+		seriesPayloads, err = seriesSerializer.MarshalSplitCompress(bufferContext, s.seriesCompression.compressor)
+		extraHeaders = s.seriesCompression.protobufHeaders
 	}
 
 	if err != nil {
@@ -337,16 +363,17 @@ func (s *Serializer) SendSketch(sketches metrics.SketchSeriesList) error {
 	}
 	sketchesSerializer := metricsserializer.SketchSeriesList(sketches)
 	if s.enableSketchProtobufStream {
-		payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.DefaultBufferContext())
+		bufferContext := marshaler.DefaultBufferContext()
+		bufferContext.SizeReport = s.sizeReport.NewPayload("sketches")
+		payloads, err := sketchesSerializer.MarshalSplitCompress(bufferContext, s.sketchesCompression.compressor)
 		if err == nil {
-			return s.Forwarder.SubmitSketchSeries(payloads, protobufExtraHeadersWithCompression)
+			return s.Forwarder.SubmitSketchSeries(payloads, s.sketchesCompression.protobufHeaders)
 		}
 		log.Warnf("Error: %v trying to stream compress SketchSeriesList - falling back to split/compress method", err)
 	}
 
-	compress := true
 	useV1API := false // Sketches only have a v2 endpoint
-	splitSketches, extraHeaders, err := s.serializePayload(sketchesSerializer, sketchesSerializer, compress, useV1API)
+	splitSketches, extraHeaders, err := s.serializePayload(sketchesSerializer, sketchesSerializer, s.sketchesCompression, useV1API)
 	if err != nil {
 		return fmt.Errorf("dropping sketch payload: %s", err)
 	}
@@ -356,21 +383,21 @@ func (s *Serializer) SendSketch(sketches metrics.SketchSeriesList) error {
 
 // SendMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitMetadata)
+	return s.sendMetadata(m, s.Forwarder.SubmitMetadata, "metadata")
 }
 
 // SendHostMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendHostMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitHostMetadata)
+	return s.sendMetadata(m, s.Forwarder.SubmitHostMetadata, "host_metadata")
 }
 
 // SendAgentchecksMetadata serializes a metadata payload and sends it to the forwarder
 func (s *Serializer) SendAgentchecksMetadata(m marshaler.JSONMarshaler) error {
-	return s.sendMetadata(m, s.Forwarder.SubmitAgentChecksMetadata)
+	return s.sendMetadata(m, s.Forwarder.SubmitAgentChecksMetadata, "agentchecks_metadata")
 }
 
-func (s *Serializer) sendMetadata(m marshaler.JSONMarshaler, submit func(payload forwarder.Payloads, extra http.Header) error) error {
-	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, true, split.JSONMarshalFct)
+func (s *Serializer) sendMetadata(m marshaler.JSONMarshaler, submit func(payload forwarder.Payloads, extra http.Header) error, payloadType string) error {
+	mustSplit, compressedPayload, payload, err := split.CheckSizeAndSerialize(m, s.metadataCompression.compressor, split.JSONMarshalFct)
 	if err != nil {
 		return fmt.Errorf("could not determine size of metadata payload: %s", err)
 	}
@@ -381,9 +408,10 @@ func (s *Serializer) sendMetadata(m marshaler.JSONMarshaler, submit func(payload
 		return fmt.Errorf("metadata payload was too big to send (%d bytes compressed, %d bytes uncompressed), metadata payloads cannot be split", len(compressedPayload), len(payload))
 	}
 
-	if err := submit(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
+	if err := submit(forwarder.Payloads{&compressedPayload}, s.metadataCompression.jsonHeaders); err != nil {
 		return err
 	}
+	s.sizeReport.AddPayload(payloadType, len(payload), len(compressedPayload))
 
 	log.Infof("Sent metadata payload, size (raw/compressed): %d/%d bytes.", len(payload), len(compressedPayload))
 	return nil
@@ -401,11 +429,11 @@ func (s *Serializer) SendProcessesMetadata(data interface{}) error {
 	if err != nil {
 		return fmt.Errorf("could not serialize processes metadata payload: %s", err)
 	}
-	compressedPayload, err := compression.Compress(payload)
+	compressedPayload, err := s.metadataCompression.compressor.Compress(payload)
 	if err != nil {
 		return fmt.Errorf("could not compress processes metadata payload: %s", err)
 	}
-	if err := s.Forwarder.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, jsonExtraHeadersWithCompression); err != nil {
+	if err := s.Forwarder.SubmitV1Intake(forwarder.Payloads{&compressedPayload}, s.metadataCompression.jsonHeaders); err != nil {
 		return err
 	}
 
@@ -14,6 +14,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/stretchr/testify/assert"
@@ -25,20 +26,18 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/serializer/sizereport"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
-var initialContentEncoding = compression.ContentEncoding
+var zlibCompressor, _ = compression.NewCompressor(compression.ZlibKind)
 
-func resetContentEncoding() {
-	compression.ContentEncoding = initialContentEncoding
-	initExtraHeaders()
+// defaultCompression returns the compression used by default for all payloads
+func defaultCompression() *payloadCompression {
+	return newPayloadCompression(zlibCompressor)
 }
 
-func TestInitExtraHeadersNoopCompression(t *testing.T) {
-	compression.ContentEncoding = ""
-	defer resetContentEncoding()
-
+func TestInitExtraHeaders(t *testing.T) {
 	initExtraHeaders()
 
 	expected := make(http.Header)
@@ -49,44 +48,61 @@ func TestInitExtraHeadersNoopCompression(t *testing.T) {
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
 	expected.Set("Content-Type", protobufContentType)
 	assert.Equal(t, expected, protobufExtraHeaders)
+}
+
+func TestNewPayloadCompressionNoopCompression(t *testing.T) {
+	compressor, err := compression.NewCompressor(compression.NoneKind)
+	require.NoError(t, err)
+	c := newPayloadCompression(compressor)
 
 	// No "Content-Encoding" header
-	expected = make(http.Header)
+	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	assert.Equal(t, expected, c.jsonHeaders)
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+	assert.Equal(t, expected, c.protobufHeaders)
 }
 
-func TestInitExtraHeadersWithCompression(t *testing.T) {
-	compression.ContentEncoding = "zstd"
-	defer resetContentEncoding()
-
-	initExtraHeaders()
+func TestNewPayloadCompressionWithCompression(t *testing.T) {
+	compressor, err := compression.NewCompressor(compression.GzipKind)
+	require.NoError(t, err)
+	c := newPayloadCompression(compressor)
 
+	// "Content-Encoding" header present with correct value
 	expected := make(http.Header)
 	expected.Set("Content-Type", jsonContentType)
-	assert.Equal(t, expected, jsonExtraHeaders)
+	expected.Set("Content-Encoding", "gzip")
+	assert.Equal(t, expected, c.jsonHeaders)
 
 	expected = make(http.Header)
 	expected.Set("Content-Type", protobufContentType)
+	expected.Set("Content-Encoding", "gzip")
 	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeaders)
+	assert.Equal(t, expected, c.protobufHeaders)
 
-	// "Content-Encoding" header present with correct value
-	expected = make(http.Header)
-	expected.Set("Content-Type", jsonContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	assert.Equal(t, expected, jsonExtraHeadersWithCompression)
+	// The headers without compression are left untouched
+	assert.Empty(t, jsonExtraHeaders.Get("Content-Encoding"))
+	assert.Empty(t, protobufExtraHeaders.Get("Content-Encoding"))
+}
 
-	expected = make(http.Header)
-	expected.Set("Content-Type", protobufContentType)
-	expected.Set("Content-Encoding", compression.ContentEncoding)
-	expected.Set(payloadVersionHTTPHeader, AgentPayloadVersion)
-	assert.Equal(t, expected, protobufExtraHeadersWithCompression)
+func TestPayloadCompressionFromConfig(t *testing.T) {
+	mockConfig := config.Mock()
+	defer mockConfig.Set("serializer_compressor_kind", compression.ZlibKind)
+	defer mockConfig.Set("serializer_compressor_kinds.series", "")
+	defer mockConfig.Set("serializer_compressor_kinds.metadata", "")
+
+	assert.Equal(t, "deflate", payloadCompressionFromConfig("series").compressor.ContentEncoding())
+
+	mockConfig.Set("serializer_compressor_kind", compression.GzipKind)
+	mockConfig.Set("serializer_compressor_kinds.series", compression.NoneKind)
+	mockConfig.Set("serializer_compressor_kinds.metadata", "unknown")
+	assert.Equal(t, "gzip", payloadCompressionFromConfig("events").compressor.ContentEncoding())
+	assert.Equal(t, "", payloadCompressionFromConfig("series").compressor.ContentEncoding())
+	// Falls back to zlib when the compression is not available
+	assert.Equal(t, "deflate", payloadCompressionFromConfig("metadata").compressor.ContentEncoding())
 }
 
 func TestAgentPayloadVersion(t *testing.T) {
@@ -112,9 +128,9 @@ type testPayload struct{}
 
 func (p *testPayload) MarshalJSON() ([]byte, error) { return jsonString, nil }
 func (p *testPayload) Marshal() ([]byte, error)     { return protobufString, nil }
-func (p *testPayload) MarshalSplitCompress(bufferContext *marshaler.BufferContext) ([]*[]byte, error) {
+func (p *testPayload) MarshalSplitCompress(bufferContext *marshaler.BufferContext, compressor compression.Compressor) ([]*[]byte, error) {
 	payloads := forwarder.Payloads{}
-	payload, err := compression.Compress(protobufString)
+	payload, err := compressor.Compress(protobufString)
 	if err != nil {
 		return nil, err
 	}
@@ -166,7 +182,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 	payloads := forwarder.Payloads{}
 	var err error
 	if compress {
-		payload, err = compression.Compress(payload)
+		payload, err = zlibCompressor.Compress(payload)
 		if err != nil {
 			return nil, err
 		}
@@ -178,7 +194,7 @@ func mkPayloads(payload []byte, compress bool) (forwarder.Payloads, error) {
 func createJSONPayloadMatcher(prefix string) interface{} {
 	return mock.MatchedBy(func(payloads forwarder.Payloads) bool {
 		for _, compressedPayload := range payloads {
-			if payload, err := compression.Decompress(*compressedPayload); err != nil {
+			if payload, err := zlibCompressor.Decompress(*compressedPayload); err != nil {
 				return false
 			} else {
 				if strings.HasPrefix(string(payload), prefix) {
@@ -193,7 +209,7 @@ func createJSONPayloadMatcher(prefix string) interface{} {
 func createProtoPayloadMatcher(content []byte) interface{} {
 	return mock.MatchedBy(func(payloads forwarder.Payloads) bool {
 		for _, compressedPayload := range payloads {
-			if payload, err := compression.Decompress(*compressedPayload); err != nil {
+			if payload, err := zlibCompressor.Decompress(*compressedPayload); err != nil {
 				return false
 			} else {
 				if reflect.DeepEqual(content, payload) {
@@ -211,7 +227,7 @@ func TestSendV1Events(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 
 	matcher := createJSONPayloadMatcher(`{"apiKey":"","events":{},"internalHostname"`)
-	f.On("SubmitV1Intake", matcher, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1Intake", matcher, defaultCompression().jsonHeaders).Return(nil).Times(1)
 
 	s := NewSerializer(f, nil, nil)
 	err := s.SendEvents([]*metrics.Event{})
@@ -240,7 +256,7 @@ func TestSendV1EventsCreateMarshalersBySourceType(t *testing.T) {
 		})
 	}
 
-	f.On("SubmitV1Intake", payloadsCountMatcher(1), jsonExtraHeadersWithCompression).Return(nil)
+	f.On("SubmitV1Intake", payloadsCountMatcher(1), defaultCompression().jsonHeaders).Return(nil)
 	err := s.SendEvents(events)
 	assert.NoError(t, err)
 	f.AssertExpectations(t)
@@ -248,7 +264,7 @@ func TestSendV1EventsCreateMarshalersBySourceType(t *testing.T) {
 	config.Datadog.Set("serializer_max_payload_size", 20)
 	defer config.Datadog.Set("serializer_max_payload_size", nil)
 
-	f.On("SubmitV1Intake", payloadsCountMatcher(3), jsonExtraHeadersWithCompression).Return(nil)
+	f.On("SubmitV1Intake", payloadsCountMatcher(3), defaultCompression().jsonHeaders).Return(nil)
 	err = s.SendEvents(events)
 	assert.NoError(t, err)
 	f.AssertExpectations(t)
@@ -257,7 +273,7 @@ func TestSendV1EventsCreateMarshalersBySourceType(t *testing.T) {
 func TestSendV1ServiceChecks(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	matcher := createJSONPayloadMatcher(`[{"check":"","host_name":"","timestamp":0,"status":0,"message":"","tags":null}]`)
-	f.On("SubmitV1CheckRuns", matcher, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1CheckRuns", matcher, defaultCompression().jsonHeaders).Return(nil).Times(1)
 	config.Datadog.Set("enable_service_checks_stream_payload_serialization", false)
 	defer config.Datadog.Set("enable_service_checks_stream_payload_serialization", nil)
 
@@ -271,7 +287,7 @@ func TestSendV1Series(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	matcher := createJSONPayloadMatcher(`{"series":[]}`)
 
-	f.On("SubmitV1Series", matcher, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1Series", matcher, defaultCompression().jsonHeaders).Return(nil).Times(1)
 	config.Datadog.Set("enable_stream_payload_serialization", false)
 	defer config.Datadog.Set("enable_stream_payload_serialization", nil)
 
@@ -285,7 +301,7 @@ func TestSendV1Series(t *testing.T) {
 func TestSendSeries(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	matcher := createProtoPayloadMatcher([]byte{0xa, 0xa, 0xa, 0x6, 0xa, 0x4, 0x68, 0x6f, 0x73, 0x74, 0x28, 0x3})
-	f.On("SubmitSeries", matcher, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitSeries", matcher, defaultCompression().protobufHeaders).Return(nil).Times(1)
 	config.Datadog.Set("use_v2_api.series", true)
 	defer config.Datadog.Set("use_v2_api.series", false)
 
@@ -296,11 +312,66 @@ func TestSendSeries(t *testing.T) {
 	f.AssertExpectations(t)
 }
 
+func TestSendSeriesWithCompressionOverride(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("use_v2_api.series", true)
+	mockConfig.Set("serializer_compressor_kinds.series", compression.GzipKind)
+	defer mockConfig.Set("use_v2_api.series", false)
+	defer mockConfig.Set("serializer_compressor_kinds.series", "")
+
+	gzipCompressor, err := compression.NewCompressor(compression.GzipKind)
+	require.NoError(t, err)
+	matcher := mock.MatchedBy(func(payloads forwarder.Payloads) bool {
+		payload, err := gzipCompressor.Decompress(*payloads[0])
+		return err == nil && reflect.DeepEqual([]byte{0xa, 0xa, 0xa, 0x6, 0xa, 0x4, 0x68, 0x6f, 0x73, 0x74, 0x28, 0x3}, payload)
+	})
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitSeries", matcher, newPayloadCompression(gzipCompressor).protobufHeaders).Return(nil).Times(1)
+
+	s := NewSerializer(f, nil, nil)
+	err = s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{&metrics.Serie{}}))
+	require.Nil(t, err)
+	f.AssertExpectations(t)
+}
+
+func TestSendSeriesWithSizeReport(t *testing.T) {
+	mockConfig := config.Mock()
+	mockConfig.Set("serializer_size_report.enabled", true)
+	defer mockConfig.Set("serializer_size_report.enabled", false)
+
+	f := &forwarder.MockedForwarder{}
+	f.On("SubmitV1Series", mock.Anything, mock.Anything).Return(nil).Times(1)
+	f.On("SubmitSeries", mock.Anything, mock.Anything).Return(nil).Times(1)
+	series := metrics.Series{
+		{Name: "redis.net.clients", CheckID: "redis:1", Points: []metrics.Point{{Ts: 1000, Value: 1}}, MType: metrics.APIGaugeType},
+		{Name: "app.requests", Points: []metrics.Point{{Ts: 1000, Value: 1}}, MType: metrics.APIGaugeType},
+	}
+
+	sizeReport := sizereport.Get()
+	sizeReport.StartFlush(time.Now())
+	s := NewSerializer(f, nil, nil)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(series)))
+	mockConfig.Set("use_v2_api.series", true)
+	defer mockConfig.Set("use_v2_api.series", false)
+	require.NoError(t, s.SendIterableSeries(metricsserializer.CreateSerieSource(series)))
+	f.AssertExpectations(t)
+
+	sizeReport.StartFlush(time.Now())
+	report := sizeReport.LastFlush()
+	require.Len(t, report.PayloadTypes, 1)
+	assert.Equal(t, "series", report.PayloadTypes[0].Name)
+	assert.Equal(t, int64(2), report.PayloadTypes[0].Items)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "redis:1", report.Checks[0].Name)
+	assert.Equal(t, int64(2), report.Checks[0].Items)
+	require.Len(t, report.MetricPrefixes, 2)
+}
+
 func TestSendSketch(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 
 	matcher := createProtoPayloadMatcher([]byte{18, 0})
-	f.On("SubmitSketchSeries", matcher, protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitSketchSeries", matcher, defaultCompression().protobufHeaders).Return(nil).Times(1)
 
 	s := NewSerializer(f, nil, nil)
 	err := s.SendSketch(metrics.SketchSeriesList{})
@@ -310,7 +381,7 @@ func TestSendSketch(t *testing.T) {
 
 func TestSendMetadata(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
-	f.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitMetadata", jsonPayloads, defaultCompression().jsonHeaders).Return(nil).Times(1)
 
 	s := NewSerializer(f, nil, nil)
 
@@ -319,7 +390,7 @@ func TestSendMetadata(t *testing.T) {
 	require.Nil(t, err)
 	f.AssertExpectations(t)
 
-	f.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(fmt.Errorf("some error")).Times(1)
+	f.On("SubmitMetadata", jsonPayloads, defaultCompression().jsonHeaders).Return(fmt.Errorf("some error")).Times(1)
 	err = s.SendMetadata(payload)
 	require.NotNil(t, err)
 	f.AssertExpectations(t)
@@ -333,7 +404,7 @@ func TestSendProcessesMetadata(t *testing.T) {
 	f := &forwarder.MockedForwarder{}
 	payload := []byte("\"test\"")
 	payloads, _ := mkPayloads(payload, true)
-	f.On("SubmitV1Intake", payloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitV1Intake", payloads, defaultCompression().jsonHeaders).Return(nil).Times(1)
 
 	s := NewSerializer(f, nil, nil)
 
@@ -341,7 +412,7 @@ func TestSendProcessesMetadata(t *testing.T) {
 	require.Nil(t, err)
 	f.AssertExpectations(t)
 
-	f.On("SubmitV1Intake", payloads, jsonExtraHeadersWithCompression).Return(fmt.Errorf("some error")).Times(1)
+	f.On("SubmitV1Intake", payloads, defaultCompression().jsonHeaders).Return(fmt.Errorf("some error")).Times(1)
 	err = s.SendProcessesMetadata("test")
 	require.NotNil(t, err)
 	f.AssertExpectations(t)
@@ -386,7 +457,7 @@ func TestSendWithDisabledKind(t *testing.T) {
 	f.AssertNotCalled(t, "SubmitSketchSeries")
 
 	// We never disable metadata
-	f.On("SubmitMetadata", jsonPayloads, jsonExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitMetadata", jsonPayloads, defaultCompression().jsonHeaders).Return(nil).Times(1)
 	s.SendMetadata(payload)
 	f.AssertNumberOfCalls(t, "SubmitMetadata", 1) // called once for the metadata
 }
@@ -3,8 +3,8 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zlib && test
-// +build zlib,test
+//go:build test
+// +build test
 
 package serializer
 
@@ -18,6 +18,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
 	"github.com/stretchr/testify/require"
 )
 
@@ -74,15 +75,19 @@ func BenchmarkSeries(b *testing.B) {
 		}
 	}
 	bufferContext := marshaler.DefaultBufferContext()
-	pb := func(series metrics.Series) (forwarder.Payloads, error) {
-		iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
-		return iterableSeries.MarshalSplitCompress(bufferContext)
+	pb := func(compressor compression.Compressor) func(series metrics.Series) (forwarder.Payloads, error) {
+		return func(series metrics.Series) (forwarder.Payloads, error) {
+			iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
+			return iterableSeries.MarshalSplitCompress(bufferContext, compressor)
+		}
 	}
 
 	payloadBuilder := stream.NewJSONPayloadBuilder(true)
-	json := func(series metrics.Series) (forwarder.Payloads, error) {
-		iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
-		return payloadBuilder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig)
+	json := func(compressor compression.Compressor) func(series metrics.Series) (forwarder.Payloads, error) {
+		return func(series metrics.Series) (forwarder.Payloads, error) {
+			iterableSeries := &metricsserializer.IterableSeries{SerieSource: metricsserializer.CreateSerieSource(series)}
+			return payloadBuilder.BuildWithOnErrItemTooBigPolicy(iterableSeries, stream.DropItemOnErrItemTooBig, compressor)
+		}
 	}
 
 	for _, items := range []int{5, 10, 100, 500, 1000, 10000, 100000} {
@@ -91,8 +96,11 @@ func BenchmarkSeries(b *testing.B) {
 				b.Run(fmt.Sprintf("%02d-points", points), func(b *testing.B) {
 					for _, tags := range []int{10, 50} {
 						b.Run(fmt.Sprintf("%02d-tags", tags), func(b *testing.B) {
-							b.Run("pb", bench(items, points, tags, pb))
-							b.Run("json", bench(items, points, tags, json))
+							for _, kind := range compression.AvailableKinds() {
+								compressor, _ := compression.NewCompressor(kind)
+								b.Run("pb-"+kind, bench(items, points, tags, pb(compressor)))
+								b.Run("json-"+kind, bench(items, points, tags, json(compressor)))
+							}
 						})
 					}
 				})
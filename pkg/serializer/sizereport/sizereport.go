@@ -0,0 +1,249 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package sizereport attributes the bytes of the payloads sent during a flush to
+// their payload type, to the checks and to the metric prefixes of their items.
+package sizereport
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+var (
+	defaultRecorder     *Recorder
+	defaultRecorderOnce sync.Once
+)
+
+// Get returns the recorder of the agent, or nil when `serializer_size_report.enabled`
+// is false. All the methods of a nil Recorder are no-ops.
+func Get() *Recorder {
+	if !config.Datadog.GetBool("serializer_size_report.enabled") {
+		return nil
+	}
+	defaultRecorderOnce.Do(func() {
+		defaultRecorder = NewRecorder(config.Datadog.GetInt("serializer_size_report.max_entries"))
+	})
+	return defaultRecorder
+}
+
+// Source is where an item of a payload comes from.
+type Source struct {
+	// CheckID is the ID of the check which sent the item, empty if it does not
+	// come from a check, e.g. for DogStatsD metrics.
+	CheckID string
+	// MetricPrefix is the first segment of the name of the metric, its namespace.
+	MetricPrefix string
+}
+
+// MetricSource returns the source of a metric of a check.
+func MetricSource(checkID, name string) Source {
+	prefix := name
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		prefix = name[:i]
+	}
+	return Source{CheckID: checkID, MetricPrefix: prefix}
+}
+
+// Entry holds the bytes attributed to a payload type, a check or a metric prefix.
+type Entry struct {
+	Name string `json:"name"`
+	// Items is the number of items, or the number of payloads for a payload type.
+	Items             int64 `json:"items"`
+	UncompressedBytes int64 `json:"uncompressed_bytes"`
+	CompressedBytes   int64 `json:"compressed_bytes"`
+}
+
+func (e *Entry) add(items, uncompressed, compressed int64) {
+	e.Items += items
+	e.UncompressedBytes += uncompressed
+	e.CompressedBytes += compressed
+}
+
+// Report is the size report of a flush. The entries are sorted by decreasing
+// compressed size.
+type Report struct {
+	Start          time.Time `json:"start"`
+	End            time.Time `json:"end"`
+	PayloadTypes   []Entry   `json:"payload_types"`
+	Checks         []Entry   `json:"checks"`
+	MetricPrefixes []Entry   `json:"metric_prefixes"`
+	// TruncatedChecks and TruncatedMetricPrefixes are the number of checks and
+	// metric prefixes not listed, with the smallest sizes.
+	TruncatedChecks         int `json:"truncated_checks"`
+	TruncatedMetricPrefixes int `json:"truncated_metric_prefixes"`
+}
+
+// flushSizes holds the sizes recorded during a flush.
+type flushSizes struct {
+	start          time.Time
+	payloadTypes   map[string]*Entry
+	checks         map[string]*Entry
+	metricPrefixes map[string]*Entry
+}
+
+func newFlushSizes(start time.Time) *flushSizes {
+	return &flushSizes{
+		start:          start,
+		payloadTypes:   make(map[string]*Entry),
+		checks:         make(map[string]*Entry),
+		metricPrefixes: make(map[string]*Entry),
+	}
+}
+
+func entry(entries map[string]*Entry, name string) *Entry {
+	e, ok := entries[name]
+	if !ok {
+		e = &Entry{Name: name}
+		entries[name] = e
+	}
+	return e
+}
+
+// sortedEntries returns the maxEntries biggest entries, or all of them if maxEntries
+// is not positive, and the number of entries left out.
+func sortedEntries(entries map[string]*Entry, maxEntries int) ([]Entry, int) {
+	sorted := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		sorted = append(sorted, *e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].CompressedBytes != sorted[j].CompressedBytes {
+			return sorted[i].CompressedBytes > sorted[j].CompressedBytes
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	if maxEntries > 0 && len(sorted) > maxEntries {
+		return sorted[:maxEntries], len(sorted) - maxEntries
+	}
+	return sorted, 0
+}
+
+// Recorder records the sizes of the payloads of the current flush and keeps the
+// report of the last one.
+type Recorder struct {
+	maxEntries int
+
+	m       sync.Mutex
+	current *flushSizes
+	last    *Report
+}
+
+// NewRecorder returns a new Recorder listing at most maxEntries checks and metric
+// prefixes in its reports.
+func NewRecorder(maxEntries int) *Recorder {
+	return &Recorder{
+		maxEntries: maxEntries,
+		current:    newFlushSizes(time.Now()),
+	}
+}
+
+// StartFlush ends the current flush, its report becoming the last one, and starts a
+// new one. The payloads sent asynchronously by a flush, like the service checks and
+// the events, are reported with it as long as they are sent before the next flush.
+func (r *Recorder) StartFlush(now time.Time) {
+	if r == nil {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	ended := r.current
+	report := &Report{Start: ended.start, End: now}
+	report.PayloadTypes, _ = sortedEntries(ended.payloadTypes, 0)
+	report.Checks, report.TruncatedChecks = sortedEntries(ended.checks, r.maxEntries)
+	report.MetricPrefixes, report.TruncatedMetricPrefixes = sortedEntries(ended.metricPrefixes, r.maxEntries)
+	r.last = report
+	r.current = newFlushSizes(now)
+}
+
+// LastFlush returns the report of the last flush, or nil before the end of the first one.
+func (r *Recorder) LastFlush() *Report {
+	if r == nil {
+		return nil
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	return r.last
+}
+
+// AddPayload records a payload of the given type whose items are not attributed.
+func (r *Recorder) AddPayload(payloadType string, uncompressed, compressed int) {
+	if r == nil {
+		return
+	}
+	r.m.Lock()
+	defer r.m.Unlock()
+	entry(r.current.payloadTypes, payloadType).add(1, int64(uncompressed), int64(compressed))
+}
+
+// addPayload records a payload and attributes its compressed bytes to the sources
+// of its items, in proportion to their uncompressed size.
+func (r *Recorder) addPayload(payloadType string, items map[Source]*Entry, uncompressed, compressed int) {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	entry(r.current.payloadTypes, payloadType).add(1, int64(uncompressed), int64(compressed))
+	if uncompressed == 0 {
+		return
+	}
+	for source, item := range items {
+		share := int64(compressed) * item.UncompressedBytes / int64(uncompressed)
+		if source.CheckID != "" {
+			entry(r.current.checks, source.CheckID).add(item.Items, item.UncompressedBytes, share)
+		}
+		if source.MetricPrefix != "" {
+			entry(r.current.metricPrefixes, source.MetricPrefix).add(item.Items, item.UncompressedBytes, share)
+		}
+	}
+}
+
+// NewPayload returns a Payload recording the payloads of the given type.
+func (r *Recorder) NewPayload(payloadType string) *Payload {
+	if r == nil {
+		return nil
+	}
+	return &Payload{
+		recorder:    r,
+		payloadType: payloadType,
+		items:       make(map[Source]*Entry),
+	}
+}
+
+// Payload gathers the sizes of the items of a payload while it is built. It can be
+// reused for the next payloads of the same type once a payload is done. All the
+// methods of a nil Payload are no-ops.
+type Payload struct {
+	recorder    *Recorder
+	payloadType string
+	items       map[Source]*Entry
+}
+
+// AddItem records an item of the payload.
+func (p *Payload) AddItem(source Source, size int) {
+	if p == nil {
+		return
+	}
+	item, ok := p.items[source]
+	if !ok {
+		item = &Entry{}
+		p.items[source] = item
+	}
+	item.add(1, int64(size), 0)
+}
+
+// Done records the payload with its total sizes, including its header and footer,
+// and resets the items for the next payload.
+func (p *Payload) Done(uncompressed, compressed int) {
+	if p == nil {
+		return
+	}
+	p.recorder.addPayload(p.payloadType, p.items, uncompressed, compressed)
+	p.items = make(map[Source]*Entry)
+}
@@ -0,0 +1,88 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sizereport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestMetricSource(t *testing.T) {
+	assert.Equal(t, Source{CheckID: "cpu", MetricPrefix: "system"}, MetricSource("cpu", "system.cpu.user"))
+	assert.Equal(t, Source{MetricPrefix: "standalone"}, MetricSource("", "standalone"))
+	assert.Equal(t, Source{MetricPrefix: ".hidden"}, MetricSource("", ".hidden"))
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(2)
+	assert.Nil(t, r.LastFlush())
+
+	p := r.NewPayload("series")
+	p.AddItem(MetricSource("redis:1", "redis.net.clients"), 300)
+	p.AddItem(MetricSource("redis:1", "redis.mem.used"), 300)
+	p.AddItem(MetricSource("", "app.requests"), 200)
+	p.AddItem(MetricSource("", "datadog.agent.running"), 100)
+	p.Done(1000, 100)
+	p.AddItem(MetricSource("cpu", "system.cpu.user"), 400)
+	p.Done(500, 50)
+	r.AddPayload("host_metadata", 2000, 400)
+
+	start := time.Now()
+	r.StartFlush(start)
+	report := r.LastFlush()
+	require.NotNil(t, report)
+	assert.Equal(t, start, report.End)
+
+	assert.Equal(t, []Entry{
+		{Name: "host_metadata", Items: 1, UncompressedBytes: 2000, CompressedBytes: 400},
+		{Name: "series", Items: 2, UncompressedBytes: 1500, CompressedBytes: 150},
+	}, report.PayloadTypes)
+	assert.Equal(t, []Entry{
+		{Name: "redis:1", Items: 2, UncompressedBytes: 600, CompressedBytes: 60},
+		{Name: "cpu", Items: 1, UncompressedBytes: 400, CompressedBytes: 40},
+	}, report.Checks)
+	assert.Equal(t, 0, report.TruncatedChecks)
+	assert.Equal(t, []Entry{
+		{Name: "redis", Items: 2, UncompressedBytes: 600, CompressedBytes: 60},
+		{Name: "system", Items: 1, UncompressedBytes: 400, CompressedBytes: 40},
+	}, report.MetricPrefixes)
+	assert.Equal(t, 2, report.TruncatedMetricPrefixes)
+
+	// The next flush starts empty
+	r.StartFlush(start.Add(15 * time.Second))
+	report = r.LastFlush()
+	assert.Equal(t, start, report.Start)
+	assert.Empty(t, report.PayloadTypes)
+	assert.Empty(t, report.Checks)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	p := r.NewPayload("series")
+	assert.Nil(t, p)
+	p.AddItem(Source{}, 10)
+	p.Done(10, 1)
+	r.AddPayload("metadata", 10, 1)
+	r.StartFlush(time.Now())
+	assert.Nil(t, r.LastFlush())
+}
+
+func TestGet(t *testing.T) {
+	mockConfig := config.Mock()
+	assert.Nil(t, Get())
+
+	mockConfig.Set("serializer_size_report.enabled", true)
+	defer mockConfig.Set("serializer_size_report.enabled", false)
+	r := Get()
+	require.NotNil(t, r)
+	assert.Same(t, r, Get())
+	assert.Equal(t, 20, r.maxEntries)
+}
@@ -61,8 +61,8 @@ func init() {
 
 // CheckSizeAndSerialize Check the size of a payload and marshall it (optionally compress it)
 // The dual role makes sense as you will never serialize without checking the size of the payload
-func CheckSizeAndSerialize(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFct) (bool, []byte, []byte, error) {
-	compressedPayload, payload, err := serializeMarshaller(m, compress, marshalFct)
+func CheckSizeAndSerialize(m marshaler.AbstractMarshaler, compressor compression.Compressor, marshalFct MarshalFct) (bool, []byte, []byte, error) {
+	compressedPayload, payload, err := serializeMarshaller(m, compressor, marshalFct)
 	if err != nil {
 		return false, nil, nil, err
 	}
@@ -73,10 +73,10 @@ func CheckSizeAndSerialize(m marshaler.AbstractMarshaler, compress bool, marshal
 }
 
 // Payloads serializes a metadata payload and sends it to the forwarder
-func Payloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFct) (forwarder.Payloads, error) {
+func Payloads(m marshaler.AbstractMarshaler, compressor compression.Compressor, marshalFct MarshalFct) (forwarder.Payloads, error) {
 	marshallers := []marshaler.AbstractMarshaler{m}
 	smallEnoughPayloads := forwarder.Payloads{}
-	tooBig, compressedPayload, _, err := CheckSizeAndSerialize(m, compress, marshalFct)
+	tooBig, compressedPayload, _, err := CheckSizeAndSerialize(m, compressor, marshalFct)
 	if err != nil {
 		return smallEnoughPayloads, err
 	}
@@ -103,7 +103,7 @@ func Payloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFc
 		for _, toSplit := range tempSlice {
 			var e error
 			// we have to do this every time to get the proper payload
-			compressedPayload, payload, e := serializeMarshaller(toSplit, compress, marshalFct)
+			compressedPayload, payload, e := serializeMarshaller(toSplit, compressor, marshalFct)
 			if e != nil {
 				return smallEnoughPayloads, e
 			}
@@ -125,7 +125,7 @@ func Payloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFc
 			// after the payload has been split, loop through the chunks
 			for _, chunk := range chunks {
 				// serialize the payload
-				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerialize(chunk, compress, marshalFct)
+				tooBigChunk, compressedPayload, _, err := CheckSizeAndSerialize(chunk, compressor, marshalFct)
 				if err != nil {
 					log.Debugf("Error serializing a chunk: %s", err)
 					continue
@@ -159,20 +159,14 @@ func Payloads(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFc
 }
 
 // serializeMarshaller serializes the marshaller and returns both the compressed and uncompressed payloads
-func serializeMarshaller(m marshaler.AbstractMarshaler, compress bool, marshalFct MarshalFct) ([]byte, []byte, error) {
-	var payload []byte
-	var compressedPayload []byte
-	var err error
-	payload, err = marshalFct(m)
-	compressedPayload = payload
+func serializeMarshaller(m marshaler.AbstractMarshaler, compressor compression.Compressor, marshalFct MarshalFct) ([]byte, []byte, error) {
+	payload, err := marshalFct(m)
 	if err != nil {
 		return nil, nil, err
 	}
-	if compress {
-		compressedPayload, err = compression.Compress(payload)
-		if err != nil {
-			return nil, nil, err
-		}
+	compressedPayload, err := compressor.Compress(payload)
+	if err != nil {
+		return nil, nil, err
 	}
 	return compressedPayload, payload, nil
 }
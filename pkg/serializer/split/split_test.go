@@ -22,6 +22,18 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
+var (
+	zlibCompressor, _ = compression.NewCompressor(compression.ZlibKind)
+	noneCompressor, _ = compression.NewCompressor(compression.NoneKind)
+)
+
+func testCompressor(compress bool) compression.Compressor {
+	if compress {
+		return zlibCompressor
+	}
+	return noneCompressor
+}
+
 func TestSplitPayloadsSeries(t *testing.T) {
 	// Override size limits to avoid test timeouts
 	prevMaxPayloadSizeCompressed := maxPayloadSizeCompressed
@@ -72,7 +84,7 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 		testSeries = append(testSeries, &point)
 	}
 
-	payloads, err := Payloads(testSeries, compress, JSONMarshalFct)
+	payloads, err := Payloads(testSeries, testCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testSeries)
@@ -81,7 +93,7 @@ func testSplitPayloadsSeries(t *testing.T, numPoints int, compress bool) {
 		var s = map[string]metricsserializer.Series{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = zlibCompressor.Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -122,7 +134,7 @@ func BenchmarkSplitPayloadsSeries(b *testing.B) {
 	for n := 0; n < b.N; n++ {
 		// always record the result of Payloads to prevent
 		// the compiler eliminating the function call.
-		r, _ = Payloads(testSeries, true, JSONMarshalFct)
+		r, _ = Payloads(testSeries, zlibCompressor, JSONMarshalFct)
 
 	}
 	// ensure we actually had to split
@@ -184,7 +196,7 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 		testEvent = append(testEvent, &event)
 	}
 
-	payloads, err := Payloads(testEvent, compress, JSONMarshalFct)
+	payloads, err := Payloads(testEvent, testCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testEvent)
@@ -193,7 +205,7 @@ func testSplitPayloadsEvents(t *testing.T, numPoints int, compress bool) {
 		var s map[string]interface{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = zlibCompressor.Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -249,7 +261,7 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 		testServiceChecks = append(testServiceChecks, &sc)
 	}
 
-	payloads, err := Payloads(testServiceChecks, compress, JSONMarshalFct)
+	payloads, err := Payloads(testServiceChecks, testCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	originalLength := len(testServiceChecks)
@@ -258,7 +270,7 @@ func testSplitPayloadsServiceChecks(t *testing.T, numPoints int, compress bool)
 		var s []interface{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = zlibCompressor.Decompress(*payload)
 			require.Nil(t, err)
 		}
 
@@ -304,7 +316,7 @@ func testSplitPayloadsSketches(t *testing.T, numPoints int, compress bool) {
 		testSketchSeries[i] = metricsserializer.Makeseries(i)
 	}
 
-	payloads, err := Payloads(testSketchSeries, compress, JSONMarshalFct)
+	payloads, err := Payloads(testSketchSeries, testCompressor(compress), JSONMarshalFct)
 	require.Nil(t, err)
 
 	var splitSketches = []metricsserializer.SketchSeriesList{}
@@ -312,7 +324,7 @@ func testSplitPayloadsSketches(t *testing.T, numPoints int, compress bool) {
 		var s = map[string]metricsserializer.SketchSeriesList{}
 
 		if compress {
-			*payload, err = compression.Decompress(*payload)
+			*payload, err = zlibCompressor.Decompress(*payload)
 			require.Nil(t, err)
 		}
 
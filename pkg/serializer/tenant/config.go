@@ -0,0 +1,99 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tenant
+
+import (
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+	"github.com/DataDog/datadog-agent/pkg/forwarder"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+)
+
+// Config is the configuration of a tenant in `tenant_routing.tenants`.
+type Config struct {
+	// TagValue is the value of the routing tag of the tenant data.
+	TagValue string `mapstructure:"tag_value"`
+	APIKey   string `mapstructure:"api_key"`
+	// URL is the intake of the tenant organization, the main `dd_url` by default.
+	URL string `mapstructure:"dd_url"`
+	// Hosts lists the hosts whose data is sent to the tenant when it has no routing tag.
+	Hosts []string `mapstructure:"hosts"`
+}
+
+// GetConfigs returns the tenants of `tenant_routing.tenants`.
+func GetConfigs() ([]Config, error) {
+	if config.Datadog.GetString("tenant_routing.tag") == "" {
+		return nil, fmt.Errorf("tenant_routing.tag is not set")
+	}
+
+	var tenants []Config
+	if err := config.Datadog.UnmarshalKey("tenant_routing.tenants", &tenants); err != nil {
+		return nil, fmt.Errorf("invalid tenant_routing.tenants: %v", err)
+	}
+
+	known := make(map[string]bool)
+	hosts := make(map[string]string)
+	for i := range tenants {
+		t := &tenants[i]
+		if t.TagValue == "" {
+			return nil, fmt.Errorf("tenant #%d of tenant_routing.tenants has no tag_value", i)
+		}
+		if known[t.TagValue] {
+			return nil, fmt.Errorf("tenant %q is configured several times in tenant_routing.tenants", t.TagValue)
+		}
+		known[t.TagValue] = true
+
+		t.APIKey = config.SanitizeAPIKey(t.APIKey)
+		if t.APIKey == "" {
+			return nil, fmt.Errorf("tenant %q of tenant_routing.tenants has no api_key", t.TagValue)
+		}
+		if t.URL == "" {
+			t.URL = config.GetMainInfraEndpoint()
+		}
+		for _, host := range t.Hosts {
+			if other, ok := hosts[host]; ok {
+				return nil, fmt.Errorf("host %q is configured for both tenants %q and %q in tenant_routing.tenants", host, other, t.TagValue)
+			}
+			hosts[host] = t.TagValue
+		}
+	}
+	return tenants, nil
+}
+
+// NewForwarders returns a forwarder per tenant, sending to the intake of the tenant with
+// its API key. Their retry queues are kept in memory only. Their domains are qualified
+// with `tenant:<tag_value>` in the forwarder expvars and telemetry.
+func NewForwarders(tenants []Config) map[string]forwarder.Forwarder {
+	forwarders := make(map[string]forwarder.Forwarder, len(tenants))
+	for _, t := range tenants {
+		options := forwarder.NewOptions(map[string][]string{t.URL: {t.APIKey}})
+		options.Name = "tenant:" + t.TagValue
+		forwarders[t.TagValue] = forwarder.NewDefaultForwarder(options)
+	}
+	return forwarders
+}
+
+// NewRouterFromConfig returns a Router sending the data of each tenant with a
+// serializer using the forwarder of the tenant.
+func NewRouterFromConfig(defaultSerializer serializer.MetricSerializer, tenants []Config, forwarders map[string]forwarder.Forwarder) *Router {
+	serializers := make(map[string]serializer.MetricSerializer, len(forwarders))
+	for tenant, fwd := range forwarders {
+		serializers[tenant] = serializer.NewSerializer(fwd, nil, nil)
+	}
+	hosts := make(map[string]string)
+	for _, t := range tenants {
+		for _, host := range t.Hosts {
+			hosts[host] = t.TagValue
+		}
+	}
+	return NewRouter(
+		defaultSerializer,
+		config.Datadog.GetString("tenant_routing.tag"),
+		hosts,
+		serializers,
+		config.Datadog.GetBool("tenant_routing.copy_to_default"))
+}
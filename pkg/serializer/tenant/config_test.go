@@ -0,0 +1,65 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package tenant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/config"
+)
+
+func TestGetConfigs(t *testing.T) {
+	mockConfig := config.Mock()
+	defer mockConfig.Set("tenant_routing.tag", "")
+	defer mockConfig.Set("tenant_routing.tenants", nil)
+
+	_, err := GetConfigs()
+	assert.EqualError(t, err, "tenant_routing.tag is not set")
+
+	mockConfig.Set("tenant_routing.tag", "team")
+	mockConfig.Set("tenant_routing.tenants", []map[string]interface{}{
+		{"tag_value": "a", "api_key": " key_a\n"},
+		{"tag_value": "b", "api_key": "key_b", "dd_url": "https://app.datadoghq.eu", "hosts": []string{"host_b"}},
+	})
+	tenants, err := GetConfigs()
+	require.NoError(t, err)
+	assert.Equal(t, []Config{
+		{TagValue: "a", APIKey: "key_a", URL: config.GetMainInfraEndpoint()},
+		{TagValue: "b", APIKey: "key_b", URL: "https://app.datadoghq.eu", Hosts: []string{"host_b"}},
+	}, tenants)
+
+	forwarders := NewForwarders(tenants)
+	assert.Len(t, forwarders, 2)
+	assert.NotNil(t, forwarders["a"])
+
+	mockConfig.Set("tenant_routing.tenants", []map[string]interface{}{
+		{"tag_value": "a", "api_key": "key_a"},
+		{"tag_value": "a", "api_key": "key_b"},
+	})
+	_, err = GetConfigs()
+	assert.EqualError(t, err, `tenant "a" is configured several times in tenant_routing.tenants`)
+
+	mockConfig.Set("tenant_routing.tenants", []map[string]interface{}{
+		{"tag_value": "a", "api_key": "key_a", "hosts": []string{"host_a"}},
+		{"tag_value": "b", "api_key": "key_b", "hosts": []string{"host_b", "host_a"}},
+	})
+	_, err = GetConfigs()
+	assert.EqualError(t, err, `host "host_a" is configured for both tenants "a" and "b" in tenant_routing.tenants`)
+
+	mockConfig.Set("tenant_routing.tenants", []map[string]interface{}{{"tag_value": "a"}})
+	_, err = GetConfigs()
+	assert.EqualError(t, err, `tenant "a" of tenant_routing.tenants has no api_key`)
+
+	mockConfig.Set("tenant_routing.tenants", []map[string]interface{}{{"api_key": "key_a"}})
+	_, err = GetConfigs()
+	assert.EqualError(t, err, "tenant #0 of tenant_routing.tenants has no tag_value")
+}
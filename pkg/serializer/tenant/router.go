@@ -0,0 +1,274 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package tenant routes the series, sketches, service checks and events to the
+// organization of a tenant, based on the value of a tag or on their host.
+package tenant
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tlmRouted = telemetry.NewCounter("tenant_routing", "routed",
+		[]string{"tenant", "payload_kind"}, "Count of series, sketches, service checks and events routed to a tenant")
+	tlmErrors = telemetry.NewCounter("tenant_routing", "errors",
+		[]string{"tenant"}, "Count of errors sending the data of a tenant")
+)
+
+// Router is a serializer.MetricSerializer sending the series, sketches, service
+// checks and events tagged with `<tag>:<tenant>`, or sent by a host of the tenant, to
+// the serializer of the tenant. The data of the other tenants and hosts, or without the
+// tag, and the other payloads are sent by the default serializer. The host metadata is
+// also sent to every tenant so that the hosts are known in their organizations.
+type Router struct {
+	serializer.MetricSerializer
+	tagPrefix     string
+	hosts         map[string]string
+	tenants       map[string]serializer.MetricSerializer
+	copyToDefault bool
+}
+
+// NewRouter returns a new Router routing on the values of the tag key, and on the hosts
+// mapped to their tenant by hosts. The tag takes precedence over the host. When
+// copyToDefault is true, the data of the tenants is also sent by the default serializer.
+func NewRouter(defaultSerializer serializer.MetricSerializer, tag string, hosts map[string]string, tenants map[string]serializer.MetricSerializer, copyToDefault bool) *Router {
+	return &Router{
+		MetricSerializer: defaultSerializer,
+		tagPrefix:        tag + ":",
+		hosts:            hosts,
+		tenants:          tenants,
+		copyToDefault:    copyToDefault,
+	}
+}
+
+// tenant returns the tenant of a tag, or "" if the tag is not the routing tag of a
+// known tenant.
+func (r *Router) tenant(tag string) string {
+	if !strings.HasPrefix(tag, r.tagPrefix) {
+		return ""
+	}
+	if value := tag[len(r.tagPrefix):]; r.tenants[value] != nil {
+		return value
+	}
+	return ""
+}
+
+// tenantOfHost returns the tenant of host, or "" if host is not a host of a known tenant.
+func (r *Router) tenantOfHost(host string) string {
+	if tenant := r.hosts[host]; r.tenants[tenant] != nil {
+		return tenant
+	}
+	return ""
+}
+
+// tenantOfTags returns the tenant of the first routing tag of tags, or the tenant of
+// host, or "".
+func (r *Router) tenantOfTags(tags []string, host string) string {
+	for _, tag := range tags {
+		if tenant := r.tenant(tag); tenant != "" {
+			return tenant
+		}
+	}
+	return r.tenantOfHost(host)
+}
+
+// tenantOfCompositeTags returns the tenant of the first routing tag of tags, or the
+// tenant of host, or "".
+func (r *Router) tenantOfCompositeTags(tags tagset.CompositeTags, host string) string {
+	tenant := ""
+	tags.Find(func(tag string) bool {
+		tenant = r.tenant(tag)
+		return tenant != ""
+	})
+	if tenant != "" {
+		return tenant
+	}
+	return r.tenantOfHost(host)
+}
+
+// SendIterableSeries sends the series of the tenants to their serializers and the other
+// series to the default serializer. The series of the default serializer are streamed
+// while the series of the tenants are kept in memory until the iteration is over.
+func (r *Router) SendIterableSeries(serieSource metrics.SerieSource) error {
+	tenantSeries := make(map[string][]*metrics.Serie)
+	source := &serieFilter{
+		source: serieSource,
+		keep: func(serie *metrics.Serie) bool {
+			tenant := r.tenantOfCompositeTags(serie.Tags, serie.Host)
+			if tenant == "" {
+				return true
+			}
+			tenantSeries[tenant] = append(tenantSeries[tenant], serie)
+			return r.copyToDefault
+		},
+	}
+
+	err := r.MetricSerializer.SendIterableSeries(source)
+	// The default serializer may stop early, for instance when the series are
+	// disabled: the series of the tenants must still be collected.
+	for source.MoveNext() {
+	}
+
+	errs := &routingErrors{}
+	errs.add("", err)
+	for tenant, series := range tenantSeries {
+		tlmRouted.Add(float64(len(series)), tenant, "series")
+		errs.add(tenant, r.tenants[tenant].SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	}
+	return errs.err()
+}
+
+// SendSketch sends the sketches of the tenants to their serializers and the other
+// sketches to the default serializer.
+func (r *Router) SendSketch(sketches metrics.SketchSeriesList) error {
+	var defaultSketches metrics.SketchSeriesList
+	tenantSketches := make(map[string]metrics.SketchSeriesList)
+	for _, sketch := range sketches {
+		tenant := r.tenantOfCompositeTags(sketch.Tags, sketch.Host)
+		if tenant == "" || r.copyToDefault {
+			defaultSketches = append(defaultSketches, sketch)
+		}
+		if tenant != "" {
+			tenantSketches[tenant] = append(tenantSketches[tenant], sketch)
+		}
+	}
+
+	errs := &routingErrors{}
+	if len(defaultSketches) > 0 || len(tenantSketches) == 0 {
+		errs.add("", r.MetricSerializer.SendSketch(defaultSketches))
+	}
+	for tenant, sketches := range tenantSketches {
+		tlmRouted.Add(float64(len(sketches)), tenant, "sketches")
+		errs.add(tenant, r.tenants[tenant].SendSketch(sketches))
+	}
+	return errs.err()
+}
+
+// SendServiceChecks sends the service checks of the tenants to their serializers and
+// the other service checks to the default serializer.
+func (r *Router) SendServiceChecks(serviceChecks metrics.ServiceChecks) error {
+	var defaultServiceChecks metrics.ServiceChecks
+	tenantServiceChecks := make(map[string]metrics.ServiceChecks)
+	for _, sc := range serviceChecks {
+		tenant := r.tenantOfTags(sc.Tags, sc.Host)
+		if tenant == "" || r.copyToDefault {
+			defaultServiceChecks = append(defaultServiceChecks, sc)
+		}
+		if tenant != "" {
+			tenantServiceChecks[tenant] = append(tenantServiceChecks[tenant], sc)
+		}
+	}
+
+	errs := &routingErrors{}
+	if len(defaultServiceChecks) > 0 || len(tenantServiceChecks) == 0 {
+		errs.add("", r.MetricSerializer.SendServiceChecks(defaultServiceChecks))
+	}
+	for tenant, serviceChecks := range tenantServiceChecks {
+		tlmRouted.Add(float64(len(serviceChecks)), tenant, "service_checks")
+		errs.add(tenant, r.tenants[tenant].SendServiceChecks(serviceChecks))
+	}
+	return errs.err()
+}
+
+// SendEvents sends the events of the tenants to their serializers and the other events
+// to the default serializer.
+func (r *Router) SendEvents(events metrics.Events) error {
+	var defaultEvents metrics.Events
+	tenantEvents := make(map[string]metrics.Events)
+	for _, event := range events {
+		tenant := r.tenantOfTags(event.Tags, event.Host)
+		if tenant == "" || r.copyToDefault {
+			defaultEvents = append(defaultEvents, event)
+		}
+		if tenant != "" {
+			tenantEvents[tenant] = append(tenantEvents[tenant], event)
+		}
+	}
+
+	errs := &routingErrors{}
+	if len(defaultEvents) > 0 || len(tenantEvents) == 0 {
+		errs.add("", r.MetricSerializer.SendEvents(defaultEvents))
+	}
+	for tenant, events := range tenantEvents {
+		tlmRouted.Add(float64(len(events)), tenant, "events")
+		errs.add(tenant, r.tenants[tenant].SendEvents(events))
+	}
+	return errs.err()
+}
+
+// SendHostMetadata sends the host metadata to the default serializer and to every tenant.
+func (r *Router) SendHostMetadata(m marshaler.JSONMarshaler) error {
+	errs := &routingErrors{}
+	errs.add("", r.MetricSerializer.SendHostMetadata(m))
+	for tenant, s := range r.tenants {
+		errs.add(tenant, s.SendHostMetadata(m))
+	}
+	return errs.err()
+}
+
+// routingErrors gathers the errors of the default serializer and of the tenants.
+type routingErrors struct {
+	messages []string
+}
+
+// add records the error of a tenant, or of the default serializer when tenant is "".
+func (e *routingErrors) add(tenant string, err error) {
+	if err == nil {
+		return
+	}
+	if tenant == "" {
+		e.messages = append(e.messages, err.Error())
+		return
+	}
+	tlmErrors.Inc(tenant)
+	log.Debugf("Error sending the data of tenant %q: %v", tenant, err)
+	e.messages = append(e.messages, fmt.Sprintf("tenant %q: %v", tenant, err))
+}
+
+func (e *routingErrors) err() error {
+	if len(e.messages) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(e.messages, "; "))
+}
+
+// serieFilter is a metrics.SerieSource iterating over the series of source for which
+// keep returns true.
+type serieFilter struct {
+	source  metrics.SerieSource
+	keep    func(*metrics.Serie) bool
+	current *metrics.Serie
+	count   uint64
+}
+
+func (f *serieFilter) MoveNext() bool {
+	for f.source.MoveNext() {
+		if serie := f.source.Current(); f.keep(serie) {
+			f.current = serie
+			f.count++
+			return true
+		}
+	}
+	f.current = nil
+	return false
+}
+
+func (f *serieFilter) Current() *metrics.Serie {
+	return f.current
+}
+
+func (f *serieFilter) SeriesCount() uint64 {
+	return f.count
+}
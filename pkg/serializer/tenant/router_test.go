@@ -0,0 +1,182 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+// +build test
+
+package tenant
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/serializer"
+	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func seriesNames(expected ...string) interface{} {
+	return mock.MatchedBy(func(source metrics.SerieSource) bool {
+		var names []string
+		for source.MoveNext() {
+			names = append(names, source.Current().Name)
+		}
+		return assert.ObjectsAreEqual(expected, names)
+	})
+}
+
+func newTestRouter(copyToDefault bool) (*Router, *serializer.MockSerializer, *serializer.MockSerializer, *serializer.MockSerializer) {
+	datadog, a, b := &serializer.MockSerializer{}, &serializer.MockSerializer{}, &serializer.MockSerializer{}
+	tenants := map[string]serializer.MetricSerializer{"a": a, "b": b}
+	hosts := map[string]string{"host_a": "a", "host_b": "b", "host_c": "c"}
+	return NewRouter(datadog, "team", hosts, tenants, copyToDefault), datadog, a, b
+}
+
+func TestRouterSendIterableSeries(t *testing.T) {
+	series := []*metrics.Serie{
+		{Name: "untagged"},
+		{Name: "team_a", Tags: tagset.CompositeTagsFromSlice([]string{"env:prod", "team:a"})},
+		{Name: "team_b", Tags: tagset.NewCompositeTags([]string{"env:prod"}, []string{"team:b"})},
+		{Name: "team_c", Tags: tagset.CompositeTagsFromSlice([]string{"team:c"})},
+		{Name: "team_a_2", Tags: tagset.CompositeTagsFromSlice([]string{"team:a"})},
+	}
+
+	r, datadog, a, b := newTestRouter(false)
+	datadog.On("SendIterableSeries", seriesNames("untagged", "team_c")).Return(nil).Once()
+	a.On("SendIterableSeries", seriesNames("team_a", "team_a_2")).Return(nil).Once()
+	b.On("SendIterableSeries", seriesNames("team_b")).Return(errors.New("unreachable")).Once()
+
+	err := r.SendIterableSeries(metricsserializer.NewSerieSliceSource(series))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `tenant "b": unreachable`)
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertExpectations(t)
+
+	r, datadog, a, b = newTestRouter(true)
+	datadog.On("SendIterableSeries", seriesNames("untagged", "team_a", "team_b", "team_c", "team_a_2")).Return(nil).Once()
+	a.On("SendIterableSeries", seriesNames("team_a", "team_a_2")).Return(nil).Once()
+	b.On("SendIterableSeries", seriesNames("team_b")).Return(nil).Once()
+
+	assert.NoError(t, r.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertExpectations(t)
+}
+
+func TestRouterSendIterableSeriesDefaultStopsEarly(t *testing.T) {
+	series := []*metrics.Serie{
+		{Name: "untagged"},
+		{Name: "team_a", Tags: tagset.CompositeTagsFromSlice([]string{"team:a"})},
+	}
+
+	r, datadog, a, _ := newTestRouter(false)
+	datadog.On("SendIterableSeries", mock.Anything).Return(nil).Once()
+	a.On("SendIterableSeries", seriesNames("team_a")).Return(nil).Once()
+
+	assert.NoError(t, r.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	a.AssertExpectations(t)
+}
+
+func TestRouterSendIterableSeriesByHost(t *testing.T) {
+	series := []*metrics.Serie{
+		{Name: "host_a", Host: "host_a"},
+		{Name: "host_a_team_b", Host: "host_a", Tags: tagset.CompositeTagsFromSlice([]string{"team:b"})},
+		{Name: "host_c", Host: "host_c"},
+		{Name: "other_host", Host: "other_host"},
+	}
+
+	r, datadog, a, b := newTestRouter(false)
+	datadog.On("SendIterableSeries", seriesNames("host_c", "other_host")).Return(nil).Once()
+	a.On("SendIterableSeries", seriesNames("host_a")).Return(nil).Once()
+	b.On("SendIterableSeries", seriesNames("host_a_team_b")).Return(nil).Once()
+
+	assert.NoError(t, r.SendIterableSeries(metricsserializer.NewSerieSliceSource(series)))
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertExpectations(t)
+}
+
+func TestRouterSendSketch(t *testing.T) {
+	sketches := metrics.SketchSeriesList{
+		{Name: "untagged"},
+		{Name: "team_a", Tags: tagset.CompositeTagsFromSlice([]string{"team:a"})},
+	}
+
+	r, datadog, a, b := newTestRouter(false)
+	datadog.On("SendSketch", metrics.SketchSeriesList{sketches[0]}).Return(nil).Once()
+	a.On("SendSketch", metrics.SketchSeriesList{sketches[1]}).Return(nil).Once()
+
+	assert.NoError(t, r.SendSketch(sketches))
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertNotCalled(t, "SendSketch", mock.Anything)
+
+	// Only tenant data: nothing is sent to the default organization
+	r, datadog, a, _ = newTestRouter(false)
+	a.On("SendSketch", metrics.SketchSeriesList{sketches[1]}).Return(nil).Once()
+
+	assert.NoError(t, r.SendSketch(sketches[1:]))
+	datadog.AssertNotCalled(t, "SendSketch", mock.Anything)
+	a.AssertExpectations(t)
+}
+
+func TestRouterSendServiceChecks(t *testing.T) {
+	serviceChecks := metrics.ServiceChecks{
+		{CheckName: "untagged"},
+		{CheckName: "team_b", Tags: []string{"env:prod", "team:b"}},
+		{CheckName: "host_b", Host: "host_b"},
+	}
+
+	r, datadog, a, b := newTestRouter(true)
+	datadog.On("SendServiceChecks", serviceChecks).Return(nil).Once()
+	b.On("SendServiceChecks", metrics.ServiceChecks{serviceChecks[1], serviceChecks[2]}).Return(nil).Once()
+
+	assert.NoError(t, r.SendServiceChecks(serviceChecks))
+	datadog.AssertExpectations(t)
+	b.AssertExpectations(t)
+	a.AssertNotCalled(t, "SendServiceChecks", mock.Anything)
+}
+
+func TestRouterSendEvents(t *testing.T) {
+	events := metrics.Events{
+		{Title: "team_a", Tags: []string{"team:a"}},
+		{Title: "team_b", Tags: []string{"team:b"}},
+		{Title: "team_d", Tags: []string{"team:d"}},
+	}
+
+	r, datadog, a, b := newTestRouter(false)
+	datadog.On("SendEvents", metrics.Events{events[2]}).Return(errors.New("invalid")).Once()
+	a.On("SendEvents", metrics.Events{events[0]}).Return(nil).Once()
+	b.On("SendEvents", metrics.Events{events[1]}).Return(nil).Once()
+
+	assert.EqualError(t, r.SendEvents(events), "invalid")
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertExpectations(t)
+
+	// Without any data, the default serializer is still called
+	r, datadog, _, _ = newTestRouter(false)
+	datadog.On("SendEvents", metrics.Events(nil)).Return(nil).Once()
+	assert.NoError(t, r.SendEvents(nil))
+	datadog.AssertExpectations(t)
+}
+
+func TestRouterSendHostMetadata(t *testing.T) {
+	r, datadog, a, b := newTestRouter(false)
+	datadog.On("SendHostMetadata", mock.Anything).Return(nil).Once()
+	a.On("SendHostMetadata", mock.Anything).Return(nil).Once()
+	b.On("SendHostMetadata", mock.Anything).Return(nil).Once()
+
+	assert.NoError(t, r.SendHostMetadata(nil))
+	datadog.AssertExpectations(t)
+	a.AssertExpectations(t)
+	b.AssertExpectations(t)
+}
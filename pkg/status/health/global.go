@@ -31,6 +31,14 @@ func Deregister(handle *Handle) error {
 	return readinessOnlyCatalog.deregister(handle)
 }
 
+// SetDegraded reports a healthy component as running with reduced functionality, or
+// back to full functionality
+func SetDegraded(handle *Handle, degraded bool) {
+	if readinessAndLivenessCatalog.setDegraded(handle, degraded) != nil {
+		_ = readinessOnlyCatalog.setDegraded(handle, degraded)
+	}
+}
+
 // GetLive returns health of all components registered for liveness
 func GetLive() Status {
 	return readinessAndLivenessCatalog.getStatus()
@@ -42,6 +50,7 @@ func GetReady() (ret Status) {
 	readyStatus := readinessOnlyCatalog.getStatus()
 	ret.Healthy = append(liveStatus.Healthy, readyStatus.Healthy...)
 	ret.Unhealthy = append(liveStatus.Unhealthy, readyStatus.Unhealthy...)
+	ret.Degraded = append(liveStatus.Degraded, readyStatus.Degraded...)
 	return
 }
 
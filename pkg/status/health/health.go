@@ -24,10 +24,16 @@ func (h *Handle) Deregister() error {
 	return Deregister(h)
 }
 
+// SetDegraded allows a healthy component to report that it runs with reduced functionality
+func (h *Handle) SetDegraded(degraded bool) {
+	SetDegraded(h, degraded)
+}
+
 type component struct {
 	name       string
 	healthChan chan time.Time
 	healthy    bool
+	degraded   bool
 }
 
 type catalog struct {
@@ -119,11 +125,26 @@ func (c *catalog) deregister(handle *Handle) error {
 	return nil
 }
 
+// setDegraded sets the degraded flag of a component
+func (c *catalog) setDegraded(handle *Handle, degraded bool) error {
+	c.Lock()
+	defer c.Unlock()
+	component, found := c.components[handle]
+	if !found {
+		return errors.New("component not registered")
+	}
+	component.degraded = degraded
+	return nil
+}
+
 // Status represents the current status of registered components
 // it is built and returned by GetStatus()
 type Status struct {
 	Healthy   []string
 	Unhealthy []string
+	// Degraded lists the healthy components running with reduced functionality,
+	// they are also listed in Healthy.
+	Degraded []string `json:",omitempty"`
 }
 
 // getStatus allows to query the health status of the agent
@@ -149,6 +170,9 @@ func (c *catalog) getStatus() Status {
 	for _, component := range c.components {
 		if component.healthy {
 			status.Healthy = append(status.Healthy, component.name)
+			if component.degraded {
+				status.Degraded = append(status.Degraded, component.name)
+			}
 		} else {
 			status.Unhealthy = append(status.Unhealthy, component.name)
 		}
@@ -65,6 +65,31 @@ func TestRegisterAndUnhealthy(t *testing.T) {
 	assert.Contains(t, status.Unhealthy, "test1")
 }
 
+func TestDegraded(t *testing.T) {
+	cat := newCatalog()
+	token := cat.register("test1")
+
+	require.NoError(t, cat.setDegraded(token, true))
+	status := cat.getStatus()
+	assert.Contains(t, status.Unhealthy, "test1")
+	assert.Empty(t, status.Degraded, "unhealthy components are not reported degraded")
+
+	// Empty the channel so the component is healthy after the next ping
+	for i := 0; i < bufferSize; i++ {
+		<-token.C
+	}
+	cat.pingComponents(time.Now())
+	status = cat.getStatus()
+	assert.Contains(t, status.Healthy, "test1")
+	assert.Equal(t, []string{"test1"}, status.Degraded)
+
+	require.NoError(t, cat.setDegraded(token, false))
+	assert.Empty(t, cat.getStatus().Degraded)
+
+	require.NoError(t, cat.deregister(token))
+	assert.Error(t, cat.setDegraded(token, true))
+}
+
 func TestRegisterTriplets(t *testing.T) {
 	cat := newCatalog()
 	cat.register("triplet")
@@ -98,6 +98,11 @@ func FormatStatus(data []byte) (string, error) {
 			renderStatusTemplate(b, "/otlp.tmpl", stats)
 		}
 	}
+	payloadSizesFunc := func() {
+		if config.Datadog.GetBool("serializer_size_report.enabled") {
+			renderStatusTemplate(b, "/payloadsizes.tmpl", stats["payloadSizeReport"])
+		}
+	}
 
 	var renderFuncs []func()
 
@@ -107,7 +112,7 @@ func FormatStatus(data []byte) (string, error) {
 	} else {
 		renderFuncs = []func(){headerFunc, checkStatsFunc, jmxFetchFunc, forwarderFunc, endpointsFunc,
 			logsAgentFunc, systemProbeFunc, processAgentFunc, traceAgentFunc, aggregatorFunc, dogstatsdFunc,
-			clusterAgentFunc, snmpTrapFunc, autodiscoveryFunc, otlpFunc}
+			clusterAgentFunc, snmpTrapFunc, autodiscoveryFunc, otlpFunc, payloadSizesFunc}
 	}
 
 	renderAgentSections(renderFuncs)
@@ -39,12 +39,14 @@ type Agent struct {
 	OTLPReceiver          *api.OTLPReceiver
 	Concentrator          *stats.Concentrator
 	ClientStatsAggregator *stats.ClientStatsAggregator
+	SpanMetrics           *stats.SpanMetrics
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
 	NoPrioritySampler     *sampler.NoPrioritySampler
+	SamplingRules         *sampler.SamplingRules
 	EventProcessor        *event.Processor
 	TraceWriter           *writer.TraceWriter
 	StatsWriter           *writer.StatsWriter
@@ -60,6 +62,9 @@ type Agent struct {
 	// ModifySpan will be called on all spans, if non-nil.
 	ModifySpan func(*pb.Span)
 
+	// liveLinter lints received payloads on behalf of LintLive callers.
+	liveLinter liveLinter
+
 	// In takes incoming payloads to be processed by the agent.
 	In chan *api.Payload
 
@@ -74,6 +79,7 @@ type Agent struct {
 // which may be cancelled in order to gracefully stop the agent.
 func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	dynConf := sampler.NewDynamicConfig()
+	rules := sampler.NewSamplingRules(conf)
 	in := make(chan *api.Payload, 1000)
 	statsChan := make(chan pb.StatsPayload, 100)
 
@@ -84,12 +90,14 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig) *Agent {
 	agnt := &Agent{
 		Concentrator:          stats.NewConcentrator(conf, statsChan, time.Now()),
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsChan),
+		SpanMetrics:           stats.NewSpanMetrics(conf),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
-		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
-		ErrorsSampler:         sampler.NewErrorsSampler(conf),
+		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf, rules),
+		ErrorsSampler:         sampler.NewErrorsSampler(conf, rules),
 		RareSampler:           sampler.NewRareSampler(),
-		NoPrioritySampler:     sampler.NewNoPrioritySampler(conf),
+		NoPrioritySampler:     sampler.NewNoPrioritySampler(conf, rules),
+		SamplingRules:         rules,
 		EventProcessor:        newEventProcessor(conf),
 		TraceWriter:           writer.NewTraceWriter(conf),
 		StatsWriter:           writer.NewStatsWriter(conf, statsChan),
@@ -110,9 +118,11 @@ func (a *Agent) Run() {
 		a.Receiver,
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.SpanMetrics,
 		a.PrioritySampler,
 		a.ErrorsSampler,
 		a.NoPrioritySampler,
+		a.SamplingRules,
 		a.EventProcessor,
 		a.OTLPReceiver,
 	} {
@@ -171,11 +181,13 @@ func (a *Agent) loop() {
 			for _, stopper := range []interface{ Stop() }{
 				a.Concentrator,
 				a.ClientStatsAggregator,
+				a.SpanMetrics,
 				a.TraceWriter,
 				a.StatsWriter,
 				a.PrioritySampler,
 				a.ErrorsSampler,
 				a.NoPrioritySampler,
+				a.SamplingRules,
 				a.RareSampler,
 				a.EventProcessor,
 				a.OTLPReceiver,
@@ -203,6 +215,8 @@ func (a *Agent) Process(p *api.Payload) {
 	ss := new(writer.SampledChunks)
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
+	a.liveLinter.offer(p)
+
 	p.TracerPayload.Env = traceutil.NormalizeTag(p.TracerPayload.Env)
 
 	a.discardSpans(p)
@@ -302,6 +316,8 @@ func (a *Agent) Process(p *api.Payload) {
 		if !p.ClientComputedStats {
 			statsInput.Traces = append(statsInput.Traces, pt)
 		}
+		// span metrics are computed before sampling, to account for all received spans
+		a.SpanMetrics.Add(&pt)
 
 		numEvents, keep, filteredChunk := a.sample(now, ts, pt)
 		if !keep {
@@ -483,7 +499,8 @@ func (a *Agent) samplePriorityTrace(now time.Time, pt traceutil.ProcessedTrace)
 	if a.PrioritySampler.Sample(now, pt.TraceChunk, pt.Root, pt.TracerEnv, pt.ClientDroppedP0sWeight) {
 		return true
 	}
-	if traceContainsError(pt.TraceChunk.Spans) {
+	// the sampling rules decide once: the errors sampler doesn't apply them again
+	if traceContainsError(pt.TraceChunk.Spans) && !a.PrioritySampler.SampledByRule(pt.TraceChunk, pt.Root, pt.TracerEnv) {
 		return a.ErrorsSampler.Sample(now, pt.TraceChunk.Spans, pt.Root, pt.TracerEnv)
 	}
 	return rare
@@ -809,17 +809,17 @@ func TestSampling(t *testing.T) {
 		sampledCfg := &config.AgentConfig{ExtraSampleRate: 1, TargetTPS: 5, ErrorTPS: 10, DisableRareSampler: ac.disableRareSampler}
 
 		a := &Agent{
-			NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
-			ErrorsSampler:     sampler.NewErrorsSampler(cfg),
-			PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
+			NoPrioritySampler: sampler.NewNoPrioritySampler(cfg, nil),
+			ErrorsSampler:     sampler.NewErrorsSampler(cfg, nil),
+			PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}, nil),
 			RareSampler:       sampler.NewRareSampler(),
 			conf:              cfg,
 		}
 		if ac.errorsSampled {
-			a.ErrorsSampler = sampler.NewErrorsSampler(sampledCfg)
+			a.ErrorsSampler = sampler.NewErrorsSampler(sampledCfg, nil)
 		}
 		if ac.noPrioritySampled {
-			a.NoPrioritySampler = sampler.NewNoPrioritySampler(sampledCfg)
+			a.NoPrioritySampler = sampler.NewNoPrioritySampler(sampledCfg, nil)
 		}
 		return a
 	}
@@ -938,6 +938,39 @@ func TestSampling(t *testing.T) {
 	}
 }
 
+func TestSamplingRuleNotAppliedTwice(t *testing.T) {
+	rate := 0.5
+	cfg := &config.AgentConfig{
+		ExtraSampleRate:    1,
+		TargetTPS:          5,
+		ErrorTPS:           10,
+		DisableRareSampler: true,
+		SamplingRules:      []*config.SamplingRule{{Service: "serv1", SampleRate: &rate}},
+	}
+	rules := sampler.NewSamplingRules(cfg)
+	a := &Agent{
+		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg, rules),
+		ErrorsSampler:     sampler.NewErrorsSampler(cfg, rules),
+		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}, rules),
+		RareSampler:       sampler.NewRareSampler(),
+		conf:              cfg,
+	}
+
+	root := &pb.Span{
+		TraceID:  1, // sampled at a rate of 0.5
+		Service:  "serv1",
+		Start:    time.Now().UnixNano(),
+		Duration: (100 * time.Millisecond).Nanoseconds(),
+		Metrics:  map[string]float64{"_top_level": 1},
+		Error:    1,
+	}
+	pt := traceutil.ProcessedTrace{TraceChunk: testutil.TraceChunkWithSpan(root), Root: root}
+	pt.TraceChunk.Priority = int32(sampler.PriorityAutoDrop)
+	// the chunk dropped by the tracer applying the rate of the rule is not kept by the
+	// errors sampler applying the rule a second time
+	assert.False(t, a.runSamplers(time.Now(), pt, true))
+}
+
 func TestPartialSamplingFree(t *testing.T) {
 	cfg := &config.AgentConfig{DisableRareSampler: true, BucketInterval: 10 * time.Second}
 	statsChan := make(chan pb.StatsPayload, 100)
@@ -948,9 +981,9 @@ func TestPartialSamplingFree(t *testing.T) {
 		Concentrator:      stats.NewConcentrator(cfg, statsChan, time.Now()),
 		Blacklister:       filters.NewBlacklister(cfg.Ignore["resource"]),
 		Replacer:          filters.NewReplacer(cfg.ReplaceTags),
-		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg),
-		ErrorsSampler:     sampler.NewErrorsSampler(cfg),
-		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}),
+		NoPrioritySampler: sampler.NewNoPrioritySampler(cfg, nil),
+		ErrorsSampler:     sampler.NewErrorsSampler(cfg, nil),
+		PrioritySampler:   sampler.NewPrioritySampler(cfg, &sampler.DynamicConfig{}, nil),
 		EventProcessor:    newEventProcessor(cfg),
 		RareSampler:       sampler.NewRareSampler(),
 		TraceWriter:       &writer.TraceWriter{In: writerChan},
@@ -0,0 +1,300 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"go.uber.org/atomic"
+)
+
+const (
+	// lintDropIgnoreResources is the drop reason of traces rejected by apm_config.ignore_resources.
+	lintDropIgnoreResources = "ignore_resources"
+	// lintDropFilterTags is the drop reason of traces rejected by apm_config.filter_tags.
+	lintDropFilterTags = "filter_tags"
+)
+
+// LintPayload implements api.PayloadLinter.
+func (a *Agent) LintPayload(tp *pb.TracerPayload, lang string) *api.LintReport {
+	rep := &api.LintReport{Payloads: 1}
+	for _, chunk := range tp.Chunks {
+		rep.Traces = append(rep.Traces, a.lintTrace(chunk.Spans, lang))
+	}
+	return rep
+}
+
+// LintLive implements api.PayloadLinter.
+func (a *Agent) LintLive(ctx context.Context, n int) *api.LintReport {
+	c := &lintCapture{n: n, report: &api.LintReport{}, done: make(chan struct{})}
+	a.liveLinter.add(a, c)
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		a.liveLinter.remove(c)
+	}
+	a.liveLinter.mu.Lock()
+	defer a.liveLinter.mu.Unlock()
+	return c.report
+}
+
+// lintTrace returns the diagnostics of trace t, received from a tracer of the given
+// language. It runs the same normalization, filtering and truncation steps as Process,
+// on a copy of the trace.
+func (a *Agent) lintTrace(t pb.Trace, lang string) *api.TraceDiagnostic {
+	d := &api.TraceDiagnostic{Spans: len(t)}
+	if len(t) > 0 {
+		d.TraceID = t[0].TraceID
+	}
+	ts := &info.TagStats{Tags: info.Tags{Lang: lang}, Stats: info.NewStats()}
+	normalized := copyTrace(t)
+	if err := normalizeTrace(ts, normalized); err != nil {
+		if reasons := ts.TracesDropped.Reasons(); len(reasons) > 0 {
+			d.DropReason = reasons[0]
+		}
+		d.Error = err.Error()
+		return d
+	}
+	root := traceutil.GetRoot(normalized)
+	if !a.Blacklister.Allows(root) {
+		d.DropReason = lintDropIgnoreResources
+		d.Error = "root span resource " + strconv.Quote(root.Resource) + " matches apm_config.ignore_resources"
+		return d
+	}
+	if filteredByTags(root, a.conf.RequireTags, a.conf.RejectTags) {
+		d.DropReason = lintDropFilterTags
+		d.Error = "root span tags do not meet apm_config.filter_tags requirements"
+		return d
+	}
+
+	spanIDs := make(map[uint64]struct{}, len(t))
+	for _, s := range t {
+		sd := lintSpan(s, lang)
+		if _, ok := spanIDs[s.SpanID]; ok {
+			sd.Reasons = append(sd.Reasons, "duplicate_span_id")
+		}
+		spanIDs[s.SpanID] = struct{}{}
+		if len(sd.Reasons) > 0 || len(sd.Changes) > 0 {
+			d.ModifiedSpans = append(d.ModifiedSpans, sd)
+		}
+	}
+	return d
+}
+
+// lintSpan returns the diagnostics of normalizing and truncating a copy of span s.
+func lintSpan(s *pb.Span, lang string) *api.SpanDiagnostic {
+	ts := &info.TagStats{Tags: info.Tags{Lang: lang}, Stats: info.NewStats()}
+	normalized := copySpan(s)
+	normalize(ts, normalized) //nolint:errcheck // the whole trace was already normalized
+	truncated := copySpan(normalized)
+	Truncate(truncated)
+
+	sd := &api.SpanDiagnostic{
+		SpanID:  s.SpanID,
+		Service: s.Service,
+		Name:    s.Name,
+		Reasons: ts.SpansMalformed.Reasons(),
+		Changes: diffSpans(s, truncated),
+	}
+	for _, c := range diffSpans(normalized, truncated) {
+		var reason string
+		switch {
+		case c.Field == "resource":
+			reason = "resource_truncate"
+		case strings.HasPrefix(c.Field, "metrics."):
+			reason = "metrics_key_truncate"
+		case c.Removed || c.Before == "":
+			// truncated keys are replaced, so their old key is removed and the new one added
+			reason = "meta_key_truncate"
+		default:
+			reason = "meta_value_truncate"
+		}
+		sd.Reasons = appendUnique(sd.Reasons, reason)
+	}
+	return sd
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// diffSpans returns the changes between spans a and b, sorted by field name.
+func diffSpans(a, b *pb.Span) []api.FieldChange {
+	var changes []api.FieldChange
+	diff := func(field, before, after string) {
+		if before != after {
+			changes = append(changes, api.FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	diff("service", a.Service, b.Service)
+	diff("name", a.Name, b.Name)
+	diff("resource", a.Resource, b.Resource)
+	diff("type", a.Type, b.Type)
+	diff("parent_id", strconv.FormatUint(a.ParentID, 10), strconv.FormatUint(b.ParentID, 10))
+	diff("start", strconv.FormatInt(a.Start, 10), strconv.FormatInt(b.Start, 10))
+	diff("duration", strconv.FormatInt(a.Duration, 10), strconv.FormatInt(b.Duration, 10))
+	for k, v := range a.Meta {
+		if bv, ok := b.Meta[k]; ok {
+			diff("meta."+k, v, bv)
+		} else {
+			changes = append(changes, api.FieldChange{Field: "meta." + k, Before: v, Removed: true})
+		}
+	}
+	for k, v := range b.Meta {
+		if _, ok := a.Meta[k]; !ok {
+			diff("meta."+k, "", v)
+		}
+	}
+	for k, v := range a.Metrics {
+		if _, ok := b.Metrics[k]; !ok {
+			changes = append(changes, api.FieldChange{Field: "metrics." + k, Before: strconv.FormatFloat(v, 'f', -1, 64), Removed: true})
+		}
+	}
+	for k, v := range b.Metrics {
+		if _, ok := a.Metrics[k]; !ok {
+			diff("metrics."+k, "", strconv.FormatFloat(v, 'f', -1, 64))
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func copySpan(s *pb.Span) *pb.Span {
+	c := *s
+	c.Meta = make(map[string]string, len(s.Meta))
+	for k, v := range s.Meta {
+		c.Meta[k] = v
+	}
+	c.Metrics = make(map[string]float64, len(s.Metrics))
+	for k, v := range s.Metrics {
+		c.Metrics[k] = v
+	}
+	return &c
+}
+
+func copyTrace(t pb.Trace) pb.Trace {
+	c := make(pb.Trace, len(t))
+	for i, s := range t {
+		c[i] = copySpan(s)
+	}
+	return c
+}
+
+// lintCapture collects the diagnostics of live payloads for a LintLive caller.
+type lintCapture struct {
+	// n is the number of payloads still expected.
+	n      int
+	report *api.LintReport
+	// done is closed once n payloads were linted.
+	done chan struct{}
+}
+
+// lintLiveQueueSize is the number of received payloads which can wait to be linted on
+// behalf of LintLive callers. Payloads received while the queue is full are not linted.
+const lintLiveQueueSize = 100
+
+// lintInput is a copy of a received payload, waiting to be linted.
+type lintInput struct {
+	tp   *pb.TracerPayload
+	lang string
+}
+
+// liveLinter lints the payloads received by the agent while LintLive calls are waiting
+// for them. The payloads are linted by a separate goroutine, started on the first LintLive
+// call, so that Process is not slowed down. Its zero value is ready to use.
+type liveLinter struct {
+	// active is the number of pending captures; it allows Process to skip linting
+	// without locking.
+	active atomic.Int32
+
+	start sync.Once
+	// in receives the payloads offered by Process.
+	in chan *lintInput
+
+	mu       sync.Mutex // guards below fields and the captures' reports
+	captures map[*lintCapture]struct{}
+}
+
+// add registers c, starting the goroutine linting the payloads of agent a if needed.
+func (l *liveLinter) add(a *Agent, c *lintCapture) {
+	l.start.Do(func() {
+		l.in = make(chan *lintInput, lintLiveQueueSize)
+		go l.run(a)
+	})
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.captures == nil {
+		l.captures = make(map[*lintCapture]struct{})
+	}
+	l.captures[c] = struct{}{}
+	l.active.Inc()
+}
+
+func (l *liveLinter) remove(c *lintCapture) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.captures[c]; ok {
+		delete(l.captures, c)
+		l.active.Dec()
+	}
+}
+
+// offer queues a copy of the payload p to be linted, if any LintLive call is waiting.
+// It never blocks: the payload is skipped when the queue is full.
+func (l *liveLinter) offer(p *api.Payload) {
+	if l.active.Load() == 0 {
+		return
+	}
+	chunks := make([]*pb.TraceChunk, len(p.TracerPayload.Chunks))
+	for i, c := range p.TracerPayload.Chunks {
+		chunks[i] = &pb.TraceChunk{Spans: copyTrace(c.Spans)}
+	}
+	select {
+	case l.in <- &lintInput{tp: &pb.TracerPayload{Chunks: chunks}, lang: p.Source.Lang}:
+	default:
+	}
+}
+
+// run lints the offered payloads until the context of agent a is done.
+func (l *liveLinter) run(a *Agent) {
+	for {
+		select {
+		case <-a.ctx.Done():
+			return
+		case in := <-l.in:
+			l.report(a.LintPayload(in.tp, in.lang))
+		}
+	}
+}
+
+// report adds rep to the reports of the pending captures.
+func (l *liveLinter) report(rep *api.LintReport) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for c := range l.captures {
+		c.report.Add(rep)
+		c.n--
+		if c.n <= 0 {
+			close(c.done)
+			delete(l.captures, c)
+			l.active.Dec()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLintTestAgent(t *testing.T) *Agent {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Ignore["resource"] = []string{"GET /health"}
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return NewAgent(ctx, cfg)
+}
+
+func TestLintPayload(t *testing.T) {
+	a := newLintTestAgent(t)
+	start := time.Now().UnixNano()
+	valid := &pb.Span{TraceID: 1, SpanID: 1, Service: "web", Name: "http.request", Resource: "GET /", Start: start}
+	malformed := &pb.Span{
+		TraceID:  1,
+		SpanID:   2,
+		ParentID: 1,
+		Name:     "http.request",
+		Resource: strings.Repeat("r", traceutil.MaxResourceLen+10),
+		Start:    start,
+		Duration: -1,
+		Meta: map[string]string{
+			"http.status_code": "999",
+			strings.Repeat("k", traceutil.MaxMetaKeyLen+1): "v",
+		},
+	}
+	tp := &pb.TracerPayload{Chunks: []*pb.TraceChunk{
+		{Spans: []*pb.Span{valid, malformed}},
+		{Spans: []*pb.Span{{TraceID: 0, SpanID: 3, Start: start}}},
+		{Spans: []*pb.Span{{TraceID: 4, SpanID: 4, Service: "web", Name: "http.request", Resource: "GET /health", Start: start}}},
+		{Spans: []*pb.Span{}},
+	}}
+	rep := a.LintPayload(tp, "go")
+	assert.Equal(t, 1, rep.Payloads)
+	require.Len(t, rep.Traces, 4)
+
+	d := rep.Traces[0]
+	assert.Equal(t, uint64(1), d.TraceID)
+	assert.Equal(t, 2, d.Spans)
+	assert.Empty(t, d.DropReason)
+	require.Len(t, d.ModifiedSpans, 1)
+	sd := d.ModifiedSpans[0]
+	assert.Equal(t, uint64(2), sd.SpanID)
+	assert.ElementsMatch(t, []string{
+		"service_empty",
+		"invalid_duration",
+		"invalid_http_status_code",
+		"resource_truncate",
+		"meta_key_truncate",
+	}, sd.Reasons)
+	fields := make(map[string]api.FieldChange)
+	for _, c := range sd.Changes {
+		fields[c.Field] = c
+	}
+	assert.Equal(t, api.FieldChange{Field: "service", Before: "", After: "unnamed-go-service"}, fields["service"])
+	assert.Equal(t, api.FieldChange{Field: "duration", Before: "-1", After: "0"}, fields["duration"])
+	assert.Equal(t, api.FieldChange{Field: "meta.http.status_code", Before: "999", Removed: true}, fields["meta.http.status_code"])
+	assert.Len(t, fields["resource"].After, traceutil.MaxResourceLen)
+	// the received spans are left untouched
+	assert.Equal(t, "", malformed.Service)
+	assert.Equal(t, int64(-1), malformed.Duration)
+
+	assert.Equal(t, "trace_id_zero", rep.Traces[1].DropReason)
+	assert.Contains(t, rep.Traces[1].Error, "TraceID is zero")
+	assert.Equal(t, lintDropIgnoreResources, rep.Traces[2].DropReason)
+	assert.Equal(t, "empty_trace", rep.Traces[3].DropReason)
+}
+
+func TestLintLive(t *testing.T) {
+	a := newLintTestAgent(t)
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		rep := a.LintLive(ctx, 1)
+		assert.Equal(t, 0, rep.Payloads)
+		assert.Equal(t, int32(0), a.liveLinter.active.Load())
+	})
+
+	t.Run("process", func(t *testing.T) {
+		reports := make(chan *api.LintReport)
+		go func() { reports <- a.LintLive(context.Background(), 2) }()
+		for a.liveLinter.active.Load() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for i := 0; i < 2; i++ {
+			a.Process(&api.Payload{
+				TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpan(&pb.Span{TraceID: 1, SpanID: 1})),
+				Source:        info.NewReceiverStats().GetTagStats(info.Tags{Lang: "go"}),
+			})
+		}
+		select {
+		case rep := <-reports:
+			assert.Equal(t, 2, rep.Payloads)
+			require.Len(t, rep.Traces, 2)
+			require.Len(t, rep.Traces[0].ModifiedSpans, 1)
+			assert.Contains(t, rep.Traces[0].ModifiedSpans[0].Reasons, "service_empty")
+		case <-time.After(time.Second):
+			t.Fatal("no report received")
+		}
+		assert.Equal(t, int32(0), a.liveLinter.active.Load())
+	})
+}
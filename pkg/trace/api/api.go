@@ -108,6 +108,7 @@ func (r *HTTPReceiver) buildMux() *http.ServeMux {
 
 	hash, infoHandler := r.makeInfoHandler()
 	r.attachDebugHandlers(mux)
+	r.attachLintHandlers(mux)
 	for _, e := range endpoints {
 		if e.IsEnabled != nil && !e.IsEnabled(r.conf) {
 			continue
@@ -136,10 +137,7 @@ func (r *HTTPReceiver) Start() {
 		return
 	}
 
-	timeout := 5 * time.Second
-	if r.conf.ReceiverTimeout > 0 {
-		timeout = time.Duration(r.conf.ReceiverTimeout) * time.Second
-	}
+	timeout := r.timeout()
 	httpLogger := log.NewThrottled(5, 10*time.Second) // limit to 5 messages every 10 seconds
 	r.server = &http.Server{
 		ReadTimeout:  timeout,
@@ -200,6 +198,14 @@ func (r *HTTPReceiver) Start() {
 	}()
 }
 
+// timeout returns the read and write timeout of the HTTP server.
+func (r *HTTPReceiver) timeout() time.Duration {
+	if r.conf.ReceiverTimeout > 0 {
+		return time.Duration(r.conf.ReceiverTimeout) * time.Second
+	}
+	return 5 * time.Second
+}
+
 func (r *HTTPReceiver) attachDebugHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
@@ -578,7 +584,12 @@ func (r *HTTPReceiver) handleTraces(v Version, w http.ResponseWriter, req *http.
 		ClientComputedStats:    req.Header.Get(headerComputedStats) != "",
 		ClientDroppedP0s:       droppedTracesFromHeader(req.Header, ts),
 	}
+	r.sendPayload(payload)
+}
 
+// sendPayload sends payload to the receiver's output channel, without ever
+// dropping it.
+func (r *HTTPReceiver) sendPayload(payload *Payload) {
 	select {
 	case r.out <- payload:
 		// ok
@@ -0,0 +1,467 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package jaeger implements the Thrift encoding of the batches sent by Jaeger clients, as
+// defined by the jaeger.thrift IDL of the Jaeger project. Only the structures used by the
+// trace receiver are implemented, and unknown fields are skipped.
+package jaeger
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+// TagType is the type of the value of a tag.
+type TagType int32
+
+// Tag types, as defined by the IDL.
+const (
+	TagTypeString TagType = 0
+	TagTypeDouble TagType = 1
+	TagTypeBool   TagType = 2
+	TagTypeLong   TagType = 3
+	TagTypeBinary TagType = 4
+)
+
+// SpanRefType is the type of a span reference.
+type SpanRefType int32
+
+// Span reference types, as defined by the IDL.
+const (
+	SpanRefTypeChildOf     SpanRefType = 0
+	SpanRefTypeFollowsFrom SpanRefType = 1
+)
+
+// Tag is a key/value pair attached to a span, a log or a process. Only the value
+// matching the type of the tag is encoded.
+type Tag struct {
+	Key     string
+	VType   TagType
+	VStr    string
+	VDouble float64
+	VBool   bool
+	VLong   int64
+	VBinary []byte
+}
+
+// Log is a timed event of a span.
+type Log struct {
+	Timestamp int64
+	Fields    []*Tag
+}
+
+// SpanRef is a reference from a span to another span.
+type SpanRef struct {
+	RefType     SpanRefType
+	TraceIDLow  int64
+	TraceIDHigh int64
+	SpanID      int64
+}
+
+// Span is a Jaeger span. Times are in microseconds.
+type Span struct {
+	TraceIDLow    int64
+	TraceIDHigh   int64
+	SpanID        int64
+	ParentSpanID  int64
+	OperationName string
+	References    []*SpanRef
+	Flags         int32
+	StartTime     int64
+	Duration      int64
+	Tags          []*Tag
+	Logs          []*Log
+}
+
+// Process describes the process emitting a batch.
+type Process struct {
+	ServiceName string
+	Tags        []*Tag
+}
+
+// Batch is a set of spans emitted by a process.
+type Batch struct {
+	Process *Process
+	Spans   []*Span
+}
+
+// field identifies a struct field by its ID and type.
+type field struct {
+	id  int16
+	typ thrift.TType
+}
+
+// readStruct reads the fields of a struct, calling read for each of them. read returns
+// false for unknown fields, which are skipped.
+func readStruct(ctx context.Context, iprot thrift.TProtocol, read func(f field) (bool, error)) error {
+	if _, err := iprot.ReadStructBegin(ctx); err != nil {
+		return err
+	}
+	for {
+		_, typ, id, err := iprot.ReadFieldBegin(ctx)
+		if err != nil {
+			return err
+		}
+		if typ == thrift.STOP {
+			break
+		}
+		known, err := read(field{id: id, typ: typ})
+		if err != nil {
+			return err
+		}
+		if !known {
+			if err := iprot.Skip(ctx, typ); err != nil {
+				return err
+			}
+		}
+		if err := iprot.ReadFieldEnd(ctx); err != nil {
+			return err
+		}
+	}
+	return iprot.ReadStructEnd(ctx)
+}
+
+// readList reads a list of structs, calling read for each of them.
+func readList(ctx context.Context, iprot thrift.TProtocol, read func() error) error {
+	typ, size, err := iprot.ReadListBegin(ctx)
+	if err != nil {
+		return err
+	}
+	if typ != thrift.STRUCT {
+		return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, fmt.Errorf("unexpected list element type %d", typ))
+	}
+	for i := 0; i < size; i++ {
+		if err := read(); err != nil {
+			return err
+		}
+	}
+	return iprot.ReadListEnd(ctx)
+}
+
+func readTags(ctx context.Context, iprot thrift.TProtocol) ([]*Tag, error) {
+	var tags []*Tag
+	err := readList(ctx, iprot, func() error {
+		t := &Tag{}
+		if err := t.Read(ctx, iprot); err != nil {
+			return err
+		}
+		tags = append(tags, t)
+		return nil
+	})
+	return tags, err
+}
+
+// Read reads the tag from iprot.
+func (t *Tag) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	return readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.STRING}:
+			t.Key, err = iprot.ReadString(ctx)
+		case field{2, thrift.I32}:
+			var v int32
+			v, err = iprot.ReadI32(ctx)
+			t.VType = TagType(v)
+		case field{3, thrift.STRING}:
+			t.VStr, err = iprot.ReadString(ctx)
+		case field{4, thrift.DOUBLE}:
+			t.VDouble, err = iprot.ReadDouble(ctx)
+		case field{5, thrift.BOOL}:
+			t.VBool, err = iprot.ReadBool(ctx)
+		case field{6, thrift.I64}:
+			t.VLong, err = iprot.ReadI64(ctx)
+		case field{7, thrift.STRING}:
+			t.VBinary, err = iprot.ReadBinary(ctx)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// Read reads the log from iprot.
+func (l *Log) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	return readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.I64}:
+			l.Timestamp, err = iprot.ReadI64(ctx)
+		case field{2, thrift.LIST}:
+			l.Fields, err = readTags(ctx, iprot)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// Read reads the span reference from iprot.
+func (r *SpanRef) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	return readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.I32}:
+			var v int32
+			v, err = iprot.ReadI32(ctx)
+			r.RefType = SpanRefType(v)
+		case field{2, thrift.I64}:
+			r.TraceIDLow, err = iprot.ReadI64(ctx)
+		case field{3, thrift.I64}:
+			r.TraceIDHigh, err = iprot.ReadI64(ctx)
+		case field{4, thrift.I64}:
+			r.SpanID, err = iprot.ReadI64(ctx)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// Read reads the span from iprot.
+func (s *Span) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	return readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.I64}:
+			s.TraceIDLow, err = iprot.ReadI64(ctx)
+		case field{2, thrift.I64}:
+			s.TraceIDHigh, err = iprot.ReadI64(ctx)
+		case field{3, thrift.I64}:
+			s.SpanID, err = iprot.ReadI64(ctx)
+		case field{4, thrift.I64}:
+			s.ParentSpanID, err = iprot.ReadI64(ctx)
+		case field{5, thrift.STRING}:
+			s.OperationName, err = iprot.ReadString(ctx)
+		case field{6, thrift.LIST}:
+			err = readList(ctx, iprot, func() error {
+				ref := &SpanRef{}
+				if err := ref.Read(ctx, iprot); err != nil {
+					return err
+				}
+				s.References = append(s.References, ref)
+				return nil
+			})
+		case field{7, thrift.I32}:
+			s.Flags, err = iprot.ReadI32(ctx)
+		case field{8, thrift.I64}:
+			s.StartTime, err = iprot.ReadI64(ctx)
+		case field{9, thrift.I64}:
+			s.Duration, err = iprot.ReadI64(ctx)
+		case field{10, thrift.LIST}:
+			s.Tags, err = readTags(ctx, iprot)
+		case field{11, thrift.LIST}:
+			err = readList(ctx, iprot, func() error {
+				l := &Log{}
+				if err := l.Read(ctx, iprot); err != nil {
+					return err
+				}
+				s.Logs = append(s.Logs, l)
+				return nil
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// Read reads the process from iprot.
+func (p *Process) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	return readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.STRING}:
+			p.ServiceName, err = iprot.ReadString(ctx)
+		case field{2, thrift.LIST}:
+			p.Tags, err = readTags(ctx, iprot)
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+}
+
+// Read reads the batch from iprot. The process and the spans of a batch are required.
+func (b *Batch) Read(ctx context.Context, iprot thrift.TProtocol) error {
+	var hasSpans bool
+	err := readStruct(ctx, iprot, func(f field) (known bool, err error) {
+		switch f {
+		case field{1, thrift.STRUCT}:
+			b.Process = &Process{}
+			err = b.Process.Read(ctx, iprot)
+		case field{2, thrift.LIST}:
+			hasSpans = true
+			err = readList(ctx, iprot, func() error {
+				s := &Span{}
+				if err := s.Read(ctx, iprot); err != nil {
+					return err
+				}
+				b.Spans = append(b.Spans, s)
+				return nil
+			})
+		default:
+			return false, nil
+		}
+		return true, err
+	})
+	if err != nil {
+		return err
+	}
+	if b.Process == nil {
+		return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, fmt.Errorf("required field process is not set"))
+	}
+	if !hasSpans {
+		return thrift.NewTProtocolExceptionWithType(thrift.INVALID_DATA, fmt.Errorf("required field spans is not set"))
+	}
+	return nil
+}
+
+// writer writes the fields of a struct, stopping at the first error.
+type writer struct {
+	ctx   context.Context
+	oprot thrift.TProtocol
+	err   error
+}
+
+func (w *writer) do(f func() error) {
+	if w.err == nil {
+		w.err = f()
+	}
+}
+
+func (w *writer) structBegin(name string) {
+	w.do(func() error { return w.oprot.WriteStructBegin(w.ctx, name) })
+}
+
+func (w *writer) structEnd() {
+	w.do(func() error { return w.oprot.WriteFieldStop(w.ctx) })
+	w.do(func() error { return w.oprot.WriteStructEnd(w.ctx) })
+}
+
+func (w *writer) field(name string, typ thrift.TType, id int16, value func() error) {
+	w.do(func() error { return w.oprot.WriteFieldBegin(w.ctx, name, typ, id) })
+	w.do(value)
+	w.do(func() error { return w.oprot.WriteFieldEnd(w.ctx) })
+}
+
+func (w *writer) i32(name string, id int16, v int32) {
+	w.field(name, thrift.I32, id, func() error { return w.oprot.WriteI32(w.ctx, v) })
+}
+
+func (w *writer) i64(name string, id int16, v int64) {
+	w.field(name, thrift.I64, id, func() error { return w.oprot.WriteI64(w.ctx, v) })
+}
+
+func (w *writer) str(name string, id int16, v string) {
+	w.field(name, thrift.STRING, id, func() error { return w.oprot.WriteString(w.ctx, v) })
+}
+
+// list writes a list field of n structs, write writing the i-th struct.
+func (w *writer) list(name string, id int16, n int, write func(i int) error) {
+	w.field(name, thrift.LIST, id, func() error {
+		if err := w.oprot.WriteListBegin(w.ctx, thrift.STRUCT, n); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			if err := write(i); err != nil {
+				return err
+			}
+		}
+		return w.oprot.WriteListEnd(w.ctx)
+	})
+}
+
+// tags writes an optional list of tags.
+func (w *writer) tags(id int16, tags []*Tag) {
+	if tags != nil {
+		w.list("tags", id, len(tags), func(i int) error { return tags[i].Write(w.ctx, w.oprot) })
+	}
+}
+
+// Write writes the tag to oprot.
+func (t *Tag) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Tag")
+	w.str("key", 1, t.Key)
+	w.i32("vType", 2, int32(t.VType))
+	switch t.VType {
+	case TagTypeString:
+		w.str("vStr", 3, t.VStr)
+	case TagTypeDouble:
+		w.field("vDouble", thrift.DOUBLE, 4, func() error { return oprot.WriteDouble(ctx, t.VDouble) })
+	case TagTypeBool:
+		w.field("vBool", thrift.BOOL, 5, func() error { return oprot.WriteBool(ctx, t.VBool) })
+	case TagTypeLong:
+		w.i64("vLong", 6, t.VLong)
+	case TagTypeBinary:
+		w.field("vBinary", thrift.STRING, 7, func() error { return oprot.WriteBinary(ctx, t.VBinary) })
+	}
+	w.structEnd()
+	return w.err
+}
+
+// Write writes the log to oprot.
+func (l *Log) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Log")
+	w.i64("timestamp", 1, l.Timestamp)
+	w.list("fields", 2, len(l.Fields), func(i int) error { return l.Fields[i].Write(ctx, oprot) })
+	w.structEnd()
+	return w.err
+}
+
+// Write writes the span reference to oprot.
+func (r *SpanRef) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("SpanRef")
+	w.i32("refType", 1, int32(r.RefType))
+	w.i64("traceIdLow", 2, r.TraceIDLow)
+	w.i64("traceIdHigh", 3, r.TraceIDHigh)
+	w.i64("spanId", 4, r.SpanID)
+	w.structEnd()
+	return w.err
+}
+
+// Write writes the span to oprot.
+func (s *Span) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Span")
+	w.i64("traceIdLow", 1, s.TraceIDLow)
+	w.i64("traceIdHigh", 2, s.TraceIDHigh)
+	w.i64("spanId", 3, s.SpanID)
+	w.i64("parentSpanId", 4, s.ParentSpanID)
+	w.str("operationName", 5, s.OperationName)
+	if s.References != nil {
+		w.list("references", 6, len(s.References), func(i int) error { return s.References[i].Write(ctx, oprot) })
+	}
+	w.i32("flags", 7, s.Flags)
+	w.i64("startTime", 8, s.StartTime)
+	w.i64("duration", 9, s.Duration)
+	w.tags(10, s.Tags)
+	if s.Logs != nil {
+		w.list("logs", 11, len(s.Logs), func(i int) error { return s.Logs[i].Write(ctx, oprot) })
+	}
+	w.structEnd()
+	return w.err
+}
+
+// Write writes the process to oprot.
+func (p *Process) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Process")
+	w.str("serviceName", 1, p.ServiceName)
+	w.tags(2, p.Tags)
+	w.structEnd()
+	return w.err
+}
+
+// Write writes the batch to oprot.
+func (b *Batch) Write(ctx context.Context, oprot thrift.TProtocol) error {
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Batch")
+	if b.Process != nil {
+		w.field("process", thrift.STRUCT, 1, func() error { return b.Process.Write(ctx, oprot) })
+	}
+	w.list("spans", 2, len(b.Spans), func(i int) error { return b.Spans[i].Write(ctx, oprot) })
+	w.structEnd()
+	return w.err
+}
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package jaeger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	batch := &Batch{
+		Process: &Process{
+			ServiceName: "checkout",
+			Tags:        []*Tag{{Key: "hostname", VType: TagTypeString, VStr: "web-1"}},
+		},
+		Spans: []*Span{{
+			TraceIDLow:    1,
+			TraceIDHigh:   2,
+			SpanID:        3,
+			ParentSpanID:  4,
+			OperationName: "GET",
+			References:    []*SpanRef{{RefType: SpanRefTypeFollowsFrom, TraceIDLow: 1, TraceIDHigh: 2, SpanID: 5}},
+			Flags:         2,
+			StartTime:     1000,
+			Duration:      10,
+			Tags: []*Tag{
+				{Key: "ratio", VType: TagTypeDouble, VDouble: 0.5},
+				{Key: "error", VType: TagTypeBool, VBool: true},
+				{Key: "status", VType: TagTypeLong, VLong: 500},
+				{Key: "payload", VType: TagTypeBinary, VBinary: []byte{0xca, 0xfe}},
+			},
+			Logs: []*Log{{Timestamp: 1005, Fields: []*Tag{{Key: "event", VType: TagTypeString, VStr: "error"}}}},
+		}},
+	}
+
+	buf := thrift.NewTMemoryBuffer()
+	require.NoError(t, batch.Write(ctx, thrift.NewTBinaryProtocolConf(buf, nil)))
+
+	var decoded Batch
+	require.NoError(t, decoded.Read(ctx, thrift.NewTBinaryProtocolConf(buf, nil)))
+	assert.Equal(t, batch, &decoded)
+}
+
+func TestBatchSkipsUnknownFields(t *testing.T) {
+	ctx := context.Background()
+	buf := thrift.NewTMemoryBuffer()
+	oprot := thrift.NewTBinaryProtocolConf(buf, nil)
+
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Batch")
+	w.field("process", thrift.STRUCT, 1, func() error {
+		return (&Process{ServiceName: "checkout"}).Write(ctx, oprot)
+	})
+	w.list("spans", 2, 0, nil)
+	w.i64("seqNo", 3, 42)
+	w.structEnd()
+	require.NoError(t, w.err)
+
+	var batch Batch
+	require.NoError(t, batch.Read(ctx, thrift.NewTBinaryProtocolConf(buf, nil)))
+	assert.Equal(t, "checkout", batch.Process.ServiceName)
+	assert.Empty(t, batch.Spans)
+}
+
+func TestBatchRequiredFields(t *testing.T) {
+	ctx := context.Background()
+	buf := thrift.NewTMemoryBuffer()
+	oprot := thrift.NewTBinaryProtocolConf(buf, nil)
+
+	w := &writer{ctx: ctx, oprot: oprot}
+	w.structBegin("Batch")
+	w.list("spans", 2, 0, nil)
+	w.structEnd()
+	require.NoError(t, w.err)
+
+	var batch Batch
+	assert.Error(t, batch.Read(ctx, thrift.NewTBinaryProtocolConf(buf, nil)))
+}
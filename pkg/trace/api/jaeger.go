@@ -0,0 +1,197 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/jaeger"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/apache/thrift/lib/go/thrift"
+)
+
+const (
+	// jaegerNoServiceName is the service of Jaeger spans whose process has no service name.
+	jaegerNoServiceName = "JaegerNoServiceName"
+
+	// jaegerFlagDebug is the Jaeger span flag marking spans which must be kept.
+	jaegerFlagDebug = 2
+)
+
+// handleJaeger handles Jaeger batches encoded with the Thrift binary protocol.
+func (r *HTTPReceiver) handleJaeger(v Version, w http.ResponseWriter, req *http.Request) {
+	r.handleThirdPartySpans(v, w, req, decodeJaegerThrift)
+}
+
+// decodeJaegerThrift decodes a Thrift binary encoded Jaeger batch.
+func decodeJaegerThrift(body []byte, ts *info.TagStats) (*pb.TracerPayload, error) {
+	buf := thrift.NewTMemoryBufferLen(len(body))
+	buf.Write(body) //nolint:errcheck
+	var batch jaeger.Batch
+	if err := batch.Read(context.Background(), thrift.NewTBinaryProtocolConf(buf, nil)); err != nil {
+		return nil, err
+	}
+	return jaegerTracerPayload(&batch, ts), nil
+}
+
+// jaegerTracerPayload converts the Jaeger batch into a tracer payload.
+func jaegerTracerPayload(batch *jaeger.Batch, ts *info.TagStats) *pb.TracerPayload {
+	tp := &pb.TracerPayload{
+		LanguageName:    ts.Lang,
+		LanguageVersion: ts.LangVersion,
+		TracerVersion:   ts.TracerVersion,
+	}
+	service := jaegerNoServiceName
+	var ptags map[string]string
+	if p := batch.Process; p != nil {
+		if p.ServiceName != "" {
+			service = p.ServiceName
+		}
+		ptags = make(map[string]string, len(p.Tags))
+		for _, t := range p.Tags {
+			ptags[t.Key] = jaegerTagString(t)
+		}
+		tp.Hostname = ptags["hostname"]
+		if v := ptags["jaeger.version"]; v != "" && tp.TracerVersion == "" {
+			tp.TracerVersion = v
+		}
+		if tp.LanguageName == "" {
+			// client versions are reported as "<Language>-<version>", e.g. "Go-2.30.0"
+			if i := strings.IndexByte(ptags["jaeger.version"], '-'); i > 0 {
+				tp.LanguageName = strings.ToLower(ptags["jaeger.version"][:i])
+			}
+		}
+		tp.ContainerID = ptags["container.id"]
+	}
+	b := newTraceChunkBuilder()
+	for _, js := range batch.Spans {
+		if js == nil {
+			continue
+		}
+		span := convertJaegerSpan(js, service, ptags)
+		if tp.Env == "" {
+			tp.Env = span.Meta["env"]
+		}
+		b.add(span, js.Flags&jaegerFlagDebug != 0)
+	}
+	tp.Chunks = b.build()
+	return tp
+}
+
+// convertJaegerSpan converts the Jaeger span js, emitted by service, into a Datadog span.
+// ptags holds the tags of the emitting process, which are added to the span. Only the
+// lower 64 bits of 128-bit trace IDs are kept, the full ID is stored in the
+// "jaeger.trace_id" tag.
+func convertJaegerSpan(js *jaeger.Span, service string, ptags map[string]string) *pb.Span {
+	span := &pb.Span{
+		Service:  service,
+		TraceID:  uint64(js.TraceIDLow),
+		SpanID:   uint64(js.SpanID),
+		ParentID: uint64(js.ParentSpanID),
+		Start:    js.StartTime * 1000,
+		Duration: js.Duration * 1000,
+		Meta:     make(map[string]string, len(ptags)+len(js.Tags)),
+		Metrics:  make(map[string]float64),
+	}
+	if span.ParentID == 0 {
+		for _, ref := range js.References {
+			if ref != nil && ref.RefType == jaeger.SpanRefTypeChildOf && ref.TraceIDLow == js.TraceIDLow {
+				span.ParentID = uint64(ref.SpanID)
+				break
+			}
+		}
+	}
+	if js.TraceIDHigh != 0 {
+		span.Meta["jaeger.trace_id"] = fmt.Sprintf("%x%016x", uint64(js.TraceIDHigh), uint64(js.TraceIDLow))
+	}
+	for k, v := range ptags {
+		span.Meta[k] = v
+	}
+	for _, t := range js.Tags {
+		if t == nil {
+			continue
+		}
+		switch {
+		case t.Key == "error":
+			if jaegerTagString(t) == "true" {
+				span.Error = 1
+			}
+		case t.Key == "sampling.priority" && (t.VType == jaeger.TagTypeLong || t.VType == jaeger.TagTypeDouble):
+			span.Metrics["_sampling_priority_v1"] = jaegerTagFloat(t)
+		case t.VType == jaeger.TagTypeLong || t.VType == jaeger.TagTypeDouble:
+			span.Metrics[t.Key] = jaegerTagFloat(t)
+		default:
+			span.Meta[t.Key] = jaegerTagString(t)
+		}
+	}
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta["deployment.environment"]; env != "" {
+			span.Meta["env"] = env
+		}
+	}
+	events := make([]spanEvent, 0, len(js.Logs))
+	for _, l := range js.Logs {
+		if l == nil {
+			continue
+		}
+		e := spanEvent{TimeUnixNano: l.Timestamp * 1000, Attributes: make(map[string]string, len(l.Fields))}
+		for _, f := range l.Fields {
+			e.Attributes[f.Key] = jaegerTagString(f)
+		}
+		if name, ok := e.Attributes["event"]; ok {
+			e.Name = name
+			delete(e.Attributes, "event")
+		}
+		if span.Error == 1 && e.Name == "error" {
+			// OpenTracing error logs
+			if msg := e.Attributes["message"]; msg != "" {
+				span.Meta["error.msg"] = msg
+			}
+			if kind := e.Attributes["error.kind"]; kind != "" {
+				span.Meta["error.type"] = kind
+			}
+			if stack := e.Attributes["stack"]; stack != "" {
+				span.Meta["error.stack"] = stack
+			}
+		}
+		events = append(events, e)
+	}
+	setSpanEvents(span, events)
+	setThirdPartySpanDefaults(span, "jaeger", strings.ToLower(span.Meta["span.kind"]), js.OperationName)
+	return span
+}
+
+// jaegerTagString returns the value of the Jaeger tag t as a string.
+func jaegerTagString(t *jaeger.Tag) string {
+	switch t.VType {
+	case jaeger.TagTypeString:
+		return t.VStr
+	case jaeger.TagTypeDouble:
+		return strconv.FormatFloat(t.VDouble, 'f', -1, 64)
+	case jaeger.TagTypeBool:
+		return strconv.FormatBool(t.VBool)
+	case jaeger.TagTypeLong:
+		return strconv.FormatInt(t.VLong, 10)
+	case jaeger.TagTypeBinary:
+		return hex.EncodeToString(t.VBinary)
+	}
+	return ""
+}
+
+// jaegerTagFloat returns the value of the numeric Jaeger tag t.
+func jaegerTagFloat(t *jaeger.Tag) float64 {
+	if t.VType == jaeger.TagTypeLong {
+		return float64(t.VLong)
+	}
+	return t.VDouble
+}
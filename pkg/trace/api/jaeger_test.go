@@ -0,0 +1,156 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/jaeger"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+	"github.com/apache/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jaegerStringTag(k, v string) *jaeger.Tag {
+	return &jaeger.Tag{Key: k, VType: jaeger.TagTypeString, VStr: v}
+}
+
+func jaegerLongTag(k string, v int64) *jaeger.Tag {
+	return &jaeger.Tag{Key: k, VType: jaeger.TagTypeLong, VLong: v}
+}
+
+func jaegerBoolTag(k string, v bool) *jaeger.Tag {
+	return &jaeger.Tag{Key: k, VType: jaeger.TagTypeBool, VBool: v}
+}
+
+func encodeJaegerBatch(t *testing.T, batch *jaeger.Batch) []byte {
+	buf := thrift.NewTMemoryBuffer()
+	require.NoError(t, batch.Write(context.Background(), thrift.NewTBinaryProtocolConf(buf, nil)))
+	return buf.Bytes()
+}
+
+func TestJaegerThrift(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(r.handleWithVersion(jaegerThrift, r.handleJaeger))
+	defer server.Close()
+
+	start := time.Unix(1652000000, 0)
+	body := encodeJaegerBatch(t, &jaeger.Batch{
+		Process: &jaeger.Process{
+			ServiceName: "checkout",
+			Tags: []*jaeger.Tag{
+				jaegerStringTag("hostname", "web-1"),
+				jaegerStringTag("jaeger.version", "Go-2.30.0"),
+			},
+		},
+		Spans: []*jaeger.Span{
+			{
+				TraceIDLow:    1,
+				TraceIDHigh:   2,
+				SpanID:        3,
+				OperationName: "HTTP POST",
+				StartTime:     start.UnixNano() / 1000,
+				Duration:      2000,
+				Tags: []*jaeger.Tag{
+					jaegerStringTag("span.kind", "server"),
+					jaegerStringTag("http.method", "POST"),
+					jaegerLongTag("http.status_code", 500),
+					jaegerBoolTag("error", true),
+					jaegerStringTag("env", "staging"),
+				},
+				Logs: []*jaeger.Log{{
+					Timestamp: start.UnixNano()/1000 + 10,
+					Fields: []*jaeger.Tag{
+						jaegerStringTag("event", "error"),
+						jaegerStringTag("message", "out of stock"),
+						jaegerStringTag("error.kind", "StockError"),
+					},
+				}},
+			},
+			{
+				TraceIDLow:    1,
+				TraceIDHigh:   2,
+				SpanID:        4,
+				OperationName: "reserve",
+				References:    []*jaeger.SpanRef{{RefType: jaeger.SpanRefTypeChildOf, TraceIDLow: 1, TraceIDHigh: 2, SpanID: 3}},
+				StartTime:     start.UnixNano() / 1000,
+				Duration:      1000,
+				Tags:          []*jaeger.Tag{jaegerLongTag("sampling.priority", 2)},
+			},
+			{
+				TraceIDLow:    5,
+				SpanID:        6,
+				OperationName: "debug",
+				Flags:         jaegerFlagDebug,
+			},
+		},
+	})
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/x-thrift")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		assert.Equal(t, string(jaegerThrift), p.Source.EndpointVersion)
+		tp := p.TracerPayload
+		assert.Equal(t, "web-1", tp.Hostname)
+		assert.Equal(t, "staging", tp.Env)
+		assert.Equal(t, "go", tp.LanguageName)
+		assert.Equal(t, "Go-2.30.0", tp.TracerVersion)
+		require.Len(t, tp.Chunks, 2)
+
+		chunk := tp.Chunks[0]
+		assert.EqualValues(t, sampler.PriorityUserKeep, chunk.Priority)
+		require.Len(t, chunk.Spans, 2)
+		root, child := chunk.Spans[0], chunk.Spans[1]
+		assert.Equal(t, uint64(1), root.TraceID)
+		assert.Equal(t, uint64(3), root.SpanID)
+		assert.Equal(t, start.UnixNano(), root.Start)
+		assert.Equal(t, int64(2*time.Millisecond), root.Duration)
+		assert.Equal(t, "checkout", root.Service)
+		assert.Equal(t, "jaeger.server", root.Name)
+		assert.Equal(t, "POST", root.Resource)
+		assert.Equal(t, "web", root.Type)
+		assert.Equal(t, int32(1), root.Error)
+		assert.Equal(t, "out of stock", root.Meta["error.msg"])
+		assert.Equal(t, "StockError", root.Meta["error.type"])
+		assert.Equal(t, 500.0, root.Metrics["http.status_code"])
+		assert.Equal(t, "web-1", root.Meta["hostname"])
+		assert.Equal(t, "20000000000000001", root.Meta["jaeger.trace_id"])
+		assert.Contains(t, root.Meta["events"], `"name":"error"`)
+
+		assert.Equal(t, uint64(3), child.ParentID)
+		assert.Equal(t, "jaeger.internal", child.Name)
+		assert.Equal(t, "reserve", child.Resource)
+		assert.Equal(t, 2.0, child.Metrics["_sampling_priority_v1"])
+
+		assert.EqualValues(t, sampler.PriorityUserKeep, tp.Chunks[1].Priority)
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+}
+
+func TestJaegerDecodingError(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	server := httptest.NewServer(r.handleWithVersion(jaegerThrift, r.handleJaeger))
+	defer server.Close()
+	resp, err := http.Post(server.URL, "application/x-thrift", bytes.NewReader([]byte("not thrift")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Empty(t, r.out)
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// defaultLintLivePayloads is the default number of live payloads linted by /debug/lint/live.
+const defaultLintLivePayloads = 10
+
+// PayloadLinter reports how the agent processes trace payloads. When the StatsProcessor
+// given to NewHTTPReceiver implements it, the receiver serves the /debug/lint endpoints.
+type PayloadLinter interface {
+	// LintPayload returns the diagnostics of tp, sent by a tracer of the given language.
+	// tp is not modified.
+	LintPayload(tp *pb.TracerPayload, lang string) *LintReport
+
+	// LintLive returns the diagnostics of the next n payloads received by the agent. It
+	// returns early, with the payloads received so far, when ctx is done.
+	LintLive(ctx context.Context, n int) *LintReport
+}
+
+// LintReport holds the diagnostics of one or more trace payloads: why their traces
+// would be dropped, or how their spans would be normalized and truncated.
+type LintReport struct {
+	// Payloads is the number of payloads linted.
+	Payloads int `json:"payloads"`
+	// Traces holds the diagnostics of all the traces found in the payloads.
+	Traces []*TraceDiagnostic `json:"traces"`
+}
+
+// Add appends the diagnostics of rep to r.
+func (r *LintReport) Add(rep *LintReport) {
+	r.Payloads += rep.Payloads
+	r.Traces = append(r.Traces, rep.Traces...)
+}
+
+// TraceDiagnostic holds the diagnostics of a single trace.
+type TraceDiagnostic struct {
+	// TraceID is the ID of the trace, as found in its first span.
+	TraceID uint64 `json:"trace_id"`
+	// Spans is the number of spans in the trace.
+	Spans int `json:"spans"`
+	// DropReason is set when the trace would be dropped, to the reason reported by the
+	// datadog.trace_agent.receiver.traces_dropped metric.
+	DropReason string `json:"drop_reason,omitempty"`
+	// Error explains why the trace would be dropped.
+	Error string `json:"error,omitempty"`
+	// ModifiedSpans holds the diagnostics of the spans which would be modified.
+	ModifiedSpans []*SpanDiagnostic `json:"modified_spans,omitempty"`
+}
+
+// SpanDiagnostic holds the modifications the agent would apply to a span.
+type SpanDiagnostic struct {
+	// SpanID is the ID of the span.
+	SpanID uint64 `json:"span_id"`
+	// Service and Name hold the span service and name, as received.
+	Service string `json:"service"`
+	Name    string `json:"name"`
+	// Reasons lists the reasons for which the span is modified. Normalization reasons
+	// match the ones reported by the datadog.trace_agent.normalizer.spans_malformed metric.
+	Reasons []string `json:"reasons"`
+	// Changes lists the modified fields.
+	Changes []FieldChange `json:"changes"`
+}
+
+// FieldChange describes the modification of a single span field, meta or metric.
+type FieldChange struct {
+	// Field is the name of the field, "meta.<key>" for meta and "metrics.<key>" for metrics.
+	Field string `json:"field"`
+	// Before and After hold the value of the field, before and after the modification.
+	Before string `json:"before"`
+	After  string `json:"after"`
+	// Removed reports whether the field is removed; After is then empty.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// attachLintHandlers attaches the /debug/lint endpoints to mux, if the receiver's stats
+// processor is a PayloadLinter. /debug/lint/live is only attached when enabled in the
+// configuration.
+func (r *HTTPReceiver) attachLintHandlers(mux *http.ServeMux) {
+	linter, ok := r.statsProcessor.(PayloadLinter)
+	if !ok {
+		return
+	}
+
+	// /debug/lint lints the payload in the request body. It is encoded as for the
+	// traces endpoint of the API version specified by the "v" query parameter (default v0.4).
+	mux.HandleFunc("/debug/lint", func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost && req.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		v := Version(req.URL.Query().Get("v"))
+		if v == "" {
+			v = v04
+		}
+		switch v {
+		case v01, v02, v03, v04, v05, V07:
+		default:
+			http.Error(w, fmt.Sprintf("unsupported API version %q", v), http.StatusBadRequest)
+			return
+		}
+		req.Body = apiutil.NewLimitedReader(req.Body, r.conf.MaxRequestBytes)
+		ts := &info.TagStats{Tags: info.Tags{Lang: req.Header.Get(headerLang), EndpointVersion: string(v)}, Stats: info.NewStats()}
+		tp, _, err := decodeTracerPayload(v, req, ts)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error decoding %s payload: %v", v, err), http.StatusBadRequest)
+			return
+		}
+		writeLintReport(w, linter.LintPayload(tp, ts.Lang))
+	})
+
+	if !r.conf.LintLiveEnabled {
+		return
+	}
+
+	// /debug/lint/live lints the next "n" payloads received by the agent, waiting
+	// for them at most "timeout". The timeout can not exceed the server's write timeout,
+	// so clients needing to wait longer should call it repeatedly.
+	maxTimeout := r.timeout() - 500*time.Millisecond
+	mux.HandleFunc("/debug/lint/live", func(w http.ResponseWriter, req *http.Request) {
+		n, timeout := defaultLintLivePayloads, maxTimeout
+		q := req.URL.Query()
+		if v := q.Get("n"); v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil || n <= 0 {
+				http.Error(w, "n must be a positive integer", http.StatusBadRequest)
+				return
+			}
+		}
+		if v := q.Get("timeout"); v != "" {
+			var err error
+			if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+				http.Error(w, "timeout must be a positive duration", http.StatusBadRequest)
+				return
+			}
+		}
+		if timeout > maxTimeout {
+			timeout = maxTimeout
+		}
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		writeLintReport(w, linter.LintLive(ctx, n))
+	})
+}
+
+func writeLintReport(w http.ResponseWriter, rep *LintReport) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(rep); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
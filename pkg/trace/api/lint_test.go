@@ -0,0 +1,124 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testLinter is a StatsProcessor and PayloadLinter recording its calls.
+type testLinter struct {
+	noopStatsProcessor
+	lang  string
+	spans int
+	live  int
+}
+
+func (l *testLinter) LintPayload(tp *pb.TracerPayload, lang string) *LintReport {
+	l.lang = lang
+	rep := &LintReport{Payloads: 1}
+	for _, chunk := range tp.Chunks {
+		l.spans += len(chunk.Spans)
+		rep.Traces = append(rep.Traces, &TraceDiagnostic{TraceID: chunk.Spans[0].TraceID, Spans: len(chunk.Spans)})
+	}
+	return rep
+}
+
+func (l *testLinter) LintLive(ctx context.Context, n int) *LintReport {
+	l.live = n
+	<-ctx.Done()
+	return &LintReport{}
+}
+
+func TestLintHandlers(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.LintLiveEnabled = true
+	linter := &testLinter{}
+	r := NewHTTPReceiver(conf, sampler.NewDynamicConfig(), make(chan *Payload, 1), linter)
+	server := httptest.NewServer(r.buildMux())
+	defer server.Close()
+
+	t.Run("payload", func(t *testing.T) {
+		bts, err := testutil.GetTestTraces(2, 3, true).MarshalMsg(nil)
+		require.NoError(t, err)
+		req, err := http.NewRequest("POST", server.URL+"/debug/lint?v=v0.4", bytes.NewReader(bts))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/msgpack")
+		req.Header.Set(headerLang, "python")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var rep LintReport
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&rep))
+		assert.Equal(t, 1, rep.Payloads)
+		assert.Len(t, rep.Traces, 2)
+		assert.Equal(t, "python", linter.lang)
+		assert.Equal(t, 6, linter.spans)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, tt := range []struct {
+			method, url string
+			status      int
+		}{
+			{"GET", "/debug/lint", http.StatusMethodNotAllowed},
+			{"POST", "/debug/lint?v=v9", http.StatusBadRequest},
+			{"POST", "/debug/lint?v=v0.4", http.StatusBadRequest},
+			{"GET", "/debug/lint/live?n=-1", http.StatusBadRequest},
+			{"GET", "/debug/lint/live?timeout=x", http.StatusBadRequest},
+		} {
+			req, err := http.NewRequest(tt.method, server.URL+tt.url, bytes.NewReader([]byte("invalid")))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/msgpack")
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			assert.Equal(t, tt.status, resp.StatusCode, tt.url)
+		}
+	})
+
+	t.Run("live", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/debug/lint/live?n=3&timeout=10ms")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 3, linter.live)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		r := newTestReceiverFromConfig(conf)
+		server := httptest.NewServer(r.buildMux())
+		defer server.Close()
+		resp, err := http.Get(server.URL + "/debug/lint/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("live-disabled", func(t *testing.T) {
+		r := NewHTTPReceiver(newTestReceiverConfig(), sampler.NewDynamicConfig(), make(chan *Payload, 1), linter)
+		server := httptest.NewServer(r.buildMux())
+		defer server.Close()
+		resp, err := http.Get(server.URL + "/debug/lint/live")
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
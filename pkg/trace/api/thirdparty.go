@@ -0,0 +1,190 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// spansDecoder decodes the body of a request sent by a third-party (non-Datadog)
+// tracing client into a tracer payload. The returned payload may omit its hostname,
+// environment and container ID, which are then filled in from the agent configuration
+// and the request headers.
+type spansDecoder func(body []byte, ts *info.TagStats) (*pb.TracerPayload, error)
+
+// handleThirdPartySpans handles a request sent by a third-party tracing client using the
+// endpoint version v, decoding its body with decode. The payload is then processed just
+// as the ones received on the Datadog endpoints.
+func (r *HTTPReceiver) handleThirdPartySpans(v Version, w http.ResponseWriter, req *http.Request, decode spansDecoder) {
+	ts := r.tagStats(v, req.Header)
+	start := time.Now()
+	tp, err := decodeThirdPartySpans(req, ts, decode, r.conf.MaxRequestBytes)
+	defer func(err error) {
+		tags := append(ts.AsTags(), fmt.Sprintf("success:%v", err == nil))
+		metrics.Histogram("datadog.trace_agent.receiver.serve_traces_ms", float64(time.Since(start))/float64(time.Millisecond), tags, 1)
+	}(err)
+	if err != nil {
+		httpDecodingError(err, []string{"handler:traces", fmt.Sprintf("v:%s", v)}, w)
+		log.Errorf("Cannot decode %s traces payload: %v", v, err)
+		return
+	}
+	if r.rateLimited(int64(len(tp.Chunks))) {
+		// this payload can not be accepted
+		w.WriteHeader(r.rateLimiterResponse)
+		ts.PayloadRefused.Inc()
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	ts.TracesReceived.Add(int64(len(tp.Chunks)))
+	ts.TracesBytes.Add(req.Body.(*apiutil.LimitedReader).Count)
+	ts.PayloadAccepted.Inc()
+
+	if tp.Hostname == "" {
+		tp.Hostname = r.conf.Hostname
+	}
+	if tp.Env == "" {
+		tp.Env = r.conf.DefaultEnv
+	}
+	tp.Env = traceutil.NormalizeTag(tp.Env)
+	if tp.ContainerID == "" {
+		tp.ContainerID = req.Header.Get(headerContainerID)
+	}
+	if ctags := getContainerTags(r.conf.ContainerTags, tp.ContainerID); ctags != "" {
+		if tp.Tags == nil {
+			tp.Tags = make(map[string]string)
+		}
+		tp.Tags[tagContainersTags] = ctags
+	}
+	r.sendPayload(&Payload{Source: ts, TracerPayload: tp})
+}
+
+// decodeThirdPartySpans reads the, possibly gzip compressed, body of req and decodes it
+// using decode. Compressed bodies are rejected with apiutil.ErrLimitedReaderLimitReached
+// when they decompress to more than maxBytes.
+func decodeThirdPartySpans(req *http.Request, ts *info.TagStats, decode spansDecoder, maxBytes int64) (*pb.TracerPayload, error) {
+	var body io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = apiutil.NewLimitedReader(gz, maxBytes)
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	return decode(b, ts)
+}
+
+// traceChunkBuilder groups spans received from third-party clients into trace chunks.
+type traceChunkBuilder struct {
+	chunks map[uint64]*pb.TraceChunk
+	// order holds the trace IDs in the order in which they were first seen.
+	order []uint64
+}
+
+func newTraceChunkBuilder() *traceChunkBuilder {
+	return &traceChunkBuilder{chunks: make(map[uint64]*pb.TraceChunk)}
+}
+
+// add adds span to the chunk of its trace. Debug spans, which were explicitly
+// requested to be kept by the client, mark their whole trace as user-kept.
+func (b *traceChunkBuilder) add(span *pb.Span, debug bool) {
+	chunk, ok := b.chunks[span.TraceID]
+	if !ok {
+		chunk = &pb.TraceChunk{Priority: int32(sampler.PriorityAutoKeep)}
+		b.chunks[span.TraceID] = chunk
+		b.order = append(b.order, span.TraceID)
+	}
+	if p, ok := span.Metrics["_sampling_priority_v1"]; ok {
+		chunk.Priority = int32(p)
+	}
+	if debug {
+		chunk.Priority = int32(sampler.PriorityUserKeep)
+	}
+	chunk.Spans = append(chunk.Spans, span)
+}
+
+// build returns the trace chunks, in the order in which their traces were first seen.
+func (b *traceChunkBuilder) build() []*pb.TraceChunk {
+	chunks := make([]*pb.TraceChunk, 0, len(b.order))
+	for _, id := range b.order {
+		chunks = append(chunks, b.chunks[id])
+	}
+	return chunks
+}
+
+// spanEvent is the JSON representation of a span event, as stored in the "events" tag
+// of spans received from third-party clients. It matches the representation used for
+// OTLP span events.
+type spanEvent struct {
+	TimeUnixNano int64             `json:"time_unix_nano,omitempty"`
+	Name         string            `json:"name,omitempty"`
+	Attributes   map[string]string `json:"attributes,omitempty"`
+}
+
+// setSpanEvents stores events in the "events" tag of span.
+func setSpanEvents(span *pb.Span, events []spanEvent) {
+	if len(events) == 0 {
+		return
+	}
+	b, err := json.Marshal(events)
+	if err != nil {
+		log.Debugf("Error marshalling span events: %v", err)
+		return
+	}
+	span.Meta["events"] = string(b)
+}
+
+// spanKindsByName maps lowercase span kind names, as found in Zipkin and OpenTracing
+// data, to their OpenTelemetry equivalent.
+var spanKindsByName = map[string]ptrace.SpanKind{
+	"":         ptrace.SpanKindInternal,
+	"internal": ptrace.SpanKindInternal,
+	"server":   ptrace.SpanKindServer,
+	"client":   ptrace.SpanKindClient,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+}
+
+// setThirdPartySpanDefaults fills in the name, resource and type of span based on its
+// tags, its span kind and the operation name op. prefix is the name of the client's
+// format (e.g. "zipkin").
+func setThirdPartySpanDefaults(span *pb.Span, prefix, kind, op string) {
+	k, ok := spanKindsByName[kind]
+	if !ok {
+		k = ptrace.SpanKindUnspecified
+	}
+	span.Name = prefix + "." + spanKindName(k)
+	if r := resourceFromTags(span.Meta); r != "" {
+		span.Resource = r
+	} else {
+		span.Resource = op
+	}
+	if span.Resource == "" {
+		span.Resource = span.Name
+	}
+	span.Type = spanKind2Type(k, span)
+}
@@ -81,4 +81,31 @@ const (
 	// Response: Service sampling rates.
 	//
 	V07 Version = "v0.7"
+
+	// zipkinV2JSON API
+	//
+	// Endpoint: /api/v2/spans
+	// Content-Type: application/json
+	// Payload: Zipkin v2 JSON spans list (https://zipkin.io/zipkin-api/#/default/post_spans)
+	// Response: 202 Accepted.
+	//
+	zipkinV2JSON Version = "zipkin_v2_json"
+
+	// zipkinV2Proto API
+	//
+	// Endpoint: /api/v2/spans
+	// Content-Type: application/x-protobuf
+	// Payload: Zipkin v2 ListOfSpans (zipkin.proto3)
+	// Response: 202 Accepted.
+	//
+	zipkinV2Proto Version = "zipkin_v2_proto"
+
+	// jaegerThrift API
+	//
+	// Endpoint: /api/traces
+	// Content-Type: application/x-thrift, application/vnd.apache.thrift.binary
+	// Payload: Jaeger Batch (jaeger.thrift), binary protocol
+	// Response: 202 Accepted.
+	//
+	jaegerThrift Version = "jaeger_thrift"
 )
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	zipkinmodel "github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/proto/zipkin_proto3"
+)
+
+// zipkinNoServiceName is the service of Zipkin spans having no local service name.
+const zipkinNoServiceName = "ZipkinNoServiceName"
+
+// handleZipkin handles Zipkin v2 spans, encoded either as JSON or as protobuf based on
+// the request's Content-Type.
+func (r *HTTPReceiver) handleZipkin(v Version, w http.ResponseWriter, req *http.Request) {
+	decode := decodeZipkinJSON
+	if getMediaType(req) == "application/x-protobuf" {
+		v, decode = zipkinV2Proto, decodeZipkinProto
+	}
+	r.handleThirdPartySpans(v, w, req, decode)
+}
+
+// decodeZipkinJSON decodes a JSON list of Zipkin v2 spans.
+func decodeZipkinJSON(body []byte, ts *info.TagStats) (*pb.TracerPayload, error) {
+	var spans []*zipkinmodel.SpanModel
+	if err := json.Unmarshal(body, &spans); err != nil {
+		return nil, err
+	}
+	return zipkinTracerPayload(spans, ts), nil
+}
+
+// decodeZipkinProto decodes a protobuf Zipkin v2 ListOfSpans.
+func decodeZipkinProto(body []byte, ts *info.TagStats) (*pb.TracerPayload, error) {
+	spans, err := zipkin_proto3.ParseSpans(body, false)
+	if err != nil {
+		return nil, err
+	}
+	return zipkinTracerPayload(spans, ts), nil
+}
+
+// zipkinTracerPayload converts Zipkin spans into a tracer payload.
+func zipkinTracerPayload(spans []*zipkinmodel.SpanModel, ts *info.TagStats) *pb.TracerPayload {
+	b := newTraceChunkBuilder()
+	var env string
+	for _, zs := range spans {
+		if zs == nil {
+			continue
+		}
+		span := convertZipkinSpan(zs)
+		if env == "" {
+			env = span.Meta["env"]
+		}
+		b.add(span, zs.Debug)
+	}
+	return &pb.TracerPayload{
+		Chunks:          b.build(),
+		Env:             env,
+		LanguageName:    ts.Lang,
+		LanguageVersion: ts.LangVersion,
+		TracerVersion:   ts.TracerVersion,
+	}
+}
+
+// convertZipkinSpan converts the Zipkin span zs into a Datadog span. Only the lower 64
+// bits of 128-bit trace IDs are kept, the full ID is stored in the "zipkin.trace_id" tag.
+func convertZipkinSpan(zs *zipkinmodel.SpanModel) *pb.Span {
+	span := &pb.Span{
+		TraceID:  zs.TraceID.Low,
+		SpanID:   uint64(zs.ID),
+		Duration: int64(zs.Duration),
+		Meta:     make(map[string]string, len(zs.Tags)+2),
+		Metrics:  make(map[string]float64),
+	}
+	if zs.ParentID != nil {
+		span.ParentID = uint64(*zs.ParentID)
+	}
+	if !zs.Timestamp.IsZero() {
+		span.Start = zs.Timestamp.UnixNano()
+	}
+	if zs.TraceID.High != 0 {
+		span.Meta["zipkin.trace_id"] = zs.TraceID.String()
+	}
+	for k, v := range zs.Tags {
+		span.Meta[k] = v
+	}
+	if _, ok := span.Meta["env"]; !ok {
+		if env := span.Meta["deployment.environment"]; env != "" {
+			span.Meta["env"] = env
+		}
+	}
+	if msg, ok := zs.Tags["error"]; ok {
+		// Zipkin sets the "error" tag to the error message, or to an empty string
+		span.Error = 1
+		delete(span.Meta, "error")
+		if msg != "" && msg != "true" {
+			span.Meta["error.msg"] = msg
+		}
+	}
+	kind := strings.ToLower(string(zs.Kind))
+	if kind != "" {
+		span.Meta["span.kind"] = kind
+	}
+	if e := zs.LocalEndpoint; e != nil && e.ServiceName != "" {
+		span.Service = e.ServiceName
+	} else {
+		span.Service = zipkinNoServiceName
+	}
+	if e := zs.RemoteEndpoint; e != nil {
+		if e.ServiceName != "" {
+			span.Meta["peer.service"] = e.ServiceName
+		}
+		if e.IPv4 != nil {
+			span.Meta["out.host"] = e.IPv4.String()
+		} else if e.IPv6 != nil {
+			span.Meta["out.host"] = e.IPv6.String()
+		}
+		if e.Port != 0 {
+			span.Meta["out.port"] = strconv.Itoa(int(e.Port))
+		}
+	}
+	events := make([]spanEvent, 0, len(zs.Annotations))
+	for _, a := range zs.Annotations {
+		events = append(events, spanEvent{TimeUnixNano: a.Timestamp.UnixNano(), Name: a.Value})
+	}
+	setSpanEvents(span, events)
+	setThirdPartySpanDefaults(span, "zipkin", kind, zs.Name)
+	return span
+}
@@ -0,0 +1,194 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+
+	zipkinmodel "github.com/openzipkin/zipkin-go/model"
+	"github.com/openzipkin/zipkin-go/proto/zipkin_proto3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testZipkinJSON = `[
+  {
+    "traceId": "5af7183fb1d4cf5f0000000000000002",
+    "id": "0000000000000003",
+    "name": "get /api",
+    "kind": "SERVER",
+    "timestamp": 1652000000000000,
+    "duration": 1500,
+    "localEndpoint": {"serviceName": "frontend"},
+    "tags": {"http.method": "GET", "http.route": "/api", "error": "connection reset", "env": "prod"},
+    "annotations": [{"timestamp": 1652000000000100, "value": "ws"}]
+  },
+  {
+    "traceId": "5af7183fb1d4cf5f0000000000000002",
+    "parentId": "0000000000000003",
+    "id": "0000000000000004",
+    "name": "query",
+    "kind": "CLIENT",
+    "timestamp": 1652000000000200,
+    "duration": 500,
+    "localEndpoint": {"serviceName": "frontend"},
+    "remoteEndpoint": {"serviceName": "mysql", "ipv4": "10.0.0.1", "port": 3306},
+    "tags": {"db.system": "mysql"}
+  },
+  {
+    "traceId": "0000000000000005",
+    "id": "0000000000000006",
+    "name": "job",
+    "debug": true
+  }
+]`
+
+func postZipkin(t *testing.T, r *HTTPReceiver, contentType string, body []byte, header map[string]string) *http.Response {
+	server := httptest.NewServer(r.handleWithVersion(zipkinV2JSON, r.handleZipkin))
+	defer server.Close()
+	req, err := http.NewRequest("POST", server.URL, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestZipkinJSON(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.Hostname = "agent-host"
+	r := newTestReceiverFromConfig(conf)
+
+	resp := postZipkin(t, r, "application/json", []byte(testZipkinJSON), nil)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		assert.Equal(t, string(zipkinV2JSON), p.Source.EndpointVersion)
+		assert.Equal(t, int64(1), p.Source.PayloadAccepted.Load())
+		assert.Equal(t, int64(2), p.Source.TracesReceived.Load())
+		tp := p.TracerPayload
+		assert.Equal(t, "agent-host", tp.Hostname)
+		assert.Equal(t, "prod", tp.Env)
+		require.Len(t, tp.Chunks, 2)
+
+		chunk := tp.Chunks[0]
+		assert.EqualValues(t, sampler.PriorityAutoKeep, chunk.Priority)
+		require.Len(t, chunk.Spans, 2)
+		root, child := chunk.Spans[0], chunk.Spans[1]
+		assert.Equal(t, uint64(2), root.TraceID)
+		assert.Equal(t, uint64(3), root.SpanID)
+		assert.Equal(t, uint64(0), root.ParentID)
+		assert.Equal(t, time.Unix(1652000000, 0).UnixNano(), root.Start)
+		assert.Equal(t, int64(1500*time.Microsecond), root.Duration)
+		assert.Equal(t, "frontend", root.Service)
+		assert.Equal(t, "zipkin.server", root.Name)
+		assert.Equal(t, "GET /api", root.Resource)
+		assert.Equal(t, "web", root.Type)
+		assert.Equal(t, int32(1), root.Error)
+		assert.Equal(t, "connection reset", root.Meta["error.msg"])
+		assert.Equal(t, "5af7183fb1d4cf5f0000000000000002", root.Meta["zipkin.trace_id"])
+		assert.Equal(t, `[{"time_unix_nano":1652000000000100000,"name":"ws"}]`, root.Meta["events"])
+
+		assert.Equal(t, uint64(3), child.ParentID)
+		assert.Equal(t, "zipkin.client", child.Name)
+		assert.Equal(t, "query", child.Resource)
+		assert.Equal(t, "db", child.Type)
+		assert.Equal(t, "mysql", child.Meta["peer.service"])
+		assert.Equal(t, "10.0.0.1", child.Meta["out.host"])
+		assert.Equal(t, "3306", child.Meta["out.port"])
+
+		debug := tp.Chunks[1]
+		assert.EqualValues(t, sampler.PriorityUserKeep, debug.Priority)
+		assert.Equal(t, zipkinNoServiceName, debug.Spans[0].Service)
+		assert.Equal(t, "zipkin.internal", debug.Spans[0].Name)
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+}
+
+func TestZipkinProto(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	parent := zipkinmodel.ID(7)
+	body, err := zipkin_proto3.SpanSerializer{}.Serialize([]*zipkinmodel.SpanModel{{
+		SpanContext: zipkinmodel.SpanContext{
+			TraceID:  zipkinmodel.TraceID{Low: 1},
+			ID:       8,
+			ParentID: &parent,
+		},
+		Name:          "consume",
+		Kind:          zipkinmodel.Consumer,
+		Timestamp:     time.Unix(1652000000, 0),
+		Duration:      time.Millisecond,
+		LocalEndpoint: &zipkinmodel.Endpoint{ServiceName: "worker"},
+		Tags:          map[string]string{"queue": "jobs"},
+	}})
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err = gz.Write(body)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	resp := postZipkin(t, r, "application/x-protobuf", buf.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case p := <-r.out:
+		assert.Equal(t, string(zipkinV2Proto), p.Source.EndpointVersion)
+		require.Len(t, p.TracerPayload.Chunks, 1)
+		span := p.TracerPayload.Chunks[0].Spans[0]
+		assert.Equal(t, uint64(1), span.TraceID)
+		assert.Equal(t, uint64(8), span.SpanID)
+		assert.Equal(t, uint64(7), span.ParentID)
+		assert.Equal(t, "worker", span.Service)
+		assert.Equal(t, "zipkin.consumer", span.Name)
+		assert.Equal(t, "consume", span.Resource)
+		assert.Equal(t, "jobs", span.Meta["queue"])
+		assert.Equal(t, "consumer", span.Meta["span.kind"])
+		assert.Equal(t, int64(time.Millisecond), span.Duration)
+	case <-time.After(time.Second):
+		t.Fatal("no data received")
+	}
+}
+
+func TestZipkinGzipTooLarge(t *testing.T) {
+	conf := newTestReceiverConfig()
+	conf.MaxRequestBytes = 1024
+	r := newTestReceiverFromConfig(conf)
+
+	// the compressed payload fits in the limit, but not the decompressed one
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(bytes.Repeat([]byte(" "), 64*1024))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	require.Less(t, buf.Len(), 1024)
+
+	resp := postZipkin(t, r, "application/json", buf.Bytes(), map[string]string{"Content-Encoding": "gzip"})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+	assert.Empty(t, r.out)
+}
+
+func TestZipkinDecodingError(t *testing.T) {
+	r := newTestReceiverFromConfig(newTestReceiverConfig())
+	resp := postZipkin(t, r, "application/json", []byte(`{"not":"a list"}`), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = postZipkin(t, r, "application/x-protobuf", []byte{0xff, 0xff}, nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Empty(t, r.out)
+}
@@ -217,6 +217,56 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// SpanMetric specifies a custom metric generated by the trace-agent from the spans
+// it receives, before any sampling takes place.
+type SpanMetric struct {
+	// Name specifies the name of the generated metric.
+	Name string `mapstructure:"name"`
+
+	// Type specifies the type of the metric: "count" (the default) or "distribution".
+	// Counts are reported as statsd counts of their weighted value. Distributions are
+	// reported as the ".count" and ".sum" counts and the ".min" and ".max" gauges.
+	Type string `mapstructure:"type"`
+
+	// Filter specifies a list of "key:value" conditions which a span must all meet
+	// in order to be accounted. The keys "service", "name", "resource", "type" and
+	// "error" address the respective span fields, any other key is looked up in the
+	// span's meta and metrics. A value ending in "*" matches by prefix.
+	Filter []string `mapstructure:"filter"`
+
+	// Value specifies what is measured: "duration" (in seconds) or the name of a
+	// numeric span metric or meta. When empty, count metrics count matching spans.
+	// It is required for distributions.
+	Value string `mapstructure:"value"`
+
+	// GroupBy specifies the span fields or tags used as metric tags, using the same
+	// keys as Filter. Spans without a given key are tagged with "<key>:none".
+	GroupBy []string `mapstructure:"group_by"`
+}
+
+// SamplingRule pins the sampling of the traces whose root span matches it, overriding
+// the rates computed by the agent's samplers. Rules are evaluated in order and the
+// first matching rule applies.
+type SamplingRule struct {
+	// Service specifies the service of the root span. When empty, any service matches.
+	Service string `mapstructure:"service"`
+
+	// Env specifies the env of the trace. When empty, any env matches.
+	Env string `mapstructure:"env"`
+
+	// Resource specifies a regular expression which the resource of the root span must
+	// match. When empty, any resource matches. Rates of rules without a resource are sent
+	// back to tracers, while rules with a resource are applied by the agent alone.
+	Resource string `mapstructure:"resource"`
+
+	// SampleRate specifies a fixed sampling rate, between 0 and 1.
+	SampleRate *float64 `mapstructure:"sample_rate"`
+
+	// TargetTPS specifies the number of traces per second to keep. When set, it takes
+	// precedence over SampleRate.
+	TargetTPS float64 `mapstructure:"target_tps"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -232,6 +282,29 @@ type WriterConfig struct {
 	FlushPeriodSeconds float64 `mapstructure:"flush_period_seconds"`
 }
 
+// LocalSinkConfig specifies the configuration for the local sink, which writes
+// the payloads sent by the trace and stats writers to rotating files on disk so
+// that they can be inspected or replayed at a later time.
+type LocalSinkConfig struct {
+	// Enabled reports whether payloads should be written to the local sink.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Dir specifies the directory where sink files are written.
+	Dir string `mapstructure:"dir"`
+
+	// Format specifies the encoding of the sink files. It can be either
+	// "protobuf" (the default) or "json" (JSON lines).
+	Format string `mapstructure:"format"`
+
+	// MaxFileSize specifies the size in bytes after which a sink file is
+	// rotated. Defaults to 10MB.
+	MaxFileSize int64 `mapstructure:"max_file_size"`
+
+	// MaxFiles specifies the maximum number of files kept on disk for each
+	// payload kind. When surpassed, the oldest files are removed. Defaults to 10.
+	MaxFiles int `mapstructure:"max_files"`
+}
+
 // FargateOrchestratorName is a Fargate orchestrator name.
 type FargateOrchestratorName string
 
@@ -295,6 +368,7 @@ type AgentConfig struct {
 	DisableRareSampler bool
 	MaxEPS             float64
 	MaxRemoteTPS       float64
+	SamplingRules      []*SamplingRule
 
 	// Receiver
 	ReceiverHost    string
@@ -314,7 +388,8 @@ type AgentConfig struct {
 	SynchronousFlushing     bool // Mode where traces are only submitted when FlushAsync is called, used for Serverless Extension
 	StatsWriter             *WriterConfig
 	TraceWriter             *WriterConfig
-	ConnectionResetInterval time.Duration // frequency at which outgoing connections are reset. 0 means no reset is performed
+	LocalSink               *LocalSinkConfig // optional local copy of written payloads, for debugging and replay
+	ConnectionResetInterval time.Duration    // frequency at which outgoing connections are reset. 0 means no reset is performed
 
 	// internal telemetry
 	StatsdHost     string
@@ -364,6 +439,16 @@ type AgentConfig struct {
 	// OTLPReceiver holds the configuration for OpenTelemetry receiver.
 	OTLPReceiver *OTLP
 
+	// ZipkinReceiverEnabled reports whether the receiver accepts Zipkin v2 spans on /api/v2/spans.
+	ZipkinReceiverEnabled bool
+
+	// JaegerReceiverEnabled reports whether the receiver accepts Jaeger Thrift batches on /api/traces.
+	JaegerReceiverEnabled bool
+
+	// LintLiveEnabled reports whether the receiver serves /debug/lint/live, which lints the
+	// payloads received by the agent.
+	LintLiveEnabled bool
+
 	// ProfilingProxy specifies settings for the profiling proxy.
 	ProfilingProxy ProfilingProxyConfig
 
@@ -389,6 +474,9 @@ type AgentConfig struct {
 
 	// ContainerTags ...
 	ContainerTags func(cid string) ([]string, error) `json:"-"`
+
+	// SpanMetrics specifies custom metrics to generate from all received spans.
+	SpanMetrics []*SpanMetric
 }
 
 // RemoteClient client is used to APM Sampling Updates from a remote source. Within the Datadog Agent
@@ -435,6 +523,7 @@ func New() *AgentConfig {
 
 		StatsWriter:             new(WriterConfig),
 		TraceWriter:             new(WriterConfig),
+		LocalSink:               new(LocalSinkConfig),
 		ConnectionResetInterval: 0, // disabled
 
 		StatsdHost: "localhost",
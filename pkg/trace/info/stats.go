@@ -196,6 +196,18 @@ func mapToString(m map[string]int64) string {
 	return strings.Join(results, ", ")
 }
 
+// nonZeroKeys returns the sorted keys of m having a positive value.
+func nonZeroKeys(m map[string]int64) []string {
+	var keys []string
+	for k, v := range m {
+		if v > 0 {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // TracesDropped contains counts for reasons traces have been dropped
 type TracesDropped struct {
 	// all atomic values are included as values in this struct, to simplify umarshaling and
@@ -239,6 +251,11 @@ func (s *TracesDropped) String() string {
 	return mapToString(s.tagValues())
 }
 
+// Reasons returns the sorted list of reasons for which traces were dropped.
+func (s *TracesDropped) Reasons() []string {
+	return nonZeroKeys(s.tagValues())
+}
+
 // SpansMalformed contains counts for reasons malformed spans have been accepted after applying automatic fixes
 type SpansMalformed struct {
 	// all atomic values are included as values in this struct, to simplify umarshaling and
@@ -292,6 +309,11 @@ func (s *SpansMalformed) String() string {
 	return mapToString(s.tagValues())
 }
 
+// Reasons returns the sorted list of reasons for which malformed spans were fixed.
+func (s *SpansMalformed) Reasons() []string {
+	return nonZeroKeys(s.tagValues())
+}
+
 // maxAbsPriority specifies the absolute maximum priority for stats purposes. For example, with a value
 // of 10, the range of priorities reported will be [-10, 10].
 const maxAbsPriority = 10
@@ -41,6 +41,10 @@ func TestTracesDropped(t *testing.T) {
 	t.Run("String", func(t *testing.T) {
 		assert.Equal(t, "decoding_error:1, foreign_span:1, span_id_zero:1, trace_id_zero:1", s.String())
 	})
+
+	t.Run("Reasons", func(t *testing.T) {
+		assert.Equal(t, []string{"decoding_error", "foreign_span", "span_id_zero", "trace_id_zero"}, s.Reasons())
+	})
 }
 
 func TestSpansMalformed(t *testing.T) {
@@ -71,6 +75,11 @@ func TestSpansMalformed(t *testing.T) {
 	t.Run("String", func(t *testing.T) {
 		assert.Equal(t, "resource_empty:1, service_empty:1, service_invalid:1, span_name_truncate:1, type_truncate:1", s.String())
 	})
+
+	t.Run("Reasons", func(t *testing.T) {
+		assert.Equal(t, []string{"resource_empty", "service_empty", "service_invalid", "span_name_truncate", "type_truncate"}, s.Reasons())
+		assert.Empty(t, (&SpansMalformed{}).Reasons())
+	})
 }
 
 func TestStatsTags(t *testing.T) {
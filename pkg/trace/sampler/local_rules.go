@@ -0,0 +1,179 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+)
+
+// SamplingRules holds the sampling rules configured on the agent (apm_config.sampling_rules).
+// A rule pins the rate of the traces whose root span it matches, either to a fixed
+// sample rate or to a rate adjusted to match a targetTPS. The rules are shared by the
+// samplers so that the targetTPS of a rule applies to all the chunks it matches.
+type SamplingRules struct {
+	rules []*localRule
+}
+
+type localRule struct {
+	service  string
+	env      string
+	resource *regexp.Regexp
+	// rate is the fixed sample rate of the rule, used when the rule has no targetTPS.
+	rate float64
+	// fixed is true when the rule applies rate instead of a rate computed by sampler.
+	fixed bool
+	// sampler counts the chunks matching the rule. When the rule has a targetTPS,
+	// it also computes the rates per signature to apply.
+	sampler *Sampler
+}
+
+// NewSamplingRules returns the sampling rules configured in conf, or nil if no rule is configured.
+func NewSamplingRules(conf *config.AgentConfig) *SamplingRules {
+	return newSamplingRules(conf.SamplingRules, []string{"sampler:rules"})
+}
+
+// newSamplingRules returns the rules compiled from conf. Invalid rules are logged and ignored.
+// It returns nil if no rule is configured.
+func newSamplingRules(conf []*config.SamplingRule, tags []string) *SamplingRules {
+	var rules []*localRule
+	for i, c := range conf {
+		r, err := newLocalRule(c, append([]string{"rule:" + strconv.Itoa(i)}, tags...))
+		if err != nil {
+			log.Errorf("Ignoring sampling rule #%d: %v", i, err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	return &SamplingRules{rules: rules}
+}
+
+func newLocalRule(c *config.SamplingRule, tags []string) (*localRule, error) {
+	r := &localRule{
+		service: c.Service,
+		env:     c.Env,
+	}
+	if c.Resource != "" {
+		re, err := regexp.Compile(c.Resource)
+		if err != nil {
+			return nil, err
+		}
+		r.resource = re
+	}
+	switch {
+	case c.TargetTPS > 0:
+		r.sampler = newSampler(1.0, c.TargetTPS, tags)
+	case c.SampleRate != nil:
+		if *c.SampleRate < 0 || *c.SampleRate > 1 {
+			return nil, fmt.Errorf("sample_rate must be between 0 and 1, got %f", *c.SampleRate)
+		}
+		r.rate = *c.SampleRate
+		r.fixed = true
+		r.sampler = newSampler(1.0, 0, tags)
+	default:
+		return nil, errors.New("one of sample_rate or target_tps must be set")
+	}
+	return r, nil
+}
+
+// match returns the first rule matching the root span and env, or nil.
+func (lr *SamplingRules) match(root *pb.Span, env string) *localRule {
+	if lr == nil {
+		return nil
+	}
+	for _, r := range lr.rules {
+		if r.service != "" && r.service != root.Service {
+			continue
+		}
+		if r.env != "" && r.env != env {
+			continue
+		}
+		if r.resource != nil && !r.resource.MatchString(root.Resource) {
+			continue
+		}
+		return r
+	}
+	return nil
+}
+
+// perService reports whether the rule applies to whole services. The rates of such
+// rules can be applied by tracers, unlike the ones of rules matching resources.
+func (r *localRule) perService() bool {
+	return r.resource == nil
+}
+
+// countWeightedSig counts a chunk matching the rule. It returns true if rates were updated.
+func (r *localRule) countWeightedSig(now time.Time, sig Signature, weight float32) bool {
+	return r.sampler.countWeightedSig(now, sig, weight)
+}
+
+// countSample counts a chunk sampled by the rule.
+func (r *localRule) countSample() {
+	r.sampler.countSample()
+}
+
+// getSignatureSampleRate returns the rate the rule applies to a signature.
+func (r *localRule) getSignatureSampleRate(sig Signature) float64 {
+	if r.fixed {
+		return r.rate
+	}
+	return r.sampler.getSignatureSampleRate(sig)
+}
+
+// getServiceSampleRates returns the rates applied to all signatures counted by rules
+// matching whole services. A signature counted by several rules keeps the rate of the first one.
+func (lr *SamplingRules) getServiceSampleRates() map[Signature]rm {
+	if lr == nil {
+		return nil
+	}
+	res := make(map[Signature]rm)
+	for _, r := range lr.rules {
+		if !r.perService() {
+			continue
+		}
+		rates, _ := r.sampler.getAllSignatureSampleRates()
+		for sig, rate := range rates {
+			if _, ok := res[sig]; ok {
+				continue
+			}
+			if r.fixed {
+				rate = r.rate
+			}
+			res[sig] = rm{r: rate}
+		}
+	}
+	return res
+}
+
+// Start runs the reporting loop of each rule.
+func (lr *SamplingRules) Start() {
+	if lr == nil {
+		return
+	}
+	for _, r := range lr.rules {
+		r.sampler.Start()
+	}
+}
+
+// Stop stops the reporting loop of each rule.
+func (lr *SamplingRules) Stop() {
+	if lr == nil {
+		return
+	}
+	for _, r := range lr.rules {
+		r.sampler.Stop()
+	}
+}
@@ -0,0 +1,231 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleRate(r float64) *float64 { return &r }
+
+func TestNewLocalRules(t *testing.T) {
+	assert.Nil(t, newSamplingRules(nil, nil))
+	rules := newSamplingRules([]*config.SamplingRule{
+		{Service: "web"}, // no rate
+		{Service: "web", SampleRate: ruleRate(1.5)}, // invalid rate
+		{Resource: "(", SampleRate: ruleRate(1)},    // invalid regexp
+		{Service: "web", SampleRate: ruleRate(0)},
+		{Resource: "^/healthz$", TargetTPS: 0.1, SampleRate: ruleRate(1)},
+	}, nil)
+	require.NotNil(t, rules)
+	require.Len(t, rules.rules, 2)
+	assert.True(t, rules.rules[0].fixed)
+	assert.Equal(t, 0.0, rules.rules[0].rate)
+	assert.False(t, rules.rules[1].fixed)
+	assert.Equal(t, 0.1, rules.rules[1].sampler.targetTPS.Load())
+}
+
+func TestLocalRulesMatch(t *testing.T) {
+	rules := newSamplingRules([]*config.SamplingRule{
+		{Service: "web", Resource: "^POST /checkout$", SampleRate: ruleRate(1)},
+		{Service: "web", Env: "prod", SampleRate: ruleRate(0.5)},
+		{Resource: "/healthz", TargetTPS: 0.1},
+	}, nil)
+	for _, tt := range []struct {
+		service, resource, env string
+		rule                   int
+	}{
+		{"web", "POST /checkout", "prod", 0},
+		{"web", "GET /checkout", "prod", 1},
+		{"web", "GET /checkout", "staging", -1},
+		{"web", "GET /healthz", "staging", 2},
+		{"db", "GET /healthz", "prod", 2},
+		{"db", "SELECT", "prod", -1},
+	} {
+		r := rules.match(&pb.Span{Service: tt.service, Resource: tt.resource}, tt.env)
+		if tt.rule < 0 {
+			assert.Nil(t, r, "%v", tt)
+			continue
+		}
+		assert.Equal(t, rules.rules[tt.rule], r, "%v", tt)
+	}
+	var none *SamplingRules
+	assert.Nil(t, none.match(&pb.Span{Service: "web"}, "prod"))
+}
+
+func TestPrioritySamplerServiceRule(t *testing.T) {
+	assert := assert.New(t)
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1.0,
+		TargetTPS:       10,
+		SamplingRules:   []*config.SamplingRule{{Service: "pinned", SampleRate: ruleRate(0.25)}},
+	}
+	s := NewPrioritySampler(conf, NewDynamicConfig(), NewSamplingRules(conf))
+
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		for _, svc := range []string{"pinned", "other"} {
+			chunk, root := getTestTraceWithService(t, svc, s)
+			delete(root.Metrics, agentRateKey)
+			chunk.Priority = int32(PriorityAutoKeep)
+			// the client choice is respected for rules matching whole services
+			assert.True(s.Sample(now, chunk, root, defaultEnv, 0))
+			if svc == "pinned" {
+				assert.Equal(0.25, root.Metrics[deprecatedRateKey])
+			}
+		}
+		now = now.Add(bucketDuration)
+	}
+
+	state := s.rateByService.GetNewState("")
+	assert.Equal(0.25, state.Rates[ServiceSignature{Name: "pinned", Env: defaultEnv}.String()])
+	assert.Equal(1.0, state.Rates[ServiceSignature{Name: "other", Env: defaultEnv}.String()])
+}
+
+func TestPrioritySamplerResourceRule(t *testing.T) {
+	assert := assert.New(t)
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1.0,
+		TargetTPS:       10,
+		SamplingRules: []*config.SamplingRule{
+			{Service: "web", Resource: "^POST /checkout$", SampleRate: ruleRate(1)},
+			{Resource: "/healthz", SampleRate: ruleRate(0)},
+		},
+	}
+	s := NewPrioritySampler(conf, NewDynamicConfig(), NewSamplingRules(conf))
+	now := time.Now()
+
+	chunk, root := getTestTraceWithService(t, "web", s)
+	root.Resource = "POST /checkout"
+	chunk.Priority = int32(PriorityAutoDrop)
+	assert.True(s.Sample(now, chunk, root, defaultEnv, 0), "rule keeps all checkouts")
+	assert.Equal(int32(PriorityAutoKeep), chunk.Priority)
+	assert.Equal(1.0, root.Metrics[agentRateKey])
+
+	chunk, root = getTestTraceWithService(t, "web", s)
+	root.Resource = "GET /healthz"
+	chunk.Priority = int32(PriorityAutoKeep)
+	assert.False(s.Sample(now, chunk, root, defaultEnv, 0), "rule drops all health checks")
+	assert.Equal(int32(PriorityAutoDrop), chunk.Priority)
+
+	chunk, root = getTestTraceWithService(t, "web", s)
+	root.Resource = "GET /healthz"
+	chunk.Priority = int32(PriorityUserKeep)
+	assert.True(s.Sample(now, chunk, root, defaultEnv, 0), "user choices are respected")
+
+	// rates of rules matching resources are not sent to tracers
+	s.updateRates()
+	state := s.rateByService.GetNewState("")
+	_, ok := state.Rates[ServiceSignature{Name: "web", Env: defaultEnv}.String()]
+	assert.False(ok)
+}
+
+func TestScoreSamplerRule(t *testing.T) {
+	assert := assert.New(t)
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1,
+		TargetTPS:       10,
+		SamplingRules:   []*config.SamplingRule{{Service: "mcnulty", Resource: "/healthz", SampleRate: ruleRate(0)}},
+	}
+	s := NewNoPrioritySampler(conf, NewSamplingRules(conf))
+	now := time.Now()
+
+	trace, root := getTestTrace()
+	root.Resource = "/healthz"
+	assert.False(s.Sample(now, trace, root, defaultEnv))
+
+	trace, root = getTestTrace()
+	root.Resource = "/checkout"
+	assert.True(s.Sample(now, trace, root, defaultEnv))
+	assert.Equal(1.0, root.Metrics[noPriorityRateKey])
+}
+
+func TestRuleTargetTPS(t *testing.T) {
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1,
+		TargetTPS:       10,
+		SamplingRules:   []*config.SamplingRule{{Resource: "/healthz", TargetTPS: 1}},
+	}
+	s := NewNoPrioritySampler(conf, NewSamplingRules(conf))
+
+	const generatedTPS = 50
+	now := time.Now()
+	var kept int
+	for i := 0; i < 30; i++ {
+		for j := 0; j < generatedTPS*int(bucketDuration.Seconds()); j++ {
+			trace, root := getTestTrace()
+			root.Resource = "/healthz"
+			if s.Sample(now, trace, root, defaultEnv) && i >= 20 {
+				kept++
+			}
+		}
+		now = now.Add(bucketDuration)
+	}
+	keptTPS := float64(kept) / (10 * bucketDuration.Seconds())
+	assert.InEpsilon(t, 1, keptTPS, 0.3)
+}
+
+func TestRuleTargetTPSSharedBySamplers(t *testing.T) {
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1,
+		TargetTPS:       10,
+		ErrorTPS:        10,
+		SamplingRules:   []*config.SamplingRule{{Resource: "/healthz", TargetTPS: 1}},
+	}
+	rules := NewSamplingRules(conf)
+	noPriority := NewNoPrioritySampler(conf, rules)
+	errors := NewErrorsSampler(conf, rules)
+
+	const generatedTPS = 50
+	now := time.Now()
+	var kept int
+	for i := 0; i < 30; i++ {
+		for j := 0; j < generatedTPS*int(bucketDuration.Seconds()); j++ {
+			trace, root := getTestTrace()
+			root.Resource = "/healthz"
+			s := noPriority.Sample
+			if j%2 == 0 {
+				s = errors.Sample
+			}
+			if s(now, trace, root, defaultEnv) && i >= 20 {
+				kept++
+			}
+		}
+		now = now.Add(bucketDuration)
+	}
+	// the target applies to the chunks of both samplers
+	keptTPS := float64(kept) / (10 * bucketDuration.Seconds())
+	assert.InEpsilon(t, 1, keptTPS, 0.3)
+}
+
+func TestPrioritySamplerSampledByRule(t *testing.T) {
+	assert := assert.New(t)
+	conf := &config.AgentConfig{
+		ExtraSampleRate: 1.0,
+		TargetTPS:       10,
+		SamplingRules:   []*config.SamplingRule{{Resource: "/healthz", SampleRate: ruleRate(0)}},
+	}
+	s := NewPrioritySampler(conf, NewDynamicConfig(), NewSamplingRules(conf))
+
+	chunk, root := getTestTraceWithService(t, "web", s)
+	root.Resource = "GET /healthz"
+	chunk.Priority = int32(PriorityAutoKeep)
+	assert.True(s.SampledByRule(chunk, root, defaultEnv))
+
+	chunk.Priority = int32(PriorityUserKeep)
+	assert.False(s.SampledByRule(chunk, root, defaultEnv), "rules don't apply to manual priorities")
+
+	chunk, root = getTestTraceWithService(t, "web", s)
+	root.Resource = "GET /checkout"
+	chunk.Priority = int32(PriorityAutoKeep)
+	assert.False(s.SampledByRule(chunk, root, defaultEnv))
+}
@@ -34,7 +34,8 @@ const (
 // PrioritySampler computes priority rates per tracerEnv, service to apply in a feedback loop with trace-agent clients.
 // Computed rates are sent in http responses to trace-agent. The rates are continuously adjusted in function
 // of the received traffic to match a targetTPS (target traces per second).
-// In order of priority, the sampler will match a targetTPS set remotely (remoteRates) and then the local targetTPS.
+// In order of priority, the sampler applies the locally configured sampling rules (rules), then matches a targetTPS
+// set remotely (remoteRates) and then the local targetTPS.
 type PrioritySampler struct {
 	agentEnv string
 	// localRates targetTPS is defined locally on the agent
@@ -45,6 +46,9 @@ type PrioritySampler struct {
 	// remoteRates can be nil if remote config is not enabled
 	// or in the core-agent remote client.
 	remoteRates *RemoteRates
+	// rules are sampling rules configured locally, shared with the other samplers. They take
+	// precedence over remoteRates and localRates. rules can be nil if no rule is configured.
+	rules *SamplingRules
 
 	// rateByService contains the sampling rates in % to communicate with trace-agent clients.
 	// This struct is shared with the agent API which sends the rates in http responses to spans post requests
@@ -54,11 +58,12 @@ type PrioritySampler struct {
 }
 
 // NewPrioritySampler returns an initialized Sampler
-func NewPrioritySampler(conf *config.AgentConfig, dynConf *DynamicConfig) *PrioritySampler {
+func NewPrioritySampler(conf *config.AgentConfig, dynConf *DynamicConfig, rules *SamplingRules) *PrioritySampler {
 	s := &PrioritySampler{
 		agentEnv:      conf.DefaultEnv,
 		localRates:    newSampler(conf.ExtraSampleRate, conf.TargetTPS, []string{"sampler:priority"}),
 		remoteRates:   newRemoteRates(conf.RemoteSamplingClient, conf.MaxRemoteTPS, conf.AgentVersion),
+		rules:         rules,
 		rateByService: &dynConf.RateByService,
 		catalog:       newServiceLookup(conf.MaxCatalogEntries),
 		exit:          make(chan struct{}),
@@ -129,7 +134,12 @@ func (s *PrioritySampler) Sample(now time.Time, trace *pb.TraceChunk, root *pb.S
 		return sampled
 	}
 
-	signature := s.catalog.register(ServiceSignature{Name: root.Service, Env: toSamplerEnv(tracerEnv, s.agentEnv)})
+	env := toSamplerEnv(tracerEnv, s.agentEnv)
+	signature := s.catalog.register(ServiceSignature{Name: root.Service, Env: env})
+
+	if rule := s.matchRule(trace, root, env); rule != nil {
+		return s.sampleWithRule(now, trace, root, rule, signature, clientDroppedP0sWeight)
+	}
 
 	// Update sampler state by counting this trace
 	s.countSignature(now, root, signature, clientDroppedP0sWeight)
@@ -141,6 +151,24 @@ func (s *PrioritySampler) Sample(now time.Time, trace *pb.TraceChunk, root *pb.S
 	return sampled
 }
 
+// matchRule returns the sampling rule deciding the sampling of a chunk, or nil. Sampling rules
+// only consider root spans and don't apply to the priorities set manually.
+func (s *PrioritySampler) matchRule(trace *pb.TraceChunk, root *pb.Span, env string) *localRule {
+	if root.ParentID != 0 {
+		return nil
+	}
+	if samplingPriority, _ := GetSamplingPriority(trace); samplingPriority < 0 || samplingPriority > 1 {
+		return nil
+	}
+	return s.rules.match(root, env)
+}
+
+// SampledByRule reports whether the sampling of a chunk is decided by a sampling rule. Such
+// chunks must not be sampled again by the ErrorsSampler, which would apply the rule a second time.
+func (s *PrioritySampler) SampledByRule(trace *pb.TraceChunk, root *pb.Span, tracerEnv string) bool {
+	return s.matchRule(trace, root, toSamplerEnv(tracerEnv, s.agentEnv)) != nil
+}
+
 // countSignature counts all chunks received with local chunk root signature.
 func (s *PrioritySampler) countSignature(now time.Time, root *pb.Span, signature Signature, clientDroppedP0Weight float64) {
 	rootWeight := weightRoot(root)
@@ -165,23 +193,61 @@ func (s *PrioritySampler) countSampled(now time.Time, root *pb.Span, signature S
 	}
 }
 
-func (s *PrioritySampler) applyRate(sampled bool, root *pb.Span, signature Signature) float64 {
-	if root.ParentID != 0 {
-		return 1.0
+// sampleWithRule samples a root chunk matching a locally configured sampling rule.
+// Rates of rules matching whole services are sent to tracers, which apply them: the client
+// choice is respected. Rules matching resources are applied by the agent alone, which then
+// overrides the automatic decision of the client.
+func (s *PrioritySampler) sampleWithRule(now time.Time, trace *pb.TraceChunk, root *pb.Span, rule *localRule, signature Signature, clientDroppedP0sWeight float64) bool {
+	if rule.countWeightedSig(now, signature, weightRoot(root)+float32(clientDroppedP0sWeight)) && rule.perService() {
+		s.updateRates()
+	}
+	rate := rule.getSignatureSampleRate(signature)
+
+	var sampled bool
+	if rule.perService() {
+		sampled = trace.Priority > 0
+		if _, ok := tracerRate(root); sampled && !ok {
+			setMetric(root, deprecatedRateKey, rate)
+		}
+	} else {
+		sampled = SampleByRate(root.TraceID, rate)
+		trace.Priority = int32(PriorityAutoDrop)
+		if sampled {
+			trace.Priority = int32(PriorityAutoKeep)
+			setMetric(root, agentRateKey, rate)
+		}
 	}
+	if sampled {
+		rule.countSample()
+	}
+	return sampled
+}
+
+// tracerRate returns the priority rate applied by the tracer to root, if any.
+func tracerRate(root *pb.Span) (float64, bool) {
 	// recent tracers annotate roots with applied priority rate
 	// agentRateKey is set when the agent computed rate is applied
 	if rate, ok := getMetric(root, agentRateKey); ok {
-		return rate
+		return rate, true
 	}
 	// ruleRateKey is set when a tracer rule rate is applied
 	if rate, ok := getMetric(root, ruleRateKey); ok {
-		return rate
+		return rate, true
 	}
 
 	// slow path used by older tracer versions
 	// dd-trace-go used to set the rate in deprecatedRateKey
 	if rate, ok := getMetric(root, deprecatedRateKey); ok {
+		return rate, true
+	}
+	return 0, false
+}
+
+func (s *PrioritySampler) applyRate(sampled bool, root *pb.Span, signature Signature) float64 {
+	if root.ParentID != 0 {
+		return 1.0
+	}
+	if rate, ok := tracerRate(root); ok {
 		return rate
 	}
 	if s.remoteRates != nil {
@@ -205,6 +271,15 @@ func (s *PrioritySampler) ratesByService() map[ServiceSignature]rm {
 	if s.remoteRates != nil {
 		remoteRates = s.remoteRates.getAllSignatureSampleRates()
 	}
+	// rates of sampling rules matching whole services take precedence over remote rates
+	if ruleRates := s.rules.getServiceSampleRates(); len(ruleRates) > 0 {
+		if remoteRates == nil {
+			remoteRates = make(map[Signature]rm, len(ruleRates))
+		}
+		for sig, r := range ruleRates {
+			remoteRates[sig] = r
+		}
+	}
 	localRates, defaultRate := s.localRates.getAllSignatureSampleRates()
 	return s.catalog.ratesByService(s.agentEnv, localRates, remoteRates, defaultRate)
 }
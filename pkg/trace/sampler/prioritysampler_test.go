@@ -29,7 +29,7 @@ func getTestPrioritySampler() *PrioritySampler {
 		TargetTPS:       0.0,
 	}
 
-	return NewPrioritySampler(conf, &DynamicConfig{})
+	return NewPrioritySampler(conf, &DynamicConfig{}, nil)
 }
 
 func getTestTraceWithService(t *testing.T, service string, s *PrioritySampler) (*pb.TraceChunk, *pb.Span) {
@@ -127,7 +127,7 @@ func TestPrioritySamplerWithNilRemote(t *testing.T) {
 		ExtraSampleRate: 1.0,
 		TargetTPS:       1.0,
 	}
-	s := NewPrioritySampler(conf, NewDynamicConfig())
+	s := NewPrioritySampler(conf, NewDynamicConfig(), nil)
 	s.Start()
 	s.updateRates()
 	s.reportStats()
@@ -141,7 +141,7 @@ func TestPrioritySamplerWithRemote(t *testing.T) {
 		ExtraSampleRate: 1.0,
 		TargetTPS:       1.0,
 	}
-	s := NewPrioritySampler(conf, NewDynamicConfig())
+	s := NewPrioritySampler(conf, NewDynamicConfig(), nil)
 	s.remoteRates = newRemoteRates(nil, 10, "6.0.0")
 	s.Start()
 	s.updateRates()
@@ -36,21 +36,24 @@ type ScoreSampler struct {
 	disabled        bool
 	mu              sync.Mutex
 	shrinkAllowList map[Signature]float64
+	// rules are sampling rules configured locally, shared with the other samplers. They take
+	// precedence over the rates computed by Sampler. rules can be nil if no rule is configured.
+	rules *SamplingRules
 }
 
 // NewNoPrioritySampler returns an initialized Sampler dedicated to traces with
 // no priority set.
-func NewNoPrioritySampler(conf *config.AgentConfig) *NoPrioritySampler {
+func NewNoPrioritySampler(conf *config.AgentConfig, rules *SamplingRules) *NoPrioritySampler {
 	s := newSampler(conf.ExtraSampleRate, conf.TargetTPS, []string{"sampler:no_priority"})
-	return &NoPrioritySampler{ScoreSampler{Sampler: s, samplingRateKey: noPriorityRateKey}}
+	return &NoPrioritySampler{ScoreSampler{Sampler: s, samplingRateKey: noPriorityRateKey, rules: rules}}
 }
 
 // NewErrorsSampler returns an initialized Sampler dedicate to errors. It behaves
 // just like the the normal ScoreEngine except for its GetType method (useful
 // for reporting).
-func NewErrorsSampler(conf *config.AgentConfig) *ErrorsSampler {
+func NewErrorsSampler(conf *config.AgentConfig, rules *SamplingRules) *ErrorsSampler {
 	s := newSampler(conf.ExtraSampleRate, conf.ErrorTPS, []string{"sampler:error"})
-	return &ErrorsSampler{ScoreSampler{Sampler: s, samplingRateKey: errorsRateKey, disabled: conf.ErrorTPS == 0}}
+	return &ErrorsSampler{ScoreSampler{Sampler: s, samplingRateKey: errorsRateKey, disabled: conf.ErrorTPS == 0, rules: rules}}
 }
 
 // Sample counts an incoming trace and tells if it is a sample which has to be kept
@@ -64,13 +67,25 @@ func (s *ScoreSampler) Sample(now time.Time, trace pb.Trace, root *pb.Span, env
 		return false
 	}
 	signature := computeSignatureWithRootAndEnv(trace, root, env)
+	if rule := s.rules.match(root, env); rule != nil {
+		rule.countWeightedSig(now, signature, weightRoot(root))
+		sampled := s.applySampleRate(root, rule.getSignatureSampleRate(signature))
+		if sampled {
+			rule.countSample()
+		}
+		return sampled
+	}
 	signature = s.shrink(signature)
 	// Update sampler state by counting this trace
 	s.countWeightedSig(now, signature, weightRoot(root))
 
 	rate := s.getSignatureSampleRate(signature)
 
-	return s.applySampleRate(root, rate)
+	sampled := s.applySampleRate(root, rate)
+	if sampled {
+		s.countSample()
+	}
+	return sampled
 }
 
 func (s *ScoreSampler) applySampleRate(root *pb.Span, rate float64) bool {
@@ -79,7 +94,6 @@ func (s *ScoreSampler) applySampleRate(root *pb.Span, rate float64) bool {
 	traceID := root.TraceID
 	sampled := SampleByRate(traceID, newRate)
 	if sampled {
-		s.countSample()
 		setMetric(root, s.samplingRateKey, rate)
 	}
 	return sampled
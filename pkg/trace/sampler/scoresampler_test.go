@@ -26,7 +26,7 @@ func getTestErrorsSampler(tps float64) *ErrorsSampler {
 		ExtraSampleRate: 1,
 		ErrorTPS:        tps,
 	}
-	return NewErrorsSampler(conf)
+	return NewErrorsSampler(conf, nil)
 }
 
 func getTestTrace() (pb.Trace, *pb.Span) {
@@ -0,0 +1,357 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+)
+
+const (
+	// SpanMetricCount is the type of span metrics counting spans or summing a value.
+	SpanMetricCount = "count"
+	// SpanMetricDistribution is the type of span metrics summarizing the distribution of a value
+	// with its count, sum, minimum and maximum.
+	SpanMetricDistribution = "distribution"
+
+	// spanMetricValueDuration specifies that the span duration is measured.
+	spanMetricValueDuration = "duration"
+	// tagSeparator separates the tags of a span metric context key; it can not be
+	// found in tag values.
+	tagSeparator = "\x00"
+)
+
+// spanFilter is a single "key:value" condition of a span metric filter.
+type spanFilter struct {
+	key, value string
+	// prefix reports whether value should match by prefix.
+	prefix bool
+}
+
+func (f *spanFilter) match(s *pb.Span) bool {
+	v, ok := spanTag(s, f.key)
+	if !ok {
+		return false
+	}
+	if f.prefix {
+		return strings.HasPrefix(v, f.value)
+	}
+	return v == f.value
+}
+
+// spanMetric is a validated config.SpanMetric.
+type spanMetric struct {
+	name         string
+	distribution bool
+	filters      []spanFilter
+	value        string
+	groupBy      []string
+}
+
+// newSpanMetric validates c and returns the corresponding spanMetric.
+func newSpanMetric(c *config.SpanMetric) (*spanMetric, error) {
+	if c.Name == "" {
+		return nil, fmt.Errorf("span metric has no name")
+	}
+	m := &spanMetric{name: c.Name, value: c.Value, groupBy: c.GroupBy}
+	switch c.Type {
+	case "", SpanMetricCount:
+	case SpanMetricDistribution:
+		if c.Value == "" {
+			return nil, fmt.Errorf("span metric %q: distributions require a value", c.Name)
+		}
+		m.distribution = true
+	default:
+		return nil, fmt.Errorf("span metric %q: unknown type %q", c.Name, c.Type)
+	}
+	for _, f := range c.Filter {
+		parts := strings.SplitN(f, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("span metric %q: invalid filter %q, expected \"key:value\"", c.Name, f)
+		}
+		sf := spanFilter{key: strings.TrimSpace(parts[0]), value: strings.TrimSpace(parts[1])}
+		if strings.HasSuffix(sf.value, "*") {
+			sf.value = strings.TrimSuffix(sf.value, "*")
+			sf.prefix = true
+		}
+		m.filters = append(m.filters, sf)
+	}
+	return m, nil
+}
+
+func (m *spanMetric) match(s *pb.Span) bool {
+	for i := range m.filters {
+		if !m.filters[i].match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// contextKey returns the tags of the metric computed for s, joined by tagSeparator.
+func (m *spanMetric) contextKey(s *pb.Span) string {
+	if len(m.groupBy) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i, k := range m.groupBy {
+		if i > 0 {
+			b.WriteString(tagSeparator)
+		}
+		v, ok := spanTag(s, k)
+		if !ok || v == "" {
+			v = "none"
+		}
+		b.WriteString(k)
+		b.WriteByte(':')
+		b.WriteString(strings.ReplaceAll(v, tagSeparator, ""))
+	}
+	return b.String()
+}
+
+// spanTag returns the value of the span field or tag named key.
+func spanTag(s *pb.Span, key string) (string, bool) {
+	switch key {
+	case "service":
+		return s.Service, true
+	case "name":
+		return s.Name, true
+	case "resource":
+		return s.Resource, true
+	case "type":
+		return s.Type, true
+	case "error":
+		return strconv.Itoa(int(s.Error)), true
+	}
+	if v, ok := s.Meta[key]; ok {
+		return v, true
+	}
+	if v, ok := s.Metrics[key]; ok {
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// spanValue returns the numeric value measured by m on span s.
+func (m *spanMetric) spanValue(s *pb.Span) (float64, bool) {
+	switch m.value {
+	case "":
+		return 1, true
+	case spanMetricValueDuration:
+		return float64(s.Duration) / float64(time.Second), true
+	}
+	if v, ok := s.Metrics[m.value]; ok {
+		return v, true
+	}
+	if v, ok := s.Meta[m.value]; ok {
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// spanMetricKey identifies a time series computed by SpanMetrics.
+type spanMetricKey struct {
+	// metric is the index of the span metric in SpanMetrics.metrics.
+	metric int
+	tags   string
+}
+
+// countKey identifies a count reported by SpanMetrics.
+type countKey struct {
+	name, tags string
+}
+
+// spanSummary summarizes the weighted values of a distribution span metric.
+type spanSummary struct {
+	count, sum, min, max float64
+}
+
+func (s *spanSummary) insert(v, weight float64) {
+	if s.count == 0 || v < s.min {
+		s.min = v
+	}
+	if s.count == 0 || v > s.max {
+		s.max = v
+	}
+	s.count += weight
+	s.sum += v * weight
+}
+
+// SpanMetrics generates the custom metrics defined in the agent configuration
+// (apm_config.span_metrics) from received spans. It is fed before sampling, so
+// that metrics account for all the traffic, and periodically reports the computed
+// values through the trace-agent's statsd client.
+//
+// Weighted counts and sums are reported as statsd counts, so that they add up when
+// several flushes land in the same DogStatsD interval and across agents. Their
+// fractional part is carried over to the next flush.
+type SpanMetrics struct {
+	metrics       []*spanMetric
+	flushInterval time.Duration
+
+	mu        sync.Mutex // guards below maps
+	counts    map[spanMetricKey]float64
+	summaries map[spanMetricKey]*spanSummary
+
+	flushMu sync.Mutex // guards remainders
+	// remainders holds the fractional part of the counts reported by the previous flush.
+	remainders map[countKey]float64
+
+	exit   chan struct{}
+	exitWG sync.WaitGroup
+}
+
+// NewSpanMetrics returns a new SpanMetrics computing the span metrics found in
+// conf. Invalid definitions are logged and ignored.
+func NewSpanMetrics(conf *config.AgentConfig) *SpanMetrics {
+	sm := &SpanMetrics{
+		flushInterval: conf.BucketInterval,
+		counts:        make(map[spanMetricKey]float64),
+		summaries:     make(map[spanMetricKey]*spanSummary),
+		remainders:    make(map[countKey]float64),
+		exit:          make(chan struct{}),
+	}
+	for _, c := range conf.SpanMetrics {
+		m, err := newSpanMetric(c)
+		if err != nil {
+			log.Errorf("Invalid span metric definition, ignoring: %v", err)
+			continue
+		}
+		sm.metrics = append(sm.metrics, m)
+	}
+	if sm.flushInterval <= 0 {
+		sm.flushInterval = 10 * time.Second
+	}
+	return sm
+}
+
+// Start starts periodically reporting span metrics.
+func (sm *SpanMetrics) Start() {
+	if len(sm.metrics) == 0 {
+		return
+	}
+	sm.exitWG.Add(1)
+	go func() {
+		defer watchdog.LogOnPanic()
+		defer sm.exitWG.Done()
+		t := time.NewTicker(sm.flushInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-t.C:
+				sm.Flush()
+			case <-sm.exit:
+				sm.Flush()
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops reporting span metrics, flushing the remaining values.
+func (sm *SpanMetrics) Stop() {
+	close(sm.exit)
+	sm.exitWG.Wait()
+}
+
+// Add accounts the spans of the processed trace pt into the span metrics. It is
+// a no-op on a nil SpanMetrics.
+func (sm *SpanMetrics) Add(pt *traceutil.ProcessedTrace) {
+	if sm == nil || len(sm.metrics) == 0 {
+		return
+	}
+	weight := weight(pt.Root)
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, s := range pt.TraceChunk.Spans {
+		for i, m := range sm.metrics {
+			if !m.match(s) {
+				continue
+			}
+			v, ok := m.spanValue(s)
+			if !ok {
+				continue
+			}
+			key := spanMetricKey{metric: i, tags: m.contextKey(s)}
+			if !m.distribution {
+				sm.counts[key] += v * weight
+				continue
+			}
+			sum, ok := sm.summaries[key]
+			if !ok {
+				sum = &spanSummary{}
+				sm.summaries[key] = sum
+			}
+			sum.insert(v, weight)
+		}
+	}
+}
+
+// Flush reports all span metrics computed since the previous flush.
+func (sm *SpanMetrics) Flush() {
+	sm.mu.Lock()
+	counts, summaries := sm.counts, sm.summaries
+	sm.counts = make(map[spanMetricKey]float64, len(counts))
+	sm.summaries = make(map[spanMetricKey]*spanSummary, len(summaries))
+	sm.mu.Unlock()
+
+	sm.flushMu.Lock()
+	defer sm.flushMu.Unlock()
+	remainders := make(map[countKey]float64, len(sm.remainders))
+	for k, v := range counts {
+		sm.count(remainders, sm.metrics[k.metric].name, k.tags, v)
+	}
+	for k, s := range summaries {
+		name := sm.metrics[k.metric].name
+		sm.count(remainders, name+".count", k.tags, s.count)
+		sm.count(remainders, name+".sum", k.tags, s.sum)
+		// the extremes of the flush interval can be aggregated with max and min
+		metrics.Gauge(name+".min", s.min, splitTags(k.tags), 1)
+		metrics.Gauge(name+".max", s.max, splitTags(k.tags), 1)
+	}
+	// the remainders of the counts which were not updated are rounded rather than
+	// kept forever
+	for k, r := range sm.remainders {
+		if _, ok := remainders[k]; ok {
+			continue
+		}
+		if n := int64(math.Round(r)); n != 0 {
+			metrics.Count(k.name, n, splitTags(k.tags), 1)
+		}
+	}
+	sm.remainders = remainders
+}
+
+// count reports the integer part of v, plus the remainder carried from the previous
+// flush, as the count name and stores its fractional part in remainders.
+func (sm *SpanMetrics) count(remainders map[countKey]float64, name, tags string, v float64) {
+	key := countKey{name: name, tags: tags}
+	total := v + sm.remainders[key]
+	n := int64(total)
+	remainders[key] = total - float64(n)
+	if n != 0 {
+		metrics.Count(name, n, splitTags(tags), 1)
+	}
+}
+
+func splitTags(tags string) []string {
+	if tags == "" {
+		return nil
+	}
+	return strings.Split(tags, tagSeparator)
+}
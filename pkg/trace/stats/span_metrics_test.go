@@ -0,0 +1,170 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/metrics"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingStatsClient records the last gauge and the sum of the counts of each
+// reported metric. It can not be replaced by testutil.TestStatsClient, which would
+// cause an import cycle.
+type recordingStatsClient struct {
+	counts, gauges map[string]float64
+}
+
+func newRecordingStatsClient() *recordingStatsClient {
+	return &recordingStatsClient{counts: make(map[string]float64), gauges: make(map[string]float64)}
+}
+
+func metricKey(name string, tags []string) string {
+	for _, tag := range tags {
+		name += "|" + tag
+	}
+	return name
+}
+
+func (c *recordingStatsClient) Gauge(name string, value float64, tags []string, _ float64) error {
+	c.gauges[metricKey(name, tags)] = value
+	return nil
+}
+
+func (c *recordingStatsClient) Count(name string, value int64, tags []string, _ float64) error {
+	c.counts[metricKey(name, tags)] += float64(value)
+	return nil
+}
+
+func (c *recordingStatsClient) Histogram(string, float64, []string, float64) error    { return nil }
+func (c *recordingStatsClient) Timing(string, time.Duration, []string, float64) error { return nil }
+func (c *recordingStatsClient) Flush() error                                          { return nil }
+
+func spanMetricsTrace(spans ...*pb.Span) *traceutil.ProcessedTrace {
+	return &traceutil.ProcessedTrace{
+		TraceChunk: &pb.TraceChunk{Spans: spans},
+		Root:       spans[0],
+	}
+}
+
+func TestNewSpanMetric(t *testing.T) {
+	for _, tt := range []struct {
+		in  config.SpanMetric
+		err bool
+	}{
+		{in: config.SpanMetric{Name: "a"}},
+		{in: config.SpanMetric{Name: "a", Type: "distribution", Value: "duration", Filter: []string{"service:web"}}},
+		{in: config.SpanMetric{Type: "count"}, err: true},
+		{in: config.SpanMetric{Name: "a", Type: "gauge"}, err: true},
+		{in: config.SpanMetric{Name: "a", Type: "distribution"}, err: true},
+		{in: config.SpanMetric{Name: "a", Filter: []string{"service"}}, err: true},
+	} {
+		_, err := newSpanMetric(&tt.in)
+		if tt.err {
+			assert.Error(t, err, tt.in.Name)
+		} else {
+			assert.NoError(t, err, tt.in.Name)
+		}
+	}
+}
+
+func TestSpanMetricMatch(t *testing.T) {
+	m, err := newSpanMetric(&config.SpanMetric{
+		Name:   "checkout.hits",
+		Filter: []string{"service:web", "resource:POST /checkout*", "http.status_code:200"},
+	})
+	assert.NoError(t, err)
+
+	span := &pb.Span{
+		Service:  "web",
+		Resource: "POST /checkout/confirm",
+		Meta:     map[string]string{"http.status_code": "200"},
+	}
+	assert.True(t, m.match(span))
+	span.Meta["http.status_code"] = "500"
+	assert.False(t, m.match(span))
+	span.Meta["http.status_code"] = "200"
+	span.Resource = "GET /checkout"
+	assert.False(t, m.match(span))
+}
+
+func TestSpanMetrics(t *testing.T) {
+	stats := newRecordingStatsClient()
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	sm := NewSpanMetrics(&config.AgentConfig{
+		SpanMetrics: []*config.SpanMetric{
+			{Name: "web.hits", Filter: []string{"service:web"}, GroupBy: []string{"resource", "region"}},
+			{Name: "web.latency", Type: "distribution", Value: "duration", Filter: []string{"service:web"}},
+			{Name: "invalid", Type: "distribution"},
+		},
+	})
+	assert.Len(t, sm.metrics, 2)
+
+	sm.Add(spanMetricsTrace(
+		&pb.Span{Service: "web", Resource: "/a", Duration: int64(time.Second), Meta: map[string]string{"region": "us"}},
+		&pb.Span{Service: "web", Resource: "/a", Duration: int64(3 * time.Second), Meta: map[string]string{"region": "us"}},
+		&pb.Span{Service: "web", Resource: "/b", Duration: int64(2 * time.Second)},
+		&pb.Span{Service: "db", Resource: "/a", Duration: int64(time.Second)},
+	))
+	// client-side sampled trace, weighted accordingly
+	sm.Add(spanMetricsTrace(
+		&pb.Span{Service: "web", Resource: "/b", Duration: int64(2 * time.Second), Metrics: map[string]float64{"_sample_rate": 0.5}},
+	))
+	sm.Flush()
+
+	assert.Equal(t, map[string]float64{
+		"web.hits|resource:/a|region:us":   2,
+		"web.hits|resource:/b|region:none": 3,
+		"web.latency.count":                5,
+		"web.latency.sum":                  10,
+	}, stats.counts)
+	assert.Equal(t, map[string]float64{"web.latency.max": 3, "web.latency.min": 1}, stats.gauges)
+
+	// values are reset after each flush
+	stats = newRecordingStatsClient()
+	metrics.Client = stats
+	sm.Flush()
+	assert.Empty(t, stats.counts)
+	assert.Empty(t, stats.gauges)
+}
+
+func TestSpanMetricsFractionalSum(t *testing.T) {
+	stats := newRecordingStatsClient()
+	defer func(old metrics.StatsClient) { metrics.Client = old }(metrics.Client)
+	metrics.Client = stats
+
+	sm := NewSpanMetrics(&config.AgentConfig{
+		SpanMetrics: []*config.SpanMetric{{Name: "cart.ratio", Value: "ratio"}},
+	})
+	for i := 0; i < 3; i++ {
+		sm.Add(spanMetricsTrace(
+			&pb.Span{Service: "web", Metrics: map[string]float64{"ratio": 0.1}},
+			&pb.Span{Service: "web", Metrics: map[string]float64{"ratio": 0.2}},
+		))
+		sm.Flush()
+	}
+
+	// the fractional part of the sums is carried over to the next flushes
+	assert.Empty(t, stats.counts)
+	sm.Add(spanMetricsTrace(&pb.Span{Service: "web", Metrics: map[string]float64{"ratio": 0.2}}))
+	sm.Flush()
+	assert.Equal(t, map[string]float64{"cart.ratio": 1}, stats.counts)
+
+	// the remainder of a count which is no longer updated is rounded
+	sm.Add(spanMetricsTrace(&pb.Span{Service: "web", Metrics: map[string]float64{"ratio": 0.6}}))
+	sm.Flush()
+	sm.Flush()
+	assert.Equal(t, map[string]float64{"cart.ratio": 2}, stats.counts)
+	assert.Empty(t, stats.gauges)
+}
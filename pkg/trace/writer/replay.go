@@ -0,0 +1,206 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	// agentPathTraces is the trace-agent API path accepting tracer payloads.
+	agentPathTraces = "/v0.7/traces"
+	// agentPathStats is the trace-agent API path accepting client stats payloads.
+	agentPathStats = "/v0.6/stats"
+)
+
+// Replayer sends records read from local sink files either to the Datadog intake,
+// as the writers would, or to the receiver of another trace-agent.
+type Replayer struct {
+	// agent reports whether the target is another trace-agent rather than the intake.
+	agent bool
+	// traces and stats send to the traces and stats API paths of the target.
+	traces, stats *sender
+}
+
+// NewReplayer returns a Replayer based on the configuration cfg. If agentURL is
+// empty, records are sent to the main configured endpoint; otherwise they are sent
+// to the trace-agent listening at agentURL, and cfg may be nil to use the default
+// HTTP client settings.
+func NewReplayer(cfg *config.AgentConfig, agentURL string) (*Replayer, error) {
+	if cfg == nil {
+		if agentURL == "" {
+			return nil, fmt.Errorf("no configuration to read the endpoint from")
+		}
+		cfg = config.New()
+	}
+	host, apiKey := agentURL, ""
+	tracesPath, statsPath := agentPathTraces, agentPathStats
+	if agentURL == "" {
+		if len(cfg.Endpoints) == 0 {
+			return nil, fmt.Errorf("no endpoint configured")
+		}
+		host, apiKey = cfg.Endpoints[0].Host, cfg.Endpoints[0].APIKey
+		tracesPath, statsPath = pathTraces, pathStats
+	}
+	newReplaySender := func(path string) (*sender, error) {
+		u, err := url.Parse(strings.TrimSuffix(host, "/") + path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replay target %q: %v", host, err)
+		}
+		// the sender is only used for its synchronous do method, so its loop is never started
+		return &sender{cfg: &senderConfig{
+			client: cfg.NewHTTPClient(),
+			url:    u,
+			apiKey: apiKey,
+		}}, nil
+	}
+	traces, err := newReplaySender(tracesPath)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := newReplaySender(statsPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{agent: agentURL != "", traces: traces, stats: stats}, nil
+}
+
+// ReplayFile sends all the records found in the sink file at path, returning the
+// number of requests which were successfully sent.
+func (r *Replayer) ReplayFile(path string) (int, error) {
+	var n int
+	err := ReadSinkFile(path, func(rec *SinkRecord) error {
+		sent, err := r.Replay(rec)
+		n += sent
+		return err
+	})
+	return n, err
+}
+
+// Replay sends the record rec to the target, returning the number of requests
+// which were successfully sent.
+func (r *Replayer) Replay(rec *SinkRecord) (int, error) {
+	var (
+		payloads []*payload
+		s        *sender
+		err      error
+	)
+	switch {
+	case rec.Traces != nil:
+		s = r.traces
+		payloads, err = r.tracePayloads(rec.Traces)
+	case rec.Stats != nil:
+		s = r.stats
+		payloads, err = r.statsPayloads(rec.Stats)
+	default:
+		return 0, fmt.Errorf("empty %s record", rec.Kind)
+	}
+	if err != nil {
+		return 0, err
+	}
+	for i, p := range payloads {
+		req, err := p.httpRequest(s.cfg.url)
+		if err != nil {
+			return i, err
+		}
+		if err := s.do(req); err != nil {
+			return i, fmt.Errorf("error sending %s to %s: %v", rec.Kind, s.cfg.url, err)
+		}
+		log.Debugf("Replayed %s payload to %s (%d bytes)", rec.Kind, s.cfg.url, p.body.Len())
+	}
+	return len(payloads), nil
+}
+
+// tracePayloads encodes ap as the trace writer would or, when replaying to another
+// agent, as one v0.7 request per tracer payload.
+func (r *Replayer) tracePayloads(ap *pb.AgentPayload) ([]*payload, error) {
+	if !r.agent {
+		b, err := proto.Marshal(ap)
+		if err != nil {
+			return nil, err
+		}
+		p := newPayload(map[string]string{
+			"Content-Type":     "application/x-protobuf",
+			"Content-Encoding": "gzip",
+		})
+		if err := gzipBytes(p.body, b); err != nil {
+			return nil, err
+		}
+		return []*payload{p}, nil
+	}
+	payloads := make([]*payload, 0, len(ap.TracerPayloads))
+	for _, tp := range ap.TracerPayloads {
+		b, err := tp.MarshalMsg(nil)
+		if err != nil {
+			return nil, err
+		}
+		p := newPayload(map[string]string{
+			"Content-Type":                "application/msgpack",
+			"Datadog-Meta-Lang":           tp.LanguageName,
+			"Datadog-Meta-Lang-Version":   tp.LanguageVersion,
+			"Datadog-Meta-Tracer-Version": tp.TracerVersion,
+			"Datadog-Container-ID":        tp.ContainerID,
+			// stats were recorded separately and are replayed as such, so the
+			// receiving agent must not compute them a second time.
+			"Datadog-Client-Computed-Stats": "yes",
+		})
+		p.body.Write(b)
+		payloads = append(payloads, p)
+	}
+	return payloads, nil
+}
+
+// statsPayloads encodes sp as the stats writer would or, when replaying to another
+// agent, as one v0.6 request per client stats payload.
+func (r *Replayer) statsPayloads(sp *pb.StatsPayload) ([]*payload, error) {
+	if !r.agent {
+		p := newPayload(map[string]string{
+			"Content-Type":     "application/msgpack",
+			"Content-Encoding": "gzip",
+		})
+		if err := encodePayload(p.body, *sp); err != nil {
+			return nil, err
+		}
+		return []*payload{p}, nil
+	}
+	payloads := make([]*payload, 0, len(sp.Stats))
+	for i := range sp.Stats {
+		csp := &sp.Stats[i]
+		p := newPayload(map[string]string{
+			"Content-Type":                "application/msgpack",
+			"Datadog-Meta-Lang":           csp.Lang,
+			"Datadog-Meta-Tracer-Version": csp.TracerVersion,
+		})
+		if err := msgp.Encode(p.body, csp); err != nil {
+			return nil, err
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads, nil
+}
+
+// gzipBytes writes b to buf, gzip compressed.
+func gzipBytes(buf *bytes.Buffer, b []byte) error {
+	gz, err := gzip.NewWriterLevel(buf, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	if _, err := gz.Write(b); err != nil {
+		return err
+	}
+	return gz.Close()
+}
@@ -0,0 +1,286 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	// SinkFormatProtobuf writes sink records as length-prefixed protobuf messages.
+	SinkFormatProtobuf = "protobuf"
+	// SinkFormatJSON writes sink records as JSON lines.
+	SinkFormatJSON = "json"
+
+	// defaultSinkMaxFileSize is the default size after which a sink file is rotated.
+	defaultSinkMaxFileSize = 10 * 1024 * 1024
+	// defaultSinkMaxFiles is the default number of files kept for each record kind.
+	defaultSinkMaxFiles = 10
+)
+
+// SinkRecordKind specifies the kind of payload stored in a sink record.
+type SinkRecordKind string
+
+const (
+	// SinkRecordTraces specifies a record holding a *pb.AgentPayload.
+	SinkRecordTraces SinkRecordKind = "traces"
+	// SinkRecordStats specifies a record holding a *pb.StatsPayload.
+	SinkRecordStats SinkRecordKind = "stats"
+)
+
+// SinkRecord is a single payload read back from a sink file. Depending on Kind,
+// exactly one of Traces or Stats is set.
+type SinkRecord struct {
+	Kind   SinkRecordKind   `json:"kind"`
+	Traces *pb.AgentPayload `json:"traces,omitempty"`
+	Stats  *pb.StatsPayload `json:"stats,omitempty"`
+}
+
+// sinkFileExtensions maps sink formats to the extension of the files they produce.
+var sinkFileExtensions = map[string]string{
+	SinkFormatProtobuf: ".pb",
+	SinkFormatJSON:     ".jsonl",
+}
+
+// fileSink writes payloads of a single kind to rotating files in a directory.
+// It is safe for concurrent use.
+type fileSink struct {
+	dir         string
+	kind        SinkRecordKind
+	format      string
+	maxFileSize int64
+	maxFiles    int
+
+	mu   sync.Mutex // guards below fields
+	f    *os.File
+	w    *bufio.Writer
+	size int64
+}
+
+// newFileSink returns a new fileSink writing records of the given kind based on
+// the configuration cfg. It returns nil if the sink is disabled.
+func newFileSink(cfg *config.LocalSinkConfig, kind SinkRecordKind) (*fileSink, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	if cfg.Dir == "" {
+		return nil, errors.New("local sink directory is not set")
+	}
+	format := strings.ToLower(cfg.Format)
+	if format == "" {
+		format = SinkFormatProtobuf
+	}
+	if _, ok := sinkFileExtensions[format]; !ok {
+		return nil, fmt.Errorf("unknown local sink format %q", cfg.Format)
+	}
+	if err := os.MkdirAll(cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &fileSink{
+		dir:         cfg.Dir,
+		kind:        kind,
+		format:      format,
+		maxFileSize: cfg.MaxFileSize,
+		maxFiles:    cfg.MaxFiles,
+	}
+	if s.maxFileSize <= 0 {
+		s.maxFileSize = defaultSinkMaxFileSize
+	}
+	if s.maxFiles <= 0 {
+		s.maxFiles = defaultSinkMaxFiles
+	}
+	return s, nil
+}
+
+// newWriterSink creates the sink for the given kind, logging and disabling it
+// in case of a configuration error.
+func newWriterSink(cfg *config.AgentConfig, kind SinkRecordKind) *fileSink {
+	s, err := newFileSink(cfg.LocalSink, kind)
+	if err != nil {
+		log.Errorf("Local sink for %s disabled: %v", kind, err)
+		return nil
+	}
+	if s != nil {
+		log.Infof("Writing %s payloads to local sink in %s (format=%s)", kind, s.dir, s.format)
+	}
+	return s
+}
+
+// Write writes the message m to the current sink file, rotating it if needed.
+func (s *fileSink) Write(m proto.Message) error {
+	var (
+		b   []byte
+		err error
+	)
+	switch s.format {
+	case SinkFormatJSON:
+		rec := SinkRecord{Kind: s.kind}
+		switch v := m.(type) {
+		case *pb.AgentPayload:
+			rec.Traces = v
+		case *pb.StatsPayload:
+			rec.Stats = v
+		default:
+			return fmt.Errorf("unsupported sink message type %T", m)
+		}
+		if b, err = json.Marshal(rec); err != nil {
+			return err
+		}
+		b = append(b, '\n')
+	default:
+		pbytes, err := proto.Marshal(m)
+		if err != nil {
+			return err
+		}
+		b = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(pbytes))
+		b = append(b[:binary.PutUvarint(b, uint64(len(pbytes)))], pbytes...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil || s.size+int64(len(b)) > s.maxFileSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.w.Write(b)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return s.w.Flush()
+}
+
+// rotate closes the current file, opens a new one and removes the oldest files
+// beyond maxFiles. It must be called with s.mu held.
+func (s *fileSink) rotate() error {
+	if err := s.closeFile(); err != nil {
+		log.Warnf("Error closing local sink file: %v", err)
+	}
+	name := fmt.Sprintf("%s-%d%s", s.kind, time.Now().UnixNano(), sinkFileExtensions[s.format])
+	f, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	s.w = bufio.NewWriter(f)
+	s.size = 0
+
+	files, err := filepath.Glob(filepath.Join(s.dir, string(s.kind)+"-*"+sinkFileExtensions[s.format]))
+	if err != nil {
+		return err
+	}
+	// file names embed a timestamp, so they sort chronologically
+	sort.Strings(files)
+	for len(files) > s.maxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			log.Warnf("Error removing old local sink file: %v", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+func (s *fileSink) closeFile() error {
+	if s.f == nil {
+		return nil
+	}
+	f := s.f
+	s.f = nil
+	if err := s.w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Close flushes and closes the current sink file.
+func (s *fileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeFile()
+}
+
+// ReadSinkFile reads all the records in the sink file at path, calling fn for each
+// of them. The format and kind of the file are inferred from its name. Reading stops
+// at the first error returned by fn.
+func ReadSinkFile(path string, fn func(*SinkRecord) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	if strings.HasSuffix(path, sinkFileExtensions[SinkFormatJSON]) {
+		dec := json.NewDecoder(r)
+		for {
+			var rec SinkRecord
+			if err := dec.Decode(&rec); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := fn(&rec); err != nil {
+				return err
+			}
+		}
+	}
+
+	var kind SinkRecordKind
+	switch base := filepath.Base(path); {
+	case strings.HasPrefix(base, string(SinkRecordTraces)+"-"):
+		kind = SinkRecordTraces
+	case strings.HasPrefix(base, string(SinkRecordStats)+"-"):
+		kind = SinkRecordStats
+	default:
+		return fmt.Errorf("can not infer record kind from file name %q", base)
+	}
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return err
+		}
+		rec := SinkRecord{Kind: kind}
+		if kind == SinkRecordTraces {
+			rec.Traces = &pb.AgentPayload{}
+			err = proto.Unmarshal(b, rec.Traces)
+		} else {
+			rec.Stats = &pb.StatsPayload{}
+			err = proto.Unmarshal(b, rec.Stats)
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(&rec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package writer
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/pb"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func testAgentPayload() *pb.AgentPayload {
+	return &pb.AgentPayload{
+		HostName: testHostname,
+		Env:      testEnv,
+		TracerPayloads: []*pb.TracerPayload{{
+			LanguageName: "go",
+			Chunks:       testutil.GetTestTraceChunks(2, 3, true),
+		}},
+	}
+}
+
+func testStatsPayload() *pb.StatsPayload {
+	return &pb.StatsPayload{
+		AgentHostname: testHostname,
+		AgentEnv:      testEnv,
+		Stats: []pb.ClientStatsPayload{
+			{Hostname: "a", Lang: "go", Stats: []pb.ClientStatsBucket{testutil.RandomBucket(3)}},
+			{Hostname: "b", Lang: "go", Stats: []pb.ClientStatsBucket{testutil.RandomBucket(3)}},
+		},
+	}
+}
+
+func readSinkDir(t *testing.T, dir string) []*SinkRecord {
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	var recs []*SinkRecord
+	for _, f := range files {
+		require.NoError(t, ReadSinkFile(f, func(rec *SinkRecord) error {
+			recs = append(recs, rec)
+			return nil
+		}))
+	}
+	return recs
+}
+
+func TestFileSink(t *testing.T) {
+	for _, format := range []string{"", SinkFormatProtobuf, SinkFormatJSON} {
+		t.Run("format:"+format, func(t *testing.T) {
+			dir := t.TempDir()
+			cfg := &config.LocalSinkConfig{Enabled: true, Dir: dir, Format: format}
+			traces, err := newFileSink(cfg, SinkRecordTraces)
+			require.NoError(t, err)
+			stats, err := newFileSink(cfg, SinkRecordStats)
+			require.NoError(t, err)
+
+			ap, sp := testAgentPayload(), testStatsPayload()
+			require.NoError(t, traces.Write(ap))
+			require.NoError(t, traces.Write(ap))
+			require.NoError(t, stats.Write(sp))
+			require.NoError(t, traces.Close())
+			require.NoError(t, stats.Close())
+
+			var ntraces, nstats int
+			for _, rec := range readSinkDir(t, dir) {
+				switch rec.Kind {
+				case SinkRecordTraces:
+					ntraces++
+					assert.True(t, proto.Equal(ap, rec.Traces))
+				case SinkRecordStats:
+					nstats++
+					assert.True(t, proto.Equal(sp, rec.Stats))
+				}
+			}
+			assert.Equal(t, 2, ntraces)
+			assert.Equal(t, 1, nstats)
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		s, err := newFileSink(&config.LocalSinkConfig{Dir: t.TempDir()}, SinkRecordTraces)
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := newFileSink(&config.LocalSinkConfig{Enabled: true}, SinkRecordTraces)
+		assert.Error(t, err)
+		_, err = newFileSink(&config.LocalSinkConfig{Enabled: true, Dir: t.TempDir(), Format: "xml"}, SinkRecordTraces)
+		assert.Error(t, err)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		dir := t.TempDir()
+		s, err := newFileSink(&config.LocalSinkConfig{
+			Enabled:     true,
+			Dir:         dir,
+			MaxFileSize: 1, // every write rotates
+			MaxFiles:    3,
+		}, SinkRecordTraces)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			require.NoError(t, s.Write(testAgentPayload()))
+		}
+		require.NoError(t, s.Close())
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		assert.Len(t, files, 3)
+		assert.Len(t, readSinkDir(t, dir), 3)
+	})
+}
+
+func TestReplayer(t *testing.T) {
+	rec := []*SinkRecord{
+		{Kind: SinkRecordTraces, Traces: testAgentPayload()},
+		{Kind: SinkRecordStats, Stats: testStatsPayload()},
+	}
+
+	t.Run("intake", func(t *testing.T) {
+		srv := newTestServer()
+		defer srv.Close()
+		cfg := &config.AgentConfig{Endpoints: []*config.Endpoint{{Host: srv.URL, APIKey: "123"}}}
+		r, err := NewReplayer(cfg, "")
+		require.NoError(t, err)
+		for _, rec := range rec {
+			n, err := r.Replay(rec)
+			require.NoError(t, err)
+			assert.Equal(t, 1, n)
+		}
+		payloads := srv.Payloads()
+		require.Len(t, payloads, 2)
+		for _, p := range payloads {
+			assert.Equal(t, "123", p.headers["Dd-Api-Key"])
+			assert.Equal(t, "gzip", p.headers["Content-Encoding"])
+		}
+		gz, err := gzip.NewReader(payloads[0].body)
+		require.NoError(t, err)
+		b, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+		var ap pb.AgentPayload
+		require.NoError(t, proto.Unmarshal(b, &ap))
+		assert.True(t, proto.Equal(rec[0].Traces, &ap))
+	})
+
+	t.Run("agent", func(t *testing.T) {
+		srv := newTestServer()
+		defer srv.Close()
+		// no configuration is needed to replay to a trace-agent
+		r, err := NewReplayer(nil, srv.URL)
+		require.NoError(t, err)
+		n, err := r.Replay(rec[0])
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		n, err = r.Replay(rec[1])
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+
+		payloads := srv.Payloads()
+		require.Len(t, payloads, 3)
+		var tp pb.TracerPayload
+		_, err = tp.UnmarshalMsg(payloads[0].body.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, "go", payloads[0].headers["Datadog-Meta-Lang"])
+		assert.Len(t, tp.Chunks, 2)
+		var csp pb.ClientStatsPayload
+		require.NoError(t, msgp.Decode(payloads[1].body, &csp))
+		assert.Equal(t, "a", csp.Hostname)
+	})
+	t.Run("no-config", func(t *testing.T) {
+		_, err := NewReplayer(nil, "")
+		assert.Error(t, err)
+	})
+}
@@ -41,6 +41,7 @@ const (
 type StatsWriter struct {
 	in      <-chan pb.StatsPayload
 	senders []*sender
+	sink    *fileSink // optional local sink; nil when disabled
 	stop    chan struct{}
 	stats   *info.StatsWriterInfo
 	conf    *config.AgentConfig
@@ -58,6 +59,7 @@ func NewStatsWriter(cfg *config.AgentConfig, in <-chan pb.StatsPayload) *StatsWr
 	sw := &StatsWriter{
 		in:        in,
 		stats:     &info.StatsWriterInfo{},
+		sink:      newWriterSink(cfg, SinkRecordStats),
 		stop:      make(chan struct{}),
 		flushChan: make(chan chan struct{}),
 		syncMode:  cfg.SynchronousFlushing,
@@ -127,6 +129,11 @@ func (w *StatsWriter) Stop() {
 	w.stop <- struct{}{}
 	<-w.stop
 	stopSenders(w.senders)
+	if w.sink != nil {
+		if err := w.sink.Close(); err != nil {
+			log.Errorf("Error closing stats writer local sink: %v", err)
+		}
+	}
 }
 
 func (w *StatsWriter) addStats(sp pb.StatsPayload) {
@@ -137,6 +144,11 @@ func (w *StatsWriter) addStats(sp pb.StatsPayload) {
 
 // SendPayload sends a stats payload to the Datadog backend.
 func (w *StatsWriter) SendPayload(p pb.StatsPayload) {
+	if w.sink != nil {
+		if err := w.sink.Write(&p); err != nil {
+			w.easylog.Warn("Error writing stats payload to local sink: %v", err)
+		}
+	}
 	req := newPayload(map[string]string{
 		headerLanguages:    strings.Join(info.Languages(), "|"),
 		"Content-Type":     "application/msgpack",
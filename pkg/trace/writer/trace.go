@@ -53,6 +53,7 @@ type TraceWriter struct {
 	targetTPS float64
 	errorTPS  float64
 	senders   []*sender
+	sink      *fileSink // optional local sink; nil when disabled
 	stop      chan struct{}
 	stats     *info.TraceWriterInfo
 	wg        sync.WaitGroup // waits for gzippers
@@ -78,6 +79,7 @@ func NewTraceWriter(cfg *config.AgentConfig) *TraceWriter {
 		targetTPS: cfg.TargetTPS,
 		errorTPS:  cfg.ErrorTPS,
 		stats:     &info.TraceWriterInfo{},
+		sink:      newWriterSink(cfg, SinkRecordTraces),
 		stop:      make(chan struct{}),
 		flushChan: make(chan chan struct{}),
 		syncMode:  cfg.SynchronousFlushing,
@@ -115,6 +117,11 @@ func (w *TraceWriter) Stop() {
 	w.stop <- struct{}{}
 	<-w.stop
 	stopSenders(w.senders)
+	if w.sink != nil {
+		if err := w.sink.Close(); err != nil {
+			log.Errorf("Error closing trace writer local sink: %v", err)
+		}
+	}
 }
 
 // Run starts the TraceWriter.
@@ -237,6 +244,11 @@ func (w *TraceWriter) flush() {
 		log.Errorf("Failed to serialize payload, data dropped: %v", err)
 		return
 	}
+	if w.sink != nil {
+		if err := w.sink.Write(&p); err != nil {
+			w.easylog.Warn("Error writing trace payload to local sink: %v", err)
+		}
+	}
 
 	w.stats.BytesUncompressed.Add(int64(len(b)))
 	w.stats.BytesEstimated.Add(int64(w.bufferedSize))
@@ -0,0 +1,86 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package compression provides the compression algorithms used to send payloads to the intake.
+// The algorithm is selected at runtime with NewCompressor.
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// NoneKind does not compress payloads
+	NoneKind = "none"
+	// ZlibKind compresses payloads with zlib (deflate)
+	ZlibKind = "zlib"
+	// GzipKind compresses payloads with gzip
+	GzipKind = "gzip"
+	// ZstdKind compresses payloads with zstd. It is only available in builds with the zstd build tag.
+	ZstdKind = "zstd"
+)
+
+// Compressor compresses and decompresses payloads with a given algorithm
+type Compressor interface {
+	// Compress compresses src as a whole
+	Compress(src []byte) ([]byte, error)
+	// Decompress decompresses src as a whole
+	Decompress(src []byte) ([]byte, error)
+	// CompressBound returns the worst case size needed for a destination buffer
+	CompressBound(sourceLen int) int
+	// ContentEncoding returns the HTTP Content-Encoding header value describing the
+	// compressed payloads, or an empty string if payloads are not compressed
+	ContentEncoding() string
+	// NewStreamCompressor returns a StreamCompressor writing the compressed data to output
+	NewStreamCompressor(output *bytes.Buffer) StreamCompressor
+}
+
+// StreamCompressor compresses the data written to it incrementally
+type StreamCompressor interface {
+	io.WriteCloser
+	// Flush writes the data compressed so far to the output
+	Flush() error
+}
+
+var compressors = map[string]Compressor{
+	NoneKind: noneCompressor{},
+	ZlibKind: zlibCompressor{},
+	GzipKind: gzipCompressor{},
+}
+
+// NewCompressor returns the Compressor of the given kind
+func NewCompressor(kind string) (Compressor, error) {
+	if c, ok := compressors[kind]; ok {
+		return c, nil
+	}
+	if kind == ZstdKind {
+		return nil, fmt.Errorf("%s compression is not available in this build", kind)
+	}
+	return nil, fmt.Errorf("unknown compression kind %q, available kinds are %v", kind, AvailableKinds())
+}
+
+// AvailableKinds returns the kinds of compression available in this build
+func AvailableKinds() []string {
+	kinds := make([]string, 0, len(compressors))
+	for kind := range compressors {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewCompressorForContentEncoding returns the Compressor producing payloads with the given
+// HTTP Content-Encoding header value. An empty encoding means that payloads are not compressed.
+func NewCompressorForContentEncoding(encoding string) (Compressor, error) {
+	for _, c := range compressors {
+		if c.ContentEncoding() == encoding {
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("no compression available for the content encoding %q", encoding)
+}
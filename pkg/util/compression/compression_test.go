@@ -0,0 +1,97 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPayload = bytes.Repeat([]byte(`{"metric":"system.cpu.user","points":[[1640995200,12.5]],"tags":["env:prod","service:web"],"host":"i-0123456789"},`), 1000)
+
+func TestNewCompressor(t *testing.T) {
+	for _, kind := range AvailableKinds() {
+		c, err := NewCompressor(kind)
+		assert.NoError(t, err, kind)
+		assert.NotNil(t, c, kind)
+	}
+	_, err := NewCompressor("lz4")
+	assert.Error(t, err)
+}
+
+func TestNewCompressorForContentEncoding(t *testing.T) {
+	for _, kind := range AvailableKinds() {
+		c, _ := NewCompressor(kind)
+		found, err := NewCompressorForContentEncoding(c.ContentEncoding())
+		assert.NoError(t, err, kind)
+		assert.Equal(t, c, found, kind)
+	}
+	_, err := NewCompressorForContentEncoding("br")
+	assert.Error(t, err)
+}
+
+func TestCompressors(t *testing.T) {
+	for _, kind := range AvailableKinds() {
+		t.Run(kind, func(t *testing.T) {
+			c, err := NewCompressor(kind)
+			require.NoError(t, err)
+
+			compressed, err := c.Compress(testPayload)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(compressed), c.CompressBound(len(testPayload)))
+			if kind != NoneKind {
+				assert.Less(t, len(compressed), len(testPayload))
+				assert.NotEmpty(t, c.ContentEncoding())
+			}
+
+			decompressed, err := c.Decompress(compressed)
+			require.NoError(t, err)
+			assert.Equal(t, testPayload, decompressed)
+		})
+	}
+}
+
+func TestStreamCompressors(t *testing.T) {
+	for _, kind := range AvailableKinds() {
+		t.Run(kind, func(t *testing.T) {
+			c, err := NewCompressor(kind)
+			require.NoError(t, err)
+
+			var output bytes.Buffer
+			w := c.NewStreamCompressor(&output)
+			half := len(testPayload) / 2
+			_, err = w.Write(testPayload[:half])
+			require.NoError(t, err)
+			require.NoError(t, w.Flush())
+			_, err = w.Write(testPayload[half:])
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			decompressed, err := c.Decompress(output.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, testPayload, decompressed)
+		})
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	for _, kind := range AvailableKinds() {
+		c, _ := NewCompressor(kind)
+		b.Run(fmt.Sprintf("kind=%s", kind), func(b *testing.B) {
+			var compressed []byte
+			b.SetBytes(int64(len(testPayload)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				compressed, _ = c.Compress(testPayload)
+			}
+			b.ReportMetric(float64(len(compressed)), "bytes/payload")
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+)
+
+// gzipCompressor compresses with gzip
+type gzipCompressor struct{}
+
+// Compress will compress the data with gzip
+func (gzipCompressor) Compress(src []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w := gzip.NewWriter(&b)
+	_, err := w.Write(src)
+	if err != nil {
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Decompress will decompress the data with gzip
+func (gzipCompressor) Decompress(src []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (gzipCompressor) CompressBound(sourceLen int) int {
+	// Same deflate bound as zlib, with the 18 bytes gzip header and trailer
+	// instead of the 6 bytes zlib ones.
+	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 25
+}
+
+// ContentEncoding returns the HTTP header value associated with gzip
+func (gzipCompressor) ContentEncoding() string {
+	return "gzip"
+}
+
+// NewStreamCompressor returns a gzip writer
+func (gzipCompressor) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return gzip.NewWriter(output)
+}
@@ -0,0 +1,48 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package compression
+
+import "bytes"
+
+// noneCompressor does not compress anything
+type noneCompressor struct{}
+
+// Compress will not compress anything
+func (noneCompressor) Compress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// Decompress will not decompress anything
+func (noneCompressor) Decompress(src []byte) ([]byte, error) {
+	return src, nil
+}
+
+// CompressBound returns the worst case size needed for a destination buffer
+func (noneCompressor) CompressBound(sourceLen int) int {
+	return sourceLen
+}
+
+// ContentEncoding is empty since there's no compression
+func (noneCompressor) ContentEncoding() string {
+	return ""
+}
+
+// NewStreamCompressor returns a StreamCompressor copying the data to output
+func (noneCompressor) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return noneStreamCompressor{output}
+}
+
+type noneStreamCompressor struct {
+	*bytes.Buffer
+}
+
+func (noneStreamCompressor) Flush() error {
+	return nil
+}
+
+func (noneStreamCompressor) Close() error {
+	return nil
+}
@@ -3,9 +3,6 @@
 // This product includes software developed at Datadog (https://www.datadoghq.com/).
 // Copyright 2016-present Datadog, Inc.
 
-//go:build zlib
-// +build zlib
-
 package compression
 
 import (
@@ -14,12 +11,11 @@ import (
 	"io/ioutil"
 )
 
-// ContentEncoding describes the HTTP header value associated with the compression method
-// var instead of const to ease testing
-var ContentEncoding = "deflate"
+// zlibCompressor compresses with zlib
+type zlibCompressor struct{}
 
 // Compress will compress the data with zlib
-func Compress(src []byte) ([]byte, error) {
+func (zlibCompressor) Compress(src []byte) ([]byte, error) {
 	var b bytes.Buffer
 	w := zlib.NewWriter(&b)
 	_, err := w.Write(src)
@@ -35,7 +31,7 @@ func Compress(src []byte) ([]byte, error) {
 }
 
 // Decompress will decompress the data with zlib
-func Decompress(src []byte) ([]byte, error) {
+func (zlibCompressor) Decompress(src []byte) ([]byte, error) {
 	r, err := zlib.NewReader(bytes.NewReader(src))
 	if err != nil {
 		return nil, err
@@ -49,8 +45,18 @@ func Decompress(src []byte) ([]byte, error) {
 	return dst, nil
 }
 
-//  CompressBound returns the worst case size needed for a destination buffer
-func CompressBound(sourceLen int) int {
+// CompressBound returns the worst case size needed for a destination buffer
+func (zlibCompressor) CompressBound(sourceLen int) int {
 	// From https://code.woboq.org/gcc/zlib/compress.c.html#compressBound
 	return sourceLen + (sourceLen >> 12) + (sourceLen >> 14) + (sourceLen >> 25) + 13
 }
+
+// ContentEncoding returns the HTTP header value associated with zlib
+func (zlibCompressor) ContentEncoding() string {
+	return "deflate"
+}
+
+// NewStreamCompressor returns a zlib writer
+func (zlibCompressor) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return zlib.NewWriter(output)
+}
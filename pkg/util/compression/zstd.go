@@ -9,27 +9,52 @@
 package compression
 
 import (
+	"bytes"
+
 	zstd_0 "github.com/DataDog/zstd_0"
 )
 
 // TODO: the intake still uses a pre-v1 (unstable) version of the zstd compression format.
 // The agent shouldn't use zstd compression until the intake supports a stable v1 format.
 
-// ContentEncoding describes the HTTP header value associated with the compression method
-// var instead of const to ease testing
-var ContentEncoding = "zstd"
+func init() {
+	compressors[ZstdKind] = zstdCompressor{}
+}
+
+// zstdCompressor compresses with zstd
+type zstdCompressor struct{}
 
 // Compress will compress the data with zstd
-func Compress(src []byte) ([]byte, error) {
+func (zstdCompressor) Compress(src []byte) ([]byte, error) {
 	return zstd_0.Compress(nil, src)
 }
 
 // Decompress will decompress the data with zstd
-func Decompress(src []byte) ([]byte, error) {
+func (zstdCompressor) Decompress(src []byte) ([]byte, error) {
 	return zstd_0.Decompress(nil, src)
 }
 
 // CompressBound returns the worst case size needed for a destination buffer
-func CompressBound(sourceLen int) int {
+func (zstdCompressor) CompressBound(sourceLen int) int {
 	return zstd_0.CompressBound(sourceLen)
 }
+
+// ContentEncoding returns the HTTP header value associated with zstd
+func (zstdCompressor) ContentEncoding() string {
+	return "zstd"
+}
+
+// NewStreamCompressor returns a zstd writer
+func (zstdCompressor) NewStreamCompressor(output *bytes.Buffer) StreamCompressor {
+	return zstdStreamCompressor{zstd_0.NewWriter(output)}
+}
+
+// zstdStreamCompressor wraps the zstd writer which compresses and writes each
+// block to its output as soon as it is written, so it doesn't need to be flushed.
+type zstdStreamCompressor struct {
+	*zstd_0.Writer
+}
+
+func (zstdStreamCompressor) Flush() error {
+	return nil
+}
@@ -20,6 +20,8 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
+var zlibCompressor, _ = compression.NewCompressor(compression.ZlibKind)
+
 func testMetadata(t *testing.T, d *dogstatsdTest) {
 	// waiting for metadata payload
 	timeOut := time.Tick(10 * time.Second)
@@ -33,7 +35,9 @@ func testMetadata(t *testing.T, d *dogstatsdTest) {
 	require.Len(t, requests, 1)
 
 	metadata := v5.Payload{}
-	err := json.Unmarshal([]byte(requests[0]), &metadata)
+	decompressedBody, err := zlibCompressor.Decompress([]byte(requests[0]))
+	require.NoError(t, err, "Could not decompress metadata request")
+	err = json.Unmarshal(decompressedBody, &metadata)
 	require.NoError(t, err, "Could not Unmarshal metadata request")
 
 	require.NotNil(t, metadata.Os)
@@ -59,7 +63,7 @@ func TestReceiveAndForward(t *testing.T) {
 	require.Len(t, requests, 1)
 
 	sc := []metrics.ServiceCheck{}
-	decompressedBody, err := compression.Decompress([]byte(requests[0]))
+	decompressedBody, err := zlibCompressor.Decompress([]byte(requests[0]))
 	require.NoError(t, err, "Could not decompress request body")
 	err = json.Unmarshal(decompressedBody, &sc)
 	require.NoError(t, err, fmt.Sprintf("Could not Unmarshal request body: %s", decompressedBody))